@@ -3,12 +3,16 @@ package main
 import (
 	"context"
 	"fmt"
+	"gator-swamp/internal/analytics"
+	"gator-swamp/internal/auth"
 	"gator-swamp/internal/config"
 	"gator-swamp/internal/database"
 	"gator-swamp/internal/engine"
 	"gator-swamp/internal/engine/actors"
 	"gator-swamp/internal/handlers"
+	"gator-swamp/internal/mailer"
 	"gator-swamp/internal/middleware"
+	"gator-swamp/internal/storage"
 	"gator-swamp/internal/utils"
 	"log"
 	"net/http"
@@ -18,6 +22,7 @@ import (
 	"time"
 
 	"github.com/asynkron/protoactor-go/actor"
+	"github.com/google/uuid"
 )
 
 func main() {
@@ -30,6 +35,7 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
+	auth.Configure(config.JWTSecret, config.TokenTTL)
 
 	// Initialize MongoDB with configuration
 	mongodb, err := database.NewMongoDB(config.MongoDBURI)
@@ -37,6 +43,40 @@ func main() {
 		log.Fatalf("Failed to connect to MongoDB: %v", err)
 	}
 
+	// Fill in fields older documents lack. Rewriting large collections can take far longer
+	// than creating indexes, so backfills get a timeout of their own.
+	backfillCtx, backfillCancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	mongodb.Backfill(backfillCtx)
+	if err := mongodb.RescorePostControversy(backfillCtx, config.MinControversialVotes); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	backfillCancel()
+
+	// Create indexes required by features such as share links. Registration and voting
+	// rely on unique indexes to reject duplicates, so the server doesn't start without them.
+	indexCtx, indexCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := mongodb.EnsureIndexes(indexCtx); err != nil {
+		log.Fatalf("Failed to create indexes: %v", err)
+	}
+	// Accounts on the bootstrap admin lists become administrators; further admins are
+	// granted by existing ones
+	if granted, err := mongodb.GrantBootstrapAdmins(indexCtx, config.AdminEmails, config.AdminUserIDs); err != nil {
+		log.Printf("Warning: %v", err)
+	} else if granted > 0 {
+		log.Printf("Granted admin role to %d bootstrap accounts", granted)
+	}
+	indexCancel()
+	middleware.AuthorizeAdmins(mongodb.IsUserAdmin)
+
+	// Authenticated requests keep users' last active times current, writing each user's
+	// time at most once per interval
+	middleware.TrackActivity(config.LastActiveWriteInterval, func(ctx context.Context, userID uuid.UUID, at time.Time) error {
+		return mongodb.TouchUserLastActive(ctx, userID, at, config.LastActiveWriteInterval)
+	})
+
+	// Start buffering analytics events; they are flushed to MongoDB in batches
+	analyticsRecorder := analytics.Start(mongodb)
+
 	// Set up graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -58,22 +98,63 @@ func main() {
 	rootContext := system.Root
 
 	// Initialize engine
-	gatorEngine := engine.NewEngine(system, metrics, mongodb)
+	gatorEngine := engine.NewEngine(system, metrics, mongodb, config)
 	engineProps := actor.PropsFromProducer(func() actor.Actor {
 		return gatorEngine
 	})
 	enginePID := rootContext.Spawn(engineProps)
 
-	// Initialize comment actor
-	commentActor := rootContext.Spawn(actor.PropsFromProducer(func() actor.Actor {
-		return actors.NewCommentActor(enginePID, mongodb)
-	}))
-
 	// Initialize direct message actor
 	directMessageActor := rootContext.Spawn(actor.PropsFromProducer(func() actor.Actor {
 		return actors.NewDirectMessageActor(mongodb)
 	}))
 
+	// Initialize share link actor
+	shareActor := rootContext.Spawn(actor.PropsFromProducer(func() actor.Actor {
+		return actors.NewShareActor(mongodb, gatorEngine.GetPostActor())
+	}))
+
+	// Initialize multireddit actor
+	multiredditActor := rootContext.Spawn(actor.PropsFromProducer(func() actor.Actor {
+		return actors.NewMultiredditActor(mongodb)
+	}))
+
+	// Initialize sitemap actor
+	sitemapActor := rootContext.Spawn(actor.PropsFromProducer(func() actor.Actor {
+		return actors.NewSitemapActor(mongodb, config.PublicBaseURL)
+	}))
+
+	// Initialize digest actor, which computes daily top-post digests per subreddit
+	digestActor := rootContext.Spawn(actor.PropsFromProducer(func() actor.Actor {
+		return actors.NewDigestActor(mongodb)
+	}))
+
+	// Initialize notification actor, which serves notifications recorded by other actors
+	notificationActor := rootContext.Spawn(actor.PropsFromProducer(func() actor.Actor {
+		return actors.NewNotificationActor(mongodb)
+	}))
+
+	// Initialize saved item actor for users' saved posts and comments
+	savedItemActor := rootContext.Spawn(actor.PropsFromProducer(func() actor.Actor {
+		return actors.NewSavedItemActor(mongodb)
+	}))
+
+	// Initialize janitor actor for periodic maintenance such as analytics rollups,
+	// finalizing deletions once their undo window has passed and archiving old posts
+	rootContext.Spawn(actor.PropsFromProducer(func() actor.Actor {
+		return actors.NewJanitorActor(mongodb, config.UndeleteWindow, config.ArchiveAfter)
+	}))
+
+	// Uploaded media is kept on local disk and served from /media/files/
+	mediaStorage, err := storage.NewLocalStorage(config.MediaDir, config.MediaPublicURL)
+	if err != nil {
+		log.Fatalf("Failed to initialize media storage: %v", err)
+	}
+
+	// Outgoing email, such as password resets, is written to the log until a mail
+	// service is configured
+	mail := mailer.NewLogMailer()
+
 	// Initialize server with all dependencies
 	server := handlers.NewServer(
 		system,
@@ -81,9 +162,18 @@ func main() {
 		gatorEngine,
 		enginePID,
 		metrics,
-		commentActor,
+		gatorEngine.GetCommentActor(),
 		directMessageActor,
+		shareActor,
+		multiredditActor,
+		sitemapActor,
+		digestActor,
+		notificationActor,
+		savedItemActor,
+		mediaStorage,
+		mail,
 		mongodb,
+		config,
 	)
 
 	// Set up HTTP router with middleware
@@ -93,7 +183,7 @@ func main() {
 	corsConfig := &middleware.CORSConfig{
 		AllowedOrigins:   config.AllowedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowedHeaders:   []string{"Content-Type", "Authorization", "Accept", "Origin", "X-Requested-With"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "Accept", "Origin", "X-Requested-With", "Idempotency-Key"},
 		ExposedHeaders:   []string{"Content-Length", "Content-Type"},
 		AllowCredentials: true,
 		MaxAge:           86400, // 24 hours
@@ -103,24 +193,175 @@ func main() {
 	mux.HandleFunc("/health", middleware.ApplyCORS(server.HandleHealth(), corsConfig))
 	mux.HandleFunc("/user/register", middleware.ApplyCORS(server.HandleUserRegistration(), corsConfig))
 	mux.HandleFunc("/user/login", middleware.ApplyCORS(server.HandleUserLogin(), corsConfig))
+	mux.HandleFunc("/user/refresh", middleware.ApplyCORS(server.HandleUserRefresh(), corsConfig))
+	mux.HandleFunc("/user/logout", middleware.ApplyCORS(server.HandleUserLogout(), corsConfig))
+	mux.HandleFunc("/user/logout-all", middleware.ApplyCORS(server.HandleUserLogoutAll(), corsConfig))
+	mux.HandleFunc("/user/password-reset/request", middleware.ApplyCORS(server.HandlePasswordResetRequest(), corsConfig))
+	mux.HandleFunc("/user/password-reset/confirm", middleware.ApplyCORS(server.HandlePasswordResetConfirm(), corsConfig))
+	mux.HandleFunc("/user/verify-email", middleware.ApplyCORS(server.HandleVerifyEmail(), corsConfig))
+	mux.HandleFunc("/s/", middleware.ApplyCORS(server.HandleShareRedirect(), corsConfig))
+	mux.Handle("/media/files/", http.StripPrefix("/media/files/", http.FileServer(http.Dir(config.MediaDir))))
+	// Sitemap file names are dynamic (/sitemap-posts-<n>.xml), so the sitemap handler
+	// also acts as the fallback route and returns 404 for anything else
+	mux.HandleFunc("/", middleware.ApplyCORS(server.HandleSitemap(), corsConfig))
 
-	// Protected endpoints (JWT required)
+	// Endpoints whose GETs are public; a JWT is optional for reads and required for writes
 	mux.HandleFunc("/subreddit",
-		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleSubreddits(), "/subreddit"), corsConfig))
+		middleware.ApplyCORS(middleware.ApplyOptionalJWTMiddleware(server.HandleSubreddits(), "/subreddit"), corsConfig))
 	mux.HandleFunc("/subreddit/members",
-		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleSubredditMembers(), "/subreddit/members"), corsConfig))
+		middleware.ApplyCORS(middleware.ApplyOptionalJWTMiddleware(server.HandleSubredditMembers(), "/subreddit/members"), corsConfig))
+	mux.HandleFunc("/subreddit/digest",
+		middleware.ApplyCORS(middleware.ApplyOptionalJWTMiddleware(server.HandleSubredditDigest(), "/subreddit/digest"), corsConfig))
+	mux.HandleFunc("/subreddit/flairs",
+		middleware.ApplyCORS(middleware.ApplyOptionalJWTMiddleware(server.HandleSubredditFlairs(), "/subreddit/flairs"), corsConfig))
+	mux.HandleFunc("/subreddit/userflairs",
+		middleware.ApplyCORS(middleware.ApplyOptionalJWTMiddleware(server.HandleSubredditUserFlairs(), "/subreddit/userflairs"), corsConfig))
+	mux.HandleFunc("/subreddit/moderators",
+		middleware.ApplyCORS(middleware.ApplyOptionalJWTMiddleware(server.HandleSubredditModerators(), "/subreddit/moderators"), corsConfig))
 	mux.HandleFunc("/post",
-		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandlePost(), "/post"), corsConfig))
+		middleware.ApplyCORS(middleware.ApplyOptionalJWTMiddleware(server.HandlePost(), "/post"), corsConfig))
+	mux.HandleFunc("/r/",
+		middleware.ApplyCORS(middleware.ApplyOptionalJWTMiddleware(server.HandlePostBySlug(), "/r/"), corsConfig))
+	mux.HandleFunc("/posts",
+		middleware.ApplyCORS(middleware.ApplyOptionalJWTMiddleware(server.HandleGetPostsByIDs(), "/posts"), corsConfig))
+	mux.HandleFunc("/posts/top",
+		middleware.ApplyCORS(middleware.ApplyOptionalJWTMiddleware(server.HandleTopPosts(), "/posts/top"), corsConfig))
+	mux.HandleFunc("/posts/trending",
+		middleware.ApplyCORS(middleware.ApplyOptionalJWTMiddleware(server.HandleTrendingPosts(), "/posts/trending"), corsConfig))
+	mux.HandleFunc("/post/related",
+		middleware.ApplyCORS(middleware.ApplyOptionalJWTMiddleware(server.HandleRelatedPosts(), "/post/related"), corsConfig))
+	mux.HandleFunc("/user/by-username",
+		middleware.ApplyCORS(middleware.ApplyOptionalJWTMiddleware(server.HandleGetUserByUsername(), "/user/by-username"), corsConfig))
+	mux.HandleFunc("/user/following",
+		middleware.ApplyCORS(middleware.ApplyOptionalJWTMiddleware(server.HandleGetFollowing(), "/user/following"), corsConfig))
+	mux.HandleFunc("/user/followers",
+		middleware.ApplyCORS(middleware.ApplyOptionalJWTMiddleware(server.HandleGetFollowers(), "/user/followers"), corsConfig))
+	mux.HandleFunc("/user/activity",
+		middleware.ApplyCORS(middleware.ApplyOptionalJWTMiddleware(server.HandleGetUserActivity(), "/user/activity"), corsConfig))
+	mux.HandleFunc("/user/posts",
+		middleware.ApplyCORS(middleware.ApplyOptionalJWTMiddleware(server.HandleGetUserPosts(), "/user/posts"), corsConfig))
+	mux.HandleFunc("/user/overview-posts",
+		middleware.ApplyCORS(middleware.ApplyOptionalJWTMiddleware(server.HandleGetUserPosts(), "/user/overview-posts"), corsConfig))
+	mux.HandleFunc("/user/comments",
+		middleware.ApplyCORS(middleware.ApplyOptionalJWTMiddleware(server.HandleGetUserComments(), "/user/comments"), corsConfig))
+	mux.HandleFunc("/comment",
+		middleware.ApplyCORS(middleware.ApplyOptionalJWTMiddleware(server.HandleComment(), "/comment"), corsConfig))
+	mux.HandleFunc("/comment/post",
+		middleware.ApplyCORS(middleware.ApplyOptionalJWTMiddleware(server.HandleGetPostComments(), "/comment/post"), corsConfig))
+	mux.HandleFunc("/comment/search",
+		middleware.ApplyCORS(middleware.ApplyOptionalJWTMiddleware(server.HandleSearchComments(), "/comment/search"), corsConfig))
+	mux.HandleFunc("/posts/recent",
+		middleware.ApplyCORS(middleware.ApplyOptionalJWTMiddleware(server.HandleRecentPosts(), "/posts/recent"), corsConfig))
+
+	// Protected endpoints (JWT required)
+	mux.HandleFunc("/subreddit/join",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleJoinSubreddit(), "/subreddit/join"), corsConfig))
+	mux.HandleFunc("/subreddit/leave",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleLeaveSubreddit(), "/subreddit/leave"), corsConfig))
+	mux.HandleFunc("/subreddit/approve",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleApproveSubredditUser(), "/subreddit/approve"), corsConfig))
+	mux.HandleFunc("/subreddit/search",
+		middleware.ApplyCORS(middleware.ApplyOptionalJWTMiddleware(server.HandleSearchSubreddits(), "/subreddit/search"), corsConfig))
+	mux.HandleFunc("/subreddit/ban",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleBanSubredditUser(), "/subreddit/ban"), corsConfig))
+	mux.HandleFunc("/subreddit/unban",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleUnbanSubredditUser(), "/subreddit/unban"), corsConfig))
+	mux.HandleFunc("/subreddit/bans",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleSubredditBans(), "/subreddit/bans"), corsConfig))
+	mux.HandleFunc("/subreddit/modqueue",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleModQueue(), "/subreddit/modqueue"), corsConfig))
+	mux.HandleFunc("/subreddit/modqueue/action",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleModQueueAction(), "/subreddit/modqueue/action"), corsConfig))
+	mux.HandleFunc("/subreddit/automod",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleSubredditAutomod(), "/subreddit/automod"), corsConfig))
+	mux.HandleFunc("/subreddit/analytics",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleSubredditAnalytics(), "/subreddit/analytics"), corsConfig))
+	mux.HandleFunc("/subreddit/settings",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleSubredditSettings(), "/subreddit/settings"), corsConfig))
+	mux.HandleFunc("/subreddit/userflair",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleSetUserFlair(), "/subreddit/userflair"), corsConfig))
+	mux.HandleFunc("/subreddit/pin",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandlePinPost(), "/subreddit/pin"), corsConfig))
+	mux.HandleFunc("/media/upload",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleMediaUpload(), "/media/upload"), corsConfig))
 	mux.HandleFunc("/post/vote",
 		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleVote(), "/post/vote"), corsConfig))
+	mux.HandleFunc("/post/unvote",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleUnvote(), "/post/unvote"), corsConfig))
+	mux.HandleFunc("/post/vote/batch",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleVoteBatch(), "/post/vote/batch"), corsConfig))
+	mux.HandleFunc("/post/votes",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleGetPostVoteStatuses(), "/post/votes"), corsConfig))
+	mux.HandleFunc("/post/poll/vote",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleVotePoll(), "/post/poll/vote"), corsConfig))
+	mux.HandleFunc("/post/crosspost",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleCrosspost(), "/post/crosspost"), corsConfig))
+	mux.HandleFunc("/post/flair",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleSetPostFlair(), "/post/flair"), corsConfig))
+	mux.HandleFunc("/post/hide",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleHidePost(), "/post/hide"), corsConfig))
+	mux.HandleFunc("/post/unhide",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleUnhidePost(), "/post/unhide"), corsConfig))
+	mux.HandleFunc("/post/share",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleSharePost(), "/post/share"), corsConfig))
 	mux.HandleFunc("/user/feed",
 		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleGetFeed(), "/user/feed"), corsConfig))
+	mux.HandleFunc("/user/feed/following",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleGetFollowingFeed(), "/user/feed/following"), corsConfig))
+	mux.HandleFunc("/user/follow",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleFollowUser(), "/user/follow"), corsConfig))
+	mux.HandleFunc("/user/unfollow",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleUnfollowUser(), "/user/unfollow"), corsConfig))
+	mux.HandleFunc("/user/block",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleBlockUser(), "/user/block"), corsConfig))
+	mux.HandleFunc("/user/unblock",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleUnblockUser(), "/user/unblock"), corsConfig))
+	mux.HandleFunc("/user/preferences",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleUserPreferences(), "/user/preferences"), corsConfig))
+	mux.HandleFunc("/user/blocked",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleGetBlockedUsers(), "/user/blocked"), corsConfig))
+	mux.HandleFunc("/user/scheduled",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleScheduledPosts(), "/user/scheduled"), corsConfig))
+	mux.HandleFunc("/user/notifications",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleGetNotifications(), "/user/notifications"), corsConfig))
+	mux.HandleFunc("/user/notifications/read",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleMarkNotificationsRead(), "/user/notifications/read"), corsConfig))
+	mux.HandleFunc("/user/hidden",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleGetHiddenPosts(), "/user/hidden"), corsConfig))
+	mux.HandleFunc("/user/save",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleSaveItem(), "/user/save"), corsConfig))
+	mux.HandleFunc("/user/unsave",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleUnsaveItem(), "/user/unsave"), corsConfig))
+	mux.HandleFunc("/user/saved",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleGetSavedItems(), "/user/saved"), corsConfig))
+	mux.HandleFunc("/user/save-post",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleSavePost(), "/user/save-post"), corsConfig))
+	mux.HandleFunc("/user/unsave-post",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleUnsavePost(), "/user/unsave-post"), corsConfig))
+	mux.HandleFunc("/user/saved-posts",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleGetSavedPosts(), "/user/saved-posts"), corsConfig))
+	mux.HandleFunc("/user/multireddits",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleMultireddits(), "/user/multireddits"), corsConfig))
+	mux.HandleFunc("/user/multireddits/subreddits",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleMultiredditSubreddits(), "/user/multireddits/subreddits"), corsConfig))
+	mux.HandleFunc("/user/multireddits/",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleMultiredditFeed(), "/user/multireddits/"), corsConfig))
+	// The same multireddit endpoints under the singular path, with the feed's multireddit
+	// given by the multiId query parameter
+	mux.HandleFunc("/user/multireddit",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleMultireddits(), "/user/multireddit"), corsConfig))
+	mux.HandleFunc("/user/multireddit/subreddits",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleMultiredditSubreddits(), "/user/multireddit/subreddits"), corsConfig))
+	mux.HandleFunc("/user/multireddit/feed",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleMultiredditFeed(), "/user/multireddit/feed"), corsConfig))
 	mux.HandleFunc("/user/profile",
-		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleUserProfile(), "/user/profile"), corsConfig))
-	mux.HandleFunc("/comment",
-		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleComment(), "/comment"), corsConfig))
-	mux.HandleFunc("/comment/post",
-		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleGetPostComments(), "/comment/post"), corsConfig))
+		middleware.ApplyCORS(middleware.ApplyOptionalJWTMiddleware(server.HandleUserProfile(), "/user/profile"), corsConfig))
+	mux.HandleFunc("/user/password",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleChangePassword(), "/user/password"), corsConfig))
+	mux.HandleFunc("/user/email",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleChangeEmail(), "/user/email"), corsConfig))
+	mux.HandleFunc("/user/verify-email/resend",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleResendEmailVerification(), "/user/verify-email/resend"), corsConfig))
 	mux.HandleFunc("/messages",
 		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleDirectMessages(), "/messages"), corsConfig))
 	mux.HandleFunc("/messages/conversation",
@@ -129,10 +370,34 @@ func main() {
 		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleMarkMessageRead(), "/messages/read"), corsConfig))
 	mux.HandleFunc("/comment/vote",
 		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleCommentVote(), "/comment/vote"), corsConfig))
-	mux.HandleFunc("/posts/recent",
-		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleRecentPosts(), "/posts/recent"), corsConfig))
+	mux.HandleFunc("/comment/undelete",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleUndeleteComment(), "/comment/undelete"), corsConfig))
+	mux.HandleFunc("/comment/history",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleGetCommentHistory(), "/comment/history"), corsConfig))
+	mux.HandleFunc("/comment/distinguish",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleDistinguishComment(), "/comment/distinguish"), corsConfig))
+	mux.HandleFunc("/post/undelete",
+		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleUndeletePost(), "/post/undelete"), corsConfig))
 	mux.HandleFunc("/users",
 		middleware.ApplyCORS(middleware.ApplyJWTMiddleware(server.HandleGetAllUsers(), "/users"), corsConfig))
+	mux.HandleFunc("/admin/analytics",
+		middleware.ApplyCORS(middleware.ApplyAdminMiddleware(server.HandleAdminAnalytics(), "/admin/analytics"), corsConfig))
+	mux.HandleFunc("/admin/users/related",
+		middleware.ApplyCORS(middleware.ApplyAdminMiddleware(server.HandleAdminRelatedUsers(), "/admin/users/related"), corsConfig))
+	mux.HandleFunc("/admin/users/profile",
+		middleware.ApplyCORS(middleware.ApplyAdminMiddleware(server.HandleAdminUserProfile(), "/admin/users/profile"), corsConfig))
+	mux.HandleFunc("/admin/users/karma",
+		middleware.ApplyCORS(middleware.ApplyAdminMiddleware(server.HandleAdminAdjustKarma(), "/admin/users/karma"), corsConfig))
+	mux.HandleFunc("/admin/users/admin",
+		middleware.ApplyCORS(middleware.ApplyAdminMiddleware(server.HandleAdminSetRole(), "/admin/users/admin"), corsConfig))
+	mux.HandleFunc("/admin/users/ban",
+		middleware.ApplyCORS(middleware.ApplyAdminMiddleware(server.HandleAdminBanUser(), "/admin/users/ban"), corsConfig))
+	mux.HandleFunc("/admin/posts/delete",
+		middleware.ApplyCORS(middleware.ApplyAdminMiddleware(server.HandleAdminDeletePost(), "/admin/posts/delete"), corsConfig))
+	mux.HandleFunc("/admin/subreddits/delete",
+		middleware.ApplyCORS(middleware.ApplyAdminMiddleware(server.HandleAdminDeleteSubreddit(), "/admin/subreddits/delete"), corsConfig))
+	mux.HandleFunc("/admin/subreddits/reconcile-members",
+		middleware.ApplyCORS(middleware.ApplyAdminMiddleware(server.HandleAdminReconcileMembers(), "/admin/subreddits/reconcile-members"), corsConfig))
 
 	// Set up HTTP server
 	serverAddr := fmt.Sprintf("%s:%d", config.Server.Host, config.Server.Port)
@@ -165,6 +430,9 @@ func main() {
 		log.Printf("HTTP server shutdown error: %v", err)
 	}
 
+	// Flush buffered analytics events before the connection goes away
+	analyticsRecorder.Stop()
+
 	// Close MongoDB connection
 	if err := mongodb.Close(shutdownCtx); err != nil {
 		log.Printf("Error closing MongoDB connection: %v", err)
@@ -9,7 +9,7 @@ require (
 	golang.org/x/crypto v0.26.0
 )
 
-require github.com/golang-jwt/jwt/v5 v5.2.1 // indirect
+require github.com/golang-jwt/jwt/v5 v5.2.1
 
 require (
 	github.com/Workiva/go-datastructures v1.1.3 // indirect
@@ -0,0 +1,151 @@
+// Package analytics records product events (views, votes, posts, signups, subreddit joins) for
+// time-series reporting. Events are buffered in memory and written to MongoDB in
+// batches; recording never blocks or fails the caller.
+package analytics
+
+import (
+	"context"
+	"gator-swamp/internal/database"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event types
+const (
+	EventView   = "view"
+	EventVote   = "vote"
+	EventPost   = "post"
+	EventSignup = "signup"
+	EventJoin   = "join" // A user joining a subreddit
+)
+
+const (
+	bufferSize    = 10000 // Events beyond this are dropped rather than blocking callers
+	batchSize     = 500
+	flushInterval = 5 * time.Second
+)
+
+// Recorder buffers events and flushes them to MongoDB from a background goroutine
+type Recorder struct {
+	events  chan database.AnalyticsEventDocument
+	mongodb *database.MongoDB
+	done    chan struct{}
+	wg      sync.WaitGroup
+	dropped int
+	mu      sync.Mutex
+}
+
+var defaultRecorder *Recorder
+
+// Start creates the process-wide recorder and begins flushing events.
+// Calls to Record before Start are ignored.
+func Start(mongodb *database.MongoDB) *Recorder {
+	r := &Recorder{
+		events:  make(chan database.AnalyticsEventDocument, bufferSize),
+		mongodb: mongodb,
+		done:    make(chan struct{}),
+	}
+	r.wg.Add(1)
+	go r.run()
+
+	defaultRecorder = r
+	return r
+}
+
+// Record queues an event. entityID is the thing the event is about (a post for
+// views and votes, the new user for signups); userID is the actor, if known.
+func Record(eventType string, entityID, userID uuid.UUID) {
+	RecordInSubreddit(eventType, entityID, userID, uuid.Nil)
+}
+
+// RecordInSubreddit queues an event attributed to a subreddit so rollups can be filtered by it
+func RecordInSubreddit(eventType string, entityID, userID, subredditID uuid.UUID) {
+	r := defaultRecorder
+	if r == nil {
+		return
+	}
+
+	event := database.AnalyticsEventDocument{
+		Type:      eventType,
+		EntityID:  entityID.String(),
+		CreatedAt: time.Now().UTC(),
+	}
+	if userID != uuid.Nil {
+		event.UserID = userID.String()
+	}
+	if subredditID != uuid.Nil {
+		event.SubredditID = subredditID.String()
+	}
+
+	select {
+	case r.events <- event:
+	default:
+		r.mu.Lock()
+		r.dropped++
+		r.mu.Unlock()
+	}
+}
+
+// Stop flushes any buffered events and stops the background goroutine
+func (r *Recorder) Stop() {
+	close(r.done)
+	r.wg.Wait()
+}
+
+func (r *Recorder) run() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]database.AnalyticsEventDocument, 0, batchSize)
+	for {
+		select {
+		case event := <-r.events:
+			batch = append(batch, event)
+			if len(batch) >= batchSize {
+				batch = r.flush(batch)
+			}
+		case <-ticker.C:
+			batch = r.flush(batch)
+		case <-r.done:
+			// Drain whatever is still queued before exiting
+			for {
+				select {
+				case event := <-r.events:
+					batch = append(batch, event)
+				default:
+					r.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush writes the batch and returns an empty slice to reuse. Failed batches are
+// logged and discarded; analytics are best-effort.
+func (r *Recorder) flush(batch []database.AnalyticsEventDocument) []database.AnalyticsEventDocument {
+	r.mu.Lock()
+	dropped := r.dropped
+	r.dropped = 0
+	r.mu.Unlock()
+	if dropped > 0 {
+		log.Printf("Analytics: Dropped %d events because the buffer was full", dropped)
+	}
+
+	if len(batch) == 0 {
+		return batch
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := r.mongodb.InsertAnalyticsEvents(ctx, batch); err != nil {
+		log.Printf("Analytics: Failed to write %d events: %v", len(batch), err)
+	}
+	return batch[:0]
+}
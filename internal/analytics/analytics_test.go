@@ -0,0 +1,81 @@
+package analytics
+
+import (
+	"context"
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/database/dbtest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// useRecorder makes r the process-wide recorder for the rest of the test
+func useRecorder(t *testing.T, r *Recorder) {
+	t.Helper()
+	previous := defaultRecorder
+	defaultRecorder = r
+	t.Cleanup(func() { defaultRecorder = previous })
+}
+
+// Callers must never wait on analytics, so events that don't fit in the buffer are
+// counted as dropped instead
+func TestRecordDropsEventsWhenBufferIsFull(t *testing.T) {
+	// Nothing drains this recorder
+	r := &Recorder{events: make(chan database.AnalyticsEventDocument, 2)}
+	useRecorder(t, r)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 5; i++ {
+			Record(EventView, uuid.New(), uuid.Nil)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Record blocked on a full buffer")
+	}
+
+	if len(r.events) != 2 {
+		t.Fatalf("%d events buffered, want 2", len(r.events))
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.dropped != 3 {
+		t.Fatalf("%d events dropped, want 3", r.dropped)
+	}
+}
+
+func TestRecordBeforeStartIsIgnored(t *testing.T) {
+	useRecorder(t, nil)
+	Record(EventSignup, uuid.New(), uuid.New())
+}
+
+// Events still buffered when the recorder stops are written rather than lost
+func TestStopFlushesBufferedEvents(t *testing.T) {
+	mongodb := dbtest.New(t)
+	previous := defaultRecorder
+	t.Cleanup(func() { defaultRecorder = previous })
+
+	r := Start(mongodb)
+	subredditID := uuid.New()
+	Record(EventSignup, uuid.New(), uuid.Nil)
+	RecordInSubreddit(EventJoin, subredditID, uuid.New(), subredditID)
+	r.Stop()
+
+	ctx := context.Background()
+	count, err := mongodb.AnalyticsEvents.CountDocuments(ctx, bson.M{})
+	if err != nil || count != 2 {
+		t.Fatalf("%d events written (%v), want 2", count, err)
+	}
+	var join database.AnalyticsEventDocument
+	if err := mongodb.AnalyticsEvents.FindOne(ctx, bson.M{"type": EventJoin}).Decode(&join); err != nil {
+		t.Fatalf("finding join event: %v", err)
+	}
+	if join.SubredditID != subredditID.String() || join.UserID == "" {
+		t.Fatalf("join event = %+v, want it attributed to the user and subreddit", join)
+	}
+}
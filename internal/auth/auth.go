@@ -0,0 +1,109 @@
+// Package auth issues and validates the signed JWTs clients authenticate with. Tokens
+// are signed with HS256 using the secret given to Configure.
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const issuer = "gator-swamp-api"
+
+// Errors returned by ValidateToken, wrapping the underlying cause. Check them with errors.Is.
+var (
+	ErrTokenExpired = errors.New("token expired")
+	ErrTokenInvalid = errors.New("invalid token")
+	ErrTokenRevoked = errors.New("token revoked")
+)
+
+// Claims are the contents of a token
+type Claims struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Username string    `json:"username"`
+	jwt.RegisteredClaims
+}
+
+var (
+	mu     sync.RWMutex
+	secret []byte
+	ttl    time.Duration
+)
+
+// Configure sets the secret tokens are signed with and how long they stay valid. It
+// must be called before tokens are generated or validated.
+func Configure(signingSecret string, tokenTTL time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+	secret = []byte(signingSecret)
+	ttl = tokenTTL
+}
+
+func settings() ([]byte, time.Duration, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if len(secret) == 0 {
+		return nil, 0, errors.New("auth: no signing secret configured")
+	}
+	return secret, ttl, nil
+}
+
+// GenerateToken issues a token for the user that expires after the configured lifetime
+func GenerateToken(userID uuid.UUID, username string) (string, error) {
+	key, lifetime, err := settings()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := &Claims{
+		UserID:   userID,
+		Username: username,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(lifetime)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    issuer,
+			Subject:   userID.String(),
+			ID:        uuid.New().String(),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(key)
+}
+
+// ValidateToken checks a token's signature, issuer and lifetime and returns its claims.
+// Expired tokens fail with ErrTokenExpired, revoked ones with ErrTokenRevoked and all
+// other problems with ErrTokenInvalid. A token is expired from the second its expiry names.
+func ValidateToken(tokenString string) (*Claims, error) {
+	key, _, err := settings()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &Claims{}
+	_, err = jwt.ParseWithClaims(tokenString, claims,
+		func(token *jwt.Token) (interface{}, error) {
+			return key, nil
+		},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, fmt.Errorf("%w: %v", ErrTokenExpired, err)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrTokenInvalid, err)
+	}
+	if claims.UserID == uuid.Nil {
+		return nil, fmt.Errorf("%w: missing user ID", ErrTokenInvalid)
+	}
+	if isRevoked(claims) {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
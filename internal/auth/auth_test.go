@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const testSecret = "test-signing-secret"
+
+func configureForTest(t *testing.T) {
+	t.Helper()
+	Configure(testSecret, time.Hour)
+}
+
+// signClaims signs claims the way GenerateToken does, so tests can vary one field
+func signClaims(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.Claims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatalf("signing token: %v", err)
+	}
+	return token
+}
+
+// validClaims returns claims that ValidateToken accepts, for tests to spoil
+func validClaims(userID uuid.UUID) *Claims {
+	now := time.Now()
+	return &Claims{
+		UserID:   userID,
+		Username: "gator",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    issuer,
+			Subject:   userID.String(),
+			ID:        uuid.New().String(),
+		},
+	}
+}
+
+func TestGenerateAndValidateRoundTrip(t *testing.T) {
+	configureForTest(t)
+	userID := uuid.New()
+
+	token, err := GenerateToken(userID, "gator")
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	claims, err := ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+
+	if claims.UserID != userID || claims.Username != "gator" {
+		t.Errorf("claims = %s %q, want %s %q", claims.UserID, claims.Username, userID, "gator")
+	}
+	if claims.Issuer != issuer || claims.Subject != userID.String() {
+		t.Errorf("issuer %q, subject %q; want %q, %q", claims.Issuer, claims.Subject, issuer, userID)
+	}
+	if claims.ID == "" {
+		t.Error("token has no ID, so it can't be revoked")
+	}
+	if claims.ExpiresAt == nil || claims.ExpiresAt.Sub(claims.IssuedAt.Time) != time.Hour {
+		t.Errorf("token lifetime = %v, want the configured hour", claims.ExpiresAt)
+	}
+}
+
+func TestValidateTokenRejects(t *testing.T) {
+	configureForTest(t)
+	userID := uuid.New()
+
+	expired := validClaims(userID)
+	expired.IssuedAt = jwt.NewNumericDate(time.Now().Add(-2 * time.Hour))
+	expired.NotBefore = expired.IssuedAt
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+
+	wrongIssuer := validClaims(userID)
+	wrongIssuer.Issuer = "someone-else"
+
+	noExpiry := validClaims(userID)
+	noExpiry.ExpiresAt = nil
+
+	noUser := validClaims(uuid.Nil)
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"expired", signClaims(t, jwt.SigningMethodHS256, []byte(testSecret), expired), ErrTokenExpired},
+		{"wrong issuer", signClaims(t, jwt.SigningMethodHS256, []byte(testSecret), wrongIssuer), ErrTokenInvalid},
+		{"missing exp", signClaims(t, jwt.SigningMethodHS256, []byte(testSecret), noExpiry), ErrTokenInvalid},
+		{"missing user ID", signClaims(t, jwt.SigningMethodHS256, []byte(testSecret), noUser), ErrTokenInvalid},
+		{"wrong secret", signClaims(t, jwt.SigningMethodHS256, []byte("another-secret"), validClaims(userID)), ErrTokenInvalid},
+		{"wrong alg HS512", signClaims(t, jwt.SigningMethodHS512, []byte(testSecret), validClaims(userID)), ErrTokenInvalid},
+		{"wrong alg none", signClaims(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, validClaims(userID)), ErrTokenInvalid},
+		{"garbage", "not.a.token", ErrTokenInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := ValidateToken(tt.token)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("ValidateToken error = %v, want %v", err, tt.want)
+			}
+			if claims != nil {
+				t.Errorf("ValidateToken returned claims %+v with an error", claims)
+			}
+		})
+	}
+}
+
+func TestValidateTokenRejectsRevokedID(t *testing.T) {
+	configureForTest(t)
+
+	revoked, err := GenerateToken(uuid.New(), "gator")
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	other, err := GenerateToken(uuid.New(), "other")
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	claims, err := ValidateToken(revoked)
+	if err != nil {
+		t.Fatalf("ValidateToken before revoking: %v", err)
+	}
+	RevokeToken(claims)
+
+	if _, err := ValidateToken(revoked); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("ValidateToken of revoked jti error = %v, want %v", err, ErrTokenRevoked)
+	}
+	if _, err := ValidateToken(other); err != nil {
+		t.Errorf("revoking one token rejected another: %v", err)
+	}
+}
+
+func TestValidateTokenRejectsRevokedUser(t *testing.T) {
+	configureForTest(t)
+	userID := uuid.New()
+
+	// Revocations cover tokens issued in earlier seconds only
+	before := validClaims(userID)
+	before.IssuedAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	before.NotBefore = before.IssuedAt
+	token := signClaims(t, jwt.SigningMethodHS256, []byte(testSecret), before)
+
+	RevokeUserTokens(userID, time.Now())
+
+	if _, err := ValidateToken(token); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("ValidateToken of token issued before logout-all error = %v, want %v", err, ErrTokenRevoked)
+	}
+
+	after := validClaims(userID)
+	after.IssuedAt = jwt.NewNumericDate(time.Now().Add(time.Second))
+	if _, err := ValidateToken(signClaims(t, jwt.SigningMethodHS256, []byte(testSecret), after)); err != nil {
+		t.Errorf("token issued after logout-all was rejected: %v", err)
+	}
+}
+
+func TestOpaqueTokensHashConsistently(t *testing.T) {
+	token, hash, err := NewPasswordResetToken()
+	if err != nil {
+		t.Fatalf("NewPasswordResetToken: %v", err)
+	}
+	if hash == token || HashPasswordResetToken(token) != hash {
+		t.Errorf("reset token hash %q doesn't match hashing the token again", hash)
+	}
+
+	other, _, err := NewPasswordResetToken()
+	if err != nil {
+		t.Fatalf("NewPasswordResetToken: %v", err)
+	}
+	if other == token {
+		t.Error("two reset tokens were the same")
+	}
+}
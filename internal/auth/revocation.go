@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// userRevocation rejects a user's tokens issued before a logout from every device. It is
+// kept until the last of those tokens would have expired.
+type userRevocation struct {
+	before time.Time
+	until  time.Time
+}
+
+// The revocation list lives in memory and only holds entries for tokens that haven't
+// expired yet, so it stays as small as the number of recent logouts
+var (
+	revokedMu     sync.Mutex
+	revokedTokens = make(map[string]time.Time) // Token ID to the token's expiry
+	revokedUsers  = make(map[uuid.UUID]userRevocation)
+)
+
+// RevokeToken rejects a token until it expires. Tokens issued before tokens had IDs can't
+// be revoked one by one and simply run out.
+func RevokeToken(claims *Claims) {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return
+	}
+
+	revokedMu.Lock()
+	defer revokedMu.Unlock()
+	pruneRevocations(time.Now())
+	revokedTokens[claims.ID] = claims.ExpiresAt.Time
+}
+
+// RevokeUserTokens rejects every token issued to a user before at
+func RevokeUserTokens(userID uuid.UUID, at time.Time) {
+	_, lifetime, _ := settings()
+
+	revokedMu.Lock()
+	defer revokedMu.Unlock()
+	pruneRevocations(time.Now())
+	// Issue times are kept to the second, so only tokens from earlier seconds are covered
+	revokedUsers[userID] = userRevocation{before: at.Truncate(time.Second), until: at.Add(lifetime)}
+}
+
+// isRevoked reports whether a token was revoked on its own or with the rest of its
+// user's tokens
+func isRevoked(claims *Claims) bool {
+	now := time.Now()
+
+	revokedMu.Lock()
+	defer revokedMu.Unlock()
+	if expiry, ok := revokedTokens[claims.ID]; ok && claims.ID != "" && now.Before(expiry) {
+		return true
+	}
+	revocation, ok := revokedUsers[claims.UserID]
+	return ok && now.Before(revocation.until) &&
+		claims.IssuedAt != nil && claims.IssuedAt.Time.Before(revocation.before)
+}
+
+// pruneRevocations drops entries for tokens that have expired anyway. The caller holds
+// revokedMu.
+func pruneRevocations(now time.Time) {
+	for id, expiry := range revokedTokens {
+		if !now.Before(expiry) {
+			delete(revokedTokens, id)
+		}
+	}
+	for userID, revocation := range revokedUsers {
+		if !now.Before(revocation.until) {
+			delete(revokedUsers, userID)
+		}
+	}
+}
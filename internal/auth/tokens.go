@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// opaqueTokenBytes is how much randomness a refresh, password reset or email
+// verification token carries
+const opaqueTokenBytes = 32
+
+// NewRefreshToken returns a random opaque refresh token and the hash it is stored under.
+// Only the hash is kept, so a leaked sessions collection can't be used to sign in.
+func NewRefreshToken() (token, hash string, err error) {
+	token, err = newOpaqueToken()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate refresh token: %v", err)
+	}
+	return token, HashRefreshToken(token), nil
+}
+
+// HashRefreshToken returns the hash a refresh token is stored and looked up under
+func HashRefreshToken(token string) string {
+	return hashOpaqueToken(token)
+}
+
+// NewPasswordResetToken returns a random single-use password reset token and the hash
+// it is stored under
+func NewPasswordResetToken() (token, hash string, err error) {
+	token, err = newOpaqueToken()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate password reset token: %v", err)
+	}
+	return token, HashPasswordResetToken(token), nil
+}
+
+// HashPasswordResetToken returns the hash a password reset token is stored and looked
+// up under
+func HashPasswordResetToken(token string) string {
+	return hashOpaqueToken(token)
+}
+
+// NewEmailVerificationToken returns a random single-use email verification token and
+// the hash it is stored under
+func NewEmailVerificationToken() (token, hash string, err error) {
+	token, err = newOpaqueToken()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate email verification token: %v", err)
+	}
+	return token, HashEmailVerificationToken(token), nil
+}
+
+// HashEmailVerificationToken returns the hash an email verification token is stored and
+// looked up under
+func HashEmailVerificationToken(token string) string {
+	return hashOpaqueToken(token)
+}
+
+func newOpaqueToken() (string, error) {
+	b := make([]byte, opaqueTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -3,14 +3,39 @@ package config
 
 import (
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"golang.org/x/crypto/bcrypt"
 )
 
+// Actions taken when a registration matches a banned or abuse-deleted account
+const (
+	DuplicateAccountReject = "reject" // Refuse the registration
+	DuplicateAccountFlag   = "flag"   // Create the account but flag it for admin review
+)
+
+// RateLimit allows at most Count actions within any period of length Window
+type RateLimit struct {
+	Count  int
+	Window time.Duration
+}
+
+// SubredditCreationRules are what an account needs to create a subreddit. Administrators
+// bypass them.
+type SubredditCreationRules struct {
+	MinAccountAge time.Duration
+	MinKarma      int
+	MaxPerDay     int // Subreddits a user can create in any 24 hours; 0 disables the cap
+
+	RequireVerifiedEmail bool // Only users who verified their email can create subreddits
+}
+
 // ServerConfig holds all server-related settings
 type ServerConfig struct {
 	Port           int
@@ -20,12 +45,64 @@ type ServerConfig struct {
 
 // Config holds the complete application configuration
 type Config struct {
-	Server         *ServerConfig
-	MongoDBURI     string
-	AllowedOrigins []string
-	Debug          bool
+	Server          *ServerConfig
+	MongoDBURI      string
+	AllowedOrigins  []string
+	Debug           bool
+	PublicBaseURL   string        // Base URL of the web client, used to build links to content
+	AdminUserIDs    []string      // Users made administrators at startup
+	AdminEmails     []string      // Accounts made administrators at startup or when they verify their email, by email
+	UndeleteWindow  time.Duration // How long authors can undo deleting a post or comment
+	MaxCommentDepth int           // Deepest reply level allowed; top-level comments are depth 0
+
+	// Longest post title and content allowed, in characters
+	MaxPostTitleLength   int
+	MaxPostContentLength int
+
+	DuplicateLinkWindow time.Duration // How far back a link post counts as a duplicate of a new one; 0 disables the check
+	ArchiveAfter        time.Duration // Age at which posts become read-only; 0 disables archival
+
+	PostRateLimits          []RateLimit   // How often an author can create posts; empty disables the limits
+	ModeratorPostRateFactor int           // Multiplies the counts of PostRateLimits in subreddits the author moderates
+	IdempotencyKeyTTL       time.Duration // How long a post creation can be replayed with the same idempotency key
+
+	CommentCollapseThreshold int // Comments at or below this karma are collapsed in trees by default
+	MinControversialVotes    int // Posts with fewer votes score zero for the controversial sort
+
+	MediaExtensions map[string]string // File extensions allowed for post media, mapped to their media type
+
+	// Uploaded media, stored on local disk and served from MediaPublicURL
+	MediaDir            string
+	MediaPublicURL      string
+	MediaMaxUploadBytes int64
+	MediaUploadTypes    map[string]string // Content types accepted for upload, mapped to the file extension stored
+
+	DuplicateAccountAction string // DuplicateAccountReject or DuplicateAccountFlag
+
+	SubredditCreation SubredditCreationRules
+
+	PasswordHashCost  int // bcrypt cost of new password hashes; older hashes are upgraded at login
+	MinPasswordLength int // Shortest password accepted at registration, in characters
+
+	JWTSecret       string        // Signs login tokens; required unless Debug is set
+	TokenTTL        time.Duration // How long a login token stays valid
+	RefreshTokenTTL time.Duration // How long a refresh token stays valid; each refresh issues a new one
+
+	PasswordResetTTL            time.Duration // How long an emailed password reset token can be used
+	PasswordResetResendInterval time.Duration // Least time between password reset emails to a user
+	PasswordResetIPLimit        int           // Password reset requests accepted from one IP address an hour; 0 disables the cap
+
+	EmailVerificationTTL            time.Duration // How long an emailed verification token can be used
+	EmailVerificationResendInterval time.Duration // Least time between verification emails to a user
+
+	LastActiveWriteInterval time.Duration // Least time between writes of a user's last active time
+	ActiveRecentlyWindow    time.Duration // How recently a user must have been active to show as active on their profile
 }
 
+// devJWTSecret signs tokens in debug mode when JWT_SECRET isn't set. It is public, so
+// tokens signed with it prove nothing.
+const devJWTSecret = "gatorswamp-dev-secret"
+
 // DefaultConfig provides default server settings
 func DefaultConfig() *ServerConfig {
 	return &ServerConfig{
@@ -85,10 +162,58 @@ func LoadConfig() (*Config, error) {
 
 	// Initialize complete config
 	config := &Config{
-		Server:         serverConfig,
-		MongoDBURI:     mongoURI,
-		AllowedOrigins: []string{"*"}, // Default to allow all origins
-		Debug:          false,
+		Server:          serverConfig,
+		MongoDBURI:      mongoURI,
+		AllowedOrigins:  []string{"*"}, // Default to allow all origins
+		Debug:           false,
+		PublicBaseURL:   "http://localhost:3000",
+		UndeleteWindow:  30 * time.Minute,
+		MaxCommentDepth: 10,
+
+		MaxPostTitleLength:   300,
+		MaxPostContentLength: 40000,
+
+		DuplicateLinkWindow: 30 * 24 * time.Hour,
+		ArchiveAfter:        180 * 24 * time.Hour,
+
+		PostRateLimits:          []RateLimit{{Count: 1, Window: 30 * time.Second}, {Count: 10, Window: time.Hour}},
+		ModeratorPostRateFactor: 5,
+		IdempotencyKeyTTL:       24 * time.Hour,
+
+		CommentCollapseThreshold: -5,
+		MinControversialVotes:    10,
+		DuplicateAccountAction:   DuplicateAccountReject,
+
+		MediaExtensions: map[string]string{
+			"jpg": "image", "jpeg": "image", "png": "image", "gif": "image", "webp": "image",
+			"mp4": "video", "webm": "video",
+		},
+
+		MediaDir:            "uploads",
+		MediaPublicURL:      fmt.Sprintf("http://%s:%d/media/files", serverConfig.Host, serverConfig.Port),
+		MediaMaxUploadBytes: 10 << 20,
+		MediaUploadTypes: map[string]string{
+			"image/jpeg": "jpg", "image/png": "png", "image/gif": "gif", "image/webp": "webp",
+			"video/mp4": "mp4", "video/webm": "webm",
+		},
+
+		SubredditCreation: SubredditCreationRules{MinAccountAge: 7 * 24 * time.Hour, MinKarma: 50, MaxPerDay: 3},
+
+		PasswordHashCost:  14,
+		MinPasswordLength: 8,
+
+		TokenTTL:        24 * time.Hour,
+		RefreshTokenTTL: 30 * 24 * time.Hour,
+
+		PasswordResetTTL:            15 * time.Minute,
+		PasswordResetResendInterval: 5 * time.Minute,
+		PasswordResetIPLimit:        10,
+
+		EmailVerificationTTL:            24 * time.Hour,
+		EmailVerificationResendInterval: 5 * time.Minute,
+
+		LastActiveWriteInterval: 5 * time.Minute,
+		ActiveRecentlyWindow:    15 * time.Minute,
 	}
 
 	// Override remaining settings from environment if provided
@@ -100,5 +225,251 @@ func LoadConfig() (*Config, error) {
 		config.Debug = true
 	}
 
+	if baseURL := os.Getenv("PUBLIC_BASE_URL"); baseURL != "" {
+		config.PublicBaseURL = strings.TrimRight(baseURL, "/")
+	}
+
+	if admins := os.Getenv("ADMIN_USER_IDS"); admins != "" {
+		for _, id := range strings.Split(admins, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				config.AdminUserIDs = append(config.AdminUserIDs, id)
+			}
+		}
+	}
+
+	if emails := os.Getenv("ADMIN_EMAILS"); emails != "" {
+		for _, email := range strings.Split(emails, ",") {
+			if email = strings.TrimSpace(email); email != "" {
+				config.AdminEmails = append(config.AdminEmails, strings.ToLower(email))
+			}
+		}
+	}
+
+	if minutesStr := os.Getenv("UNDELETE_WINDOW_MINUTES"); minutesStr != "" {
+		if minutes, err := strconv.Atoi(minutesStr); err == nil && minutes >= 0 {
+			config.UndeleteWindow = time.Duration(minutes) * time.Minute
+		}
+	}
+
+	if daysStr := os.Getenv("DUPLICATE_LINK_WINDOW_DAYS"); daysStr != "" {
+		if days, err := strconv.Atoi(daysStr); err == nil && days >= 0 {
+			config.DuplicateLinkWindow = time.Duration(days) * 24 * time.Hour
+		}
+	}
+
+	if daysStr := os.Getenv("POST_ARCHIVE_DAYS"); daysStr != "" {
+		if days, err := strconv.Atoi(daysStr); err == nil && days >= 0 {
+			config.ArchiveAfter = time.Duration(days) * 24 * time.Hour
+		}
+	}
+
+	// POST_RATE_LIMITS replaces the defaults, e.g. "1/30s,10/1h"; "off" disables them
+	if limits := os.Getenv("POST_RATE_LIMITS"); limits == "off" {
+		config.PostRateLimits = nil
+	} else if limits != "" {
+		config.PostRateLimits = nil
+		for _, entry := range strings.Split(limits, ",") {
+			countStr, windowStr, ok := strings.Cut(strings.TrimSpace(entry), "/")
+			count, countErr := strconv.Atoi(countStr)
+			window, windowErr := time.ParseDuration(windowStr)
+			if !ok || countErr != nil || windowErr != nil || count <= 0 || window <= 0 {
+				return nil, fmt.Errorf("POST_RATE_LIMITS entries must look like count/duration, got %q", entry)
+			}
+			config.PostRateLimits = append(config.PostRateLimits, RateLimit{Count: count, Window: window})
+		}
+	}
+
+	if factorStr := os.Getenv("MODERATOR_POST_RATE_FACTOR"); factorStr != "" {
+		if factor, err := strconv.Atoi(factorStr); err == nil && factor >= 1 {
+			config.ModeratorPostRateFactor = factor
+		}
+	}
+
+	if hoursStr := os.Getenv("IDEMPOTENCY_KEY_TTL_HOURS"); hoursStr != "" {
+		if hours, err := strconv.Atoi(hoursStr); err == nil && hours > 0 {
+			config.IdempotencyKeyTTL = time.Duration(hours) * time.Hour
+		}
+	}
+
+	if depthStr := os.Getenv("MAX_COMMENT_DEPTH"); depthStr != "" {
+		if depth, err := strconv.Atoi(depthStr); err == nil && depth >= 0 {
+			config.MaxCommentDepth = depth
+		}
+	}
+
+	if lengthStr := os.Getenv("MAX_POST_TITLE_LENGTH"); lengthStr != "" {
+		if length, err := strconv.Atoi(lengthStr); err == nil && length > 0 {
+			config.MaxPostTitleLength = length
+		}
+	}
+
+	if lengthStr := os.Getenv("MAX_POST_CONTENT_LENGTH"); lengthStr != "" {
+		if length, err := strconv.Atoi(lengthStr); err == nil && length > 0 {
+			config.MaxPostContentLength = length
+		}
+	}
+
+	if thresholdStr := os.Getenv("COMMENT_COLLAPSE_THRESHOLD"); thresholdStr != "" {
+		if threshold, err := strconv.Atoi(thresholdStr); err == nil {
+			config.CommentCollapseThreshold = threshold
+		}
+	}
+
+	if votesStr := os.Getenv("MIN_CONTROVERSIAL_VOTES"); votesStr != "" {
+		if votes, err := strconv.Atoi(votesStr); err == nil && votes >= 0 {
+			config.MinControversialVotes = votes
+		}
+	}
+
+	// MEDIA_EXTENSIONS replaces the defaults, e.g. "jpg=image,png=image,mp4=video"
+	if extensions := os.Getenv("MEDIA_EXTENSIONS"); extensions != "" {
+		config.MediaExtensions = make(map[string]string)
+		for _, entry := range strings.Split(extensions, ",") {
+			ext, mediaType, ok := strings.Cut(strings.TrimSpace(entry), "=")
+			ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+			if !ok || ext == "" || strings.TrimSpace(mediaType) == "" {
+				return nil, fmt.Errorf("MEDIA_EXTENSIONS entries must look like ext=type, got %q", entry)
+			}
+			config.MediaExtensions[ext] = strings.TrimSpace(mediaType)
+		}
+	}
+
+	if dir := os.Getenv("MEDIA_DIR"); dir != "" {
+		config.MediaDir = dir
+	}
+
+	if mediaURL := os.Getenv("MEDIA_PUBLIC_URL"); mediaURL != "" {
+		config.MediaPublicURL = strings.TrimRight(mediaURL, "/")
+	}
+
+	if sizeStr := os.Getenv("MEDIA_MAX_UPLOAD_MB"); sizeStr != "" {
+		if size, err := strconv.Atoi(sizeStr); err == nil && size > 0 {
+			config.MediaMaxUploadBytes = int64(size) << 20
+		}
+	}
+
+	// MEDIA_UPLOAD_TYPES replaces the defaults, e.g. "image/png=png,video/mp4=mp4"
+	if types := os.Getenv("MEDIA_UPLOAD_TYPES"); types != "" {
+		config.MediaUploadTypes = make(map[string]string)
+		for _, entry := range strings.Split(types, ",") {
+			contentType, ext, ok := strings.Cut(strings.TrimSpace(entry), "=")
+			contentType = strings.ToLower(strings.TrimSpace(contentType))
+			ext = strings.TrimPrefix(strings.TrimSpace(ext), ".")
+			if !ok || contentType == "" || ext == "" {
+				return nil, fmt.Errorf("MEDIA_UPLOAD_TYPES entries must look like type=ext, got %q", entry)
+			}
+			config.MediaUploadTypes[contentType] = ext
+		}
+	}
+
+	if daysStr := os.Getenv("SUBREDDIT_MIN_ACCOUNT_AGE_DAYS"); daysStr != "" {
+		if days, err := strconv.Atoi(daysStr); err == nil && days >= 0 {
+			config.SubredditCreation.MinAccountAge = time.Duration(days) * 24 * time.Hour
+		}
+	}
+
+	if karmaStr := os.Getenv("SUBREDDIT_MIN_KARMA"); karmaStr != "" {
+		if karma, err := strconv.Atoi(karmaStr); err == nil {
+			config.SubredditCreation.MinKarma = karma
+		}
+	}
+
+	if countStr := os.Getenv("SUBREDDIT_MAX_PER_DAY"); countStr != "" {
+		if count, err := strconv.Atoi(countStr); err == nil && count >= 0 {
+			config.SubredditCreation.MaxPerDay = count
+		}
+	}
+
+	if required := os.Getenv("SUBREDDIT_REQUIRE_VERIFIED_EMAIL"); required != "" {
+		config.SubredditCreation.RequireVerifiedEmail = required == "true"
+	}
+
+	switch action := os.Getenv("DUPLICATE_ACCOUNT_ACTION"); action {
+	case DuplicateAccountReject, DuplicateAccountFlag:
+		config.DuplicateAccountAction = action
+	case "":
+	default:
+		return nil, fmt.Errorf("DUPLICATE_ACCOUNT_ACTION must be %q or %q", DuplicateAccountReject, DuplicateAccountFlag)
+	}
+
+	if costStr := os.Getenv("BCRYPT_COST"); costStr != "" {
+		cost, err := strconv.Atoi(costStr)
+		if err != nil || cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("BCRYPT_COST must be a number from %d to %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+		config.PasswordHashCost = cost
+	}
+
+	if lengthStr := os.Getenv("MIN_PASSWORD_LENGTH"); lengthStr != "" {
+		length, err := strconv.Atoi(lengthStr)
+		if err != nil || length < 1 || length > 72 {
+			return nil, fmt.Errorf("MIN_PASSWORD_LENGTH must be a number from 1 to 72")
+		}
+		config.MinPasswordLength = length
+	}
+
+	if hoursStr := os.Getenv("JWT_TTL_HOURS"); hoursStr != "" {
+		if hours, err := strconv.Atoi(hoursStr); err == nil && hours > 0 {
+			config.TokenTTL = time.Duration(hours) * time.Hour
+		}
+	}
+
+	if daysStr := os.Getenv("REFRESH_TOKEN_TTL_DAYS"); daysStr != "" {
+		if days, err := strconv.Atoi(daysStr); err == nil && days > 0 {
+			config.RefreshTokenTTL = time.Duration(days) * 24 * time.Hour
+		}
+	}
+
+	if minutesStr := os.Getenv("PASSWORD_RESET_TTL_MINUTES"); minutesStr != "" {
+		if minutes, err := strconv.Atoi(minutesStr); err == nil && minutes > 0 {
+			config.PasswordResetTTL = time.Duration(minutes) * time.Minute
+		}
+	}
+
+	if minutesStr := os.Getenv("PASSWORD_RESET_RESEND_MINUTES"); minutesStr != "" {
+		if minutes, err := strconv.Atoi(minutesStr); err == nil && minutes >= 0 {
+			config.PasswordResetResendInterval = time.Duration(minutes) * time.Minute
+		}
+	}
+
+	if limitStr := os.Getenv("PASSWORD_RESET_IP_LIMIT"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil && limit >= 0 {
+			config.PasswordResetIPLimit = limit
+		}
+	}
+
+	if hoursStr := os.Getenv("EMAIL_VERIFICATION_TTL_HOURS"); hoursStr != "" {
+		if hours, err := strconv.Atoi(hoursStr); err == nil && hours > 0 {
+			config.EmailVerificationTTL = time.Duration(hours) * time.Hour
+		}
+	}
+
+	if minutesStr := os.Getenv("EMAIL_VERIFICATION_RESEND_MINUTES"); minutesStr != "" {
+		if minutes, err := strconv.Atoi(minutesStr); err == nil && minutes >= 0 {
+			config.EmailVerificationResendInterval = time.Duration(minutes) * time.Minute
+		}
+	}
+
+	if minutesStr := os.Getenv("LAST_ACTIVE_WRITE_INTERVAL_MINUTES"); minutesStr != "" {
+		if minutes, err := strconv.Atoi(minutesStr); err == nil && minutes > 0 {
+			config.LastActiveWriteInterval = time.Duration(minutes) * time.Minute
+		}
+	}
+
+	if minutesStr := os.Getenv("ACTIVE_RECENTLY_MINUTES"); minutesStr != "" {
+		if minutes, err := strconv.Atoi(minutesStr); err == nil && minutes > 0 {
+			config.ActiveRecentlyWindow = time.Duration(minutes) * time.Minute
+		}
+	}
+
+	config.JWTSecret = os.Getenv("JWT_SECRET")
+	if config.JWTSecret == "" {
+		if !config.Debug {
+			return nil, fmt.Errorf("JWT_SECRET environment variable is required unless DEBUG=true")
+		}
+		log.Printf("Warning: JWT_SECRET is not set; signing tokens with the public development secret")
+		config.JWTSecret = devJWTSecret
+	}
+
 	return config, nil
 }
@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Activity item types
+const (
+	ActivityTypePost    = "post"
+	ActivityTypeComment = "comment"
+)
+
+// ActivityItem is a single entry in a user's combined post/comment timeline
+type ActivityItem struct {
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Post      *models.Post    `json:"post,omitempty"`
+	Comment   *models.Comment `json:"comment,omitempty"`
+	PostTitle string          `json:"postTitle,omitempty"` // Title of the commented post, for comments
+}
+
+// ActivityCursor is the composite cursor for the activity timeline. It records the
+// position in each underlying listing and whether that listing has run out.
+type ActivityCursor struct {
+	PostCursor    string `json:"p,omitempty"`
+	CommentCursor string `json:"c,omitempty"`
+	PostsDone     bool   `json:"pd,omitempty"`
+	CommentsDone  bool   `json:"cd,omitempty"`
+}
+
+// GetUserActivity merges a user's posts and comments newest-first. It fetches up to
+// limit items from each side, merges them and only advances each side's sub-cursor
+// past the items actually returned, so nothing is skipped between pages.
+func (m *MongoDB) GetUserActivity(ctx context.Context, userID uuid.UUID, limit int, cursor string) ([]*ActivityItem, string, error) {
+	if limit <= 0 {
+		return nil, "", utils.NewAppError(utils.ErrInvalidInput, "Limit must be positive", nil)
+	}
+
+	var state ActivityCursor
+	if cursor != "" {
+		if err := DecodeCursor(cursor, &state); err != nil {
+			return nil, "", err
+		}
+	}
+
+	posts := []*models.Post{}
+	if !state.PostsDone {
+		var err error
+		posts, _, err = m.GetPostsByAuthor(ctx, userID, false, limit, state.PostCursor)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	comments := []*models.Comment{}
+	if !state.CommentsDone {
+		var err error
+		comments, _, err = m.GetUserComments(ctx, userID, limit, state.CommentCursor, false)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	// Merge the two newest-first lists
+	items := make([]*ActivityItem, 0, limit)
+	pi, ci := 0, 0
+	for len(items) < limit && (pi < len(posts) || ci < len(comments)) {
+		takePost := ci >= len(comments) ||
+			(pi < len(posts) && !posts[pi].CreatedAt.Before(comments[ci].CreatedAt))
+
+		if takePost {
+			items = append(items, &ActivityItem{
+				Type:      ActivityTypePost,
+				CreatedAt: posts[pi].CreatedAt,
+				Post:      posts[pi],
+			})
+			pi++
+		} else {
+			items = append(items, &ActivityItem{
+				Type:      ActivityTypeComment,
+				CreatedAt: comments[ci].CreatedAt,
+				Comment:   comments[ci],
+			})
+			ci++
+		}
+	}
+
+	if err := m.attachActivityPostTitles(ctx, items); err != nil {
+		return nil, "", err
+	}
+
+	// Advance each sub-cursor past the last item consumed from that side
+	next := state
+	if pi > 0 {
+		last := posts[pi-1]
+		next.PostCursor = EncodeCursor(PostCursor{CreatedAt: last.CreatedAt, ID: last.ID.String()})
+	}
+	if ci > 0 {
+		last := comments[ci-1]
+		next.CommentCursor = EncodeCursor(CommentCursor{CreatedAt: last.CreatedAt, ID: last.ID.String()})
+	}
+	// A side is exhausted once a short page was fetched and fully consumed
+	if !state.PostsDone && len(posts) < limit && pi == len(posts) {
+		next.PostsDone = true
+	}
+	if !state.CommentsDone && len(comments) < limit && ci == len(comments) {
+		next.CommentsDone = true
+	}
+
+	if next.PostsDone && next.CommentsDone {
+		return items, "", nil
+	}
+	return items, EncodeCursor(next), nil
+}
+
+// attachActivityPostTitles fills in the post title for comment items with a single lookup
+func (m *MongoDB) attachActivityPostTitles(ctx context.Context, items []*ActivityItem) error {
+	postIDs := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, item := range items {
+		if item.Comment == nil {
+			continue
+		}
+		id := item.Comment.PostID.String()
+		if !seen[id] {
+			seen[id] = true
+			postIDs = append(postIDs, id)
+		}
+	}
+
+	titles, err := m.GetPostTitles(ctx, postIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if item.Comment != nil {
+			item.PostTitle = titles[item.Comment.PostID.String()]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"gator-swamp/internal/utils"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AdminAuditEntry records an action an administrator took
+type AdminAuditEntry struct {
+	ID         string    `bson:"_id" json:"id"`
+	ActorID    string    `bson:"actorId" json:"actorId"`
+	Action     string    `bson:"action" json:"action"`
+	TargetType string    `bson:"targetType" json:"targetType"` // "post", "subreddit" or "user"
+	TargetID   string    `bson:"targetId" json:"targetId"`
+	Reason     string    `bson:"reason,omitempty" json:"reason,omitempty"`
+	Details    bson.M    `bson:"details,omitempty" json:"details,omitempty"`
+	CreatedAt  time.Time `bson:"createdAt" json:"createdAt"`
+}
+
+// IsUserAdmin reports whether a user is a site administrator. Unknown users aren't.
+func (m *MongoDB) IsUserAdmin(ctx context.Context, userID uuid.UUID) (bool, error) {
+	var doc struct {
+		IsAdmin bool `bson:"isAdmin"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"isAdmin": 1})
+	err := m.Users.FindOne(ctx, bson.M{"_id": userID.String()}, opts).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check admin status: %v", err)
+	}
+	return doc.IsAdmin, nil
+}
+
+// SetUserAdmin grants or revokes a user's administrator role
+func (m *MongoDB) SetUserAdmin(ctx context.Context, userID uuid.UUID, isAdmin bool) error {
+	update := bson.M{"$set": bson.M{"isAdmin": true}}
+	if !isAdmin {
+		update = bson.M{"$unset": bson.M{"isAdmin": ""}}
+	}
+
+	result, err := m.Users.UpdateOne(ctx, bson.M{"_id": userID.String()}, update)
+	if err != nil {
+		return fmt.Errorf("failed to update admin status: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return utils.NewAppError(utils.ErrUserNotFound, "User not found", nil)
+	}
+	return nil
+}
+
+// SetUserBanned bans a user site-wide or lifts their ban. Registrations and email
+// changes to addresses that normalize to a banned account's are treated as ban evasion.
+func (m *MongoDB) SetUserBanned(ctx context.Context, userID uuid.UUID, banned bool) error {
+	result, err := m.Users.UpdateOne(ctx, bson.M{"_id": userID.String()},
+		bson.M{"$set": bson.M{"isBanned": banned}})
+	if err != nil {
+		return fmt.Errorf("failed to update ban status: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return utils.NewAppError(utils.ErrUserNotFound, "User not found", nil)
+	}
+	return nil
+}
+
+// GrantBootstrapAdmins makes the accounts with the given user IDs, or with the given
+// emails matched regardless of case, administrators. Emails only count once verified, so
+// nobody gets the role by registering or changing to a listed address they don't own.
+// It returns how many accounts were newly granted.
+func (m *MongoDB) GrantBootstrapAdmins(ctx context.Context, emails, userIDs []string) (int64, error) {
+	if len(emails) == 0 && len(userIDs) == 0 {
+		return 0, nil
+	}
+
+	lowered := make([]string, len(emails))
+	for i, email := range emails {
+		lowered[i] = strings.ToLower(email)
+	}
+	filter := bson.M{
+		"$or": []bson.M{
+			{"emailLower": bson.M{"$in": lowered}, "emailVerified": true},
+			{"_id": bson.M{"$in": userIDs}},
+		},
+		"isAdmin": bson.M{"$ne": true},
+	}
+
+	result, err := m.Users.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"isAdmin": true}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to grant bootstrap admins: %v", err)
+	}
+	return result.ModifiedCount, nil
+}
+
+// RecordAdminAction appends an entry to the admin audit log
+func (m *MongoDB) RecordAdminAction(ctx context.Context, entry *AdminAuditEntry) error {
+	if entry.ID == "" {
+		entry.ID = uuid.New().String()
+	}
+	if _, err := m.AdminAudit.InsertOne(ctx, entry); err != nil {
+		return fmt.Errorf("failed to record admin action: %v", err)
+	}
+	return nil
+}
+
+// EnsureAdminAuditIndexes creates indexes for looking up the audit log by the admin
+// who acted or by what they acted on
+func (m *MongoDB) EnsureAdminAuditIndexes(ctx context.Context) error {
+	_, err := m.AdminAudit.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "actorId", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{Keys: bson.D{{Key: "targetId", Value: 1}, {Key: "createdAt", Value: -1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create admin audit indexes: %v", err)
+	}
+	return nil
+}
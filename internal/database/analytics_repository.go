@@ -0,0 +1,180 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// analyticsEventRetention is how long raw events are kept before the TTL index removes them.
+// It only needs to outlive the daily rollup job with some margin for re-runs.
+const analyticsEventRetention = 30 * 24 * time.Hour
+
+// AnalyticsDateLayout is the format of the date component of daily rollups
+const AnalyticsDateLayout = "2006-01-02"
+
+// AnalyticsEventDocument represents a single raw analytics event in MongoDB
+type AnalyticsEventDocument struct {
+	Type        string    `bson:"type"`
+	EntityID    string    `bson:"entityId"`
+	UserID      string    `bson:"userId,omitempty"`
+	SubredditID string    `bson:"subredditId,omitempty"`
+	CreatedAt   time.Time `bson:"createdAt"`
+}
+
+// AnalyticsDailyDocument is the rollup of one event type for one entity on one UTC day
+type AnalyticsDailyDocument struct {
+	ID          string    `bson:"_id"` // date|type|entity
+	Date        string    `bson:"date"`
+	Type        string    `bson:"type"`
+	EntityID    string    `bson:"entityId"`
+	SubredditID string    `bson:"subredditId,omitempty"`
+	Count       int       `bson:"count"`
+	UpdatedAt   time.Time `bson:"updatedAt"`
+}
+
+// AnalyticsPoint is the total for a metric on a single day
+type AnalyticsPoint struct {
+	Date  string `json:"date" bson:"_id"`
+	Count int    `json:"count" bson:"count"`
+}
+
+// InsertAnalyticsEvents bulk-inserts raw events. Inserts are unordered so one bad
+// document doesn't drop the rest of the batch.
+func (m *MongoDB) InsertAnalyticsEvents(ctx context.Context, events []AnalyticsEventDocument) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, len(events))
+	for i := range events {
+		docs[i] = events[i]
+	}
+
+	_, err := m.AnalyticsEvents.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	if err != nil {
+		return fmt.Errorf("failed to insert analytics events: %v", err)
+	}
+	return nil
+}
+
+// RollupAnalyticsDay aggregates the raw events of one UTC day into analytics_daily.
+// Counts are recomputed from scratch and written with replace-upserts, so re-running
+// the rollup for the same day is idempotent.
+func (m *MongoDB) RollupAnalyticsDay(ctx context.Context, day time.Time) (int, error) {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+	date := start.Format(AnalyticsDateLayout)
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"createdAt": bson.M{"$gte": start, "$lt": end}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":         bson.M{"type": "$type", "entityId": "$entityId"},
+			"subredditId": bson.M{"$max": "$subredditId"},
+			"count":       bson.M{"$sum": 1},
+		}}},
+	}
+
+	cursor, err := m.AnalyticsEvents.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, fmt.Errorf("failed to aggregate analytics events: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	now := time.Now()
+	writes := make([]mongo.WriteModel, 0)
+	for cursor.Next(ctx) {
+		var row struct {
+			ID struct {
+				Type     string `bson:"type"`
+				EntityID string `bson:"entityId"`
+			} `bson:"_id"`
+			SubredditID string `bson:"subredditId"`
+			Count       int    `bson:"count"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return 0, fmt.Errorf("failed to decode analytics rollup: %v", err)
+		}
+
+		doc := AnalyticsDailyDocument{
+			ID:          date + "|" + row.ID.Type + "|" + row.ID.EntityID,
+			Date:        date,
+			Type:        row.ID.Type,
+			EntityID:    row.ID.EntityID,
+			SubredditID: row.SubredditID,
+			Count:       row.Count,
+			UpdatedAt:   now,
+		}
+		writes = append(writes, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"_id": doc.ID}).
+			SetReplacement(doc).
+			SetUpsert(true))
+	}
+	if err := cursor.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read analytics rollup: %v", err)
+	}
+
+	if len(writes) == 0 {
+		return 0, nil
+	}
+
+	if _, err := m.AnalyticsDaily.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+		return 0, fmt.Errorf("failed to write analytics rollup: %v", err)
+	}
+	return len(writes), nil
+}
+
+// GetAnalyticsSeries returns per-day totals for a metric between two dates (inclusive),
+// optionally restricted to one subreddit
+func (m *MongoDB) GetAnalyticsSeries(ctx context.Context, metric, from, to, subredditID string) ([]AnalyticsPoint, error) {
+	match := bson.M{
+		"type": metric,
+		"date": bson.M{"$gte": from, "$lte": to},
+	}
+	if subredditID != "" {
+		match["subredditId"] = subredditID
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{"_id": "$date", "count": bson.M{"$sum": "$count"}}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+
+	cursor, err := m.AnalyticsDaily.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query analytics: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	points := make([]AnalyticsPoint, 0)
+	if err := cursor.All(ctx, &points); err != nil {
+		return nil, fmt.Errorf("failed to decode analytics: %v", err)
+	}
+	return points, nil
+}
+
+// EnsureAnalyticsIndexes creates the TTL index on raw events and the query index on rollups
+func (m *MongoDB) EnsureAnalyticsIndexes(ctx context.Context) error {
+	_, err := m.AnalyticsEvents.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "createdAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(analyticsEventRetention.Seconds())),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create analytics event indexes: %v", err)
+	}
+
+	_, err = m.AnalyticsDaily.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "type", Value: 1}, {Key: "date", Value: 1}, {Key: "subredditId", Value: 1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create analytics rollup indexes: %v", err)
+	}
+	return nil
+}
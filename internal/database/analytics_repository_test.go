@@ -0,0 +1,58 @@
+package database_test
+
+import (
+	"context"
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/database/dbtest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Rollups are recomputed from the raw events, so running one again, as the hourly job
+// does for the current day, neither double counts nor duplicates rows
+func TestRollupAnalyticsDayIsIdempotent(t *testing.T) {
+	mongodb := dbtest.New(t)
+	ctx := context.Background()
+
+	day := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	postID, subredditID := uuid.New().String(), uuid.New().String()
+	var events []database.AnalyticsEventDocument
+	for i := 0; i < 3; i++ {
+		events = append(events, database.AnalyticsEventDocument{
+			Type: "view", EntityID: postID, SubredditID: subredditID, CreatedAt: day.Add(time.Duration(i) * time.Hour),
+		})
+	}
+	events = append(events,
+		database.AnalyticsEventDocument{Type: "signup", EntityID: uuid.New().String(), CreatedAt: day.Add(23 * time.Hour)},
+		// The next day's events are left for its own rollup
+		database.AnalyticsEventDocument{Type: "view", EntityID: postID, SubredditID: subredditID, CreatedAt: day.Add(24 * time.Hour)},
+	)
+	if err := mongodb.InsertAnalyticsEvents(ctx, events); err != nil {
+		t.Fatalf("InsertAnalyticsEvents: %v", err)
+	}
+
+	for run := 1; run <= 2; run++ {
+		rows, err := mongodb.RollupAnalyticsDay(ctx, day.Add(12*time.Hour))
+		if err != nil {
+			t.Fatalf("RollupAnalyticsDay run %d: %v", run, err)
+		}
+		if rows != 2 {
+			t.Fatalf("RollupAnalyticsDay run %d wrote %d rows, want 2", run, rows)
+		}
+
+		stored, err := mongodb.AnalyticsDaily.CountDocuments(ctx, bson.M{})
+		if err != nil || stored != 2 {
+			t.Fatalf("%d rollup rows stored after run %d (%v), want 2", stored, run, err)
+		}
+		var views database.AnalyticsDailyDocument
+		if err := mongodb.AnalyticsDaily.FindOne(ctx, bson.M{"_id": "2024-03-10|view|" + postID}).Decode(&views); err != nil {
+			t.Fatalf("finding view rollup after run %d: %v", run, err)
+		}
+		if views.Count != 3 || views.SubredditID != subredditID {
+			t.Fatalf("view rollup after run %d = %d in %q, want 3 in %q", run, views.Count, views.SubredditID, subredditID)
+		}
+	}
+}
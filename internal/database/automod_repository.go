@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"gator-swamp/internal/i18n"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// AutomodRuleDB represents an automod rule as stored in the subreddit document, and in
+// the posts and comments that matched it
+type AutomodRuleDB struct {
+	ID      string `bson:"id"`
+	Pattern string `bson:"pattern,omitempty"`
+	Domain  string `bson:"domain,omitempty"`
+	Action  string `bson:"action"`
+}
+
+// automodRuleToDB converts an automod rule for storage, returning nil for a missing one
+func automodRuleToDB(rule *models.AutomodRule) *AutomodRuleDB {
+	if rule == nil {
+		return nil
+	}
+	return &AutomodRuleDB{ID: rule.ID.String(), Pattern: rule.Pattern, Domain: rule.Domain, Action: rule.Action}
+}
+
+// automodRuleFromDB converts a stored automod rule, returning nil for a missing one
+func automodRuleFromDB(doc *AutomodRuleDB) *models.AutomodRule {
+	if doc == nil {
+		return nil
+	}
+	id, _ := uuid.Parse(doc.ID)
+	return &models.AutomodRule{ID: id, Pattern: doc.Pattern, Domain: doc.Domain, Action: doc.Action}
+}
+
+// automodRulesFromDB converts a subreddit's stored automod rules, skipping any with a
+// malformed ID
+func automodRulesFromDB(docs []AutomodRuleDB) []models.AutomodRule {
+	rules := make([]models.AutomodRule, 0, len(docs))
+	for i := range docs {
+		if _, err := uuid.Parse(docs[i].ID); err != nil {
+			continue
+		}
+		rules = append(rules, *automodRuleFromDB(&docs[i]))
+	}
+	return rules
+}
+
+// SetSubredditAutomod replaces a subreddit's automod rules
+func (m *MongoDB) SetSubredditAutomod(ctx context.Context, subredditID uuid.UUID, rules []models.AutomodRule) error {
+	docs := make([]AutomodRuleDB, len(rules))
+	for i := range rules {
+		docs[i] = *automodRuleToDB(&rules[i])
+	}
+
+	result, err := m.Subreddits.UpdateOne(ctx,
+		bson.M{"_id": subredditID.String()},
+		bson.M{"$set": bson.M{"automod": docs}})
+	if err != nil {
+		return fmt.Errorf("failed to update automod rules: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrSubredditNotFound, nil, nil)
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gator-swamp/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BlockedPlaceholder replaces the content of comments by users the viewer blocked
+const BlockedPlaceholder = "[blocked]"
+
+// BlockDocument records that one user blocked another. Blocks are stored one way; it
+// is up to each read path whether the blocked user also stops seeing the blocker.
+type BlockDocument struct {
+	ID        string    `bson:"_id"`
+	BlockerID string    `bson:"blockerId"`
+	BlockedID string    `bson:"blockedId"`
+	CreatedAt time.Time `bson:"createdAt"`
+}
+
+// BlockCursor marks a position in a user's blocked users, most recently blocked first
+type BlockCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+}
+
+// BlockUser makes blockerID block blockedID. Blocking again keeps the original time.
+func (m *MongoDB) BlockUser(ctx context.Context, blockerID, blockedID uuid.UUID, at time.Time) error {
+	filter := bson.M{"blockerId": blockerID.String(), "blockedId": blockedID.String()}
+	update := bson.M{"$setOnInsert": bson.M{"_id": uuid.New().String(), "createdAt": at}}
+
+	_, err := m.Blocks.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// A concurrent block of the same user won the race
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to block user: %v", err)
+	}
+	return nil
+}
+
+// UnblockUser lifts a block. Unblocking a user who isn't blocked is a no-op.
+func (m *MongoDB) UnblockUser(ctx context.Context, blockerID, blockedID uuid.UUID) error {
+	filter := bson.M{"blockerId": blockerID.String(), "blockedId": blockedID.String()}
+	if _, err := m.Blocks.DeleteOne(ctx, filter); err != nil {
+		return fmt.Errorf("failed to unblock user: %v", err)
+	}
+	return nil
+}
+
+// IsBlocked reports whether blockerID has blocked blockedID
+func (m *MongoDB) IsBlocked(ctx context.Context, blockerID, blockedID uuid.UUID) (bool, error) {
+	filter := bson.M{"blockerId": blockerID.String(), "blockedId": blockedID.String()}
+	count, err := m.Blocks.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	if err != nil {
+		return false, fmt.Errorf("failed to check block: %v", err)
+	}
+	return count > 0, nil
+}
+
+// IsBlockedEitherWay reports whether either user has blocked the other
+func (m *MongoDB) IsBlockedEitherWay(ctx context.Context, userID1, userID2 uuid.UUID) (bool, error) {
+	filter := bson.M{"$or": []bson.M{
+		{"blockerId": userID1.String(), "blockedId": userID2.String()},
+		{"blockerId": userID2.String(), "blockedId": userID1.String()},
+	}}
+	count, err := m.Blocks.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	if err != nil {
+		return false, fmt.Errorf("failed to check block: %v", err)
+	}
+	return count > 0, nil
+}
+
+// getBlockedUserIDs returns the IDs of every user a user has blocked
+func (m *MongoDB) getBlockedUserIDs(ctx context.Context, blockerID string) ([]string, error) {
+	opts := options.Find().SetProjection(bson.M{"blockedId": 1})
+	cursor, err := m.Blocks.Find(ctx, bson.M{"blockerId": blockerID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blocked users: %v", err)
+	}
+	var docs []BlockDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode blocked users: %v", err)
+	}
+
+	ids := make([]string, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.BlockedID
+	}
+	return ids, nil
+}
+
+// GetBlockedUserIDs returns the set of users a user has blocked
+func (m *MongoDB) GetBlockedUserIDs(ctx context.Context, blockerID uuid.UUID) (map[uuid.UUID]bool, error) {
+	ids, err := m.getBlockedUserIDs(ctx, blockerID.String())
+	if err != nil {
+		return nil, err
+	}
+	return parseUserIDSet(ids), nil
+}
+
+// GetBlockRelatedUserIDs returns the set of users a user has blocked or been blocked by
+func (m *MongoDB) GetBlockRelatedUserIDs(ctx context.Context, userID uuid.UUID) (map[uuid.UUID]bool, error) {
+	filter := bson.M{"$or": []bson.M{{"blockerId": userID.String()}, {"blockedId": userID.String()}}}
+	cursor, err := m.Blocks.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blocks: %v", err)
+	}
+	var docs []BlockDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode blocks: %v", err)
+	}
+
+	ids := make([]string, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.BlockedID
+		if doc.BlockedID == userID.String() {
+			ids[i] = doc.BlockerID
+		}
+	}
+	return parseUserIDSet(ids), nil
+}
+
+func parseUserIDSet(ids []string) map[uuid.UUID]bool {
+	set := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		if userID, err := uuid.Parse(id); err == nil {
+			set[userID] = true
+		}
+	}
+	return set
+}
+
+// GetBlockedUsers returns a page of the users a user has blocked, most recently blocked
+// first. Accounts that no longer exist are skipped, which can leave the page short.
+func (m *MongoDB) GetBlockedUsers(ctx context.Context, blockerID uuid.UUID, limit int, cursor string) ([]*models.BlockedUser, string, error) {
+	filter := bson.M{"blockerId": blockerID.String()}
+	if cursor != "" {
+		var after BlockCursor
+		if err := DecodeCursor(cursor, &after); err != nil {
+			return nil, "", err
+		}
+		filter = bson.M{"$and": []bson.M{filter, {"$or": []bson.M{
+			{"createdAt": bson.M{"$lt": after.CreatedAt}},
+			{"createdAt": after.CreatedAt, "_id": bson.M{"$lt": after.ID}},
+		}}}}
+	}
+
+	// Fetch one extra row to learn whether another page exists
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit + 1))
+
+	dbCursor, err := m.Blocks.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get blocked users: %v", err)
+	}
+	var docs []BlockDocument
+	if err := dbCursor.All(ctx, &docs); err != nil {
+		return nil, "", fmt.Errorf("failed to decode blocked users: %v", err)
+	}
+
+	nextCursor := ""
+	if len(docs) > limit {
+		docs = docs[:limit]
+		last := docs[len(docs)-1]
+		nextCursor = EncodeCursor(BlockCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	blockedIDs := make([]string, len(docs))
+	for i, doc := range docs {
+		blockedIDs[i] = doc.BlockedID
+	}
+	users, err := m.getLiveUsers(ctx, blockedIDs)
+	if err != nil {
+		return nil, "", err
+	}
+
+	blocked := make([]*models.BlockedUser, 0, len(docs))
+	for _, doc := range docs {
+		user, ok := users[doc.BlockedID]
+		if !ok {
+			continue
+		}
+		blocked = append(blocked, &models.BlockedUser{
+			UserID:    user.ID,
+			Username:  user.Username,
+			BlockedAt: doc.CreatedAt,
+		})
+	}
+
+	return blocked, nextCursor, nil
+}
+
+// EnsureBlockIndexes creates required indexes for the blocks collection. The unique
+// index makes blocking the same user twice a no-op.
+func (m *MongoDB) EnsureBlockIndexes(ctx context.Context) error {
+	var errs []error
+	_, err := m.Blocks.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "blockerId", Value: 1}, {Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			// Finding who blocked a user, for checks in both directions
+			Keys: bson.D{{Key: "blockedId", Value: 1}},
+		},
+	})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to create block indexes: %v", err))
+	}
+	err = createUniqueIndex(ctx, m.Blocks, "block", mongo.IndexModel{
+		Keys:    bson.D{{Key: "blockerId", Value: 1}, {Key: "blockedId", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
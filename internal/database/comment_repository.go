@@ -3,9 +3,11 @@ package database
 import (
 	"context"
 	"fmt"
+	"gator-swamp/internal/i18n"
 	"gator-swamp/internal/models"
 	"gator-swamp/internal/utils"
 	"log"
+	"regexp"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,21 +18,38 @@ import (
 
 // CommentDocument represents comment data in MongoDB
 type CommentDocument struct {
-	ID          string    `bson:"_id"`
-	Content     string    `bson:"content"`
-	AuthorID    string    `bson:"authorId"`
-	PostID      string    `bson:"postId"`
-	SubredditID string    `bson:"subredditId"`
-	ParentID    *string   `bson:"parentId,omitempty"`
-	Children    []string  `bson:"children"`
-	CreatedAt   time.Time `bson:"createdAt"`
-	UpdatedAt   time.Time `bson:"updatedAt"`
-	IsDeleted   bool      `bson:"isDeleted"`
-	Upvotes     int       `bson:"upvotes"`
-	Downvotes   int       `bson:"downvotes"`
-	Karma       int       `bson:"karma"`
+	ID          string     `bson:"_id"`
+	Content     string     `bson:"content"`
+	AuthorID    string     `bson:"authorId"`
+	PostID      string     `bson:"postId"`
+	SubredditID string     `bson:"subredditId"`
+	ParentID    *string    `bson:"parentId,omitempty"`
+	Depth       int        `bson:"depth"`
+	Path        string     `bson:"path,omitempty"` // Materialized path, see CommentPath
+	Children    []string   `bson:"children"`
+	CreatedAt   time.Time  `bson:"createdAt"`
+	UpdatedAt   time.Time  `bson:"updatedAt"`
+	IsDeleted   bool       `bson:"isDeleted"`
+	IsEdited    bool       `bson:"isEdited"`
+	IsStickied  bool       `bson:"isStickied"`
+	Distinguish string     `bson:"distinguishedAs,omitempty"`
+	DeletedAt   *time.Time `bson:"deletedAt,omitempty"`
+	DeletedBy   string     `bson:"deletedBy,omitempty"`
+	Upvotes     int        `bson:"upvotes"`
+	Downvotes   int        `bson:"downvotes"`
+	Karma       int        `bson:"karma"`
+	Controversy float64    `bson:"controversy"`
+	Score       float64    `bson:"score"` // Wilson lower bound, used by the best sort
+
+	// Previous versions, oldest first. Only ever appended to by EditComment.
+	Edits []models.CommentEdit `bson:"edits,omitempty"`
+
+	Automod *AutomodRuleDB `bson:"automod,omitempty"`
 }
 
+// DeletedPlaceholder replaces the content of deleted posts and comments
+const DeletedPlaceholder = "[deleted]"
+
 type VoteDocument struct {
 	ID        string    `bson:"_id"`
 	UserID    string    `bson:"userId"`
@@ -53,10 +72,22 @@ func (m *MongoDB) SaveComment(ctx context.Context, comment *models.Comment) erro
 		CreatedAt:   comment.CreatedAt,
 		UpdatedAt:   comment.UpdatedAt,
 		IsDeleted:   comment.IsDeleted,
+		IsEdited:    comment.IsEdited,
+		IsStickied:  comment.IsStickied,
+		Distinguish: comment.DistinguishedAs,
+		DeletedAt:   comment.DeletedAt,
 		Upvotes:     comment.Upvotes,
 		Downvotes:   comment.Downvotes,
 		Karma:       comment.Karma,
+		Controversy: utils.ControversyScore(comment.Upvotes, comment.Downvotes),
+		Score:       utils.WilsonScore(comment.Upvotes, comment.Downvotes),
+		Depth:       comment.Depth,
+		Path:        comment.Path,
 		SubredditID: comment.SubredditID.String(),
+		Automod:     automodRuleToDB(comment.Automod),
+	}
+	if comment.DeletedBy != nil {
+		doc.DeletedBy = comment.DeletedBy.String()
 	}
 
 	// Convert Children UUIDs to strings
@@ -96,7 +127,7 @@ func (m *MongoDB) GetComment(ctx context.Context, id uuid.UUID) (*models.Comment
 	err := m.Comments.FindOne(ctx, bson.M{"_id": id.String()}).Decode(&doc)
 	if err == mongo.ErrNoDocuments {
 		log.Printf("No comment found with ID: %s", id.String())
-		return nil, utils.NewAppError(utils.ErrNotFound, "Comment not found", err)
+		return nil, utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrCommentNotFound, nil, err)
 	}
 	if err != nil {
 		log.Printf("Error finding comment with ID %s: %v", id.String(), err)
@@ -107,40 +138,559 @@ func (m *MongoDB) GetComment(ctx context.Context, id uuid.UUID) (*models.Comment
 	return convertCommentDocumentToModel(&doc)
 }
 
-// GetPostComments retrieves all comments for a post
-func (m *MongoDB) GetPostComments(ctx context.Context, postID uuid.UUID) ([]*models.Comment, error) {
-	cursor, err := m.Comments.Find(ctx, bson.M{"postId": postID.String()})
+// GetPostComments retrieves a post's comments in the given sort order, starting after the
+// cursor if supplied. A limit of 0 returns every remaining comment.
+func (m *MongoDB) GetPostComments(ctx context.Context, postID uuid.UUID, sort string, limit int, cursor string) ([]*models.Comment, string, error) {
+	return m.findCommentsPage(ctx, bson.M{"postId": postID.String()}, sort, limit, cursor)
+}
+
+// GetTopLevelComments pages through the comments of a post that aren't replies
+func (m *MongoDB) GetTopLevelComments(ctx context.Context, postID uuid.UUID, sort string, limit int, cursor string) ([]*models.Comment, string, error) {
+	return m.findCommentsPage(ctx, bson.M{"postId": postID.String(), "parentId": nil}, sort, limit, cursor)
+}
+
+// SearchPostComments finds a post's comments whose content matches query, best matches
+// first. Matching is Mongo's case-insensitive text search, and deleted comments are excluded.
+func (m *MongoDB) SearchPostComments(ctx context.Context, postID uuid.UUID, query string, limit int) ([]*models.Comment, error) {
+	filter := bson.M{
+		"postId":    postID.String(),
+		"isDeleted": bson.M{"$ne": true},
+		"$text":     bson.M{"$search": query},
+	}
+	// Named textScore so it can't collide with the stored best-sort score
+	relevance := bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetProjection(bson.M{"textScore": relevance}).
+		SetSort(bson.D{{Key: "textScore", Value: relevance}, {Key: "createdAt", Value: 1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := m.Comments.Find(ctx, filter, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get post comments: %v", err)
+		return nil, fmt.Errorf("failed to search comments: %v", err)
 	}
 	defer cursor.Close(ctx)
 
-	var comments []*models.Comment
+	comments := make([]*models.Comment, 0)
 	for cursor.Next(ctx) {
 		var doc CommentDocument
 		if err := cursor.Decode(&doc); err != nil {
 			return nil, fmt.Errorf("failed to decode comment: %v", err)
 		}
-
 		comment, err := convertCommentDocumentToModel(&doc)
 		if err != nil {
 			return nil, err
 		}
 		comments = append(comments, comment)
 	}
+	return comments, cursor.Err()
+}
+
+// GetCommentParentIDs maps each comment of a post to its parent, nil for top-level comments
+func (m *MongoDB) GetCommentParentIDs(ctx context.Context, postID uuid.UUID) (map[uuid.UUID]*uuid.UUID, error) {
+	opts := options.Find().SetProjection(bson.M{"_id": 1, "parentId": 1})
+	cursor, err := m.Comments.Find(ctx, bson.M{"postId": postID.String()}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment parents: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	parents := make(map[uuid.UUID]*uuid.UUID)
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID       string  `bson:"_id"`
+			ParentID *string `bson:"parentId"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode comment: %v", err)
+		}
+		id, err := uuid.Parse(doc.ID)
+		if err != nil {
+			continue
+		}
+		var parentID *uuid.UUID
+		if doc.ParentID != nil {
+			if parsed, err := uuid.Parse(*doc.ParentID); err == nil {
+				parentID = &parsed
+			}
+		}
+		parents[id] = parentID
+	}
+	return parents, cursor.Err()
+}
+
+// CommentPath returns the materialized path of a comment: its parent's path followed
+// by its own ID, or just its ID for a top-level comment. Every comment in a branch
+// shares the branch root's path as a prefix.
+func CommentPath(parentPath string, id uuid.UUID) string {
+	if parentPath == "" {
+		return id.String()
+	}
+	return parentPath + "/" + id.String()
+}
+
+// GetCommentSubtree retrieves a comment together with every reply beneath it, at any
+// depth, oldest first
+func (m *MongoDB) GetCommentSubtree(ctx context.Context, commentID uuid.UUID) ([]*models.Comment, error) {
+	var doc CommentDocument
+	err := m.Comments.FindOne(ctx, bson.M{"_id": commentID.String()},
+		options.FindOne().SetProjection(bson.M{"path": 1})).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrCommentNotFound, nil, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment: %v", err)
+	}
+	if doc.Path == "" {
+		return nil, fmt.Errorf("comment %s has no path", commentID)
+	}
 
+	// An anchored prefix regex is answered from the path index
+	filter := bson.M{"path": bson.M{"$regex": "^" + regexp.QuoteMeta(doc.Path) + "(/|$)"}}
+	comments, _, err := m.findCommentsPage(ctx, filter, SortOld, 0, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment subtree: %v", err)
+	}
 	return comments, nil
 }
 
+// GetThreadRoots retrieves a post's top-level comments, oldest first. Their paths are
+// the prefixes GetCommentSubtree matches on.
+func (m *MongoDB) GetThreadRoots(ctx context.Context, postID uuid.UUID) ([]*models.Comment, error) {
+	filter := bson.M{"postId": postID.String(), "path": bson.M{"$regex": "^[^/]+$"}}
+	roots, _, err := m.findCommentsPage(ctx, filter, SortOld, 0, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get thread roots: %v", err)
+	}
+	return roots, nil
+}
+
+// GetDirectReplies retrieves the immediate replies to a comment in the given sort order
+func (m *MongoDB) GetDirectReplies(ctx context.Context, parentID uuid.UUID, sort string) ([]*models.Comment, error) {
+	replies, _, err := m.findCommentsPage(ctx, bson.M{"parentId": parentID.String()}, sort, 0, "")
+	return replies, err
+}
+
+// GetCommentReplies retrieves every reply beneath the given comments, at any depth
+func (m *MongoDB) GetCommentReplies(ctx context.Context, parentIDs []uuid.UUID) ([]*models.Comment, error) {
+	replies := make([]*models.Comment, 0)
+	level := make([]string, len(parentIDs))
+	for i, id := range parentIDs {
+		level[i] = id.String()
+	}
+
+	// Walk the thread one level at a time using the parentId index
+	for len(level) > 0 {
+		children, _, err := m.findCommentsPage(ctx, bson.M{"parentId": bson.M{"$in": level}}, SortOld, 0, "")
+		if err != nil {
+			return nil, err
+		}
+		level = level[:0]
+		for _, child := range children {
+			replies = append(replies, child)
+			level = append(level, child.ID.String())
+		}
+	}
+
+	return replies, nil
+}
+
+// commentSortKey is one field of a comment sort order
+type commentSortKey struct {
+	field string
+	desc  bool
+}
+
+// commentSortKeys returns the fields a comment listing is ordered by. Every order starts
+// with isStickied so a pinned comment leads, and ends with createdAt and _id so positions
+// are unique and cursors are stable.
+func commentSortKeys(sort string) []commentSortKey {
+	stickied := commentSortKey{"isStickied", true}
+	switch sort {
+	case SortNew:
+		return []commentSortKey{stickied, {"createdAt", true}, {"_id", true}}
+	case SortTop:
+		return []commentSortKey{stickied, {"karma", true}, {"createdAt", true}, {"_id", true}}
+	case SortControversial:
+		return []commentSortKey{stickied, {"controversy", true}, {"createdAt", true}, {"_id", true}}
+	case SortBest:
+		return []commentSortKey{stickied, {"score", true}, {"createdAt", true}, {"_id", true}}
+	default:
+		return []commentSortKey{stickied, {"createdAt", false}, {"_id", false}}
+	}
+}
+
+// cursorValue returns the cursor's value for a sort field
+func (c CommentCursor) cursorValue(field string) interface{} {
+	switch field {
+	case "isStickied":
+		return c.Stickied
+	case "karma":
+		return c.Karma
+	case "controversy":
+		return c.Controversy
+	case "score":
+		return c.Score
+	case "createdAt":
+		return c.CreatedAt
+	default:
+		return c.ID
+	}
+}
+
+// afterCommentCursor matches the comments that come after the cursor in the sort order
+func afterCommentCursor(keys []commentSortKey, cursor CommentCursor) bson.M {
+	clauses := make([]bson.M, 0, len(keys))
+	for i, key := range keys {
+		clause := bson.M{}
+		for _, prev := range keys[:i] {
+			clause[prev.field] = cursor.cursorValue(prev.field)
+		}
+		op := "$gt"
+		if key.desc {
+			op = "$lt"
+		}
+		clause[key.field] = bson.M{op: cursor.cursorValue(key.field)}
+		clauses = append(clauses, clause)
+	}
+	return bson.M{"$or": clauses}
+}
+
+// findCommentsPage returns comments matching filter in the given sort order, with a
+// cursor for the next page that is empty once the last comment has been returned
+func (m *MongoDB) findCommentsPage(ctx context.Context, filter bson.M, sort string, limit int, cursor string) ([]*models.Comment, string, error) {
+	if sort == "" {
+		sort = SortOld
+	}
+	keys := commentSortKeys(sort)
+
+	if cursor != "" {
+		var after CommentCursor
+		if err := DecodeCursor(cursor, &after); err != nil {
+			return nil, "", err
+		}
+		if after.Sort != sort {
+			return nil, "", utils.NewAppError(utils.ErrInvalidInput, "Cursor was issued for a different sort", nil)
+		}
+		filter = bson.M{"$and": []bson.M{filter, afterCommentCursor(keys, after)}}
+	}
+
+	order := make(bson.D, len(keys))
+	for i, key := range keys {
+		direction := 1
+		if key.desc {
+			direction = -1
+		}
+		order[i] = bson.E{Key: key.field, Value: direction}
+	}
+	opts := options.Find().SetSort(order)
+	if limit > 0 {
+		opts.SetLimit(int64(limit) + 1) // One extra to tell whether another page exists
+	}
+
+	dbCursor, err := m.Comments.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get post comments: %v", err)
+	}
+	defer dbCursor.Close(ctx)
+
+	comments := make([]*models.Comment, 0)
+	var last CommentDocument
+	hasMore := false
+	for dbCursor.Next(ctx) {
+		if limit > 0 && len(comments) == limit {
+			hasMore = true
+			break
+		}
+
+		var doc CommentDocument
+		if err := dbCursor.Decode(&doc); err != nil {
+			return nil, "", fmt.Errorf("failed to decode comment: %v", err)
+		}
+
+		comment, err := convertCommentDocumentToModel(&doc)
+		if err != nil {
+			return nil, "", err
+		}
+		comments = append(comments, comment)
+		last = doc
+	}
+
+	if err := dbCursor.Err(); err != nil {
+		return nil, "", fmt.Errorf("cursor iteration failed: %v", err)
+	}
+
+	nextCursor := ""
+	if hasMore {
+		nextCursor = EncodeCursor(CommentCursor{
+			Sort:        sort,
+			Stickied:    last.IsStickied,
+			Karma:       last.Karma,
+			Controversy: last.Controversy,
+			Score:       last.Score,
+			CreatedAt:   last.CreatedAt,
+			ID:          last.ID,
+		})
+	}
+	return comments, nextCursor, nil
+}
+
+// CommentWithContext is a comment along with the post and subreddit it was made in,
+// for listings shown outside the post
+type CommentWithContext struct {
+	*models.Comment
+	PostTitle     string `json:"postTitle"`
+	SubredditName string `json:"subredditName"`
+}
+
+// GetUserCommentsWithContext pages through a user's comments like GetUserComments and
+// attaches the title of each comment's post and the name of its subreddit
+func (m *MongoDB) GetUserCommentsWithContext(ctx context.Context, userID uuid.UUID, limit int, cursor string, includeDeleted bool) ([]*CommentWithContext, string, error) {
+	comments, nextCursor, err := m.GetUserComments(ctx, userID, limit, cursor, includeDeleted)
+	if err != nil {
+		return nil, "", err
+	}
+
+	postIDs := make([]string, 0, len(comments))
+	subredditIDs := make([]string, 0, len(comments))
+	for _, comment := range comments {
+		postIDs = append(postIDs, comment.PostID.String())
+		subredditIDs = append(subredditIDs, comment.SubredditID.String())
+	}
+
+	titles, err := m.GetPostTitles(ctx, postIDs)
+	if err != nil {
+		return nil, "", err
+	}
+	names, err := m.GetSubredditNames(ctx, subredditIDs)
+	if err != nil {
+		return nil, "", err
+	}
+
+	items := make([]*CommentWithContext, len(comments))
+	for i, comment := range comments {
+		items[i] = &CommentWithContext{
+			Comment:       comment,
+			PostTitle:     titles[comment.PostID.String()],
+			SubredditName: names[comment.SubredditID.String()],
+		}
+	}
+	return items, nextCursor, nil
+}
+
+// EditComment replaces a comment's content, appending the previous content to its edit
+// history in the same update. Deleted comments are left untouched and yield ErrNotFound.
+func (m *MongoDB) EditComment(ctx context.Context, commentID uuid.UUID, content string, editedAt time.Time) error {
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"edits": bson.M{"$concatArrays": bson.A{
+				bson.M{"$ifNull": bson.A{"$edits", bson.A{}}},
+				bson.A{bson.M{"content": "$content", "editedAt": editedAt}},
+			}},
+			"content":   bson.M{"$literal": content}, // User text must not be read as a field path
+			"isEdited":  true,
+			"updatedAt": editedAt,
+		}}},
+	}
+
+	filter := bson.M{"_id": commentID.String(), "isDeleted": bson.M{"$ne": true}}
+	result, err := m.Comments.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to edit comment: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrCommentNotFound, nil, nil)
+	}
+	return nil
+}
+
+// GetCommentEdits returns the previous versions of a comment, oldest first
+func (m *MongoDB) GetCommentEdits(ctx context.Context, commentID uuid.UUID) ([]models.CommentEdit, error) {
+	var doc CommentDocument
+	opts := options.FindOne().SetProjection(bson.M{"edits": 1})
+	err := m.Comments.FindOne(ctx, bson.M{"_id": commentID.String()}, opts).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrCommentNotFound, nil, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment edits: %v", err)
+	}
+	if doc.Edits == nil {
+		return []models.CommentEdit{}, nil
+	}
+	return doc.Edits, nil
+}
+
+// SetCommentDistinction updates how a comment is distinguished and whether it is stickied.
+// Stickying a comment unstickies any other comment on the same post, and the IDs of those
+// comments are returned so caches can follow.
+func (m *MongoDB) SetCommentDistinction(ctx context.Context, comment *models.Comment, distinguishedAs string, sticky bool) ([]uuid.UUID, error) {
+	unstickied := make([]uuid.UUID, 0)
+	if sticky {
+		filter := bson.M{
+			"postId":     comment.PostID.String(),
+			"isStickied": true,
+			"_id":        bson.M{"$ne": comment.ID.String()},
+		}
+		cursor, err := m.Comments.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+		if err != nil {
+			return nil, fmt.Errorf("failed to find stickied comments: %v", err)
+		}
+		var docs []struct {
+			ID string `bson:"_id"`
+		}
+		if err := cursor.All(ctx, &docs); err != nil {
+			return nil, fmt.Errorf("failed to decode stickied comments: %v", err)
+		}
+		for _, doc := range docs {
+			if id, err := uuid.Parse(doc.ID); err == nil {
+				unstickied = append(unstickied, id)
+			}
+		}
+
+		if _, err := m.Comments.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"isStickied": false}}); err != nil {
+			return nil, fmt.Errorf("failed to unsticky comments: %v", err)
+		}
+	}
+
+	set := bson.M{"isStickied": sticky}
+	update := bson.M{"$set": set}
+	if distinguishedAs != "" {
+		set["distinguishedAs"] = distinguishedAs
+	} else {
+		update["$unset"] = bson.M{"distinguishedAs": ""}
+	}
+
+	result, err := m.Comments.UpdateOne(ctx, bson.M{"_id": comment.ID.String()}, update)
+	if err != nil {
+		return nil, fmt.Errorf("failed to distinguish comment: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return nil, utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrCommentNotFound, nil, nil)
+	}
+	return unstickied, nil
+}
+
+// GetCommentCounts counts the comments that aren't deleted on each of the given posts.
+// Posts without comments are absent from the result.
+func (m *MongoDB) GetCommentCounts(ctx context.Context, postIDs []uuid.UUID) (map[uuid.UUID]int, error) {
+	ids := make([]string, len(postIDs))
+	for i, id := range postIDs {
+		ids[i] = id.String()
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"postId": bson.M{"$in": ids}, "isDeleted": bson.M{"$ne": true}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$postId", "count": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := m.Comments.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count comments: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	counts := make(map[uuid.UUID]int, len(postIDs))
+	for cursor.Next(ctx) {
+		var row struct {
+			PostID string `bson:"_id"`
+			Count  int    `bson:"count"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return nil, fmt.Errorf("failed to decode comment count: %v", err)
+		}
+		if postID, err := uuid.Parse(row.PostID); err == nil {
+			counts[postID] = row.Count
+		}
+	}
+	return counts, cursor.Err()
+}
+
+// AttachCommentCounts sets CommentCount on each post from a single aggregation
+func (m *MongoDB) AttachCommentCounts(ctx context.Context, posts []*models.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	postIDs := make([]uuid.UUID, len(posts))
+	for i, post := range posts {
+		postIDs[i] = post.ID
+	}
+	counts, err := m.GetCommentCounts(ctx, postIDs)
+	if err != nil {
+		return err
+	}
+	for _, post := range posts {
+		post.CommentCount = counts[post.ID]
+	}
+	return nil
+}
+
+// GetUserComments retrieves a user's comments newest-first, starting after the cursor if supplied.
+// Deleted comments are skipped unless includeDeleted is set.
+func (m *MongoDB) GetUserComments(ctx context.Context, userID uuid.UUID, limit int, cursor string, includeDeleted bool) ([]*models.Comment, string, error) {
+	filter := bson.M{"authorId": userID.String()}
+	if !includeDeleted {
+		filter["isDeleted"] = false
+	}
+	if cursor != "" {
+		var after CommentCursor
+		if err := DecodeCursor(cursor, &after); err != nil {
+			return nil, "", err
+		}
+		filter = bson.M{"$and": []bson.M{filter, {"$or": []bson.M{
+			{"createdAt": bson.M{"$lt": after.CreatedAt}},
+			{"createdAt": after.CreatedAt, "_id": bson.M{"$lt": after.ID}},
+		}}}}
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+
+	dbCursor, err := m.Comments.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get user comments: %v", err)
+	}
+	defer dbCursor.Close(ctx)
+
+	comments := make([]*models.Comment, 0)
+	for dbCursor.Next(ctx) {
+		var doc CommentDocument
+		if err := dbCursor.Decode(&doc); err != nil {
+			return nil, "", fmt.Errorf("failed to decode comment: %v", err)
+		}
+
+		comment, err := convertCommentDocumentToModel(&doc)
+		if err != nil {
+			return nil, "", err
+		}
+		comments = append(comments, comment)
+	}
+
+	if err := dbCursor.Err(); err != nil {
+		return nil, "", fmt.Errorf("cursor iteration failed: %v", err)
+	}
+
+	nextCursor := ""
+	if limit > 0 && len(comments) == limit {
+		last := comments[len(comments)-1]
+		nextCursor = EncodeCursor(CommentCursor{CreatedAt: last.CreatedAt, ID: last.ID.String()})
+	}
+
+	return comments, nextCursor, nil
+}
+
 // UpdateCommentVotes updates the vote counts and karma for a comment
 func (m *MongoDB) UpdateCommentVotes(ctx context.Context, commentID uuid.UUID, upvotes, downvotes int) error {
 	filter := bson.M{"_id": commentID.String()}
 	update := bson.M{
 		"$set": bson.M{
-			"upvotes":   upvotes,
-			"downvotes": downvotes,
-			"karma":     upvotes - downvotes,
-			"updatedAt": time.Now(),
+			"upvotes":     upvotes,
+			"downvotes":   downvotes,
+			"karma":       upvotes - downvotes,
+			"controversy": utils.ControversyScore(upvotes, downvotes),
+			"score":       utils.WilsonScore(upvotes, downvotes),
+			"updatedAt":   time.Now(),
 		},
 	}
 
@@ -150,7 +700,7 @@ func (m *MongoDB) UpdateCommentVotes(ctx context.Context, commentID uuid.UUID, u
 	}
 
 	if result.MatchedCount == 0 {
-		return utils.NewAppError(utils.ErrNotFound, "Comment not found", nil)
+		return utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrCommentNotFound, nil, nil)
 	}
 
 	return nil
@@ -196,21 +746,134 @@ func convertCommentDocumentToModel(doc *CommentDocument) (*models.Comment, error
 		children[i] = childID
 	}
 
-	return &models.Comment{
-		ID:          id,
-		Content:     doc.Content,
-		AuthorID:    authorID,
-		PostID:      postID,
-		SubredditID: subredditID,
-		ParentID:    parentID,
-		Children:    children,
-		CreatedAt:   doc.CreatedAt,
-		UpdatedAt:   doc.UpdatedAt,
-		IsDeleted:   doc.IsDeleted,
-		Upvotes:     doc.Upvotes,
-		Downvotes:   doc.Downvotes,
-		Karma:       doc.Karma,
-	}, nil
+	comment := &models.Comment{
+		ID:              id,
+		Content:         doc.Content,
+		AuthorID:        authorID,
+		PostID:          postID,
+		SubredditID:     subredditID,
+		ParentID:        parentID,
+		Depth:           doc.Depth,
+		Path:            doc.Path,
+		Children:        children,
+		CreatedAt:       doc.CreatedAt,
+		UpdatedAt:       doc.UpdatedAt,
+		IsDeleted:       doc.IsDeleted,
+		IsEdited:        doc.IsEdited,
+		IsStickied:      doc.IsStickied,
+		DistinguishedAs: doc.Distinguish,
+		DeletedAt:       doc.DeletedAt,
+		Upvotes:         doc.Upvotes,
+		Downvotes:       doc.Downvotes,
+		Karma:           doc.Karma,
+		Automod:         automodRuleFromDB(doc.Automod),
+	}
+	if doc.DeletedBy != "" {
+		deletedBy, err := uuid.Parse(doc.DeletedBy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deleted-by ID: %v", err)
+		}
+		comment.DeletedBy = &deletedBy
+	}
+	return comment, nil
+}
+
+// SoftDeleteComments marks comments deleted in one update, skipping ones that are
+// already deleted. Original content is stashed so it can be restored during the
+// undo window; the janitor scrubs it afterwards.
+func (m *MongoDB) SoftDeleteComments(ctx context.Context, commentIDs []string, deletedBy uuid.UUID, deletedAt time.Time) error {
+	return m.softDeleteCommentsMatching(ctx, bson.M{"_id": bson.M{"$in": commentIDs}}, deletedBy, deletedAt)
+}
+
+// SoftDeletePostComments soft-deletes every remaining comment on a post, as part of
+// deleting the post itself
+func (m *MongoDB) SoftDeletePostComments(ctx context.Context, postID, deletedBy uuid.UUID, deletedAt time.Time) error {
+	return m.softDeleteCommentsMatching(ctx, bson.M{"postId": postID.String()}, deletedBy, deletedAt)
+}
+
+func (m *MongoDB) softDeleteCommentsMatching(ctx context.Context, filter bson.M, deletedBy uuid.UUID, deletedAt time.Time) error {
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"isDeleted":      true,
+			"deletedAt":      deletedAt,
+			"deletedBy":      deletedBy.String(),
+			"deletedContent": "$content",
+			"content":        DeletedPlaceholder,
+			"updatedAt":      deletedAt,
+		}}},
+	}
+
+	filter["isDeleted"] = bson.M{"$ne": true}
+	if _, err := m.Comments.UpdateMany(ctx, filter, update); err != nil {
+		return fmt.Errorf("failed to delete comments: %v", err)
+	}
+	return nil
+}
+
+// RestoreComments reverses SoftDeleteComments for the comments removed by the same
+// deletion (same deleter and timestamp) whose stashed content still exists
+func (m *MongoDB) RestoreComments(ctx context.Context, commentIDs []string, deletedBy uuid.UUID, deletedAt time.Time) (int64, error) {
+	return m.restoreCommentsMatching(ctx, bson.M{"_id": bson.M{"$in": commentIDs}}, deletedBy, deletedAt)
+}
+
+// RestorePostComments reverses SoftDeletePostComments, leaving comments that were
+// deleted separately from the post alone
+func (m *MongoDB) RestorePostComments(ctx context.Context, postID, deletedBy uuid.UUID, deletedAt time.Time) (int64, error) {
+	return m.restoreCommentsMatching(ctx, bson.M{"postId": postID.String()}, deletedBy, deletedAt)
+}
+
+func (m *MongoDB) restoreCommentsMatching(ctx context.Context, filter bson.M, deletedBy uuid.UUID, deletedAt time.Time) (int64, error) {
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"isDeleted": false, "content": "$deletedContent", "updatedAt": time.Now()}}},
+		{{Key: "$unset", Value: bson.A{"deletedAt", "deletedBy", "deletedContent"}}},
+	}
+
+	filter["isDeleted"] = true
+	filter["deletedBy"] = deletedBy.String()
+	filter["deletedAt"] = deletedAt
+	filter["deletedContent"] = bson.M{"$exists": true}
+	result, err := m.Comments.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, fmt.Errorf("failed to restore comments: %v", err)
+	}
+	return result.ModifiedCount, nil
+}
+
+// ScrubDeletedComments permanently discards the stashed content of comments deleted before cutoff
+func (m *MongoDB) ScrubDeletedComments(ctx context.Context, cutoff time.Time) (int64, error) {
+	filter := bson.M{
+		"isDeleted":      true,
+		"deletedAt":      bson.M{"$lt": cutoff},
+		"deletedContent": bson.M{"$exists": true},
+	}
+	result, err := m.Comments.UpdateMany(ctx, filter, bson.M{"$unset": bson.M{"deletedContent": ""}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to scrub deleted comments: %v", err)
+	}
+	return result.ModifiedCount, nil
+}
+
+// backfillComments fills in ranking scores, depths, paths and sticky flags for comments
+// stored before they were tracked
+func (m *MongoDB) backfillComments(ctx context.Context) error {
+	if err := m.backfillCommentRankings(ctx); err != nil {
+		return err
+	}
+	if err := m.backfillCommentDepth(ctx); err != nil {
+		return err
+	}
+	if err := m.backfillCommentPaths(ctx); err != nil {
+		return err
+	}
+	// Listings sort on isStickied, and cursors can only compare it against booleans
+	_, err := m.Comments.UpdateMany(ctx,
+		bson.M{"isStickied": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"isStickied": false}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to backfill comment sticky flags: %v", err)
+	}
+	return nil
 }
 
 // EnsureCommentIndexes creates required indexes for the comments collection
@@ -222,12 +885,27 @@ func (m *MongoDB) EnsureCommentIndexes(ctx context.Context) error {
 				{Key: "createdAt", Value: -1},
 			},
 		},
+		{
+			Keys: bson.D{{Key: "postId", Value: 1}, {Key: "karma", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "postId", Value: 1}, {Key: "controversy", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "postId", Value: 1}, {Key: "score", Value: -1}},
+		},
 		{
 			Keys: bson.D{{Key: "authorId", Value: 1}},
 		},
 		{
 			Keys: bson.D{{Key: "parentId", Value: 1}},
 		},
+		{
+			Keys: bson.D{{Key: "path", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "postId", Value: 1}, {Key: "content", Value: "text"}},
+		},
 	}
 
 	_, err := m.Comments.Indexes().CreateMany(ctx, indexes)
@@ -238,6 +916,169 @@ func (m *MongoDB) EnsureCommentIndexes(ctx context.Context) error {
 	return nil
 }
 
+// backfillCommentRankings scores comments stored before controversy or best scores were tracked
+func (m *MongoDB) backfillCommentRankings(ctx context.Context) error {
+	cursor, err := m.Comments.Find(ctx,
+		bson.M{"$or": []bson.M{
+			{"controversy": bson.M{"$exists": false}},
+			{"score": bson.M{"$exists": false}},
+		}},
+		options.Find().SetProjection(bson.M{"_id": 1, "upvotes": 1, "downvotes": 1}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to find unscored comments: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc CommentDocument
+		if err := cursor.Decode(&doc); err != nil {
+			return fmt.Errorf("failed to decode comment: %v", err)
+		}
+		_, err := m.Comments.UpdateOne(ctx,
+			bson.M{"_id": doc.ID},
+			bson.M{"$set": bson.M{
+				"controversy": utils.ControversyScore(doc.Upvotes, doc.Downvotes),
+				"score":       utils.WilsonScore(doc.Upvotes, doc.Downvotes),
+			}},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to backfill comment scores: %v", err)
+		}
+	}
+	return cursor.Err()
+}
+
+// backfillCommentDepth computes the depth of comments stored before depth was tracked by
+// walking their parent chains
+func (m *MongoDB) backfillCommentDepth(ctx context.Context) error {
+	missing, err := m.Comments.CountDocuments(ctx, bson.M{"depth": bson.M{"$exists": false}})
+	if err != nil {
+		return fmt.Errorf("failed to count comments without depth: %v", err)
+	}
+	if missing == 0 {
+		return nil
+	}
+
+	cursor, err := m.Comments.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"_id": 1, "parentId": 1}))
+	if err != nil {
+		return fmt.Errorf("failed to load comment parents: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	parents := make(map[string]string)
+	for cursor.Next(ctx) {
+		var doc CommentDocument
+		if err := cursor.Decode(&doc); err != nil {
+			return fmt.Errorf("failed to decode comment: %v", err)
+		}
+		if doc.ParentID != nil {
+			parents[doc.ID] = *doc.ParentID
+		} else {
+			parents[doc.ID] = ""
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("failed to load comment parents: %v", err)
+	}
+
+	depths := make(map[string]int, len(parents))
+	var depthOf func(id string, seen int) int
+	depthOf = func(id string, seen int) int {
+		if depth, ok := depths[id]; ok {
+			return depth
+		}
+		parent, ok := parents[id]
+		// Missing parents and cycles end the walk rather than looping forever
+		if !ok || parent == "" || seen > len(parents) {
+			depths[id] = 0
+			return 0
+		}
+		if _, exists := parents[parent]; !exists {
+			depths[id] = 1
+			return 1
+		}
+		depths[id] = depthOf(parent, seen+1) + 1
+		return depths[id]
+	}
+
+	for id := range parents {
+		_, err := m.Comments.UpdateOne(ctx,
+			bson.M{"_id": id, "depth": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"depth": depthOf(id, 0)}},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to backfill comment depth: %v", err)
+		}
+	}
+	return nil
+}
+
+// backfillCommentPaths computes the materialized path of comments stored before paths
+// were tracked by walking their parent chains
+func (m *MongoDB) backfillCommentPaths(ctx context.Context) error {
+	missing, err := m.Comments.CountDocuments(ctx, bson.M{"path": bson.M{"$exists": false}})
+	if err != nil {
+		return fmt.Errorf("failed to count comments without path: %v", err)
+	}
+	if missing == 0 {
+		return nil
+	}
+
+	cursor, err := m.Comments.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"_id": 1, "parentId": 1}))
+	if err != nil {
+		return fmt.Errorf("failed to load comment parents: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	parents := make(map[string]string)
+	for cursor.Next(ctx) {
+		var doc CommentDocument
+		if err := cursor.Decode(&doc); err != nil {
+			return fmt.Errorf("failed to decode comment: %v", err)
+		}
+		if doc.ParentID != nil {
+			parents[doc.ID] = *doc.ParentID
+		} else {
+			parents[doc.ID] = ""
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("failed to load comment parents: %v", err)
+	}
+
+	paths := make(map[string]string, len(parents))
+	var pathOf func(id string, seen int) string
+	pathOf = func(id string, seen int) string {
+		if path, ok := paths[id]; ok {
+			return path
+		}
+		parent := parents[id]
+		// Missing parents and cycles start the path at the comment itself
+		if parent == "" || seen > len(parents) {
+			paths[id] = id
+			return id
+		}
+		if _, exists := parents[parent]; !exists {
+			paths[id] = parent + "/" + id
+			return paths[id]
+		}
+		paths[id] = pathOf(parent, seen+1) + "/" + id
+		return paths[id]
+	}
+
+	for id := range parents {
+		_, err := m.Comments.UpdateOne(ctx,
+			bson.M{"_id": id, "path": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"path": pathOf(id, 0)}},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to backfill comment path: %v", err)
+		}
+	}
+	return nil
+}
+
 func (m *MongoDB) GetUserVoteOnComment(ctx context.Context, userID, commentID uuid.UUID) (bool, bool, error) {
 	var vote VoteDocument
 	err := m.Votes.FindOne(ctx, bson.M{
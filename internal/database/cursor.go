@@ -0,0 +1,51 @@
+package database
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"gator-swamp/internal/utils"
+	"time"
+)
+
+// PostCursor marks a position in a sorted post listing. It is handed to clients
+// as an opaque base64 string and carries every key the listing is sorted on.
+type PostCursor struct {
+	Hot         float64   `json:"h,omitempty"`
+	Controversy float64   `json:"c,omitempty"`
+	Karma       int       `json:"k"`
+	CreatedAt   time.Time `json:"t"`
+	ID          string    `json:"id"`
+}
+
+// CommentCursor marks a position in a comment listing. Sort records the order the
+// cursor was issued for; the sort keys not used by that order are left zero.
+type CommentCursor struct {
+	Sort        string    `json:"s,omitempty"`
+	Stickied    bool      `json:"p,omitempty"`
+	Karma       int       `json:"k,omitempty"`
+	Controversy float64   `json:"c,omitempty"`
+	Score       float64   `json:"b,omitempty"`
+	CreatedAt   time.Time `json:"t"`
+	ID          string    `json:"id"`
+}
+
+// EncodeCursor serializes a cursor into an opaque string
+func EncodeCursor(cursor interface{}) string {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses an opaque cursor string into dst. Malformed input yields ErrInvalidInput.
+func DecodeCursor(raw string, dst interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return utils.NewAppError(utils.ErrInvalidInput, "Invalid cursor", err)
+	}
+	if err := json.Unmarshal(data, dst); err != nil {
+		return utils.NewAppError(utils.ErrInvalidInput, "Invalid cursor", err)
+	}
+	return nil
+}
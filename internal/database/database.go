@@ -3,6 +3,7 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -13,16 +14,42 @@ import (
 )
 
 type MongoDB struct {
-	Client     *mongo.Client
-	Users      *mongo.Collection
-	Posts      *mongo.Collection
-	Comments   *mongo.Collection
-	Subreddits *mongo.Collection
-	Messages   *mongo.Collection
-	Votes      *mongo.Collection
+	Client          *mongo.Client
+	Users           *mongo.Collection
+	Posts           *mongo.Collection
+	Comments        *mongo.Collection
+	Subreddits      *mongo.Collection
+	Messages        *mongo.Collection
+	Votes           *mongo.Collection
+	ShareLinks      *mongo.Collection
+	Multireddits    *mongo.Collection
+	AnalyticsEvents *mongo.Collection
+	AnalyticsDaily  *mongo.Collection
+	Digests         *mongo.Collection
+	DigestState     *mongo.Collection
+	Notifications   *mongo.Collection
+	SavedItems      *mongo.Collection
+	HiddenPosts     *mongo.Collection
+	PollVotes       *mongo.Collection
+	PostVotes       *mongo.Collection
+	IdempotencyKeys *mongo.Collection
+	SubredditBans   *mongo.Collection
+	Sessions        *mongo.Collection
+	PasswordResets  *mongo.Collection
+	Follows         *mongo.Collection
+	Blocks          *mongo.Collection
+	AdminAudit      *mongo.Collection
+
+	EmailVerifications *mongo.Collection
 }
 
 func NewMongoDB(uri string) (*MongoDB, error) {
+	return NewMongoDBNamed(uri, "gator_swamp")
+}
+
+// NewMongoDBNamed connects to MongoDB like NewMongoDB but keeps to the named database,
+// so tests can run against a database of their own
+func NewMongoDBNamed(uri, name string) (*MongoDB, error) {
 	serverAPI := options.ServerAPI(options.ServerAPIVersion1)
 	opts := options.Client().ApplyURI(uri).SetServerAPIOptions(serverAPI)
 
@@ -42,17 +69,125 @@ func NewMongoDB(uri string) (*MongoDB, error) {
 	log.Println("Successfully connected to MongoDB!")
 
 	// Initialize database and collections
-	db := client.Database("gator_swamp")
+	db := client.Database(name)
 	return &MongoDB{
-		Client:     client,
-		Users:      db.Collection("users"),
-		Posts:      db.Collection("posts"),
-		Comments:   db.Collection("comments"),
-		Subreddits: db.Collection("subreddits"),
-		Messages:   db.Collection("messages"),
+		Client:          client,
+		Users:           db.Collection("users"),
+		Posts:           db.Collection("posts"),
+		Comments:        db.Collection("comments"),
+		Subreddits:      db.Collection("subreddits"),
+		Messages:        db.Collection("messages"),
+		ShareLinks:      db.Collection("share_links"),
+		Multireddits:    db.Collection("multireddits"),
+		AnalyticsEvents: db.Collection("analytics_events"),
+		AnalyticsDaily:  db.Collection("analytics_daily"),
+		Digests:         db.Collection("digests"),
+		DigestState:     db.Collection("digest_state"),
+		Notifications:   db.Collection("notifications"),
+		SavedItems:      db.Collection("saved_items"),
+		HiddenPosts:     db.Collection("hidden_posts"),
+		PollVotes:       db.Collection("poll_votes"),
+		PostVotes:       db.Collection("post_votes"),
+		IdempotencyKeys: db.Collection("idempotency_keys"),
+		SubredditBans:   db.Collection("subreddit_bans"),
+		Sessions:        db.Collection("sessions"),
+		PasswordResets:  db.Collection("password_resets"),
+		Follows:         db.Collection("follows"),
+		Blocks:          db.Collection("blocks"),
+		AdminAudit:      db.Collection("admin_audit"),
+
+		EmailVerifications: db.Collection("email_verifications"),
 	}, nil
 }
 
+// Backfill fills in fields added since older documents were stored. Some of the indexes
+// EnsureIndexes creates are on these fields, so it runs first. It can take a while on
+// large collections, and failures are logged rather than returned.
+func (m *MongoDB) Backfill(ctx context.Context) {
+	for _, backfill := range []func(context.Context) error{
+		m.backfillUsers,
+		m.backfillPostHotScores,
+		m.backfillPostSlugs,
+		m.backfillSubredditNames,
+		m.backfillComments,
+	} {
+		if err := backfill(ctx); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+}
+
+// UniqueIndexError is a failure to create an index that keeps documents unique
+type UniqueIndexError struct {
+	Index string
+	Err   error
+}
+
+func (e *UniqueIndexError) Error() string {
+	return fmt.Sprintf("failed to create unique %s index: %v", e.Index, e.Err)
+}
+
+func (e *UniqueIndexError) Unwrap() error {
+	return e.Err
+}
+
+// createUniqueIndex creates a unique index on its own, so other indexes failing doesn't
+// hold it back and its failure can be told apart from theirs
+func createUniqueIndex(ctx context.Context, collection *mongo.Collection, name string, model mongo.IndexModel) error {
+	if _, err := collection.Indexes().CreateOne(ctx, model); err != nil {
+		return &UniqueIndexError{Index: name, Err: err}
+	}
+	return nil
+}
+
+// EnsureIndexes creates the indexes that features rely on for correctness or speed.
+// Writes rely on the unique indexes to reject duplicates, so failing to create one is
+// returned; other failures are logged so a single bad index doesn't block startup.
+func (m *MongoDB) EnsureIndexes(ctx context.Context) error {
+	var missing []error
+	for _, ensure := range []func(context.Context) error{
+		m.EnsureShareLinkIndexes,
+		m.EnsureMultiredditIndexes,
+		m.EnsureAnalyticsIndexes,
+		m.EnsurePostIndexes,
+		m.EnsurePostSlugIndexes,
+		m.EnsureDigestIndexes,
+		m.EnsureUserIndexes,
+		m.EnsureCommentIndexes,
+		m.EnsureNotificationIndexes,
+		m.EnsureSavedItemIndexes,
+		m.EnsureHiddenPostIndexes,
+		m.EnsurePollVoteIndexes,
+		m.EnsurePostVoteIndexes,
+		m.EnsureIdempotencyKeyIndexes,
+		m.EnsureSubredditIndexes,
+		m.EnsureSubredditBanIndexes,
+		m.EnsureSessionIndexes,
+		m.EnsurePasswordResetIndexes,
+		m.EnsureEmailVerificationIndexes,
+		m.EnsureFollowIndexes,
+		m.EnsureBlockIndexes,
+		m.EnsureAdminAuditIndexes,
+	} {
+		err := ensure(ctx)
+		errs := []error{err}
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			errs = joined.Unwrap()
+		}
+		for _, err := range errs {
+			var uniqueErr *UniqueIndexError
+			switch {
+			case err == nil:
+			case errors.As(err, &uniqueErr):
+				missing = append(missing, err)
+			default:
+				log.Printf("Warning: %v", err)
+			}
+		}
+	}
+	return errors.Join(missing...)
+}
+
 func (m *MongoDB) Close(ctx context.Context) error {
 	return m.Client.Disconnect(ctx)
 }
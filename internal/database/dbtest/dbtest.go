@@ -0,0 +1,76 @@
+// Package dbtest gives tests a MongoDB database of their own to run against
+package dbtest
+
+import (
+	"context"
+	"gator-swamp/internal/database"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// URIEnv names the environment variable holding the URI of the MongoDB deployment tests
+// run against. Tests that need MongoDB are skipped when it isn't set.
+const URIEnv = "GATOR_TEST_MONGODB_URI"
+
+// New connects to the MongoDB deployment named by URIEnv and returns a MongoDB using a
+// fresh database with the indexes features rely on. The database is dropped when the
+// test finishes.
+func New(t testing.TB) *database.MongoDB {
+	t.Helper()
+
+	uri := os.Getenv(URIEnv)
+	if uri == "" {
+		t.Skipf("%s is not set; skipping test that needs MongoDB", URIEnv)
+	}
+
+	name := "gator_swamp_test_" + strings.ReplaceAll(uuid.New().String(), "-", "")[:12]
+	mongodb, err := database.NewMongoDBNamed(uri, name)
+	if err != nil {
+		t.Fatalf("Failed to connect to MongoDB: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	mongodb.Backfill(ctx)
+	if err := mongodb.EnsureIndexes(ctx); err != nil {
+		t.Fatalf("Failed to create indexes: %v", err)
+	}
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := mongodb.Users.Database().Drop(ctx); err != nil {
+			t.Logf("Failed to drop test database %s: %v", name, err)
+		}
+		mongodb.Client.Disconnect(ctx)
+	})
+	return mongodb
+}
+
+// RequireTransactions skips the test unless the deployment can run transactions, as a
+// replica set can and a standalone server can't
+func RequireTransactions(t testing.TB, mongodb *database.MongoDB) {
+	t.Helper()
+	if !SupportsTransactions(t, mongodb) {
+		t.Skip("MongoDB deployment doesn't support transactions; run against a replica set")
+	}
+}
+
+// SupportsTransactions reports whether the deployment can run transactions, for tests
+// that cover both ways of writing
+func SupportsTransactions(t testing.TB, mongodb *database.MongoDB) bool {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	supported, err := mongodb.SupportsTransactions(ctx)
+	if err != nil {
+		t.Fatalf("SupportsTransactions: %v", err)
+	}
+	return supported
+}
@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"gator-swamp/internal/i18n"
+	"gator-swamp/internal/utils"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DigestDateLayout is the format of digest dates (UTC days)
+const DigestDateLayout = "2006-01-02"
+
+// DigestPost is a post snapshot stored in a digest
+type DigestPost struct {
+	PostID         string    `bson:"postId" json:"postId"`
+	Title          string    `bson:"title" json:"title"`
+	AuthorID       string    `bson:"authorId" json:"authorId"`
+	AuthorUsername string    `bson:"authorUsername" json:"authorUsername"`
+	Karma          int       `bson:"karma" json:"karma"`
+	CreatedAt      time.Time `bson:"createdAt" json:"createdAt"`
+}
+
+// DigestDocument is the top posts of one subreddit for one UTC day
+type DigestDocument struct {
+	ID            string       `bson:"_id" json:"-"` // subredditId|date
+	SubredditID   string       `bson:"subredditId" json:"subredditId"`
+	SubredditName string       `bson:"subredditName" json:"subredditName"`
+	Date          string       `bson:"date" json:"date"`
+	Posts         []DigestPost `bson:"posts" json:"posts"`
+	GeneratedAt   time.Time    `bson:"generatedAt" json:"generatedAt"`
+}
+
+// digestStateDocument records the last day a subreddit's digest was computed for
+type digestStateDocument struct {
+	SubredditID string    `bson:"_id"`
+	LastDate    string    `bson:"lastDate"`
+	UpdatedAt   time.Time `bson:"updatedAt"`
+}
+
+func digestID(subredditID, date string) string {
+	return subredditID + "|" + date
+}
+
+// GetTopPostsInRange returns the highest-karma posts of a subreddit created in [start, end)
+func (m *MongoDB) GetTopPostsInRange(ctx context.Context, subredditID string, start, end time.Time, limit int) ([]*PostDocument, error) {
+	filter := bson.M{
+		"subredditid": subredditID,
+		"createdat":   bson.M{"$gte": start, "$lt": end},
+		"isdeleted":   bson.M{"$ne": true},
+		"status":      bson.M{"$nin": UnlistedPostStatuses},
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "karma", Value: -1}, {Key: "createdat", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := m.Posts.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top posts: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	posts := make([]*PostDocument, 0)
+	if err := cursor.All(ctx, &posts); err != nil {
+		return nil, fmt.Errorf("failed to decode top posts: %v", err)
+	}
+	return posts, nil
+}
+
+// SaveDigest stores a digest, replacing any existing digest for the same subreddit and date
+func (m *MongoDB) SaveDigest(ctx context.Context, digest *DigestDocument) error {
+	digest.ID = digestID(digest.SubredditID, digest.Date)
+	opts := options.Replace().SetUpsert(true)
+	if _, err := m.Digests.ReplaceOne(ctx, bson.M{"_id": digest.ID}, digest, opts); err != nil {
+		return fmt.Errorf("failed to save digest: %v", err)
+	}
+	return nil
+}
+
+// GetDigest retrieves the digest of a subreddit for a date
+func (m *MongoDB) GetDigest(ctx context.Context, subredditID, date string) (*DigestDocument, error) {
+	var digest DigestDocument
+	err := m.Digests.FindOne(ctx, bson.M{"_id": digestID(subredditID, date)}).Decode(&digest)
+	if err == mongo.ErrNoDocuments {
+		return nil, utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrDigestNotFound, map[string]string{"date": date}, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get digest: %v", err)
+	}
+	return &digest, nil
+}
+
+// GetDigestLastDates returns the last computed digest date for every subreddit that has one
+func (m *MongoDB) GetDigestLastDates(ctx context.Context) (map[string]string, error) {
+	cursor, err := m.DigestState.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get digest state: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	lastDates := make(map[string]string)
+	for cursor.Next(ctx) {
+		var doc digestStateDocument
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode digest state: %v", err)
+		}
+		lastDates[doc.SubredditID] = doc.LastDate
+	}
+	return lastDates, cursor.Err()
+}
+
+// SetDigestLastDate records that a subreddit's digests are complete up to and including date
+func (m *MongoDB) SetDigestLastDate(ctx context.Context, subredditID, date string) error {
+	opts := options.Update().SetUpsert(true)
+	_, err := m.DigestState.UpdateOne(ctx,
+		bson.M{"_id": subredditID},
+		bson.M{"$set": bson.M{"lastDate": date, "updatedAt": time.Now()}},
+		opts,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update digest state: %v", err)
+	}
+	return nil
+}
+
+// EnsureDigestIndexes creates the post index used by the time-ranged top query
+func (m *MongoDB) EnsureDigestIndexes(ctx context.Context) error {
+	_, err := m.Posts.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "subredditid", Value: 1}, {Key: "createdat", Value: -1}, {Key: "karma", Value: -1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create digest indexes: %v", err)
+	}
+	return nil
+}
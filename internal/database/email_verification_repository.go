@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"gator-swamp/internal/utils"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EmailVerificationDocument is a single-use token proving a user receives mail at an
+// address, stored by its hash. MongoDB removes it once it expires.
+type EmailVerificationDocument struct {
+	TokenHash string    `bson:"_id"`
+	UserID    string    `bson:"userId"`
+	Email     string    `bson:"email"` // The address the token was sent to
+	CreatedAt time.Time `bson:"createdAt"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
+
+// CreateEmailVerification stores a verification token for a user's email that stays
+// valid for ttl. Tokens the user was sent earlier stop working, so only the latest
+// email can be used.
+func (m *MongoDB) CreateEmailVerification(ctx context.Context, userID uuid.UUID, email, tokenHash string, now time.Time, ttl time.Duration) error {
+	if _, err := m.EmailVerifications.DeleteMany(ctx, bson.M{"userId": userID.String()}); err != nil {
+		return fmt.Errorf("failed to replace email verification: %v", err)
+	}
+
+	doc := EmailVerificationDocument{
+		TokenHash: tokenHash,
+		UserID:    userID.String(),
+		Email:     email,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+	if _, err := m.EmailVerifications.InsertOne(ctx, doc); err != nil {
+		return fmt.Errorf("failed to create email verification: %v", err)
+	}
+	return nil
+}
+
+// LastEmailVerificationSentAt returns when a user's outstanding verification token was
+// created, or the zero time if they have none
+func (m *MongoDB) LastEmailVerificationSentAt(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	var doc EmailVerificationDocument
+	opts := options.FindOne().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetProjection(bson.M{"createdAt": 1})
+	err := m.EmailVerifications.FindOne(ctx, bson.M{"userId": userID.String()}, opts).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get email verification: %v", err)
+	}
+	return doc.CreatedAt, nil
+}
+
+// VerifyEmail consumes the verification token stored under tokenHash and marks the
+// user's email verified. Unknown, already used and expired tokens, and tokens sent to
+// an address the user has since changed, fail with ErrInvalidToken.
+func (m *MongoDB) VerifyEmail(ctx context.Context, tokenHash string, now time.Time) (uuid.UUID, error) {
+	var doc EmailVerificationDocument
+	err := m.EmailVerifications.FindOneAndDelete(ctx, bson.M{"_id": tokenHash}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return uuid.Nil, utils.NewAppError(utils.ErrInvalidToken, "Verification token is invalid or has already been used", nil)
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get email verification: %v", err)
+	}
+
+	// The TTL monitor only runs once a minute, so expired tokens can still be found
+	if !doc.ExpiresAt.After(now) {
+		return uuid.Nil, utils.NewAppError(utils.ErrInvalidToken, "Verification token has expired", nil)
+	}
+
+	userID, err := uuid.Parse(doc.UserID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid user ID in email verification: %v", err)
+	}
+
+	// Matching on the address keeps a token sent before an email change from verifying
+	// the new address
+	filter := bson.M{"_id": doc.UserID, "emailLower": strings.ToLower(doc.Email)}
+	result, err := m.Users.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"emailVerified": true}})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to verify email: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return uuid.Nil, utils.NewAppError(utils.ErrInvalidToken, "Verification token is no longer valid", nil)
+	}
+	return userID, nil
+}
+
+// EnsureEmailVerificationIndexes creates the index used to find and replace a user's
+// tokens and the TTL index that removes tokens once they expire
+func (m *MongoDB) EnsureEmailVerificationIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+	if _, err := m.EmailVerifications.Indexes().CreateMany(ctx, indexes); err != nil {
+		return fmt.Errorf("failed to create email verification indexes: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,327 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gator-swamp/internal/models"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FollowDocument records that one user follows another
+type FollowDocument struct {
+	ID         string    `bson:"_id"`
+	FollowerID string    `bson:"followerId"`
+	FollowedID string    `bson:"followedId"`
+	CreatedAt  time.Time `bson:"createdAt"`
+}
+
+// FollowCursor marks a position in a list of follows, most recent first
+type FollowCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+}
+
+// FollowUser makes followerID follow followedID. Following again keeps the original time.
+func (m *MongoDB) FollowUser(ctx context.Context, followerID, followedID uuid.UUID, at time.Time) error {
+	filter := bson.M{"followerId": followerID.String(), "followedId": followedID.String()}
+	update := bson.M{"$setOnInsert": bson.M{"_id": uuid.New().String(), "createdAt": at}}
+
+	_, err := m.Follows.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// A concurrent follow of the same user won the race
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to follow user: %v", err)
+	}
+	return nil
+}
+
+// UnfollowUser stops followerID following followedID. Unfollowing a user who isn't
+// followed is a no-op.
+func (m *MongoDB) UnfollowUser(ctx context.Context, followerID, followedID uuid.UUID) error {
+	filter := bson.M{"followerId": followerID.String(), "followedId": followedID.String()}
+	if _, err := m.Follows.DeleteOne(ctx, filter); err != nil {
+		return fmt.Errorf("failed to unfollow user: %v", err)
+	}
+	return nil
+}
+
+// CountFollows returns how many users follow a user and how many users they follow
+func (m *MongoDB) CountFollows(ctx context.Context, userID uuid.UUID) (followers, following int64, err error) {
+	followers, err = m.Follows.CountDocuments(ctx, bson.M{"followedId": userID.String()})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count followers: %v", err)
+	}
+	following, err = m.Follows.CountDocuments(ctx, bson.M{"followerId": userID.String()})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count followed users: %v", err)
+	}
+	return followers, following, nil
+}
+
+// GetFollowing returns a page of the users a user follows, most recently followed first
+func (m *MongoDB) GetFollowing(ctx context.Context, userID uuid.UUID, limit int, cursor string) ([]*models.FollowEntry, string, error) {
+	return m.getFollows(ctx, "followerId", "followedId", userID, limit, cursor)
+}
+
+// GetFollowers returns a page of the users following a user, most recent first
+func (m *MongoDB) GetFollowers(ctx context.Context, userID uuid.UUID, limit int, cursor string) ([]*models.FollowEntry, string, error) {
+	return m.getFollows(ctx, "followedId", "followerId", userID, limit, cursor)
+}
+
+// getFollows lists the follows whose ownField is userID, describing the user in
+// otherField of each. Follows of accounts that no longer exist are deleted and skipped,
+// which can leave the page short.
+func (m *MongoDB) getFollows(ctx context.Context, ownField, otherField string, userID uuid.UUID, limit int, cursor string) ([]*models.FollowEntry, string, error) {
+	filter := bson.M{ownField: userID.String()}
+	if cursor != "" {
+		var after FollowCursor
+		if err := DecodeCursor(cursor, &after); err != nil {
+			return nil, "", err
+		}
+		filter = bson.M{"$and": []bson.M{filter, {"$or": []bson.M{
+			{"createdAt": bson.M{"$lt": after.CreatedAt}},
+			{"createdAt": after.CreatedAt, "_id": bson.M{"$lt": after.ID}},
+		}}}}
+	}
+
+	// Fetch one extra row to learn whether another page exists
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit + 1))
+
+	dbCursor, err := m.Follows.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get follows: %v", err)
+	}
+	var docs []FollowDocument
+	if err := dbCursor.All(ctx, &docs); err != nil {
+		return nil, "", fmt.Errorf("failed to decode follows: %v", err)
+	}
+
+	nextCursor := ""
+	if len(docs) > limit {
+		docs = docs[:limit]
+		last := docs[len(docs)-1]
+		nextCursor = EncodeCursor(FollowCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	otherIDs := make([]string, len(docs))
+	for i, doc := range docs {
+		otherIDs[i] = followOther(&doc, otherField)
+	}
+	users, err := m.getLiveUsers(ctx, otherIDs)
+	if err != nil {
+		return nil, "", err
+	}
+
+	entries := make([]*models.FollowEntry, 0, len(docs))
+	var stale []string
+	for _, doc := range docs {
+		user, ok := users[followOther(&doc, otherField)]
+		if !ok {
+			stale = append(stale, doc.ID)
+			continue
+		}
+		entries = append(entries, &models.FollowEntry{
+			UserID:      user.ID,
+			Username:    user.Username,
+			DisplayName: user.DisplayName,
+			AvatarURL:   user.AvatarURL,
+			FollowedAt:  doc.CreatedAt,
+		})
+	}
+	m.deleteStaleFollows(ctx, bson.M{"_id": bson.M{"$in": stale}}, len(stale))
+
+	return entries, nextCursor, nil
+}
+
+func followOther(doc *FollowDocument, otherField string) string {
+	if otherField == "followerId" {
+		return doc.FollowerID
+	}
+	return doc.FollowedID
+}
+
+// getLiveUsers looks up the given users, keyed by ID, leaving out accounts that were
+// removed or deleted for abuse
+func (m *MongoDB) getLiveUsers(ctx context.Context, userIDs []string) (map[string]*models.User, error) {
+	users := make(map[string]*models.User, len(userIDs))
+	if len(userIDs) == 0 {
+		return users, nil
+	}
+
+	filter := bson.M{"_id": bson.M{"$in": userIDs}, "abuseDeletedAt": bson.M{"$exists": false}}
+	opts := options.Find().SetProjection(bson.M{"_id": 1, "username": 1, "displayName": 1, "avatarUrl": 1})
+	cursor, err := m.Users.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %v", err)
+	}
+	var docs []UserDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode users: %v", err)
+	}
+
+	for _, doc := range docs {
+		userID, err := uuid.Parse(doc.ID)
+		if err != nil {
+			continue
+		}
+		users[doc.ID] = &models.User{
+			ID:          userID,
+			Username:    doc.Username,
+			DisplayName: doc.DisplayName,
+			AvatarURL:   doc.AvatarURL,
+		}
+	}
+	return users, nil
+}
+
+// deleteStaleFollows removes follows involving accounts that no longer exist. The
+// listing they were found in goes ahead regardless, so failures are only logged.
+func (m *MongoDB) deleteStaleFollows(ctx context.Context, filter bson.M, count int) {
+	if count == 0 {
+		return
+	}
+	if _, err := m.Follows.DeleteMany(ctx, filter); err != nil {
+		log.Printf("Warning: Failed to delete %d follows of removed accounts: %v", count, err)
+	}
+}
+
+// getFollowedIDs returns the IDs of the users a user follows whose accounts still exist
+func (m *MongoDB) getFollowedIDs(ctx context.Context, followerID string) ([]string, error) {
+	opts := options.Find().SetProjection(bson.M{"followedId": 1})
+	cursor, err := m.Follows.Find(ctx, bson.M{"followerId": followerID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get followed users: %v", err)
+	}
+	var docs []FollowDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode followed users: %v", err)
+	}
+
+	ids := make([]string, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.FollowedID
+	}
+	users, err := m.getLiveUsers(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	live := ids[:0]
+	var stale []string
+	for _, id := range ids {
+		if _, ok := users[id]; ok {
+			live = append(live, id)
+		} else {
+			stale = append(stale, id)
+		}
+	}
+	m.deleteStaleFollows(ctx, bson.M{"followerId": followerID, "followedId": bson.M{"$in": stale}}, len(stale))
+	return live, nil
+}
+
+// GetFollowingFeed returns a page of recent posts by the users a user follows, newest
+// first, leaving out posts the user hid. Like GetPostsByAuthor, deleted posts and posts
+// awaiting review aren't listed.
+func (m *MongoDB) GetFollowingFeed(ctx context.Context, userID uuid.UUID, limit int, cursor string) ([]*models.Post, string, error) {
+	authorIDs, err := m.getFollowedIDs(ctx, userID.String())
+	if err != nil {
+		return nil, "", err
+	}
+	if len(authorIDs) == 0 {
+		return []*models.Post{}, "", nil
+	}
+	hiddenIDs, err := m.getHiddenPostIDs(ctx, userID.String())
+	if err != nil {
+		return nil, "", err
+	}
+
+	filter := bson.M{
+		"authorid":  bson.M{"$in": authorIDs},
+		"status":    bson.M{"$nin": UnlistedPostStatuses},
+		"isdeleted": bson.M{"$ne": true},
+	}
+	if len(hiddenIDs) > 0 {
+		filter["_id"] = bson.M{"$nin": hiddenIDs}
+	}
+	if cursor != "" {
+		var after PostCursor
+		if err := DecodeCursor(cursor, &after); err != nil {
+			return nil, "", err
+		}
+		filter = bson.M{"$and": []bson.M{filter, afterPostCursor(SortNew, after)}}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdat", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit))
+
+	dbCursor, err := m.Posts.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get following feed: %v", err)
+	}
+	defer dbCursor.Close(ctx)
+
+	posts := make([]*models.Post, 0)
+	for dbCursor.Next(ctx) {
+		var doc PostDocument
+		if err := dbCursor.Decode(&doc); err != nil {
+			log.Printf("Error decoding post document: %v", err)
+			continue
+		}
+
+		post, err := m.DocumentToModel(&doc)
+		if err != nil {
+			log.Printf("Error converting document to model: %v", err)
+			continue
+		}
+		posts = append(posts, post)
+	}
+
+	if err := dbCursor.Err(); err != nil {
+		return nil, "", fmt.Errorf("cursor iteration failed: %v", err)
+	}
+
+	nextCursor := ""
+	if len(posts) == limit {
+		last := posts[len(posts)-1]
+		nextCursor = EncodeCursor(PostCursor{CreatedAt: last.CreatedAt, ID: last.ID.String()})
+	}
+
+	return posts, nextCursor, nil
+}
+
+// EnsureFollowIndexes creates required indexes for the follows collection. The unique
+// index makes following the same user twice a no-op.
+func (m *MongoDB) EnsureFollowIndexes(ctx context.Context) error {
+	var errs []error
+	_, err := m.Follows.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "followerId", Value: 1}, {Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "followedId", Value: 1}, {Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}},
+		},
+	})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to create follow indexes: %v", err))
+	}
+	err = createUniqueIndex(ctx, m.Follows, "follow", mongo.IndexModel{
+		Keys:    bson.D{{Key: "followerId", Value: 1}, {Key: "followedId", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,155 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gator-swamp/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// HiddenPostDocument records that a user asked not to be shown a post
+type HiddenPostDocument struct {
+	ID       string    `bson:"_id"`
+	UserID   string    `bson:"userId"`
+	PostID   string    `bson:"postId"`
+	HiddenAt time.Time `bson:"hiddenAt"`
+}
+
+// HiddenPostCursor marks a position in a user's hidden posts, most recently hidden first
+type HiddenPostCursor struct {
+	HiddenAt time.Time `json:"t"`
+	ID       string    `json:"id"`
+}
+
+// HidePost hides a post from a user's listings. Hiding it again keeps the original time.
+func (m *MongoDB) HidePost(ctx context.Context, userID, postID uuid.UUID, hiddenAt time.Time) error {
+	filter := bson.M{"userId": userID.String(), "postId": postID.String()}
+	update := bson.M{"$setOnInsert": bson.M{"_id": uuid.New().String(), "hiddenAt": hiddenAt}}
+
+	_, err := m.HiddenPosts.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// A concurrent hide of the same post won the race
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to hide post: %v", err)
+	}
+	return nil
+}
+
+// UnhidePost shows a hidden post again. Unhiding a post that isn't hidden is a no-op.
+func (m *MongoDB) UnhidePost(ctx context.Context, userID, postID uuid.UUID) error {
+	filter := bson.M{"userId": userID.String(), "postId": postID.String()}
+	if _, err := m.HiddenPosts.DeleteOne(ctx, filter); err != nil {
+		return fmt.Errorf("failed to unhide post: %v", err)
+	}
+	return nil
+}
+
+// getHiddenPostIDs returns the IDs of every post a user has hidden
+func (m *MongoDB) getHiddenPostIDs(ctx context.Context, userID string) ([]string, error) {
+	opts := options.Find().SetProjection(bson.M{"postId": 1})
+	cursor, err := m.HiddenPosts.Find(ctx, bson.M{"userId": userID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hidden posts: %v", err)
+	}
+	var docs []HiddenPostDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode hidden posts: %v", err)
+	}
+
+	ids := make([]string, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.PostID
+	}
+	return ids, nil
+}
+
+// GetHiddenPosts returns a page of the posts a user has hidden, most recently hidden first
+func (m *MongoDB) GetHiddenPosts(ctx context.Context, userID uuid.UUID, limit int, cursor string) ([]*models.HiddenPost, string, error) {
+	filter := bson.M{"userId": userID.String()}
+	if cursor != "" {
+		var after HiddenPostCursor
+		if err := DecodeCursor(cursor, &after); err != nil {
+			return nil, "", err
+		}
+		filter = bson.M{"$and": []bson.M{filter, {"$or": []bson.M{
+			{"hiddenAt": bson.M{"$lt": after.HiddenAt}},
+			{"hiddenAt": after.HiddenAt, "_id": bson.M{"$lt": after.ID}},
+		}}}}
+	}
+
+	// Fetch one extra row to learn whether another page exists
+	opts := options.Find().
+		SetSort(bson.D{{Key: "hiddenAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit + 1))
+
+	dbCursor, err := m.HiddenPosts.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get hidden posts: %v", err)
+	}
+	var docs []HiddenPostDocument
+	if err := dbCursor.All(ctx, &docs); err != nil {
+		return nil, "", fmt.Errorf("failed to decode hidden posts: %v", err)
+	}
+
+	nextCursor := ""
+	if len(docs) > limit {
+		docs = docs[:limit]
+		last := docs[len(docs)-1]
+		nextCursor = EncodeCursor(HiddenPostCursor{HiddenAt: last.HiddenAt, ID: last.ID})
+	}
+
+	postIDs := make([]string, len(docs))
+	for i, doc := range docs {
+		postIDs[i] = doc.PostID
+	}
+	posts, err := m.getPostsByIDs(ctx, postIDs)
+	if err != nil {
+		return nil, "", err
+	}
+
+	hidden := make([]*models.HiddenPost, 0, len(docs))
+	for _, doc := range docs {
+		postID, err := uuid.Parse(doc.PostID)
+		if err != nil {
+			continue
+		}
+		item := &models.HiddenPost{PostID: postID, HiddenAt: doc.HiddenAt}
+		if post, ok := posts[doc.PostID]; ok {
+			item.Title = post.Title
+			item.SubredditID = post.SubredditID
+		}
+		hidden = append(hidden, item)
+	}
+
+	return hidden, nextCursor, nil
+}
+
+// EnsureHiddenPostIndexes creates required indexes for the hidden_posts collection. The
+// unique index makes hiding the same post twice a no-op.
+func (m *MongoDB) EnsureHiddenPostIndexes(ctx context.Context) error {
+	var errs []error
+	_, err := m.HiddenPosts.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "userId", Value: 1}, {Key: "hiddenAt", Value: -1}, {Key: "_id", Value: -1}},
+		},
+	})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to create hidden post indexes: %v", err))
+	}
+	err = createUniqueIndex(ctx, m.HiddenPosts, "hidden post", mongo.IndexModel{
+		Keys:    bson.D{{Key: "userId", Value: 1}, {Key: "postId", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
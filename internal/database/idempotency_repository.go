@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gator-swamp/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// IdempotencyKeyDocument remembers the post created under a client-supplied idempotency
+// key, so a retried request can be answered with it instead of creating another post
+type IdempotencyKeyDocument struct {
+	AuthorID    string    `bson:"authorId"`
+	Key         string    `bson:"key"`
+	Fingerprint string    `bson:"fingerprint"` // Hash of the request the key was first used with
+	PostID      string    `bson:"postId"`
+	ExpiresAt   time.Time `bson:"expiresAt"`
+}
+
+// GetIdempotencyKey returns the unexpired record of an author's idempotency key, or nil
+// if the key hasn't been used
+func (m *MongoDB) GetIdempotencyKey(ctx context.Context, authorID uuid.UUID, key string) (*IdempotencyKeyDocument, error) {
+	filter := bson.M{
+		"authorId":  authorID.String(),
+		"key":       key,
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}
+
+	var doc IdempotencyKeyDocument
+	if err := m.IdempotencyKeys.FindOne(ctx, filter).Decode(&doc); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get idempotency key: %v", err)
+	}
+	return &doc, nil
+}
+
+// SaveIdempotencyKey records the post created under an author's idempotency key,
+// replacing any expired record of the key that the TTL index hasn't removed yet
+func (m *MongoDB) SaveIdempotencyKey(ctx context.Context, doc *IdempotencyKeyDocument) error {
+	filter := bson.M{"authorId": doc.AuthorID, "key": doc.Key}
+	opts := options.Replace().SetUpsert(true)
+	if _, err := m.IdempotencyKeys.ReplaceOne(ctx, filter, doc, opts); err != nil {
+		return fmt.Errorf("failed to save idempotency key: %v", err)
+	}
+	return nil
+}
+
+// GetPostAnyStatus retrieves a post by ID whatever its status, so authors replaying the
+// creation of a scheduled post get it back
+func (m *MongoDB) GetPostAnyStatus(ctx context.Context, postID string) (*models.Post, error) {
+	var doc PostDocument
+	if err := m.Posts.FindOne(ctx, bson.M{"_id": postID}).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to get post: %v", err)
+	}
+	return m.DocumentToModel(&doc)
+}
+
+// EnsureIdempotencyKeyIndexes creates the index that makes a key unique per author and
+// the TTL index that removes records once they expire
+func (m *MongoDB) EnsureIdempotencyKeyIndexes(ctx context.Context) error {
+	var errs []error
+	_, err := m.IdempotencyKeys.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to create idempotency key indexes: %v", err))
+	}
+	err = createUniqueIndex(ctx, m.IdempotencyKeys, "idempotency key", mongo.IndexModel{
+		Keys:    bson.D{{Key: "authorId", Value: 1}, {Key: "key", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ModQueueCursor marks a position in a subreddit's review queue, oldest post first
+type ModQueueCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+}
+
+// GetPendingPosts returns a page of the posts awaiting moderator review in a subreddit,
+// oldest first
+func (m *MongoDB) GetPendingPosts(ctx context.Context, subredditID uuid.UUID, limit int, cursor string) ([]*models.Post, string, error) {
+	filter := bson.M{
+		"subredditid": subredditID.String(),
+		"status":      models.PostStatusPending,
+		"isdeleted":   bson.M{"$ne": true},
+	}
+	if cursor != "" {
+		var after ModQueueCursor
+		if err := DecodeCursor(cursor, &after); err != nil {
+			return nil, "", err
+		}
+		filter = bson.M{"$and": []bson.M{filter, {"$or": []bson.M{
+			{"createdat": bson.M{"$gt": after.CreatedAt}},
+			{"createdat": after.CreatedAt, "_id": bson.M{"$gt": after.ID}},
+		}}}}
+	}
+
+	// Fetch one extra row to learn whether another page exists
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdat", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(int64(limit + 1))
+	posts, err := m.findPosts(ctx, filter, opts)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get pending posts: %v", err)
+	}
+
+	nextCursor := ""
+	if len(posts) > limit {
+		posts = posts[:limit]
+		last := posts[len(posts)-1]
+		nextCursor = EncodeCursor(ModQueueCursor{CreatedAt: last.CreatedAt, ID: last.ID.String()})
+	}
+	return posts, nextCursor, nil
+}
+
+// GetPendingPost returns a post awaiting moderator review, or nil if the post isn't pending
+func (m *MongoDB) GetPendingPost(ctx context.Context, postID uuid.UUID) (*models.Post, error) {
+	filter := bson.M{"_id": postID.String(), "status": models.PostStatusPending, "isdeleted": bson.M{"$ne": true}}
+
+	var doc PostDocument
+	if err := m.Posts.FindOne(ctx, filter).Decode(&doc); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get pending post: %v", err)
+	}
+	return m.DocumentToModel(&doc)
+}
+
+// ApprovePendingPost publishes a post awaiting review, dating it to now like a newly
+// created post. A poll on it keeps its full duration. It reports false, changing
+// nothing, if the post was no longer pending.
+func (m *MongoDB) ApprovePendingPost(ctx context.Context, post *models.Post, now time.Time) (bool, error) {
+	hot := utils.HotScore(post.Karma, now)
+	set := bson.M{"status": models.PostStatusPublished, "createdat": now, "hot": hot}
+	var closesAt time.Time
+	if post.Poll != nil {
+		closesAt = post.Poll.ClosesAt.Add(now.Sub(post.CreatedAt))
+		set["poll.closesat"] = closesAt
+	}
+
+	result, err := m.Posts.UpdateOne(ctx,
+		bson.M{"_id": post.ID.String(), "status": models.PostStatusPending},
+		bson.M{"$set": set})
+	if err != nil {
+		return false, fmt.Errorf("failed to approve post: %v", err)
+	}
+	if result.ModifiedCount == 0 {
+		return false, nil
+	}
+
+	post.Status = models.PostStatusPublished
+	post.CreatedAt = now
+	post.HotScore = hot
+	if post.Poll != nil {
+		post.Poll.ClosesAt = closesAt
+	}
+	return true, nil
+}
+
+// RemovePendingPost rejects a post awaiting review, which then stays hidden from everyone.
+// It reports false, changing nothing, if the post was no longer pending.
+func (m *MongoDB) RemovePendingPost(ctx context.Context, postID uuid.UUID) (bool, error) {
+	result, err := m.Posts.UpdateOne(ctx,
+		bson.M{"_id": postID.String(), "status": models.PostStatusPending},
+		bson.M{"$set": bson.M{"status": models.PostStatusRemoved}})
+	if err != nil {
+		return false, fmt.Errorf("failed to remove post: %v", err)
+	}
+	return result.ModifiedCount > 0, nil
+}
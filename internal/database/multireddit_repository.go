@@ -0,0 +1,142 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"gator-swamp/internal/i18n"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MultiredditDocument represents a user's multireddit in MongoDB
+type MultiredditDocument struct {
+	ID           string    `bson:"_id"`
+	OwnerID      string    `bson:"ownerId"`
+	Name         string    `bson:"name"`
+	SubredditIDs []string  `bson:"subredditIds"`
+	IsPublic     bool      `bson:"isPublic"`
+	CreatedAt    time.Time `bson:"createdAt"`
+	UpdatedAt    time.Time `bson:"updatedAt"`
+}
+
+// SaveMultireddit creates or updates a multireddit
+func (m *MongoDB) SaveMultireddit(ctx context.Context, multi *models.Multireddit) error {
+	doc := MultiredditDocument{
+		ID:           multi.ID.String(),
+		OwnerID:      multi.OwnerID.String(),
+		Name:         multi.Name,
+		SubredditIDs: make([]string, len(multi.SubredditIDs)),
+		IsPublic:     multi.IsPublic,
+		CreatedAt:    multi.CreatedAt,
+		UpdatedAt:    multi.UpdatedAt,
+	}
+	for i, id := range multi.SubredditIDs {
+		doc.SubredditIDs[i] = id.String()
+	}
+
+	opts := options.Update().SetUpsert(true)
+	_, err := m.Multireddits.UpdateOne(ctx, bson.M{"_id": doc.ID}, bson.M{"$set": doc}, opts)
+	if err != nil {
+		return fmt.Errorf("failed to save multireddit: %v", err)
+	}
+	return nil
+}
+
+// GetMultireddit retrieves a multireddit by ID
+func (m *MongoDB) GetMultireddit(ctx context.Context, id uuid.UUID) (*models.Multireddit, error) {
+	var doc MultiredditDocument
+	err := m.Multireddits.FindOne(ctx, bson.M{"_id": id.String()}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrMultiredditNotFound, nil, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get multireddit: %v", err)
+	}
+	return convertMultiredditDocumentToModel(&doc)
+}
+
+// ListUserMultireddits retrieves all multireddits owned by a user
+func (m *MongoDB) ListUserMultireddits(ctx context.Context, ownerID uuid.UUID) ([]*models.Multireddit, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+	cursor, err := m.Multireddits.Find(ctx, bson.M{"ownerId": ownerID.String()}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list multireddits: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	multis := make([]*models.Multireddit, 0)
+	for cursor.Next(ctx) {
+		var doc MultiredditDocument
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode multireddit: %v", err)
+		}
+		multi, err := convertMultiredditDocumentToModel(&doc)
+		if err != nil {
+			return nil, err
+		}
+		multis = append(multis, multi)
+	}
+
+	return multis, cursor.Err()
+}
+
+// DeleteMultireddit removes a multireddit
+func (m *MongoDB) DeleteMultireddit(ctx context.Context, id uuid.UUID) error {
+	result, err := m.Multireddits.DeleteOne(ctx, bson.M{"_id": id.String()})
+	if err != nil {
+		return fmt.Errorf("failed to delete multireddit: %v", err)
+	}
+	if result.DeletedCount == 0 {
+		return utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrMultiredditNotFound, nil, nil)
+	}
+	return nil
+}
+
+// EnsureMultiredditIndexes creates required indexes for the multireddits collection
+func (m *MongoDB) EnsureMultiredditIndexes(ctx context.Context) error {
+	_, err := m.Multireddits.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "ownerId", Value: 1}, {Key: "createdAt", Value: 1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create multireddit indexes: %v", err)
+	}
+	return nil
+}
+
+// Helper function to convert MultiredditDocument to models.Multireddit
+func convertMultiredditDocumentToModel(doc *MultiredditDocument) (*models.Multireddit, error) {
+	id, err := uuid.Parse(doc.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid multireddit ID: %v", err)
+	}
+
+	ownerID, err := uuid.Parse(doc.OwnerID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid owner ID: %v", err)
+	}
+
+	subredditIDs := make([]uuid.UUID, 0, len(doc.SubredditIDs))
+	for _, idStr := range doc.SubredditIDs {
+		subredditID, err := uuid.Parse(idStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subreddit ID: %v", err)
+		}
+		subredditIDs = append(subredditIDs, subredditID)
+	}
+
+	return &models.Multireddit{
+		ID:           id,
+		OwnerID:      ownerID,
+		Name:         doc.Name,
+		SubredditIDs: subredditIDs,
+		IsPublic:     doc.IsPublic,
+		CreatedAt:    doc.CreatedAt,
+		UpdatedAt:    doc.UpdatedAt,
+	}, nil
+}
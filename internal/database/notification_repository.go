@@ -0,0 +1,164 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"gator-swamp/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NotificationDocument represents a user notification in MongoDB
+type NotificationDocument struct {
+	ID          string    `bson:"_id"`
+	RecipientID string    `bson:"recipientId"`
+	Type        string    `bson:"type"`
+	CommentID   string    `bson:"commentId"`
+	PostID      string    `bson:"postId"`
+	CreatedAt   time.Time `bson:"createdAt"`
+	Read        bool      `bson:"read"`
+}
+
+// NotificationCursor marks a position in a user's notifications, newest first
+type NotificationCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+}
+
+// SaveNotification stores a new notification
+func (m *MongoDB) SaveNotification(ctx context.Context, notification *models.Notification) error {
+	doc := NotificationDocument{
+		ID:          notification.ID.String(),
+		RecipientID: notification.RecipientID.String(),
+		Type:        notification.Type,
+		CommentID:   notification.CommentID.String(),
+		PostID:      notification.PostID.String(),
+		CreatedAt:   notification.CreatedAt,
+		Read:        notification.Read,
+	}
+
+	if _, err := m.Notifications.InsertOne(ctx, doc); err != nil {
+		return fmt.Errorf("failed to save notification: %v", err)
+	}
+	return nil
+}
+
+// GetUserNotifications returns a page of a user's notifications, newest first, along
+// with the cursor for the next page. The cursor is empty when no notifications remain.
+func (m *MongoDB) GetUserNotifications(ctx context.Context, recipientID uuid.UUID, limit int, cursor string, unreadOnly bool) ([]*models.Notification, string, error) {
+	filter := bson.M{"recipientId": recipientID.String()}
+	if unreadOnly {
+		filter["read"] = false
+	}
+	if cursor != "" {
+		var after NotificationCursor
+		if err := DecodeCursor(cursor, &after); err != nil {
+			return nil, "", err
+		}
+		filter = bson.M{"$and": []bson.M{filter, {"$or": []bson.M{
+			{"createdAt": bson.M{"$lt": after.CreatedAt}},
+			{"createdAt": after.CreatedAt, "_id": bson.M{"$lt": after.ID}},
+		}}}}
+	}
+
+	// Fetch one extra row to learn whether another page exists
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit + 1))
+
+	dbCursor, err := m.Notifications.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get notifications: %v", err)
+	}
+	defer dbCursor.Close(ctx)
+
+	notifications := make([]*models.Notification, 0)
+	for dbCursor.Next(ctx) {
+		var doc NotificationDocument
+		if err := dbCursor.Decode(&doc); err != nil {
+			return nil, "", fmt.Errorf("failed to decode notification: %v", err)
+		}
+		notification, err := convertNotificationDocumentToModel(&doc)
+		if err != nil {
+			return nil, "", err
+		}
+		notifications = append(notifications, notification)
+	}
+	if err := dbCursor.Err(); err != nil {
+		return nil, "", fmt.Errorf("cursor iteration failed: %v", err)
+	}
+
+	nextCursor := ""
+	if len(notifications) > limit {
+		notifications = notifications[:limit]
+		last := notifications[len(notifications)-1]
+		nextCursor = EncodeCursor(NotificationCursor{CreatedAt: last.CreatedAt, ID: last.ID.String()})
+	}
+
+	return notifications, nextCursor, nil
+}
+
+// MarkNotificationsRead marks a user's notifications as read and returns how many changed.
+// With no IDs every unread notification of the user is marked. IDs belonging to other
+// users are ignored.
+func (m *MongoDB) MarkNotificationsRead(ctx context.Context, recipientID uuid.UUID, notificationIDs []uuid.UUID) (int64, error) {
+	filter := bson.M{"recipientId": recipientID.String(), "read": false}
+	if len(notificationIDs) > 0 {
+		ids := make([]string, len(notificationIDs))
+		for i, id := range notificationIDs {
+			ids[i] = id.String()
+		}
+		filter["_id"] = bson.M{"$in": ids}
+	}
+
+	result, err := m.Notifications.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"read": true}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %v", err)
+	}
+	return result.ModifiedCount, nil
+}
+
+// EnsureNotificationIndexes creates required indexes for the notifications collection
+func (m *MongoDB) EnsureNotificationIndexes(ctx context.Context) error {
+	_, err := m.Notifications.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "recipientId", Value: 1}, {Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create notification indexes: %v", err)
+	}
+	return nil
+}
+
+// Helper function to convert NotificationDocument to models.Notification
+func convertNotificationDocumentToModel(doc *NotificationDocument) (*models.Notification, error) {
+	id, err := uuid.Parse(doc.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid notification ID: %v", err)
+	}
+	recipientID, err := uuid.Parse(doc.RecipientID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient ID: %v", err)
+	}
+	commentID, err := uuid.Parse(doc.CommentID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid comment ID: %v", err)
+	}
+	postID, err := uuid.Parse(doc.PostID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid post ID: %v", err)
+	}
+
+	return &models.Notification{
+		ID:          id,
+		RecipientID: recipientID,
+		Type:        doc.Type,
+		CommentID:   commentID,
+		PostID:      postID,
+		CreatedAt:   doc.CreatedAt,
+		Read:        doc.Read,
+	}, nil
+}
@@ -0,0 +1,99 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"gator-swamp/internal/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PasswordResetDocument is a single-use password reset token, stored by its hash.
+// MongoDB removes it once it expires.
+type PasswordResetDocument struct {
+	TokenHash string    `bson:"_id"`
+	UserID    string    `bson:"userId"`
+	CreatedAt time.Time `bson:"createdAt"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
+
+// CreatePasswordReset stores a reset token for a user that stays valid for ttl. Tokens
+// the user was sent earlier stop working, so only the latest email can be used.
+func (m *MongoDB) CreatePasswordReset(ctx context.Context, userID uuid.UUID, tokenHash string, now time.Time, ttl time.Duration) error {
+	if _, err := m.PasswordResets.DeleteMany(ctx, bson.M{"userId": userID.String()}); err != nil {
+		return fmt.Errorf("failed to replace password reset: %v", err)
+	}
+
+	doc := PasswordResetDocument{
+		TokenHash: tokenHash,
+		UserID:    userID.String(),
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+	if _, err := m.PasswordResets.InsertOne(ctx, doc); err != nil {
+		return fmt.Errorf("failed to create password reset: %v", err)
+	}
+	return nil
+}
+
+// LastPasswordResetSentAt returns when a user's outstanding reset token was created, or
+// the zero time if they have none
+func (m *MongoDB) LastPasswordResetSentAt(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	var doc PasswordResetDocument
+	opts := options.FindOne().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetProjection(bson.M{"createdAt": 1})
+	err := m.PasswordResets.FindOne(ctx, bson.M{"userId": userID.String()}, opts).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get password reset: %v", err)
+	}
+	return doc.CreatedAt, nil
+}
+
+// ConsumePasswordReset deletes the reset token stored under tokenHash and returns the
+// user it was issued to. Unknown, already used and expired tokens fail with
+// ErrInvalidToken.
+func (m *MongoDB) ConsumePasswordReset(ctx context.Context, tokenHash string, now time.Time) (uuid.UUID, error) {
+	var doc PasswordResetDocument
+	err := m.PasswordResets.FindOneAndDelete(ctx, bson.M{"_id": tokenHash}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return uuid.Nil, utils.NewAppError(utils.ErrInvalidToken, "Password reset token is invalid or has already been used", nil)
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get password reset: %v", err)
+	}
+
+	// The TTL monitor only runs once a minute, so expired tokens can still be found
+	if !doc.ExpiresAt.After(now) {
+		return uuid.Nil, utils.NewAppError(utils.ErrInvalidToken, "Password reset token has expired", nil)
+	}
+
+	userID, err := uuid.Parse(doc.UserID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid user ID in password reset: %v", err)
+	}
+	return userID, nil
+}
+
+// EnsurePasswordResetIndexes creates the index used to replace a user's tokens and the
+// TTL index that removes tokens once they expire
+func (m *MongoDB) EnsurePasswordResetIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+	if _, err := m.PasswordResets.Indexes().CreateMany(ctx, indexes); err != nil {
+		return fmt.Errorf("failed to create password reset indexes: %v", err)
+	}
+	return nil
+}
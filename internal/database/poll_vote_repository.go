@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"gator-swamp/internal/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PollVoteDocument records the option a user chose in a poll
+type PollVoteDocument struct {
+	ID      string    `bson:"_id"`
+	PostID  string    `bson:"postId"`
+	UserID  string    `bson:"userId"`
+	Option  int       `bson:"option"`
+	VotedAt time.Time `bson:"votedAt"`
+}
+
+// RecordPollVote records a user's choice in a poll and adds it to the poll's counts.
+// A user can vote once per poll; a second vote fails with ErrDuplicate.
+func (m *MongoDB) RecordPollVote(ctx context.Context, postID, userID uuid.UUID, option int, votedAt time.Time) error {
+	doc := PollVoteDocument{
+		ID:      uuid.New().String(),
+		PostID:  postID.String(),
+		UserID:  userID.String(),
+		Option:  option,
+		VotedAt: votedAt,
+	}
+	if _, err := m.PollVotes.InsertOne(ctx, doc); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return utils.NewAppError(utils.ErrDuplicate, "Already voted in this poll", nil)
+		}
+		return fmt.Errorf("failed to record poll vote: %v", err)
+	}
+
+	update := bson.M{"$inc": bson.M{fmt.Sprintf("poll.counts.%d", option): 1}}
+	if _, err := m.Posts.UpdateOne(ctx, bson.M{"_id": postID.String()}, update); err != nil {
+		return fmt.Errorf("failed to count poll vote: %v", err)
+	}
+	return nil
+}
+
+// GetPollChoices returns the options a user chose in the given polls, by post ID.
+// Polls the user hasn't voted in are left out.
+func (m *MongoDB) GetPollChoices(ctx context.Context, userID uuid.UUID, postIDs []string) (map[string]int, error) {
+	choices := make(map[string]int)
+	if len(postIDs) == 0 {
+		return choices, nil
+	}
+
+	filter := bson.M{"userId": userID.String(), "postId": bson.M{"$in": postIDs}}
+	cursor, err := m.PollVotes.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get poll choices: %v", err)
+	}
+	var docs []PollVoteDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode poll choices: %v", err)
+	}
+	for _, doc := range docs {
+		choices[doc.PostID] = doc.Option
+	}
+	return choices, nil
+}
+
+// EnsurePollVoteIndexes creates the index that limits users to one vote per poll
+func (m *MongoDB) EnsurePollVoteIndexes(ctx context.Context) error {
+	return createUniqueIndex(ctx, m.PollVotes, "poll vote", mongo.IndexModel{
+		Keys:    bson.D{{Key: "postId", Value: 1}, {Key: "userId", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+}
@@ -4,6 +4,7 @@ package database
 import (
 	"context"
 	"fmt"
+	"gator-swamp/internal/i18n"
 	"gator-swamp/internal/models"
 	"gator-swamp/internal/utils"
 	"log"
@@ -17,34 +18,117 @@ import (
 
 // PostDocument represents the MongoDB schema for a post.
 type PostDocument struct {
-	ID             string    `bson:"_id"`
-	Title          string    `bson:"title"`
-	Content        string    `bson:"content"`
-	AuthorID       string    `bson:"authorid"`
-	AuthorUsername string    `bson:"authorusername"`
-	SubredditID    string    `bson:"subredditid"`
-	SubredditName  string    `bson:"subredditname"`
-	CreatedAt      time.Time `bson:"createdat"`
-	Upvotes        int       `bson:"upvotes"`
-	Downvotes      int       `bson:"downvotes"`
-	Karma          int       `bson:"karma"`
+	ID             string     `bson:"_id"`
+	Title          string     `bson:"title"`
+	Slug           string     `bson:"slug,omitempty"`
+	OldSlugs       []string   `bson:"oldslugs,omitempty"`
+	Content        string     `bson:"content"`
+	URL            string     `bson:"url,omitempty"`
+	NormalizedURL  string     `bson:"normalizedurl,omitempty"`
+	PostType       string     `bson:"posttype,omitempty"` // Missing on posts from before link posts, which are text posts
+	Status         string     `bson:"status,omitempty"`   // Missing on posts from before scheduling, which are published
+	ScheduledAt    *time.Time `bson:"scheduledat,omitempty"`
+	MediaURLs      []string   `bson:"mediaurls,omitempty"`
+	MediaType      string     `bson:"mediatype,omitempty"`
+	AuthorID       string     `bson:"authorid"`
+	AuthorUsername string     `bson:"authorusername"`
+	SubredditID    string     `bson:"subredditid"`
+	SubredditName  string     `bson:"subredditname"`
+	FlairID        string     `bson:"flairid,omitempty"`
+	FlairText      string     `bson:"flairtext,omitempty"`
+	CreatedAt      time.Time  `bson:"createdat"`
+	EditedAt       *time.Time `bson:"editedat,omitempty"`
+	Upvotes        int        `bson:"upvotes"`
+	Downvotes      int        `bson:"downvotes"`
+	Karma          int        `bson:"karma"`
+	HotScore       float64    `bson:"hot"`         // See utils.HotScore
+	Controversy    float64    `bson:"controversy"` // See utils.PostControversyScore
+	ViewCount      int        `bson:"viewcount"`
+	ShareCount     int        `bson:"sharecount,omitempty"`
+	IsPinned       bool       `bson:"ispinned,omitempty"`
+	PinnedAt       *time.Time `bson:"pinnedat,omitempty"`
+	IsArchived     bool       `bson:"isarchived,omitempty"`
+	IsDeleted      bool       `bson:"isdeleted"`
+	DeletedAt      *time.Time `bson:"deletedat,omitempty"`
+	DeletedBy      string     `bson:"deletedby,omitempty"`
+
+	FlaggedForReview bool `bson:"flaggedforreview,omitempty"`
+
+	CrosspostParentID string `bson:"crosspostparentid,omitempty"`
+
+	Poll *PollDocument `bson:"poll,omitempty"`
+
+	SubredditIconURL string `bson:"subredditiconurl,omitempty"`
+
+	Automod *AutomodRuleDB `bson:"automod,omitempty"`
+}
+
+// PollDocument is the poll of a poll post as stored in its post document
+type PollDocument struct {
+	Options           []string  `bson:"options"`
+	ClosesAt          time.Time `bson:"closesat"`
+	ResultsAfterClose bool      `bson:"resultsafterclose,omitempty"`
+	Counts            []int     `bson:"counts"` // Votes per option, kept in step with poll_votes
 }
 
 // ModelToDocument converts a Post model to a MongoDB document.
 func (m *MongoDB) ModelToDocument(post *models.Post) *PostDocument {
-	return &PostDocument{
+	doc := &PostDocument{
 		ID:             post.ID.String(),
 		Title:          post.Title,
+		Slug:           post.Slug,
+		OldSlugs:       post.OldSlugs,
 		Content:        post.Content,
+		URL:            post.URL,
+		NormalizedURL:  post.NormalizedURL,
+		PostType:       post.PostType,
+		Status:         post.Status,
+		ScheduledAt:    post.ScheduledAt,
+		MediaURLs:      post.MediaURLs,
+		MediaType:      post.MediaType,
 		AuthorID:       post.AuthorID.String(),
 		AuthorUsername: post.AuthorUsername,
 		SubredditID:    post.SubredditID.String(),
 		SubredditName:  post.SubredditName,
+		FlairText:      post.FlairText,
 		CreatedAt:      post.CreatedAt,
+		EditedAt:       post.EditedAt,
 		Upvotes:        post.Upvotes,
 		Downvotes:      post.Downvotes,
 		Karma:          post.Karma,
+		HotScore:       utils.HotScore(post.Karma, post.CreatedAt),
+		Controversy:    post.ControversyScore,
+		ViewCount:      post.ViewCount,
+		ShareCount:     post.ShareCount,
+		IsPinned:       post.IsPinned,
+		PinnedAt:       post.PinnedAt,
+		IsArchived:     post.IsArchived,
+		IsDeleted:      post.IsDeleted,
+		DeletedAt:      post.DeletedAt,
+
+		FlaggedForReview: post.FlaggedForReview,
+		SubredditIconURL: post.SubredditIconURL,
+
+		Automod: automodRuleToDB(post.Automod),
+	}
+	if post.FlairID != nil {
+		doc.FlairID = post.FlairID.String()
+	}
+	if post.CrosspostParentID != nil {
+		doc.CrosspostParentID = post.CrosspostParentID.String()
 	}
+	if post.Poll != nil {
+		doc.Poll = &PollDocument{
+			Options:           post.Poll.Options,
+			ClosesAt:          post.Poll.ClosesAt,
+			ResultsAfterClose: post.Poll.ResultsAfterClose,
+			Counts:            post.Poll.Tally,
+		}
+	}
+	if post.DeletedBy != nil {
+		doc.DeletedBy = post.DeletedBy.String()
+	}
+	return doc
 }
 
 // DocumentToModel converts a MongoDB document to a Post model.
@@ -64,19 +148,82 @@ func (m *MongoDB) DocumentToModel(doc *PostDocument) (*models.Post, error) {
 		return nil, fmt.Errorf("invalid subreddit ID: %v", err)
 	}
 
-	return &models.Post{
+	post := &models.Post{
 		ID:             id,
 		Title:          doc.Title,
+		Slug:           doc.Slug,
+		OldSlugs:       doc.OldSlugs,
 		Content:        doc.Content,
+		URL:            doc.URL,
+		NormalizedURL:  doc.NormalizedURL,
+		PostType:       doc.PostType,
+		Status:         doc.Status,
+		ScheduledAt:    doc.ScheduledAt,
+		MediaURLs:      doc.MediaURLs,
+		MediaType:      doc.MediaType,
 		AuthorID:       authorID,
 		AuthorUsername: doc.AuthorUsername,
 		SubredditID:    subredditID,
 		SubredditName:  doc.SubredditName,
+		FlairText:      doc.FlairText,
 		CreatedAt:      doc.CreatedAt,
+		EditedAt:       doc.EditedAt,
 		Upvotes:        doc.Upvotes,
 		Downvotes:      doc.Downvotes,
 		Karma:          doc.Karma,
-	}, nil
+		HotScore:       doc.HotScore,
+		ViewCount:      doc.ViewCount,
+		ShareCount:     doc.ShareCount,
+		IsPinned:       doc.IsPinned,
+		PinnedAt:       doc.PinnedAt,
+		IsArchived:     doc.IsArchived,
+		IsDeleted:      doc.IsDeleted,
+		DeletedAt:      doc.DeletedAt,
+
+		FlaggedForReview: doc.FlaggedForReview,
+		ControversyScore: doc.Controversy,
+		SubredditIconURL: doc.SubredditIconURL,
+
+		Automod: automodRuleFromDB(doc.Automod),
+	}
+	if post.PostType == "" {
+		post.PostType = models.PostTypeText
+	}
+	if post.Status == "" {
+		post.Status = models.PostStatusPublished
+	}
+	if doc.FlairID != "" {
+		flairID, err := uuid.Parse(doc.FlairID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid flair ID: %v", err)
+		}
+		post.FlairID = &flairID
+	}
+	if doc.CrosspostParentID != "" {
+		parentID, err := uuid.Parse(doc.CrosspostParentID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid crosspost parent ID: %v", err)
+		}
+		post.CrosspostParentID = &parentID
+	}
+	if doc.Poll != nil {
+		tally := make([]int, len(doc.Poll.Options))
+		copy(tally, doc.Poll.Counts)
+		post.Poll = &models.Poll{
+			Options:           doc.Poll.Options,
+			ClosesAt:          doc.Poll.ClosesAt,
+			ResultsAfterClose: doc.Poll.ResultsAfterClose,
+			Tally:             tally,
+		}
+	}
+	if doc.DeletedBy != "" {
+		deletedBy, err := uuid.Parse(doc.DeletedBy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deleted-by ID: %v", err)
+		}
+		post.DeletedBy = &deletedBy
+	}
+	return post, nil
 }
 
 // SavePost creates or updates a post in MongoDB.
@@ -95,10 +242,12 @@ func (m *MongoDB) SavePost(ctx context.Context, post *models.Post) error {
 func (m *MongoDB) GetPost(ctx context.Context, id uuid.UUID) (*models.Post, error) {
 	var doc PostDocument
 
-	// Find the post by its ID.
-	err := m.Posts.FindOne(ctx, bson.M{"_id": id.String()}).Decode(&doc)
+	// Find the post by its ID. Scheduled, pending and removed posts don't exist as far as
+	// readers are concerned.
+	filter := bson.M{"_id": id.String(), "status": bson.M{"$nin": UnlistedPostStatuses}}
+	err := m.Posts.FindOne(ctx, filter).Decode(&doc)
 	if err == mongo.ErrNoDocuments {
-		return nil, utils.NewAppError(utils.ErrNotFound, "Post not found", err)
+		return nil, utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, err)
 	}
 	if err != nil {
 		return nil, err
@@ -107,42 +256,265 @@ func (m *MongoDB) GetPost(ctx context.Context, id uuid.UUID) (*models.Post, erro
 	return m.DocumentToModel(&doc)
 }
 
-// GetSubredditPosts retrieves all posts for a given subreddit ID.
-func (m *MongoDB) GetSubredditPosts(ctx context.Context, subredditID uuid.UUID) ([]*models.Post, error) {
-	log.Printf("Querying MongoDB for posts in subreddit: %s", subredditID.String())
+// GetPostsByIDs retrieves the posts with the given IDs in one query, in no particular
+// order. IDs without a post, and posts that aren't published, are skipped.
+func (m *MongoDB) GetPostsByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.Post, error) {
+	idStrings := make([]string, len(ids))
+	for i, id := range ids {
+		idStrings[i] = id.String()
+	}
+	filter := bson.M{"_id": bson.M{"$in": idStrings}, "status": bson.M{"$nin": UnlistedPostStatuses}}
+	return m.findPosts(ctx, filter, options.Find())
+}
 
-	cursor, err := m.Posts.Find(ctx, bson.M{"subredditid": subredditID.String()})
+// EditPost replaces the title and/or content of a post that isn't deleted and
+// returns the updated post. Nil fields are left unchanged. A non-empty slug replaces
+// the post's slug, with oldSlugs as the full list of its earlier ones.
+func (m *MongoDB) EditPost(ctx context.Context, postID uuid.UUID, title, content *string, slug string, oldSlugs []string, editedAt time.Time) (*models.Post, error) {
+	set := bson.M{"editedat": editedAt}
+	if title != nil {
+		set["title"] = *title
+	}
+	if slug != "" {
+		set["slug"] = slug
+		set["oldslugs"] = oldSlugs
+	}
+	if content != nil {
+		set["content"] = *content
+	}
+
+	filter := bson.M{"_id": postID.String(), "isdeleted": bson.M{"$ne": true}}
+	result, err := m.Posts.UpdateOne(ctx, filter, bson.M{"$set": set})
 	if err != nil {
-		return nil, fmt.Errorf("database query failed: %v", err)
+		return nil, fmt.Errorf("failed to edit post: %v", err)
 	}
-	defer cursor.Close(ctx)
+	if result.MatchedCount == 0 {
+		return nil, utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil)
+	}
+	return m.GetPost(ctx, postID)
+}
 
-	var posts []*models.Post
-	for cursor.Next(ctx) {
-		var doc PostDocument
-		if err := cursor.Decode(&doc); err != nil {
-			log.Printf("Error decoding post document: %v", err)
-			continue
+// SetPostPinned pins or unpins a post that isn't deleted and returns the updated post
+func (m *MongoDB) SetPostPinned(ctx context.Context, postID uuid.UUID, pinned bool, pinnedAt time.Time) (*models.Post, error) {
+	update := bson.M{"$set": bson.M{"ispinned": true, "pinnedat": pinnedAt}}
+	if !pinned {
+		update = bson.M{"$unset": bson.M{"ispinned": "", "pinnedat": ""}}
+	}
+
+	filter := bson.M{"_id": postID.String(), "isdeleted": bson.M{"$ne": true}}
+	result, err := m.Posts.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pin post: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return nil, utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil)
+	}
+	return m.GetPost(ctx, postID)
+}
+
+// AttachCrosspostOrigins fills in the current details of the originals of any crossposts
+// among posts, with one query for all of them
+func (m *MongoDB) AttachCrosspostOrigins(ctx context.Context, posts []*models.Post) error {
+	var parentIDs []string
+	for _, post := range posts {
+		if post.CrosspostParentID != nil {
+			parentIDs = append(parentIDs, post.CrosspostParentID.String())
 		}
+	}
+	if len(parentIDs) == 0 {
+		return nil
+	}
 
-		post, err := m.DocumentToModel(&doc)
-		if err != nil {
-			log.Printf("Error converting document to model: %v", err)
+	parents, err := m.getPostsByIDs(ctx, parentIDs)
+	if err != nil {
+		return err
+	}
+	for _, post := range posts {
+		if post.CrosspostParentID == nil {
 			continue
 		}
-		posts = append(posts, post)
+		origin := &models.CrosspostOrigin{PostID: *post.CrosspostParentID, IsDeleted: true}
+		if parent, ok := parents[post.CrosspostParentID.String()]; ok && !parent.IsDeleted {
+			origin = &models.CrosspostOrigin{
+				PostID:         parent.ID,
+				Title:          parent.Title,
+				SubredditID:    parent.SubredditID,
+				SubredditName:  parent.SubredditName,
+				AuthorID:       parent.AuthorID,
+				AuthorUsername: parent.AuthorUsername,
+				Karma:          parent.Karma,
+			}
+		}
+		post.CrosspostParent = origin
 	}
+	return nil
+}
 
-	if err := cursor.Err(); err != nil {
-		return nil, fmt.Errorf("cursor iteration failed: %v", err)
+// ArchivePostsBatch flags up to limit published posts created before cutoff as archived
+// and returns how many it flagged. Callers repeat it until it flags fewer than limit.
+func (m *MongoDB) ArchivePostsBatch(ctx context.Context, cutoff time.Time, limit int) (int, error) {
+	filter := bson.M{
+		"createdat":  bson.M{"$lt": cutoff},
+		"isarchived": bson.M{"$ne": true},
+		"status":     bson.M{"$nin": UnlistedPostStatuses},
+	}
+	opts := options.Find().SetProjection(bson.M{"_id": 1}).SetLimit(int64(limit))
+	cursor, err := m.Posts.Find(ctx, filter, opts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find posts to archive: %v", err)
+	}
+	var docs []struct {
+		ID string `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return 0, fmt.Errorf("failed to decode posts to archive: %v", err)
+	}
+	if len(docs) == 0 {
+		return 0, nil
 	}
 
-	log.Printf("Found %d posts in subreddit %s", len(posts), subredditID)
-	return posts, nil
+	ids := make([]string, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.ID
+	}
+	result, err := m.Posts.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}}, bson.M{"$set": bson.M{"isarchived": true}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to archive posts: %v", err)
+	}
+	return int(result.ModifiedCount), nil
+}
+
+// IncrementPostViews adds view counts to posts in a single bulk write
+func (m *MongoDB) IncrementPostViews(ctx context.Context, views map[uuid.UUID]int) error {
+	if len(views) == 0 {
+		return nil
+	}
+
+	writes := make([]mongo.WriteModel, 0, len(views))
+	for postID, count := range views {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": postID.String()}).
+			SetUpdate(bson.M{"$inc": bson.M{"viewcount": count}}))
+	}
+	if _, err := m.Posts.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+		return fmt.Errorf("failed to record post views: %v", err)
+	}
+	return nil
 }
 
-// UpdatePostVotes modifies the vote counts and karma for a post.
-func (m *MongoDB) UpdatePostVotes(ctx context.Context, postID uuid.UUID, upvoteDelta, downvoteDelta int) error {
+// IncrementPostShares adds share counts to posts in a single bulk write
+func (m *MongoDB) IncrementPostShares(ctx context.Context, shares map[uuid.UUID]int) error {
+	if len(shares) == 0 {
+		return nil
+	}
+
+	writes := make([]mongo.WriteModel, 0, len(shares))
+	for postID, count := range shares {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": postID.String()}).
+			SetUpdate(bson.M{"$inc": bson.M{"sharecount": count}}))
+	}
+	if _, err := m.Posts.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+		return fmt.Errorf("failed to record post shares: %v", err)
+	}
+	return nil
+}
+
+// SetPostFlair sets the flair on a post that isn't deleted, or clears it when flair is
+// nil, and returns the updated post
+func (m *MongoDB) SetPostFlair(ctx context.Context, postID uuid.UUID, flair *models.PostFlair) (*models.Post, error) {
+	update := bson.M{"$unset": bson.M{"flairid": "", "flairtext": ""}}
+	if flair != nil {
+		update = bson.M{"$set": bson.M{"flairid": flair.ID.String(), "flairtext": flair.Text}}
+	}
+
+	filter := bson.M{"_id": postID.String(), "isdeleted": bson.M{"$ne": true}}
+	result, err := m.Posts.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set post flair: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return nil, utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil)
+	}
+	return m.GetPost(ctx, postID)
+}
+
+// CountPinnedPosts counts a subreddit's pinned posts that aren't deleted
+func (m *MongoDB) CountPinnedPosts(ctx context.Context, subredditID uuid.UUID) (int64, error) {
+	filter := bson.M{"subredditid": subredditID.String(), "ispinned": true, "isdeleted": bson.M{"$ne": true}}
+	count, err := m.Posts.CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count pinned posts: %v", err)
+	}
+	return count, nil
+}
+
+// FindDuplicateLink returns the newest post in a subreddit created since the given time
+// that links to normalizedURL and isn't deleted, or nil if there is none
+func (m *MongoDB) FindDuplicateLink(ctx context.Context, subredditID uuid.UUID, normalizedURL string, since time.Time) (*models.Post, error) {
+	filter := bson.M{
+		"subredditid":   subredditID.String(),
+		"normalizedurl": normalizedURL,
+		"createdat":     bson.M{"$gte": since},
+		"isdeleted":     bson.M{"$ne": true},
+		"status":        bson.M{"$nin": UnlistedPostStatuses},
+	}
+	opts := options.FindOne().SetSort(bson.D{{Key: "createdat", Value: -1}})
+
+	var doc PostDocument
+	if err := m.Posts.FindOne(ctx, filter, opts).Decode(&doc); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to look up duplicate link: %v", err)
+	}
+	return m.DocumentToModel(&doc)
+}
+
+// GetRelatedPosts finds up to limit posts in the same subreddit as post whose titles
+// share the most terms with its title, best matches first. Without any matches it falls
+// back to the subreddit's newest posts. The post itself, its crossposts, and deleted or
+// scheduled posts are left out.
+func (m *MongoDB) GetRelatedPosts(ctx context.Context, post *models.Post, limit int) ([]*models.Post, error) {
+	filter := bson.M{
+		"subredditid":       post.SubredditID.String(),
+		"_id":               bson.M{"$ne": post.ID.String()},
+		"crosspostparentid": bson.M{"$ne": post.ID.String()},
+		"isdeleted":         bson.M{"$ne": true},
+		"status":            bson.M{"$nin": UnlistedPostStatuses},
+	}
+
+	textFilter := bson.M{"$text": bson.M{"$search": post.Title}}
+	for key, value := range filter {
+		textFilter[key] = value
+	}
+	relevance := bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetProjection(bson.M{"textScore": relevance}).
+		SetSort(bson.D{{Key: "textScore", Value: relevance}, {Key: "createdat", Value: -1}}).
+		SetLimit(int64(limit))
+	posts, err := m.findPosts(ctx, textFilter, opts)
+	if err != nil || len(posts) > 0 {
+		return posts, err
+	}
+
+	opts = options.Find().SetSort(bson.D{{Key: "createdat", Value: -1}}).SetLimit(int64(limit))
+	return m.findPosts(ctx, filter, opts)
+}
+
+// GetSubredditPosts retrieves a page of a subreddit's posts in the given sort order,
+// starting after the cursor if supplied, along with the cursor for the next page. The
+// query's subreddits are replaced by subredditID, and pinned posts lead the first page.
+func (m *MongoDB) GetSubredditPosts(ctx context.Context, subredditID uuid.UUID, query FeedQuery) ([]*models.Post, string, error) {
+	log.Printf("Querying MongoDB for %s posts in subreddit: %s", query.Sort, subredditID.String())
+
+	query.SubredditIDs = []string{subredditID.String()}
+	query.PinnedFirst = true
+	return m.GetFeedPosts(ctx, query)
+}
+
+// UpdatePostVotes modifies the vote counts and karma for a post and rescores it for the hot sort.
+func (m *MongoDB) UpdatePostVotes(ctx context.Context, postID uuid.UUID, upvoteDelta, downvoteDelta, minControversialVotes int) error {
 	filter := bson.M{"_id": postID.String()}
 	update := bson.M{
 		"$inc": bson.M{
@@ -152,18 +524,242 @@ func (m *MongoDB) UpdatePostVotes(ctx context.Context, postID uuid.UUID, upvoteD
 		},
 	}
 
-	result, err := m.Posts.UpdateOne(ctx, filter, update)
+	opts := options.FindOneAndUpdate().
+		SetReturnDocument(options.After).
+		SetProjection(bson.M{"upvotes": 1, "downvotes": 1, "karma": 1, "createdat": 1})
+	var doc PostDocument
+	err := m.Posts.FindOneAndUpdate(ctx, filter, update, opts).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil)
+	}
 	if err != nil {
 		return err
 	}
-	if result.MatchedCount == 0 {
-		return utils.NewAppError(utils.ErrNotFound, "Post not found", nil)
+
+	_, err = m.Posts.UpdateOne(ctx, filter, bson.M{"$set": bson.M{
+		"hot":         utils.HotScore(doc.Karma, doc.CreatedAt),
+		"controversy": utils.PostControversyScore(doc.Upvotes, doc.Downvotes, minControversialVotes),
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to update post scores: %w", err)
+	}
+	return nil
+}
+
+// RefreshHotScores recomputes the hot score of every live post created since the given
+// time from its current karma, and returns how many posts were rescored
+func (m *MongoDB) RefreshHotScores(ctx context.Context, since time.Time) (int, error) {
+	count, err := m.rescorePosts(ctx, bson.M{"createdat": bson.M{"$gte": since}, "isdeleted": bson.M{"$ne": true}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to refresh hot scores: %v", err)
+	}
+	return count, nil
+}
+
+// rescorePosts recomputes the hot score of the posts matching filter in one bulk write
+func (m *MongoDB) rescorePosts(ctx context.Context, filter bson.M) (int, error) {
+	cursor, err := m.Posts.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1, "karma": 1, "createdat": 1}))
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var writes []mongo.WriteModel
+	for cursor.Next(ctx) {
+		var doc PostDocument
+		if err := cursor.Decode(&doc); err != nil {
+			return 0, err
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": doc.ID}).
+			SetUpdate(bson.M{"$set": bson.M{"hot": utils.HotScore(doc.Karma, doc.CreatedAt)}}))
+	}
+	if err := cursor.Err(); err != nil {
+		return 0, err
+	}
+
+	if len(writes) == 0 {
+		return 0, nil
+	}
+	if _, err := m.Posts.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+		return 0, err
+	}
+	return len(writes), nil
+}
+
+// RescorePostControversy recomputes the controversy score of every post with both
+// upvotes and downvotes, the only posts that can score above zero, and of posts stored
+// before the controversial sort existed. Scores depend on minControversialVotes, so this
+// runs on startup in case it changed.
+func (m *MongoDB) RescorePostControversy(ctx context.Context, minControversialVotes int) error {
+	filter := bson.M{"$or": []bson.M{
+		{"controversy": bson.M{"$exists": false}},
+		{"upvotes": bson.M{"$gt": 0}, "downvotes": bson.M{"$gt": 0}},
+	}}
+	opts := options.Find().SetProjection(bson.M{"_id": 1, "upvotes": 1, "downvotes": 1, "controversy": 1})
+	cursor, err := m.Posts.Find(ctx, filter, opts)
+	if err != nil {
+		return fmt.Errorf("failed to find posts to rescore: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var writes []mongo.WriteModel
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID          string   `bson:"_id"`
+			Upvotes     int      `bson:"upvotes"`
+			Downvotes   int      `bson:"downvotes"`
+			Controversy *float64 `bson:"controversy"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return fmt.Errorf("failed to decode post: %v", err)
+		}
+		score := utils.PostControversyScore(doc.Upvotes, doc.Downvotes, minControversialVotes)
+		if doc.Controversy != nil && *doc.Controversy == score {
+			continue
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": doc.ID}).
+			SetUpdate(bson.M{"$set": bson.M{"controversy": score}}))
+	}
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("failed to rescore post controversy: %v", err)
+	}
+
+	if len(writes) == 0 {
+		return nil
+	}
+	if _, err := m.Posts.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+		return fmt.Errorf("failed to rescore post controversy: %v", err)
+	}
+	return nil
+}
+
+// backfillPostHotScores scores posts stored before the hot sort existed
+func (m *MongoDB) backfillPostHotScores(ctx context.Context) error {
+	if _, err := m.rescorePosts(ctx, bson.M{"hot": bson.M{"$exists": false}}); err != nil {
+		return fmt.Errorf("failed to backfill post hot scores: %v", err)
+	}
+	return nil
+}
+
+// EnsurePostIndexes creates the indexes behind the subreddit listing sorts
+func (m *MongoDB) EnsurePostIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "subredditid", Value: 1}, {Key: "hot", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "subredditid", Value: 1}, {Key: "createdat", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "subredditid", Value: 1}, {Key: "karma", Value: -1}, {Key: "createdat", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "subredditid", Value: 1}, {Key: "ispinned", Value: 1}, {Key: "pinnedat", Value: -1}},
+		},
+		{
+			// Controversial listings, in a subreddit and across all of them
+			Keys: bson.D{{Key: "subredditid", Value: 1}, {Key: "controversy", Value: -1}, {Key: "createdat", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "controversy", Value: -1}, {Key: "createdat", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			// Top listings over a time window
+			Keys: bson.D{{Key: "subredditid", Value: 1}, {Key: "createdat", Value: -1}, {Key: "karma", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "createdat", Value: -1}, {Key: "karma", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "authorid", Value: 1}, {Key: "createdat", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			// Publishing due scheduled posts
+			Keys:    bson.D{{Key: "scheduledat", Value: 1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"status": models.PostStatusScheduled}),
+		},
+		{
+			// Moderator review queues
+			Keys:    bson.D{{Key: "subredditid", Value: 1}, {Key: "createdat", Value: 1}, {Key: "_id", Value: 1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"status": models.PostStatusPending}),
+		},
+		{
+			// Repost detection for link posts
+			Keys: bson.D{{Key: "subredditid", Value: 1}, {Key: "normalizedurl", Value: 1}, {Key: "createdat", Value: -1}},
+		},
+		{
+			// Related posts
+			Keys: bson.D{{Key: "subredditid", Value: 1}, {Key: "title", Value: "text"}},
+		},
+	}
+	if _, err := m.Posts.Indexes().CreateMany(ctx, indexes); err != nil {
+		return fmt.Errorf("failed to create post indexes: %v", err)
 	}
 	return nil
 }
 
-// GetUserFeedPosts retrieves a user's feed posts, sorted by karma and creation date.
-func (m *MongoDB) GetUserFeedPosts(ctx context.Context, userID uuid.UUID, limit int) ([]*models.Post, error) {
+// Sort modes supported by post and comment listings
+const (
+	SortNew           = "new"
+	SortTop           = "top"
+	SortHot           = "hot" // Posts only
+	SortOld           = "old" // Comments only
+	SortControversial = "controversial"
+	SortBest          = "best" // Comments only
+)
+
+// Time windows for the top sort of post listings
+const (
+	TopWindowHour  = "hour"
+	TopWindowDay   = "day"
+	TopWindowWeek  = "week"
+	TopWindowMonth = "month"
+	TopWindowYear  = "year"
+	TopWindowAll   = "all"
+)
+
+// TopWindowSince returns the earliest creation time of posts in a top listing over the
+// given window, ending at now. Unknown windows are treated as a day, and the all-time
+// window returns the zero time.
+func TopWindowSince(window string, now time.Time) time.Time {
+	now = now.UTC()
+	switch window {
+	case TopWindowHour:
+		return now.Add(-time.Hour)
+	case TopWindowWeek:
+		return now.AddDate(0, 0, -7)
+	case TopWindowMonth:
+		return now.AddDate(0, -1, 0)
+	case TopWindowYear:
+		return now.AddDate(-1, 0, 0)
+	case TopWindowAll:
+		return time.Time{}
+	default:
+		return now.AddDate(0, 0, -1)
+	}
+}
+
+// FeedQuery describes a sorted, paginated listing of posts across a set of subreddits
+type FeedQuery struct {
+	SubredditIDs  []string
+	AllSubreddits bool // Ignores SubredditIDs and lists posts from every subreddit
+	Sort          string
+	Limit         int
+	Cursor        string
+	HiddenFor     string    // User whose hidden posts, and posts by users they blocked, are left out; empty to leave nothing out
+	FlairID       string    // Only posts with this flair; empty for any
+	PinnedFirst   bool      // Puts pinned posts, most recently pinned first, ahead of the first page
+	Since         time.Time // Only posts created at or after this time; zero for no bound
+	HideNSFW      bool      // Leaves out posts in subreddits marked NSFW
+
+	ExcludeSubredditIDs []string // Subreddits whose posts are left out, such as private ones the viewer can't read
+}
+
+// GetUserFeedPosts retrieves a user's feed posts in the given sort, by karma and
+// creation date unless another is asked for
+func (m *MongoDB) GetUserFeedPosts(ctx context.Context, userID uuid.UUID, sort string, hideNSFW bool, limit int) ([]*models.Post, error) {
 	// Fetch the user's subscribed subreddits.
 	user, err := m.GetUser(ctx, userID)
 	if err != nil {
@@ -176,22 +772,114 @@ func (m *MongoDB) GetUserFeedPosts(ctx context.Context, userID uuid.UUID, limit
 		subredditIDStrings[i] = id.String()
 	}
 
+	if sort != SortNew && sort != SortHot && sort != SortControversial {
+		sort = SortTop
+	}
+	posts, _, err := m.GetFeedPosts(ctx, FeedQuery{
+		SubredditIDs: subredditIDStrings,
+		Sort:         sort,
+		Limit:        limit,
+		HiddenFor:    userID.String(),
+		HideNSFW:     hideNSFW,
+	})
+	return posts, err
+}
+
+// GetFeedPosts retrieves posts from the given subreddits in the requested sort order,
+// starting after the cursor if one is supplied. It returns the cursor for the next page,
+// which is empty once the listing is exhausted. Deleted posts are left out.
+func (m *MongoDB) GetFeedPosts(ctx context.Context, query FeedQuery) ([]*models.Post, string, error) {
+	filter := bson.M{"isdeleted": bson.M{"$ne": true}, "status": bson.M{"$nin": UnlistedPostStatuses}}
+	subredditFilter := bson.M{}
+	if !query.AllSubreddits {
+		subredditFilter["$in"] = query.SubredditIDs
+	}
+	excluded := query.ExcludeSubredditIDs
+	if query.HideNSFW {
+		nsfwIDs, err := m.getNSFWSubredditIDs(ctx)
+		if err != nil {
+			return nil, "", err
+		}
+		excluded = append(append([]string{}, excluded...), nsfwIDs...)
+	}
+	if len(excluded) > 0 {
+		subredditFilter["$nin"] = excluded
+	}
+	if len(subredditFilter) > 0 {
+		filter["subredditid"] = subredditFilter
+	}
+	if !query.Since.IsZero() {
+		filter["createdat"] = bson.M{"$gte": query.Since}
+	}
+	if query.FlairID != "" {
+		filter["flairid"] = query.FlairID
+	}
+	if query.HiddenFor != "" {
+		hiddenIDs, err := m.getHiddenPostIDs(ctx, query.HiddenFor)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(hiddenIDs) > 0 {
+			filter["_id"] = bson.M{"$nin": hiddenIDs}
+		}
+		blockedIDs, err := m.getBlockedUserIDs(ctx, query.HiddenFor)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(blockedIDs) > 0 {
+			filter["authorid"] = bson.M{"$nin": blockedIDs}
+		}
+	}
+
+	var pinned []*models.Post
+	if query.PinnedFirst {
+		if query.Cursor == "" {
+			pinnedFilter := bson.M{"ispinned": true}
+			for key, value := range filter {
+				pinnedFilter[key] = value
+			}
+			var err error
+			pinned, err = m.findPosts(ctx, pinnedFilter, options.Find().SetSort(bson.D{{Key: "pinnedat", Value: -1}}))
+			if err != nil {
+				return nil, "", err
+			}
+		}
+		filter["ispinned"] = bson.M{"$ne": true}
+	}
+
+	var sort bson.D
+	switch query.Sort {
+	case SortNew:
+		sort = bson.D{{Key: "createdat", Value: -1}, {Key: "_id", Value: -1}}
+	case SortHot:
+		sort = bson.D{{Key: "hot", Value: -1}, {Key: "_id", Value: -1}}
+	case SortControversial:
+		sort = bson.D{{Key: "controversy", Value: -1}, {Key: "createdat", Value: -1}, {Key: "_id", Value: -1}}
+	default:
+		sort = bson.D{{Key: "karma", Value: -1}, {Key: "createdat", Value: -1}, {Key: "_id", Value: -1}}
+	}
+
+	if query.Cursor != "" {
+		var cursor PostCursor
+		if err := DecodeCursor(query.Cursor, &cursor); err != nil {
+			return nil, "", err
+		}
+		filter = bson.M{"$and": []bson.M{filter, afterPostCursor(query.Sort, cursor)}}
+	}
+
 	// Define aggregation pipeline to retrieve feed posts.
 	pipeline := []bson.M{
-		{"$match": bson.M{"subredditid": bson.M{"$in": subredditIDStrings}}},
-		{"$sort": bson.D{
-			{Key: "karma", Value: -1},
-			{Key: "createdat", Value: -1},
-		}},
+		{"$match": filter},
+		{"$sort": sort},
 	}
 
-	if limit > 0 {
-		pipeline = append(pipeline, bson.M{"$limit": limit})
+	if query.Limit > 0 {
+		pipeline = append(pipeline, bson.M{"$limit": query.Limit})
 	}
 
 	cursor, err := m.Posts.Aggregate(ctx, pipeline)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch feed: %v", err)
+		return nil, "", fmt.Errorf("failed to fetch feed: %v", err)
 	}
 	defer cursor.Close(ctx)
 
@@ -212,8 +900,264 @@ func (m *MongoDB) GetUserFeedPosts(ctx context.Context, userID uuid.UUID, limit
 	}
 
 	if err := cursor.Err(); err != nil {
-		return nil, fmt.Errorf("error reading feed posts: %v", err)
+		return nil, "", fmt.Errorf("error reading feed posts: %v", err)
+	}
+
+	nextCursor := ""
+	if query.Limit > 0 && len(posts) == query.Limit {
+		last := posts[len(posts)-1]
+		nextCursor = EncodeCursor(PostCursor{
+			Hot:         last.HotScore,
+			Controversy: last.ControversyScore,
+			Karma:       last.Karma,
+			CreatedAt:   last.CreatedAt,
+			ID:          last.ID.String(),
+		})
+	}
+
+	return append(pinned, posts...), nextCursor, nil
+}
+
+// UnlistedPostStatuses are the statuses of posts that readers can't see: scheduled posts
+// before they are published, and posts held for or removed by moderator review
+var UnlistedPostStatuses = []string{models.PostStatusScheduled, models.PostStatusPending, models.PostStatusRemoved}
+
+// findPosts loads the posts matching filter, skipping any that fail to decode
+func (m *MongoDB) findPosts(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]*models.Post, error) {
+	cursor, err := m.Posts.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get posts: %v", err)
 	}
+	defer cursor.Close(ctx)
 
-	return posts, nil
+	var posts []*models.Post
+	for cursor.Next(ctx) {
+		var doc PostDocument
+		if err := cursor.Decode(&doc); err != nil {
+			log.Printf("Error decoding post: %v", err)
+			continue
+		}
+		post, err := m.DocumentToModel(&doc)
+		if err != nil {
+			log.Printf("Error converting document to model: %v", err)
+			continue
+		}
+		posts = append(posts, post)
+	}
+	return posts, cursor.Err()
+}
+
+// afterPostCursor builds a filter matching posts that sort strictly after the cursor
+func afterPostCursor(sort string, cursor PostCursor) bson.M {
+	if sort == SortHot {
+		return bson.M{"$or": []bson.M{
+			{"hot": bson.M{"$lt": cursor.Hot}},
+			{"hot": cursor.Hot, "_id": bson.M{"$lt": cursor.ID}},
+		}}
+	}
+	if sort == SortControversial {
+		return bson.M{"$or": []bson.M{
+			{"controversy": bson.M{"$lt": cursor.Controversy}},
+			{"controversy": cursor.Controversy, "createdat": bson.M{"$lt": cursor.CreatedAt}},
+			{"controversy": cursor.Controversy, "createdat": cursor.CreatedAt, "_id": bson.M{"$lt": cursor.ID}},
+		}}
+	}
+	if sort == SortNew {
+		return bson.M{"$or": []bson.M{
+			{"createdat": bson.M{"$lt": cursor.CreatedAt}},
+			{"createdat": cursor.CreatedAt, "_id": bson.M{"$lt": cursor.ID}},
+		}}
+	}
+
+	return bson.M{"$or": []bson.M{
+		{"karma": bson.M{"$lt": cursor.Karma}},
+		{"karma": cursor.Karma, "createdat": bson.M{"$lt": cursor.CreatedAt}},
+		{"karma": cursor.Karma, "createdat": cursor.CreatedAt, "_id": bson.M{"$lt": cursor.ID}},
+	}}
+}
+
+// GetPostsByAuthor retrieves a user's posts newest-first, starting after the cursor if
+// supplied. Deleted posts, and posts awaiting moderator review, are left out unless
+// ownPosts is set because the author is the one asking.
+func (m *MongoDB) GetPostsByAuthor(ctx context.Context, authorID uuid.UUID, ownPosts bool, limit int, cursor string) ([]*models.Post, string, error) {
+	unlisted := UnlistedPostStatuses
+	if ownPosts {
+		unlisted = []string{models.PostStatusScheduled, models.PostStatusRemoved}
+	}
+	filter := bson.M{"authorid": authorID.String(), "status": bson.M{"$nin": unlisted}}
+	if !ownPosts {
+		filter["isdeleted"] = bson.M{"$ne": true}
+	}
+	if cursor != "" {
+		var after PostCursor
+		if err := DecodeCursor(cursor, &after); err != nil {
+			return nil, "", err
+		}
+		filter = bson.M{"$and": []bson.M{filter, afterPostCursor(SortNew, after)}}
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdat", Value: -1}, {Key: "_id", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+
+	dbCursor, err := m.Posts.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get posts by author: %v", err)
+	}
+	defer dbCursor.Close(ctx)
+
+	posts := make([]*models.Post, 0)
+	for dbCursor.Next(ctx) {
+		var doc PostDocument
+		if err := dbCursor.Decode(&doc); err != nil {
+			log.Printf("Error decoding post document: %v", err)
+			continue
+		}
+
+		post, err := m.DocumentToModel(&doc)
+		if err != nil {
+			log.Printf("Error converting document to model: %v", err)
+			continue
+		}
+		posts = append(posts, post)
+	}
+
+	if err := dbCursor.Err(); err != nil {
+		return nil, "", fmt.Errorf("cursor iteration failed: %v", err)
+	}
+
+	nextCursor := ""
+	if limit > 0 && len(posts) == limit {
+		last := posts[len(posts)-1]
+		nextCursor = EncodeCursor(PostCursor{CreatedAt: last.CreatedAt, ID: last.ID.String()})
+	}
+
+	return posts, nextCursor, nil
+}
+
+// GetPostTitles looks up the titles of the given posts, keyed by post ID
+func (m *MongoDB) GetPostTitles(ctx context.Context, postIDs []string) (map[string]string, error) {
+	titles := make(map[string]string, len(postIDs))
+	if len(postIDs) == 0 {
+		return titles, nil
+	}
+
+	opts := options.Find().SetProjection(bson.M{"_id": 1, "title": 1})
+	cursor, err := m.Posts.Find(ctx, bson.M{"_id": bson.M{"$in": postIDs}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post titles: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID    string `bson:"_id"`
+			Title string `bson:"title"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			continue
+		}
+		titles[doc.ID] = doc.Title
+	}
+
+	return titles, cursor.Err()
+}
+
+// SoftDeletePost marks a post deleted. The original content is stashed so the author
+// can restore it during the undo window; the janitor scrubs it afterwards.
+func (m *MongoDB) SoftDeletePost(ctx context.Context, postID, deletedBy uuid.UUID, deletedAt time.Time) error {
+	update := softDeletePostPipeline(deletedBy, deletedAt)
+	result, err := m.Posts.UpdateOne(ctx, bson.M{"_id": postID.String(), "isdeleted": bson.M{"$ne": true}}, update)
+	if err != nil {
+		return fmt.Errorf("failed to delete post: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil)
+	}
+	return nil
+}
+
+// subredditDeleteBatchSize is how many posts SoftDeleteSubredditPosts deletes per update
+const subredditDeleteBatchSize = 500
+
+// SoftDeleteSubredditPosts soft-deletes every remaining post in a subreddit, scheduled
+// ones included, along with their comments. Posts are deleted in batches, each batch
+// with its comments, and the IDs of the deleted posts are returned. Running it again
+// after a failure picks up the posts that are left.
+func (m *MongoDB) SoftDeleteSubredditPosts(ctx context.Context, subredditID, deletedBy uuid.UUID, deletedAt time.Time) ([]string, error) {
+	filter := bson.M{"subredditid": subredditID.String(), "isdeleted": bson.M{"$ne": true}}
+	cursor, err := m.Posts.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find subreddit posts: %v", err)
+	}
+	var docs []struct {
+		ID string `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode subreddit posts: %v", err)
+	}
+
+	postIDs := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		postIDs = append(postIDs, doc.ID)
+	}
+
+	update := softDeletePostPipeline(deletedBy, deletedAt)
+	for start := 0; start < len(postIDs); start += subredditDeleteBatchSize {
+		batch := postIDs[start:min(start+subredditDeleteBatchSize, len(postIDs))]
+		batchFilter := bson.M{"_id": bson.M{"$in": batch}, "isdeleted": bson.M{"$ne": true}}
+		if _, err := m.Posts.UpdateMany(ctx, batchFilter, update); err != nil {
+			return nil, fmt.Errorf("failed to delete subreddit posts: %v", err)
+		}
+		if err := m.softDeleteCommentsMatching(ctx, bson.M{"postId": bson.M{"$in": batch}}, deletedBy, deletedAt); err != nil {
+			return nil, err
+		}
+	}
+	return postIDs, nil
+}
+
+// softDeletePostPipeline is the update that marks posts deleted, stashing their content
+func softDeletePostPipeline(deletedBy uuid.UUID, deletedAt time.Time) mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"isdeleted":      true,
+			"deletedat":      deletedAt,
+			"deletedby":      deletedBy.String(),
+			"deletedcontent": "$content",
+			"content":        DeletedPlaceholder,
+		}}},
+	}
+}
+
+// RestorePost reverses SoftDeletePost while the stashed content is still present
+func (m *MongoDB) RestorePost(ctx context.Context, postID uuid.UUID) (*models.Post, error) {
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"isdeleted": false, "content": "$deletedcontent"}}},
+		{{Key: "$unset", Value: bson.A{"deletedat", "deletedby", "deletedcontent"}}},
+	}
+
+	filter := bson.M{"_id": postID.String(), "isdeleted": true, "deletedcontent": bson.M{"$exists": true}}
+	result, err := m.Posts.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore post: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return nil, utils.NewAppError(utils.ErrGone, "Post can no longer be restored", nil)
+	}
+	return m.GetPost(ctx, postID)
+}
+
+// ScrubDeletedPosts permanently discards the stashed content of posts deleted before cutoff
+func (m *MongoDB) ScrubDeletedPosts(ctx context.Context, cutoff time.Time) (int64, error) {
+	filter := bson.M{
+		"isdeleted":      true,
+		"deletedat":      bson.M{"$lt": cutoff},
+		"deletedcontent": bson.M{"$exists": true},
+	}
+	result, err := m.Posts.UpdateMany(ctx, filter, bson.M{"$unset": bson.M{"deletedcontent": ""}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to scrub deleted posts: %v", err)
+	}
+	return result.ModifiedCount, nil
 }
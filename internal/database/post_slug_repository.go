@@ -0,0 +1,126 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gator-swamp/internal/i18n"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UniquePostSlug returns a slug for a post with the given title that no other post in the
+// subreddit uses, now or as an old slug. On a collision the start of the post's ID is
+// appended, and failing that the whole ID.
+func (m *MongoDB) UniquePostSlug(ctx context.Context, subredditID, postID uuid.UUID, title string) (string, error) {
+	base := utils.Slugify(title)
+	for _, slug := range []string{base, base + "-" + postID.String()[:6], base + "-" + postID.String()} {
+		taken, err := m.postSlugTaken(ctx, subredditID, postID, slug)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return slug, nil
+		}
+	}
+	return "", fmt.Errorf("no free slug for post %s", postID)
+}
+
+// postSlugTaken reports whether a post other than postID in the subreddit resolves from slug
+func (m *MongoDB) postSlugTaken(ctx context.Context, subredditID, postID uuid.UUID, slug string) (bool, error) {
+	filter := bson.M{
+		"subredditid": subredditID.String(),
+		"_id":         bson.M{"$ne": postID.String()},
+		"$or":         bson.A{bson.M{"slug": slug}, bson.M{"oldslugs": slug}},
+	}
+	count, err := m.Posts.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	if err != nil {
+		return false, fmt.Errorf("failed to check post slug: %v", err)
+	}
+	return count > 0, nil
+}
+
+// GetPostBySlug retrieves the post in a subreddit whose current or old slug is slug
+func (m *MongoDB) GetPostBySlug(ctx context.Context, subredditID uuid.UUID, slug string) (*models.Post, error) {
+	filter := bson.M{
+		"subredditid": subredditID.String(),
+		"$or":         bson.A{bson.M{"slug": slug}, bson.M{"oldslugs": slug}},
+		"status":      bson.M{"$nin": UnlistedPostStatuses},
+	}
+
+	var doc PostDocument
+	err := m.Posts.FindOne(ctx, filter).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m.DocumentToModel(&doc)
+}
+
+// backfillPostSlugs gives slugs to posts created before permalinks existed, oldest first
+// so they keep the plain slug on a collision
+func (m *MongoDB) backfillPostSlugs(ctx context.Context) error {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdat", Value: 1}}).
+		SetProjection(bson.M{"_id": 1, "title": 1, "subredditid": 1})
+	cursor, err := m.Posts.Find(ctx, bson.M{"slug": bson.M{"$exists": false}}, opts)
+	if err != nil {
+		return fmt.Errorf("failed to find posts without slugs: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc PostDocument
+		if err := cursor.Decode(&doc); err != nil {
+			return fmt.Errorf("failed to decode post: %v", err)
+		}
+		postID, err := uuid.Parse(doc.ID)
+		if err != nil {
+			continue
+		}
+		subredditID, err := uuid.Parse(doc.SubredditID)
+		if err != nil {
+			continue
+		}
+		slug, err := m.UniquePostSlug(ctx, subredditID, postID, doc.Title)
+		if err != nil {
+			return err
+		}
+		if _, err := m.Posts.UpdateOne(ctx, bson.M{"_id": doc.ID}, bson.M{"$set": bson.M{"slug": slug}}); err != nil {
+			return fmt.Errorf("failed to backfill post slug: %v", err)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("failed to backfill post slugs: %v", err)
+	}
+	return nil
+}
+
+// EnsurePostSlugIndexes creates the index that keeps slugs unique within a subreddit and
+// the index behind lookups by former slugs
+func (m *MongoDB) EnsurePostSlugIndexes(ctx context.Context) error {
+	var errs []error
+	_, err := m.Posts.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "subredditid", Value: 1}, {Key: "oldslugs", Value: 1}},
+	})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to create post slug indexes: %v", err))
+	}
+	err = createUniqueIndex(ctx, m.Posts, "post slug", mongo.IndexModel{
+		Keys: bson.D{{Key: "subredditid", Value: 1}, {Key: "slug", Value: 1}},
+		Options: options.Index().
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"slug": bson.M{"$exists": true}}),
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,231 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gator-swamp/internal/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PostVoteDocument records the direction of a user's vote on a post
+type PostVoteDocument struct {
+	ID       string    `bson:"_id"`
+	PostID   string    `bson:"postId"`
+	UserID   string    `bson:"userId"`
+	IsUpvote bool      `bson:"isUpvote"`
+	VotedAt  time.Time `bson:"votedAt"`
+}
+
+// GetPostVote returns a user's vote on a post, or nil if they haven't voted on it
+func (m *MongoDB) GetPostVote(ctx context.Context, postID, userID uuid.UUID) (*PostVoteDocument, error) {
+	filter := bson.M{"postId": postID.String(), "userId": userID.String()}
+
+	var doc PostVoteDocument
+	if err := m.PostVotes.FindOne(ctx, filter).Decode(&doc); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get post vote: %v", err)
+	}
+	return &doc, nil
+}
+
+// RecordPostVote records a user's first vote on a post. A user has one vote per post;
+// recording another fails with ErrDuplicate.
+func (m *MongoDB) RecordPostVote(ctx context.Context, postID, userID uuid.UUID, isUpvote bool, votedAt time.Time) error {
+	doc := PostVoteDocument{
+		ID:       uuid.New().String(),
+		PostID:   postID.String(),
+		UserID:   userID.String(),
+		IsUpvote: isUpvote,
+		VotedAt:  votedAt,
+	}
+	if _, err := m.PostVotes.InsertOne(ctx, doc); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return utils.NewAppError(utils.ErrDuplicate, "Already voted", nil)
+		}
+		return fmt.Errorf("failed to record post vote: %w", err)
+	}
+	return nil
+}
+
+// SwitchPostVote turns a user's vote on a post to the given direction. It fails with
+// ErrDuplicate if the user has no vote in the other direction to switch.
+func (m *MongoDB) SwitchPostVote(ctx context.Context, postID, userID uuid.UUID, isUpvote bool, votedAt time.Time) error {
+	filter := bson.M{
+		"postId":   postID.String(),
+		"userId":   userID.String(),
+		"isUpvote": !isUpvote,
+	}
+	update := bson.M{"$set": bson.M{"isUpvote": isUpvote, "votedAt": votedAt}}
+
+	result, err := m.PostVotes.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to switch post vote: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return utils.NewAppError(utils.ErrDuplicate, "Already voted", nil)
+	}
+	return nil
+}
+
+// DeletePostVote removes a user's vote in the given direction from a post. It fails with
+// ErrNotFound if the user has no such vote to remove.
+func (m *MongoDB) DeletePostVote(ctx context.Context, postID, userID uuid.UUID, isUpvote bool) error {
+	filter := bson.M{
+		"postId":   postID.String(),
+		"userId":   userID.String(),
+		"isUpvote": isUpvote,
+	}
+
+	result, err := m.PostVotes.DeleteOne(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to delete post vote: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return utils.NewAppError(utils.ErrNotFound, "No vote to remove", nil)
+	}
+	return nil
+}
+
+// PostVote is a vote to store on a post. A switched vote turns the user's existing vote
+// in the other direction around, which was cast at PreviousVotedAt. A removed vote takes
+// back the user's existing vote in the IsUpvote direction, cast at PreviousVotedAt.
+// Otherwise it is the user's first vote on the post.
+type PostVote struct {
+	PostID          uuid.UUID
+	UserID          uuid.UUID
+	IsUpvote        bool
+	VotedAt         time.Time
+	Switched        bool
+	Removed         bool
+	PreviousVotedAt time.Time
+}
+
+// SavePostVote records a vote, failing with ErrDuplicate, or ErrNotFound for a removed
+// vote, if the user's vote isn't what the vote expects it to be
+func (m *MongoDB) SavePostVote(ctx context.Context, vote PostVote) error {
+	if vote.Removed {
+		return m.DeletePostVote(ctx, vote.PostID, vote.UserID, vote.IsUpvote)
+	}
+	if vote.Switched {
+		return m.SwitchPostVote(ctx, vote.PostID, vote.UserID, vote.IsUpvote, vote.VotedAt)
+	}
+	return m.RecordPostVote(ctx, vote.PostID, vote.UserID, vote.IsUpvote, vote.VotedAt)
+}
+
+// UndoPostVote puts a user's vote back as it was before SavePostVote stored the vote
+func (m *MongoDB) UndoPostVote(ctx context.Context, vote PostVote) error {
+	if vote.Removed {
+		return m.RecordPostVote(ctx, vote.PostID, vote.UserID, vote.IsUpvote, vote.PreviousVotedAt)
+	}
+	if vote.Switched {
+		return m.SwitchPostVote(ctx, vote.PostID, vote.UserID, !vote.IsUpvote, vote.PreviousVotedAt)
+	}
+	filter := bson.M{"postId": vote.PostID.String(), "userId": vote.UserID.String()}
+	if _, err := m.PostVotes.DeleteOne(ctx, filter); err != nil {
+		return fmt.Errorf("failed to delete post vote: %w", err)
+	}
+	return nil
+}
+
+// ErrTransactionsUnsupported is returned by UpdateVoteTransactional when MongoDB can't
+// run transactions, as on a standalone server rather than a replica set
+var ErrTransactionsUnsupported = errors.New("MongoDB deployment doesn't support transactions")
+
+// illegalOperationCode is the error code of a transaction started outside a replica set
+const illegalOperationCode = 20
+
+// SupportsTransactions reports whether the deployment can run transactions, which a
+// replica set or sharded cluster can and a standalone server can't
+func (m *MongoDB) SupportsTransactions(ctx context.Context) (bool, error) {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := m.Client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return false, fmt.Errorf("failed to check MongoDB deployment: %w", err)
+	}
+	return hello.SetName != "" || hello.Msg == "isdbgrid", nil
+}
+
+// VoteChange is how a vote moves a post's counts and its author's karma
+type VoteChange struct {
+	UpvoteDelta           int
+	DownvoteDelta         int
+	AuthorKarma           int // Added to the author's post karma; zero leaves it alone
+	MinControversialVotes int // See utils.PostControversyScore
+}
+
+// UpdateVoteTransactional records a vote and applies it to the post's counts and its
+// author's karma in a single transaction, so either all of them change or none does. It
+// returns ErrTransactionsUnsupported, having changed nothing, if the deployment can't
+// run transactions. Authors whose accounts are gone are skipped.
+func (m *MongoDB) UpdateVoteTransactional(ctx context.Context, vote PostVote, authorID uuid.UUID, change VoteChange) error {
+	session, err := m.Client.StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start session: %v", err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		if err := m.SavePostVote(sessCtx, vote); err != nil {
+			return nil, err
+		}
+		if err := m.UpdatePostVotes(sessCtx, vote.PostID, change.UpvoteDelta, change.DownvoteDelta, change.MinControversialVotes); err != nil {
+			return nil, err
+		}
+		if change.AuthorKarma == 0 {
+			return nil, nil
+		}
+		err := m.UpdateUserKarma(sessCtx, authorID, change.AuthorKarma, "post")
+		if err != nil && !utils.IsErrorCode(err, utils.ErrUserNotFound) {
+			return nil, err
+		}
+		return nil, nil
+	})
+
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == illegalOperationCode {
+		return ErrTransactionsUnsupported
+	}
+	return err
+}
+
+// GetPostVoteDirections returns whether a user upvoted each of the given posts they
+// voted on, by post ID. Posts the user hasn't voted on are left out.
+func (m *MongoDB) GetPostVoteDirections(ctx context.Context, userID uuid.UUID, postIDs []string) (map[string]bool, error) {
+	directions := make(map[string]bool)
+	if len(postIDs) == 0 {
+		return directions, nil
+	}
+
+	filter := bson.M{"userId": userID.String(), "postId": bson.M{"$in": postIDs}}
+	opts := options.Find().SetProjection(bson.M{"postId": 1, "isUpvote": 1})
+	cursor, err := m.PostVotes.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post votes: %v", err)
+	}
+	var docs []PostVoteDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode post votes: %v", err)
+	}
+	for _, doc := range docs {
+		directions[doc.PostID] = doc.IsUpvote
+	}
+	return directions, nil
+}
+
+// EnsurePostVoteIndexes creates the index that limits users to one vote per post
+func (m *MongoDB) EnsurePostVoteIndexes(ctx context.Context) error {
+	return createUniqueIndex(ctx, m.PostVotes, "post vote", mongo.IndexModel{
+		Keys:    bson.D{{Key: "postId", Value: 1}, {Key: "userId", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+}
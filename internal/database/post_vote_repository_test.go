@@ -0,0 +1,158 @@
+package database_test
+
+import (
+	"context"
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/database/dbtest"
+	"gator-swamp/internal/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// voteFixture is a post by an author, ready to be voted on by a voter
+type voteFixture struct {
+	mongodb  *database.MongoDB
+	post     *models.Post
+	authorID uuid.UUID
+	voterID  uuid.UUID
+}
+
+func newVoteFixture(t *testing.T) *voteFixture {
+	t.Helper()
+	mongodb := dbtest.New(t)
+	dbtest.RequireTransactions(t, mongodb)
+
+	ctx := context.Background()
+	f := &voteFixture{mongodb: mongodb, authorID: uuid.New(), voterID: uuid.New()}
+	author := &models.User{
+		ID:        f.authorID,
+		Username:  "author",
+		Email:     "author@example.com",
+		CreatedAt: time.Now(),
+	}
+	if err := mongodb.SaveUser(ctx, author); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+
+	f.post = &models.Post{
+		ID:          uuid.New(),
+		Title:       "A post",
+		Slug:        "a-post",
+		AuthorID:    f.authorID,
+		SubredditID: uuid.New(),
+		CreatedAt:   time.Now(),
+	}
+	if err := mongodb.SavePost(ctx, f.post); err != nil {
+		t.Fatalf("SavePost: %v", err)
+	}
+	return f
+}
+
+func (f *voteFixture) upvote() database.PostVote {
+	return database.PostVote{PostID: f.post.ID, UserID: f.voterID, IsUpvote: true, VotedAt: time.Now()}
+}
+
+// assertNoVote checks that the voter's vote wasn't recorded
+func (f *voteFixture) assertNoVote(t *testing.T) {
+	t.Helper()
+	vote, err := f.mongodb.GetPostVote(context.Background(), f.post.ID, f.voterID)
+	if err != nil {
+		t.Fatalf("GetPostVote: %v", err)
+	}
+	if vote != nil {
+		t.Errorf("vote record was kept after the transaction failed: %+v", vote)
+	}
+}
+
+func TestUpdateVoteTransactionalStoresAllWrites(t *testing.T) {
+	f := newVoteFixture(t)
+	ctx := context.Background()
+
+	change := database.VoteChange{UpvoteDelta: 1, AuthorKarma: 1}
+	if err := f.mongodb.UpdateVoteTransactional(ctx, f.upvote(), f.authorID, change); err != nil {
+		t.Fatalf("UpdateVoteTransactional: %v", err)
+	}
+
+	vote, err := f.mongodb.GetPostVote(ctx, f.post.ID, f.voterID)
+	if err != nil || vote == nil || !vote.IsUpvote {
+		t.Fatalf("vote record = %+v, %v; want an upvote", vote, err)
+	}
+	post, err := f.mongodb.GetPost(ctx, f.post.ID)
+	if err != nil {
+		t.Fatalf("GetPost: %v", err)
+	}
+	if post.Upvotes != 1 || post.Karma != 1 {
+		t.Errorf("post upvotes %d, karma %d; want 1, 1", post.Upvotes, post.Karma)
+	}
+	author, err := f.mongodb.GetUser(ctx, f.authorID)
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if author.Karma != 1 || author.PostKarma != 1 {
+		t.Errorf("author karma %d, post karma %d; want 1, 1", author.Karma, author.PostKarma)
+	}
+}
+
+// Each case breaks one write that comes after the vote record, and the whole vote must
+// be rolled back with it
+func TestUpdateVoteTransactionalRollsBackOnFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		sabotage func(t *testing.T, f *voteFixture)
+		check    func(t *testing.T, f *voteFixture)
+	}{
+		{
+			name: "post counts fail",
+			sabotage: func(t *testing.T, f *voteFixture) {
+				if _, err := f.mongodb.Posts.DeleteOne(context.Background(), bson.M{"_id": f.post.ID.String()}); err != nil {
+					t.Fatalf("deleting post: %v", err)
+				}
+			},
+			check: func(t *testing.T, f *voteFixture) {},
+		},
+		{
+			name: "author karma fails",
+			sabotage: func(t *testing.T, f *voteFixture) {
+				// $inc can't add to a string, so the karma update fails
+				_, err := f.mongodb.Users.UpdateOne(context.Background(),
+					bson.M{"_id": f.authorID.String()}, bson.M{"$set": bson.M{"postKarma": "broken"}})
+				if err != nil {
+					t.Fatalf("breaking author: %v", err)
+				}
+			},
+			check: func(t *testing.T, f *voteFixture) {
+				post, err := f.mongodb.GetPost(context.Background(), f.post.ID)
+				if err != nil {
+					t.Fatalf("GetPost: %v", err)
+				}
+				if post.Upvotes != 0 || post.Karma != 0 {
+					t.Errorf("post counts changed after the transaction failed: upvotes %d, karma %d", post.Upvotes, post.Karma)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newVoteFixture(t)
+			tt.sabotage(t, f)
+
+			change := database.VoteChange{UpvoteDelta: 1, AuthorKarma: 1}
+			err := f.mongodb.UpdateVoteTransactional(context.Background(), f.upvote(), f.authorID, change)
+			if err == nil {
+				t.Fatal("UpdateVoteTransactional succeeded; want the broken write to fail it")
+			}
+			f.assertNoVote(t)
+			tt.check(t, f)
+
+			// The user can vote again once the cause is fixed, rather than being told
+			// they already voted
+			if err := f.mongodb.SavePostVote(context.Background(), f.upvote()); err != nil {
+				t.Errorf("voting again after the failure: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gator-swamp/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SavedItemDocument records that a user saved a post or comment
+type SavedItemDocument struct {
+	ID       string    `bson:"_id"`
+	UserID   string    `bson:"userId"`
+	ItemType string    `bson:"itemType"`
+	ItemID   string    `bson:"itemId"`
+	SavedAt  time.Time `bson:"savedAt"`
+}
+
+// SavedItemCursor marks a position in a user's saved items, most recently saved first
+type SavedItemCursor struct {
+	SavedAt time.Time `json:"t"`
+	ID      string    `json:"id"`
+}
+
+// SaveItem records that a user saved an item. Saving an item again keeps the original
+// save time rather than failing.
+func (m *MongoDB) SaveItem(ctx context.Context, userID uuid.UUID, itemType string, itemID uuid.UUID, savedAt time.Time) error {
+	filter := bson.M{"userId": userID.String(), "itemType": itemType, "itemId": itemID.String()}
+	update := bson.M{"$setOnInsert": bson.M{"_id": uuid.New().String(), "savedAt": savedAt}}
+
+	_, err := m.SavedItems.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// A concurrent save of the same item won the race
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save item: %v", err)
+	}
+	return nil
+}
+
+// UnsaveItem removes an item from a user's saved items. Unsaving an item that isn't saved is a no-op.
+func (m *MongoDB) UnsaveItem(ctx context.Context, userID uuid.UUID, itemType string, itemID uuid.UUID) error {
+	filter := bson.M{"userId": userID.String(), "itemType": itemType, "itemId": itemID.String()}
+	if _, err := m.SavedItems.DeleteOne(ctx, filter); err != nil {
+		return fmt.Errorf("failed to unsave item: %v", err)
+	}
+	return nil
+}
+
+// GetSavedItems returns a page of a user's saved posts and comments, most recently saved
+// first, with the content attached. A non-empty itemType limits the page to that kind of
+// item. Items whose content was deleted come back as tombstones.
+func (m *MongoDB) GetSavedItems(ctx context.Context, userID uuid.UUID, itemType string, limit int, cursor string) ([]*models.SavedItem, string, error) {
+	filter := bson.M{"userId": userID.String()}
+	if itemType != "" {
+		filter["itemType"] = itemType
+	}
+	if cursor != "" {
+		var after SavedItemCursor
+		if err := DecodeCursor(cursor, &after); err != nil {
+			return nil, "", err
+		}
+		filter = bson.M{"$and": []bson.M{filter, {"$or": []bson.M{
+			{"savedAt": bson.M{"$lt": after.SavedAt}},
+			{"savedAt": after.SavedAt, "_id": bson.M{"$lt": after.ID}},
+		}}}}
+	}
+
+	// Fetch one extra row to learn whether another page exists
+	opts := options.Find().
+		SetSort(bson.D{{Key: "savedAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit + 1))
+
+	dbCursor, err := m.SavedItems.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get saved items: %v", err)
+	}
+	var docs []SavedItemDocument
+	if err := dbCursor.All(ctx, &docs); err != nil {
+		return nil, "", fmt.Errorf("failed to decode saved items: %v", err)
+	}
+
+	nextCursor := ""
+	if len(docs) > limit {
+		docs = docs[:limit]
+		last := docs[len(docs)-1]
+		nextCursor = EncodeCursor(SavedItemCursor{SavedAt: last.SavedAt, ID: last.ID})
+	}
+
+	postIDs := make([]string, 0)
+	commentIDs := make([]string, 0)
+	for _, doc := range docs {
+		if doc.ItemType == models.SavedItemPost {
+			postIDs = append(postIDs, doc.ItemID)
+		} else {
+			commentIDs = append(commentIDs, doc.ItemID)
+		}
+	}
+
+	posts, err := m.getPostsByIDs(ctx, postIDs)
+	if err != nil {
+		return nil, "", err
+	}
+	comments, err := m.getCommentsByIDs(ctx, commentIDs)
+	if err != nil {
+		return nil, "", err
+	}
+
+	items := make([]*models.SavedItem, 0, len(docs))
+	for _, doc := range docs {
+		itemID, err := uuid.Parse(doc.ItemID)
+		if err != nil {
+			continue
+		}
+		item := &models.SavedItem{ItemType: doc.ItemType, ItemID: itemID, SavedAt: doc.SavedAt}
+		switch doc.ItemType {
+		case models.SavedItemPost:
+			if post, ok := posts[doc.ItemID]; ok {
+				if post.IsDeleted {
+					item.Title = post.Title
+				} else {
+					item.Post = post
+				}
+			}
+		case models.SavedItemComment:
+			if comment, ok := comments[doc.ItemID]; ok && !comment.IsDeleted {
+				item.Comment = comment
+			}
+		}
+		item.Deleted = item.Post == nil && item.Comment == nil
+		items = append(items, item)
+	}
+
+	return items, nextCursor, nil
+}
+
+// getPostsByIDs loads posts keyed by ID. Missing posts are left out.
+func (m *MongoDB) getPostsByIDs(ctx context.Context, ids []string) (map[string]*models.Post, error) {
+	posts := make(map[string]*models.Post, len(ids))
+	if len(ids) == 0 {
+		return posts, nil
+	}
+
+	cursor, err := m.Posts.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get posts: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc PostDocument
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode post: %v", err)
+		}
+		post, err := m.DocumentToModel(&doc)
+		if err != nil {
+			return nil, err
+		}
+		posts[doc.ID] = post
+	}
+	return posts, cursor.Err()
+}
+
+// getCommentsByIDs loads comments keyed by ID. Missing comments are left out.
+func (m *MongoDB) getCommentsByIDs(ctx context.Context, ids []string) (map[string]*models.Comment, error) {
+	comments := make(map[string]*models.Comment, len(ids))
+	if len(ids) == 0 {
+		return comments, nil
+	}
+
+	cursor, err := m.Comments.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comments: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc CommentDocument
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode comment: %v", err)
+		}
+		comment, err := convertCommentDocumentToModel(&doc)
+		if err != nil {
+			return nil, err
+		}
+		comments[doc.ID] = comment
+	}
+	return comments, cursor.Err()
+}
+
+// EnsureSavedItemIndexes creates required indexes for the saved_items collection. The
+// unique index makes saving the same item twice a no-op.
+func (m *MongoDB) EnsureSavedItemIndexes(ctx context.Context) error {
+	var errs []error
+	_, err := m.SavedItems.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "userId", Value: 1}, {Key: "savedAt", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "userId", Value: 1}, {Key: "itemType", Value: 1}, {Key: "savedAt", Value: -1}, {Key: "_id", Value: -1}},
+		},
+	})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to create saved item indexes: %v", err))
+	}
+	err = createUniqueIndex(ctx, m.SavedItems, "saved item", mongo.IndexModel{
+		Keys:    bson.D{{Key: "userId", Value: 1}, {Key: "itemType", Value: 1}, {Key: "itemId", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetScheduledPosts returns an author's posts that are waiting to be published, soonest first
+func (m *MongoDB) GetScheduledPosts(ctx context.Context, authorID uuid.UUID) ([]*models.Post, error) {
+	filter := bson.M{"authorid": authorID.String(), "status": models.PostStatusScheduled}
+	opts := options.Find().SetSort(bson.D{{Key: "scheduledat", Value: 1}})
+	return m.findPosts(ctx, filter, opts)
+}
+
+// CancelScheduledPost deletes one of an author's scheduled posts before it is published.
+// It reports whether there was such a post.
+func (m *MongoDB) CancelScheduledPost(ctx context.Context, postID, authorID uuid.UUID) (bool, error) {
+	filter := bson.M{
+		"_id":      postID.String(),
+		"authorid": authorID.String(),
+		"status":   models.PostStatusScheduled,
+	}
+	result, err := m.Posts.DeleteOne(ctx, filter)
+	if err != nil {
+		return false, fmt.Errorf("failed to cancel scheduled post: %v", err)
+	}
+	return result.DeletedCount > 0, nil
+}
+
+// PublishDuePosts publishes the scheduled posts whose time has come, dating them to now,
+// and returns them. Posts cancelled while this runs are skipped.
+func (m *MongoDB) PublishDuePosts(ctx context.Context, now time.Time) ([]*models.Post, error) {
+	filter := bson.M{"status": models.PostStatusScheduled, "scheduledat": bson.M{"$lte": now}}
+	due, err := m.findPosts(ctx, filter, options.Find().SetSort(bson.D{{Key: "scheduledat", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	var published []*models.Post
+	for _, post := range due {
+		hot := utils.HotScore(post.Karma, now)
+		result, err := m.Posts.UpdateOne(ctx,
+			bson.M{"_id": post.ID.String(), "status": models.PostStatusScheduled},
+			bson.M{
+				"$set":   bson.M{"status": models.PostStatusPublished, "createdat": now, "hot": hot},
+				"$unset": bson.M{"scheduledat": ""},
+			})
+		if err != nil {
+			return published, fmt.Errorf("failed to publish scheduled post: %v", err)
+		}
+		if result.ModifiedCount == 0 {
+			continue
+		}
+
+		post.Status = models.PostStatusPublished
+		post.ScheduledAt = nil
+		post.CreatedAt = now
+		post.HotScore = hot
+		published = append(published, post)
+	}
+	return published, nil
+}
@@ -0,0 +1,160 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"gator-swamp/internal/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SessionDocument is one refresh token of a login session, stored by its hash. Every
+// refresh replaces the session's token with a new one in the same family; replaced
+// tokens are kept, marked rotated, until they expire so that reusing one is detected.
+type SessionDocument struct {
+	TokenHash string     `bson:"_id"`
+	FamilyID  string     `bson:"familyId"`
+	UserID    string     `bson:"userId"`
+	CreatedAt time.Time  `bson:"createdAt"`
+	ExpiresAt time.Time  `bson:"expiresAt"`
+	RotatedAt *time.Time `bson:"rotatedAt,omitempty"`
+}
+
+// CreateSession starts a login session for a user with its first refresh token
+func (m *MongoDB) CreateSession(ctx context.Context, userID uuid.UUID, tokenHash string, now time.Time, ttl time.Duration) error {
+	doc := SessionDocument{
+		TokenHash: tokenHash,
+		FamilyID:  uuid.New().String(),
+		UserID:    userID.String(),
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+	if _, err := m.Sessions.InsertOne(ctx, doc); err != nil {
+		return fmt.Errorf("failed to create session: %v", err)
+	}
+	return nil
+}
+
+// RotateSession exchanges the refresh token stored under oldHash for the one stored under
+// newHash, which stays valid for ttl, and returns the session's user. Unknown and expired
+// tokens fail with ErrUnauthorized. So does a token that was already rotated, which means
+// it was stolen or replayed: the whole session is revoked so no token of it works again.
+func (m *MongoDB) RotateSession(ctx context.Context, oldHash, newHash string, now time.Time, ttl time.Duration) (uuid.UUID, error) {
+	filter := bson.M{
+		"_id":       oldHash,
+		"rotatedAt": bson.M{"$exists": false},
+		"expiresAt": bson.M{"$gt": now},
+	}
+	var current SessionDocument
+	err := m.Sessions.FindOneAndUpdate(ctx, filter, bson.M{"$set": bson.M{"rotatedAt": now}}).Decode(&current)
+	if err == mongo.ErrNoDocuments {
+		return uuid.Nil, m.rejectRefreshToken(ctx, oldHash, now)
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to rotate session: %v", err)
+	}
+
+	userID, err := uuid.Parse(current.UserID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid user ID in session: %v", err)
+	}
+
+	next := SessionDocument{
+		TokenHash: newHash,
+		FamilyID:  current.FamilyID,
+		UserID:    current.UserID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+	if _, err := m.Sessions.InsertOne(ctx, next); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to rotate session: %v", err)
+	}
+	return userID, nil
+}
+
+// rejectRefreshToken explains why a refresh token can't be rotated, revoking its session
+// if the token had already been rotated
+func (m *MongoDB) rejectRefreshToken(ctx context.Context, tokenHash string, now time.Time) error {
+	var doc SessionDocument
+	err := m.Sessions.FindOne(ctx, bson.M{"_id": tokenHash}).Decode(&doc)
+	if err == mongo.ErrNoDocuments || err == nil && !doc.ExpiresAt.After(now) {
+		return utils.NewAppError(utils.ErrUnauthorized, "Invalid or expired refresh token", nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get session: %v", err)
+	}
+
+	if _, err := m.Sessions.DeleteMany(ctx, bson.M{"familyId": doc.FamilyID}); err != nil {
+		return fmt.Errorf("failed to revoke session: %v", err)
+	}
+	return utils.NewAppError(utils.ErrUnauthorized, "Refresh token was already used; the session has been revoked", nil)
+}
+
+// DeleteSession ends the session a refresh token belongs to, whether or not the token is
+// still current, and returns the session's user. Unknown tokens return uuid.Nil.
+func (m *MongoDB) DeleteSession(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	var doc SessionDocument
+	err := m.Sessions.FindOne(ctx, bson.M{"_id": tokenHash}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return uuid.Nil, nil
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get session: %v", err)
+	}
+
+	if _, err := m.Sessions.DeleteMany(ctx, bson.M{"familyId": doc.FamilyID}); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to delete session: %v", err)
+	}
+	userID, _ := uuid.Parse(doc.UserID)
+	return userID, nil
+}
+
+// DeleteUserSessions ends every session of a user
+func (m *MongoDB) DeleteUserSessions(ctx context.Context, userID uuid.UUID) error {
+	if _, err := m.Sessions.DeleteMany(ctx, bson.M{"userId": userID.String()}); err != nil {
+		return fmt.Errorf("failed to delete sessions: %v", err)
+	}
+	return nil
+}
+
+// DeleteOtherUserSessions ends every session of a user except the one the refresh token
+// stored under keepHash belongs to. If that token isn't one of the user's, every session
+// is ended.
+func (m *MongoDB) DeleteOtherUserSessions(ctx context.Context, userID uuid.UUID, keepHash string) error {
+	filter := bson.M{"userId": userID.String()}
+
+	var keep SessionDocument
+	err := m.Sessions.FindOne(ctx, bson.M{"_id": keepHash, "userId": userID.String()}).Decode(&keep)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return fmt.Errorf("failed to get session: %v", err)
+	}
+	if err == nil {
+		filter["familyId"] = bson.M{"$ne": keep.FamilyID}
+	}
+
+	if _, err := m.Sessions.DeleteMany(ctx, filter); err != nil {
+		return fmt.Errorf("failed to delete sessions: %v", err)
+	}
+	return nil
+}
+
+// EnsureSessionIndexes creates the indexes used to end sessions and the TTL index that
+// removes refresh tokens once they expire
+func (m *MongoDB) EnsureSessionIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "familyId", Value: 1}}},
+		{Keys: bson.D{{Key: "userId", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+	if _, err := m.Sessions.Indexes().CreateMany(ctx, indexes); err != nil {
+		return fmt.Errorf("failed to create session indexes: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"gator-swamp/internal/i18n"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ShareLinkDocument represents a short share code in MongoDB
+type ShareLinkDocument struct {
+	Code      string    `bson:"_id"`
+	PostID    string    `bson:"postId"`
+	CreatorID string    `bson:"creatorId"`
+	CreatedAt time.Time `bson:"createdAt"`
+	Clicks    int       `bson:"clicks"`
+	Channel   string    `bson:"channel,omitempty"`
+}
+
+// ShareStats summarizes how often a post has been shared and followed
+type ShareStats struct {
+	Shares int `bson:"shares" json:"shares"`
+	Clicks int `bson:"clicks" json:"clicks"`
+}
+
+// CreateShareLink inserts a new share link. A duplicate key error is returned
+// unchanged so callers can retry with a fresh code.
+func (m *MongoDB) CreateShareLink(ctx context.Context, link *models.ShareLink) error {
+	doc := ShareLinkDocument{
+		Code:      link.Code,
+		PostID:    link.PostID.String(),
+		CreatorID: link.CreatorID.String(),
+		CreatedAt: link.CreatedAt,
+		Clicks:    link.Clicks,
+		Channel:   link.Channel,
+	}
+
+	_, err := m.ShareLinks.InsertOne(ctx, doc)
+	return err
+}
+
+// GetShareLink retrieves a share link by its code
+func (m *MongoDB) GetShareLink(ctx context.Context, code string) (*models.ShareLink, error) {
+	var doc ShareLinkDocument
+	err := m.ShareLinks.FindOne(ctx, bson.M{"_id": code}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrShareLinkNotFound, nil, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get share link: %v", err)
+	}
+	return shareLinkFromDocument(&doc)
+}
+
+// FindRecentShareLink returns the newest link a user created for a post since the given
+// time, or nil if there is none
+func (m *MongoDB) FindRecentShareLink(ctx context.Context, postID, creatorID uuid.UUID, since time.Time) (*models.ShareLink, error) {
+	filter := bson.M{
+		"postId":    postID.String(),
+		"creatorId": creatorID.String(),
+		"createdAt": bson.M{"$gte": since},
+	}
+	opts := options.FindOne().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+
+	var doc ShareLinkDocument
+	if err := m.ShareLinks.FindOne(ctx, filter, opts).Decode(&doc); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to look up recent share link: %v", err)
+	}
+	return shareLinkFromDocument(&doc)
+}
+
+func shareLinkFromDocument(doc *ShareLinkDocument) (*models.ShareLink, error) {
+	postID, err := uuid.Parse(doc.PostID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid post ID in share link: %v", err)
+	}
+
+	creatorID, err := uuid.Parse(doc.CreatorID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid creator ID in share link: %v", err)
+	}
+
+	return &models.ShareLink{
+		Code:      doc.Code,
+		PostID:    postID,
+		CreatorID: creatorID,
+		CreatedAt: doc.CreatedAt,
+		Clicks:    doc.Clicks,
+		Channel:   doc.Channel,
+	}, nil
+}
+
+// IncrementShareLinkClicks applies accumulated click deltas in a single bulk write
+func (m *MongoDB) IncrementShareLinkClicks(ctx context.Context, deltas map[string]int) error {
+	if len(deltas) == 0 {
+		return nil
+	}
+
+	writes := make([]mongo.WriteModel, 0, len(deltas))
+	for code, delta := range deltas {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": code}).
+			SetUpdate(bson.M{"$inc": bson.M{"clicks": delta}}))
+	}
+
+	if _, err := m.ShareLinks.BulkWrite(ctx, writes); err != nil {
+		return fmt.Errorf("failed to update share link clicks: %v", err)
+	}
+	return nil
+}
+
+// GetPostShareStats counts the share links created for a post and their total clicks
+func (m *MongoDB) GetPostShareStats(ctx context.Context, postID uuid.UUID) (*ShareStats, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{"postId": postID.String()}},
+		{"$group": bson.M{
+			"_id":    nil,
+			"shares": bson.M{"$sum": 1},
+			"clicks": bson.M{"$sum": "$clicks"},
+		}},
+	}
+
+	cursor, err := m.ShareLinks.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate share stats: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	stats := &ShareStats{}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(stats); err != nil {
+			return nil, fmt.Errorf("failed to decode share stats: %v", err)
+		}
+	}
+	return stats, cursor.Err()
+}
+
+// EnsureShareLinkIndexes creates required indexes for the share_links collection
+func (m *MongoDB) EnsureShareLinkIndexes(ctx context.Context) error {
+	_, err := m.ShareLinks.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "postId", Value: 1}}},
+		{Keys: bson.D{{Key: "postId", Value: 1}, {Key: "creatorId", Value: 1}, {Key: "createdAt", Value: -1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create share link indexes: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SitemapEntry is the minimal information needed to list a page in a sitemap
+type SitemapEntry struct {
+	Key     string // Post ID or subreddit name, used to build the URL
+	LastMod time.Time
+}
+
+// publicSubredditFilter matches subreddits that may be listed publicly: not deleted,
+// not private and not marked NSFW by their moderators (SubredditDB.NSFW). Flags that
+// are absent on older documents are treated as public.
+func publicSubredditFilter() bson.M {
+	return bson.M{
+		"isDeleted": bson.M{"$ne": true},
+		"type":      bson.M{"$ne": "private"},
+		"nsfw":      bson.M{"$ne": true},
+	}
+}
+
+// getHiddenSubredditIDs returns the IDs of subreddits whose posts must not be listed publicly
+func (m *MongoDB) getHiddenSubredditIDs(ctx context.Context) ([]string, error) {
+	filter := bson.M{"$nor": bson.A{publicSubredditFilter()}}
+	opts := options.Find().SetProjection(bson.M{"_id": 1})
+
+	cursor, err := m.Subreddits.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find hidden subreddits: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	ids := make([]string, 0)
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID string `bson:"_id"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode subreddit: %v", err)
+		}
+		ids = append(ids, doc.ID)
+	}
+	return ids, cursor.Err()
+}
+
+// publicPostFilter matches non-deleted posts outside the given hidden subreddits
+func publicPostFilter(hiddenSubredditIDs []string) bson.M {
+	return bson.M{
+		"isdeleted":   bson.M{"$ne": true},
+		"status":      bson.M{"$nin": UnlistedPostStatuses},
+		"subredditid": bson.M{"$nin": hiddenSubredditIDs},
+	}
+}
+
+// CountPublicPosts returns the number of posts eligible for the sitemap
+func (m *MongoDB) CountPublicPosts(ctx context.Context) (int64, error) {
+	hidden, err := m.getHiddenSubredditIDs(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := m.Posts.CountDocuments(ctx, publicPostFilter(hidden))
+	if err != nil {
+		return 0, fmt.Errorf("failed to count posts: %v", err)
+	}
+	return count, nil
+}
+
+// GetPublicPostEntries returns up to limit public posts with IDs after afterID, in _id
+// order. Pages are keyed on the last ID of the previous page rather than skipped to, so
+// later pages cost no more to read than the first; an empty afterID starts from the
+// beginning.
+func (m *MongoDB) GetPublicPostEntries(ctx context.Context, afterID string, limit int) ([]SitemapEntry, error) {
+	hidden, err := m.getHiddenSubredditIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := publicPostFilter(hidden)
+	if afterID != "" {
+		filter["_id"] = bson.M{"$gt": afterID}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(int64(limit)).
+		SetProjection(bson.M{"_id": 1, "createdat": 1}).
+		SetBatchSize(5000)
+
+	cursor, err := m.Posts.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list posts for sitemap: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	entries := make([]SitemapEntry, 0)
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID        string    `bson:"_id"`
+			CreatedAt time.Time `bson:"createdat"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode post: %v", err)
+		}
+		entries = append(entries, SitemapEntry{Key: doc.ID, LastMod: doc.CreatedAt})
+	}
+	return entries, cursor.Err()
+}
+
+// GetPublicSubredditEntries returns up to limit public subreddits ordered by name
+func (m *MongoDB) GetPublicSubredditEntries(ctx context.Context, limit int) ([]SitemapEntry, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "name", Value: 1}}).
+		SetLimit(int64(limit)).
+		SetProjection(bson.M{"name": 1, "createdAt": 1})
+
+	cursor, err := m.Subreddits.Find(ctx, publicSubredditFilter(), opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subreddits for sitemap: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	entries := make([]SitemapEntry, 0)
+	for cursor.Next(ctx) {
+		var doc struct {
+			Name      string    `bson:"name"`
+			CreatedAt time.Time `bson:"createdAt"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode subreddit: %v", err)
+		}
+		entries = append(entries, SitemapEntry{Key: doc.Name, LastMod: doc.CreatedAt})
+	}
+	return entries, cursor.Err()
+}
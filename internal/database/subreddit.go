@@ -2,9 +2,15 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"gator-swamp/internal/i18n"
 	"gator-swamp/internal/models"
 	"gator-swamp/internal/utils"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,11 +23,104 @@ import (
 type SubredditDB struct {
 	ID          string    `bson:"_id"`
 	Name        string    `bson:"name"`
+	NameLower   string    `bson:"nameLower"` // For case-insensitive name search
 	Description string    `bson:"description"`
 	CreatorID   string    `bson:"creatorId"`
 	Members     int       `bson:"members"`
 	CreatedAt   time.Time `bson:"createdAt"`
 	Posts       []string  `bson:"posts"`
+	Flairs      []FlairDB `bson:"flairs,omitempty"`
+
+	PollResultsAfterClose bool `bson:"pollResultsAfterClose,omitempty"`
+	MinKarmaToPost        int  `bson:"minKarmaToPost,omitempty"`
+	NSFW                  bool `bson:"nsfw,omitempty"`
+
+	Type          string   `bson:"type,omitempty"` // Absent on subreddits created before types existed, which are public
+	ApprovedUsers []string `bson:"approvedUsers,omitempty"`
+
+	Moderators []ModeratorDB `bson:"moderators,omitempty"`
+
+	IconURL      string `bson:"iconUrl,omitempty"`
+	BannerURL    string `bson:"bannerUrl,omitempty"`
+	PrimaryColor string `bson:"primaryColor,omitempty"`
+
+	UserFlairs []UserFlairTemplateDB `bson:"userFlairs,omitempty"`
+
+	Automod []AutomodRuleDB `bson:"automod,omitempty"`
+}
+
+// ModeratorDB represents a subreddit moderator other than the creator as stored in the
+// subreddit document
+type ModeratorDB struct {
+	UserID  string    `bson:"userId"`
+	AddedAt time.Time `bson:"addedAt"`
+	AddedBy string    `bson:"addedBy"`
+}
+
+// SubredditSettingsUpdate holds the moderator-editable settings of a subreddit. Nil
+// fields are left unchanged.
+type SubredditSettingsUpdate struct {
+	Description           *string
+	PollResultsAfterClose *bool
+	MinKarmaToPost        *int
+	NSFW                  *bool
+	Type                  *models.SubredditType
+	IconURL               *string // Empty removes the icon
+	BannerURL             *string // Empty removes the banner
+	PrimaryColor          *string // Empty removes the color
+}
+
+// FlairDB represents a subreddit's flair template as stored in the subreddit document
+type FlairDB struct {
+	ID    string `bson:"id"`
+	Text  string `bson:"text"`
+	Color string `bson:"color,omitempty"`
+}
+
+// subredditTypeFromDB converts a stored subreddit type, treating a missing one as public
+func subredditTypeFromDB(t string) models.SubredditType {
+	if t == "" {
+		return models.SubredditPublic
+	}
+	return models.SubredditType(t)
+}
+
+// approvedUsersFromDB converts stored approved user IDs, skipping any that are malformed
+func approvedUsersFromDB(ids []string) []uuid.UUID {
+	users := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if userID, err := uuid.Parse(id); err == nil {
+			users = append(users, userID)
+		}
+	}
+	return users
+}
+
+// moderatorsFromDB converts stored moderators, skipping any with a malformed user ID
+func moderatorsFromDB(docs []ModeratorDB) []models.Moderator {
+	moderators := make([]models.Moderator, 0, len(docs))
+	for _, doc := range docs {
+		userID, err := uuid.Parse(doc.UserID)
+		if err != nil {
+			continue
+		}
+		addedBy, _ := uuid.Parse(doc.AddedBy)
+		moderators = append(moderators, models.Moderator{UserID: userID, AddedAt: doc.AddedAt, AddedBy: addedBy})
+	}
+	return moderators
+}
+
+// flairsFromDB converts stored flair templates, skipping any with a malformed ID
+func flairsFromDB(docs []FlairDB) []models.PostFlair {
+	flairs := make([]models.PostFlair, 0, len(docs))
+	for _, doc := range docs {
+		id, err := uuid.Parse(doc.ID)
+		if err != nil {
+			continue
+		}
+		flairs = append(flairs, models.PostFlair{ID: id, Text: doc.Text, Color: doc.Color})
+	}
+	return flairs
 }
 
 // CreateSubreddit creates a new subreddit in MongoDB
@@ -29,17 +128,20 @@ func (m *MongoDB) CreateSubreddit(ctx context.Context, subreddit *models.Subredd
 	subredditDB := SubredditDB{
 		ID:          subreddit.ID.String(),
 		Name:        subreddit.Name,
+		NameLower:   strings.ToLower(subreddit.Name),
 		Description: subreddit.Description,
 		CreatorID:   subreddit.CreatorID.String(),
 		Members:     subreddit.Members,
 		CreatedAt:   subreddit.CreatedAt,
 		Posts:       make([]string, 0), // Initialize empty posts array
+
+		Type: string(subreddit.Type),
 	}
 
 	_, err := m.Subreddits.InsertOne(ctx, subredditDB)
 	if err != nil {
 		if mongo.IsDuplicateKeyError(err) {
-			return fmt.Errorf("subreddit with name %s already exists", subreddit.Name)
+			return utils.NewAppError(utils.ErrDuplicate, fmt.Sprintf("subreddit name %s is taken", subreddit.Name), nil)
 		}
 		return fmt.Errorf("failed to create subreddit: %v", err)
 	}
@@ -50,7 +152,7 @@ func (m *MongoDB) CreateSubreddit(ctx context.Context, subreddit *models.Subredd
 // GetSubredditByID retrieves a subreddit by its ID
 func (m *MongoDB) GetSubredditByID(ctx context.Context, id uuid.UUID) (*models.Subreddit, error) {
 	var subredditDB SubredditDB
-	err := m.Subreddits.FindOne(ctx, bson.M{"_id": id.String()}).Decode(&subredditDB)
+	err := m.Subreddits.FindOne(ctx, bson.M{"_id": id.String(), "isDeleted": bson.M{"$ne": true}}).Decode(&subredditDB)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
@@ -81,13 +183,31 @@ func (m *MongoDB) GetSubredditByID(ctx context.Context, id uuid.UUID) (*models.S
 		Members:     subredditDB.Members,
 		CreatedAt:   subredditDB.CreatedAt,
 		Posts:       posts,
+		Flairs:      flairsFromDB(subredditDB.Flairs),
+
+		PollResultsAfterClose: subredditDB.PollResultsAfterClose,
+		MinKarmaToPost:        subredditDB.MinKarmaToPost,
+		NSFW:                  subredditDB.NSFW,
+
+		Type:          subredditTypeFromDB(subredditDB.Type),
+		ApprovedUsers: approvedUsersFromDB(subredditDB.ApprovedUsers),
+
+		Moderators: moderatorsFromDB(subredditDB.Moderators),
+
+		IconURL:      subredditDB.IconURL,
+		BannerURL:    subredditDB.BannerURL,
+		PrimaryColor: subredditDB.PrimaryColor,
+
+		UserFlairs: userFlairTemplatesFromDB(subredditDB.UserFlairs),
+
+		Automod: automodRulesFromDB(subredditDB.Automod),
 	}, nil
 }
 
 // GetSubredditByName retrieves a subreddit by its name
 func (m *MongoDB) GetSubredditByName(ctx context.Context, name string) (*models.Subreddit, error) {
 	var subredditDB SubredditDB
-	err := m.Subreddits.FindOne(ctx, bson.M{"name": name}).Decode(&subredditDB)
+	err := m.Subreddits.FindOne(ctx, bson.M{"name": name, "isDeleted": bson.M{"$ne": true}}).Decode(&subredditDB)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
@@ -121,12 +241,30 @@ func (m *MongoDB) GetSubredditByName(ctx context.Context, name string) (*models.
 		Members:     subredditDB.Members,
 		CreatedAt:   subredditDB.CreatedAt,
 		Posts:       posts,
+		Flairs:      flairsFromDB(subredditDB.Flairs),
+
+		PollResultsAfterClose: subredditDB.PollResultsAfterClose,
+		MinKarmaToPost:        subredditDB.MinKarmaToPost,
+		NSFW:                  subredditDB.NSFW,
+
+		Type:          subredditTypeFromDB(subredditDB.Type),
+		ApprovedUsers: approvedUsersFromDB(subredditDB.ApprovedUsers),
+
+		Moderators: moderatorsFromDB(subredditDB.Moderators),
+
+		IconURL:      subredditDB.IconURL,
+		BannerURL:    subredditDB.BannerURL,
+		PrimaryColor: subredditDB.PrimaryColor,
+
+		UserFlairs: userFlairTemplatesFromDB(subredditDB.UserFlairs),
+
+		Automod: automodRulesFromDB(subredditDB.Automod),
 	}, nil
 }
 
-// ListSubreddits retrieves all subreddits
+// ListSubreddits retrieves all subreddits that haven't been deleted
 func (m *MongoDB) ListSubreddits(ctx context.Context) ([]*models.Subreddit, error) {
-	cursor, err := m.Subreddits.Find(ctx, bson.M{})
+	cursor, err := m.Subreddits.Find(ctx, bson.M{"isDeleted": bson.M{"$ne": true}})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list subreddits: %v", err)
 	}
@@ -156,43 +294,469 @@ func (m *MongoDB) ListSubreddits(ctx context.Context) ([]*models.Subreddit, erro
 			CreatorID:   creatorID,
 			Members:     subredditDB.Members,
 			CreatedAt:   subredditDB.CreatedAt,
+			Flairs:      flairsFromDB(subredditDB.Flairs),
+
+			PollResultsAfterClose: subredditDB.PollResultsAfterClose,
+			MinKarmaToPost:        subredditDB.MinKarmaToPost,
+			NSFW:                  subredditDB.NSFW,
+
+			Type:          subredditTypeFromDB(subredditDB.Type),
+			ApprovedUsers: approvedUsersFromDB(subredditDB.ApprovedUsers),
+
+			Moderators: moderatorsFromDB(subredditDB.Moderators),
+
+			IconURL:      subredditDB.IconURL,
+			BannerURL:    subredditDB.BannerURL,
+			PrimaryColor: subredditDB.PrimaryColor,
+
+			UserFlairs: userFlairTemplatesFromDB(subredditDB.UserFlairs),
+
+			Automod: automodRulesFromDB(subredditDB.Automod),
 		})
 	}
 
 	return subreddits, nil
 }
 
-// UpdateSubredditMembers updates the member count
-func (m *MongoDB) UpdateSubredditMembers(ctx context.Context, id uuid.UUID, delta int) error {
-	result, err := m.Subreddits.UpdateOne(
-		ctx,
-		bson.M{"_id": id.String()},
-		bson.M{"$inc": bson.M{"members": delta}},
-	)
+// UpdateSubredditSettings applies the non-nil settings in update to a subreddit
+func (m *MongoDB) UpdateSubredditSettings(ctx context.Context, subredditID uuid.UUID, update SubredditSettingsUpdate) error {
+	set := bson.M{}
+	if update.Description != nil {
+		set["description"] = *update.Description
+	}
+	if update.PollResultsAfterClose != nil {
+		set["pollResultsAfterClose"] = *update.PollResultsAfterClose
+	}
+	if update.MinKarmaToPost != nil {
+		set["minKarmaToPost"] = *update.MinKarmaToPost
+	}
+	if update.NSFW != nil {
+		set["nsfw"] = *update.NSFW
+	}
+	if update.Type != nil {
+		set["type"] = string(*update.Type)
+	}
+	if update.IconURL != nil {
+		set["iconUrl"] = *update.IconURL
+	}
+	if update.BannerURL != nil {
+		set["bannerUrl"] = *update.BannerURL
+	}
+	if update.PrimaryColor != nil {
+		set["primaryColor"] = *update.PrimaryColor
+	}
+	if len(set) == 0 {
+		return nil
+	}
+
+	result, err := m.Subreddits.UpdateOne(ctx, bson.M{"_id": subredditID.String()}, bson.M{"$set": set})
+	if err != nil {
+		return fmt.Errorf("failed to update subreddit settings: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrSubredditNotFound, nil, nil)
+	}
+	return nil
+}
+
+// AddSubredditFlair adds a flair template to a subreddit
+func (m *MongoDB) AddSubredditFlair(ctx context.Context, subredditID uuid.UUID, flair models.PostFlair) error {
+	doc := FlairDB{ID: flair.ID.String(), Text: flair.Text, Color: flair.Color}
+	result, err := m.Subreddits.UpdateOne(ctx,
+		bson.M{"_id": subredditID.String()},
+		bson.M{"$push": bson.M{"flairs": doc}})
+	if err != nil {
+		return fmt.Errorf("failed to add flair: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrSubredditNotFound, nil, nil)
+	}
+	return nil
+}
 
+// UpdateSubredditFlair changes a flair template's text and color. Posts that already carry
+// it keep the text they were given.
+func (m *MongoDB) UpdateSubredditFlair(ctx context.Context, subredditID uuid.UUID, flair models.PostFlair) error {
+	result, err := m.Subreddits.UpdateOne(ctx,
+		bson.M{"_id": subredditID.String(), "flairs.id": flair.ID.String()},
+		bson.M{"$set": bson.M{"flairs.$.text": flair.Text, "flairs.$.color": flair.Color}})
 	if err != nil {
-		return fmt.Errorf("failed to update member count: %v", err)
+		return fmt.Errorf("failed to update flair: %v", err)
 	}
+	if result.MatchedCount == 0 {
+		return utils.NewAppError(utils.ErrNotFound, "Flair not found", nil)
+	}
+	return nil
+}
 
+// DeleteSubredditFlair removes a flair template. Posts that carry it keep their flair text.
+func (m *MongoDB) DeleteSubredditFlair(ctx context.Context, subredditID, flairID uuid.UUID) error {
+	result, err := m.Subreddits.UpdateOne(ctx,
+		bson.M{"_id": subredditID.String(), "flairs.id": flairID.String()},
+		bson.M{"$pull": bson.M{"flairs": bson.M{"id": flairID.String()}}})
+	if err != nil {
+		return fmt.Errorf("failed to delete flair: %v", err)
+	}
 	if result.MatchedCount == 0 {
-		return fmt.Errorf("subreddit not found")
+		return utils.NewAppError(utils.ErrNotFound, "Flair not found", nil)
+	}
+	return nil
+}
+
+// IsSubredditModerator reports whether a user moderates a subreddit, as its creator or
+// one of the moderators added since
+func (m *MongoDB) IsSubredditModerator(ctx context.Context, subredditID, userID uuid.UUID) (bool, error) {
+	subreddit, err := m.GetSubredditByID(ctx, subredditID)
+	if err != nil {
+		return false, err
+	}
+	if subreddit == nil {
+		return false, nil
+	}
+	return subreddit.IsModerator(userID), nil
+}
+
+// AddSubredditModerator makes a user a moderator of a subreddit. It reports whether that
+// changed anything, which it doesn't when the user already was one.
+func (m *MongoDB) AddSubredditModerator(ctx context.Context, subredditID uuid.UUID, moderator models.Moderator) (bool, error) {
+	doc := ModeratorDB{
+		UserID:  moderator.UserID.String(),
+		AddedAt: moderator.AddedAt,
+		AddedBy: moderator.AddedBy.String(),
+	}
+	filter := bson.M{
+		"_id":               subredditID.String(),
+		"isDeleted":         bson.M{"$ne": true},
+		"moderators.userId": bson.M{"$ne": doc.UserID},
+	}
+	result, err := m.Subreddits.UpdateOne(ctx, filter, bson.M{"$push": bson.M{"moderators": doc}})
+	if err != nil {
+		return false, fmt.Errorf("failed to add moderator: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		// Either the subreddit is gone or the user already moderates it
+		return false, m.VerifyAndGetSubreddit(ctx, subredditID)
+	}
+	return true, nil
+}
+
+// RemoveSubredditModerator removes a user from a subreddit's moderators. It reports
+// whether that changed anything, which it doesn't when the user wasn't one.
+func (m *MongoDB) RemoveSubredditModerator(ctx context.Context, subredditID, userID uuid.UUID) (bool, error) {
+	result, err := m.Subreddits.UpdateOne(ctx,
+		bson.M{"_id": subredditID.String(), "isDeleted": bson.M{"$ne": true}},
+		bson.M{"$pull": bson.M{"moderators": bson.M{"userId": userID.String()}}})
+	if err != nil {
+		return false, fmt.Errorf("failed to remove moderator: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return false, utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrSubredditNotFound, nil, nil)
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// GetSubredditNames looks up the names of the given subreddits by ID
+func (m *MongoDB) GetSubredditNames(ctx context.Context, subredditIDs []string) (map[string]string, error) {
+	names := make(map[string]string, len(subredditIDs))
+	if len(subredditIDs) == 0 {
+		return names, nil
+	}
+
+	opts := options.Find().SetProjection(bson.M{"_id": 1, "name": 1})
+	cursor, err := m.Subreddits.Find(ctx, bson.M{"_id": bson.M{"$in": subredditIDs}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subreddit names: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID   string `bson:"_id"`
+			Name string `bson:"name"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			continue
+		}
+		names[doc.ID] = doc.Name
+	}
+
+	return names, cursor.Err()
+}
+
+// UpdateSubredditMembers adds delta to a subreddit's member count and returns the new count
+func (m *MongoDB) UpdateSubredditMembers(ctx context.Context, id uuid.UUID, delta int) (int, error) {
+	opts := options.FindOneAndUpdate().
+		SetReturnDocument(options.After).
+		SetProjection(bson.M{"members": 1})
+
+	var doc struct {
+		Members int `bson:"members"`
+	}
+	err := m.Subreddits.FindOneAndUpdate(ctx, bson.M{"_id": id.String()}, bson.M{"$inc": bson.M{"members": delta}}, opts).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return 0, fmt.Errorf("subreddit not found")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to update member count: %v", err)
+	}
+	return doc.Members, nil
+}
+
+// MemberCountCorrection is a subreddit whose stored member count had drifted from the
+// subscriptions of its members, and the count it was corrected to
+type MemberCountCorrection struct {
+	SubredditID string `json:"subredditId"`
+	Name        string `json:"name"`
+	Was         int    `json:"was"`
+	Now         int    `json:"now"`
+}
+
+// ReconcileSubredditMemberCounts recounts every subreddit's members from user
+// subscriptions, which are the record of membership, and corrects the counts that
+// drifted. A count that changes while it is being recounted is left for the next run
+// rather than overwritten.
+func (m *MongoDB) ReconcileSubredditMemberCounts(ctx context.Context) ([]MemberCountCorrection, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$unwind", Value: "$subreddits"}},
+		{{Key: "$group", Value: bson.M{"_id": "$subreddits", "count": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := m.Users.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count subreddit members: %v", err)
+	}
+	var counted []struct {
+		SubredditID string `bson:"_id"`
+		Count       int    `bson:"count"`
+	}
+	if err := cursor.All(ctx, &counted); err != nil {
+		return nil, fmt.Errorf("failed to decode subreddit member counts: %v", err)
+	}
+	counts := make(map[string]int, len(counted))
+	for _, c := range counted {
+		counts[c.SubredditID] = c.Count
+	}
+
+	opts := options.Find().SetProjection(bson.M{"_id": 1, "name": 1, "members": 1})
+	docs, err := m.findSubreddits(ctx, bson.M{"isDeleted": bson.M{"$ne": true}}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	corrections := make([]MemberCountCorrection, 0)
+	for _, doc := range docs {
+		actual := counts[doc.ID]
+		if doc.Members == actual {
+			continue
+		}
+		filter := bson.M{"_id": doc.ID, "members": doc.Members}
+		result, err := m.Subreddits.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"members": actual}})
+		if err != nil {
+			return corrections, fmt.Errorf("failed to correct member count: %v", err)
+		}
+		if result.ModifiedCount > 0 {
+			corrections = append(corrections, MemberCountCorrection{
+				SubredditID: doc.ID,
+				Name:        doc.Name,
+				Was:         doc.Members,
+				Now:         actual,
+			})
+		}
+	}
+	return corrections, nil
+}
+
+// SubredditNameTaken reports whether a subreddit, deleted or not, has the name ignoring case
+func (m *MongoDB) SubredditNameTaken(ctx context.Context, name string) (bool, error) {
+	filter := bson.M{"nameLower": strings.ToLower(name)}
+	count, err := m.Subreddits.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	if err != nil {
+		return false, fmt.Errorf("failed to check subreddit name: %v", err)
+	}
+	return count > 0, nil
+}
+
+// FindSubredditNameCollisions returns the groups of subreddit names that differ only in
+// case, which subreddits created before names were compared that way may have
+func (m *MongoDB) FindSubredditNameCollisions(ctx context.Context) ([][]string, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$nameLower",
+			"names": bson.M{"$push": "$name"},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+	}
+	cursor, err := m.Subreddits.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find subreddit name collisions: %v", err)
+	}
+	var groups []struct {
+		Names []string `bson:"names"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, fmt.Errorf("failed to decode subreddit name collisions: %v", err)
 	}
 
+	collisions := make([][]string, 0, len(groups))
+	for _, group := range groups {
+		collisions = append(collisions, group.Names)
+	}
+	return collisions, nil
+}
+
+// backfillSubredditNames gives lowercased names to subreddits created before they were
+// stored
+func (m *MongoDB) backfillSubredditNames(ctx context.Context) error {
+	backfill := mongo.Pipeline{{{Key: "$set", Value: bson.M{"nameLower": bson.M{"$toLower": "$name"}}}}}
+	if _, err := m.Subreddits.UpdateMany(ctx, bson.M{"nameLower": bson.M{"$exists": false}}, backfill); err != nil {
+		return fmt.Errorf("failed to backfill lowercased subreddit names: %v", err)
+	}
 	return nil
 }
 
-// EnsureSubredditIndexes creates required indexes
+// EnsureSubredditIndexes creates the unique name indexes, the indexes behind subreddit
+// search and the index behind the daily cap on creating subreddits.
+// Names that differ only in case are logged for an operator to resolve rather than
+// renamed; until they are, the case-insensitive unique index can't be created.
 func (m *MongoDB) EnsureSubredditIndexes(ctx context.Context) error {
-	_, err := m.Subreddits.Indexes().CreateOne(ctx, mongo.IndexModel{
+	var errs []error
+	collisions, err := m.FindSubredditNameCollisions(ctx)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, names := range collisions {
+		log.Printf("Warning: subreddit names differ only in case: %s", strings.Join(names, ", "))
+	}
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "nameLower", Value: 1}, {Key: "members", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "description", Value: "text"}},
+		},
+		{
+			Keys: bson.D{{Key: "creatorId", Value: 1}, {Key: "createdAt", Value: 1}},
+		},
+	}
+	if _, err := m.Subreddits.Indexes().CreateMany(ctx, indexes); err != nil {
+		errs = append(errs, fmt.Errorf("failed to create subreddit indexes: %v", err))
+	}
+
+	// Created on their own so that collisions only hold back these indexes
+	if err := createUniqueIndex(ctx, m.Subreddits, "subreddit name", mongo.IndexModel{
 		Keys:    bson.D{{Key: "name", Value: 1}},
 		Options: options.Index().SetUnique(true),
-	})
+	}); err != nil {
+		errs = append(errs, err)
+	}
+	if err := createUniqueIndex(ctx, m.Subreddits, "case-insensitive subreddit name", mongo.IndexModel{
+		Keys:    bson.D{{Key: "nameLower", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
 
+// GetSubredditCreationTimes returns when a user created the subreddits they created since
+// the given time, oldest first. Subreddits deleted since still count.
+func (m *MongoDB) GetSubredditCreationTimes(ctx context.Context, creatorID uuid.UUID, since time.Time) ([]time.Time, error) {
+	filter := bson.M{"creatorId": creatorID.String(), "createdAt": bson.M{"$gt": since}}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: 1}}).
+		SetProjection(bson.M{"createdAt": 1})
+	cursor, err := m.Subreddits.Find(ctx, filter, opts)
 	if err != nil {
-		return fmt.Errorf("failed to create name index: %v", err)
+		return nil, fmt.Errorf("failed to get created subreddits: %v", err)
+	}
+	var docs []struct {
+		CreatedAt time.Time `bson:"createdAt"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode created subreddits: %v", err)
 	}
 
-	return nil
+	times := make([]time.Time, len(docs))
+	for i, doc := range docs {
+		times[i] = doc.CreatedAt
+	}
+	return times, nil
+}
+
+// SearchSubreddits returns up to limit subreddits whose name starts with query, ignoring
+// case, most members first. With matchDescription, subreddits whose description matches
+// query as text are included too. Subreddits in excludeIDs are left out.
+func (m *MongoDB) SearchSubreddits(ctx context.Context, query string, limit int, matchDescription bool, excludeIDs []string) ([]*models.SubredditSearchResult, error) {
+	base := bson.M{"isDeleted": bson.M{"$ne": true}}
+	if len(excludeIDs) > 0 {
+		base["_id"] = bson.M{"$nin": excludeIDs}
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "members", Value: -1}, {Key: "_id", Value: 1}}).
+		SetLimit(int64(limit)).
+		SetProjection(bson.M{"_id": 1, "name": 1, "description": 1, "members": 1})
+
+	byName := bson.M{"nameLower": bson.M{"$regex": "^" + regexp.QuoteMeta(strings.ToLower(query))}}
+	for key, value := range base {
+		byName[key] = value
+	}
+	docs, err := m.findSubreddits(ctx, byName, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if matchDescription {
+		byDescription := bson.M{"$text": bson.M{"$search": query}}
+		for key, value := range base {
+			byDescription[key] = value
+		}
+		more, err := m.findSubreddits(ctx, byDescription, opts)
+		if err != nil {
+			return nil, err
+		}
+		seen := make(map[string]bool, len(docs))
+		for _, doc := range docs {
+			seen[doc.ID] = true
+		}
+		for _, doc := range more {
+			if !seen[doc.ID] {
+				docs = append(docs, doc)
+			}
+		}
+		sort.SliceStable(docs, func(i, j int) bool { return docs[i].Members > docs[j].Members })
+		if len(docs) > limit {
+			docs = docs[:limit]
+		}
+	}
+
+	results := make([]*models.SubredditSearchResult, 0, len(docs))
+	for _, doc := range docs {
+		id, err := uuid.Parse(doc.ID)
+		if err != nil {
+			continue
+		}
+		results = append(results, &models.SubredditSearchResult{
+			ID:          id,
+			Name:        doc.Name,
+			Description: doc.Description,
+			Members:     doc.Members,
+		})
+	}
+	return results, nil
+}
+
+// findSubreddits returns the subreddit documents matching filter
+func (m *MongoDB) findSubreddits(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]SubredditDB, error) {
+	cursor, err := m.Subreddits.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search subreddits: %v", err)
+	}
+	var docs []SubredditDB
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode subreddits: %v", err)
+	}
+	return docs, nil
 }
 
 func (m *MongoDB) UpdateSubredditPosts(ctx context.Context, subredditID uuid.UUID, postID uuid.UUID, isAdding bool) error {
@@ -246,12 +810,128 @@ func (m *MongoDB) GetSubredditMembers(ctx context.Context, subredditID uuid.UUID
 
 func (m *MongoDB) VerifyAndGetSubreddit(ctx context.Context, subredditID uuid.UUID) error {
 	var subredditDB SubredditDB
-	err := m.Subreddits.FindOne(ctx, bson.M{"_id": subredditID.String()}).Decode(&subredditDB)
+	err := m.Subreddits.FindOne(ctx, bson.M{"_id": subredditID.String(), "isDeleted": bson.M{"$ne": true}}).Decode(&subredditDB)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return utils.NewAppError(utils.ErrNotFound, "subreddit not found", nil)
+			return utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrSubredditNotFound, nil, nil)
 		}
 		return utils.NewAppError(utils.ErrDatabase, "failed to verify subreddit", err)
 	}
 	return nil
 }
+
+// ApproveSubredditUser adds a user to a subreddit's approved users. It reports whether
+// that changed anything, which it doesn't when the user was already approved.
+func (m *MongoDB) ApproveSubredditUser(ctx context.Context, subredditID, userID uuid.UUID) (bool, error) {
+	result, err := m.Subreddits.UpdateOne(ctx,
+		bson.M{"_id": subredditID.String(), "isDeleted": bson.M{"$ne": true}},
+		bson.M{"$addToSet": bson.M{"approvedUsers": userID.String()}})
+	if err != nil {
+		return false, fmt.Errorf("failed to approve user: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return false, utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrSubredditNotFound, nil, nil)
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// CanViewSubreddit reports whether a viewer may read a subreddit's posts. Private
+// subreddits are open to their members and approved users only; uuid.Nil, an anonymous
+// viewer, can read only the others.
+func (m *MongoDB) CanViewSubreddit(ctx context.Context, subreddit *models.Subreddit, viewerID uuid.UUID) (bool, error) {
+	if subreddit.Type != models.SubredditPrivate {
+		return true, nil
+	}
+	if viewerID == uuid.Nil {
+		return false, nil
+	}
+	if subreddit.IsApproved(viewerID) {
+		return true, nil
+	}
+	filter := bson.M{"_id": viewerID.String(), "subreddits": subreddit.ID.String()}
+	count, err := m.Users.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	if err != nil {
+		return false, fmt.Errorf("failed to check subreddit membership: %v", err)
+	}
+	return count > 0, nil
+}
+
+// getNSFWSubredditIDs returns the IDs of the subreddits marked NSFW, whose posts are left
+// out of feeds for users who don't want to see them
+func (m *MongoDB) getNSFWSubredditIDs(ctx context.Context) ([]string, error) {
+	cursor, err := m.Subreddits.Find(ctx, bson.M{"nsfw": true}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find NSFW subreddits: %v", err)
+	}
+	var docs []struct {
+		ID string `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode NSFW subreddits: %v", err)
+	}
+
+	ids := make([]string, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.ID
+	}
+	return ids, nil
+}
+
+// GetPrivateSubredditIDsHiddenFrom returns the IDs of the private subreddits whose posts
+// a viewer may not read, by the rules of CanViewSubreddit
+func (m *MongoDB) GetPrivateSubredditIDsHiddenFrom(ctx context.Context, viewerID uuid.UUID) ([]string, error) {
+	filter := bson.M{"type": string(models.SubredditPrivate), "isDeleted": bson.M{"$ne": true}}
+	if viewerID != uuid.Nil {
+		var user struct {
+			Subreddits []string `bson:"subreddits"`
+		}
+		err := m.Users.FindOne(ctx, bson.M{"_id": viewerID.String()},
+			options.FindOne().SetProjection(bson.M{"subreddits": 1})).Decode(&user)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("failed to get user subscriptions: %v", err)
+		}
+		if len(user.Subreddits) > 0 {
+			filter["_id"] = bson.M{"$nin": user.Subreddits}
+		}
+		filter["creatorId"] = bson.M{"$ne": viewerID.String()}
+		filter["moderators.userId"] = bson.M{"$ne": viewerID.String()}
+		filter["approvedUsers"] = bson.M{"$ne": viewerID.String()}
+	}
+
+	cursor, err := m.Subreddits.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find private subreddits: %v", err)
+	}
+	var docs []struct {
+		ID string `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode private subreddits: %v", err)
+	}
+
+	ids := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		ids = append(ids, doc.ID)
+	}
+	return ids, nil
+}
+
+// MarkSubredditDeleted marks a subreddit deleted, after which it is treated as missing.
+// The document is kept, so its name stays taken.
+func (m *MongoDB) MarkSubredditDeleted(ctx context.Context, subredditID, deletedBy uuid.UUID, deletedAt time.Time) error {
+	filter := bson.M{"_id": subredditID.String(), "isDeleted": bson.M{"$ne": true}}
+	update := bson.M{"$set": bson.M{
+		"isDeleted": true,
+		"deletedAt": deletedAt,
+		"deletedBy": deletedBy.String(),
+	}}
+
+	result, err := m.Subreddits.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to delete subreddit: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrSubredditNotFound, nil, nil)
+	}
+	return nil
+}
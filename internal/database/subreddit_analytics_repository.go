@@ -0,0 +1,140 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SubredditActivityDay counts a subreddit's activity on one UTC day
+type SubredditActivityDay struct {
+	Date       string `json:"date"`
+	Posts      int    `json:"posts"`
+	Comments   int    `json:"comments"`
+	NewMembers int    `json:"newMembers"`
+}
+
+// SubredditTopPost is one of the highest scoring posts in a subreddit's analytics period
+type SubredditTopPost struct {
+	ID        string    `json:"postId" bson:"_id"`
+	Title     string    `json:"title" bson:"title"`
+	Karma     int       `json:"karma" bson:"karma"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdat"`
+}
+
+// SubredditAnalytics is a subreddit's daily activity over a period, oldest day first,
+// with its top posts from the same period
+type SubredditAnalytics struct {
+	SubredditID string                 `json:"subredditId"`
+	From        string                 `json:"from"`
+	To          string                 `json:"to"`
+	Days        []SubredditActivityDay `json:"days"`
+	TopPosts    []SubredditTopPost     `json:"topPosts"`
+	GeneratedAt time.Time              `json:"generatedAt"`
+}
+
+// countByDay counts the documents matching filter in each UTC day, keyed by date
+func countByDay(ctx context.Context, coll *mongo.Collection, filter bson.M, dateField string) (map[string]int, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$" + dateField}},
+			"count": bson.M{"$sum": 1},
+		}}},
+	}
+
+	cursor, err := coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var points []AnalyticsPoint
+	if err := cursor.All(ctx, &points); err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(points))
+	for _, point := range points {
+		counts[point.Date] = point.Count
+	}
+	return counts, nil
+}
+
+// GetSubredditAnalytics returns a subreddit's posts, comments and new members for each of
+// the given number of UTC days up to and including today, and its topLimit highest
+// scoring posts created in that time. New members come from the analytics rollups, so
+// today's count lags like the rest of them.
+func (m *MongoDB) GetSubredditAnalytics(ctx context.Context, subredditID uuid.UUID, now time.Time, days, topLimit int) (*SubredditAnalytics, error) {
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	since := today.AddDate(0, 0, -(days - 1))
+	from := since.Format(AnalyticsDateLayout)
+	to := today.Format(AnalyticsDateLayout)
+
+	postFilter := bson.M{
+		"subredditid": subredditID.String(),
+		"createdat":   bson.M{"$gte": since},
+		"status":      bson.M{"$nin": UnlistedPostStatuses},
+	}
+	posts, err := countByDay(ctx, m.Posts, postFilter, "createdat")
+	if err != nil {
+		return nil, fmt.Errorf("failed to count posts by day: %v", err)
+	}
+
+	comments, err := countByDay(ctx, m.Comments, bson.M{
+		"subredditId": subredditID.String(),
+		"createdAt":   bson.M{"$gte": since},
+	}, "createdAt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to count comments by day: %v", err)
+	}
+
+	// "join" is analytics.EventJoin, recorded each time someone becomes a member
+	joins, err := m.GetAnalyticsSeries(ctx, "join", from, to, subredditID.String())
+	if err != nil {
+		return nil, err
+	}
+	newMembers := make(map[string]int, len(joins))
+	for _, point := range joins {
+		newMembers[point.Date] = point.Count
+	}
+
+	activity := make([]SubredditActivityDay, 0, days)
+	for day := since; !day.After(today); day = day.AddDate(0, 0, 1) {
+		date := day.Format(AnalyticsDateLayout)
+		activity = append(activity, SubredditActivityDay{
+			Date:       date,
+			Posts:      posts[date],
+			Comments:   comments[date],
+			NewMembers: newMembers[date],
+		})
+	}
+
+	postFilter["isdeleted"] = bson.M{"$ne": true}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "karma", Value: -1}, {Key: "createdat", Value: -1}}).
+		SetLimit(int64(topLimit)).
+		SetProjection(bson.M{"title": 1, "karma": 1, "createdat": 1})
+	cursor, err := m.Posts.Find(ctx, postFilter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top posts: %v", err)
+	}
+	topPosts := make([]SubredditTopPost, 0, topLimit)
+	if err := cursor.All(ctx, &topPosts); err != nil {
+		return nil, fmt.Errorf("failed to decode top posts: %v", err)
+	}
+
+	return &SubredditAnalytics{
+		SubredditID: subredditID.String(),
+		From:        from,
+		To:          to,
+		Days:        activity,
+		TopPosts:    topPosts,
+		GeneratedAt: now,
+	}, nil
+}
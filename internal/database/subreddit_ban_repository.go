@@ -0,0 +1,188 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gator-swamp/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SubredditBanDocument records that a moderator banned a user from a subreddit
+type SubredditBanDocument struct {
+	ID          string     `bson:"_id"`
+	SubredditID string     `bson:"subredditId"`
+	UserID      string     `bson:"userId"`
+	BannedBy    string     `bson:"bannedBy"`
+	Reason      string     `bson:"reason"`
+	BannedAt    time.Time  `bson:"bannedAt"`
+	ExpiresAt   *time.Time `bson:"expiresAt"` // Null for a permanent ban
+}
+
+// SubredditBanCursor marks a position in a subreddit's bans, most recent first
+type SubredditBanCursor struct {
+	BannedAt time.Time `json:"t"`
+	ID       string    `json:"id"`
+}
+
+// activeBanFilter matches bans that are permanent or haven't expired by now
+func activeBanFilter(now time.Time) bson.M {
+	return bson.M{"$or": []bson.M{
+		{"expiresAt": nil},
+		{"expiresAt": bson.M{"$gt": now}},
+	}}
+}
+
+// BanSubredditUser bans a user from a subreddit. Banning a user who is already banned
+// replaces the reason and expiry of their ban.
+func (m *MongoDB) BanSubredditUser(ctx context.Context, ban *models.SubredditBan) error {
+	filter := bson.M{"subredditId": ban.SubredditID.String(), "userId": ban.UserID.String()}
+	update := bson.M{
+		"$set": bson.M{
+			"bannedBy":  ban.BannedBy.String(),
+			"reason":    ban.Reason,
+			"bannedAt":  ban.BannedAt,
+			"expiresAt": ban.ExpiresAt,
+		},
+		"$setOnInsert": bson.M{"_id": uuid.New().String()},
+	}
+
+	_, err := m.SubredditBans.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// A concurrent ban of the same user inserted first; apply ours over it
+		_, err = m.SubredditBans.UpdateOne(ctx, filter, update)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to ban user: %v", err)
+	}
+	return nil
+}
+
+// UnbanSubredditUser lifts a user's ban from a subreddit, reporting whether they were banned
+func (m *MongoDB) UnbanSubredditUser(ctx context.Context, subredditID, userID uuid.UUID) (bool, error) {
+	filter := bson.M{"subredditId": subredditID.String(), "userId": userID.String()}
+	result, err := m.SubredditBans.DeleteOne(ctx, filter)
+	if err != nil {
+		return false, fmt.Errorf("failed to unban user: %v", err)
+	}
+	return result.DeletedCount > 0, nil
+}
+
+// GetActiveSubredditBan returns a user's ban from a subreddit, or nil if they aren't
+// banned or their ban expired before now
+func (m *MongoDB) GetActiveSubredditBan(ctx context.Context, subredditID, userID uuid.UUID, now time.Time) (*models.SubredditBan, error) {
+	filter := bson.M{"$and": []bson.M{
+		{"subredditId": subredditID.String(), "userId": userID.String()},
+		activeBanFilter(now),
+	}}
+
+	var doc SubredditBanDocument
+	if err := m.SubredditBans.FindOne(ctx, filter).Decode(&doc); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get subreddit ban: %v", err)
+	}
+	return subredditBanFromDocument(&doc)
+}
+
+// GetSubredditBans returns a page of a subreddit's unexpired bans, most recent first
+func (m *MongoDB) GetSubredditBans(ctx context.Context, subredditID uuid.UUID, now time.Time, limit int, cursor string) ([]*models.SubredditBan, string, error) {
+	conditions := []bson.M{{"subredditId": subredditID.String()}, activeBanFilter(now)}
+	if cursor != "" {
+		var after SubredditBanCursor
+		if err := DecodeCursor(cursor, &after); err != nil {
+			return nil, "", err
+		}
+		conditions = append(conditions, bson.M{"$or": []bson.M{
+			{"bannedAt": bson.M{"$lt": after.BannedAt}},
+			{"bannedAt": after.BannedAt, "_id": bson.M{"$lt": after.ID}},
+		}})
+	}
+
+	// Fetch one extra row to learn whether another page exists
+	opts := options.Find().
+		SetSort(bson.D{{Key: "bannedAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit + 1))
+
+	dbCursor, err := m.SubredditBans.Find(ctx, bson.M{"$and": conditions}, opts)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get subreddit bans: %v", err)
+	}
+	var docs []SubredditBanDocument
+	if err := dbCursor.All(ctx, &docs); err != nil {
+		return nil, "", fmt.Errorf("failed to decode subreddit bans: %v", err)
+	}
+
+	nextCursor := ""
+	if len(docs) > limit {
+		docs = docs[:limit]
+		last := docs[len(docs)-1]
+		nextCursor = EncodeCursor(SubredditBanCursor{BannedAt: last.BannedAt, ID: last.ID})
+	}
+
+	bans := make([]*models.SubredditBan, 0, len(docs))
+	for i := range docs {
+		ban, err := subredditBanFromDocument(&docs[i])
+		if err != nil {
+			continue
+		}
+		bans = append(bans, ban)
+	}
+	return bans, nextCursor, nil
+}
+
+func subredditBanFromDocument(doc *SubredditBanDocument) (*models.SubredditBan, error) {
+	subredditID, err := uuid.Parse(doc.SubredditID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subreddit ID: %v", err)
+	}
+	userID, err := uuid.Parse(doc.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %v", err)
+	}
+	bannedBy, err := uuid.Parse(doc.BannedBy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid banned-by ID: %v", err)
+	}
+	return &models.SubredditBan{
+		SubredditID: subredditID,
+		UserID:      userID,
+		BannedBy:    bannedBy,
+		Reason:      doc.Reason,
+		BannedAt:    doc.BannedAt,
+		ExpiresAt:   doc.ExpiresAt,
+	}, nil
+}
+
+// EnsureSubredditBanIndexes creates the index that gives a user one ban per subreddit,
+// the index behind ban listings, and the TTL index that removes temporary bans once
+// they expire. Permanent bans have no expiry for the TTL index to act on.
+func (m *MongoDB) EnsureSubredditBanIndexes(ctx context.Context) error {
+	var errs []error
+	_, err := m.SubredditBans.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "subredditId", Value: 1}, {Key: "bannedAt", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to create subreddit ban indexes: %v", err))
+	}
+	err = createUniqueIndex(ctx, m.SubredditBans, "subreddit ban", mongo.IndexModel{
+		Keys:    bson.D{{Key: "subredditId", Value: 1}, {Key: "userId", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
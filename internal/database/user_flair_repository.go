@@ -0,0 +1,215 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"gator-swamp/internal/i18n"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UserFlairTemplateDB represents a subreddit's user flair template as stored in the
+// subreddit document
+type UserFlairTemplateDB struct {
+	ID      string `bson:"id"`
+	Text    string `bson:"text"`
+	Color   string `bson:"color,omitempty"`
+	ModOnly bool   `bson:"modOnly,omitempty"`
+}
+
+// UserFlairDB represents a member's flair in one subreddit as stored in the user
+// document, under subredditFlairs keyed by subreddit ID
+type UserFlairDB struct {
+	TemplateID string `bson:"templateId"`
+	Text       string `bson:"text"`
+	Color      string `bson:"color,omitempty"`
+}
+
+// userFlairField is the user document field holding a member's flair in a subreddit
+func userFlairField(subredditID uuid.UUID) string {
+	return "subredditFlairs." + subredditID.String()
+}
+
+// userFlairTemplatesFromDB converts stored user flair templates, skipping any with a
+// malformed ID
+func userFlairTemplatesFromDB(docs []UserFlairTemplateDB) []models.UserFlairTemplate {
+	templates := make([]models.UserFlairTemplate, 0, len(docs))
+	for _, doc := range docs {
+		id, err := uuid.Parse(doc.ID)
+		if err != nil {
+			continue
+		}
+		templates = append(templates, models.UserFlairTemplate{ID: id, Text: doc.Text, Color: doc.Color, ModOnly: doc.ModOnly})
+	}
+	return templates
+}
+
+// AddUserFlairTemplate adds a user flair template to a subreddit
+func (m *MongoDB) AddUserFlairTemplate(ctx context.Context, subredditID uuid.UUID, template models.UserFlairTemplate) error {
+	doc := UserFlairTemplateDB{ID: template.ID.String(), Text: template.Text, Color: template.Color, ModOnly: template.ModOnly}
+	result, err := m.Subreddits.UpdateOne(ctx,
+		bson.M{"_id": subredditID.String()},
+		bson.M{"$push": bson.M{"userFlairs": doc}})
+	if err != nil {
+		return fmt.Errorf("failed to add user flair: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrSubredditNotFound, nil, nil)
+	}
+	return nil
+}
+
+// UpdateUserFlairTemplate changes a user flair template's text, color and whether only
+// moderators can assign it. Members who wear it are updated to match; a member who chose
+// it keeps it even if it becomes mod-only.
+func (m *MongoDB) UpdateUserFlairTemplate(ctx context.Context, subredditID uuid.UUID, template models.UserFlairTemplate) error {
+	result, err := m.Subreddits.UpdateOne(ctx,
+		bson.M{"_id": subredditID.String(), "userFlairs.id": template.ID.String()},
+		bson.M{"$set": bson.M{
+			"userFlairs.$.text":    template.Text,
+			"userFlairs.$.color":   template.Color,
+			"userFlairs.$.modOnly": template.ModOnly,
+		}})
+	if err != nil {
+		return fmt.Errorf("failed to update user flair: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return utils.NewAppError(utils.ErrNotFound, "Flair not found", nil)
+	}
+
+	field := userFlairField(subredditID)
+	if _, err := m.Users.UpdateMany(ctx,
+		bson.M{field + ".templateId": template.ID.String()},
+		bson.M{"$set": bson.M{field + ".text": template.Text, field + ".color": template.Color}}); err != nil {
+		return fmt.Errorf("failed to update members' flair: %v", err)
+	}
+	return nil
+}
+
+// DeleteUserFlairTemplate removes a user flair template, and the flair of every member
+// who wore it
+func (m *MongoDB) DeleteUserFlairTemplate(ctx context.Context, subredditID, templateID uuid.UUID) error {
+	result, err := m.Subreddits.UpdateOne(ctx,
+		bson.M{"_id": subredditID.String(), "userFlairs.id": templateID.String()},
+		bson.M{"$pull": bson.M{"userFlairs": bson.M{"id": templateID.String()}}})
+	if err != nil {
+		return fmt.Errorf("failed to delete user flair: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return utils.NewAppError(utils.ErrNotFound, "Flair not found", nil)
+	}
+
+	field := userFlairField(subredditID)
+	if _, err := m.Users.UpdateMany(ctx,
+		bson.M{field + ".templateId": templateID.String()},
+		bson.M{"$unset": bson.M{field: ""}}); err != nil {
+		return fmt.Errorf("failed to remove members' flair: %v", err)
+	}
+	return nil
+}
+
+// SetUserFlair sets a member's flair in a subreddit, or clears it when flair is nil. It
+// fails with ErrNotFound if the user isn't a member of the subreddit.
+func (m *MongoDB) SetUserFlair(ctx context.Context, subredditID, userID uuid.UUID, flair *models.UserFlair) error {
+	update := bson.M{"$unset": bson.M{userFlairField(subredditID): ""}}
+	if flair != nil {
+		update = bson.M{"$set": bson.M{userFlairField(subredditID): UserFlairDB{
+			TemplateID: flair.TemplateID.String(),
+			Text:       flair.Text,
+			Color:      flair.Color,
+		}}}
+	}
+
+	result, err := m.Users.UpdateOne(ctx, bson.M{"_id": userID.String(), "subreddits": subredditID.String()}, update)
+	if err != nil {
+		return fmt.Errorf("failed to set user flair: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return utils.NewAppError(utils.ErrNotFound, "User is not a member of the subreddit", nil)
+	}
+	return nil
+}
+
+// getUserFlairs returns the flairs of the given users, by user ID and then subreddit ID.
+// Users without any flair are left out.
+func (m *MongoDB) getUserFlairs(ctx context.Context, userIDs []string) (map[string]map[string]UserFlairDB, error) {
+	flairs := make(map[string]map[string]UserFlairDB)
+	if len(userIDs) == 0 {
+		return flairs, nil
+	}
+
+	filter := bson.M{"_id": bson.M{"$in": userIDs}, "subredditFlairs": bson.M{"$exists": true}}
+	opts := options.Find().SetProjection(bson.M{"subredditFlairs": 1})
+	cursor, err := m.Users.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user flairs: %v", err)
+	}
+	var docs []struct {
+		ID     string                 `bson:"_id"`
+		Flairs map[string]UserFlairDB `bson:"subredditFlairs"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode user flairs: %v", err)
+	}
+	for _, doc := range docs {
+		flairs[doc.ID] = doc.Flairs
+	}
+	return flairs, nil
+}
+
+// userFlairFromDB converts a stored user flair, returning nil for a missing one
+func userFlairFromDB(doc UserFlairDB, ok bool) *models.UserFlair {
+	if !ok {
+		return nil
+	}
+	templateID, _ := uuid.Parse(doc.TemplateID)
+	return &models.UserFlair{TemplateID: templateID, Text: doc.Text, Color: doc.Color}
+}
+
+// AttachPostAuthorFlairs sets each post's AuthorFlair to its author's flair in the post's
+// subreddit
+func (m *MongoDB) AttachPostAuthorFlairs(ctx context.Context, posts []*models.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	authorIDs := make([]string, len(posts))
+	for i, post := range posts {
+		authorIDs[i] = post.AuthorID.String()
+	}
+	flairs, err := m.getUserFlairs(ctx, authorIDs)
+	if err != nil {
+		return err
+	}
+	for _, post := range posts {
+		doc, ok := flairs[post.AuthorID.String()][post.SubredditID.String()]
+		post.AuthorFlair = userFlairFromDB(doc, ok)
+	}
+	return nil
+}
+
+// AttachCommentAuthorFlairs sets each comment's AuthorFlair to its author's flair in the
+// comment's subreddit
+func (m *MongoDB) AttachCommentAuthorFlairs(ctx context.Context, comments []*models.Comment) error {
+	if len(comments) == 0 {
+		return nil
+	}
+
+	authorIDs := make([]string, len(comments))
+	for i, comment := range comments {
+		authorIDs[i] = comment.AuthorID.String()
+	}
+	flairs, err := m.getUserFlairs(ctx, authorIDs)
+	if err != nil {
+		return err
+	}
+	for _, comment := range comments {
+		doc, ok := flairs[comment.AuthorID.String()][comment.SubredditID.String()]
+		comment.AuthorFlair = userFlairFromDB(doc, ok)
+	}
+	return nil
+}
@@ -3,10 +3,12 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"gator-swamp/internal/models"
 	"gator-swamp/internal/utils"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,12 +22,29 @@ type UserDocument struct {
 	ID             string    `bson:"_id"`            // MongoDB primary key
 	Username       string    `bson:"username"`       // Username
 	Email          string    `bson:"email"`          // Email address
+	EmailVerified  bool      `bson:"emailVerified"`  // The user proved they receive mail at Email
 	HashedPassword string    `bson:"hashedPassword"` // Hashed password
-	Karma          int       `bson:"karma"`          // User's karma points
+	Karma          int       `bson:"karma"`          // User's total karma points
+	PostKarma      int       `bson:"postKarma"`      // Karma earned from posts
+	CommentKarma   int       `bson:"commentKarma"`   // Karma earned from comments
 	CreatedAt      time.Time `bson:"createdAt"`      // Account creation timestamp
 	LastActive     time.Time `bson:"lastActive"`     // Last active timestamp
 	IsConnected    bool      `bson:"isConnected"`    // Connection status
 	Subreddits     []string  `bson:"subreddits"`     // List of subscribed subreddit IDs
+
+	DisplayName string `bson:"displayName,omitempty"` // Name shown instead of the username
+	Bio         string `bson:"bio,omitempty"`         // Short self-description
+	AvatarURL   string `bson:"avatarUrl,omitempty"`   // Profile picture
+
+	EmailLower       string     `bson:"emailLower"`               // Lowercased email, unique across users
+	UsernameLower    string     `bson:"usernameLower"`            // Lowercased username, unique across users
+	NormalizedEmail  string     `bson:"normalizedEmail"`          // Canonical email used for duplicate detection
+	IsBanned         bool       `bson:"isBanned"`                 // Site-wide ban
+	AbuseDeletedAt   *time.Time `bson:"abuseDeletedAt,omitempty"` // When the account was deleted for abuse
+	FlaggedForReview bool       `bson:"flaggedForReview"`         // Awaiting admin review
+	IsAdmin          bool       `bson:"isAdmin,omitempty"`        // Site administrator; never cleared by saving a user
+
+	Preferences *models.UserPreferences `bson:"preferences,omitempty"` // Absent until the user sets any
 }
 
 // SaveUser creates or updates a user in MongoDB
@@ -35,12 +54,33 @@ func (m *MongoDB) SaveUser(ctx context.Context, user *models.User) error {
 		ID:             user.ID.String(),
 		Username:       user.Username,
 		Email:          user.Email,
+		EmailVerified:  user.EmailVerified,
 		HashedPassword: user.HashedPassword,
 		Karma:          user.Karma,
+		PostKarma:      user.PostKarma,
+		CommentKarma:   user.CommentKarma,
 		CreatedAt:      user.CreatedAt,
 		LastActive:     user.LastActive,
 		IsConnected:    user.IsConnected,
 		Subreddits:     make([]string, len(user.Subreddits)),
+
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		AvatarURL:   user.AvatarURL,
+
+		EmailLower:       strings.ToLower(user.Email),
+		UsernameLower:    strings.ToLower(user.Username),
+		NormalizedEmail:  user.NormalizedEmail,
+		IsBanned:         user.IsBanned,
+		AbuseDeletedAt:   user.AbuseDeletedAt,
+		FlaggedForReview: user.FlaggedForReview,
+		IsAdmin:          user.IsAdmin,
+
+		Preferences: &user.Preferences,
+	}
+
+	if doc.NormalizedEmail == "" {
+		doc.NormalizedEmail = utils.NormalizeEmail(user.Email)
 	}
 
 	// Convert subreddit UUIDs to strings
@@ -53,9 +93,66 @@ func (m *MongoDB) SaveUser(ctx context.Context, user *models.User) error {
 	update := bson.M{"$set": doc}
 
 	_, err := m.Users.UpdateOne(ctx, filter, update, opts)
+	if mongo.IsDuplicateKeyError(err) {
+		return duplicateUserError(err)
+	}
 	return err
 }
 
+// ProfileUpdate holds the profile fields to change; nil fields are left as they are
+type ProfileUpdate struct {
+	DisplayName *string
+	Bio         *string
+	AvatarURL   *string
+}
+
+// UpdateUserProfile changes the given profile fields of a user. Fields set to an empty
+// string are cleared.
+func (m *MongoDB) UpdateUserProfile(ctx context.Context, userID uuid.UUID, update ProfileUpdate) error {
+	set, unset := bson.M{}, bson.M{}
+	for field, value := range map[string]*string{
+		"displayName": update.DisplayName,
+		"bio":         update.Bio,
+		"avatarUrl":   update.AvatarURL,
+	} {
+		switch {
+		case value == nil:
+		case *value == "":
+			unset[field] = ""
+		default:
+			set[field] = *value
+		}
+	}
+	if len(set) == 0 && len(unset) == 0 {
+		return nil
+	}
+
+	changes := bson.M{}
+	if len(set) > 0 {
+		changes["$set"] = set
+	}
+	if len(unset) > 0 {
+		changes["$unset"] = unset
+	}
+	result, err := m.Users.UpdateOne(ctx, bson.M{"_id": userID.String()}, changes)
+	if err != nil {
+		return fmt.Errorf("failed to update profile: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return utils.NewAppError(utils.ErrUserNotFound, "User not found", nil)
+	}
+	return nil
+}
+
+// duplicateUserError reports which field a duplicate key error on the users collection
+// collided on. Emails and usernames are unique regardless of case.
+func duplicateUserError(err error) *utils.AppError {
+	if strings.Contains(err.Error(), "usernameLower") {
+		return utils.NewAppError(utils.ErrDuplicate, "Username is already taken", err)
+	}
+	return utils.NewAppError(utils.ErrDuplicate, "Email is already registered", err)
+}
+
 // GetUser retrieves a user from MongoDB by their ID
 func (m *MongoDB) GetUser(ctx context.Context, id uuid.UUID) (*models.User, error) {
 	var doc UserDocument
@@ -89,21 +186,58 @@ func (m *MongoDB) GetUser(ctx context.Context, id uuid.UUID) (*models.User, erro
 		ID:             userID,
 		Username:       doc.Username,
 		Email:          doc.Email,
+		EmailVerified:  doc.EmailVerified,
 		HashedPassword: doc.HashedPassword,
 		Karma:          doc.Karma,
+		PostKarma:      doc.PostKarma,
+		CommentKarma:   doc.CommentKarma,
 		CreatedAt:      doc.CreatedAt,
 		LastActive:     doc.LastActive,
 		IsConnected:    doc.IsConnected,
 		Subreddits:     subreddits,
+
+		DisplayName: doc.DisplayName,
+		Bio:         doc.Bio,
+		AvatarURL:   doc.AvatarURL,
+
+		NormalizedEmail:  doc.NormalizedEmail,
+		IsBanned:         doc.IsBanned,
+		AbuseDeletedAt:   doc.AbuseDeletedAt,
+		FlaggedForReview: doc.FlaggedForReview,
+		IsAdmin:          doc.IsAdmin,
+
+		Preferences: preferencesOf(&doc),
 	}, nil
 }
 
+// GetUserIDByUsername finds the ID of the user with a username, which is matched
+// regardless of case
+func (m *MongoDB) GetUserIDByUsername(ctx context.Context, username string) (uuid.UUID, error) {
+	var doc struct {
+		ID string `bson:"_id"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"_id": 1})
+	err := m.Users.FindOne(ctx, bson.M{"usernameLower": strings.ToLower(username)}, opts).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return uuid.Nil, utils.NewAppError(utils.ErrUserNotFound, "User not found", err)
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	userID, err := uuid.Parse(doc.ID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid user ID in database: %v", err)
+	}
+	return userID, nil
+}
+
 // GetUserByEmail retrieves a user from MongoDB by their email address
 func (m *MongoDB) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	var doc UserDocument
 
-	// Query the user document by email
-	err := m.Users.FindOne(ctx, bson.M{"email": email}).Decode(&doc)
+	// Query the user document by email, which is matched regardless of case
+	err := m.Users.FindOne(ctx, bson.M{"emailLower": strings.ToLower(email)}).Decode(&doc)
 	if err == mongo.ErrNoDocuments {
 		return nil, utils.NewAppError(utils.ErrUserNotFound, "User not found", err)
 	}
@@ -130,21 +264,192 @@ func (m *MongoDB) GetUserByEmail(ctx context.Context, email string) (*models.Use
 		ID:             userID,
 		Username:       doc.Username,
 		Email:          doc.Email,
+		EmailVerified:  doc.EmailVerified,
 		HashedPassword: doc.HashedPassword,
 		Karma:          doc.Karma,
+		PostKarma:      doc.PostKarma,
+		CommentKarma:   doc.CommentKarma,
 		CreatedAt:      doc.CreatedAt,
 		LastActive:     doc.LastActive,
 		IsConnected:    doc.IsConnected,
 		Subreddits:     subreddits,
+
+		DisplayName: doc.DisplayName,
+		Bio:         doc.Bio,
+		AvatarURL:   doc.AvatarURL,
+
+		NormalizedEmail:  doc.NormalizedEmail,
+		IsBanned:         doc.IsBanned,
+		AbuseDeletedAt:   doc.AbuseDeletedAt,
+		FlaggedForReview: doc.FlaggedForReview,
+		IsAdmin:          doc.IsAdmin,
+
+		Preferences: preferencesOf(&doc),
 	}, nil
 }
 
-// UpdateUserKarma increments a user's karma score
-func (m *MongoDB) UpdateUserKarma(ctx context.Context, userID uuid.UUID, delta int) error {
-	log.Printf("Updating karma for user %s by %d in MongoDB", userID, delta)
+// RelatedAccount is an account sharing a normalized email with another account
+type RelatedAccount struct {
+	ID               string     `bson:"_id" json:"id"`
+	Username         string     `bson:"username" json:"username"`
+	Email            string     `bson:"email" json:"email"`
+	CreatedAt        time.Time  `bson:"createdAt" json:"createdAt"`
+	LastActive       time.Time  `bson:"lastActive" json:"lastActive"`
+	IsBanned         bool       `bson:"isBanned" json:"isBanned"`
+	AbuseDeletedAt   *time.Time `bson:"abuseDeletedAt,omitempty" json:"abuseDeletedAt,omitempty"`
+	FlaggedForReview bool       `bson:"flaggedForReview" json:"flaggedForReview"`
+}
+
+// GetAccountsByNormalizedEmail returns every account registered with an email that
+// normalizes to normalizedEmail, oldest first
+func (m *MongoDB) GetAccountsByNormalizedEmail(ctx context.Context, normalizedEmail string) ([]*RelatedAccount, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+	cursor, err := m.Users.Find(ctx, bson.M{"normalizedEmail": normalizedEmail}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get related accounts: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	accounts := make([]*RelatedAccount, 0)
+	if err := cursor.All(ctx, &accounts); err != nil {
+		return nil, fmt.Errorf("failed to decode related accounts: %v", err)
+	}
+	return accounts, nil
+}
+
+// backfillUsers fills in normalized and lowercased emails and usernames for accounts
+// created before they were stored. Accounts from before karma was split by source have
+// their karma credited to posts so their totals don't change.
+func (m *MongoDB) backfillUsers(ctx context.Context) error {
+	_, err := m.Users.UpdateMany(ctx,
+		bson.M{"postKarma": bson.M{"$exists": false}},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{
+				"postKarma":    bson.M{"$ifNull": bson.A{"$karma", 0}},
+				"commentKarma": bson.M{"$ifNull": bson.A{"$commentKarma", 0}},
+			}}},
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to backfill karma breakdown: %v", err)
+	}
+
+	cursor, err := m.Users.Find(ctx,
+		bson.M{"normalizedEmail": bson.M{"$exists": false}},
+		options.Find().SetProjection(bson.M{"_id": 1, "email": 1}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to find users without normalized email: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID    string `bson:"_id"`
+			Email string `bson:"email"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return fmt.Errorf("failed to decode user: %v", err)
+		}
+		_, err := m.Users.UpdateOne(ctx,
+			bson.M{"_id": doc.ID},
+			bson.M{"$set": bson.M{"normalizedEmail": utils.NormalizeEmail(doc.Email)}},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to backfill normalized email: %v", err)
+		}
+	}
+
+	for _, field := range []string{"email", "username"} {
+		lowerField := field + "Lower"
+		backfill := mongo.Pipeline{{{Key: "$set", Value: bson.M{lowerField: bson.M{"$toLower": "$" + field}}}}}
+		if _, err := m.Users.UpdateMany(ctx, bson.M{lowerField: bson.M{"$exists": false}}, backfill); err != nil {
+			return fmt.Errorf("failed to backfill lowercased %s: %v", field, err)
+		}
+	}
+	return nil
+}
+
+// EnsureUserIndexes indexes normalized emails and creates the indexes that keep emails
+// and usernames unique regardless of case. Each index is created whether or not the
+// others could be.
+func (m *MongoDB) EnsureUserIndexes(ctx context.Context) error {
+	var errs []error
+	_, err := m.Users.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "normalizedEmail", Value: 1}},
+	})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to create user indexes: %v", err))
+	}
+
+	// Accounts created before case was ignored may collide. They are logged for an
+	// operator to resolve; until they are, the unique index can't be created.
+	for _, field := range []string{"email", "username"} {
+		collisions, err := m.findUserCollisions(ctx, field)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, values := range collisions {
+			log.Printf("Warning: user %ss differ only in case: %s", field, strings.Join(values, ", "))
+		}
+
+		err = createUniqueIndex(ctx, m.Users, field, mongo.IndexModel{
+			Keys:    bson.D{{Key: field + "Lower", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// findUserCollisions returns the groups of values of a user field that are the same
+// once lowercased
+func (m *MongoDB) findUserCollisions(ctx context.Context, field string) ([][]string, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{
+			"_id":    "$" + field + "Lower",
+			"values": bson.M{"$push": "$" + field},
+			"count":  bson.M{"$sum": 1},
+		}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+	}
+	cursor, err := m.Users.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user %s collisions: %v", field, err)
+	}
+	var groups []struct {
+		Values []string `bson:"values"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, fmt.Errorf("failed to decode user %s collisions: %v", field, err)
+	}
+
+	collisions := make([][]string, 0, len(groups))
+	for _, group := range groups {
+		collisions = append(collisions, group.Values)
+	}
+	return collisions, nil
+}
+
+// UpdateUserKarma increments a user's total karma along with the bucket for
+// the given source ("post" or "comment")
+func (m *MongoDB) UpdateUserKarma(ctx context.Context, userID uuid.UUID, delta int, source string) error {
+	log.Printf("Updating %s karma for user %s by %d in MongoDB", source, userID, delta)
+
+	var bucket string
+	switch source {
+	case "post":
+		bucket = "postKarma"
+	case "comment":
+		bucket = "commentKarma"
+	default:
+		return fmt.Errorf("unknown karma source: %q", source)
+	}
 
 	filter := bson.M{"_id": userID.String()}
-	update := bson.M{"$inc": bson.M{"karma": delta}}
+	update := bson.M{"$inc": bson.M{"karma": delta, bucket: delta}}
 
 	result, err := m.Users.UpdateOne(ctx, filter, update)
 	if err != nil {
@@ -176,6 +481,92 @@ func (m *MongoDB) UpdateUserActivity(ctx context.Context, userID uuid.UUID, isCo
 	return nil
 }
 
+// TouchUserLastActive sets a user's last active time to at, unless it was already set
+// within minInterval of it
+func (m *MongoDB) TouchUserLastActive(ctx context.Context, userID uuid.UUID, at time.Time, minInterval time.Duration) error {
+	filter := bson.M{
+		"_id":        userID.String(),
+		"lastActive": bson.M{"$not": bson.M{"$gt": at.Add(-minInterval)}},
+	}
+	if _, err := m.Users.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"lastActive": at}}); err != nil {
+		return fmt.Errorf("failed to update last active time: %v", err)
+	}
+	return nil
+}
+
+// preferencesOf returns a user's stored preferences, or the defaults if they never set any
+func preferencesOf(doc *UserDocument) models.UserPreferences {
+	if doc.Preferences == nil {
+		return models.UserPreferences{}
+	}
+	return *doc.Preferences
+}
+
+// GetUserPreferences returns a user's preferences, with defaults for anything they
+// never set
+func (m *MongoDB) GetUserPreferences(ctx context.Context, userID uuid.UUID) (*models.UserPreferences, error) {
+	var doc UserDocument
+	opts := options.FindOne().SetProjection(bson.M{"preferences": 1})
+	err := m.Users.FindOne(ctx, bson.M{"_id": userID.String()}, opts).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, utils.NewAppError(utils.ErrUserNotFound, "User not found", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get preferences: %v", err)
+	}
+
+	prefs := preferencesOf(&doc)
+	return &prefs, nil
+}
+
+// SetUserPreferences replaces a user's preferences
+func (m *MongoDB) SetUserPreferences(ctx context.Context, userID uuid.UUID, prefs *models.UserPreferences) error {
+	update := bson.M{"$set": bson.M{"preferences": prefs}}
+	result, err := m.Users.UpdateOne(ctx, bson.M{"_id": userID.String()}, update)
+	if err != nil {
+		return fmt.Errorf("failed to save preferences: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return utils.NewAppError(utils.ErrUserNotFound, "User not found", nil)
+	}
+	return nil
+}
+
+// UpdateUserEmail changes a user's email address, which then needs verifying again.
+// Addresses used by another account fail with ErrDuplicate.
+func (m *MongoDB) UpdateUserEmail(ctx context.Context, userID uuid.UUID, email string) error {
+	update := bson.M{"$set": bson.M{
+		"email":           email,
+		"emailLower":      strings.ToLower(email),
+		"normalizedEmail": utils.NormalizeEmail(email),
+		"emailVerified":   false,
+	}}
+
+	result, err := m.Users.UpdateOne(ctx, bson.M{"_id": userID.String()}, update)
+	if mongo.IsDuplicateKeyError(err) {
+		return duplicateUserError(err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update email: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return utils.NewAppError(utils.ErrUserNotFound, "User not found", nil)
+	}
+	return nil
+}
+
+// UpdateUserPassword replaces a user's stored password hash
+func (m *MongoDB) UpdateUserPassword(ctx context.Context, userID uuid.UUID, hashedPassword string) error {
+	result, err := m.Users.UpdateOne(ctx, bson.M{"_id": userID.String()}, bson.M{"$set": bson.M{"hashedPassword": hashedPassword}})
+	if err != nil {
+		return fmt.Errorf("failed to update password: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return utils.NewAppError(utils.ErrUserNotFound, "User not found", nil)
+	}
+	return nil
+}
+
 // GetUserSubreddits retrieves the subreddits a user is subscribed to
 func (m *MongoDB) GetUserSubreddits(ctx context.Context, userID uuid.UUID) ([]SubredditTitles, error) {
 	var user models.User
@@ -211,25 +602,46 @@ func (m *MongoDB) GetUserSubreddits(ctx context.Context, userID uuid.UUID) ([]Su
 	return subreddits, nil
 }
 
-// UpdateUserSubreddits adds or removes a subreddit from a user's subscriptions
-func (m *MongoDB) UpdateUserSubreddits(ctx context.Context, userID uuid.UUID, subredditID uuid.UUID, isJoining bool) error {
+// UpdateUserSubreddits adds or removes a subreddit from a user's subscriptions. It
+// reports whether that changed anything, which it doesn't when the user was already in
+// or out of the subreddit.
+func (m *MongoDB) UpdateUserSubreddits(ctx context.Context, userID uuid.UUID, subredditID uuid.UUID, isJoining bool) (bool, error) {
 	filter := bson.M{"_id": userID.String()}
 	var update bson.M
 
 	if isJoining {
 		update = bson.M{"$addToSet": bson.M{"subreddits": subredditID.String()}}
 	} else {
-		update = bson.M{"$pull": bson.M{"subreddits": subredditID.String()}}
+		// Members who leave lose their flair there
+		update = bson.M{
+			"$pull":  bson.M{"subreddits": subredditID.String()},
+			"$unset": bson.M{userFlairField(subredditID): ""},
+		}
 	}
 
 	result, err := m.Users.UpdateOne(ctx, filter, update)
 	if err != nil {
-		return err
+		return false, err
 	}
 	if result.MatchedCount == 0 {
-		return utils.NewAppError(utils.ErrUserNotFound, "User not found", nil)
+		return false, utils.NewAppError(utils.ErrUserNotFound, "User not found", nil)
 	}
-	return nil
+	return result.ModifiedCount > 0, nil
+}
+
+// RemoveSubredditFromUsers drops a subreddit from the subscriptions of every user in it
+// and returns how many users that affected
+func (m *MongoDB) RemoveSubredditFromUsers(ctx context.Context, subredditID uuid.UUID) (int64, error) {
+	result, err := m.Users.UpdateMany(ctx,
+		bson.M{"subreddits": subredditID.String()},
+		bson.M{
+			"$pull":  bson.M{"subreddits": subredditID.String()},
+			"$unset": bson.M{userFlairField(subredditID): ""},
+		})
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove subreddit from users: %v", err)
+	}
+	return result.ModifiedCount, nil
 }
 
 // SubredditTitles represents a lightweight structure for subreddit ID and name
@@ -0,0 +1,54 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/database/dbtest"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Accounts from before emails were unique regardless of case can keep the email index
+// from being created. The username index must still be created, and the failure
+// returned so the server doesn't start without the email index.
+func TestEmailCollisionsDoNotHoldBackUsernameIndex(t *testing.T) {
+	mongodb := dbtest.New(t)
+	ctx := context.Background()
+	if _, err := mongodb.Users.Indexes().DropAll(ctx); err != nil {
+		t.Fatalf("dropping user indexes: %v", err)
+	}
+
+	// As backfilled, with emails that collide once lowercased
+	for _, user := range []bson.M{
+		{"_id": uuid.New().String(), "username": "alligator", "usernameLower": "alligator",
+			"email": "Gator@example.com", "emailLower": "gator@example.com"},
+		{"_id": uuid.New().String(), "username": "crocodile", "usernameLower": "crocodile",
+			"email": "gator@example.com", "emailLower": "gator@example.com"},
+	} {
+		if _, err := mongodb.Users.InsertOne(ctx, user); err != nil {
+			t.Fatalf("inserting legacy user: %v", err)
+		}
+	}
+
+	err := mongodb.EnsureIndexes(ctx)
+	var uniqueErr *database.UniqueIndexError
+	if !errors.As(err, &uniqueErr) || uniqueErr.Index != "email" {
+		t.Fatalf("EnsureIndexes = %v, want the unique email index to be missing", err)
+	}
+
+	duplicate := &models.User{
+		ID:        uuid.New(),
+		Username:  "Alligator",
+		Email:     "someone@example.com",
+		CreatedAt: time.Now(),
+	}
+	if err := mongodb.SaveUser(ctx, duplicate); !utils.IsErrorCode(err, utils.ErrDuplicate) {
+		t.Fatalf("SaveUser with a taken username = %v, want ErrDuplicate", err)
+	}
+}
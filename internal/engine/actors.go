@@ -2,8 +2,10 @@ package engine
 
 import (
 	"fmt"
+	"gator-swamp/internal/config"
 	"gator-swamp/internal/database"
 	"gator-swamp/internal/engine/actors"
+	"gator-swamp/internal/i18n"
 	"gator-swamp/internal/utils"
 	"log"
 	"time"
@@ -38,6 +40,7 @@ type (
 type Engine struct {
 	subredditActor *actor.PID
 	postActor      *actor.PID
+	commentActor   *actor.PID
 	userSupervisor *actor.PID
 	context        *actor.RootContext
 	metrics        *utils.MetricsCollector
@@ -45,7 +48,7 @@ type Engine struct {
 }
 
 // NewEngine creates a new engine instance with all required actors
-func NewEngine(system *actor.ActorSystem, metrics *utils.MetricsCollector, mongodb *database.MongoDB) *Engine {
+func NewEngine(system *actor.ActorSystem, metrics *utils.MetricsCollector, mongodb *database.MongoDB, cfg *config.Config) *Engine {
 	context := system.Root
 	log.Printf("Creating Engine with actors...")
 
@@ -64,24 +67,30 @@ func NewEngine(system *actor.ActorSystem, metrics *utils.MetricsCollector, mongo
 
 	// Now create other actors with enginePID
 	supervisorProps := actor.PropsFromProducer(func() actor.Actor {
-		return actors.NewUserSupervisor(e.mongodb)
+		return actors.NewUserSupervisor(e.mongodb, cfg.DuplicateAccountAction, cfg.PasswordHashCost)
 	})
 
 	subredditProps := actor.PropsFromProducer(func() actor.Actor {
-		return actors.NewSubredditActor(metrics, e.mongodb)
+		return actors.NewSubredditActor(metrics, e.mongodb, cfg.SubredditCreation)
 	})
 
 	postProps := actor.PropsFromProducer(func() actor.Actor {
-		return actors.NewPostActor(metrics, enginePID, e.mongodb)
+		return actors.NewPostActor(metrics, enginePID, e.mongodb, cfg.UndeleteWindow, cfg.DuplicateLinkWindow, cfg.ArchiveAfter, cfg.MaxPostTitleLength, cfg.MaxPostContentLength, cfg.MediaExtensions, cfg.PostRateLimits, cfg.ModeratorPostRateFactor, cfg.IdempotencyKeyTTL, cfg.MinControversialVotes)
+	})
+
+	commentProps := actor.PropsFromProducer(func() actor.Actor {
+		return actors.NewCommentActor(enginePID, e.mongodb, cfg.UndeleteWindow, cfg.ArchiveAfter, cfg.MaxCommentDepth)
 	})
 
 	userSupervisorPID := context.Spawn(supervisorProps)
 	subredditPID := context.Spawn(subredditProps)
 	postPID := context.Spawn(postProps)
+	commentPID := context.Spawn(commentProps)
 
 	e.userSupervisor = userSupervisorPID
 	e.subredditActor = subredditPID
 	e.postActor = postPID
+	e.commentActor = commentPID
 
 	return e
 }
@@ -120,7 +129,7 @@ func (e *Engine) Receive(context actor.Context) {
 		userState, ok := userResult.(*actors.UserState)
 		if !ok || userState == nil {
 			log.Printf("Engine: User not found")
-			context.Respond(utils.NewAppError(utils.ErrNotFound, "User not found", nil))
+			context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrUserNotFound, nil, nil))
 			return
 		}
 
@@ -159,7 +168,7 @@ func (e *Engine) Receive(context actor.Context) {
 
 		userState, ok := result.(*actors.UserState)
 		if !ok || userState == nil {
-			context.Respond(utils.NewAppError(utils.ErrNotFound, "User not found", nil))
+			context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrUserNotFound, nil, nil))
 			return
 		}
 
@@ -211,6 +220,29 @@ func (e *Engine) Receive(context actor.Context) {
 		log.Printf("Engine: Forwarding karma update to UserSupervisor")
 		context.Send(e.userSupervisor, msg)
 
+	case *actors.ReloadPostCommentsMsg:
+		context.Send(e.commentActor, msg)
+
+	case *actors.DeleteSubredditMsg:
+		future := context.RequestFuture(e.subredditActor, msg, actors.SubredditDeleteTimeout)
+		result, err := future.Result()
+		if err != nil {
+			context.Respond(utils.NewAppError(utils.ErrActorTimeout, "Failed to delete subreddit", err))
+			return
+		}
+
+		// Once deleted, the subreddit's posts leave the post cache and their comments
+		// are reloaded as deleted
+		if deletion, ok := result.(*actors.SubredditDeletion); ok && !deletion.DryRun {
+			context.Send(e.postActor, &actors.EvictSubredditPostsMsg{SubredditID: msg.SubredditID})
+			for _, id := range deletion.PostIDs {
+				if postID, err := uuid.Parse(id); err == nil {
+					context.Send(e.commentActor, &actors.ReloadPostCommentsMsg{PostID: postID})
+				}
+			}
+		}
+		context.Respond(result)
+
 	case *actors.GetUserFeedMsg:
 		// First validate user exists
 		userFuture := context.RequestFuture(e.userSupervisor,
@@ -225,7 +257,7 @@ func (e *Engine) Receive(context actor.Context) {
 
 		userState, ok := result.(*actors.UserState)
 		if !ok || userState == nil {
-			context.Respond(utils.NewAppError(utils.ErrNotFound, "User not found", nil))
+			context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrUserNotFound, nil, nil))
 			return
 		}
 
@@ -279,6 +311,27 @@ func isSubredditMessage(msg interface{}) bool {
 		*actors.GetSubredditMembersMsg,
 		*actors.GetSubredditByIDMsg,
 		*actors.GetSubredditByNameMsg,
+		*actors.ListFlairsMsg,
+		*actors.CreateFlairMsg,
+		*actors.UpdateFlairMsg,
+		*actors.DeleteFlairMsg,
+		*actors.ListUserFlairsMsg,
+		*actors.CreateUserFlairMsg,
+		*actors.UpdateUserFlairMsg,
+		*actors.DeleteUserFlairMsg,
+		*actors.SetUserFlairMsg,
+		*actors.UpdateSubredditSettingsMsg,
+		*actors.ApproveSubredditUserMsg,
+		*actors.SearchSubredditsMsg,
+		*actors.ListModeratorsMsg,
+		*actors.AddModeratorMsg,
+		*actors.RemoveModeratorMsg,
+		*actors.BanUserMsg,
+		*actors.UnbanUserMsg,
+		*actors.ListBansMsg,
+		*actors.GetSubredditAnalyticsMsg,
+		*actors.GetAutomodMsg,
+		*actors.UpdateAutomodMsg,
 		*actors.GetCountsMsg:
 		return true
 	default:
@@ -302,10 +355,34 @@ func isUserMessage(msg interface{}) bool {
 func isPostMessage(msg interface{}) bool {
 	switch msg.(type) {
 	case *actors.CreatePostMsg,
+		*actors.CrosspostMsg,
+		*actors.VotePollMsg,
+		*actors.GetScheduledPostsMsg,
+		*actors.CancelScheduledPostMsg,
+		*actors.GetModQueueMsg,
+		*actors.ModQueueActionMsg,
 		*actors.GetPostMsg,
+		*actors.GetPostBySlugMsg,
+		*actors.GetPostsByIDsMsg,
 		*actors.GetSubredditPostsMsg,
+		*actors.GetTopPostsMsg,
+		*actors.GetRelatedPostsMsg,
+		*actors.GetTrendingPostsMsg,
 		*actors.VotePostMsg,
-		*actors.DeletePostMsg:
+		*actors.UnvotePostMsg,
+		*actors.VotePostBatchMsg,
+		*actors.GetPostVoteStatusesMsg,
+		*actors.EditPostMsg,
+		*actors.DeletePostMsg,
+		*actors.UndeletePostMsg,
+		*actors.SetPostFlairMsg,
+		*actors.PinPostMsg,
+		*actors.HidePostMsg,
+		*actors.UnhidePostMsg,
+		*actors.GetHiddenPostsMsg,
+		*actors.GetFollowingFeedMsg,
+		*actors.GetUserPostsMsg,
+		*actors.GetUserActivityMsg:
 		return true
 	default:
 		return false
@@ -325,6 +402,10 @@ func (e *Engine) GetPostActor() *actor.PID {
 	return e.postActor
 }
 
+func (e *Engine) GetCommentActor() *actor.PID {
+	return e.commentActor
+}
+
 func (e *Engine) GetMongoDB() *database.MongoDB {
 	return e.mongodb
 }
@@ -0,0 +1,161 @@
+package actors
+
+import (
+	"fmt"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxAutomodRules         = 100
+	maxAutomodPatternLength = 256
+
+	// Compiled patterns kept by each matcher before it starts over. Patterns only change
+	// when moderators edit their rules, so this is rarely reached.
+	maxCompiledAutomodPatterns = 1000
+)
+
+// automodDomainPattern matches the domains automod rules can name
+var automodDomainPattern = regexp.MustCompile(`^([a-z0-9-]+\.)+[a-z]{2,}$`)
+
+// automodLinkPattern finds web addresses in the text of posts and comments
+var automodLinkPattern = regexp.MustCompile(`(?i)https?://[^\s<>()\[\]"']+`)
+
+// automodSeverity orders the automod actions, so the strictest of several matching rules wins
+var automodSeverity = map[string]int{
+	models.AutomodActionReport: 1,
+	models.AutomodActionQueue:  2,
+	models.AutomodActionRemove: 3,
+}
+
+// compileAutomodPattern compiles a rule's pattern the way it is matched
+func compileAutomodPattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("(?i)" + pattern)
+}
+
+// validateAutomodRules checks the rules moderators submit and returns them cleaned up:
+// domains lowercased without a leading "www.", and rules without an ID given a new one.
+// Patterns are compiled here so a bad one is rejected before it can reach submissions.
+func validateAutomodRules(rules []models.AutomodRule) ([]models.AutomodRule, *utils.AppError) {
+	if len(rules) > maxAutomodRules {
+		return nil, utils.NewAppError(utils.ErrInvalidInput,
+			fmt.Sprintf("A subreddit can have at most %d automod rules", maxAutomodRules), nil)
+	}
+
+	cleaned := make([]models.AutomodRule, 0, len(rules))
+	seen := make(map[uuid.UUID]bool, len(rules))
+	for i, rule := range rules {
+		if _, ok := automodSeverity[rule.Action]; !ok {
+			return nil, utils.NewAppError(utils.ErrInvalidInput,
+				fmt.Sprintf("Rule %d: action must be remove, queue or report", i+1), nil)
+		}
+
+		rule.Domain = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(rule.Domain)), "www.")
+		if (rule.Pattern == "") == (rule.Domain == "") {
+			return nil, utils.NewAppError(utils.ErrInvalidInput,
+				fmt.Sprintf("Rule %d: set either a pattern or a domain", i+1), nil)
+		}
+		if rule.Pattern != "" {
+			if len(rule.Pattern) > maxAutomodPatternLength {
+				return nil, utils.NewAppError(utils.ErrInvalidInput,
+					fmt.Sprintf("Rule %d: pattern must be at most %d characters", i+1, maxAutomodPatternLength), nil)
+			}
+			if _, err := compileAutomodPattern(rule.Pattern); err != nil {
+				return nil, utils.NewAppError(utils.ErrInvalidInput,
+					fmt.Sprintf("Rule %d: invalid pattern: %v", i+1, err), nil)
+			}
+		}
+		if rule.Domain != "" && !automodDomainPattern.MatchString(rule.Domain) {
+			return nil, utils.NewAppError(utils.ErrInvalidInput,
+				fmt.Sprintf("Rule %d: invalid domain", i+1), nil)
+		}
+
+		if rule.ID == uuid.Nil {
+			rule.ID = uuid.New()
+		}
+		if seen[rule.ID] {
+			return nil, utils.NewAppError(utils.ErrInvalidInput,
+				fmt.Sprintf("Rule %d: duplicate rule ID", i+1), nil)
+		}
+		seen[rule.ID] = true
+		cleaned = append(cleaned, rule)
+	}
+	return cleaned, nil
+}
+
+// automodMatcher evaluates a subreddit's automod rules against new submissions,
+// keeping the patterns it has compiled
+type automodMatcher struct {
+	patterns map[string]*regexp.Regexp
+}
+
+func newAutomodMatcher() *automodMatcher {
+	return &automodMatcher{patterns: make(map[string]*regexp.Regexp)}
+}
+
+// match returns the strictest rule that text, or one of the links in it or in links,
+// matches, or nil if none does. The first such rule wins a tie.
+func (m *automodMatcher) match(rules []models.AutomodRule, text string, links ...string) *models.AutomodRule {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	var hosts []string
+	links = append(links[:len(links):len(links)], automodLinkPattern.FindAllString(text, -1)...)
+	for _, link := range links {
+		if parsed, err := url.Parse(link); err == nil && parsed.Hostname() != "" {
+			hosts = append(hosts, strings.ToLower(parsed.Hostname()))
+		}
+	}
+
+	var matched *models.AutomodRule
+	for i := range rules {
+		rule := &rules[i]
+		if matched != nil && automodSeverity[rule.Action] <= automodSeverity[matched.Action] {
+			continue
+		}
+		if rule.Pattern != "" && m.matchesPattern(rule.Pattern, text) ||
+			rule.Domain != "" && matchesDomain(rule.Domain, hosts) {
+			matched = rule
+		}
+	}
+	if matched == nil {
+		return nil
+	}
+	rule := *matched
+	return &rule
+}
+
+// matchesPattern reports whether text matches pattern. Patterns are validated when
+// rules are saved; one that still fails to compile is logged and never matches.
+func (m *automodMatcher) matchesPattern(pattern, text string) bool {
+	re, ok := m.patterns[pattern]
+	if !ok {
+		compiled, err := compileAutomodPattern(pattern)
+		if err != nil {
+			log.Printf("Automod: skipping invalid pattern %q: %v", pattern, err)
+		}
+		if len(m.patterns) >= maxCompiledAutomodPatterns {
+			m.patterns = make(map[string]*regexp.Regexp)
+		}
+		m.patterns[pattern] = compiled
+		re = compiled
+	}
+	return re != nil && re.MatchString(text)
+}
+
+// matchesDomain reports whether any of hosts is domain or one of its subdomains
+func matchesDomain(domain string, hosts []string) bool {
+	for _, host := range hosts {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
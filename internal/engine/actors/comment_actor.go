@@ -2,10 +2,14 @@ package actors
 
 import (
 	stdctx "context"
+	"fmt"
 	"gator-swamp/internal/database"
+	"gator-swamp/internal/i18n"
 	"gator-swamp/internal/models"
+	"gator-swamp/internal/types"
 	"gator-swamp/internal/utils"
 	"log"
+	"sort"
 	"time"
 
 	"github.com/asynkron/protoactor-go/actor"
@@ -13,6 +17,11 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 )
 
+const (
+	defaultCommentPageSize = 50
+	maxCommentPageSize     = 200
+)
+
 // Message types for CommentActor
 type (
 	CreateCommentMsg struct {
@@ -34,12 +43,86 @@ type (
 		AuthorID  uuid.UUID `json:"authorId"`
 	}
 
+	UndeleteCommentMsg struct {
+		CommentID   uuid.UUID `json:"commentId"`
+		RequesterID uuid.UUID `json:"requesterId"`
+	}
+
+	// ReloadPostCommentsMsg refreshes the cached comments of a post after they were
+	// changed in MongoDB by another actor, e.g. when the post was deleted
+	ReloadPostCommentsMsg struct {
+		PostID uuid.UUID
+	}
+
 	GetCommentMsg struct {
 		CommentID uuid.UUID `json:"commentId"`
+		ViewerID  uuid.UUID `json:"viewerId"` // Comments in private subreddits are only served to members; uuid.Nil for anonymous viewers
+	}
+
+	// GetCommentContextMsg requests a comment with up to Context ancestors above it and
+	// its immediate replies below, for rendering a permalink
+	GetCommentContextMsg struct {
+		CommentID uuid.UUID `json:"commentId"`
+		Context   int       `json:"context"`
+		ViewerID  uuid.UUID `json:"viewerId"` // See GetCommentMsg
+	}
+
+	// DistinguishCommentMsg marks a comment as coming from a moderator or the post's author
+	// and optionally pins it above the post's other comments
+	DistinguishCommentMsg struct {
+		CommentID       uuid.UUID `json:"commentId"`
+		RequesterID     uuid.UUID `json:"requesterId"`
+		DistinguishedAs string    `json:"distinguishedAs"` // A models.CommentDistinguished* value, or empty to clear
+		Sticky          bool      `json:"sticky"`
+	}
+
+	// SearchCommentsMsg searches the text of a post's comments
+	SearchCommentsMsg struct {
+		PostID   uuid.UUID `json:"postId"`
+		Query    string    `json:"query"`
+		Limit    int       `json:"limit"`
+		ViewerID uuid.UUID `json:"viewerId"` // See GetCommentMsg
+	}
+
+	// GetCommentHistoryMsg requests the previous versions of a comment
+	GetCommentHistoryMsg struct {
+		CommentID   uuid.UUID `json:"commentId"`
+		RequesterID uuid.UUID `json:"requesterId"` // Must be the author or a moderator of the comment's subreddit
 	}
 
 	GetCommentsForPostMsg struct {
-		PostID uuid.UUID `json:"postId"`
+		PostID   uuid.UUID `json:"postId"`
+		Sort     string    `json:"sort"` // One of the database.Sort* comment orders, defaults to old
+		Limit    int       `json:"limit"`
+		Cursor   string    `json:"cursor"`
+		ViewerID uuid.UUID `json:"viewerId"` // See GetCommentMsg
+	}
+
+	// GetCommentTreeMsg requests a page of a post's top-level comments with their replies nested beneath
+	GetCommentTreeMsg struct {
+		PostID   uuid.UUID  `json:"postId"`
+		Sort     string     `json:"sort"` // Orders top-level comments and the replies at every level
+		Limit    int        `json:"limit"`
+		Cursor   string     `json:"cursor"`
+		MaxDepth int        `json:"maxDepth"`           // Levels shown per thread, counting its root; 0 for no limit
+		MaxNodes int        `json:"maxNodes"`           // Replies shown across the response; 0 for no limit
+		BranchID *uuid.UUID `json:"branchId,omitempty"` // Load only this comment's subtree instead of a page of threads
+
+		CollapseThreshold int  `json:"collapseThreshold"` // Comments at or below this karma are collapsed
+		Expand            bool `json:"expand"`            // Keep the content and replies of collapsed comments
+
+		// Comments by users the viewer blocked are masked, and private subreddits' comments
+		// are only served to members; uuid.Nil for anonymous viewers
+		ViewerID uuid.UUID `json:"viewerId"`
+	}
+
+	// GetUserCommentsMsg requests a page of a user's comments, newest first
+	GetUserCommentsMsg struct {
+		UserID         uuid.UUID `json:"userId"`
+		Limit          int       `json:"limit"`
+		Cursor         string    `json:"cursor"`
+		IncludeDeleted bool      `json:"includeDeleted"` // Show deleted comments as "[deleted]" instead of omitting them
+		ViewerID       uuid.UUID `json:"viewerId"`       // Comments in private subreddits the viewer can't read are left out
 	}
 
 	VoteCommentMsg struct {
@@ -51,22 +134,52 @@ type (
 	loadCommentsFromDBMsg struct{}
 )
 
+// CommentPermalink is a permalinked comment shown within its thread
+type CommentPermalink struct {
+	CommentID      uuid.UUID           `json:"commentId"`      // The permalinked comment, somewhere inside Thread
+	HasMoreParents bool                `json:"hasMoreParents"` // Ancestors above the requested context were left out
+	Thread         *models.CommentNode `json:"thread"`         // Outermost included ancestor down to the comment's immediate replies
+}
+
+// CommentSearchResult is a comment matching a search, with the IDs of its ancestors from
+// the top-level comment down so a client can expand the thread to it
+type CommentSearchResult struct {
+	*models.Comment
+	AncestorIDs []uuid.UUID `json:"ancestorIds"`
+}
+
+// CommentHistory is a comment's current content along with its earlier versions
+type CommentHistory struct {
+	CommentID uuid.UUID            `json:"commentId"`
+	Content   string               `json:"content"`
+	IsEdited  bool                 `json:"isEdited"`
+	Edits     []models.CommentEdit `json:"edits"` // Oldest first
+}
+
 // CommentActor manages comment operations
 type CommentActor struct {
-	comments     map[uuid.UUID]*models.Comment
-	postComments map[uuid.UUID][]uuid.UUID
-	commentVotes map[uuid.UUID]map[uuid.UUID]bool
-	enginePID    *actor.PID
-	mongodb      *database.MongoDB
+	comments       map[uuid.UUID]*models.Comment
+	postComments   map[uuid.UUID][]uuid.UUID
+	commentVotes   map[uuid.UUID]map[uuid.UUID]bool
+	enginePID      *actor.PID
+	mongodb        *database.MongoDB
+	undeleteWindow time.Duration // How long authors can restore their deleted comments
+	archiveAfter   time.Duration // Age at which posts stop accepting comments; 0 disables archival
+	maxDepth       int           // Deepest reply level accepted
+	automod        *automodMatcher
 }
 
-func NewCommentActor(enginePID *actor.PID, mongodb *database.MongoDB) actor.Actor {
+func NewCommentActor(enginePID *actor.PID, mongodb *database.MongoDB, undeleteWindow, archiveAfter time.Duration, maxDepth int) actor.Actor {
 	return &CommentActor{
-		comments:     make(map[uuid.UUID]*models.Comment),
-		postComments: make(map[uuid.UUID][]uuid.UUID),
-		commentVotes: make(map[uuid.UUID]map[uuid.UUID]bool),
-		enginePID:    enginePID,
-		mongodb:      mongodb,
+		comments:       make(map[uuid.UUID]*models.Comment),
+		postComments:   make(map[uuid.UUID][]uuid.UUID),
+		commentVotes:   make(map[uuid.UUID]map[uuid.UUID]bool),
+		enginePID:      enginePID,
+		mongodb:        mongodb,
+		undeleteWindow: undeleteWindow,
+		archiveAfter:   archiveAfter,
+		maxDepth:       maxDepth,
+		automod:        newAutomodMatcher(),
 	}
 }
 
@@ -91,12 +204,36 @@ func (a *CommentActor) Receive(context actor.Context) {
 	case *DeleteCommentMsg:
 		a.handleDeleteComment(context, msg)
 
+	case *UndeleteCommentMsg:
+		a.handleUndeleteComment(context, msg)
+
+	case *ReloadPostCommentsMsg:
+		a.handleReloadPostComments(msg)
+
 	case *GetCommentMsg:
 		a.handleGetComment(context, msg)
 
+	case *GetCommentContextMsg:
+		a.handleGetCommentContext(context, msg)
+
+	case *GetCommentHistoryMsg:
+		a.handleGetCommentHistory(context, msg)
+
+	case *DistinguishCommentMsg:
+		a.handleDistinguishComment(context, msg)
+
+	case *SearchCommentsMsg:
+		a.handleSearchComments(context, msg)
+
 	case *GetCommentsForPostMsg:
 		a.handleGetPostComments(context, msg)
 
+	case *GetCommentTreeMsg:
+		a.handleGetCommentTree(context, msg)
+
+	case *GetUserCommentsMsg:
+		a.handleGetUserComments(context, msg)
+
 	case *VoteCommentMsg:
 		a.handleVoteComment(context, msg)
 	}
@@ -124,6 +261,7 @@ func (a *CommentActor) handleLoadComments(context actor.Context) {
 		id, _ := uuid.Parse(doc.ID)
 		authorID, _ := uuid.Parse(doc.AuthorID)
 		postID, _ := uuid.Parse(doc.PostID)
+		subredditID, _ := uuid.Parse(doc.SubredditID)
 
 		// Parse parent ID if it exists
 		var parentID *uuid.UUID
@@ -142,18 +280,25 @@ func (a *CommentActor) handleLoadComments(context actor.Context) {
 
 		// Create the comment
 		comment := &models.Comment{
-			ID:        id,
-			Content:   doc.Content,
-			AuthorID:  authorID,
-			PostID:    postID,
-			ParentID:  parentID,
-			Children:  children,
-			CreatedAt: doc.CreatedAt,
-			UpdatedAt: doc.UpdatedAt,
-			IsDeleted: doc.IsDeleted,
-			Upvotes:   doc.Upvotes,
-			Downvotes: doc.Downvotes,
-			Karma:     doc.Karma,
+			ID:              id,
+			Content:         doc.Content,
+			AuthorID:        authorID,
+			PostID:          postID,
+			SubredditID:     subredditID,
+			ParentID:        parentID,
+			Depth:           doc.Depth,
+			Path:            doc.Path,
+			Children:        children,
+			CreatedAt:       doc.CreatedAt,
+			UpdatedAt:       doc.UpdatedAt,
+			IsDeleted:       doc.IsDeleted,
+			IsEdited:        doc.IsEdited,
+			IsStickied:      doc.IsStickied,
+			DistinguishedAs: doc.Distinguish,
+			DeletedAt:       doc.DeletedAt,
+			Upvotes:         doc.Upvotes,
+			Downvotes:       doc.Downvotes,
+			Karma:           doc.Karma,
 		}
 
 		// Update local caches
@@ -173,11 +318,32 @@ func (a *CommentActor) handleCreateComment(context actor.Context, msg *CreateCom
 	post, err := a.mongodb.GetPost(ctx, msg.PostID)
 	if err != nil {
 		log.Printf("Error fetching post: %v", err)
-		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch parent post", err))
+		if utils.IsErrorCode(err, utils.ErrNotFound) {
+			context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil))
+		} else {
+			context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch parent post", err))
+		}
 		return
 	}
 
 	now := time.Now()
+	if post.ArchivedAt(now, a.archiveAfter) {
+		context.Respond(utils.NewLocalizedError(utils.ErrArchived, i18n.ErrPostArchived, nil, nil))
+		return
+	}
+	if appErr := checkCanViewSubreddit(ctx, a.mongodb, post.SubredditID, msg.AuthorID); appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+	if appErr := checkSubredditBan(ctx, a.mongodb, post.SubredditID, msg.AuthorID); appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+	rule, appErr := a.automodRule(ctx, post.SubredditID, msg.AuthorID, msg.Content)
+	if appErr != nil {
+		context.Respond(appErr)
+		return
+	}
 	commentID := uuid.New()
 	log.Printf("Generated new comment ID: %s", commentID)
 
@@ -195,21 +361,39 @@ func (a *CommentActor) handleCreateComment(context actor.Context, msg *CreateCom
 		Upvotes:     0,
 		Downvotes:   0,
 		Karma:       0,
+		Path:        database.CommentPath("", commentID),
+		Automod:     rule,
 	}
+
+	var parentComment *models.Comment
 	if msg.ParentID != nil {
 		log.Printf("This is a reply to comment ID: %s", msg.ParentID.String())
 
-		parentComment, err := a.mongodb.GetComment(ctx, *msg.ParentID)
+		parentComment, err = a.mongodb.GetComment(ctx, *msg.ParentID)
 		if err != nil {
 			log.Printf("Error fetching parent comment: %v", err)
 			if utils.IsErrorCode(err, utils.ErrNotFound) {
-				context.Respond(utils.NewAppError(utils.ErrNotFound, "Parent comment not found", nil))
+				context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrParentCommentNotFound, nil, nil))
 			} else {
 				context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch parent comment", err))
 			}
 			return
 		}
 
+		// A parent on another post would leave the reply unreachable from its thread
+		if parentComment.PostID != msg.PostID {
+			context.Respond(utils.NewAppError(utils.ErrInvalidInput, "Parent comment belongs to a different post", nil))
+			return
+		}
+
+		newComment.Depth = parentComment.Depth + 1
+		newComment.Path = database.CommentPath(parentComment.Path, commentID)
+		if newComment.Depth > a.maxDepth {
+			context.Respond(utils.NewAppError(utils.ErrInvalidInput,
+				fmt.Sprintf("Replies can be nested at most %d levels deep", a.maxDepth), nil))
+			return
+		}
+
 		// Update parent's children array
 		parentComment.Children = append(parentComment.Children, commentID)
 		parentComment.UpdatedAt = now
@@ -232,11 +416,32 @@ func (a *CommentActor) handleCreateComment(context actor.Context, msg *CreateCom
 		return
 	}
 
+	// Comments have no review queue, so automod holds them back by removing them. The
+	// removal stashes the content like any other, for moderators to review.
+	if rule != nil && rule.Action != models.AutomodActionReport {
+		deletedAt := now.UTC().Truncate(time.Millisecond)
+		if err := a.mongodb.SoftDeleteComments(ctx, []string{commentID.String()}, uuid.Nil, deletedAt); err != nil {
+			log.Printf("Error removing comment %s for automod: %v", commentID, err)
+			context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to save comment", err))
+			return
+		}
+		removedBy := uuid.Nil
+		newComment.IsDeleted = true
+		newComment.Content = database.DeletedPlaceholder
+		newComment.UpdatedAt = deletedAt
+		newComment.DeletedAt = &deletedAt
+		newComment.DeletedBy = &removedBy
+	}
+
 	// Update local cache for the new comment
 	a.comments[commentID] = newComment
 	a.postComments[msg.PostID] = append(a.postComments[msg.PostID], commentID)
 	a.commentVotes[commentID] = make(map[uuid.UUID]bool)
 
+	if parentComment != nil && !newComment.IsDeleted {
+		a.notifyReply(ctx, parentComment, newComment)
+	}
+
 	// Create response
 	response := struct {
 		ID          string    `json:"id"`
@@ -245,6 +450,7 @@ func (a *CommentActor) handleCreateComment(context actor.Context, msg *CreateCom
 		PostID      string    `json:"postId"`
 		SubredditID string    `json:"subredditId"`
 		ParentID    *string   `json:"parentId,omitempty"`
+		Depth       int       `json:"depth"`
 		Children    []string  `json:"children"`
 		CreatedAt   time.Time `json:"createdAt"`
 		UpdatedAt   time.Time `json:"updatedAt"`
@@ -258,6 +464,7 @@ func (a *CommentActor) handleCreateComment(context actor.Context, msg *CreateCom
 		AuthorID:    newComment.AuthorID.String(),
 		PostID:      newComment.PostID.String(),
 		SubredditID: newComment.SubredditID.String(),
+		Depth:       newComment.Depth,
 		Children:    make([]string, 0),
 		CreatedAt:   newComment.CreatedAt,
 		UpdatedAt:   newComment.UpdatedAt,
@@ -278,10 +485,53 @@ func (a *CommentActor) handleCreateComment(context actor.Context, msg *CreateCom
 
 // If this is a reply to another comment, update the parent comment's children array
 
+// automodRule returns the strictest of the subreddit's automod rules a new comment
+// matches, or nil if it matches none. Moderators' comments aren't checked.
+func (a *CommentActor) automodRule(ctx stdctx.Context, subredditID, authorID uuid.UUID, content string) (*models.AutomodRule, *utils.AppError) {
+	subreddit, err := a.mongodb.GetSubredditByID(ctx, subredditID)
+	if err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "Failed to fetch subreddit details", err)
+	}
+	if subreddit == nil || subreddit.IsModerator(authorID) {
+		return nil, nil
+	}
+	return a.automod.match(subreddit.Automod, content), nil
+}
+
+// notifyReply records a notification for the author of the comment being replied to.
+// Self-replies, replies to deleted comments and replies from users the author blocked
+// notify nobody. A failure is logged rather than failing the reply, which has already
+// been saved.
+func (a *CommentActor) notifyReply(ctx stdctx.Context, parent, reply *models.Comment) {
+	if parent.IsDeleted || parent.AuthorID == reply.AuthorID {
+		return
+	}
+	blocked, err := a.mongodb.IsBlocked(ctx, parent.AuthorID, reply.AuthorID)
+	if err != nil {
+		log.Printf("Failed to check blocks before notifying %s of reply %s: %v", parent.AuthorID, reply.ID, err)
+		return
+	}
+	if blocked {
+		return
+	}
+
+	notification := &models.Notification{
+		ID:          uuid.New(),
+		RecipientID: parent.AuthorID,
+		Type:        models.NotificationCommentReply,
+		CommentID:   reply.ID,
+		PostID:      reply.PostID,
+		CreatedAt:   reply.CreatedAt,
+	}
+	if err := a.mongodb.SaveNotification(ctx, notification); err != nil {
+		log.Printf("Failed to notify %s of reply %s: %v", parent.AuthorID, reply.ID, err)
+	}
+}
+
 func (a *CommentActor) handleEditComment(context actor.Context, msg *EditCommentMsg) {
 	comment, exists := a.comments[msg.CommentID]
 	if !exists {
-		context.Respond(utils.NewAppError(utils.ErrNotFound, "Comment not found", nil))
+		context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrCommentNotFound, nil, nil))
 		return
 	}
 
@@ -291,27 +541,33 @@ func (a *CommentActor) handleEditComment(context actor.Context, msg *EditComment
 	}
 
 	if comment.IsDeleted {
-		context.Respond(utils.NewAppError(utils.ErrInvalidInput, "Cannot edit deleted comment", nil))
+		context.Respond(utils.NewAppError(utils.ErrGone, "Cannot edit deleted comment", nil))
 		return
 	}
 
-	comment.Content = msg.Content
-	comment.UpdatedAt = time.Now()
-
-	// Update in MongoDB
+	// The previous content is appended to the edit history by the same update
+	editedAt := time.Now().UTC().Truncate(time.Millisecond)
 	ctx := stdctx.Background()
-	if err := a.mongodb.SaveComment(ctx, comment); err != nil {
+	if err := a.mongodb.EditComment(ctx, comment.ID, msg.Content, editedAt); err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			context.Respond(appErr)
+			return
+		}
 		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to update comment", err))
 		return
 	}
 
+	comment.Content = msg.Content
+	comment.UpdatedAt = editedAt
+	comment.IsEdited = true
+
 	context.Respond(comment)
 }
 
 func (a *CommentActor) handleDeleteComment(context actor.Context, msg *DeleteCommentMsg) {
 	comment, exists := a.comments[msg.CommentID]
 	if !exists {
-		context.Respond(utils.NewAppError(utils.ErrNotFound, "Comment not found", nil))
+		context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrCommentNotFound, nil, nil))
 		return
 	}
 
@@ -320,72 +576,360 @@ func (a *CommentActor) handleDeleteComment(context actor.Context, msg *DeleteCom
 		return
 	}
 
-	comment.IsDeleted = true
-	comment.Content = "[deleted]"
-	comment.UpdatedAt = time.Now()
+	if comment.IsDeleted {
+		context.Respond(utils.NewAppError(utils.ErrGone, "Comment already deleted", nil))
+		return
+	}
 
-	// Update in MongoDB
+	// Only the comment itself is deleted. It keeps its place and children so the
+	// replies stay readable under a "[deleted]" placeholder.
+	deletedAt := time.Now().UTC().Truncate(time.Millisecond)
 	ctx := stdctx.Background()
-	if err := a.mongodb.SaveComment(ctx, comment); err != nil {
+	if err := a.mongodb.SoftDeleteComments(ctx, []string{comment.ID.String()}, msg.AuthorID, deletedAt); err != nil {
 		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to delete comment", err))
 		return
 	}
 
-	// Recursively handle child comments if any
-	for _, childID := range comment.Children {
-		a.deleteCommentAndChildren(context, childID)
-	}
+	deletedBy := msg.AuthorID
+	comment.IsDeleted = true
+	comment.Content = database.DeletedPlaceholder
+	comment.UpdatedAt = deletedAt
+	comment.DeletedAt = &deletedAt
+	comment.DeletedBy = &deletedBy
 
 	context.Respond(true)
 }
 
-func (a *CommentActor) deleteCommentAndChildren(context actor.Context, commentID uuid.UUID) {
-	if comment, exists := a.comments[commentID]; exists {
-		comment.IsDeleted = true
-		comment.Content = "[deleted]"
-		comment.UpdatedAt = time.Now()
+// handleReloadPostComments replaces the cached copies of a post's comments with the
+// versions stored in MongoDB
+func (a *CommentActor) handleReloadPostComments(msg *ReloadPostCommentsMsg) {
+	comments, _, err := a.mongodb.GetPostComments(stdctx.Background(), msg.PostID, database.SortOld, 0, "")
+	if err != nil {
+		log.Printf("Error reloading comments for post %s: %v", msg.PostID, err)
+		return
+	}
+	for _, comment := range comments {
+		a.comments[comment.ID] = comment
+	}
+}
 
-		// Update in MongoDB
-		ctx := stdctx.Background()
-		if err := a.mongodb.SaveComment(ctx, comment); err != nil {
-			log.Printf("Error deleting child comment %s: %v", commentID, err)
+// handleUndeleteComment restores a comment if its author deleted it within the undo window
+func (a *CommentActor) handleUndeleteComment(context actor.Context, msg *UndeleteCommentMsg) {
+	ctx := stdctx.Background()
+	comment, err := a.mongodb.GetComment(ctx, msg.CommentID)
+	if err != nil {
+		if utils.IsErrorCode(err, utils.ErrNotFound) {
+			context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrCommentNotFound, nil, nil))
 			return
 		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get comment", err))
+		return
+	}
 
-		for _, childID := range comment.Children {
-			a.deleteCommentAndChildren(context, childID)
-		}
+	if comment.AuthorID != msg.RequesterID {
+		context.Respond(utils.NewAppError(utils.ErrUnauthorized, "Not authorized to restore comment", nil))
+		return
+	}
+	if !comment.IsDeleted || comment.DeletedAt == nil || comment.DeletedBy == nil {
+		context.Respond(utils.NewAppError(utils.ErrInvalidInput, "Comment is not deleted", nil))
+		return
+	}
+	if *comment.DeletedBy != comment.AuthorID {
+		context.Respond(utils.NewAppError(utils.ErrForbidden, "Comments removed by a moderator cannot be restored", nil))
+		return
+	}
+	if time.Since(*comment.DeletedAt) > a.undeleteWindow {
+		context.Respond(utils.NewAppError(utils.ErrGone, "The undo window for this deletion has passed", nil))
+		return
+	}
+
+	restored, err := a.mongodb.RestoreComments(ctx, []string{comment.ID.String()}, *comment.DeletedBy, *comment.DeletedAt)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to restore comment", err))
+		return
+	}
+	if restored == 0 {
+		context.Respond(utils.NewAppError(utils.ErrGone, "The undo window for this deletion has passed", nil))
+		return
+	}
+
+	// Refresh the cached copy from MongoDB so the restored content is visible again
+	fresh, err := a.mongodb.GetComment(ctx, comment.ID)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to reload comment", err))
+		return
 	}
+	a.comments[fresh.ID] = fresh
+
+	log.Printf("Restored comment %s", fresh.ID)
+	context.Respond(fresh)
 }
 
 func (a *CommentActor) handleGetComment(context actor.Context, msg *GetCommentMsg) {
+	ctx := stdctx.Background()
 	// Try cache first
-	if comment, exists := a.comments[msg.CommentID]; exists {
-		context.Respond(comment)
+	comment, exists := a.comments[msg.CommentID]
+	if !exists {
+		// If not in cache, try MongoDB
+		var err error
+		comment, err = a.mongodb.GetComment(ctx, msg.CommentID)
+		if err != nil {
+			if utils.IsErrorCode(err, utils.ErrNotFound) {
+				context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrCommentNotFound, nil, nil))
+				return
+			}
+			context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get comment", err))
+			return
+		}
+
+		// Update cache
+		a.comments[comment.ID] = comment
+	}
+
+	if appErr := checkCanViewSubreddit(ctx, a.mongodb, comment.SubredditID, msg.ViewerID); appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+	a.attachAuthorFlairs(comment)
+	context.Respond(comment)
+}
+
+// handleGetCommentContext walks ParentID up from the comment and collects its direct
+// replies, then nests them into a single chain. Deleted ancestors keep their place as
+// placeholders so the chain isn't broken.
+func (a *CommentActor) handleGetCommentContext(context actor.Context, msg *GetCommentContextMsg) {
+	ctx := stdctx.Background()
+	target, err := a.mongodb.GetComment(ctx, msg.CommentID)
+	if err != nil {
+		if utils.IsErrorCode(err, utils.ErrNotFound) {
+			context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrCommentNotFound, nil, nil))
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get comment", err))
+		return
+	}
+	if appErr := checkCanViewSubreddit(ctx, a.mongodb, target.SubredditID, msg.ViewerID); appErr != nil {
+		context.Respond(appErr)
 		return
 	}
 
-	// If not in cache, try MongoDB
+	comments := []*models.Comment{target}
+	current := target
+	for i := 0; i < msg.Context && current.ParentID != nil; i++ {
+		parent, err := a.mongodb.GetComment(ctx, *current.ParentID)
+		if err != nil {
+			if utils.IsErrorCode(err, utils.ErrNotFound) {
+				break
+			}
+			context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get parent comment", err))
+			return
+		}
+		comments = append(comments, parent)
+		current = parent
+	}
+
+	replies, err := a.mongodb.GetDirectReplies(ctx, target.ID, database.SortOld)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get comment replies", err))
+		return
+	}
+	comments = append(comments, replies...)
+
+	// The outermost comment is the only root, since every other one hangs off the chain
+	tree := buildCommentTree(comments, database.SortOld)
+	context.Respond(&CommentPermalink{
+		CommentID:      target.ID,
+		HasMoreParents: current.ParentID != nil,
+		Thread:         tree[0],
+	})
+}
+
+// handleDistinguishComment lets moderators of the post's subreddit and the post's author
+// distinguish their own comments and sticky a top-level comment. Stickying a comment
+// unstickies the post's previous one.
+func (a *CommentActor) handleDistinguishComment(context actor.Context, msg *DistinguishCommentMsg) {
 	ctx := stdctx.Background()
 	comment, err := a.mongodb.GetComment(ctx, msg.CommentID)
 	if err != nil {
 		if utils.IsErrorCode(err, utils.ErrNotFound) {
-			context.Respond(utils.NewAppError(utils.ErrNotFound, "Comment not found", nil))
+			context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrCommentNotFound, nil, nil))
 			return
 		}
 		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get comment", err))
 		return
 	}
+	if comment.IsDeleted {
+		context.Respond(utils.NewAppError(utils.ErrGone, "Cannot distinguish deleted comment", nil))
+		return
+	}
 
-	// Update cache
+	post, err := a.mongodb.GetPost(ctx, comment.PostID)
+	if err != nil {
+		if utils.IsErrorCode(err, utils.ErrNotFound) {
+			context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil))
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get post", err))
+		return
+	}
+
+	isModerator, err := a.mongodb.IsSubredditModerator(ctx, post.SubredditID, msg.RequesterID)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to check moderator status", err))
+		return
+	}
+	isOP := post.AuthorID == msg.RequesterID
+	if !isModerator && !isOP {
+		context.Respond(utils.NewAppError(utils.ErrForbidden, "Only moderators and the post's author can distinguish comments", nil))
+		return
+	}
+
+	switch msg.DistinguishedAs {
+	case "":
+	case models.CommentDistinguishedModerator, models.CommentDistinguishedOP:
+		if comment.AuthorID != msg.RequesterID {
+			context.Respond(utils.NewAppError(utils.ErrForbidden, "Only your own comments can be distinguished", nil))
+			return
+		}
+		if msg.DistinguishedAs == models.CommentDistinguishedModerator && !isModerator {
+			context.Respond(utils.NewAppError(utils.ErrForbidden, "Only moderators can distinguish as moderator", nil))
+			return
+		}
+		if msg.DistinguishedAs == models.CommentDistinguishedOP && !isOP {
+			context.Respond(utils.NewAppError(utils.ErrForbidden, "Only the post's author can distinguish as op", nil))
+			return
+		}
+	default:
+		context.Respond(utils.NewAppError(utils.ErrInvalidInput, "distinguishedAs must be moderator, op or empty", nil))
+		return
+	}
+
+	if msg.Sticky && comment.ParentID != nil {
+		context.Respond(utils.NewAppError(utils.ErrInvalidInput, "Only top-level comments can be stickied", nil))
+		return
+	}
+
+	unstickied, err := a.mongodb.SetCommentDistinction(ctx, comment, msg.DistinguishedAs, msg.Sticky)
+	if err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			context.Respond(appErr)
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to distinguish comment", err))
+		return
+	}
+
+	for _, id := range unstickied {
+		if cached, exists := a.comments[id]; exists {
+			cached.IsStickied = false
+		}
+	}
+	comment.DistinguishedAs = msg.DistinguishedAs
+	comment.IsStickied = msg.Sticky
 	a.comments[comment.ID] = comment
+
 	context.Respond(comment)
 }
 
+func (a *CommentActor) handleSearchComments(context actor.Context, msg *SearchCommentsMsg) {
+	ctx := stdctx.Background()
+	if appErr := a.checkCanViewPost(ctx, msg.PostID, msg.ViewerID); appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+	matches, err := a.mongodb.SearchPostComments(ctx, msg.PostID, msg.Query, commentPageSize(msg.Limit))
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to search comments", err))
+		return
+	}
+
+	results := make([]*CommentSearchResult, 0, len(matches))
+	if len(matches) == 0 {
+		context.Respond(&types.PaginatedResponse{Items: results, NextCursor: ""})
+		return
+	}
+
+	parents, err := a.mongodb.GetCommentParentIDs(ctx, msg.PostID)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get comment ancestors", err))
+		return
+	}
+
+	for _, match := range matches {
+		ancestors := make([]uuid.UUID, 0, match.Depth)
+		// Bounded by the post's comment count in case of a corrupt parent cycle
+		for parentID := match.ParentID; parentID != nil && len(ancestors) < len(parents); parentID = parents[*parentID] {
+			ancestors = append(ancestors, *parentID)
+		}
+		// Collected from the parent upwards; clients expand from the top down
+		for i, j := 0, len(ancestors)-1; i < j; i, j = i+1, j-1 {
+			ancestors[i], ancestors[j] = ancestors[j], ancestors[i]
+		}
+		results = append(results, &CommentSearchResult{Comment: match, AncestorIDs: ancestors})
+	}
+
+	context.Respond(&types.PaginatedResponse{Items: results, NextCursor: ""})
+}
+
+func (a *CommentActor) handleGetCommentHistory(context actor.Context, msg *GetCommentHistoryMsg) {
+	ctx := stdctx.Background()
+
+	comment, exists := a.comments[msg.CommentID]
+	if !exists {
+		var err error
+		comment, err = a.mongodb.GetComment(ctx, msg.CommentID)
+		if err != nil {
+			if utils.IsErrorCode(err, utils.ErrNotFound) {
+				context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrCommentNotFound, nil, nil))
+				return
+			}
+			context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get comment", err))
+			return
+		}
+	}
+
+	if comment.AuthorID != msg.RequesterID {
+		isModerator, err := a.mongodb.IsSubredditModerator(ctx, comment.SubredditID, msg.RequesterID)
+		if err != nil {
+			context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to check moderator status", err))
+			return
+		}
+		if !isModerator {
+			context.Respond(utils.NewAppError(utils.ErrForbidden, "Only the author or a moderator can view edit history", nil))
+			return
+		}
+	}
+
+	edits, err := a.mongodb.GetCommentEdits(ctx, comment.ID)
+	if err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			context.Respond(appErr)
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get comment history", err))
+		return
+	}
+
+	context.Respond(&CommentHistory{
+		CommentID: comment.ID,
+		Content:   comment.Content,
+		IsEdited:  comment.IsEdited,
+		Edits:     edits,
+	})
+}
+
 func (a *CommentActor) handleGetPostComments(context actor.Context, msg *GetCommentsForPostMsg) {
 	ctx := stdctx.Background()
-	comments, err := a.mongodb.GetPostComments(ctx, msg.PostID)
+	if appErr := a.checkCanViewPost(ctx, msg.PostID, msg.ViewerID); appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+	comments, nextCursor, err := a.mongodb.GetPostComments(ctx, msg.PostID, msg.Sort, commentPageSize(msg.Limit), msg.Cursor)
 	if err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			context.Respond(appErr)
+			return
+		}
 		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get post comments", err))
 		return
 	}
@@ -399,7 +943,326 @@ func (a *CommentActor) handleGetPostComments(context actor.Context, msg *GetComm
 		a.postComments[msg.PostID] = append(a.postComments[msg.PostID], comment.ID)
 	}
 
-	context.Respond(comments)
+	a.attachAuthorFlairs(comments...)
+	context.Respond(&types.PaginatedResponse{Items: comments, NextCursor: nextCursor})
+}
+
+// handleGetCommentTree responds with a page of a post's top-level comments, each carrying
+// all of its replies. Pages are cut between threads so a thread is never split.
+func (a *CommentActor) handleGetCommentTree(context actor.Context, msg *GetCommentTreeMsg) {
+	ctx := stdctx.Background()
+	if msg.BranchID != nil {
+		a.handleGetCommentBranch(context, msg)
+		return
+	}
+	if appErr := a.checkCanViewPost(ctx, msg.PostID, msg.ViewerID); appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+
+	topLevel, nextCursor, err := a.mongodb.GetTopLevelComments(ctx, msg.PostID, msg.Sort, commentPageSize(msg.Limit), msg.Cursor)
+	if err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			context.Respond(appErr)
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get post comments", err))
+		return
+	}
+
+	rootIDs := make([]uuid.UUID, len(topLevel))
+	for i, comment := range topLevel {
+		rootIDs[i] = comment.ID
+	}
+	replies, err := a.mongodb.GetCommentReplies(ctx, rootIDs)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get comment replies", err))
+		return
+	}
+
+	thread := append(topLevel, replies...)
+	if appErr := a.maskBlockedComments(ctx, msg.ViewerID, thread); appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+	a.attachAuthorFlairs(thread...)
+	tree := buildCommentTree(thread, msg.Sort)
+	collapseCommentTree(tree, msg.CollapseThreshold, msg.Expand)
+	pruneCommentTree(tree, msg.MaxDepth, msg.MaxNodes)
+	context.Respond(&types.PaginatedResponse{Items: tree, NextCursor: nextCursor})
+}
+
+// handleGetCommentBranch responds with the subtree below a comment whose replies were
+// cut from an earlier tree response. The branch is pruned with the same limits.
+func (a *CommentActor) handleGetCommentBranch(context actor.Context, msg *GetCommentTreeMsg) {
+	ctx := stdctx.Background()
+	branch, err := a.mongodb.GetCommentSubtree(ctx, *msg.BranchID)
+	if err != nil {
+		if utils.IsErrorCode(err, utils.ErrNotFound) {
+			context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrCommentNotFound, nil, nil))
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get comment branch", err))
+		return
+	}
+	var root *models.Comment
+	for _, comment := range branch {
+		if comment.ID == *msg.BranchID {
+			root = comment
+			break
+		}
+	}
+	if root == nil || root.PostID != msg.PostID {
+		context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrCommentNotFound, nil, nil))
+		return
+	}
+	if appErr := checkCanViewSubreddit(ctx, a.mongodb, root.SubredditID, msg.ViewerID); appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+
+	if appErr := a.maskBlockedComments(ctx, msg.ViewerID, branch); appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+
+	// Only the branch root is left without a parent, so it is the single root
+	a.attachAuthorFlairs(branch...)
+	tree := buildCommentTree(branch, msg.Sort)
+	// The root was asked for explicitly, often to open it after it came back collapsed
+	collapseCommentTree(tree[0].Replies, msg.CollapseThreshold, msg.Expand)
+	pruneCommentTree(tree, msg.MaxDepth, msg.MaxNodes)
+	context.Respond(&types.PaginatedResponse{Items: tree, NextCursor: ""})
+}
+
+// handleGetUserComments responds with a page of a user's comments and where they were made
+func (a *CommentActor) handleGetUserComments(context actor.Context, msg *GetUserCommentsMsg) {
+	ctx := stdctx.Background()
+	items, nextCursor, err := a.mongodb.GetUserCommentsWithContext(ctx, msg.UserID, commentPageSize(msg.Limit), msg.Cursor, msg.IncludeDeleted)
+	if err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			context.Respond(appErr)
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get user comments", err))
+		return
+	}
+
+	// Comments in private subreddits the viewer can't read are dropped from the page,
+	// along with their post titles, which can leave it short
+	hidden, err := hiddenSubreddits(ctx, a.mongodb, msg.ViewerID)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to check private subreddits", err))
+		return
+	}
+	visible := items[:0]
+	for _, item := range items {
+		if !hidden[item.SubredditID] {
+			visible = append(visible, item)
+		}
+	}
+
+	context.Respond(&types.PaginatedResponse{Items: visible, NextCursor: nextCursor})
+}
+
+// checkCanViewPost rejects a viewer who may not read the comments on a post, as a
+// non-member of its private subreddit
+func (a *CommentActor) checkCanViewPost(ctx stdctx.Context, postID, viewerID uuid.UUID) *utils.AppError {
+	post, err := a.mongodb.GetPost(ctx, postID)
+	if err != nil {
+		if utils.IsErrorCode(err, utils.ErrNotFound) {
+			return utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil)
+		}
+		return utils.NewAppError(utils.ErrDatabase, "Failed to fetch post", err)
+	}
+	return checkCanViewSubreddit(ctx, a.mongodb, post.SubredditID, viewerID)
+}
+
+// maskBlockedComments hides the content and author of comments by users the viewer
+// blocked. The comments keep their place so that other users' replies stay attached.
+func (a *CommentActor) maskBlockedComments(ctx stdctx.Context, viewerID uuid.UUID, comments []*models.Comment) *utils.AppError {
+	if viewerID == uuid.Nil {
+		return nil
+	}
+	blocked, err := a.mongodb.GetBlockedUserIDs(ctx, viewerID)
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "Failed to get blocked users", err)
+	}
+	for _, comment := range comments {
+		if blocked[comment.AuthorID] {
+			comment.Content = database.BlockedPlaceholder
+			comment.AuthorID = uuid.Nil
+		}
+	}
+	return nil
+}
+
+// attachAuthorFlairs fills in the flair each comment's author shows in its subreddit.
+// Failures are logged rather than failing the request.
+func (a *CommentActor) attachAuthorFlairs(comments ...*models.Comment) {
+	if err := a.mongodb.AttachCommentAuthorFlairs(stdctx.Background(), comments); err != nil {
+		log.Printf("Error attaching author flairs: %v", err)
+	}
+}
+
+// commentPageSize applies the default and maximum to a requested page size
+func commentPageSize(limit int) int {
+	if limit <= 0 {
+		return defaultCommentPageSize
+	}
+	if limit > maxCommentPageSize {
+		return maxCommentPageSize
+	}
+	return limit
+}
+
+// buildCommentTree nests comments under their parents using ParentID, ordering siblings
+// by sort. Deleted comments keep their place so their replies stay attached, but their
+// content is replaced. Replies whose parent is missing are shown at the top level.
+func buildCommentTree(comments []*models.Comment, sort string) []*models.CommentNode {
+	nodes := make(map[uuid.UUID]*models.CommentNode, len(comments))
+	for _, comment := range comments {
+		node := &models.CommentNode{Comment: *comment, Replies: make([]*models.CommentNode, 0)}
+		if node.IsDeleted {
+			node.Content = database.DeletedPlaceholder
+		}
+		nodes[comment.ID] = node
+	}
+
+	roots := make([]*models.CommentNode, 0)
+	for _, comment := range comments {
+		node := nodes[comment.ID]
+		if comment.ParentID != nil {
+			if parent, ok := nodes[*comment.ParentID]; ok {
+				parent.Replies = append(parent.Replies, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+
+	sortCommentNodes(roots, commentLess(sort))
+	return roots
+}
+
+// collapseCommentTree flags comments with karma at or below threshold as collapsed.
+// Unless expand is set, their content is dropped and their replies are moved to More,
+// so they can still be loaded as a branch. Stickied and distinguished comments are never
+// collapsed.
+func collapseCommentTree(nodes []*models.CommentNode, threshold int, expand bool) {
+	for _, node := range nodes {
+		if node.Karma <= threshold && !node.IsStickied && node.DistinguishedAs == "" {
+			node.Collapsed = true
+			if !expand {
+				node.Content = ""
+				for _, reply := range node.Replies {
+					node.More = append(node.More, reply.ID)
+				}
+				node.Replies = make([]*models.CommentNode, 0)
+				continue
+			}
+		}
+		collapseCommentTree(node.Replies, threshold, expand)
+	}
+}
+
+// pruneCommentTree cuts a sorted tree down to maxDepth levels per root and maxNodes
+// replies overall, zero meaning no limit. Replies are kept breadth-first, so shallower
+// replies win over deeper ones. Each cut reply is listed in its parent's More so the
+// branch can be requested on its own. Deleted placeholders count like any other reply.
+func pruneCommentTree(roots []*models.CommentNode, maxDepth, maxNodes int) {
+	if maxDepth <= 0 && maxNodes <= 0 {
+		return
+	}
+
+	kept := 0
+	level := roots
+	for depth := 1; len(level) > 0; depth++ {
+		next := make([]*models.CommentNode, 0)
+		for _, node := range level {
+			replies := node.Replies
+			node.Replies = make([]*models.CommentNode, 0, len(replies))
+			for _, reply := range replies {
+				if (maxDepth > 0 && depth >= maxDepth) || (maxNodes > 0 && kept >= maxNodes) {
+					node.More = append(node.More, reply.ID)
+					continue
+				}
+				node.Replies = append(node.Replies, reply)
+				next = append(next, reply)
+				kept++
+			}
+		}
+		level = next
+	}
+}
+
+// sortCommentNodes orders siblings at every level of the tree
+func sortCommentNodes(nodes []*models.CommentNode, less func(a, b *models.Comment) bool) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return less(&nodes[i].Comment, &nodes[j].Comment)
+	})
+	for _, node := range nodes {
+		sortCommentNodes(node.Replies, less)
+	}
+}
+
+// commentLess orders comments the same way the database sorts a comment listing, with a
+// stickied comment ahead of the rest
+func commentLess(sort string) func(a, b *models.Comment) bool {
+	less := commentSortLess(sort)
+	return func(a, b *models.Comment) bool {
+		if a.IsStickied != b.IsStickied {
+			return a.IsStickied
+		}
+		return less(a, b)
+	}
+}
+
+// commentSortLess orders comments by a sort mode alone
+func commentSortLess(sort string) func(a, b *models.Comment) bool {
+	newer := func(a, b *models.Comment) bool {
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID.String() > b.ID.String()
+	}
+
+	switch sort {
+	case database.SortNew:
+		return newer
+	case database.SortTop:
+		return func(a, b *models.Comment) bool {
+			if a.Karma != b.Karma {
+				return a.Karma > b.Karma
+			}
+			return newer(a, b)
+		}
+	case database.SortControversial:
+		return func(a, b *models.Comment) bool {
+			ca := utils.ControversyScore(a.Upvotes, a.Downvotes)
+			cb := utils.ControversyScore(b.Upvotes, b.Downvotes)
+			if ca != cb {
+				return ca > cb
+			}
+			return newer(a, b)
+		}
+	case database.SortBest:
+		return func(a, b *models.Comment) bool {
+			sa := utils.WilsonScore(a.Upvotes, a.Downvotes)
+			sb := utils.WilsonScore(b.Upvotes, b.Downvotes)
+			if sa != sb {
+				return sa > sb
+			}
+			return newer(a, b)
+		}
+	default:
+		return func(a, b *models.Comment) bool {
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.Before(b.CreatedAt)
+			}
+			return a.ID.String() < b.ID.String()
+		}
+	}
 }
 
 func (a *CommentActor) handleVoteComment(context actor.Context, msg *VoteCommentMsg) {
@@ -409,12 +1272,12 @@ func (a *CommentActor) handleVoteComment(context actor.Context, msg *VoteComment
 	retrievedComment, err := a.mongodb.GetComment(ctx, msg.CommentID)
 	if err != nil {
 		log.Printf("Error retrieving comment: %v", err)
-		context.Respond(utils.NewAppError(utils.ErrNotFound, "Comment not found", err))
+		context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrCommentNotFound, nil, err))
 		return
 	}
 
 	if retrievedComment.IsDeleted {
-		context.Respond(utils.NewAppError(utils.ErrNotFound, "Comment not found", nil))
+		context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrCommentNotFound, nil, nil))
 		return
 	}
 
@@ -476,6 +1339,7 @@ func (a *CommentActor) handleVoteComment(context actor.Context, msg *VoteComment
 			context.Send(a.enginePID, &UpdateKarmaMsg{
 				UserID: retrievedComment.AuthorID,
 				Delta:  karmaChange,
+				Source: KarmaSourceComment,
 			})
 		} else {
 			log.Printf("Warning: enginePID is nil, cannot send karma update")
@@ -0,0 +1,119 @@
+package actors
+
+import (
+	stdctx "context"
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/database/dbtest"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/types"
+	"gator-swamp/internal/utils"
+	"testing"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/google/uuid"
+)
+
+func TestPrivateSubredditCommentsAreServedOnlyToMembers(t *testing.T) {
+	mongodb := dbtest.New(t)
+	ctx := stdctx.Background()
+	system := actor.NewActorSystem()
+	comments := system.Root.Spawn(actor.PropsFromProducer(func() actor.Actor {
+		return NewCommentActor(nil, mongodb, time.Hour, 0, 10)
+	}))
+	t.Cleanup(func() {
+		system.Root.StopFuture(comments).Wait()
+		system.Shutdown()
+	})
+
+	ask := func(msg interface{}) interface{} {
+		t.Helper()
+		result, err := system.Root.RequestFuture(comments, msg, 5*time.Second).Result()
+		if err != nil {
+			t.Fatalf("%T: %v", msg, err)
+		}
+		return result
+	}
+	assertHidden := func(msg interface{}) {
+		t.Helper()
+		if appErr, ok := ask(msg).(*utils.AppError); !ok || appErr.Code != utils.ErrUnauthorized {
+			t.Fatalf("%T from an outsider wasn't refused as unauthorized", msg)
+		}
+	}
+
+	memberID, outsiderID := uuid.New(), uuid.New()
+	subreddit := &models.Subreddit{
+		ID:        uuid.New(),
+		Name:      "swampsecret",
+		CreatorID: memberID,
+		CreatedAt: time.Now(),
+		Type:      models.SubredditPrivate,
+	}
+	if err := mongodb.CreateSubreddit(ctx, subreddit); err != nil {
+		t.Fatalf("CreateSubreddit: %v", err)
+	}
+	for _, user := range []*models.User{
+		{ID: memberID, Username: "member", Email: "member@example.com", CreatedAt: time.Now()},
+		{ID: outsiderID, Username: "outsider", Email: "outsider@example.com", CreatedAt: time.Now()},
+	} {
+		if err := mongodb.SaveUser(ctx, user); err != nil {
+			t.Fatalf("SaveUser: %v", err)
+		}
+	}
+	if _, err := mongodb.UpdateUserSubreddits(ctx, memberID, subreddit.ID, true); err != nil {
+		t.Fatalf("UpdateUserSubreddits: %v", err)
+	}
+	post := &models.Post{
+		ID:          uuid.New(),
+		Title:       "A secret",
+		Slug:        "a-secret",
+		AuthorID:    memberID,
+		SubredditID: subreddit.ID,
+		CreatedAt:   time.Now(),
+		Status:      models.PostStatusPublished,
+	}
+	if err := mongodb.SavePost(ctx, post); err != nil {
+		t.Fatalf("SavePost: %v", err)
+	}
+
+	assertHidden(&CreateCommentMsg{Content: "Let me in", AuthorID: outsiderID, PostID: post.ID})
+	if appErr, ok := ask(&CreateCommentMsg{Content: "Welcome", AuthorID: memberID, PostID: post.ID}).(*utils.AppError); ok {
+		t.Fatalf("member couldn't comment: %v", appErr)
+	}
+	created, _, err := mongodb.GetUserComments(ctx, memberID, 1, "", false)
+	if err != nil || len(created) != 1 {
+		t.Fatalf("GetUserComments = %d comments, %v; want the member's comment", len(created), err)
+	}
+	comment := created[0]
+
+	assertHidden(&GetCommentMsg{CommentID: comment.ID, ViewerID: outsiderID})
+	assertHidden(&GetCommentMsg{CommentID: comment.ID})
+	assertHidden(&GetCommentContextMsg{CommentID: comment.ID, ViewerID: outsiderID})
+	assertHidden(&SearchCommentsMsg{PostID: post.ID, Query: "welcome", ViewerID: outsiderID})
+	assertHidden(&GetCommentsForPostMsg{PostID: post.ID, ViewerID: outsiderID})
+	assertHidden(&GetCommentTreeMsg{PostID: post.ID, ViewerID: outsiderID})
+	assertHidden(&GetCommentTreeMsg{PostID: post.ID, BranchID: &comment.ID, ViewerID: outsiderID})
+
+	if _, ok := ask(&GetCommentMsg{CommentID: comment.ID, ViewerID: memberID}).(*models.Comment); !ok {
+		t.Fatalf("member couldn't read the comment")
+	}
+	if _, ok := ask(&GetCommentTreeMsg{PostID: post.ID, ViewerID: memberID}).(*types.PaginatedResponse); !ok {
+		t.Fatalf("member couldn't read the post's comments")
+	}
+
+	// Profiles leave out the comments, and with them the post's title
+	userComments := func(viewerID uuid.UUID) []*database.CommentWithContext {
+		t.Helper()
+		page, ok := ask(&GetUserCommentsMsg{UserID: memberID, ViewerID: viewerID}).(*types.PaginatedResponse)
+		if !ok {
+			t.Fatalf("GetUserCommentsMsg failed")
+		}
+		return page.Items.([]*database.CommentWithContext)
+	}
+	if items := userComments(outsiderID); len(items) != 0 {
+		t.Fatalf("outsider sees %d of the member's comments, want none", len(items))
+	}
+	if items := userComments(memberID); len(items) != 1 || items[0].PostTitle != post.Title {
+		t.Fatalf("member sees %d of their comments, want the one", len(items))
+	}
+}
@@ -0,0 +1,45 @@
+package actors
+
+import (
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/models"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestBestSortRanksByWilsonScore(t *testing.T) {
+	now := time.Now()
+	popular := &models.Comment{ID: uuid.New(), Upvotes: 100, Downvotes: 80, Karma: 20, CreatedAt: now}
+	unanimous := &models.Comment{ID: uuid.New(), Upvotes: 5, Downvotes: 0, Karma: 5, CreatedAt: now.Add(-time.Hour)}
+
+	comments := []*models.Comment{popular, unanimous}
+	less := commentSortLess(database.SortBest)
+	sort.SliceStable(comments, func(i, j int) bool { return less(comments[i], comments[j]) })
+
+	if comments[0] != unanimous {
+		t.Errorf("best sort put 100/80 above 5/0")
+	}
+}
+
+func TestBestSortTiesFallBackToCreatedAt(t *testing.T) {
+	now := time.Now()
+	older := &models.Comment{ID: uuid.New(), Upvotes: 3, Downvotes: 1, CreatedAt: now.Add(-time.Hour)}
+	newer := &models.Comment{ID: uuid.New(), Upvotes: 3, Downvotes: 1, CreatedAt: now}
+	unvotedOld := &models.Comment{ID: uuid.New(), CreatedAt: now.Add(-2 * time.Hour)}
+	unvotedNew := &models.Comment{ID: uuid.New(), CreatedAt: now.Add(-time.Minute)}
+
+	comments := []*models.Comment{unvotedOld, older, unvotedNew, newer}
+	less := commentSortLess(database.SortBest)
+	sort.SliceStable(comments, func(i, j int) bool { return less(comments[i], comments[j]) })
+
+	want := []*models.Comment{newer, older, unvotedNew, unvotedOld}
+	for i := range want {
+		if comments[i] != want[i] {
+			t.Fatalf("position %d has the comment created at %v, want the one created at %v",
+				i, comments[i].CreatedAt, want[i].CreatedAt)
+		}
+	}
+}
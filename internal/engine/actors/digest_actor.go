@@ -0,0 +1,186 @@
+package actors
+
+import (
+	stdctx "context"
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+	"hash/fnv"
+	"log"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/asynkron/protoactor-go/scheduler"
+	"github.com/google/uuid"
+)
+
+const (
+	digestSize          = 10
+	digestCheckInterval = time.Hour
+	digestStaggerHours  = 6 // Subreddits are spread over the first hours of each UTC day
+	digestMaxBackfill   = 7 // Days of missed digests computed after an outage
+)
+
+// Message types for DigestActor
+type (
+	GetDigestMsg struct {
+		SubredditID uuid.UUID
+		Date        string // YYYY-MM-DD, defaults to yesterday
+	}
+
+	runDigestsMsg struct{}
+)
+
+// DigestActor computes each subreddit's daily top-posts digest. Progress is tracked
+// per subreddit in MongoDB, so restarts neither skip nor repeat a day.
+type DigestActor struct {
+	stopTimer scheduler.CancelFunc
+	mongodb   *database.MongoDB
+}
+
+func NewDigestActor(mongodb *database.MongoDB) actor.Actor {
+	return &DigestActor{
+		mongodb: mongodb,
+	}
+}
+
+func (a *DigestActor) Receive(context actor.Context) {
+	switch msg := context.Message().(type) {
+	case *actor.Started:
+		log.Printf("DigestActor started")
+		a.stopTimer = scheduler.NewTimerScheduler(context).
+			SendRepeatedly(time.Minute, digestCheckInterval, context.Self(), &runDigestsMsg{})
+
+	case *actor.Stopping:
+		if a.stopTimer != nil {
+			a.stopTimer()
+		}
+
+	case *runDigestsMsg:
+		a.runDigests()
+
+	case *GetDigestMsg:
+		a.handleGetDigest(context, msg)
+	}
+}
+
+func (a *DigestActor) handleGetDigest(context actor.Context, msg *GetDigestMsg) {
+	date := msg.Date
+	if date == "" {
+		date = time.Now().UTC().AddDate(0, 0, -1).Format(database.DigestDateLayout)
+	} else if _, err := time.Parse(database.DigestDateLayout, date); err != nil {
+		context.Respond(utils.NewAppError(utils.ErrInvalidInput, "Invalid date, expected YYYY-MM-DD", nil))
+		return
+	}
+
+	digest, err := a.mongodb.GetDigest(stdctx.Background(), msg.SubredditID.String(), date)
+	if err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			context.Respond(appErr)
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get digest", err))
+		return
+	}
+
+	context.Respond(digest)
+}
+
+// runDigests brings every subreddit's digests up to date through yesterday
+func (a *DigestActor) runDigests() {
+	ctx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Minute)
+	defer cancel()
+
+	subreddits, err := a.mongodb.ListSubreddits(ctx)
+	if err != nil {
+		log.Printf("DigestActor: Failed to list subreddits: %v", err)
+		return
+	}
+
+	lastDates, err := a.mongodb.GetDigestLastDates(ctx)
+	if err != nil {
+		log.Printf("DigestActor: Failed to load digest state: %v", err)
+		return
+	}
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	yesterday := today.AddDate(0, 0, -1)
+
+	for _, subreddit := range subreddits {
+		start := yesterday
+		if last, ok := lastDates[subreddit.ID.String()]; ok {
+			lastDay, err := time.Parse(database.DigestDateLayout, last)
+			if err != nil {
+				log.Printf("DigestActor: Invalid digest state for %s: %v", subreddit.ID, err)
+				continue
+			}
+			if !lastDay.Before(yesterday) {
+				continue // Already up to date
+			}
+			start = lastDay.AddDate(0, 0, 1)
+			if oldest := yesterday.AddDate(0, 0, -(digestMaxBackfill - 1)); start.Before(oldest) {
+				start = oldest
+			}
+		}
+
+		// Only the regular daily run is staggered; catching up after an outage isn't
+		if start.Equal(yesterday) && now.Sub(today) < digestStaggerOffset(subreddit.ID) {
+			continue
+		}
+
+		for day := start; !day.After(yesterday); day = day.AddDate(0, 0, 1) {
+			if err := a.computeDigest(ctx, subreddit, day); err != nil {
+				log.Printf("DigestActor: %v", err)
+				break // Retry from this day on the next run
+			}
+		}
+	}
+}
+
+// computeDigest stores the top posts of one subreddit for one day and records progress.
+// Days without posts produce no digest but still count as computed.
+func (a *DigestActor) computeDigest(ctx stdctx.Context, subreddit *models.Subreddit, day time.Time) error {
+	subredditID := subreddit.ID.String()
+	date := day.Format(database.DigestDateLayout)
+
+	posts, err := a.mongodb.GetTopPostsInRange(ctx, subredditID, day, day.AddDate(0, 0, 1), digestSize)
+	if err != nil {
+		return err
+	}
+
+	if len(posts) > 0 {
+		digest := &database.DigestDocument{
+			SubredditID:   subredditID,
+			SubredditName: subreddit.Name,
+			Date:          date,
+			Posts:         make([]database.DigestPost, 0, len(posts)),
+			GeneratedAt:   time.Now(),
+		}
+		for _, post := range posts {
+			digest.Posts = append(digest.Posts, database.DigestPost{
+				PostID:         post.ID,
+				Title:          post.Title,
+				AuthorID:       post.AuthorID,
+				AuthorUsername: post.AuthorUsername,
+				Karma:          post.Karma,
+				CreatedAt:      post.CreatedAt,
+			})
+		}
+
+		if err := a.mongodb.SaveDigest(ctx, digest); err != nil {
+			return err
+		}
+		log.Printf("DigestActor: Saved digest for r/%s on %s with %d posts", subreddit.Name, date, len(posts))
+	}
+
+	return a.mongodb.SetDigestLastDate(ctx, subredditID, date)
+}
+
+// digestStaggerOffset spreads subreddits deterministically across the stagger window
+func digestStaggerOffset(subredditID uuid.UUID) time.Duration {
+	h := fnv.New32a()
+	h.Write(subredditID[:])
+	window := uint32(digestStaggerHours * time.Hour / time.Minute)
+	return time.Duration(h.Sum32()%window) * time.Minute
+}
@@ -4,6 +4,7 @@ import (
 	stdctx "context" // Alias for standard context to avoid confusion with actor.Context
 	"gator-swamp/internal/database"
 	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
 	"log"
 	"time"
 
@@ -55,6 +56,17 @@ func NewDirectMessageActor(mongodb *database.MongoDB) actor.Actor {
 }
 
 func (a *DirectMessageActor) handleSendMessage(context actor.Context, msg *SendDirectMessageMsg) {
+	// Neither side of a block can message the other
+	blocked, err := a.mongodb.IsBlockedEitherWay(stdctx.Background(), msg.FromID, msg.ToID)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to check blocks", err))
+		return
+	}
+	if blocked {
+		context.Respond(utils.NewAppError(utils.ErrForbidden, "You can't message this user", nil))
+		return
+	}
+
 	newMessage := &models.DirectMessage{
 		ID:        uuid.New(),
 		FromID:    msg.FromID,
@@ -129,10 +141,18 @@ func (a *DirectMessageActor) handleGetUserMessages(context actor.Context, msg *G
 		}
 	}
 
+	// Messages with users on either side of a block are left out, like deleted ones
+	blocked, err := a.mongodb.GetBlockRelatedUserIDs(ctx, msg.UserID)
+	if err != nil {
+		log.Printf("Failed to get blocks for user %s: %v", msg.UserID, err)
+		context.Respond([]*models.DirectMessage{})
+		return
+	}
+
 	// Filter out deleted messages and return the result
 	var activeMessages []*models.DirectMessage
 	for _, message := range messages {
-		if !message.IsDeleted {
+		if !message.IsDeleted && !blocked[message.FromID] && !blocked[message.ToID] {
 			activeMessages = append(activeMessages, message)
 		}
 	}
@@ -142,6 +162,15 @@ func (a *DirectMessageActor) handleGetUserMessages(context actor.Context, msg *G
 }
 
 func (a *DirectMessageActor) handleGetConversation(context actor.Context, msg *GetConversationMsg) {
+	blocked, err := a.mongodb.IsBlockedEitherWay(stdctx.Background(), msg.UserID1, msg.UserID2)
+	if err != nil {
+		log.Printf("Failed to check blocks between %s and %s: %v", msg.UserID1, msg.UserID2, err)
+	}
+	if err != nil || blocked {
+		context.Respond([]*models.DirectMessage{})
+		return
+	}
+
 	if messages, exists := a.userMessages[msg.UserID1][msg.UserID2]; exists {
 		var activeMessages []*models.DirectMessage
 		for _, message := range messages {
@@ -0,0 +1,132 @@
+package actors
+
+import (
+	stdctx "context"
+	"gator-swamp/internal/database"
+	"log"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/asynkron/protoactor-go/scheduler"
+)
+
+const janitorInterval = time.Hour
+
+// Posts are archived in batches with a pause between them so a large backlog, such as
+// on the first run, doesn't monopolize the database
+const (
+	archiveBatchSize  = 500
+	archiveBatchPause = 200 * time.Millisecond
+)
+
+// runJanitorMsg triggers one pass over the maintenance tasks
+type runJanitorMsg struct{}
+
+// JanitorActor runs periodic background maintenance tasks
+type JanitorActor struct {
+	stopTimer      scheduler.CancelFunc
+	mongodb        *database.MongoDB
+	undeleteWindow time.Duration
+	archiveAfter   time.Duration // Age at which posts are archived; 0 disables archival
+}
+
+func NewJanitorActor(mongodb *database.MongoDB, undeleteWindow, archiveAfter time.Duration) actor.Actor {
+	return &JanitorActor{
+		mongodb:        mongodb,
+		undeleteWindow: undeleteWindow,
+		archiveAfter:   archiveAfter,
+	}
+}
+
+func (a *JanitorActor) Receive(context actor.Context) {
+	switch context.Message().(type) {
+	case *actor.Started:
+		log.Printf("JanitorActor started")
+		a.stopTimer = scheduler.NewTimerScheduler(context).
+			SendRepeatedly(time.Minute, janitorInterval, context.Self(), &runJanitorMsg{})
+
+	case *actor.Stopping:
+		if a.stopTimer != nil {
+			a.stopTimer()
+		}
+
+	case *runJanitorMsg:
+		a.runTasks()
+	}
+}
+
+// runTasks runs every maintenance task once. Tasks log their own failures so one
+// failing task doesn't stop the others.
+func (a *JanitorActor) runTasks() {
+	// Yesterday is finalized once the day is over; today is refreshed so the
+	// current day's numbers are at most one interval stale. Both are idempotent.
+	now := time.Now().UTC()
+	for _, day := range []time.Time{now.Add(-24 * time.Hour), now} {
+		if _, err := a.rollupAnalytics(day); err != nil {
+			log.Printf("JanitorActor: %v", err)
+		}
+	}
+
+	if err := a.finalizeDeletions(now.Add(-a.undeleteWindow)); err != nil {
+		log.Printf("JanitorActor: %v", err)
+	}
+
+	if a.archiveAfter > 0 {
+		if err := a.archivePosts(now.Add(-a.archiveAfter)); err != nil {
+			log.Printf("JanitorActor: %v", err)
+		}
+	}
+}
+
+func (a *JanitorActor) rollupAnalytics(day time.Time) (int, error) {
+	ctx, cancel := stdctx.WithTimeout(stdctx.Background(), time.Minute)
+	defer cancel()
+
+	count, err := a.mongodb.RollupAnalyticsDay(ctx, day)
+	if err != nil {
+		return 0, err
+	}
+	log.Printf("JanitorActor: Rolled up %d analytics series for %s", count, day.UTC().Format(database.AnalyticsDateLayout))
+	return count, nil
+}
+
+// finalizeDeletions drops the content kept for undoing deletions older than cutoff
+func (a *JanitorActor) finalizeDeletions(cutoff time.Time) error {
+	ctx, cancel := stdctx.WithTimeout(stdctx.Background(), time.Minute)
+	defer cancel()
+
+	posts, err := a.mongodb.ScrubDeletedPosts(ctx, cutoff)
+	if err != nil {
+		return err
+	}
+	comments, err := a.mongodb.ScrubDeletedComments(ctx, cutoff)
+	if err != nil {
+		return err
+	}
+	if posts > 0 || comments > 0 {
+		log.Printf("JanitorActor: Finalized deletion of %d posts and %d comments", posts, comments)
+	}
+	return nil
+}
+
+// archivePosts flags the posts created before cutoff as archived, a batch at a time
+func (a *JanitorActor) archivePosts(cutoff time.Time) error {
+	total := 0
+	for {
+		ctx, cancel := stdctx.WithTimeout(stdctx.Background(), time.Minute)
+		count, err := a.mongodb.ArchivePostsBatch(ctx, cutoff, archiveBatchSize)
+		cancel()
+		if err != nil {
+			return err
+		}
+		total += count
+		if count < archiveBatchSize {
+			break
+		}
+		time.Sleep(archiveBatchPause)
+	}
+	if total > 0 {
+		log.Printf("JanitorActor: Archived %d posts", total)
+	}
+	return nil
+}
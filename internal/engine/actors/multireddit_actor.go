@@ -0,0 +1,395 @@
+package actors
+
+import (
+	stdctx "context"
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/i18n"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/types"
+	"gator-swamp/internal/utils"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/google/uuid"
+)
+
+const (
+	maxMultiredditSubreddits = 50
+	maxMultiredditNameLength = 50
+	defaultMultiredditLimit  = 25
+)
+
+// Message types for MultiredditActor
+type (
+	CreateMultiredditMsg struct {
+		OwnerID      uuid.UUID
+		Name         string
+		SubredditIDs []uuid.UUID
+		IsPublic     bool
+	}
+
+	GetMultiredditMsg struct {
+		MultiredditID uuid.UUID
+		ViewerID      uuid.UUID
+	}
+
+	ListMultiredditsMsg struct {
+		OwnerID uuid.UUID
+	}
+
+	UpdateMultiredditMsg struct {
+		MultiredditID uuid.UUID
+		RequesterID   uuid.UUID
+		Name          *string
+		IsPublic      *bool
+	}
+
+	UpdateMultiredditSubredditMsg struct {
+		MultiredditID uuid.UUID
+		RequesterID   uuid.UUID
+		SubredditID   uuid.UUID
+		IsAdding      bool
+	}
+
+	DeleteMultiredditMsg struct {
+		MultiredditID uuid.UUID
+		RequesterID   uuid.UUID
+	}
+
+	GetMultiredditFeedMsg struct {
+		MultiredditID uuid.UUID
+		ViewerID      uuid.UUID
+		Sort          string
+		Limit         int
+		Cursor        string
+	}
+)
+
+// MultiredditActor manages user-defined collections of subreddits and their combined feeds
+type MultiredditActor struct {
+	mongodb *database.MongoDB
+}
+
+func NewMultiredditActor(mongodb *database.MongoDB) actor.Actor {
+	return &MultiredditActor{
+		mongodb: mongodb,
+	}
+}
+
+func (a *MultiredditActor) Receive(context actor.Context) {
+	switch msg := context.Message().(type) {
+	case *actor.Started:
+		log.Printf("MultiredditActor started")
+
+	case *CreateMultiredditMsg:
+		a.handleCreate(context, msg)
+
+	case *GetMultiredditMsg:
+		a.handleGet(context, msg)
+
+	case *ListMultiredditsMsg:
+		a.handleList(context, msg)
+
+	case *UpdateMultiredditMsg:
+		a.handleUpdate(context, msg)
+
+	case *UpdateMultiredditSubredditMsg:
+		a.handleUpdateSubreddit(context, msg)
+
+	case *DeleteMultiredditMsg:
+		a.handleDelete(context, msg)
+
+	case *GetMultiredditFeedMsg:
+		a.handleGetFeed(context, msg)
+	}
+}
+
+func (a *MultiredditActor) handleCreate(context actor.Context, msg *CreateMultiredditMsg) {
+	name, appErr := validateMultiredditName(msg.Name)
+	if appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+
+	// Deduplicate while preserving the caller's order
+	seen := make(map[uuid.UUID]bool)
+	subredditIDs := make([]uuid.UUID, 0, len(msg.SubredditIDs))
+	for _, id := range msg.SubredditIDs {
+		if !seen[id] {
+			seen[id] = true
+			subredditIDs = append(subredditIDs, id)
+		}
+	}
+
+	if len(subredditIDs) > maxMultiredditSubreddits {
+		context.Respond(utils.NewAppError(utils.ErrInvalidInput, "A multireddit can contain at most 50 subreddits", nil))
+		return
+	}
+
+	ctx := stdctx.Background()
+	for _, id := range subredditIDs {
+		if appErr := a.verifySubreddit(ctx, id); appErr != nil {
+			context.Respond(appErr)
+			return
+		}
+	}
+
+	now := time.Now()
+	multi := &models.Multireddit{
+		ID:           uuid.New(),
+		OwnerID:      msg.OwnerID,
+		Name:         name,
+		SubredditIDs: subredditIDs,
+		IsPublic:     msg.IsPublic,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	if err := a.mongodb.SaveMultireddit(ctx, multi); err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to save multireddit", err))
+		return
+	}
+
+	context.Respond(multi)
+}
+
+func (a *MultiredditActor) handleGet(context actor.Context, msg *GetMultiredditMsg) {
+	multi, appErr := a.loadViewable(msg.MultiredditID, msg.ViewerID)
+	if appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+	context.Respond(multi)
+}
+
+func (a *MultiredditActor) handleList(context actor.Context, msg *ListMultiredditsMsg) {
+	multis, err := a.mongodb.ListUserMultireddits(stdctx.Background(), msg.OwnerID)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to list multireddits", err))
+		return
+	}
+	context.Respond(multis)
+}
+
+func (a *MultiredditActor) handleUpdate(context actor.Context, msg *UpdateMultiredditMsg) {
+	multi, appErr := a.loadOwned(msg.MultiredditID, msg.RequesterID)
+	if appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+
+	if msg.Name != nil {
+		name, appErr := validateMultiredditName(*msg.Name)
+		if appErr != nil {
+			context.Respond(appErr)
+			return
+		}
+		multi.Name = name
+	}
+	if msg.IsPublic != nil {
+		multi.IsPublic = *msg.IsPublic
+	}
+	multi.UpdatedAt = time.Now()
+
+	if err := a.mongodb.SaveMultireddit(stdctx.Background(), multi); err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to update multireddit", err))
+		return
+	}
+
+	context.Respond(multi)
+}
+
+func (a *MultiredditActor) handleUpdateSubreddit(context actor.Context, msg *UpdateMultiredditSubredditMsg) {
+	multi, appErr := a.loadOwned(msg.MultiredditID, msg.RequesterID)
+	if appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+
+	ctx := stdctx.Background()
+	index := -1
+	for i, id := range multi.SubredditIDs {
+		if id == msg.SubredditID {
+			index = i
+			break
+		}
+	}
+
+	if msg.IsAdding {
+		if index >= 0 {
+			context.Respond(multi) // Already present
+			return
+		}
+		if len(multi.SubredditIDs) >= maxMultiredditSubreddits {
+			context.Respond(utils.NewAppError(utils.ErrInvalidInput, "A multireddit can contain at most 50 subreddits", nil))
+			return
+		}
+		if appErr := a.verifySubreddit(ctx, msg.SubredditID); appErr != nil {
+			context.Respond(appErr)
+			return
+		}
+		multi.SubredditIDs = append(multi.SubredditIDs, msg.SubredditID)
+	} else {
+		if index < 0 {
+			context.Respond(multi) // Nothing to remove
+			return
+		}
+		multi.SubredditIDs = append(multi.SubredditIDs[:index], multi.SubredditIDs[index+1:]...)
+	}
+	multi.UpdatedAt = time.Now()
+
+	if err := a.mongodb.SaveMultireddit(ctx, multi); err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to update multireddit", err))
+		return
+	}
+
+	context.Respond(multi)
+}
+
+func (a *MultiredditActor) handleDelete(context actor.Context, msg *DeleteMultiredditMsg) {
+	if _, appErr := a.loadOwned(msg.MultiredditID, msg.RequesterID); appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+
+	if err := a.mongodb.DeleteMultireddit(stdctx.Background(), msg.MultiredditID); err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to delete multireddit", err))
+		return
+	}
+
+	context.Respond(true)
+}
+
+// handleGetFeed lists posts from the multireddit's subreddits using the shared feed query.
+// Subreddits the viewer can no longer read are skipped rather than failing the request.
+func (a *MultiredditActor) handleGetFeed(context actor.Context, msg *GetMultiredditFeedMsg) {
+	startTime := time.Now()
+
+	multi, appErr := a.loadViewable(msg.MultiredditID, msg.ViewerID)
+	if appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+
+	ctx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+	defer cancel()
+
+	subredditIDs := make([]string, 0, len(multi.SubredditIDs))
+	for _, id := range multi.SubredditIDs {
+		if a.canViewSubreddit(ctx, id, msg.ViewerID) {
+			subredditIDs = append(subredditIDs, id.String())
+		}
+	}
+
+	limit := msg.Limit
+	if limit <= 0 || limit > 100 {
+		limit = defaultMultiredditLimit
+	}
+
+	posts, nextCursor, err := a.mongodb.GetFeedPosts(ctx, database.FeedQuery{
+		SubredditIDs: subredditIDs,
+		Sort:         msg.Sort,
+		Limit:        limit,
+		Cursor:       msg.Cursor,
+	})
+	if err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			context.Respond(appErr)
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get multireddit feed", err))
+		return
+	}
+
+	if posts == nil {
+		posts = []*models.Post{}
+	}
+	// Posts carry the same served fields as in the main feed
+	if err := a.mongodb.AttachCommentCounts(ctx, posts); err != nil {
+		log.Printf("MultiredditActor: Error attaching comment counts: %v", err)
+	}
+	if err := a.mongodb.AttachCrosspostOrigins(ctx, posts); err != nil {
+		log.Printf("MultiredditActor: Error attaching crosspost origins: %v", err)
+	}
+	if err := a.mongodb.AttachPostAuthorFlairs(ctx, posts); err != nil {
+		log.Printf("MultiredditActor: Error attaching author flairs: %v", err)
+	}
+
+	log.Printf("MultiredditActor: Served %d posts for multireddit %s in %v", len(posts), multi.ID, time.Since(startTime))
+	context.Respond(&types.PaginatedResponse{
+		Items:      posts,
+		NextCursor: nextCursor,
+	})
+}
+
+// loadViewable fetches a multireddit the viewer is allowed to read (owner, or public)
+func (a *MultiredditActor) loadViewable(id, viewerID uuid.UUID) (*models.Multireddit, *utils.AppError) {
+	multi, err := a.mongodb.GetMultireddit(stdctx.Background(), id)
+	if err != nil {
+		if utils.IsErrorCode(err, utils.ErrNotFound) {
+			return nil, utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrMultiredditNotFound, nil, nil)
+		}
+		return nil, utils.NewAppError(utils.ErrDatabase, "Failed to get multireddit", err)
+	}
+
+	// Private multireddits are reported as missing so their IDs can't be probed
+	if multi.OwnerID != viewerID && !multi.IsPublic {
+		return nil, utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrMultiredditNotFound, nil, nil)
+	}
+	return multi, nil
+}
+
+// loadOwned fetches a multireddit that the requester is allowed to modify
+func (a *MultiredditActor) loadOwned(id, requesterID uuid.UUID) (*models.Multireddit, *utils.AppError) {
+	multi, appErr := a.loadViewable(id, requesterID)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if multi.OwnerID != requesterID {
+		return nil, utils.NewAppError(utils.ErrForbidden, "Only the owner can modify this multireddit", nil)
+	}
+	return multi, nil
+}
+
+func (a *MultiredditActor) verifySubreddit(ctx stdctx.Context, id uuid.UUID) *utils.AppError {
+	subreddit, err := a.mongodb.GetSubredditByID(ctx, id)
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "Failed to get subreddit", err)
+	}
+	if subreddit == nil {
+		return utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrSubredditNotFound, nil, nil)
+	}
+	return nil
+}
+
+// canViewSubreddit reports whether a subreddit should contribute to a viewer's multireddit
+// feed: it must still exist, and if private the viewer must be allowed to read it
+func (a *MultiredditActor) canViewSubreddit(ctx stdctx.Context, id, viewerID uuid.UUID) bool {
+	subreddit, err := a.mongodb.GetSubredditByID(ctx, id)
+	if err != nil {
+		log.Printf("MultiredditActor: Error fetching subreddit %s: %v", id, err)
+		return false
+	}
+	if subreddit == nil {
+		return false
+	}
+	canView, err := a.mongodb.CanViewSubreddit(ctx, subreddit, viewerID)
+	if err != nil {
+		log.Printf("MultiredditActor: Error checking access to subreddit %s: %v", id, err)
+		return false
+	}
+	return canView
+}
+
+func validateMultiredditName(name string) (string, *utils.AppError) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", utils.NewAppError(utils.ErrInvalidInput, "Multireddit name is required", nil)
+	}
+	if len([]rune(name)) > maxMultiredditNameLength {
+		return "", utils.NewAppError(utils.ErrInvalidInput, "Multireddit name must be at most 50 characters", nil)
+	}
+	return name, nil
+}
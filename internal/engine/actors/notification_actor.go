@@ -0,0 +1,86 @@
+package actors
+
+import (
+	stdctx "context"
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/types"
+	"gator-swamp/internal/utils"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultNotificationPageSize = 25
+	maxNotificationPageSize     = 100
+)
+
+// Message types for NotificationActor
+type (
+	// GetNotificationsMsg requests a page of a user's notifications, newest first
+	GetNotificationsMsg struct {
+		UserID     uuid.UUID
+		Limit      int
+		Cursor     string
+		UnreadOnly bool
+	}
+
+	// MarkNotificationsReadMsg acknowledges notifications. An empty NotificationIDs marks all of them.
+	MarkNotificationsReadMsg struct {
+		UserID          uuid.UUID
+		NotificationIDs []uuid.UUID
+	}
+)
+
+// NotificationActor serves users' notifications. Notifications are recorded by the
+// actors that observe the triggering activity, such as the CommentActor for replies.
+type NotificationActor struct {
+	mongodb *database.MongoDB
+}
+
+func NewNotificationActor(mongodb *database.MongoDB) actor.Actor {
+	return &NotificationActor{
+		mongodb: mongodb,
+	}
+}
+
+func (a *NotificationActor) Receive(context actor.Context) {
+	switch msg := context.Message().(type) {
+	case *GetNotificationsMsg:
+		a.handleGetNotifications(context, msg)
+	case *MarkNotificationsReadMsg:
+		a.handleMarkNotificationsRead(context, msg)
+	}
+}
+
+func (a *NotificationActor) handleGetNotifications(context actor.Context, msg *GetNotificationsMsg) {
+	limit := msg.Limit
+	if limit <= 0 {
+		limit = defaultNotificationPageSize
+	}
+	if limit > maxNotificationPageSize {
+		limit = maxNotificationPageSize
+	}
+
+	notifications, nextCursor, err := a.mongodb.GetUserNotifications(stdctx.Background(), msg.UserID, limit, msg.Cursor, msg.UnreadOnly)
+	if err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			context.Respond(appErr)
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get notifications", err))
+		return
+	}
+
+	context.Respond(&types.PaginatedResponse{Items: notifications, NextCursor: nextCursor})
+}
+
+func (a *NotificationActor) handleMarkNotificationsRead(context actor.Context, msg *MarkNotificationsReadMsg) {
+	updated, err := a.mongodb.MarkNotificationsRead(stdctx.Background(), msg.UserID, msg.NotificationIDs)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to mark notifications read", err))
+		return
+	}
+
+	context.Respond(map[string]int64{"updated": updated})
+}
@@ -2,16 +2,30 @@ package actors
 
 import (
 	stdctx "context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"gator-swamp/internal/config"
 	"gator-swamp/internal/database"
+	"gator-swamp/internal/i18n"
 	"gator-swamp/internal/models"
+	"gator-swamp/internal/types"
 	"gator-swamp/internal/utils"
 	"log"
+	"math"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/asynkron/protoactor-go/actor"
+	"github.com/asynkron/protoactor-go/scheduler"
 	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -20,16 +34,75 @@ type (
 	CreatePostMsg struct {
 		Title       string
 		Content     string
+		URL         string     // Makes this a link post when set
+		MediaURLs   []string   // Attached images or videos; can't be combined with URL
+		FlairID     *uuid.UUID // One of the subreddit's flair templates
 		AuthorID    uuid.UUID
 		SubredditID uuid.UUID
+		Force       bool       // Lets a moderator post a link that was already posted recently
+		ScheduledAt *time.Time // Publishes the post at this time instead of now; past times publish immediately
+		Poll        *PollSpec  // Makes this a poll post when set
+
+		// Optional client-chosen key; repeating a request with the same key returns the
+		// post the first request created instead of creating another
+		IdempotencyKey string
+	}
+
+	// PollSpec describes the poll of a new poll post
+	PollSpec struct {
+		Options  []string
+		Duration time.Duration // How long the poll stays open
 	}
 
 	GetPostMsg struct {
-		PostID uuid.UUID
+		PostID   uuid.UUID
+		ViewerID uuid.UUID // Sees poll results once they have voted; uuid.Nil for anonymous viewers
+	}
+
+	// GetPostsByIDsMsg requests many posts at once
+	GetPostsByIDsMsg struct {
+		PostIDs  []uuid.UUID
+		ViewerID uuid.UUID // See GetPostMsg
+	}
+
+	// GetPostBySlugMsg requests a post by its permalink. Old slugs resolve too, so
+	// callers should compare the slug with the post's to redirect to its current URL.
+	GetPostBySlugMsg struct {
+		SubredditName string
+		Slug          string
+		ViewerID      uuid.UUID // See GetPostMsg
 	}
 
 	GetSubredditPostsMsg struct {
 		SubredditID uuid.UUID
+		ViewerID    uuid.UUID // Leaves out posts this user has hidden; uuid.Nil shows everything
+		FlairID     uuid.UUID // Only posts with this flair; uuid.Nil for any
+		Sort        string    // "hot" (default), "new" or "top"
+		Window      string    // Time window of the top sort, see database.TopWindowSince
+		Limit       int
+		Cursor      string
+	}
+
+	// GetTrendingPostsMsg requests the recent posts gaining upvotes fastest
+	GetTrendingPostsMsg struct {
+		ViewerID uuid.UUID // See GetPostMsg
+		Limit    int
+	}
+
+	// GetRelatedPostsMsg requests posts from the same subreddit with titles like the post's
+	GetRelatedPostsMsg struct {
+		PostID   uuid.UUID
+		ViewerID uuid.UUID // See GetPostMsg
+		Limit    int
+	}
+
+	// GetTopPostsMsg requests a page of the highest-karma posts across all subreddits
+	GetTopPostsMsg struct {
+		ViewerID uuid.UUID // See GetPostMsg
+		Sort     string    // database.SortTop (default) or database.SortControversial
+		Window   string    // See database.TopWindowSince
+		Limit    int
+		Cursor   string
 	}
 
 	VotePostMsg struct {
@@ -38,20 +111,156 @@ type (
 		IsUpvote bool
 	}
 
+	// UnvotePostMsg takes back a user's vote on a post
+	UnvotePostMsg struct {
+		PostID uuid.UUID
+		UserID uuid.UUID
+	}
+
+	// VotePostBatchMsg submits many of a user's votes at once, e.g. votes queued by an
+	// offline client
+	VotePostBatchMsg struct {
+		UserID uuid.UUID
+		Votes  []BatchVote
+	}
+
+	// GetPostVoteStatusesMsg requests how a user voted on each of many posts
+	GetPostVoteStatusesMsg struct {
+		UserID  uuid.UUID
+		PostIDs []uuid.UUID
+	}
+
 	GetUserFeedMsg struct {
+		UserID   uuid.UUID
+		Sort     string // "top" (default), "hot", "new" or "controversial"
+		HideNSFW bool   // Leave out posts from NSFW subreddits
+		Limit    int
+	}
+
+	DeletePostMsg struct {
+		PostID  uuid.UUID
+		UserID  uuid.UUID
+		AsAdmin bool // The user is an administrator, who may delete any post
+	}
+
+	EditPostMsg struct {
+		PostID   uuid.UUID
+		AuthorID uuid.UUID
+		Title    *string // Nil leaves the title unchanged
+		Content  *string // Nil leaves the content unchanged
+	}
+
+	// CrosspostMsg shares an existing post into another subreddit as a new post
+	CrosspostMsg struct {
+		OriginalPostID    uuid.UUID
+		TargetSubredditID uuid.UUID
+		AuthorID          uuid.UUID
+	}
+
+	UndeletePostMsg struct {
+		PostID      uuid.UUID
+		RequesterID uuid.UUID
+	}
+
+	// SetPostFlairMsg sets or, with a nil FlairID, clears a post's flair
+	SetPostFlairMsg struct {
+		PostID      uuid.UUID
+		RequesterID uuid.UUID
+		FlairID     *uuid.UUID
+	}
+
+	// PinPostMsg pins a post to the top of its subreddit, or unpins it
+	PinPostMsg struct {
+		PostID      uuid.UUID
+		RequesterID uuid.UUID
+		Pinned      bool
+	}
+
+	// HidePostMsg keeps a post out of a user's feed and listings. Hiding it again is a no-op.
+	HidePostMsg struct {
+		PostID uuid.UUID
+		UserID uuid.UUID
+	}
+
+	// UnhidePostMsg shows a hidden post again
+	UnhidePostMsg struct {
+		PostID uuid.UUID
+		UserID uuid.UUID
+	}
+
+	// GetHiddenPostsMsg requests a page of a user's hidden posts, most recently hidden first
+	GetHiddenPostsMsg struct {
 		UserID uuid.UUID
 		Limit  int
+		Cursor string
 	}
 
-	DeletePostMsg struct {
+	// GetFollowingFeedMsg requests a page of recent posts by the users a user follows,
+	// newest first
+	GetFollowingFeedMsg struct {
+		UserID uuid.UUID
+		Limit  int
+		Cursor string
+	}
+
+	// GetScheduledPostsMsg requests an author's posts that are waiting to be published
+	GetScheduledPostsMsg struct {
+		AuthorID uuid.UUID
+	}
+
+	// CancelScheduledPostMsg deletes one of an author's scheduled posts before it is published
+	CancelScheduledPostMsg struct {
+		PostID   uuid.UUID
+		AuthorID uuid.UUID
+	}
+
+	// GetModQueueMsg requests a page of the posts awaiting review in a subreddit, oldest
+	// first. Only its moderators may see them.
+	GetModQueueMsg struct {
+		SubredditID uuid.UUID
+		RequesterID uuid.UUID
+		Limit       int
+		Cursor      string
+	}
+
+	// ModQueueActionMsg approves or removes a post awaiting review
+	ModQueueActionMsg struct {
+		PostID      uuid.UUID
+		RequesterID uuid.UUID
+		Action      string // ModQueueApprove or ModQueueRemove
+	}
+
+	// VotePollMsg records a user's choice in a poll. Each user votes once per poll.
+	VotePollMsg struct {
 		PostID uuid.UUID
 		UserID uuid.UUID
+		Option int // Index into the poll's options
+	}
+
+	// RecordPostViewMsg counts a view of a post. It is sent without expecting a reply.
+	RecordPostViewMsg struct {
+		PostID uuid.UUID
+	}
+
+	// RecordPostShareMsg counts a share of a post. It is sent without expecting a reply.
+	RecordPostShareMsg struct {
+		PostID uuid.UUID
+	}
+
+	// EvictSubredditPostsMsg drops the cached posts of a deleted subreddit. It is sent
+	// without expecting a reply.
+	EvictSubredditPostsMsg struct {
+		SubredditID uuid.UUID
 	}
 
 	// Internal messages for actor initialization and metrics
 	GetCountsMsg           struct{}
 	initializePostActorMsg struct{}
 	loadPostsFromDBMsg     struct{}
+	refreshHotScoresMsg    struct{}
+	flushViewsMsg          struct{}
+	publishDuePostsMsg     struct{}
+	pruneRecentActivityMsg struct{}
 
 	// Internal struct for tracking votes
 	voteStatus struct {
@@ -62,6 +271,74 @@ type (
 	GetRecentPostsMsg struct {
 		Limit int
 	}
+
+	// GetUserPostsMsg requests a page of a user's posts, newest first
+	GetUserPostsMsg struct {
+		UserID      uuid.UUID
+		RequesterID uuid.UUID // Deleted and pending posts are included when this is the user themselves
+		Limit       int
+		Cursor      string
+	}
+
+	GetUserActivityMsg struct {
+		UserID   uuid.UUID
+		ViewerID uuid.UUID // Posts and comments in private subreddits the viewer can't read are left out
+		Limit    int
+		Cursor   string
+	}
+)
+
+// maxPostMedia is the most media attachments a post can have
+const maxPostMedia = 4
+
+// Actions a moderator can take on a post in the mod queue
+const (
+	ModQueueApprove = "approve"
+	ModQueueRemove  = "remove"
+)
+
+// maxPinnedPosts is the most posts a subreddit can have pinned at once
+const maxPinnedPosts = 2
+
+// maxBatchPosts is the most posts that can be fetched by ID at once
+const maxBatchPosts = 100
+
+// maxRelatedPosts is the most related posts returned for a post, and the default
+const maxRelatedPosts = 10
+
+const (
+	minPollOptions      = 2
+	maxPollOptions      = 6
+	maxPollOptionLength = 120
+	maxPollDuration     = 7 * 24 * time.Hour
+)
+
+// Views are counted in memory and written in batches, so a crash loses at most the
+// views of the last viewFlushInterval, and never more than viewFlushThreshold of them
+const (
+	viewFlushInterval  = 5 * time.Second
+	viewFlushThreshold = 100
+)
+
+const (
+	hotRefreshInterval = 5 * time.Minute
+	hotRefreshWindow   = 48 * time.Hour // Older posts have settled into their place in hot listings
+)
+
+// scheduledPublishInterval is how often scheduled posts that have come due are published
+const scheduledPublishInterval = time.Minute
+
+// activityPruneInterval is how often the post rate limit counters and the vote velocity
+// records drop what has aged out of their windows
+const activityPruneInterval = 10 * time.Minute
+
+// Trending posts are ranked by their upvotes over the last trendingWindow, and only
+// posts younger than trendingMaxAge qualify
+const (
+	trendingWindow       = time.Hour
+	trendingMaxAge       = 24 * time.Hour
+	maxTrendingPosts     = 50
+	defaultTrendingPosts = 10
 )
 
 // PostActor handles post-related operations
@@ -72,17 +349,52 @@ type PostActor struct {
 	metrics        *utils.MetricsCollector                // Metrics for performance tracking
 	enginePID      *actor.PID                             // Reference to the Engine actor
 	mongodb        *database.MongoDB                      // MongoDB client
+	undeleteWindow time.Duration                          // How long authors can restore their deleted posts
+	repostWindow   time.Duration                          // How far back a link post counts as a duplicate; 0 disables the check
+	archiveAfter   time.Duration                          // Age at which posts become read-only; 0 disables archival
+	maxTitleLen    int                                    // Longest title allowed, in characters
+	maxContentLen  int                                    // Longest content allowed, in characters
+	stopHotRefresh scheduler.CancelFunc                   // Stops the periodic hot score refresh
+	stopViewFlush  scheduler.CancelFunc                   // Stops the periodic view count flush
+	stopPublish    scheduler.CancelFunc                   // Stops the periodic publishing of scheduled posts
+	stopPrune      scheduler.CancelFunc                   // Stops the periodic pruning of the rate limit counters and vote velocities
+	pendingViews   map[uuid.UUID]int                      // Views not yet written to the database, by post
+	pendingTotal   int                                    // Sum of pendingViews
+	pendingShares  map[uuid.UUID]int                      // Shares not yet written to the database, by post; flushed with views
+	mediaTypes     map[string]string                      // Media file extensions allowed on posts, mapped to their media type
+	throttle       *postThrottle                          // Recent post creations by author, for the post rate limits
+	modRateFactor  int                                    // Multiplies the post rate limits in subreddits the author moderates
+	idempotencyTTL time.Duration                          // How long post creations can be replayed by idempotency key
+	controversyMin int                                    // Posts with fewer votes score zero for the controversial sort
+	txChecked      bool                                   // Whether the deployment has been asked if it supports transactions
+	noTransactions bool                                   // Set when MongoDB can't run transactions; votes then write counts and karma separately
+	velocity       *voteVelocity                          // Recent upvotes by post, for trending posts
+	automod        *automodMatcher                        // Checks new posts against their subreddit's automod rules
 }
 
 // NewPostActor creates a new PostActor instance
-func NewPostActor(metrics *utils.MetricsCollector, enginePID *actor.PID, mongodb *database.MongoDB) actor.Actor {
+func NewPostActor(metrics *utils.MetricsCollector, enginePID *actor.PID, mongodb *database.MongoDB, undeleteWindow, repostWindow, archiveAfter time.Duration, maxTitleLen, maxContentLen int, mediaTypes map[string]string, rateLimits []config.RateLimit, modRateFactor int, idempotencyTTL time.Duration, minControversialVotes int) actor.Actor {
 	return &PostActor{
 		postsByID:      make(map[uuid.UUID]*models.Post),
 		subredditPosts: make(map[uuid.UUID][]uuid.UUID),
 		postVotes:      make(map[uuid.UUID]map[uuid.UUID]voteStatus),
+		pendingViews:   make(map[uuid.UUID]int),
+		pendingShares:  make(map[uuid.UUID]int),
 		metrics:        metrics,
 		enginePID:      enginePID,
 		mongodb:        mongodb,
+		undeleteWindow: undeleteWindow,
+		repostWindow:   repostWindow,
+		archiveAfter:   archiveAfter,
+		maxTitleLen:    maxTitleLen,
+		maxContentLen:  maxContentLen,
+		mediaTypes:     mediaTypes,
+		throttle:       newPostThrottle(rateLimits),
+		modRateFactor:  modRateFactor,
+		idempotencyTTL: idempotencyTTL,
+		velocity:       newVoteVelocity(trendingWindow),
+		controversyMin: minControversialVotes,
+		automod:        newAutomodMatcher(),
 	}
 }
 
@@ -92,6 +404,54 @@ func (a *PostActor) Receive(context actor.Context) {
 	case *actor.Started:
 		log.Printf("PostActor started")
 		context.Send(context.Self(), &initializePostActorMsg{}) // Start initialization
+		a.stopHotRefresh = scheduler.NewTimerScheduler(context).
+			SendRepeatedly(hotRefreshInterval, hotRefreshInterval, context.Self(), &refreshHotScoresMsg{})
+		a.stopViewFlush = scheduler.NewTimerScheduler(context).
+			SendRepeatedly(viewFlushInterval, viewFlushInterval, context.Self(), &flushViewsMsg{})
+		a.stopPublish = scheduler.NewTimerScheduler(context).
+			SendRepeatedly(scheduledPublishInterval, scheduledPublishInterval, context.Self(), &publishDuePostsMsg{})
+		a.stopPrune = scheduler.NewTimerScheduler(context).
+			SendRepeatedly(activityPruneInterval, activityPruneInterval, context.Self(), &pruneRecentActivityMsg{})
+
+	case *actor.Stopping:
+		if a.stopHotRefresh != nil {
+			a.stopHotRefresh()
+		}
+		if a.stopViewFlush != nil {
+			a.stopViewFlush()
+		}
+		if a.stopPublish != nil {
+			a.stopPublish()
+		}
+		if a.stopPrune != nil {
+			a.stopPrune()
+		}
+		a.flushViews()
+		a.flushShares()
+
+	case *RecordPostViewMsg:
+		a.handleRecordPostView(msg)
+
+	case *EvictSubredditPostsMsg:
+		a.handleEvictSubredditPosts(msg)
+
+	case *RecordPostShareMsg:
+		a.handleRecordPostShare(msg)
+
+	case *flushViewsMsg:
+		a.flushViews()
+		a.flushShares()
+
+	case *refreshHotScoresMsg:
+		a.handleRefreshHotScores()
+
+	case *publishDuePostsMsg:
+		a.handlePublishDuePosts()
+
+	case *pruneRecentActivityMsg:
+		now := time.Now()
+		a.throttle.pruneAll(now)
+		a.velocity.prune(now)
 
 	case *initializePostActorMsg:
 		context.Send(context.Self(), &loadPostsFromDBMsg{}) // Trigger loading posts from DB
@@ -102,19 +462,81 @@ func (a *PostActor) Receive(context actor.Context) {
 	case *CreatePostMsg:
 		a.handleCreatePost(context, msg)
 
+	case *CrosspostMsg:
+		a.handleCrosspost(context, msg)
+
+	case *VotePollMsg:
+		a.handleVotePoll(context, msg)
+
+	case *GetModQueueMsg:
+		a.handleGetModQueue(context, msg)
+
+	case *ModQueueActionMsg:
+		a.handleModQueueAction(context, msg)
+
 	case *GetPostMsg:
 		a.handleGetPost(context, msg)
 
+	case *GetPostsByIDsMsg:
+		a.handleGetPostsByIDs(context, msg)
+
+	case *GetPostBySlugMsg:
+		a.handleGetPostBySlug(context, msg)
+
 	case *GetSubredditPostsMsg:
 		a.handleGetSubredditPosts(context, msg)
 
+	case *GetTopPostsMsg:
+		a.handleGetTopPosts(context, msg)
+
+	case *GetRelatedPostsMsg:
+		a.handleGetRelatedPosts(context, msg)
+
+	case *GetTrendingPostsMsg:
+		a.handleGetTrendingPosts(context, msg)
+
 	case *VotePostMsg:
 		a.handleVote(context, msg)
 
+	case *UnvotePostMsg:
+		a.handleUnvote(context, msg)
+
+	case *VotePostBatchMsg:
+		a.handleVoteBatch(context, msg)
+
+	case *GetPostVoteStatusesMsg:
+		a.handleGetPostVoteStatuses(context, msg)
+
 	case *GetUserFeedMsg:
 		a.handleGetUserFeed(context, msg)
 	case *GetRecentPostsMsg:
 		a.handleGetRecentPosts(context, msg)
+	case *GetUserActivityMsg:
+		a.handleGetUserActivity(context, msg)
+	case *GetUserPostsMsg:
+		a.handleGetUserPosts(context, msg)
+	case *EditPostMsg:
+		a.handleEditPost(context, msg)
+	case *DeletePostMsg:
+		a.handleDeletePost(context, msg)
+	case *UndeletePostMsg:
+		a.handleUndeletePost(context, msg)
+	case *SetPostFlairMsg:
+		a.handleSetPostFlair(context, msg)
+	case *PinPostMsg:
+		a.handlePinPost(context, msg)
+	case *HidePostMsg:
+		a.handleHidePost(context, msg)
+	case *UnhidePostMsg:
+		a.handleUnhidePost(context, msg)
+	case *GetHiddenPostsMsg:
+		a.handleGetHiddenPosts(context, msg)
+	case *GetFollowingFeedMsg:
+		a.handleGetFollowingFeed(context, msg)
+	case *GetScheduledPostsMsg:
+		a.handleGetScheduledPosts(context, msg)
+	case *CancelScheduledPostMsg:
+		a.handleCancelScheduledPost(context, msg)
 
 	default:
 		log.Printf("PostActor: Unknown message type: %T", msg)
@@ -145,6 +567,12 @@ func (a *PostActor) handleLoadPosts(context actor.Context) {
 			continue
 		}
 
+		// Deleted posts stay out of listings; they're cached again if restored. Scheduled
+		// and pending posts are cached when they are published.
+		if post.IsDeleted || post.Status != models.PostStatusPublished {
+			continue
+		}
+
 		a.postsByID[post.ID] = post
 		a.postVotes[post.ID] = make(map[uuid.UUID]voteStatus)
 		a.subredditPosts[post.SubredditID] = append(a.subredditPosts[post.SubredditID], post.ID)
@@ -158,6 +586,53 @@ func (a *PostActor) handleCreatePost(context actor.Context, msg *CreatePostMsg)
 	startTime := time.Now()
 	ctx := stdctx.Background()
 
+	fingerprint := ""
+	if msg.IdempotencyKey != "" {
+		fingerprint = requestFingerprint(msg)
+		if replayed, appErr := a.replayIdempotentPost(ctx, msg, fingerprint); appErr != nil || replayed != nil {
+			if appErr != nil {
+				context.Respond(appErr)
+			} else {
+				context.Respond(a.forViewer(msg.AuthorID, replayed)[0])
+			}
+			return
+		}
+	}
+
+	title, content := strings.TrimSpace(msg.Title), strings.TrimSpace(msg.Content)
+	if appErr := a.checkPostText(&title, &content); appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+	if appErr := a.checkPostRate(ctx, msg.AuthorID, msg.SubredditID); appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+	postURL := strings.TrimSpace(msg.URL)
+	if postURL != "" && !isWebURL(postURL) {
+		context.Respond(utils.NewAppError(utils.ErrInvalidInput, "Link must be an http or https URL", nil))
+		return
+	}
+	if postURL != "" && len(msg.MediaURLs) > 0 {
+		context.Respond(utils.NewAppError(utils.ErrInvalidInput, "A post can have a link or media attachments, not both", nil))
+		return
+	}
+	mediaType, appErr := a.mediaTypeOf(msg.MediaURLs)
+	if appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+	if msg.Poll != nil {
+		if postURL != "" || len(msg.MediaURLs) > 0 {
+			context.Respond(utils.NewAppError(utils.ErrInvalidInput, "A poll can't have a link or media attachments", nil))
+			return
+		}
+		if appErr := validatePoll(msg.Poll); appErr != nil {
+			context.Respond(appErr)
+			return
+		}
+	}
+
 	// Fetch the user to get their username
 	user, err := a.mongodb.GetUser(ctx, msg.AuthorID)
 	if err != nil {
@@ -171,19 +646,112 @@ func (a *PostActor) handleCreatePost(context actor.Context, msg *CreatePostMsg)
 		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch subreddit details", err))
 		return
 	}
+	if subreddit == nil {
+		context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrSubredditNotFound, nil, nil))
+		return
+	}
+	if appErr := a.checkCanPostIn(ctx, subreddit, user.ID); appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+	if appErr := a.checkKarmaToPost(ctx, subreddit, user); appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+	if appErr := checkSubredditBan(ctx, a.mongodb, subreddit.ID, user.ID); appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+	held, appErr := a.needsReview(ctx, subreddit, user.ID)
+	if appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+
+	normalizedURL := ""
+	if postURL != "" {
+		normalizedURL = normalizeLinkURL(postURL)
+		if appErr := a.checkDuplicateLink(ctx, msg, normalizedURL); appErr != nil {
+			context.Respond(appErr)
+			return
+		}
+	}
+
+	var flair *models.PostFlair
+	if msg.FlairID != nil {
+		if flair = findFlair(subreddit, *msg.FlairID); flair == nil {
+			context.Respond(utils.NewAppError(utils.ErrInvalidInput, "Flair is not one of the subreddit's flairs", nil))
+			return
+		}
+	}
 
 	newPost := &models.Post{
 		ID:             uuid.New(),
-		Title:          msg.Title,
-		Content:        msg.Content,
+		Title:          title,
+		Content:        content,
+		URL:            postURL,
+		NormalizedURL:  normalizedURL,
+		PostType:       models.PostTypeText,
+		Status:         models.PostStatusPublished,
+		MediaURLs:      msg.MediaURLs,
+		MediaType:      mediaType,
 		AuthorID:       msg.AuthorID,
 		AuthorUsername: user.Username,
 		SubredditID:    msg.SubredditID,
 		SubredditName:  subreddit.Name,
 		CreatedAt:      time.Now(),
-		Upvotes:        0,
+		Upvotes:        1, // The author's own upvote, see below
 		Downvotes:      0,
-		Karma:          0,
+		Karma:          1,
+
+		SubredditIconURL: subreddit.IconURL,
+	}
+	newPost.HotScore = utils.HotScore(newPost.Karma, newPost.CreatedAt)
+	if postURL != "" {
+		newPost.PostType = models.PostTypeLink
+		newPost.FlaggedForReview = content != ""
+	}
+	if flair != nil {
+		newPost.FlairID = &flair.ID
+		newPost.FlairText = flair.Text
+	}
+	if msg.ScheduledAt != nil && msg.ScheduledAt.After(newPost.CreatedAt) {
+		newPost.Status = models.PostStatusScheduled
+		newPost.ScheduledAt = msg.ScheduledAt
+	}
+	if held {
+		// Held posts are dated when a moderator approves them, not by the author's schedule
+		newPost.Status = models.PostStatusPending
+		newPost.ScheduledAt = nil
+	}
+	automodText := title + "\n" + content
+	if msg.Poll != nil {
+		automodText += "\n" + strings.Join(msg.Poll.Options, "\n")
+	}
+	automodLinks := append([]string{postURL}, msg.MediaURLs...)
+	applyAutomod(newPost, a.automodRule(subreddit, user.ID, automodText, automodLinks...))
+	if msg.Poll != nil {
+		// Scheduled polls open when they are published
+		opensAt := newPost.CreatedAt
+		if newPost.ScheduledAt != nil {
+			opensAt = *newPost.ScheduledAt
+		}
+		pollOptions := make([]string, len(msg.Poll.Options))
+		for i, option := range msg.Poll.Options {
+			pollOptions[i] = strings.TrimSpace(option)
+		}
+		newPost.PostType = models.PostTypePoll
+		newPost.Poll = &models.Poll{
+			Options:           pollOptions,
+			ClosesAt:          opensAt.Add(msg.Poll.Duration),
+			ResultsAfterClose: subreddit.PollResultsAfterClose,
+			Tally:             make([]int, len(pollOptions)),
+		}
+	}
+
+	if newPost.Slug, err = a.mongodb.UniquePostSlug(ctx, newPost.SubredditID, newPost.ID, newPost.Title); err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to save post", err))
+		return
 	}
 
 	postDoc := a.mongodb.ModelToDocument(newPost)
@@ -191,203 +759,2101 @@ func (a *PostActor) handleCreatePost(context actor.Context, msg *CreatePostMsg)
 		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to save post", err))
 		return
 	}
+	a.throttle.record(msg.AuthorID, time.Now())
+	// Authors upvote their own posts, counted in the post's score but not their karma.
+	// Without the record they could upvote again, so a failure is worth logging.
+	if err := a.mongodb.RecordPostVote(ctx, newPost.ID, msg.AuthorID, true, newPost.CreatedAt); err != nil {
+		log.Printf("PostActor: failed to record self-upvote on post %s: %v", newPost.ID, err)
+	}
+	if msg.IdempotencyKey != "" {
+		// The post exists either way, so a failure here only loses the ability to replay
+		if err := a.mongodb.SaveIdempotencyKey(ctx, &database.IdempotencyKeyDocument{
+			AuthorID:    msg.AuthorID.String(),
+			Key:         msg.IdempotencyKey,
+			Fingerprint: fingerprint,
+			PostID:      newPost.ID.String(),
+			ExpiresAt:   time.Now().Add(a.idempotencyTTL),
+		}); err != nil {
+			log.Printf("PostActor: %v", err)
+		}
+	}
+
+	// Scheduled and pending posts are cached when they are published
+	if newPost.Status != models.PostStatusPublished {
+		context.Respond(a.forViewer(msg.AuthorID, newPost)[0])
+		return
+	}
 
 	// Update local caches and respond as before
 	a.postsByID[newPost.ID] = newPost
-	a.postVotes[newPost.ID] = make(map[uuid.UUID]voteStatus)
+	a.postVotes[newPost.ID] = map[uuid.UUID]voteStatus{
+		msg.AuthorID: {IsUpvote: true, VotedAt: newPost.CreatedAt},
+	}
 	a.subredditPosts[msg.SubredditID] = append(a.subredditPosts[msg.SubredditID], newPost.ID)
 
 	a.metrics.AddOperationLatency("create_post", time.Since(startTime))
-	context.Respond(newPost)
+	context.Respond(a.forViewer(msg.AuthorID, newPost)[0])
 }
 
-// Handles retrieving a specific post by ID
-func (a *PostActor) handleGetPost(context actor.Context, msg *GetPostMsg) {
-	if post, exists := a.postsByID[msg.PostID]; exists {
-		context.Respond(post)
-		return
-	}
-
+// Handles crossposting. The new post copies the original's title and links to it, but
+// starts without votes. Crossposts of crossposts link to the first post in the chain.
+func (a *PostActor) handleCrosspost(context actor.Context, msg *CrosspostMsg) {
+	startTime := time.Now()
 	ctx := stdctx.Background()
-	var post models.Post
-	err := a.mongodb.Posts.FindOne(ctx, bson.M{"_id": msg.PostID}).Decode(&post)
+
+	original, err := a.mongodb.GetPost(ctx, msg.OriginalPostID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			context.Respond(utils.NewAppError(utils.ErrNotFound, "Post not found", nil))
-		} else {
-			context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch post", err))
+		if utils.IsErrorCode(err, utils.ErrNotFound) {
+			context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil))
+			return
 		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get post", err))
 		return
 	}
+	if original.IsDeleted {
+		context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil))
+		return
+	}
+	parentID := original.ID
+	if original.CrosspostParentID != nil {
+		parentID = *original.CrosspostParentID
+	}
 
-	a.postsByID[post.ID] = &post
-	a.postVotes[post.ID] = make(map[uuid.UUID]voteStatus)
-	a.subredditPosts[post.SubredditID] = append(a.subredditPosts[post.SubredditID], post.ID)
-
-	context.Respond(&post)
-}
-
-// Handles retrieving all posts for a subreddit
-func (a *PostActor) handleGetSubredditPosts(context actor.Context, msg *GetSubredditPostsMsg) {
-	log.Printf("Fetching posts for subreddit: %s", msg.SubredditID)
+	if original.SubredditID == msg.TargetSubredditID {
+		context.Respond(utils.NewAppError(utils.ErrInvalidInput, "A post can only be crossposted into a different subreddit", nil))
+		return
+	}
+	if appErr := checkCanViewSubreddit(ctx, a.mongodb, original.SubredditID, msg.AuthorID); appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+	if appErr := a.checkPostRate(ctx, msg.AuthorID, msg.TargetSubredditID); appErr != nil {
+		context.Respond(appErr)
+		return
+	}
 
-	// Query MongoDB directly for the latest data
-	ctx := stdctx.Background()
-	posts, err := a.mongodb.GetSubredditPosts(ctx, msg.SubredditID)
+	user, err := a.mongodb.GetUser(ctx, msg.AuthorID)
 	if err != nil {
-		log.Printf("Error fetching subreddit posts: %v", err)
-		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch subreddit posts", err))
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch author details", err))
 		return
 	}
 
-	if len(posts) == 0 {
-		log.Printf("No posts found for subreddit: %s", msg.SubredditID)
-		context.Respond([]*models.Post{}) // Return empty array instead of error
+	subreddit, err := a.mongodb.GetSubredditByID(ctx, msg.TargetSubredditID)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch subreddit details", err))
 		return
 	}
-
-	// Update local cache with fetched posts
-	for _, post := range posts {
-		a.postsByID[post.ID] = post
-		if _, exists := a.postVotes[post.ID]; !exists {
-			a.postVotes[post.ID] = make(map[uuid.UUID]voteStatus)
-		}
+	if subreddit == nil {
+		context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrSubredditNotFound, nil, nil))
+		return
+	}
+	if appErr := a.checkCanPostIn(ctx, subreddit, user.ID); appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+	if appErr := a.checkKarmaToPost(ctx, subreddit, user); appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+	if appErr := checkSubredditBan(ctx, a.mongodb, subreddit.ID, user.ID); appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+	held, appErr := a.needsReview(ctx, subreddit, user.ID)
+	if appErr != nil {
+		context.Respond(appErr)
+		return
 	}
 
-	log.Printf("Found %d posts for subreddit: %s", len(posts), msg.SubredditID)
-	context.Respond(posts)
-}
-
-// Handles voting on a post
-func (a *PostActor) handleVote(context actor.Context, msg *VotePostMsg) {
-	startTime := time.Now()
-
-	post, exists := a.postsByID[msg.PostID]
-	if !exists {
-		context.Respond(utils.NewAppError(utils.ErrNotFound, "Post not found", nil))
+	crosspost := &models.Post{
+		ID:                uuid.New(),
+		Title:             original.Title,
+		PostType:          models.PostTypeCrosspost,
+		Status:            models.PostStatusPublished,
+		CrosspostParentID: &parentID,
+		AuthorID:          msg.AuthorID,
+		AuthorUsername:    user.Username,
+		SubredditID:       subreddit.ID,
+		SubredditName:     subreddit.Name,
+		CreatedAt:         time.Now(),
+
+		SubredditIconURL: subreddit.IconURL,
+	}
+	if held {
+		crosspost.Status = models.PostStatusPending
+	}
+	applyAutomod(crosspost, a.automodRule(subreddit, user.ID, original.Title, original.URL))
+	if crosspost.Slug, err = a.mongodb.UniquePostSlug(ctx, crosspost.SubredditID, crosspost.ID, crosspost.Title); err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to save post", err))
 		return
 	}
 
-	if _, exists := a.postVotes[msg.PostID]; !exists {
-		a.postVotes[msg.PostID] = make(map[uuid.UUID]voteStatus)
+	if _, err := a.mongodb.Posts.InsertOne(ctx, a.mongodb.ModelToDocument(crosspost)); err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to save post", err))
+		return
 	}
+	a.throttle.record(msg.AuthorID, time.Now())
 
-	previousVote, hasVoted := a.postVotes[msg.PostID][msg.UserID]
+	if crosspost.Status != models.PostStatusPublished {
+		context.Respond(crosspost)
+		return
+	}
+	a.postsByID[crosspost.ID] = crosspost
+	a.postVotes[crosspost.ID] = make(map[uuid.UUID]voteStatus)
+	a.subredditPosts[crosspost.SubredditID] = append(a.subredditPosts[crosspost.SubredditID], crosspost.ID)
 
-	// Calculate vote changes
-	upvoteDelta := 0
-	downvoteDelta := 0
+	a.attachServedFields(crosspost)
+	a.metrics.AddOperationLatency("create_post", time.Since(startTime))
+	context.Respond(crosspost)
+}
 
-	if hasVoted {
-		if previousVote.IsUpvote == msg.IsUpvote {
-			context.Respond(utils.NewAppError(utils.ErrDuplicate, "Already voted", nil))
+// Handles retrieving a specific post by ID. Posts in private subreddits are only served
+// to the subreddit's members.
+func (a *PostActor) handleGetPost(context actor.Context, msg *GetPostMsg) {
+	ctx := stdctx.Background()
+	post, exists := a.postsByID[msg.PostID]
+	if !exists {
+		var err error
+		post, err = a.mongodb.GetPost(ctx, msg.PostID)
+		if err != nil {
+			if utils.IsErrorCode(err, utils.ErrNotFound) {
+				context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil))
+			} else {
+				context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch post", err))
+			}
 			return
 		}
-		if msg.IsUpvote {
-			upvoteDelta = 1
-			downvoteDelta = -1
-			post.Downvotes--
-			post.Upvotes++
+
+		a.addPendingCounts(post)
+		a.postsByID[post.ID] = post
+		a.postVotes[post.ID] = make(map[uuid.UUID]voteStatus)
+		a.subredditPosts[post.SubredditID] = append(a.subredditPosts[post.SubredditID], post.ID)
+	}
+
+	if appErr := checkCanViewSubreddit(ctx, a.mongodb, post.SubredditID, msg.ViewerID); appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+
+	a.attachServedFields(post)
+	context.Respond(a.forViewer(msg.ViewerID, post)[0])
+}
+
+// PostBatch is the result of fetching posts by ID: the posts found, in the order they were
+// requested, and the IDs that have no post
+type PostBatch struct {
+	Posts   []*models.Post `json:"posts"`
+	Missing []string       `json:"missing"`
+}
+
+// Handles retrieving many posts by ID. Cached posts are served from the cache and the
+// rest are loaded with a single query. Posts in private subreddits the viewer isn't a
+// member of are reported missing.
+func (a *PostActor) handleGetPostsByIDs(context actor.Context, msg *GetPostsByIDsMsg) {
+	if len(msg.PostIDs) > maxBatchPosts {
+		context.Respond(utils.NewAppError(utils.ErrInvalidInput,
+			fmt.Sprintf("At most %d posts can be fetched at once", maxBatchPosts), nil))
+		return
+	}
+
+	found := make(map[uuid.UUID]*models.Post, len(msg.PostIDs))
+	var uncached []uuid.UUID
+	for _, postID := range msg.PostIDs {
+		if post, exists := a.postsByID[postID]; exists {
+			found[postID] = post
 		} else {
-			upvoteDelta = -1
-			downvoteDelta = 1
-			post.Upvotes--
-			post.Downvotes++
+			uncached = append(uncached, postID)
 		}
-	} else {
-		if msg.IsUpvote {
-			upvoteDelta = 1
-			post.Upvotes++
-		} else {
-			downvoteDelta = 1
-			post.Downvotes++
+	}
+
+	if len(uncached) > 0 {
+		loaded, err := a.mongodb.GetPostsByIDs(stdctx.Background(), uncached)
+		if err != nil {
+			context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch posts", err))
+			return
+		}
+		a.addPendingCounts(loaded...)
+		for _, post := range loaded {
+			found[post.ID] = post
 		}
 	}
 
-	// Update vote status in memory
-	a.postVotes[msg.PostID][msg.UserID] = voteStatus{
-		IsUpvote: msg.IsUpvote,
-		VotedAt:  time.Now(),
+	if len(found) > 0 {
+		hidden, err := hiddenSubreddits(stdctx.Background(), a.mongodb, msg.ViewerID)
+		if err != nil {
+			context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to check private subreddits", err))
+			return
+		}
+		for postID, post := range found {
+			if hidden[post.SubredditID] {
+				delete(found, postID)
+			}
+		}
 	}
-	post.Karma = post.Upvotes - post.Downvotes
 
-	// Update MongoDB
-	// In handleVote function, replace the MongoDB update section with:
+	batch := &PostBatch{Posts: make([]*models.Post, 0, len(found)), Missing: []string{}}
+	for _, postID := range msg.PostIDs {
+		if post, ok := found[postID]; ok {
+			batch.Posts = append(batch.Posts, post)
+		} else {
+			batch.Missing = append(batch.Missing, postID.String())
+		}
+	}
+
+	a.attachServedFields(batch.Posts...)
+	batch.Posts = a.forViewer(msg.ViewerID, batch.Posts...)
+	context.Respond(batch)
+}
+
+// Handles retrieving a post by its subreddit and current or old slug
+func (a *PostActor) handleGetPostBySlug(context actor.Context, msg *GetPostBySlugMsg) {
 	ctx := stdctx.Background()
-	err := a.mongodb.UpdatePostVotes(ctx, post.ID, upvoteDelta, downvoteDelta)
+	subreddit, err := a.mongodb.GetSubredditByName(ctx, msg.SubredditName)
 	if err != nil {
-		log.Printf("Failed to update post votes in MongoDB: %v", err)
-		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to persist vote", err))
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch subreddit details", err))
+		return
+	}
+	if subreddit == nil {
+		context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrSubredditNotFound, nil, nil))
 		return
 	}
 
-	// Update user karma
-	context.Send(a.enginePID, &UpdateKarmaMsg{
-		UserID: post.AuthorID,
-		Delta: func() int {
-			if msg.IsUpvote {
-				return 1
-			}
-			return -1
-		}(),
-	})
+	post, err := a.mongodb.GetPostBySlug(ctx, subreddit.ID, msg.Slug)
+	if err != nil {
+		if utils.IsErrorCode(err, utils.ErrNotFound) {
+			context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil))
+		} else {
+			context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch post", err))
+		}
+		return
+	}
 
-	a.metrics.AddOperationLatency("vote_post", time.Since(startTime))
-	context.Respond(post)
+	// Serve the cached copy where there is one, as it has the latest votes and views.
+	// This also keeps private subreddits' posts from non-members.
+	a.handleGetPost(context, &GetPostMsg{PostID: post.ID, ViewerID: msg.ViewerID})
 }
 
-// Handles fetching the user's feed
-func (a *PostActor) handleGetUserFeed(context actor.Context, msg *GetUserFeedMsg) {
-	startTime := time.Now()
-	ctx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
-	defer cancel()
+// Handles retrieving a page of a subreddit's posts
+func (a *PostActor) handleGetSubredditPosts(context actor.Context, msg *GetSubredditPostsMsg) {
+	log.Printf("Fetching posts for subreddit: %s", msg.SubredditID)
 
-	feedPosts, err := a.mongodb.GetUserFeedPosts(ctx, msg.UserID, msg.Limit)
+	// Unknown sorts fall back to hot rather than failing the listing
+	sort := msg.Sort
+	if sort != database.SortNew && sort != database.SortTop && sort != database.SortControversial {
+		sort = database.SortHot
+	}
+
+	limit := msg.Limit
+	if limit <= 0 || limit > 100 {
+		limit = 25
+	}
+
+	// Query MongoDB directly for the latest data
+	ctx := stdctx.Background()
+	if appErr := checkCanViewSubreddit(ctx, a.mongodb, msg.SubredditID, msg.ViewerID); appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+	query := database.FeedQuery{Sort: sort, Limit: limit, Cursor: msg.Cursor}
+	if msg.ViewerID != uuid.Nil {
+		query.HiddenFor = msg.ViewerID.String()
+	}
+	if msg.FlairID != uuid.Nil {
+		query.FlairID = msg.FlairID.String()
+	}
+	if sort == database.SortTop || sort == database.SortControversial {
+		query.Since = database.TopWindowSince(msg.Window, time.Now())
+	}
+	posts, nextCursor, err := a.mongodb.GetSubredditPosts(ctx, msg.SubredditID, query)
 	if err != nil {
-		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get feed posts", err))
+		log.Printf("Error fetching subreddit posts: %v", err)
+		if appErr, ok := err.(*utils.AppError); ok {
+			context.Respond(appErr)
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch subreddit posts", err))
 		return
 	}
 
-	a.metrics.AddOperationLatency("get_feed", time.Since(startTime))
-	context.Respond(feedPosts)
+	if len(posts) == 0 {
+		log.Printf("No posts found for subreddit: %s", msg.SubredditID)
+		// Return an empty page instead of an error
+		context.Respond(&types.PaginatedResponse{Items: []*models.Post{}, NextCursor: ""})
+		return
+	}
+
+	// Update local cache with fetched posts
+	a.addPendingCounts(posts...)
+	for _, post := range posts {
+		a.postsByID[post.ID] = post
+		if _, exists := a.postVotes[post.ID]; !exists {
+			a.postVotes[post.ID] = make(map[uuid.UUID]voteStatus)
+		}
+	}
+
+	log.Printf("Found %d posts for subreddit: %s", len(posts), msg.SubredditID)
+	a.attachServedFields(posts...)
+	context.Respond(&types.PaginatedResponse{Items: a.forViewer(msg.ViewerID, posts...), NextCursor: nextCursor})
 }
 
-func (a *PostActor) handleGetRecentPosts(context actor.Context, msg *GetRecentPostsMsg) {
+// findFlair returns the subreddit's flair template with the given ID, or nil
+func findFlair(subreddit *models.Subreddit, flairID uuid.UUID) *models.PostFlair {
+	for i := range subreddit.Flairs {
+		if subreddit.Flairs[i].ID == flairID {
+			return &subreddit.Flairs[i]
+		}
+	}
+	return nil
+}
+
+// Handles the author or a moderator changing a post's flair
+func (a *PostActor) handleSetPostFlair(context actor.Context, msg *SetPostFlairMsg) {
 	ctx := stdctx.Background()
 
-	// Set up options for sorting by creation date
-	opts := options.Find().
-		SetSort(bson.D{{Key: "createdat", Value: -1}}).
-		SetLimit(int64(msg.Limit))
+	post, err := a.mongodb.GetPost(ctx, msg.PostID)
+	if err != nil {
+		if utils.IsErrorCode(err, utils.ErrNotFound) {
+			context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil))
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get post", err))
+		return
+	}
+	if post.IsDeleted {
+		context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil))
+		return
+	}
 
-	// Query MongoDB for recent posts
-	cursor, err := a.mongodb.Posts.Find(ctx, bson.M{}, opts)
+	if post.AuthorID != msg.RequesterID {
+		isModerator, err := a.mongodb.IsSubredditModerator(ctx, post.SubredditID, msg.RequesterID)
+		if err != nil {
+			context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to check moderator status", err))
+			return
+		}
+		if !isModerator {
+			context.Respond(utils.NewAppError(utils.ErrForbidden, "Only the author and moderators can change a post's flair", nil))
+			return
+		}
+	}
+
+	var flair *models.PostFlair
+	if msg.FlairID != nil {
+		subreddit, err := a.mongodb.GetSubredditByID(ctx, post.SubredditID)
+		if err != nil {
+			context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch subreddit details", err))
+			return
+		}
+		if subreddit != nil {
+			flair = findFlair(subreddit, *msg.FlairID)
+		}
+		if flair == nil {
+			context.Respond(utils.NewAppError(utils.ErrInvalidInput, "Flair is not one of the subreddit's flairs", nil))
+			return
+		}
+	}
+
+	updated, err := a.mongodb.SetPostFlair(ctx, post.ID, flair)
 	if err != nil {
-		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch recent posts", err))
+		if appErr, ok := err.(*utils.AppError); ok {
+			context.Respond(appErr)
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to set post flair", err))
 		return
 	}
-	defer cursor.Close(ctx)
 
-	var posts []*models.Post
-	for cursor.Next(ctx) {
-		var doc database.PostDocument
-		if err := cursor.Decode(&doc); err != nil {
-			log.Printf("Error decoding post document: %v", err)
-			continue
+	if cached, exists := a.postsByID[updated.ID]; exists {
+		cached.FlairID = updated.FlairID
+		cached.FlairText = updated.FlairText
+	}
+	a.addPendingCounts(updated)
+	a.attachServedFields(updated)
+	context.Respond(updated)
+}
+
+// Handles moderators pinning and unpinning posts in their subreddit
+func (a *PostActor) handlePinPost(context actor.Context, msg *PinPostMsg) {
+	ctx := stdctx.Background()
+
+	post, err := a.mongodb.GetPost(ctx, msg.PostID)
+	if err != nil {
+		if utils.IsErrorCode(err, utils.ErrNotFound) {
+			context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil))
+			return
 		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get post", err))
+		return
+	}
 
-		post, err := a.mongodb.DocumentToModel(&doc)
+	isModerator, err := a.mongodb.IsSubredditModerator(ctx, post.SubredditID, msg.RequesterID)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to check moderator status", err))
+		return
+	}
+	if !isModerator {
+		context.Respond(utils.NewAppError(utils.ErrForbidden, "Only moderators can pin posts", nil))
+		return
+	}
+
+	if post.IsDeleted {
+		context.Respond(utils.NewAppError(utils.ErrGone, "Cannot pin deleted post", nil))
+		return
+	}
+	if post.IsPinned == msg.Pinned {
+		a.addPendingCounts(post)
+		context.Respond(post)
+		return
+	}
+
+	if msg.Pinned {
+		count, err := a.mongodb.CountPinnedPosts(ctx, post.SubredditID)
 		if err != nil {
-			log.Printf("Error converting document to model: %v", err)
-			continue
+			context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to count pinned posts", err))
+			return
+		}
+		if count >= maxPinnedPosts {
+			context.Respond(utils.NewAppError(utils.ErrInvalidInput,
+				fmt.Sprintf("A subreddit can have at most %d pinned posts; unpin one first", maxPinnedPosts), nil))
+			return
 		}
-		posts = append(posts, post)
 	}
 
-	if err := cursor.Err(); err != nil {
-		context.Respond(utils.NewAppError(utils.ErrDatabase, "Error reading posts", err))
+	updated, err := a.mongodb.SetPostPinned(ctx, post.ID, msg.Pinned, time.Now().UTC().Truncate(time.Millisecond))
+	if err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			context.Respond(appErr)
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to pin post", err))
 		return
 	}
 
-	context.Respond(posts)
+	if cached, exists := a.postsByID[updated.ID]; exists {
+		cached.IsPinned = updated.IsPinned
+		cached.PinnedAt = updated.PinnedAt
+	}
+	a.addPendingCounts(updated)
+	a.attachServedFields(updated)
+	context.Respond(updated)
+}
+
+// Handles hiding a post from a user
+func (a *PostActor) handleHidePost(context actor.Context, msg *HidePostMsg) {
+	ctx := stdctx.Background()
+
+	post, err := a.mongodb.GetPost(ctx, msg.PostID)
+	if err != nil {
+		if utils.IsErrorCode(err, utils.ErrNotFound) {
+			context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil))
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get post", err))
+		return
+	}
+	if post.IsDeleted {
+		context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil))
+		return
+	}
+
+	if err := a.mongodb.HidePost(ctx, msg.UserID, msg.PostID, time.Now().UTC().Truncate(time.Millisecond)); err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to hide post", err))
+		return
+	}
+
+	context.Respond(map[string]bool{"hidden": true})
+}
+
+// Handles showing a hidden post again
+func (a *PostActor) handleUnhidePost(context actor.Context, msg *UnhidePostMsg) {
+	if err := a.mongodb.UnhidePost(stdctx.Background(), msg.UserID, msg.PostID); err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to unhide post", err))
+		return
+	}
+
+	context.Respond(map[string]bool{"hidden": false})
+}
+
+// Handles listing the posts a user has hidden
+func (a *PostActor) handleGetHiddenPosts(context actor.Context, msg *GetHiddenPostsMsg) {
+	limit := msg.Limit
+	if limit <= 0 || limit > 100 {
+		limit = 25
+	}
+
+	hidden, nextCursor, err := a.mongodb.GetHiddenPosts(stdctx.Background(), msg.UserID, limit, msg.Cursor)
+	if err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			context.Respond(appErr)
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get hidden posts", err))
+		return
+	}
+
+	context.Respond(&types.PaginatedResponse{Items: hidden, NextCursor: nextCursor})
+}
+
+// Handles retrieving a page of the top posts across all subreddits
+func (a *PostActor) handleGetTopPosts(context actor.Context, msg *GetTopPostsMsg) {
+	limit := msg.Limit
+	if limit <= 0 || limit > 100 {
+		limit = 25
+	}
+
+	sort := msg.Sort
+	if sort != database.SortControversial {
+		sort = database.SortTop
+	}
+
+	ctx := stdctx.Background()
+	hidden, err := a.mongodb.GetPrivateSubredditIDsHiddenFrom(ctx, msg.ViewerID)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to check private subreddits", err))
+		return
+	}
+	posts, nextCursor, err := a.mongodb.GetFeedPosts(ctx, database.FeedQuery{
+		AllSubreddits:       true,
+		ExcludeSubredditIDs: hidden,
+		Sort:                sort,
+		Since:               database.TopWindowSince(msg.Window, time.Now()),
+		Limit:               limit,
+		Cursor:              msg.Cursor,
+	})
+	if err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			context.Respond(appErr)
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch top posts", err))
+		return
+	}
+	if posts == nil {
+		posts = []*models.Post{}
+	}
+
+	a.addPendingCounts(posts...)
+	a.attachServedFields(posts...)
+	context.Respond(&types.PaginatedResponse{Items: a.forViewer(msg.ViewerID, posts...), NextCursor: nextCursor})
+}
+
+// Handles retrieving the posts related to a post
+func (a *PostActor) handleGetRelatedPosts(context actor.Context, msg *GetRelatedPostsMsg) {
+	limit := msg.Limit
+	if limit <= 0 || limit > maxRelatedPosts {
+		limit = maxRelatedPosts
+	}
+
+	ctx := stdctx.Background()
+	post, err := a.mongodb.GetPost(ctx, msg.PostID)
+	if err != nil {
+		if utils.IsErrorCode(err, utils.ErrNotFound) {
+			context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil))
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get post", err))
+		return
+	}
+	if post.IsDeleted {
+		context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil))
+		return
+	}
+	if appErr := checkCanViewSubreddit(ctx, a.mongodb, post.SubredditID, msg.ViewerID); appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+
+	related, err := a.mongodb.GetRelatedPosts(ctx, post, limit)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch related posts", err))
+		return
+	}
+	if related == nil {
+		related = []*models.Post{}
+	}
+
+	a.addPendingCounts(related...)
+	a.attachServedFields(related...)
+	context.Respond(a.forViewer(msg.ViewerID, related...))
+}
+
+// Handles retrieving trending posts: those under a day old and not archived that gained
+// the most upvotes per minute over the last hour, recent upvotes counting for more
+func (a *PostActor) handleGetTrendingPosts(context actor.Context, msg *GetTrendingPostsMsg) {
+	limit := msg.Limit
+	if limit <= 0 {
+		limit = defaultTrendingPosts
+	}
+	limit = min(limit, maxTrendingPosts)
+
+	hidden, err := hiddenSubreddits(stdctx.Background(), a.mongodb, msg.ViewerID)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to check private subreddits", err))
+		return
+	}
+
+	now := time.Now()
+	scores := a.velocity.scores(now)
+	trending := make([]*models.Post, 0, len(scores))
+	for postID := range scores {
+		post, exists := a.postsByID[postID]
+		if !exists || post.IsDeleted || now.Sub(post.CreatedAt) > trendingMaxAge || post.ArchivedAt(now, a.archiveAfter) {
+			continue
+		}
+		if hidden[post.SubredditID] {
+			continue
+		}
+		trending = append(trending, post)
+	}
+	sort.Slice(trending, func(i, j int) bool {
+		if scores[trending[i].ID] != scores[trending[j].ID] {
+			return scores[trending[i].ID] > scores[trending[j].ID]
+		}
+		return trending[i].CreatedAt.After(trending[j].CreatedAt)
+	})
+	if len(trending) > limit {
+		trending = trending[:limit]
+	}
+
+	a.attachServedFields(trending...)
+	context.Respond(a.forViewer(msg.ViewerID, trending...))
+}
+
+// Handles voting on a post
+func (a *PostActor) handleVote(context actor.Context, msg *VotePostMsg) {
+	startTime := time.Now()
+	post, appErr := a.vote(context, msg, startTime)
+	if appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+
+	a.metrics.AddOperationLatency("vote_post", time.Since(startTime))
+	context.Respond(post)
+}
+
+// Handles taking back a vote, which moves the post's score and its author's karma back
+// by as much as the vote moved them
+func (a *PostActor) handleUnvote(context actor.Context, msg *UnvotePostMsg) {
+	startTime := time.Now()
+	post, appErr := a.unvote(context, msg, startTime)
+	if appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+
+	a.metrics.AddOperationLatency("unvote_post", time.Since(startTime))
+	context.Respond(post)
+}
+
+// maxBatchVotes is the most votes that can be submitted at once
+const maxBatchVotes = 100
+
+// BatchVote is one vote in a VotePostBatchMsg. ClientTimestamp is when the client cast
+// the vote; it is returned with the vote's result to help clients match them up.
+type BatchVote struct {
+	PostID          uuid.UUID
+	IsUpvote        bool
+	ClientTimestamp *time.Time
+}
+
+// Outcomes of a vote in a batch
+const (
+	BatchVoteApplied   = "applied"
+	BatchVoteUnchanged = "unchanged" // The user had already voted that way
+	BatchVoteFailed    = "failed"
+)
+
+// BatchVoteResult is the outcome of one vote in a batch. Error is the AppError code of
+// a failed vote.
+type BatchVoteResult struct {
+	PostID          string     `json:"postId"`
+	Status          string     `json:"status"`
+	Error           string     `json:"error,omitempty"`
+	Karma           *int       `json:"karma,omitempty"` // The post's karma after an applied vote
+	ClientTimestamp *time.Time `json:"clientTimestamp,omitempty"`
+	SubredditID     uuid.UUID  `json:"-"` // The post's subreddit after an applied vote, for analytics
+}
+
+// Handles a batch of votes, applied in order as if each had been sent on its own. A vote
+// that fails doesn't stop the rest.
+func (a *PostActor) handleVoteBatch(context actor.Context, msg *VotePostBatchMsg) {
+	if len(msg.Votes) > maxBatchVotes {
+		context.Respond(utils.NewAppError(utils.ErrInvalidInput,
+			fmt.Sprintf("At most %d votes can be submitted at once", maxBatchVotes), nil))
+		return
+	}
+
+	startTime := time.Now()
+	results := make([]BatchVoteResult, len(msg.Votes))
+	for i, vote := range msg.Votes {
+		result := BatchVoteResult{PostID: vote.PostID.String(), ClientTimestamp: vote.ClientTimestamp}
+		post, appErr := a.vote(context, &VotePostMsg{
+			PostID:   vote.PostID,
+			UserID:   msg.UserID,
+			IsUpvote: vote.IsUpvote,
+		}, time.Now())
+		switch {
+		case appErr == nil:
+			karma := post.Karma
+			result.Status = BatchVoteApplied
+			result.Karma = &karma
+			result.SubredditID = post.SubredditID
+		case appErr.Code == utils.ErrDuplicate:
+			result.Status = BatchVoteUnchanged
+		default:
+			result.Status = BatchVoteFailed
+			result.Error = appErr.Code
+		}
+		results[i] = result
+	}
+
+	a.metrics.AddOperationLatency("vote_post_batch", time.Since(startTime))
+	context.Respond(results)
+}
+
+// vote records a user's vote on a post at now and updates the post's score and its
+// author's karma. It returns the post as voted on.
+func (a *PostActor) vote(context actor.Context, msg *VotePostMsg, now time.Time) (*models.Post, *utils.AppError) {
+	post, previousVote, hasVoted, appErr := a.votablePost(msg.PostID, msg.UserID, now)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	// Calculate vote changes
+	upvoteDelta := 0
+	downvoteDelta := 0
+	vote := database.PostVote{PostID: msg.PostID, UserID: msg.UserID, IsUpvote: msg.IsUpvote, VotedAt: now}
+
+	if hasVoted {
+		if previousVote.IsUpvote == msg.IsUpvote {
+			return nil, utils.NewAppError(utils.ErrDuplicate, "Already voted", nil)
+		}
+		if msg.IsUpvote {
+			upvoteDelta = 1
+			downvoteDelta = -1
+		} else {
+			upvoteDelta = -1
+			downvoteDelta = 1
+		}
+		vote.Switched = true
+		vote.PreviousVotedAt = previousVote.VotedAt
+	} else {
+		if msg.IsUpvote {
+			upvoteDelta = 1
+		} else {
+			downvoteDelta = 1
+		}
+	}
+
+	return a.recordVote(context, post, vote, upvoteDelta, downvoteDelta)
+}
+
+// unvote takes back a user's vote on a post at now. It returns the post without the vote.
+func (a *PostActor) unvote(context actor.Context, msg *UnvotePostMsg, now time.Time) (*models.Post, *utils.AppError) {
+	post, previousVote, hasVoted, appErr := a.votablePost(msg.PostID, msg.UserID, now)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if !hasVoted {
+		return nil, utils.NewAppError(utils.ErrNotFound, "No vote to remove", nil)
+	}
+
+	vote := database.PostVote{
+		PostID:          msg.PostID,
+		UserID:          msg.UserID,
+		IsUpvote:        previousVote.IsUpvote,
+		VotedAt:         now,
+		Removed:         true,
+		PreviousVotedAt: previousVote.VotedAt,
+	}
+	if previousVote.IsUpvote {
+		return a.recordVote(context, post, vote, -1, 0)
+	}
+	return a.recordVote(context, post, vote, 0, -1)
+}
+
+// votablePost returns a post that can be voted on at now, with the user's current vote
+// on it if they have one
+func (a *PostActor) votablePost(postID, userID uuid.UUID, now time.Time) (*models.Post, voteStatus, bool, *utils.AppError) {
+	post, exists := a.postsByID[postID]
+	if !exists {
+		return nil, voteStatus{}, false, utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil)
+	}
+	if post.ArchivedAt(now, a.archiveAfter) {
+		return nil, voteStatus{}, false, utils.NewLocalizedError(utils.ErrArchived, i18n.ErrPostArchived, nil, nil)
+	}
+
+	previousVote, hasVoted, err := a.previousVote(stdctx.Background(), postID, userID)
+	if err != nil {
+		return nil, voteStatus{}, false, utils.NewAppError(utils.ErrDatabase, "Failed to check previous vote", err)
+	}
+	return post, previousVote, hasVoted, nil
+}
+
+// recordVote stores a vote that moves the post's counts by the deltas, along with its
+// author's karma, and applies it to the cache
+func (a *PostActor) recordVote(context actor.Context, post *models.Post, vote database.PostVote, upvoteDelta, downvoteDelta int) (*models.Post, *utils.AppError) {
+	// Move the author's karma by as much as the post's: ±1 for a fresh or removed vote,
+	// ±2 for a switched one. Authors' votes on their own posts count in the post's score
+	// but not their karma, so they can't farm it.
+	change := database.VoteChange{
+		UpvoteDelta:           upvoteDelta,
+		DownvoteDelta:         downvoteDelta,
+		MinControversialVotes: a.controversyMin,
+	}
+	if vote.UserID != post.AuthorID {
+		change.AuthorKarma = upvoteDelta - downvoteDelta
+	}
+	if err := a.applyVoteChange(context, post, vote, change); err != nil {
+		// The user's vote may have changed behind the cache, so look it up again next time
+		delete(a.postVotes[vote.PostID], vote.UserID)
+		if appErr, ok := err.(*utils.AppError); ok {
+			return nil, appErr
+		}
+		log.Printf("Failed to persist post vote in MongoDB: %v", err)
+		return nil, utils.NewAppError(utils.ErrDatabase, "Failed to persist vote", err)
+	}
+
+	// Update vote status in memory
+	if vote.Removed {
+		delete(a.postVotes[vote.PostID], vote.UserID)
+	} else {
+		a.postVotes[vote.PostID][vote.UserID] = voteStatus{
+			IsUpvote: vote.IsUpvote,
+			VotedAt:  vote.VotedAt,
+		}
+	}
+
+	post.Upvotes += upvoteDelta
+	post.Downvotes += downvoteDelta
+	post.Karma = post.Upvotes - post.Downvotes
+	post.HotScore = utils.HotScore(post.Karma, post.CreatedAt)
+	post.ControversyScore = utils.PostControversyScore(post.Upvotes, post.Downvotes, a.controversyMin)
+	if vote.IsUpvote && !vote.Removed {
+		a.velocity.record(post.ID, vote.VotedAt)
+	}
+
+	return post, nil
+}
+
+// applyVoteChange stores a vote and its change to a post's counts and its author's
+// karma. All of them are written in one transaction where MongoDB supports them.
+// Otherwise the vote and counts are written here, with the vote undone if the counts
+// can't be, and the karma by the user supervisor, which logs what to reconcile if its
+// write fails.
+func (a *PostActor) applyVoteChange(context actor.Context, post *models.Post, vote database.PostVote, change database.VoteChange) error {
+	ctx := stdctx.Background()
+	karma := &UpdateKarmaMsg{UserID: post.AuthorID, Delta: change.AuthorKarma, Source: KarmaSourcePost}
+
+	// Servers that can't run transactions don't all refuse them the same way, so the
+	// deployment is asked before the first vote
+	if !a.txChecked {
+		supported, err := a.mongodb.SupportsTransactions(ctx)
+		if err != nil {
+			return err
+		}
+		if !supported {
+			log.Printf("PostActor: MongoDB deployment doesn't support transactions; votes will update post counts and author karma separately")
+		}
+		a.noTransactions = !supported
+		a.txChecked = true
+	}
+
+	if !a.noTransactions {
+		err := a.mongodb.UpdateVoteTransactional(ctx, vote, post.AuthorID, change)
+		if err == nil {
+			if change.AuthorKarma != 0 {
+				karma.Persisted = true
+				context.Send(a.enginePID, karma)
+			}
+			return nil
+		}
+		if err != database.ErrTransactionsUnsupported {
+			return err
+		}
+		log.Printf("PostActor: %v; votes will update post counts and author karma separately", err)
+		a.noTransactions = true
+	}
+
+	if err := a.mongodb.SavePostVote(ctx, vote); err != nil {
+		return err
+	}
+	if err := a.mongodb.UpdatePostVotes(ctx, post.ID, change.UpvoteDelta, change.DownvoteDelta, change.MinControversialVotes); err != nil {
+		// Leaving the vote recorded would make retries fail as duplicates while the
+		// counts never include it
+		if undoErr := a.mongodb.UndoPostVote(ctx, vote); undoErr != nil {
+			log.Printf("PostActor: Failed to undo vote of user %s on post %s, reconcile the vote record: %v",
+				vote.UserID, vote.PostID, undoErr)
+		}
+		return err
+	}
+	if change.AuthorKarma != 0 {
+		context.Send(a.enginePID, karma)
+	}
+	return nil
+}
+
+// Handles looking up how a user voted on many posts at once. The answer comes from a
+// single query of the vote records, whether or not the posts are cached. Each requested
+// post maps to "up", "down" or nil if the user hasn't voted on it.
+func (a *PostActor) handleGetPostVoteStatuses(context actor.Context, msg *GetPostVoteStatusesMsg) {
+	if len(msg.PostIDs) > maxBatchPosts {
+		context.Respond(utils.NewAppError(utils.ErrInvalidInput,
+			fmt.Sprintf("At most %d posts can be looked up at once", maxBatchPosts), nil))
+		return
+	}
+
+	postIDs := make([]string, len(msg.PostIDs))
+	for i, postID := range msg.PostIDs {
+		postIDs[i] = postID.String()
+	}
+	directions, err := a.mongodb.GetPostVoteDirections(stdctx.Background(), msg.UserID, postIDs)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get votes", err))
+		return
+	}
+
+	up, down := "up", "down"
+	statuses := make(map[string]*string, len(postIDs))
+	for _, postID := range postIDs {
+		statuses[postID] = nil
+		if isUpvote, voted := directions[postID]; voted {
+			if isUpvote {
+				statuses[postID] = &up
+			} else {
+				statuses[postID] = &down
+			}
+		}
+	}
+	context.Respond(statuses)
+}
+
+// previousVote returns a user's vote on a post, from the cache or else from MongoDB. The
+// vote records outlive restarts, so the cache only saves lookups and isn't trusted to be
+// complete; a vote found in MongoDB is cached for next time.
+func (a *PostActor) previousVote(ctx stdctx.Context, postID, userID uuid.UUID) (voteStatus, bool, error) {
+	if _, exists := a.postVotes[postID]; !exists {
+		a.postVotes[postID] = make(map[uuid.UUID]voteStatus)
+	}
+	if vote, exists := a.postVotes[postID][userID]; exists {
+		return vote, true, nil
+	}
+
+	doc, err := a.mongodb.GetPostVote(ctx, postID, userID)
+	if err != nil || doc == nil {
+		return voteStatus{}, false, err
+	}
+	vote := voteStatus{IsUpvote: doc.IsUpvote, VotedAt: doc.VotedAt}
+	a.postVotes[postID][userID] = vote
+	return vote, true, nil
+}
+
+// handleRefreshHotScores rescores recent posts from their current karma, in MongoDB and
+// in the cache. Votes already rescore a post, so this catches posts whose rescoring
+// failed or whose karma changed outside handleVote.
+func (a *PostActor) handleRefreshHotScores() {
+	ctx, cancel := stdctx.WithTimeout(stdctx.Background(), time.Minute)
+	defer cancel()
+
+	since := time.Now().Add(-hotRefreshWindow)
+	count, err := a.mongodb.RefreshHotScores(ctx, since)
+	if err != nil {
+		log.Printf("PostActor: %v", err)
+		return
+	}
+
+	for _, post := range a.postsByID {
+		if post.CreatedAt.After(since) {
+			post.HotScore = utils.HotScore(post.Karma, post.CreatedAt)
+		}
+	}
+	log.Printf("PostActor: Refreshed hot scores of %d posts", count)
+}
+
+// handlePublishDuePosts publishes the scheduled posts that have come due and caches them
+// like newly created posts
+func (a *PostActor) handlePublishDuePosts() {
+	ctx, cancel := stdctx.WithTimeout(stdctx.Background(), time.Minute)
+	defer cancel()
+
+	published, err := a.mongodb.PublishDuePosts(ctx, time.Now())
+	if err != nil {
+		log.Printf("PostActor: %v", err)
+	}
+	for _, post := range published {
+		a.postsByID[post.ID] = post
+		a.postVotes[post.ID] = make(map[uuid.UUID]voteStatus)
+		a.subredditPosts[post.SubredditID] = append(a.subredditPosts[post.SubredditID], post.ID)
+	}
+	if len(published) > 0 {
+		log.Printf("PostActor: Published %d scheduled posts", len(published))
+	}
+}
+
+// Handles listing an author's scheduled posts
+func (a *PostActor) handleGetScheduledPosts(context actor.Context, msg *GetScheduledPostsMsg) {
+	posts, err := a.mongodb.GetScheduledPosts(stdctx.Background(), msg.AuthorID)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get scheduled posts", err))
+		return
+	}
+	context.Respond(posts)
+}
+
+// Handles cancelling a scheduled post. Posts that were already published can't be
+// cancelled this way; they are deleted like any other post.
+func (a *PostActor) handleCancelScheduledPost(context actor.Context, msg *CancelScheduledPostMsg) {
+	cancelled, err := a.mongodb.CancelScheduledPost(stdctx.Background(), msg.PostID, msg.AuthorID)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to cancel scheduled post", err))
+		return
+	}
+	if !cancelled {
+		context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil))
+		return
+	}
+	context.Respond(map[string]bool{"cancelled": true})
+}
+
+// Handles listing the posts awaiting review in a subreddit
+func (a *PostActor) handleGetModQueue(context actor.Context, msg *GetModQueueMsg) {
+	limit := msg.Limit
+	if limit <= 0 || limit > 100 {
+		limit = 25
+	}
+
+	ctx := stdctx.Background()
+	isModerator, err := a.mongodb.IsSubredditModerator(ctx, msg.SubredditID, msg.RequesterID)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to check moderator status", err))
+		return
+	}
+	if !isModerator {
+		context.Respond(utils.NewAppError(utils.ErrForbidden, "Only moderators can view the mod queue", nil))
+		return
+	}
+
+	posts, nextCursor, err := a.mongodb.GetPendingPosts(ctx, msg.SubredditID, limit, msg.Cursor)
+	if err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			context.Respond(appErr)
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get mod queue", err))
+		return
+	}
+	a.attachServedFields(posts...)
+	context.Respond(&types.PaginatedResponse{Items: posts, NextCursor: nextCursor})
+}
+
+// Handles a moderator approving or removing a post awaiting review. Approved posts are
+// published as of the approval and cached like newly created posts.
+func (a *PostActor) handleModQueueAction(context actor.Context, msg *ModQueueActionMsg) {
+	if msg.Action != ModQueueApprove && msg.Action != ModQueueRemove {
+		context.Respond(utils.NewAppError(utils.ErrInvalidInput,
+			fmt.Sprintf("Action must be %q or %q", ModQueueApprove, ModQueueRemove), nil))
+		return
+	}
+
+	ctx := stdctx.Background()
+	post, err := a.mongodb.GetPendingPost(ctx, msg.PostID)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get post", err))
+		return
+	}
+	if post == nil {
+		context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil))
+		return
+	}
+
+	isModerator, err := a.mongodb.IsSubredditModerator(ctx, post.SubredditID, msg.RequesterID)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to check moderator status", err))
+		return
+	}
+	if !isModerator {
+		context.Respond(utils.NewAppError(utils.ErrForbidden, "Only moderators can review posts", nil))
+		return
+	}
+
+	if msg.Action == ModQueueRemove {
+		removed, err := a.mongodb.RemovePendingPost(ctx, post.ID)
+		if err != nil {
+			context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to remove post", err))
+			return
+		}
+		if !removed {
+			// Another moderator reviewed it first
+			context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil))
+			return
+		}
+		post.Status = models.PostStatusRemoved
+		context.Respond(post)
+		return
+	}
+
+	approved, err := a.mongodb.ApprovePendingPost(ctx, post, time.Now())
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to approve post", err))
+		return
+	}
+	if !approved {
+		context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil))
+		return
+	}
+
+	a.postsByID[post.ID] = post
+	a.postVotes[post.ID] = make(map[uuid.UUID]voteStatus)
+	a.subredditPosts[post.SubredditID] = append(a.subredditPosts[post.SubredditID], post.ID)
+
+	a.attachServedFields(post)
+	context.Respond(a.forViewer(msg.RequesterID, post)[0])
+}
+
+// Handles fetching the user's feed
+func (a *PostActor) handleGetUserFeed(context actor.Context, msg *GetUserFeedMsg) {
+	startTime := time.Now()
+	ctx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+	defer cancel()
+
+	feedPosts, err := a.mongodb.GetUserFeedPosts(ctx, msg.UserID, msg.Sort, msg.HideNSFW, msg.Limit)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get feed posts", err))
+		return
+	}
+
+	a.addPendingCounts(feedPosts...)
+	a.attachServedFields(feedPosts...)
+	a.metrics.AddOperationLatency("get_feed", time.Since(startTime))
+	context.Respond(a.forViewer(msg.UserID, feedPosts...))
+}
+
+func (a *PostActor) handleGetRecentPosts(context actor.Context, msg *GetRecentPostsMsg) {
+	ctx := stdctx.Background()
+
+	// Set up options for sorting by creation date
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdat", Value: -1}}).
+		SetLimit(int64(msg.Limit))
+
+	// Recent posts are listed anonymously, so private subreddits are left out
+	hidden, err := a.mongodb.GetPrivateSubredditIDsHiddenFrom(ctx, uuid.Nil)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to check private subreddits", err))
+		return
+	}
+	filter := bson.M{"status": bson.M{"$nin": database.UnlistedPostStatuses}}
+	if len(hidden) > 0 {
+		filter["subredditid"] = bson.M{"$nin": hidden}
+	}
+
+	// Query MongoDB for recent posts
+	cursor, err := a.mongodb.Posts.Find(ctx, filter, opts)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch recent posts", err))
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var posts []*models.Post
+	for cursor.Next(ctx) {
+		var doc database.PostDocument
+		if err := cursor.Decode(&doc); err != nil {
+			log.Printf("Error decoding post document: %v", err)
+			continue
+		}
+
+		post, err := a.mongodb.DocumentToModel(&doc)
+		if err != nil {
+			log.Printf("Error converting document to model: %v", err)
+			continue
+		}
+		posts = append(posts, post)
+	}
+
+	if err := cursor.Err(); err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Error reading posts", err))
+		return
+	}
+
+	a.addPendingCounts(posts...)
+	a.attachServedFields(posts...)
+	context.Respond(a.forViewer(uuid.Nil, posts...))
+}
+
+// Handles fetching a user's submission history
+func (a *PostActor) handleGetUserPosts(context actor.Context, msg *GetUserPostsMsg) {
+	limit := msg.Limit
+	if limit <= 0 || limit > 100 {
+		limit = 25
+	}
+
+	ctx := stdctx.Background()
+	ownPosts := msg.RequesterID == msg.UserID
+	posts, nextCursor, err := a.mongodb.GetPostsByAuthor(ctx, msg.UserID, ownPosts, limit, msg.Cursor)
+	if err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			context.Respond(appErr)
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch user posts", err))
+		return
+	}
+
+	// Posts in private subreddits the requester can't read are dropped from the page,
+	// which can leave it short
+	hidden, err := hiddenSubreddits(ctx, a.mongodb, msg.RequesterID)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to check private subreddits", err))
+		return
+	}
+	visible := posts[:0]
+	for _, post := range posts {
+		if !hidden[post.SubredditID] {
+			visible = append(visible, post)
+		}
+	}
+	posts = visible
+
+	a.addPendingCounts(posts...)
+	a.attachServedFields(posts...)
+	context.Respond(&types.PaginatedResponse{Items: a.forViewer(msg.RequesterID, posts...), NextCursor: nextCursor})
+}
+
+// Handles fetching recent posts by the users a user follows
+func (a *PostActor) handleGetFollowingFeed(context actor.Context, msg *GetFollowingFeedMsg) {
+	limit := msg.Limit
+	if limit <= 0 || limit > 100 {
+		limit = 25
+	}
+
+	ctx := stdctx.Background()
+	posts, nextCursor, err := a.mongodb.GetFollowingFeed(ctx, msg.UserID, limit, msg.Cursor)
+	if err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			context.Respond(appErr)
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get following feed", err))
+		return
+	}
+
+	// Posts in private subreddits the user can't read are dropped from the page, which
+	// can leave it short
+	hidden, err := hiddenSubreddits(ctx, a.mongodb, msg.UserID)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to check private subreddits", err))
+		return
+	}
+	visible := posts[:0]
+	for _, post := range posts {
+		if !hidden[post.SubredditID] {
+			visible = append(visible, post)
+		}
+	}
+	posts = visible
+
+	a.addPendingCounts(posts...)
+	a.attachServedFields(posts...)
+	context.Respond(&types.PaginatedResponse{Items: a.forViewer(msg.UserID, posts...), NextCursor: nextCursor})
+}
+
+// Handles fetching a user's combined post and comment timeline
+func (a *PostActor) handleGetUserActivity(context actor.Context, msg *GetUserActivityMsg) {
+	startTime := time.Now()
+	ctx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+	defer cancel()
+
+	limit := msg.Limit
+	if limit <= 0 || limit > 100 {
+		limit = 25
+	}
+
+	items, nextCursor, err := a.mongodb.GetUserActivity(ctx, msg.UserID, limit, msg.Cursor)
+	if err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			context.Respond(appErr)
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get user activity", err))
+		return
+	}
+
+	// Items in private subreddits the viewer can't read are dropped from the page, which
+	// can leave it short
+	hidden, err := hiddenSubreddits(ctx, a.mongodb, msg.ViewerID)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to check private subreddits", err))
+		return
+	}
+	visible := items[:0]
+	for _, item := range items {
+		if item.Post != nil && hidden[item.Post.SubredditID] ||
+			item.Comment != nil && hidden[item.Comment.SubredditID] {
+			continue
+		}
+		visible = append(visible, item)
+	}
+	items = visible
+
+	a.metrics.AddOperationLatency("get_user_activity", time.Since(startTime))
+	context.Respond(&types.PaginatedResponse{
+		Items:      items,
+		NextCursor: nextCursor,
+	})
+}
+
+// handleEditPost lets an author change the title and/or content of their post
+func (a *PostActor) handleEditPost(context actor.Context, msg *EditPostMsg) {
+	if msg.Title == nil && msg.Content == nil {
+		context.Respond(utils.NewAppError(utils.ErrInvalidInput, "Nothing to edit", nil))
+		return
+	}
+	var title, content *string
+	if msg.Title != nil {
+		trimmed := strings.TrimSpace(*msg.Title)
+		title = &trimmed
+	}
+	if msg.Content != nil {
+		trimmed := strings.TrimSpace(*msg.Content)
+		if trimmed == "" {
+			context.Respond(utils.NewAppError(utils.ErrInvalidInput, "Post content cannot be empty", nil))
+			return
+		}
+		if isLinkOnly(trimmed) {
+			context.Respond(utils.NewAppError(utils.ErrInvalidInput, "Post content cannot be just a link", nil))
+			return
+		}
+		content = &trimmed
+	}
+	if appErr := a.checkPostText(title, content); appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+
+	ctx := stdctx.Background()
+	post, err := a.mongodb.GetPost(ctx, msg.PostID)
+	if err != nil {
+		if utils.IsErrorCode(err, utils.ErrNotFound) {
+			context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil))
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get post", err))
+		return
+	}
+	if post.IsDeleted {
+		context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil))
+		return
+	}
+	if post.AuthorID != msg.AuthorID {
+		context.Respond(utils.NewAppError(utils.ErrUnauthorized, "Not authorized to edit post", nil))
+		return
+	}
+	if post.ArchivedAt(time.Now(), a.archiveAfter) {
+		context.Respond(utils.NewLocalizedError(utils.ErrArchived, i18n.ErrPostArchived, nil, nil))
+		return
+	}
+
+	// A new title gets a new slug; the old one keeps resolving so shared links don't break
+	slug, oldSlugs := "", post.OldSlugs
+	if title != nil {
+		newSlug, err := a.mongodb.UniquePostSlug(ctx, post.SubredditID, post.ID, *title)
+		if err != nil {
+			context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to edit post", err))
+			return
+		}
+		if newSlug != post.Slug {
+			slug = newSlug
+			oldSlugs = make([]string, 0, len(post.OldSlugs)+1)
+			for _, old := range post.OldSlugs {
+				if old != newSlug {
+					oldSlugs = append(oldSlugs, old)
+				}
+			}
+			if post.Slug != "" {
+				oldSlugs = append(oldSlugs, post.Slug)
+			}
+		}
+	}
+
+	edited, err := a.mongodb.EditPost(ctx, post.ID, title, content, slug, oldSlugs, time.Now().UTC())
+	if err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			context.Respond(appErr)
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to edit post", err))
+		return
+	}
+
+	a.addPendingCounts(edited)
+	a.postsByID[edited.ID] = edited
+	a.attachServedFields(edited)
+	context.Respond(edited)
+}
+
+// replayIdempotentPost returns the post an earlier request with the same idempotency key
+// created, or nil if the key is unused. Reusing a key for a different request fails
+// with ErrDuplicate.
+func (a *PostActor) replayIdempotentPost(ctx stdctx.Context, msg *CreatePostMsg, fingerprint string) (*models.Post, *utils.AppError) {
+	record, err := a.mongodb.GetIdempotencyKey(ctx, msg.AuthorID, msg.IdempotencyKey)
+	if err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "Failed to check idempotency key", err)
+	}
+	if record == nil {
+		return nil, nil
+	}
+	if record.Fingerprint != fingerprint {
+		return nil, utils.NewAppError(utils.ErrDuplicate, "Idempotency key was already used for a different post", nil)
+	}
+
+	postID, err := uuid.Parse(record.PostID)
+	if err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "Invalid post ID for idempotency key", err)
+	}
+	if post, exists := a.postsByID[postID]; exists {
+		a.attachServedFields(post)
+		return post, nil
+	}
+	post, err := a.mongodb.GetPostAnyStatus(ctx, record.PostID)
+	if err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "Failed to fetch post", err)
+	}
+	a.addPendingCounts(post)
+	a.attachServedFields(post)
+	return post, nil
+}
+
+// requestFingerprint hashes everything about a post creation request except its
+// idempotency key, to tell a replayed request from a different one under the same key
+func requestFingerprint(msg *CreatePostMsg) string {
+	request := *msg
+	request.IdempotencyKey = ""
+	encoded, _ := json.Marshal(request)
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// checkKarmaToPost rejects a new post from an author with less karma than the subreddit
+// requires. Its creator and moderators can always post.
+func (a *PostActor) checkKarmaToPost(ctx stdctx.Context, subreddit *models.Subreddit, author *models.User) *utils.AppError {
+	if subreddit.MinKarmaToPost <= 0 || author.Karma >= subreddit.MinKarmaToPost || author.ID == subreddit.CreatorID {
+		return nil
+	}
+	isModerator, err := a.mongodb.IsSubredditModerator(ctx, subreddit.ID, author.ID)
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "Failed to check moderator status", err)
+	}
+	if isModerator {
+		return nil
+	}
+	return utils.NewAppError(utils.ErrUnauthorized, fmt.Sprintf(
+		"Posting in r/%s requires %d karma; you have %d", subreddit.Name, subreddit.MinKarmaToPost, author.Karma), nil)
+}
+
+// needsReview reports whether a new post in a restricted subreddit from an author its
+// moderators haven't approved must wait in the mod queue. Its creator and moderators
+// publish directly.
+func (a *PostActor) needsReview(ctx stdctx.Context, subreddit *models.Subreddit, authorID uuid.UUID) (bool, *utils.AppError) {
+	if subreddit.Type != models.SubredditRestricted || subreddit.IsApproved(authorID) {
+		return false, nil
+	}
+	isModerator, err := a.mongodb.IsSubredditModerator(ctx, subreddit.ID, authorID)
+	if err != nil {
+		return false, utils.NewAppError(utils.ErrDatabase, "Failed to check moderator status", err)
+	}
+	return !isModerator, nil
+}
+
+// automodRule returns the strictest of the subreddit's automod rules a new post matches,
+// or nil if it matches none. Moderators' posts aren't checked.
+func (a *PostActor) automodRule(subreddit *models.Subreddit, authorID uuid.UUID, text string, links ...string) *models.AutomodRule {
+	if subreddit.IsModerator(authorID) {
+		return nil
+	}
+	return a.automod.match(subreddit.Automod, text, links...)
+}
+
+// applyAutomod records the automod rule a new post matched and carries out its action:
+// removed posts are stored hidden, queued ones wait in the mod queue, and reported ones
+// are published flagged for review
+func applyAutomod(post *models.Post, rule *models.AutomodRule) {
+	if rule == nil {
+		return
+	}
+	post.Automod = rule
+	switch rule.Action {
+	case models.AutomodActionRemove:
+		post.Status = models.PostStatusRemoved
+		post.ScheduledAt = nil
+	case models.AutomodActionQueue:
+		post.Status = models.PostStatusPending
+		post.ScheduledAt = nil
+	case models.AutomodActionReport:
+		post.FlaggedForReview = true
+	}
+}
+
+// checkCanPostIn rejects an author who may not read the subreddit they are posting in,
+// as a non-member of a private subreddit
+func (a *PostActor) checkCanPostIn(ctx stdctx.Context, subreddit *models.Subreddit, authorID uuid.UUID) *utils.AppError {
+	canView, err := a.mongodb.CanViewSubreddit(ctx, subreddit, authorID)
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "Failed to check subreddit membership", err)
+	}
+	if !canView {
+		return utils.NewAppError(utils.ErrUnauthorized, fmt.Sprintf(
+			"r/%s is private; only its members can post in it", subreddit.Name), nil)
+	}
+	return nil
+}
+
+// checkPostRate rejects a new post in the subreddit if the author has hit the post rate
+// limits, which are raised for the subreddit's moderators
+func (a *PostActor) checkPostRate(ctx stdctx.Context, authorID, subredditID uuid.UUID) *utils.AppError {
+	now := time.Now()
+	wait := a.throttle.wait(authorID, now, 1)
+	if wait > 0 && a.modRateFactor > 1 {
+		isModerator, err := a.mongodb.IsSubredditModerator(ctx, subredditID, authorID)
+		if err != nil {
+			return utils.NewAppError(utils.ErrDatabase, "Failed to check moderator status", err)
+		}
+		if isModerator {
+			wait = a.throttle.wait(authorID, now, a.modRateFactor)
+		}
+	}
+	if wait <= 0 {
+		return nil
+	}
+	retryAfter := int(math.Ceil(wait.Seconds()))
+	return utils.NewLocalizedError(utils.ErrTooManyRequests, i18n.ErrPostRateLimited,
+		map[string]string{"retryAfter": strconv.Itoa(retryAfter)}, nil)
+}
+
+// checkPostText checks an already trimmed title and content against the configured
+// limits, which count characters rather than bytes. Nil fields aren't checked.
+func (a *PostActor) checkPostText(title, content *string) *utils.AppError {
+	if title != nil {
+		if *title == "" {
+			return utils.NewAppError(utils.ErrInvalidInput, "Post title cannot be empty", nil)
+		}
+		if utf8.RuneCountInString(*title) > a.maxTitleLen {
+			return utils.NewAppError(utils.ErrInvalidInput,
+				fmt.Sprintf("Post title must be at most %d characters", a.maxTitleLen), nil)
+		}
+	}
+	if content != nil && utf8.RuneCountInString(*content) > a.maxContentLen {
+		return utils.NewAppError(utils.ErrInvalidInput,
+			fmt.Sprintf("Post content must be at most %d characters", a.maxContentLen), nil)
+	}
+	return nil
+}
+
+// isLinkOnly reports whether text is nothing but a single web address
+func isLinkOnly(text string) bool {
+	text = strings.TrimSpace(text)
+	if strings.ContainsAny(text, " \t\n") {
+		return false
+	}
+	return isWebURL(text)
+}
+
+// mediaTypeOf validates a post's media attachments and returns their media type,
+// which every attachment must share
+func (a *PostActor) mediaTypeOf(mediaURLs []string) (string, *utils.AppError) {
+	if len(mediaURLs) > maxPostMedia {
+		return "", utils.NewAppError(utils.ErrInvalidInput,
+			fmt.Sprintf("A post can have at most %d media attachments", maxPostMedia), nil)
+	}
+
+	mediaType := ""
+	for _, raw := range mediaURLs {
+		if !isWebURL(raw) {
+			return "", utils.NewAppError(utils.ErrInvalidInput, "Media must be http or https URLs", nil)
+		}
+		parsed, _ := url.Parse(raw)
+		ext := strings.ToLower(strings.TrimPrefix(path.Ext(parsed.Path), "."))
+		itemType, ok := a.mediaTypes[ext]
+		if !ok {
+			return "", utils.NewAppError(utils.ErrInvalidInput,
+				fmt.Sprintf("Media file type %q is not allowed", ext), nil)
+		}
+		if mediaType != "" && itemType != mediaType {
+			return "", utils.NewAppError(utils.ErrInvalidInput, "Media attachments must all be the same type", nil)
+		}
+		mediaType = itemType
+	}
+	return mediaType, nil
+}
+
+// checkDuplicateLink rejects a link post whose link was already posted in the subreddit
+// within the duplicate window, unless a moderator forces it through. The error carries
+// the existing post's ID so clients can send the user to that discussion.
+func (a *PostActor) checkDuplicateLink(ctx stdctx.Context, msg *CreatePostMsg, normalizedURL string) *utils.AppError {
+	if msg.Force {
+		isModerator, err := a.mongodb.IsSubredditModerator(ctx, msg.SubredditID, msg.AuthorID)
+		if err != nil {
+			return utils.NewAppError(utils.ErrDatabase, "Failed to check moderator status", err)
+		}
+		if !isModerator {
+			return utils.NewAppError(utils.ErrForbidden, "Only moderators can force a duplicate link", nil)
+		}
+		return nil
+	}
+	if a.repostWindow <= 0 {
+		return nil
+	}
+
+	existing, err := a.mongodb.FindDuplicateLink(ctx, msg.SubredditID, normalizedURL, time.Now().Add(-a.repostWindow))
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "Failed to check for duplicate links", err)
+	}
+	if existing == nil {
+		return nil
+	}
+	return utils.NewLocalizedError(utils.ErrDuplicate, i18n.ErrDuplicateLink,
+		map[string]string{"postId": existing.ID.String()}, nil)
+}
+
+// normalizeLinkURL puts a link in a canonical form so reposts of it can be recognized:
+// the scheme and host are lowercased, utm_* tracking parameters, the fragment and
+// trailing slashes are dropped, and the remaining query parameters are sorted
+func normalizeLinkURL(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	parsed.Host = strings.ToLower(parsed.Host)
+	parsed.Fragment = ""
+	parsed.RawFragment = ""
+	parsed.Path = strings.TrimRight(parsed.Path, "/")
+	parsed.RawPath = ""
+
+	query := parsed.Query()
+	for key := range query {
+		if strings.HasPrefix(strings.ToLower(key), "utm_") {
+			query.Del(key)
+		}
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// isWebURL reports whether raw is an absolute http or https URL. Every other scheme,
+// including javascript: and data:, is rejected.
+func isWebURL(raw string) bool {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	scheme := strings.ToLower(parsed.Scheme)
+	return (scheme == "http" || scheme == "https") && parsed.Host != ""
+}
+
+// handleDeletePost soft-deletes a post at the request of its author or a moderator of
+// its subreddit, along with the comments on it. Authors can undo this within the undo window.
+func (a *PostActor) handleDeletePost(context actor.Context, msg *DeletePostMsg) {
+	ctx := stdctx.Background()
+	post, err := a.mongodb.GetPost(ctx, msg.PostID)
+	if err != nil {
+		if utils.IsErrorCode(err, utils.ErrNotFound) {
+			context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil))
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get post", err))
+		return
+	}
+
+	// Archived posts can still be removed, but only by moderators and administrators
+	archived := post.ArchivedAt(time.Now(), a.archiveAfter)
+	if !msg.AsAdmin && (post.AuthorID != msg.UserID || archived) {
+		isModerator, err := a.mongodb.IsSubredditModerator(ctx, post.SubredditID, msg.UserID)
+		if err != nil {
+			context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to check moderator status", err))
+			return
+		}
+		if !isModerator && post.AuthorID != msg.UserID {
+			context.Respond(utils.NewAppError(utils.ErrUnauthorized, "Not authorized to delete post", nil))
+			return
+		}
+		if !isModerator {
+			context.Respond(utils.NewLocalizedError(utils.ErrArchived, i18n.ErrPostArchived, nil, nil))
+			return
+		}
+	}
+	if post.IsDeleted {
+		context.Respond(utils.NewAppError(utils.ErrGone, "Post already deleted", nil))
+		return
+	}
+
+	// Comments are deleted with the same deleter and timestamp so undeleting the post
+	// can restore exactly these
+	deletedAt := time.Now().UTC().Truncate(time.Millisecond)
+	if err := a.mongodb.SoftDeletePost(ctx, post.ID, msg.UserID, deletedAt); err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			context.Respond(appErr)
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to delete post", err))
+		return
+	}
+	if err := a.mongodb.SoftDeletePostComments(ctx, post.ID, msg.UserID, deletedAt); err != nil {
+		log.Printf("Error deleting comments of post %s: %v", post.ID, err)
+	}
+	context.Send(a.enginePID, &ReloadPostCommentsMsg{PostID: post.ID})
+
+	if err := a.mongodb.UpdateSubredditPosts(ctx, post.SubredditID, post.ID, false); err != nil {
+		log.Printf("Error removing post %s from subreddit %s: %v", post.ID, post.SubredditID, err)
+	}
+
+	delete(a.postsByID, post.ID)
+	delete(a.postVotes, post.ID)
+	remaining := a.subredditPosts[post.SubredditID][:0]
+	for _, id := range a.subredditPosts[post.SubredditID] {
+		if id != post.ID {
+			remaining = append(remaining, id)
+		}
+	}
+	a.subredditPosts[post.SubredditID] = remaining
+
+	log.Printf("Deleted post %s", post.ID)
+	context.Respond(true)
+}
+
+// handleEvictSubredditPosts forgets every cached post of a deleted subreddit
+func (a *PostActor) handleEvictSubredditPosts(msg *EvictSubredditPostsMsg) {
+	for id, post := range a.postsByID {
+		if post.SubredditID == msg.SubredditID {
+			delete(a.postsByID, id)
+			delete(a.postVotes, id)
+		}
+	}
+	delete(a.subredditPosts, msg.SubredditID)
+	log.Printf("Evicted cached posts of deleted subreddit %s", msg.SubredditID)
+}
+
+// handleUndeletePost restores a post its author deleted, as long as the undo window
+// hasn't passed. Posts removed by moderators can't be restored this way.
+func (a *PostActor) handleUndeletePost(context actor.Context, msg *UndeletePostMsg) {
+	ctx := stdctx.Background()
+	post, err := a.mongodb.GetPost(ctx, msg.PostID)
+	if err != nil {
+		if utils.IsErrorCode(err, utils.ErrNotFound) {
+			context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil))
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get post", err))
+		return
+	}
+
+	if post.AuthorID != msg.RequesterID {
+		context.Respond(utils.NewAppError(utils.ErrUnauthorized, "Not authorized to restore post", nil))
+		return
+	}
+	if !post.IsDeleted || post.DeletedAt == nil || post.DeletedBy == nil {
+		context.Respond(utils.NewAppError(utils.ErrInvalidInput, "Post is not deleted", nil))
+		return
+	}
+	if *post.DeletedBy != post.AuthorID {
+		context.Respond(utils.NewAppError(utils.ErrForbidden, "Posts removed by a moderator cannot be restored", nil))
+		return
+	}
+	if time.Since(*post.DeletedAt) > a.undeleteWindow {
+		context.Respond(utils.NewAppError(utils.ErrGone, "The undo window for this deletion has passed", nil))
+		return
+	}
+	if err := a.mongodb.VerifyAndGetSubreddit(ctx, post.SubredditID); err != nil {
+		if utils.IsErrorCode(err, utils.ErrNotFound) {
+			context.Respond(utils.NewAppError(utils.ErrGone, "The post's subreddit has been deleted", nil))
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to check subreddit", err))
+		return
+	}
+
+	restored, err := a.mongodb.RestorePost(ctx, post.ID)
+	if err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			context.Respond(appErr)
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to restore post", err))
+		return
+	}
+
+	if _, err := a.mongodb.RestorePostComments(ctx, post.ID, *post.DeletedBy, *post.DeletedAt); err != nil {
+		log.Printf("Error restoring comments of post %s: %v", post.ID, err)
+	}
+	context.Send(a.enginePID, &ReloadPostCommentsMsg{PostID: post.ID})
+
+	if err := a.mongodb.UpdateSubredditPosts(ctx, restored.SubredditID, restored.ID, true); err != nil {
+		log.Printf("Error re-adding post %s to subreddit %s: %v", restored.ID, restored.SubredditID, err)
+	}
+
+	// The deleted post may have been cached, and listed, since as a placeholder
+	_, cached := a.postsByID[restored.ID]
+	a.addPendingCounts(restored)
+	a.postsByID[restored.ID] = restored
+	if _, exists := a.postVotes[restored.ID]; !exists {
+		a.postVotes[restored.ID] = make(map[uuid.UUID]voteStatus)
+	}
+	if !cached {
+		a.subredditPosts[restored.SubredditID] = append(a.subredditPosts[restored.SubredditID], restored.ID)
+	}
+
+	log.Printf("Restored post %s", restored.ID)
+	context.Respond(restored)
+}
+
+// Handles counting a view. Cached posts include unflushed views, so their count is bumped
+// straight away.
+func (a *PostActor) handleRecordPostView(msg *RecordPostViewMsg) {
+	a.pendingViews[msg.PostID]++
+	a.pendingTotal++
+	if post, exists := a.postsByID[msg.PostID]; exists {
+		post.ViewCount++
+	}
+
+	if a.pendingTotal >= viewFlushThreshold {
+		a.flushViews()
+	}
+}
+
+// flushViews writes the views counted since the last flush. On failure they are kept
+// for the next attempt.
+func (a *PostActor) flushViews() {
+	if a.pendingTotal == 0 {
+		return
+	}
+
+	ctx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+	defer cancel()
+	if err := a.mongodb.IncrementPostViews(ctx, a.pendingViews); err != nil {
+		log.Printf("PostActor: %v", err)
+		return
+	}
+
+	a.pendingViews = make(map[uuid.UUID]int)
+	a.pendingTotal = 0
+}
+
+func (a *PostActor) handleRecordPostShare(msg *RecordPostShareMsg) {
+	a.pendingShares[msg.PostID]++
+	if post, exists := a.postsByID[msg.PostID]; exists {
+		post.ShareCount++
+	}
+}
+
+// flushShares writes the shares counted since the last flush. On failure they are kept
+// for the next attempt.
+func (a *PostActor) flushShares() {
+	if len(a.pendingShares) == 0 {
+		return
+	}
+
+	ctx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+	defer cancel()
+	if err := a.mongodb.IncrementPostShares(ctx, a.pendingShares); err != nil {
+		log.Printf("PostActor: %v", err)
+		return
+	}
+	a.pendingShares = make(map[uuid.UUID]int)
+}
+
+// addPendingCounts adds unflushed views and shares to posts freshly loaded from the
+// database. Cached posts already include them.
+func (a *PostActor) addPendingCounts(posts ...*models.Post) {
+	for _, post := range posts {
+		post.ViewCount += a.pendingViews[post.ID]
+		post.ShareCount += a.pendingShares[post.ID]
+	}
+}
+
+// handleVotePoll records a user's choice in an open poll and responds with the poll as
+// the voter now sees it
+func (a *PostActor) handleVotePoll(context actor.Context, msg *VotePollMsg) {
+	ctx := stdctx.Background()
+	post, exists := a.postsByID[msg.PostID]
+	if !exists {
+		var err error
+		if post, err = a.mongodb.GetPost(ctx, msg.PostID); err != nil {
+			if utils.IsErrorCode(err, utils.ErrNotFound) {
+				context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil))
+				return
+			}
+			context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get post", err))
+			return
+		}
+	}
+	if post.IsDeleted {
+		context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil))
+		return
+	}
+	if post.Poll == nil {
+		context.Respond(utils.NewAppError(utils.ErrInvalidInput, "Post is not a poll", nil))
+		return
+	}
+
+	now := time.Now()
+	if post.ArchivedAt(now, a.archiveAfter) {
+		context.Respond(utils.NewLocalizedError(utils.ErrArchived, i18n.ErrPostArchived, nil, nil))
+		return
+	}
+	if !now.Before(post.Poll.ClosesAt) {
+		context.Respond(utils.NewAppError(utils.ErrForbidden, "This poll has closed", nil))
+		return
+	}
+	if msg.Option < 0 || msg.Option >= len(post.Poll.Options) {
+		context.Respond(utils.NewAppError(utils.ErrInvalidInput, "No such poll option", nil))
+		return
+	}
+
+	if err := a.mongodb.RecordPollVote(ctx, post.ID, msg.UserID, msg.Option, now); err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			context.Respond(appErr)
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to record poll vote", err))
+		return
+	}
+	post.Poll.Tally[msg.Option]++
+
+	context.Respond(a.withPollResults(msg.UserID, post)[0].Poll)
+}
+
+// forViewer returns posts as viewerID should see them, with their vote and the results
+// of any polls filled in
+func (a *PostActor) forViewer(viewerID uuid.UUID, posts ...*models.Post) []*models.Post {
+	return a.withPollResults(viewerID, a.withUserVotes(viewerID, posts...)...)
+}
+
+// withUserVotes returns copies of posts with viewerID's vote on each set as UserVote,
+// looked up with a single query. Posts are returned as they are for anonymous viewers,
+// who get no UserVote.
+func (a *PostActor) withUserVotes(viewerID uuid.UUID, posts ...*models.Post) []*models.Post {
+	if viewerID == uuid.Nil || len(posts) == 0 {
+		return posts
+	}
+
+	postIDs := make([]string, len(posts))
+	for i, post := range posts {
+		postIDs[i] = post.ID.String()
+	}
+	directions, err := a.mongodb.GetPostVoteDirections(stdctx.Background(), viewerID, postIDs)
+	if err != nil {
+		log.Printf("Error getting post votes: %v", err)
+		return posts
+	}
+
+	served := make([]*models.Post, len(posts))
+	for i, post := range posts {
+		vote := models.VoteNone
+		if isUpvote, voted := directions[post.ID.String()]; voted {
+			vote = models.VoteDown
+			if isUpvote {
+				vote = models.VoteUp
+			}
+		}
+		copied := *post
+		copied.UserVote = &vote
+		served[i] = &copied
+	}
+	return served
+}
+
+// withPollResults returns posts with the results of any polls among them filled in as
+// viewerID may see them. Poll posts are replaced by copies, since the cached posts are
+// shared between viewers; other posts are returned as they are.
+func (a *PostActor) withPollResults(viewerID uuid.UUID, posts ...*models.Post) []*models.Post {
+	var pollIDs []string
+	for _, post := range posts {
+		if post.Poll != nil {
+			pollIDs = append(pollIDs, post.ID.String())
+		}
+	}
+	if len(pollIDs) == 0 {
+		return posts
+	}
+
+	choices := map[string]int{}
+	if viewerID != uuid.Nil {
+		var err error
+		if choices, err = a.mongodb.GetPollChoices(stdctx.Background(), viewerID, pollIDs); err != nil {
+			log.Printf("Error getting poll choices: %v", err)
+			choices = map[string]int{}
+		}
+	}
+
+	now := time.Now()
+	served := make([]*models.Post, len(posts))
+	for i, post := range posts {
+		served[i] = post
+		if post.Poll == nil {
+			continue
+		}
+
+		poll := *post.Poll
+		poll.IsClosed = !now.Before(poll.ClosesAt)
+		poll.TotalVotes = 0
+		for _, count := range poll.Tally {
+			poll.TotalVotes += count
+		}
+		poll.Counts = nil
+		poll.ViewerChoice = nil
+		if choice, voted := choices[post.ID.String()]; voted {
+			poll.ViewerChoice = &choice
+		}
+		if poll.IsClosed || (poll.ViewerChoice != nil && !poll.ResultsAfterClose) {
+			poll.Counts = append([]int(nil), poll.Tally...)
+		}
+
+		copied := *post
+		copied.Poll = &poll
+		served[i] = &copied
+	}
+	return served
+}
+
+// validatePoll checks the options and duration of a new poll
+func validatePoll(spec *PollSpec) *utils.AppError {
+	if len(spec.Options) < minPollOptions || len(spec.Options) > maxPollOptions {
+		return utils.NewAppError(utils.ErrInvalidInput,
+			fmt.Sprintf("A poll needs between %d and %d options", minPollOptions, maxPollOptions), nil)
+	}
+	for _, option := range spec.Options {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			return utils.NewAppError(utils.ErrInvalidInput, "Poll options cannot be empty", nil)
+		}
+		if len(option) > maxPollOptionLength {
+			return utils.NewAppError(utils.ErrInvalidInput,
+				fmt.Sprintf("Poll options can be at most %d characters", maxPollOptionLength), nil)
+		}
+	}
+	if spec.Duration < time.Hour || spec.Duration > maxPollDuration {
+		return utils.NewAppError(utils.ErrInvalidInput, "A poll must stay open for between 1 hour and 7 days", nil)
+	}
+	return nil
+}
+
+// attachServedFields fills in the archived state, comment counts, crosspost origins and
+// author flairs of posts about to be served. Failures are logged rather than failing the
+// request.
+func (a *PostActor) attachServedFields(posts ...*models.Post) {
+	now := time.Now()
+	for _, post := range posts {
+		post.IsArchived = post.ArchivedAt(now, a.archiveAfter)
+	}
+
+	ctx := stdctx.Background()
+	if err := a.mongodb.AttachCommentCounts(ctx, posts); err != nil {
+		log.Printf("Error attaching comment counts: %v", err)
+	}
+	if err := a.mongodb.AttachCrosspostOrigins(ctx, posts); err != nil {
+		log.Printf("Error attaching crosspost origins: %v", err)
+	}
+	if err := a.mongodb.AttachPostAuthorFlairs(ctx, posts); err != nil {
+		log.Printf("Error attaching author flairs: %v", err)
+	}
 }
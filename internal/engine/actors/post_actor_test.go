@@ -0,0 +1,145 @@
+package actors
+
+import (
+	stdctx "context"
+	"gator-swamp/internal/config"
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/database/dbtest"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+	"testing"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/google/uuid"
+)
+
+// postHarness runs a PostActor against a test database. A user supervisor stands in for
+// the engine, so karma updates are written to MongoDB as they are in production.
+type postHarness struct {
+	t        *testing.T
+	mongodb  *database.MongoDB
+	system   *actor.ActorSystem
+	posts    *actor.PID
+	users    *actor.PID
+	actor    *PostActor
+	authorID uuid.UUID
+}
+
+// skipStarted keeps a PostActor from loading posts and starting its timers when spawned,
+// so tests decide what is cached
+func skipStarted(next actor.ReceiverFunc) actor.ReceiverFunc {
+	return func(c actor.ReceiverContext, envelope *actor.MessageEnvelope) {
+		if _, ok := envelope.Message.(*actor.Started); ok {
+			return
+		}
+		next(c, envelope)
+	}
+}
+
+func newPostHarness(t *testing.T) *postHarness {
+	t.Helper()
+	mongodb := dbtest.New(t)
+	h := &postHarness{t: t, mongodb: mongodb, system: actor.NewActorSystem()}
+	h.authorID = h.addUser("author")
+
+	h.users = h.system.Root.Spawn(actor.PropsFromProducer(func() actor.Actor {
+		return NewUserSupervisor(mongodb, config.DuplicateAccountReject, 4)
+	}))
+	h.actor = NewPostActor(utils.NewMetricsCollector(), h.users, mongodb, time.Hour, 0, 0,
+		300, 40000, nil, nil, 1, time.Hour, 10).(*PostActor)
+	h.posts = h.system.Root.Spawn(actor.PropsFromProducer(func() actor.Actor { return h.actor },
+		actor.WithReceiverMiddleware(skipStarted)))
+
+	t.Cleanup(func() {
+		h.system.Root.StopFuture(h.posts).Wait()
+		h.system.Root.StopFuture(h.users).Wait()
+		h.system.Shutdown()
+	})
+	return h
+}
+
+func (h *postHarness) addUser(username string) uuid.UUID {
+	h.t.Helper()
+	user := &models.User{
+		ID:        uuid.New(),
+		Username:  username,
+		Email:     username + "@example.com",
+		CreatedAt: time.Now(),
+	}
+	if err := h.mongodb.SaveUser(stdctx.Background(), user); err != nil {
+		h.t.Fatalf("SaveUser: %v", err)
+	}
+	return user.ID
+}
+
+// addSubreddit stores a subreddit of the given type created by the author
+func (h *postHarness) addSubreddit(kind models.SubredditType) *models.Subreddit {
+	h.t.Helper()
+	id := uuid.New()
+	subreddit := &models.Subreddit{
+		ID:        id,
+		Name:      "swamp_" + id.String()[:8],
+		CreatorID: h.authorID,
+		CreatedAt: time.Now(),
+		Type:      kind,
+	}
+	if err := h.mongodb.CreateSubreddit(stdctx.Background(), subreddit); err != nil {
+		h.t.Fatalf("CreateSubreddit: %v", err)
+	}
+	return subreddit
+}
+
+// addPost stores a post by the author in a new public subreddit, without any votes, and
+// caches it in the actor
+func (h *postHarness) addPost() *models.Post {
+	h.t.Helper()
+	post := &models.Post{
+		ID:          uuid.New(),
+		Title:       "A post",
+		Slug:        "a-post",
+		AuthorID:    h.authorID,
+		SubredditID: h.addSubreddit(models.SubredditPublic).ID,
+		CreatedAt:   time.Now(),
+		Status:      models.PostStatusPublished,
+	}
+	if err := h.mongodb.SavePost(stdctx.Background(), post); err != nil {
+		h.t.Fatalf("SavePost: %v", err)
+	}
+	if _, appErr := h.request(&GetPostMsg{PostID: post.ID}); appErr != nil {
+		h.t.Fatalf("GetPostMsg: %v", appErr)
+	}
+	return post
+}
+
+// createPost creates a post by the author the way users do, in a new public subreddit
+func (h *postHarness) createPost() *models.Post {
+	h.t.Helper()
+	subreddit := h.addSubreddit(models.SubredditPublic)
+	post, appErr := h.request(&CreatePostMsg{
+		Title:       "A post",
+		Content:     "Its content",
+		AuthorID:    h.authorID,
+		SubredditID: subreddit.ID,
+	})
+	if appErr != nil {
+		h.t.Fatalf("CreatePostMsg: %v", appErr)
+	}
+	return post
+}
+
+func (h *postHarness) request(msg interface{}) (*models.Post, *utils.AppError) {
+	h.t.Helper()
+	result, err := h.system.Root.RequestFuture(h.posts, msg, 5*time.Second).Result()
+	if err != nil {
+		h.t.Fatalf("%T: %v", msg, err)
+	}
+	switch result := result.(type) {
+	case *models.Post:
+		return result, nil
+	case *utils.AppError:
+		return nil, result
+	}
+	h.t.Fatalf("%T answered with %T", msg, result)
+	return nil, nil
+}
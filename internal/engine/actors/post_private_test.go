@@ -0,0 +1,151 @@
+package actors
+
+import (
+	stdctx "context"
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/types"
+	"gator-swamp/internal/utils"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// addPrivatePost stores a post by the author in a new private subreddit without caching it
+func (h *postHarness) addPrivatePost() (*models.Subreddit, *models.Post) {
+	h.t.Helper()
+	subreddit := h.addSubreddit(models.SubredditPrivate)
+	post := &models.Post{
+		ID:          uuid.New(),
+		Title:       "A secret",
+		Slug:        "a-secret",
+		AuthorID:    h.authorID,
+		SubredditID: subreddit.ID,
+		CreatedAt:   time.Now(),
+		Status:      models.PostStatusPublished,
+	}
+	if err := h.mongodb.SavePost(stdctx.Background(), post); err != nil {
+		h.t.Fatalf("SavePost: %v", err)
+	}
+	return subreddit, post
+}
+
+func (h *postHarness) getPostsByIDs(viewerID uuid.UUID, postIDs ...uuid.UUID) *PostBatch {
+	h.t.Helper()
+	msg := &GetPostsByIDsMsg{PostIDs: postIDs, ViewerID: viewerID}
+	result, err := h.system.Root.RequestFuture(h.posts, msg, 5*time.Second).Result()
+	if err != nil {
+		h.t.Fatalf("GetPostsByIDsMsg: %v", err)
+	}
+	batch, ok := result.(*PostBatch)
+	if !ok {
+		h.t.Fatalf("GetPostsByIDsMsg answered with %v", result)
+	}
+	return batch
+}
+
+func TestPrivateSubredditPostIsServedOnlyToMembers(t *testing.T) {
+	h := newPostHarness(t)
+	subreddit, post := h.addPrivatePost()
+	outsiderID := h.addUser("outsider")
+	memberID := h.addUser("member")
+	if _, err := h.mongodb.UpdateUserSubreddits(stdctx.Background(), memberID, subreddit.ID, true); err != nil {
+		t.Fatalf("UpdateUserSubreddits: %v", err)
+	}
+
+	assertHidden := func(viewerID uuid.UUID, when string) {
+		t.Helper()
+		if _, appErr := h.request(&GetPostMsg{PostID: post.ID, ViewerID: viewerID}); appErr == nil || appErr.Code != utils.ErrUnauthorized {
+			t.Fatalf("GetPostMsg %s = %v, want ErrUnauthorized", when, appErr)
+		}
+		_, appErr := h.request(&GetPostBySlugMsg{SubredditName: subreddit.Name, Slug: post.Slug, ViewerID: viewerID})
+		if appErr == nil || appErr.Code != utils.ErrUnauthorized {
+			t.Fatalf("GetPostBySlugMsg %s = %v, want ErrUnauthorized", when, appErr)
+		}
+	}
+
+	// From the database, then from the cache once a member has read it
+	assertHidden(outsiderID, "from an outsider")
+	assertHidden(uuid.Nil, "anonymously")
+	if _, appErr := h.request(&GetPostMsg{PostID: post.ID, ViewerID: memberID}); appErr != nil {
+		t.Fatalf("GetPostMsg from a member: %v", appErr)
+	}
+	if _, cached := h.actor.postsByID[post.ID]; !cached {
+		t.Fatalf("post wasn't cached when the member read it")
+	}
+	assertHidden(outsiderID, "from an outsider once cached")
+	if _, appErr := h.request(&GetPostMsg{PostID: post.ID, ViewerID: h.authorID}); appErr != nil {
+		t.Fatalf("GetPostMsg from the subreddit's creator: %v", appErr)
+	}
+}
+
+func TestPrivateSubredditPostsAreMissingFromBatches(t *testing.T) {
+	h := newPostHarness(t)
+	public := h.addPost()
+	subreddit, cached := h.addPrivatePost()
+	memberID := h.addUser("member")
+	if _, err := h.mongodb.UpdateUserSubreddits(stdctx.Background(), memberID, subreddit.ID, true); err != nil {
+		t.Fatalf("UpdateUserSubreddits: %v", err)
+	}
+	if _, appErr := h.request(&GetPostMsg{PostID: cached.ID, ViewerID: memberID}); appErr != nil {
+		t.Fatalf("GetPostMsg from a member: %v", appErr)
+	}
+	_, uncached := h.addPrivatePost()
+
+	batch := h.getPostsByIDs(h.addUser("outsider"), public.ID, cached.ID, uncached.ID)
+	if len(batch.Posts) != 1 || batch.Posts[0].ID != public.ID {
+		t.Fatalf("outsider's batch has %d posts, want just the public one", len(batch.Posts))
+	}
+	if len(batch.Missing) != 2 || batch.Missing[0] != cached.ID.String() || batch.Missing[1] != uncached.ID.String() {
+		t.Fatalf("outsider's batch is missing %v, want both private posts", batch.Missing)
+	}
+
+	batch = h.getPostsByIDs(memberID, public.ID, cached.ID)
+	if len(batch.Posts) != 2 || len(batch.Missing) != 0 {
+		t.Fatalf("member's batch has %d posts and is missing %v, want both", len(batch.Posts), batch.Missing)
+	}
+}
+
+func TestUserActivityLeavesOutPrivateSubredditsFromNonMembers(t *testing.T) {
+	h := newPostHarness(t)
+	public := h.addPost()
+	_, secret := h.addPrivatePost()
+	comment := &models.Comment{
+		ID:          uuid.New(),
+		Content:     "Keep it quiet",
+		AuthorID:    h.authorID,
+		PostID:      secret.ID,
+		SubredditID: secret.SubredditID,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	comment.Path = database.CommentPath("", comment.ID)
+	if err := h.mongodb.SaveComment(stdctx.Background(), comment); err != nil {
+		t.Fatalf("SaveComment: %v", err)
+	}
+
+	activity := func(viewerID uuid.UUID) []*database.ActivityItem {
+		t.Helper()
+		msg := &GetUserActivityMsg{UserID: h.authorID, ViewerID: viewerID}
+		result, err := h.system.Root.RequestFuture(h.posts, msg, 5*time.Second).Result()
+		if err != nil {
+			t.Fatalf("GetUserActivityMsg: %v", err)
+		}
+		page, ok := result.(*types.PaginatedResponse)
+		if !ok {
+			t.Fatalf("GetUserActivityMsg answered with %v", result)
+		}
+		return page.Items.([]*database.ActivityItem)
+	}
+
+	for name, viewerID := range map[string]uuid.UUID{"outsider": h.addUser("outsider"), "anonymous viewer": uuid.Nil} {
+		items := activity(viewerID)
+		if len(items) != 1 || items[0].Post == nil || items[0].Post.ID != public.ID {
+			t.Fatalf("%s sees %d activity items, want just the public post", name, len(items))
+		}
+	}
+	if items := activity(h.authorID); len(items) != 3 {
+		t.Fatalf("author sees %d activity items, want all 3", len(items))
+	}
+}
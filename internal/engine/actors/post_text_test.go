@@ -0,0 +1,51 @@
+package actors
+
+import (
+	"gator-swamp/internal/utils"
+	"strings"
+	"testing"
+)
+
+func TestCheckPostTextCountsRunes(t *testing.T) {
+	a := &PostActor{maxTitleLen: 10, maxContentLen: 20}
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name    string
+		title   *string
+		content *string
+		wantErr bool
+	}{
+		{"ascii title at limit", str(strings.Repeat("a", 10)), nil, false},
+		{"ascii title over limit", str(strings.Repeat("a", 11)), nil, true},
+
+		// "é" is 2 bytes, "日" 3 and "🐊" 4, so these are well over the limit in bytes
+		{"2-byte title at limit", str(strings.Repeat("é", 10)), nil, false},
+		{"2-byte title over limit", str(strings.Repeat("é", 11)), nil, true},
+		{"3-byte title at limit", str(strings.Repeat("日", 10)), nil, false},
+		{"3-byte title over limit", str(strings.Repeat("日", 11)), nil, true},
+		{"4-byte title at limit", str(strings.Repeat("🐊", 10)), nil, false},
+		{"4-byte title over limit", str(strings.Repeat("🐊", 11)), nil, true},
+		{"mixed title at limit", str("Gator 🐊 日é"), nil, false},
+
+		{"multi-byte content at limit", str("t"), str(strings.Repeat("🐊", 20)), false},
+		{"multi-byte content over limit", str("t"), str(strings.Repeat("🐊", 21)), true},
+		{"mixed content at limit", str("t"), str(strings.Repeat("aé日🐊", 5)), false},
+		{"mixed content over limit", str("t"), str(strings.Repeat("aé日🐊", 5) + "a"), true},
+
+		{"empty title", str(""), nil, true},
+		{"nil fields aren't checked", nil, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appErr := a.checkPostText(tt.title, tt.content)
+			if (appErr != nil) != tt.wantErr {
+				t.Fatalf("checkPostText error = %v, want error %v", appErr, tt.wantErr)
+			}
+			if appErr != nil && appErr.Code != utils.ErrInvalidInput {
+				t.Errorf("error code = %s, want %s", appErr.Code, utils.ErrInvalidInput)
+			}
+		})
+	}
+}
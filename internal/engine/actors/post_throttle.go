@@ -0,0 +1,78 @@
+package actors
+
+import (
+	"gator-swamp/internal/config"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// postThrottle holds authors to the post rate limits. It remembers when each author
+// created their recent posts, forgetting anything older than the longest window.
+type postThrottle struct {
+	limits  []config.RateLimit
+	longest time.Duration
+	recent  map[uuid.UUID][]time.Time // Creation times by author, oldest first
+}
+
+func newPostThrottle(limits []config.RateLimit) *postThrottle {
+	t := &postThrottle{limits: limits, recent: make(map[uuid.UUID][]time.Time)}
+	for _, limit := range limits {
+		t.longest = max(t.longest, limit.Window)
+	}
+	return t
+}
+
+// wait returns how long the author must wait before creating another post, with the
+// counts of the limits multiplied by factor, or zero if they can post now
+func (t *postThrottle) wait(authorID uuid.UUID, now time.Time, factor int) time.Duration {
+	times := t.prune(authorID, now)
+	var wait time.Duration
+	for _, limit := range t.limits {
+		count := limit.Count * factor
+		if len(times) < count {
+			continue
+		}
+		// The post that has to leave the window before another fits inside it
+		blocking := times[len(times)-count]
+		if until := blocking.Add(limit.Window).Sub(now); until > wait {
+			wait = until
+		}
+	}
+	return wait
+}
+
+// record notes that the author created a post at now
+func (t *postThrottle) record(authorID uuid.UUID, now time.Time) {
+	if len(t.limits) == 0 {
+		return
+	}
+	t.recent[authorID] = append(t.prune(authorID, now), now)
+}
+
+// prune drops the author's creation times that have left the longest window and
+// returns the rest
+func (t *postThrottle) prune(authorID uuid.UUID, now time.Time) []time.Time {
+	times := t.recent[authorID]
+	cutoff := now.Add(-t.longest)
+	i := 0
+	for i < len(times) && !times[i].After(cutoff) {
+		i++
+	}
+	if i == len(times) {
+		delete(t.recent, authorID)
+		return nil
+	}
+	if i > 0 {
+		times = append(times[:0:0], times[i:]...)
+		t.recent[authorID] = times
+	}
+	return times
+}
+
+// pruneAll forgets authors who haven't posted within the longest window
+func (t *postThrottle) pruneAll(now time.Time) {
+	for authorID := range t.recent {
+		t.prune(authorID, now)
+	}
+}
@@ -0,0 +1,56 @@
+package actors
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// voteVelocity remembers when each post received its recent upvotes, to rank the posts
+// gaining votes fastest. Upvotes older than the window are forgotten.
+type voteVelocity struct {
+	window  time.Duration
+	upvotes map[uuid.UUID][]time.Time // Upvote times by post, oldest first
+}
+
+func newVoteVelocity(window time.Duration) *voteVelocity {
+	return &voteVelocity{window: window, upvotes: make(map[uuid.UUID][]time.Time)}
+}
+
+// record notes that the post was upvoted at now
+func (v *voteVelocity) record(postID uuid.UUID, now time.Time) {
+	v.upvotes[postID] = append(v.upvotes[postID], now)
+}
+
+// scores returns the upvotes per minute of every post upvoted within the window, with
+// each upvote counting less the older it is, from fully at now to nothing at the
+// window's start
+func (v *voteVelocity) scores(now time.Time) map[uuid.UUID]float64 {
+	v.prune(now)
+	scores := make(map[uuid.UUID]float64, len(v.upvotes))
+	for postID, times := range v.upvotes {
+		var weighted float64
+		for _, at := range times {
+			weighted += 1 - float64(now.Sub(at))/float64(v.window)
+		}
+		scores[postID] = weighted / v.window.Minutes()
+	}
+	return scores
+}
+
+// prune forgets upvotes older than the window, and posts left without any
+func (v *voteVelocity) prune(now time.Time) {
+	cutoff := now.Add(-v.window)
+	for postID, times := range v.upvotes {
+		i := 0
+		for i < len(times) && !times[i].After(cutoff) {
+			i++
+		}
+		switch {
+		case i == len(times):
+			delete(v.upvotes, postID)
+		case i > 0:
+			v.upvotes[postID] = append(times[:0:0], times[i:]...)
+		}
+	}
+}
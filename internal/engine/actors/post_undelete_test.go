@@ -0,0 +1,128 @@
+package actors
+
+import (
+	stdctx "context"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func (h *postHarness) deletePost(postID, userID uuid.UUID) *utils.AppError {
+	h.t.Helper()
+	result, err := h.system.Root.RequestFuture(h.posts, &DeletePostMsg{PostID: postID, UserID: userID}, 5*time.Second).Result()
+	if err != nil {
+		h.t.Fatalf("DeletePostMsg: %v", err)
+	}
+	if appErr, ok := result.(*utils.AppError); ok {
+		return appErr
+	}
+	return nil
+}
+
+func (h *postHarness) undeletePost(postID uuid.UUID) (*models.Post, *utils.AppError) {
+	h.t.Helper()
+	return h.request(&UndeletePostMsg{PostID: postID, RequesterID: h.authorID})
+}
+
+func TestUndeleteRestoresPostWithinWindow(t *testing.T) {
+	h := newPostHarness(t)
+	post := h.createPost()
+
+	if appErr := h.deletePost(post.ID, h.authorID); appErr != nil {
+		t.Fatalf("DeletePostMsg: %v", appErr)
+	}
+	// Deleted posts are still served, as placeholders, and cached as such
+	deleted, appErr := h.request(&GetPostMsg{PostID: post.ID})
+	if appErr != nil {
+		t.Fatalf("GetPostMsg after delete: %v", appErr)
+	}
+	if !deleted.IsDeleted || deleted.Content == post.Content {
+		t.Fatalf("deleted post served with content %q", deleted.Content)
+	}
+
+	restored, appErr := h.undeletePost(post.ID)
+	if appErr != nil {
+		t.Fatalf("UndeletePostMsg: %v", appErr)
+	}
+	if restored.IsDeleted || restored.Content != post.Content {
+		t.Fatalf("restored post: deleted %v, content %q; want restored with %q",
+			restored.IsDeleted, restored.Content, post.Content)
+	}
+
+	stored, err := h.mongodb.GetPost(stdctx.Background(), post.ID)
+	if err != nil {
+		t.Fatalf("GetPost: %v", err)
+	}
+	if stored.IsDeleted || stored.Content != post.Content || stored.DeletedAt != nil || stored.DeletedBy != nil {
+		t.Fatalf("stored post still carries its deletion: %+v", stored)
+	}
+	cached, appErr := h.request(&GetPostMsg{PostID: post.ID})
+	if appErr != nil {
+		t.Fatalf("GetPostMsg after undelete: %v", appErr)
+	}
+	if cached.IsDeleted || cached.Content != post.Content {
+		t.Fatalf("cached post: deleted %v, content %q; want restored", cached.IsDeleted, cached.Content)
+	}
+	if listed := h.actor.subredditPosts[post.SubredditID]; len(listed) != 1 || listed[0] != post.ID {
+		t.Fatalf("subreddit lists %v, want just the restored post", listed)
+	}
+}
+
+func TestUndeleteAfterWindowIsGone(t *testing.T) {
+	h := newPostHarness(t)
+	post := h.createPost()
+
+	if appErr := h.deletePost(post.ID, h.authorID); appErr != nil {
+		t.Fatalf("DeletePostMsg: %v", appErr)
+	}
+	// The harness allows undoing for an hour
+	_, err := h.mongodb.Posts.UpdateOne(stdctx.Background(), bson.M{"_id": post.ID.String()},
+		bson.M{"$set": bson.M{"deletedat": time.Now().Add(-2 * time.Hour)}})
+	if err != nil {
+		t.Fatalf("moving deletion back: %v", err)
+	}
+
+	if _, appErr := h.undeletePost(post.ID); appErr == nil || appErr.Code != utils.ErrGone {
+		t.Fatalf("UndeletePostMsg = %v, want ErrGone", appErr)
+	}
+	stored, err := h.mongodb.GetPost(stdctx.Background(), post.ID)
+	if err != nil {
+		t.Fatalf("GetPost: %v", err)
+	}
+	if !stored.IsDeleted || stored.Content == post.Content {
+		t.Fatalf("post was restored after the undo window")
+	}
+}
+
+func TestUndeletePostRemovedByModeratorIsForbidden(t *testing.T) {
+	h := newPostHarness(t)
+	post := h.createPost()
+
+	moderatorID := h.addUser("moderator")
+	added, err := h.mongodb.AddSubredditModerator(stdctx.Background(), post.SubredditID, models.Moderator{
+		UserID:  moderatorID,
+		AddedAt: time.Now(),
+		AddedBy: h.authorID,
+	})
+	if err != nil || !added {
+		t.Fatalf("AddSubredditModerator = %v, %v", added, err)
+	}
+	if appErr := h.deletePost(post.ID, moderatorID); appErr != nil {
+		t.Fatalf("DeletePostMsg by moderator: %v", appErr)
+	}
+
+	if _, appErr := h.undeletePost(post.ID); appErr == nil || appErr.Code != utils.ErrForbidden {
+		t.Fatalf("UndeletePostMsg = %v, want ErrForbidden", appErr)
+	}
+	stored, err := h.mongodb.GetPost(stdctx.Background(), post.ID)
+	if err != nil {
+		t.Fatalf("GetPost: %v", err)
+	}
+	if !stored.IsDeleted {
+		t.Fatalf("author restored a post a moderator removed")
+	}
+}
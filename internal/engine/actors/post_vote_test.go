@@ -0,0 +1,253 @@
+package actors
+
+import (
+	stdctx "context"
+	"fmt"
+	"gator-swamp/internal/database/dbtest"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (h *postHarness) vote(postID, userID uuid.UUID, isUpvote bool) *utils.AppError {
+	h.t.Helper()
+	_, appErr := h.request(&VotePostMsg{PostID: postID, UserID: userID, IsUpvote: isUpvote})
+	return appErr
+}
+
+func (h *postHarness) unvote(postID, userID uuid.UUID) *utils.AppError {
+	h.t.Helper()
+	_, appErr := h.request(&UnvotePostMsg{PostID: postID, UserID: userID})
+	return appErr
+}
+
+// authorPostKarma returns the author's stored post karma once the user supervisor has
+// handled the karma updates sent to it so far
+func (h *postHarness) authorPostKarma() int {
+	h.t.Helper()
+	if _, err := h.system.Root.RequestFuture(h.users, &GetUserProfileMsg{UserID: h.authorID}, 5*time.Second).Result(); err != nil {
+		h.t.Fatalf("GetUserProfileMsg: %v", err)
+	}
+	user, err := h.mongodb.GetUser(stdctx.Background(), h.authorID)
+	if err != nil {
+		h.t.Fatalf("GetUser: %v", err)
+	}
+	return user.PostKarma
+}
+
+// assertCounts checks a post's cached and stored counts, and that the author's karma is
+// its upvotes minus downvotes, less the author's own vote
+func (h *postHarness) assertCounts(step string, postID uuid.UUID, ups, downs, authorVote int) {
+	h.t.Helper()
+	cached, appErr := h.request(&GetPostMsg{PostID: postID})
+	if appErr != nil {
+		h.t.Fatalf("%s: GetPostMsg: %v", step, appErr)
+	}
+	stored, err := h.mongodb.GetPost(stdctx.Background(), postID)
+	if err != nil {
+		h.t.Fatalf("%s: GetPost: %v", step, err)
+	}
+	for _, post := range []*models.Post{cached, stored} {
+		if post.Upvotes != ups || post.Downvotes != downs || post.Karma != ups-downs {
+			h.t.Fatalf("%s: post has %d up, %d down, karma %d; want %d up, %d down, karma %d",
+				step, post.Upvotes, post.Downvotes, post.Karma, ups, downs, ups-downs)
+		}
+	}
+	if karma := h.authorPostKarma(); karma != ups-downs-authorVote {
+		h.t.Fatalf("%s: author post karma = %d, want %d", step, karma, ups-downs-authorVote)
+	}
+}
+
+func TestVoteTransitionsKeepAuthorKarmaInStep(t *testing.T) {
+	h := newPostHarness(t)
+	post := h.addPost()
+	alice, bob := uuid.New(), uuid.New()
+	names := map[uuid.UUID]string{alice: "alice", bob: "bob"}
+
+	const (
+		up     = "up"
+		down   = "down"
+		remove = "remove"
+	)
+	steps := []struct {
+		voter   uuid.UUID
+		action  string
+		wantErr string
+	}{
+		{alice, up, ""},   // none → up
+		{alice, down, ""}, // up → down
+		{alice, remove, ""},
+		{alice, down, ""}, // none → down
+		{alice, up, ""},   // down → up
+		{alice, remove, ""},
+		{alice, remove, utils.ErrNotFound},
+		{bob, up, ""},
+		{alice, up, ""},
+		{alice, up, utils.ErrDuplicate},
+		{bob, down, ""},
+		{bob, down, utils.ErrDuplicate},
+		{alice, down, ""},
+		{bob, remove, ""},
+		{alice, remove, ""},
+	}
+
+	// The votes expected to stand, as +1 or -1 by voter
+	votes := make(map[uuid.UUID]int)
+	for i, s := range steps {
+		step := fmt.Sprintf("step %d (%s %s)", i+1, names[s.voter], s.action)
+
+		var appErr *utils.AppError
+		switch s.action {
+		case up:
+			appErr = h.vote(post.ID, s.voter, true)
+		case down:
+			appErr = h.vote(post.ID, s.voter, false)
+		case remove:
+			appErr = h.unvote(post.ID, s.voter)
+		}
+		if s.wantErr != "" {
+			if appErr == nil || appErr.Code != s.wantErr {
+				t.Fatalf("%s: error = %v, want %s", step, appErr, s.wantErr)
+			}
+		} else {
+			if appErr != nil {
+				t.Fatalf("%s: %v", step, appErr)
+			}
+			switch s.action {
+			case up:
+				votes[s.voter] = 1
+			case down:
+				votes[s.voter] = -1
+			case remove:
+				delete(votes, s.voter)
+			}
+		}
+
+		ups, downs := 0, 0
+		for _, v := range votes {
+			if v > 0 {
+				ups++
+			} else {
+				downs++
+			}
+		}
+		h.assertCounts(step, post.ID, ups, downs, 0)
+	}
+}
+
+func TestUnvoteSurvivesRestart(t *testing.T) {
+	h := newPostHarness(t)
+	post := h.addPost()
+	voter := uuid.New()
+
+	if appErr := h.vote(post.ID, voter, false); appErr != nil {
+		t.Fatalf("vote: %v", appErr)
+	}
+	// A restarted actor knows the vote only from its record
+	h.actor.postVotes = make(map[uuid.UUID]map[uuid.UUID]voteStatus)
+
+	if appErr := h.unvote(post.ID, voter); appErr != nil {
+		t.Fatalf("unvote: %v", appErr)
+	}
+	h.assertCounts("after unvote", post.ID, 0, 0, 0)
+
+	vote, err := h.mongodb.GetPostVote(stdctx.Background(), post.ID, voter)
+	if err != nil {
+		t.Fatalf("GetPostVote: %v", err)
+	}
+	if vote != nil {
+		t.Errorf("vote record was kept after the vote was removed: %+v", vote)
+	}
+}
+
+func TestCreatedPostStartsWithAuthorUpvote(t *testing.T) {
+	h := newPostHarness(t)
+	post := h.createPost()
+
+	if post.Upvotes != 1 || post.Downvotes != 0 || post.Karma != 1 {
+		t.Errorf("new post has %d up, %d down, karma %d; want the author's upvote only",
+			post.Upvotes, post.Downvotes, post.Karma)
+	}
+	if post.UserVote == nil || *post.UserVote != models.VoteUp {
+		t.Errorf("new post's UserVote for its author = %v, want %q", post.UserVote, models.VoteUp)
+	}
+	vote, err := h.mongodb.GetPostVote(stdctx.Background(), post.ID, h.authorID)
+	if err != nil {
+		t.Fatalf("GetPostVote: %v", err)
+	}
+	if vote == nil || !vote.IsUpvote {
+		t.Fatalf("author's self-upvote record = %+v, want an upvote", vote)
+	}
+	h.assertCounts("after creation", post.ID, 1, 0, 1)
+
+	if appErr := h.vote(post.ID, h.authorID, true); appErr == nil || appErr.Code != utils.ErrDuplicate {
+		t.Errorf("author upvoting again: error = %v, want %s", appErr, utils.ErrDuplicate)
+	}
+	// The record, not just the cache, keeps the author from upvoting again
+	h.actor.postVotes = make(map[uuid.UUID]map[uuid.UUID]voteStatus)
+	if appErr := h.vote(post.ID, h.authorID, true); appErr == nil || appErr.Code != utils.ErrDuplicate {
+		t.Errorf("author upvoting again after a restart: error = %v, want %s", appErr, utils.ErrDuplicate)
+	}
+	h.assertCounts("after upvoting again", post.ID, 1, 0, 1)
+}
+
+func TestSelfVotesMoveScoreButNotKarma(t *testing.T) {
+	h := newPostHarness(t)
+	post := h.createPost()
+	voter := uuid.New()
+
+	// The author's vote is the last argument to assertCounts, which leaves it out of
+	// the karma expected
+	if appErr := h.vote(post.ID, h.authorID, false); appErr != nil {
+		t.Fatalf("author switching to a downvote: %v", appErr)
+	}
+	h.assertCounts("after the author switches to a downvote", post.ID, 0, 1, -1)
+
+	if appErr := h.vote(post.ID, voter, true); appErr != nil {
+		t.Fatalf("another user's upvote: %v", appErr)
+	}
+	h.assertCounts("after another user's upvote", post.ID, 1, 1, -1)
+
+	if appErr := h.unvote(post.ID, h.authorID); appErr != nil {
+		t.Fatalf("author removing their vote: %v", appErr)
+	}
+	h.assertCounts("after the author removes their vote", post.ID, 1, 0, 0)
+
+	if appErr := h.vote(post.ID, h.authorID, true); appErr != nil {
+		t.Fatalf("author upvoting again: %v", appErr)
+	}
+	h.assertCounts("after the author upvotes again", post.ID, 2, 0, 1)
+
+	if appErr := h.vote(post.ID, voter, false); appErr != nil {
+		t.Fatalf("another user switching to a downvote: %v", appErr)
+	}
+	h.assertCounts("after another user switches to a downvote", post.ID, 1, 1, 1)
+}
+
+// The actor asks MongoDB whether it can run transactions rather than being told, and
+// votes are stored either way
+func TestFirstVoteDetectsTransactionSupport(t *testing.T) {
+	h := newPostHarness(t)
+	post := h.addPost()
+	if h.actor.txChecked {
+		t.Fatalf("transaction support checked before any vote")
+	}
+
+	if appErr := h.vote(post.ID, uuid.New(), true); appErr != nil {
+		t.Fatalf("upvote: %v", appErr)
+	}
+	supported := dbtest.SupportsTransactions(t, h.mongodb)
+	if !h.actor.txChecked || h.actor.noTransactions == supported {
+		t.Fatalf("after the first vote, checked %v and transactions off %v; the deployment supports them: %v",
+			h.actor.txChecked, h.actor.noTransactions, supported)
+	}
+	h.assertCounts("after the first vote", post.ID, 1, 0, 0)
+
+	if appErr := h.vote(post.ID, uuid.New(), false); appErr != nil {
+		t.Fatalf("downvote: %v", appErr)
+	}
+	h.assertCounts("after the second vote", post.ID, 1, 1, 0)
+}
@@ -0,0 +1,143 @@
+package actors
+
+import (
+	stdctx "context"
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/i18n"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/types"
+	"gator-swamp/internal/utils"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultSavedItemPageSize = 25
+	maxSavedItemPageSize     = 100
+)
+
+// Message types for SavedItemActor
+type (
+	// SaveItemMsg saves a post or comment for a user. Saving it again is a no-op.
+	SaveItemMsg struct {
+		UserID   uuid.UUID
+		ItemType string // models.SavedItemPost or models.SavedItemComment
+		ItemID   uuid.UUID
+	}
+
+	// UnsaveItemMsg removes a post or comment from a user's saved items
+	UnsaveItemMsg struct {
+		UserID   uuid.UUID
+		ItemType string
+		ItemID   uuid.UUID
+	}
+
+	// GetSavedItemsMsg requests a page of a user's saved items, most recently saved first
+	GetSavedItemsMsg struct {
+		UserID   uuid.UUID
+		ItemType string // Empty for all saved items
+		Limit    int
+		Cursor   string
+	}
+)
+
+// SavedItemActor manages the posts and comments users have saved
+type SavedItemActor struct {
+	mongodb *database.MongoDB
+}
+
+func NewSavedItemActor(mongodb *database.MongoDB) actor.Actor {
+	return &SavedItemActor{
+		mongodb: mongodb,
+	}
+}
+
+func (a *SavedItemActor) Receive(context actor.Context) {
+	switch msg := context.Message().(type) {
+	case *SaveItemMsg:
+		a.handleSaveItem(context, msg)
+	case *UnsaveItemMsg:
+		a.handleUnsaveItem(context, msg)
+	case *GetSavedItemsMsg:
+		a.handleGetSavedItems(context, msg)
+	}
+}
+
+func (a *SavedItemActor) handleSaveItem(context actor.Context, msg *SaveItemMsg) {
+	ctx := stdctx.Background()
+
+	// Only existing content can be saved
+	var isDeleted bool
+	switch msg.ItemType {
+	case models.SavedItemPost:
+		post, err := a.mongodb.GetPost(ctx, msg.ItemID)
+		if err != nil {
+			if utils.IsErrorCode(err, utils.ErrNotFound) {
+				context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil))
+				return
+			}
+			context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get post", err))
+			return
+		}
+		isDeleted = post.IsDeleted
+	case models.SavedItemComment:
+		comment, err := a.mongodb.GetComment(ctx, msg.ItemID)
+		if err != nil {
+			if utils.IsErrorCode(err, utils.ErrNotFound) {
+				context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrCommentNotFound, nil, nil))
+				return
+			}
+			context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get comment", err))
+			return
+		}
+		isDeleted = comment.IsDeleted
+	default:
+		context.Respond(utils.NewAppError(utils.ErrInvalidInput, "Only posts and comments can be saved", nil))
+		return
+	}
+
+	if isDeleted {
+		context.Respond(utils.NewAppError(utils.ErrGone, "Deleted content cannot be saved", nil))
+		return
+	}
+
+	if err := a.mongodb.SaveItem(ctx, msg.UserID, msg.ItemType, msg.ItemID, time.Now().UTC().Truncate(time.Millisecond)); err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to save item", err))
+		return
+	}
+
+	context.Respond(map[string]bool{"saved": true})
+}
+
+func (a *SavedItemActor) handleUnsaveItem(context actor.Context, msg *UnsaveItemMsg) {
+	if err := a.mongodb.UnsaveItem(stdctx.Background(), msg.UserID, msg.ItemType, msg.ItemID); err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to unsave item", err))
+		return
+	}
+
+	context.Respond(map[string]bool{"saved": false})
+}
+
+func (a *SavedItemActor) handleGetSavedItems(context actor.Context, msg *GetSavedItemsMsg) {
+	limit := msg.Limit
+	if limit <= 0 {
+		limit = defaultSavedItemPageSize
+	}
+	if limit > maxSavedItemPageSize {
+		limit = maxSavedItemPageSize
+	}
+
+	items, nextCursor, err := a.mongodb.GetSavedItems(stdctx.Background(), msg.UserID, msg.ItemType, limit, msg.Cursor)
+	if err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			context.Respond(appErr)
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get saved items", err))
+		return
+	}
+
+	context.Respond(&types.PaginatedResponse{Items: items, NextCursor: nextCursor})
+}
@@ -0,0 +1,276 @@
+package actors
+
+import (
+	stdctx "context"
+	"crypto/rand"
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/i18n"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+	"log"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/asynkron/protoactor-go/scheduler"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	shareCodeLength      = 8
+	shareCodeAttempts    = 5
+	shareFlushInterval   = 5 * time.Second
+	shareFlushThreshold  = 100 // Flush early once this many clicks are pending
+	shareCodeAlphabet    = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	shareCodeAlphabetMax = 256 - (256 % len(shareCodeAlphabet)) // Bytes above this are rejected to avoid bias
+	shareRepeatWindow    = time.Minute                          // Shares of a post by the same user this close together count once
+	maxShareChannelLen   = 32
+)
+
+// Message types for ShareActor
+type (
+	CreateShareLinkMsg struct {
+		PostID    uuid.UUID
+		CreatorID uuid.UUID
+		Channel   string // Optional; where the link is being shared
+	}
+
+	ResolveShareLinkMsg struct {
+		Code string
+	}
+
+	GetShareStatsMsg struct {
+		PostID      uuid.UUID
+		RequesterID uuid.UUID
+	}
+
+	flushShareClicksMsg struct{}
+)
+
+// ShareStatsResponse is returned to a post's author
+type ShareStatsResponse struct {
+	PostID string `json:"postId"`
+	Shares int    `json:"shares"`
+	Clicks int    `json:"clicks"`
+}
+
+// ShareActor creates short share links and counts clicks on them.
+// Clicks are accumulated in memory and written behind in batches.
+type ShareActor struct {
+	pendingClicks map[string]int    // Click deltas not yet persisted, keyed by code
+	pendingByPost map[uuid.UUID]int // Same deltas grouped by post for stats responses
+	pendingTotal  int
+	stopFlush     scheduler.CancelFunc
+	mongodb       *database.MongoDB
+	postActor     *actor.PID // Counts each new share on the post
+}
+
+func NewShareActor(mongodb *database.MongoDB, postActor *actor.PID) actor.Actor {
+	return &ShareActor{
+		pendingClicks: make(map[string]int),
+		pendingByPost: make(map[uuid.UUID]int),
+		mongodb:       mongodb,
+		postActor:     postActor,
+	}
+}
+
+func (a *ShareActor) Receive(context actor.Context) {
+	switch msg := context.Message().(type) {
+	case *actor.Started:
+		log.Printf("ShareActor started")
+		a.stopFlush = scheduler.NewTimerScheduler(context).
+			SendRepeatedly(shareFlushInterval, shareFlushInterval, context.Self(), &flushShareClicksMsg{})
+
+	case *actor.Stopping:
+		if a.stopFlush != nil {
+			a.stopFlush()
+		}
+		a.flushClicks()
+
+	case *CreateShareLinkMsg:
+		a.handleCreateShareLink(context, msg)
+
+	case *ResolveShareLinkMsg:
+		a.handleResolveShareLink(context, msg)
+
+	case *GetShareStatsMsg:
+		a.handleGetShareStats(context, msg)
+
+	case *flushShareClicksMsg:
+		a.flushClicks()
+	}
+}
+
+// handleCreateShareLink creates a share link and counts the share on the post. A user
+// sharing the same post again within shareRepeatWindow gets their recent link back
+// instead, and the share isn't counted again.
+func (a *ShareActor) handleCreateShareLink(context actor.Context, msg *CreateShareLinkMsg) {
+	ctx := stdctx.Background()
+
+	if len(msg.Channel) > maxShareChannelLen {
+		context.Respond(utils.NewAppError(utils.ErrInvalidInput, "Share channel is too long", nil))
+		return
+	}
+
+	post, err := a.mongodb.GetPost(ctx, msg.PostID)
+	if err != nil {
+		if utils.IsErrorCode(err, utils.ErrNotFound) {
+			context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil))
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch post", err))
+		return
+	}
+	if post.IsDeleted {
+		context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil))
+		return
+	}
+
+	recent, err := a.mongodb.FindRecentShareLink(ctx, msg.PostID, msg.CreatorID, time.Now().Add(-shareRepeatWindow))
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to check recent shares", err))
+		return
+	}
+	if recent != nil {
+		context.Respond(recent)
+		return
+	}
+
+	for attempt := 0; attempt < shareCodeAttempts; attempt++ {
+		code, err := generateShareCode()
+		if err != nil {
+			context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to generate share code", err))
+			return
+		}
+
+		link := &models.ShareLink{
+			Code:      code,
+			PostID:    msg.PostID,
+			CreatorID: msg.CreatorID,
+			CreatedAt: time.Now(),
+			Channel:   msg.Channel,
+		}
+
+		err = a.mongodb.CreateShareLink(ctx, link)
+		if err == nil {
+			context.Send(a.postActor, &RecordPostShareMsg{PostID: msg.PostID})
+			context.Respond(link)
+			return
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to save share link", err))
+			return
+		}
+		log.Printf("ShareActor: Share code collision on %s, retrying", code)
+	}
+
+	context.Respond(utils.NewAppError(utils.ErrDuplicate, "Could not allocate a unique share code", nil))
+}
+
+// handleResolveShareLink responds with the post ID for a code and records the click.
+// Codes whose post was deleted or no longer exists resolve to ErrGone.
+func (a *ShareActor) handleResolveShareLink(context actor.Context, msg *ResolveShareLinkMsg) {
+	ctx := stdctx.Background()
+
+	link, err := a.mongodb.GetShareLink(ctx, msg.Code)
+	if err != nil {
+		if utils.IsErrorCode(err, utils.ErrNotFound) {
+			context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrShareLinkNotFound, nil, nil))
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to resolve share link", err))
+		return
+	}
+
+	post, err := a.mongodb.GetPost(ctx, link.PostID)
+	if err != nil && !utils.IsErrorCode(err, utils.ErrNotFound) {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch post", err))
+		return
+	}
+	if err != nil || post.IsDeleted {
+		context.Respond(utils.NewAppError(utils.ErrGone, "This post is no longer available", nil))
+		return
+	}
+
+	a.pendingClicks[link.Code]++
+	a.pendingByPost[link.PostID]++
+	a.pendingTotal++
+	if a.pendingTotal >= shareFlushThreshold {
+		a.flushClicks()
+	}
+
+	context.Respond(link)
+}
+
+func (a *ShareActor) handleGetShareStats(context actor.Context, msg *GetShareStatsMsg) {
+	ctx := stdctx.Background()
+
+	post, err := a.mongodb.GetPost(ctx, msg.PostID)
+	if err != nil {
+		if utils.IsErrorCode(err, utils.ErrNotFound) {
+			context.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil))
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch post", err))
+		return
+	}
+
+	if post.AuthorID != msg.RequesterID {
+		context.Respond(utils.NewAppError(utils.ErrForbidden, "Only the author can view share statistics", nil))
+		return
+	}
+
+	stats, err := a.mongodb.GetPostShareStats(ctx, msg.PostID)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get share statistics", err))
+		return
+	}
+
+	context.Respond(&ShareStatsResponse{
+		PostID: msg.PostID.String(),
+		Shares: stats.Shares,
+		Clicks: stats.Clicks + a.pendingByPost[msg.PostID],
+	})
+}
+
+// flushClicks persists pending click deltas. On failure the deltas are kept for the next attempt.
+func (a *ShareActor) flushClicks() {
+	if a.pendingTotal == 0 {
+		return
+	}
+
+	ctx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := a.mongodb.IncrementShareLinkClicks(ctx, a.pendingClicks); err != nil {
+		log.Printf("ShareActor: Failed to flush %d share clicks: %v", a.pendingTotal, err)
+		return
+	}
+
+	a.pendingClicks = make(map[string]int)
+	a.pendingByPost = make(map[uuid.UUID]int)
+	a.pendingTotal = 0
+}
+
+// generateShareCode returns a random base62 code using crypto/rand
+func generateShareCode() (string, error) {
+	code := make([]byte, 0, shareCodeLength)
+	buf := make([]byte, shareCodeLength*2)
+
+	for len(code) < shareCodeLength {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if int(b) >= shareCodeAlphabetMax {
+				continue
+			}
+			code = append(code, shareCodeAlphabet[int(b)%len(shareCodeAlphabet)])
+			if len(code) == shareCodeLength {
+				break
+			}
+		}
+	}
+
+	return string(code), nil
+}
@@ -0,0 +1,243 @@
+package actors
+
+import (
+	stdctx "context"
+	"encoding/xml"
+	"fmt"
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/utils"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+)
+
+const (
+	sitemapMaxURLs   = 50000 // Per-file limit from the sitemap protocol
+	sitemapCacheTTL  = time.Hour
+	sitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+	SitemapIndexName      = "index"
+	SitemapSubredditsName = "subreddits"
+	sitemapPostsPrefix    = "posts-"
+)
+
+// Message types for SitemapActor
+type (
+	// GetSitemapMsg requests one sitemap file by name: "index", "subreddits" or "posts-<n>"
+	GetSitemapMsg struct {
+		Name string
+	}
+)
+
+// XML documents defined by the sitemap protocol
+type (
+	sitemapURLSet struct {
+		XMLName xml.Name     `xml:"urlset"`
+		Xmlns   string       `xml:"xmlns,attr"`
+		URLs    []sitemapURL `xml:"url"`
+	}
+
+	sitemapURL struct {
+		Loc     string `xml:"loc"`
+		LastMod string `xml:"lastmod,omitempty"`
+	}
+
+	sitemapIndex struct {
+		XMLName  xml.Name         `xml:"sitemapindex"`
+		Xmlns    string           `xml:"xmlns,attr"`
+		Sitemaps []sitemapPointer `xml:"sitemap"`
+	}
+
+	sitemapPointer struct {
+		Loc string `xml:"loc"`
+	}
+)
+
+type cachedSitemap struct {
+	data        []byte
+	generatedAt time.Time
+}
+
+// SitemapActor generates sitemap files for public content on demand and caches them
+type SitemapActor struct {
+	baseURL      string
+	cache        map[string]cachedSitemap
+	mongodb      *database.MongoDB
+	postsPerFile int
+
+	// postPageEnds[n-1] is the last post ID on posts page n, which is where page n+1
+	// starts. It is forgotten along with the cached files.
+	postPageEnds   []string
+	postPageEndsAt time.Time
+}
+
+func NewSitemapActor(mongodb *database.MongoDB, baseURL string) actor.Actor {
+	return &SitemapActor{
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		cache:        make(map[string]cachedSitemap),
+		mongodb:      mongodb,
+		postsPerFile: sitemapMaxURLs,
+	}
+}
+
+func (a *SitemapActor) Receive(context actor.Context) {
+	switch msg := context.Message().(type) {
+	case *actor.Started:
+		log.Printf("SitemapActor started")
+
+	case *GetSitemapMsg:
+		a.handleGetSitemap(context, msg)
+	}
+}
+
+func (a *SitemapActor) handleGetSitemap(context actor.Context, msg *GetSitemapMsg) {
+	if cached, ok := a.cache[msg.Name]; ok && time.Since(cached.generatedAt) < sitemapCacheTTL {
+		context.Respond(cached.data)
+		return
+	}
+
+	ctx, cancel := stdctx.WithTimeout(stdctx.Background(), 30*time.Second)
+	defer cancel()
+
+	var doc interface{}
+	var err error
+
+	switch {
+	case msg.Name == SitemapIndexName:
+		doc, err = a.buildIndex(ctx)
+	case msg.Name == SitemapSubredditsName:
+		doc, err = a.buildSubreddits(ctx)
+	case strings.HasPrefix(msg.Name, sitemapPostsPrefix):
+		page, convErr := strconv.Atoi(strings.TrimPrefix(msg.Name, sitemapPostsPrefix))
+		if convErr != nil || page < 1 {
+			context.Respond(utils.NewAppError(utils.ErrNotFound, "Sitemap not found", nil))
+			return
+		}
+		doc, err = a.buildPosts(ctx, page)
+	default:
+		context.Respond(utils.NewAppError(utils.ErrNotFound, "Sitemap not found", nil))
+		return
+	}
+
+	if err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			context.Respond(appErr)
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to generate sitemap", err))
+		return
+	}
+
+	body, err := xml.Marshal(doc)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to encode sitemap", err))
+		return
+	}
+	data := append([]byte(xml.Header), body...)
+
+	a.cache[msg.Name] = cachedSitemap{data: data, generatedAt: time.Now()}
+	context.Respond(data)
+}
+
+func (a *SitemapActor) buildIndex(ctx stdctx.Context) (*sitemapIndex, error) {
+	count, err := a.mongodb.CountPublicPosts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The schema requires every file to list a URL, so posts files are only advertised
+	// once there are posts to fill them
+	pages := int((count + int64(a.postsPerFile) - 1) / int64(a.postsPerFile))
+
+	index := &sitemapIndex{
+		Xmlns:    sitemapNamespace,
+		Sitemaps: []sitemapPointer{{Loc: a.baseURL + "/sitemap-subreddits.xml"}},
+	}
+	for page := 1; page <= pages; page++ {
+		index.Sitemaps = append(index.Sitemaps, sitemapPointer{
+			Loc: fmt.Sprintf("%s/sitemap-posts-%d.xml", a.baseURL, page),
+		})
+	}
+	return index, nil
+}
+
+func (a *SitemapActor) buildSubreddits(ctx stdctx.Context) (*sitemapURLSet, error) {
+	// The front page comes first, which keeps the file valid on a site with no
+	// subreddits yet
+	entries, err := a.mongodb.GetPublicSubredditEntries(ctx, sitemapMaxURLs-1)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &sitemapURLSet{Xmlns: sitemapNamespace, URLs: make([]sitemapURL, 0, len(entries)+1)}
+	set.URLs = append(set.URLs, sitemapURL{Loc: a.baseURL + "/"})
+	for _, entry := range entries {
+		set.URLs = append(set.URLs, sitemapURL{
+			Loc:     a.baseURL + "/r/" + url.PathEscape(entry.Key),
+			LastMod: formatSitemapTime(entry.LastMod),
+		})
+	}
+	return set, nil
+}
+
+func (a *SitemapActor) buildPosts(ctx stdctx.Context, page int) (*sitemapURLSet, error) {
+	if time.Since(a.postPageEndsAt) >= sitemapCacheTTL {
+		a.postPageEnds = nil
+		a.postPageEndsAt = time.Now()
+	}
+
+	// Crawlers follow the index in order, so the previous page's end is usually known;
+	// otherwise the pages before this one are walked to find where it starts
+	for len(a.postPageEnds) < page-1 {
+		if _, err := a.loadPostPage(ctx, len(a.postPageEnds)+1); err != nil {
+			return nil, err
+		}
+	}
+	entries, err := a.loadPostPage(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &sitemapURLSet{Xmlns: sitemapNamespace, URLs: make([]sitemapURL, 0, len(entries))}
+	for _, entry := range entries {
+		set.URLs = append(set.URLs, sitemapURL{
+			Loc:     a.baseURL + "/post/" + entry.Key,
+			LastMod: formatSitemapTime(entry.LastMod),
+		})
+	}
+	return set, nil
+}
+
+// loadPostPage reads posts page n, whose start is already known for every page up to
+// the first unread one, and records where it ends
+func (a *SitemapActor) loadPostPage(ctx stdctx.Context, page int) ([]database.SitemapEntry, error) {
+	after := ""
+	if page > 1 {
+		after = a.postPageEnds[page-2]
+	}
+
+	entries, err := a.mongodb.GetPublicPostEntries(ctx, after, a.postsPerFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, utils.NewAppError(utils.ErrNotFound, "Sitemap not found", nil)
+	}
+
+	if len(a.postPageEnds) == page-1 {
+		a.postPageEnds = append(a.postPageEnds, entries[len(entries)-1].Key)
+	}
+	return entries, nil
+}
+
+// formatSitemapTime renders a W3C datetime, omitting unset timestamps
+func formatSitemapTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
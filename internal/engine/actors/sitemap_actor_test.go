@@ -0,0 +1,104 @@
+package actors
+
+import (
+	stdctx "context"
+	"encoding/xml"
+	"fmt"
+	"gator-swamp/internal/database/dbtest"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/google/uuid"
+)
+
+// Posts files are read from where the previous one ended, including when a crawler asks
+// for a later file first
+func TestSitemapPostsFilesFollowOnFromEachOther(t *testing.T) {
+	mongodb := dbtest.New(t)
+	ctx := stdctx.Background()
+	system := actor.NewActorSystem()
+	sitemaps := system.Root.Spawn(actor.PropsFromProducer(func() actor.Actor {
+		return &SitemapActor{
+			baseURL:      "https://gator.example",
+			cache:        make(map[string]cachedSitemap),
+			mongodb:      mongodb,
+			postsPerFile: 2,
+		}
+	}))
+	t.Cleanup(func() {
+		system.Root.StopFuture(sitemaps).Wait()
+		system.Shutdown()
+	})
+
+	subreddit := &models.Subreddit{
+		ID:        uuid.New(),
+		Name:      "swamp",
+		CreatorID: uuid.New(),
+		CreatedAt: time.Now(),
+		Type:      models.SubredditPublic,
+	}
+	if err := mongodb.CreateSubreddit(ctx, subreddit); err != nil {
+		t.Fatalf("CreateSubreddit: %v", err)
+	}
+	var want []string
+	for i := 0; i < 5; i++ {
+		post := &models.Post{
+			ID:          uuid.New(),
+			Title:       "In the swamp",
+			Slug:        fmt.Sprintf("in-the-swamp-%d", i),
+			AuthorID:    subreddit.CreatorID,
+			SubredditID: subreddit.ID,
+			CreatedAt:   time.Now(),
+			Status:      models.PostStatusPublished,
+		}
+		if err := mongodb.SavePost(ctx, post); err != nil {
+			t.Fatalf("SavePost: %v", err)
+		}
+		want = append(want, "https://gator.example/post/"+post.ID.String())
+	}
+	sort.Strings(want)
+
+	get := func(name string) interface{} {
+		t.Helper()
+		result, err := system.Root.RequestFuture(sitemaps, &GetSitemapMsg{Name: name}, 5*time.Second).Result()
+		if err != nil {
+			t.Fatalf("GetSitemapMsg %s: %v", name, err)
+		}
+		return result
+	}
+	locs := func(name string) []string {
+		t.Helper()
+		data, ok := get(name).([]byte)
+		if !ok {
+			t.Fatalf("sitemap %s wasn't served", name)
+		}
+		var set sitemapURLSet
+		if err := xml.Unmarshal(data, &set); err != nil {
+			t.Fatalf("decoding %s: %v", name, err)
+		}
+		var locs []string
+		for _, u := range set.URLs {
+			locs = append(locs, u.Loc)
+		}
+		return locs
+	}
+
+	last := locs("posts-3")
+	if len(last) != 1 || last[0] != want[4] {
+		t.Fatalf("posts-3 lists %v, want %v", last, want[4:])
+	}
+	var got []string
+	got = append(got, locs("posts-1")...)
+	got = append(got, locs("posts-2")...)
+	got = append(got, last...)
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("posts files list %v, want %v", got, want)
+	}
+	if appErr, ok := get("posts-4").(*utils.AppError); !ok || appErr.Code != utils.ErrNotFound {
+		t.Fatalf("posts-4 wasn't refused as not found")
+	}
+}
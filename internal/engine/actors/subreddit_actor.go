@@ -2,10 +2,18 @@ package actors
 
 import (
 	stdctx "context" // Import standard context package with alias to avoid confusion
+	"fmt"
+	"gator-swamp/internal/config"
 	"gator-swamp/internal/database"
+	"gator-swamp/internal/i18n"
 	"gator-swamp/internal/models"
+	"gator-swamp/internal/types"
 	"gator-swamp/internal/utils"
 	"log"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/asynkron/protoactor-go/actor"
@@ -18,6 +26,8 @@ type (
 		Name        string
 		Description string
 		CreatorID   uuid.UUID
+		Type        models.SubredditType // Empty for public
+		AsAdmin     bool                 // The creator is an administrator, who bypasses the creation rules
 	}
 
 	JoinSubredditMsg struct {
@@ -43,8 +53,331 @@ type (
 	GetSubredditByNameMsg struct {
 		Name string
 	}
+
+	// ListFlairsMsg requests a subreddit's flair templates
+	ListFlairsMsg struct {
+		SubredditID uuid.UUID
+	}
+
+	// CreateFlairMsg adds a flair template to a subreddit
+	CreateFlairMsg struct {
+		SubredditID uuid.UUID
+		RequesterID uuid.UUID
+		Text        string
+		Color       string
+	}
+
+	// UpdateFlairMsg changes a flair template's text and color
+	UpdateFlairMsg struct {
+		SubredditID uuid.UUID
+		FlairID     uuid.UUID
+		RequesterID uuid.UUID
+		Text        string
+		Color       string
+	}
+
+	// DeleteFlairMsg removes a flair template; posts keep its text
+	DeleteFlairMsg struct {
+		SubredditID uuid.UUID
+		FlairID     uuid.UUID
+		RequesterID uuid.UUID
+	}
+
+	// ListUserFlairsMsg requests a subreddit's user flair templates
+	ListUserFlairsMsg struct {
+		SubredditID uuid.UUID
+	}
+
+	// CreateUserFlairMsg adds a user flair template to a subreddit
+	CreateUserFlairMsg struct {
+		SubredditID uuid.UUID
+		RequesterID uuid.UUID
+		Text        string
+		Color       string
+		ModOnly     bool
+	}
+
+	// UpdateUserFlairMsg changes a user flair template; members wearing it follow
+	UpdateUserFlairMsg struct {
+		SubredditID uuid.UUID
+		FlairID     uuid.UUID
+		RequesterID uuid.UUID
+		Text        string
+		Color       string
+		ModOnly     bool
+	}
+
+	// DeleteUserFlairMsg removes a user flair template and takes it off members wearing it
+	DeleteUserFlairMsg struct {
+		SubredditID uuid.UUID
+		FlairID     uuid.UUID
+		RequesterID uuid.UUID
+	}
+
+	// SetUserFlairMsg sets a member's flair in a subreddit. Members choose their own;
+	// moderators can assign any member one, including mod-only templates.
+	SetUserFlairMsg struct {
+		SubredditID uuid.UUID
+		RequesterID uuid.UUID
+		UserID      uuid.UUID  // The member to flair; uuid.Nil for the requester
+		FlairID     *uuid.UUID // Nil clears the member's flair
+	}
+
+	// UpdateSubredditSettingsMsg changes a subreddit's moderator-editable settings. Nil
+	// fields are left unchanged.
+	UpdateSubredditSettingsMsg struct {
+		SubredditID           uuid.UUID
+		RequesterID           uuid.UUID
+		Name                  string // If set, must be the current name; names can't be changed
+		Description           *string
+		PollResultsAfterClose *bool
+		MinKarmaToPost        *int
+		NSFW                  *bool
+		Type                  *models.SubredditType
+		IconURL               *string // Empty removes the icon
+		BannerURL             *string // Empty removes the banner
+		PrimaryColor          *string // Empty removes the color
+	}
+
+	// SearchSubredditsMsg finds subreddits by name prefix, and by description for longer
+	// queries, hiding private subreddits the viewer can't read
+	SearchSubredditsMsg struct {
+		Query    string
+		Limit    int
+		ViewerID uuid.UUID // uuid.Nil for anonymous searches
+	}
+
+	// ListModeratorsMsg requests a subreddit's moderators
+	ListModeratorsMsg struct {
+		SubredditID uuid.UUID
+	}
+
+	// AddModeratorMsg makes a user a moderator of a subreddit
+	AddModeratorMsg struct {
+		SubredditID uuid.UUID
+		RequesterID uuid.UUID
+		UserID      uuid.UUID
+	}
+
+	// RemoveModeratorMsg takes a user off a subreddit's moderators; the creator can't be
+	RemoveModeratorMsg struct {
+		SubredditID uuid.UUID
+		RequesterID uuid.UUID
+		UserID      uuid.UUID
+	}
+
+	// ApproveSubredditUserMsg lets a user post in a restricted subreddit or join a
+	// private one
+	ApproveSubredditUserMsg struct {
+		SubredditID uuid.UUID
+		RequesterID uuid.UUID
+		UserID      uuid.UUID
+	}
+
+	// BanUserMsg bans a user from posting or commenting in a subreddit. Banning a user
+	// who is already banned replaces their ban.
+	BanUserMsg struct {
+		SubredditID uuid.UUID
+		RequesterID uuid.UUID
+		UserID      uuid.UUID
+		Reason      string
+		Duration    time.Duration // Zero for a permanent ban
+	}
+
+	// UnbanUserMsg lifts a user's ban from a subreddit
+	UnbanUserMsg struct {
+		SubredditID uuid.UUID
+		RequesterID uuid.UUID
+		UserID      uuid.UUID
+	}
+
+	// GetAutomodMsg requests a subreddit's automod rules, for its moderators
+	GetAutomodMsg struct {
+		SubredditID uuid.UUID
+		RequesterID uuid.UUID
+	}
+
+	// UpdateAutomodMsg replaces a subreddit's automod rules. Rules without an ID get one.
+	UpdateAutomodMsg struct {
+		SubredditID uuid.UUID
+		RequesterID uuid.UUID
+		Rules       []models.AutomodRule
+	}
+
+	// GetSubredditAnalyticsMsg requests a subreddit's daily activity over its last Days
+	// days, for its moderators
+	GetSubredditAnalyticsMsg struct {
+		SubredditID uuid.UUID
+		RequesterID uuid.UUID
+		Days        int
+	}
+
+	// ListBansMsg requests a page of a subreddit's current bans, most recent first
+	ListBansMsg struct {
+		SubredditID uuid.UUID
+		RequesterID uuid.UUID
+		Limit       int
+		Cursor      string
+	}
+
+	// ReconcileMemberCountsMsg recounts every subreddit's members from user
+	// subscriptions and corrects counts that drifted
+	ReconcileMemberCountsMsg struct{}
+
+	// DeleteSubredditMsg deletes a subreddit along with its posts and their comments.
+	// ConfirmName must repeat the subreddit's name. A dry run reports what would be
+	// deleted without deleting anything.
+	DeleteSubredditMsg struct {
+		SubredditID uuid.UUID
+		RequesterID uuid.UUID
+		ConfirmName string
+		AsAdmin     bool // The requester is an administrator, who may delete any subreddit
+		DryRun      bool
+	}
+)
+
+// SubredditDeleteTimeout bounds the deletion of a subreddit, which touches all its posts
+// and members
+const SubredditDeleteTimeout = 30 * time.Second
+
+// MemberReconcileTimeout bounds the recount of every subreddit's members
+const MemberReconcileTimeout = 60 * time.Second
+
+// SubredditDeletion describes the deletion of a subreddit, or with DryRun what a
+// deletion would remove
+type SubredditDeletion struct {
+	SubredditID string   `json:"subredditId"`
+	Name        string   `json:"name"`
+	DryRun      bool     `json:"dryRun"`
+	Posts       int      `json:"posts"`   // Posts deleted, or that would be
+	Members     int      `json:"members"` // Users unsubscribed, or that would be
+	PostIDs     []string `json:"-"`       // The deleted posts, for evicting them from caches
+}
+
+// SubredditResponse is a subreddit as returned to clients
+type SubredditResponse struct {
+	ID          string             `json:"ID"`
+	Name        string             `json:"Name"`
+	Description string             `json:"Description"`
+	CreatorID   string             `json:"CreatorID"`
+	Members     int                `json:"Members"`
+	CreatedAt   time.Time          `json:"CreatedAt"`
+	Posts       []uuid.UUID        `json:"Posts"`
+	Flairs      []models.PostFlair `json:"Flairs"`
+
+	PollResultsAfterClose bool `json:"PollResultsAfterClose"`
+	MinKarmaToPost        int  `json:"MinKarmaToPost"`
+	NSFW                  bool `json:"NSFW"`
+
+	Type models.SubredditType `json:"Type"`
+
+	IconURL      string `json:"IconURL"`
+	BannerURL    string `json:"BannerURL"`
+	PrimaryColor string `json:"PrimaryColor"`
+
+	UserFlairs []models.UserFlairTemplate `json:"UserFlairs"`
+}
+
+func newSubredditResponse(subreddit *models.Subreddit) *SubredditResponse {
+	return &SubredditResponse{
+		ID:          subreddit.ID.String(),
+		Name:        subreddit.Name,
+		Description: subreddit.Description,
+		CreatorID:   subreddit.CreatorID.String(),
+		Members:     subreddit.Members,
+		CreatedAt:   subreddit.CreatedAt,
+		Posts:       subreddit.Posts,
+		Flairs:      subreddit.Flairs,
+
+		PollResultsAfterClose: subreddit.PollResultsAfterClose,
+		MinKarmaToPost:        subreddit.MinKarmaToPost,
+		NSFW:                  subreddit.NSFW,
+
+		Type: subreddit.Type,
+
+		IconURL:      subreddit.IconURL,
+		BannerURL:    subreddit.BannerURL,
+		PrimaryColor: subreddit.PrimaryColor,
+
+		UserFlairs: subreddit.UserFlairs,
+	}
+}
+
+// MembershipResponse is a user's membership of a subreddit after joining or leaving it
+type MembershipResponse struct {
+	SubredditID uuid.UUID
+	IsMember    bool
+	Members     int // The subreddit's member count
+
+	Changed bool `json:"-"` // Whether the request joined or left, rather than finding it done
+}
+
+// ModeratorsResponse lists a subreddit's moderators. The creator always moderates and is
+// given separately.
+type ModeratorsResponse struct {
+	SubredditID uuid.UUID          `json:"subredditId"`
+	CreatorID   uuid.UUID          `json:"creatorId"`
+	Moderators  []models.Moderator `json:"moderators"`
+}
+
+func newModeratorsResponse(subreddit *models.Subreddit) *ModeratorsResponse {
+	moderators := subreddit.Moderators
+	if moderators == nil {
+		moderators = []models.Moderator{}
+	}
+	return &ModeratorsResponse{SubredditID: subreddit.ID, CreatorID: subreddit.CreatorID, Moderators: moderators}
+}
+
+// AutomodResponse lists a subreddit's automod rules, in the order moderators gave them
+type AutomodResponse struct {
+	SubredditID uuid.UUID            `json:"subredditId"`
+	Rules       []models.AutomodRule `json:"rules"`
+}
+
+func newAutomodResponse(subreddit *models.Subreddit) *AutomodResponse {
+	rules := subreddit.Automod
+	if rules == nil {
+		rules = []models.AutomodRule{}
+	}
+	return &AutomodResponse{SubredditID: subreddit.ID, Rules: rules}
+}
+
+// ApprovalResponse confirms that a user is approved in a subreddit
+type ApprovalResponse struct {
+	SubredditID uuid.UUID `json:"subredditId"`
+	UserID      uuid.UUID `json:"userId"`
+	Approved    bool      `json:"approved"`
+}
+
+// UnbanResponse confirms that a user is no longer banned from a subreddit
+type UnbanResponse struct {
+	SubredditID uuid.UUID `json:"subredditId"`
+	UserID      uuid.UUID `json:"userId"`
+	Banned      bool      `json:"banned"`
+}
+
+const (
+	maxSubredditFlairs = 50
+	maxFlairTextLength = 64
+	maxBanReasonLength = 300
+	maxImageURLLength  = 2048
+
+	defaultAnalyticsDays  = 30
+	maxAnalyticsDays      = 90
+	analyticsTopPosts     = 10
+	subredditAnalyticsTTL = 5 * time.Minute // Moderators refreshing the page reuse the same figures
+
+	minSubredditSearchLength   = 2 // Shorter queries would match too much to be useful
+	minDescriptionSearchLength = 3 // Queries this long also match descriptions
+	maxSubredditSearchLength   = 100
 )
 
+// subredditNamePattern matches the names subreddits can be created with
+var subredditNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,21}$`)
+
+// flairColorPattern matches the hex colors flair and subreddits can be shown in
+var flairColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
 // SubredditActor handles all subreddit-related operations
 type SubredditActor struct {
 	subredditsByName map[string]*models.Subreddit
@@ -53,15 +386,25 @@ type SubredditActor struct {
 	metrics          *utils.MetricsCollector
 	context          actor.Context
 	mongodb          *database.MongoDB
+	creationRules    config.SubredditCreationRules
+	analytics        map[subredditAnalyticsKey]*database.SubredditAnalytics // Recent analytics, until subredditAnalyticsTTL after they were generated
+}
+
+// subredditAnalyticsKey identifies a cached analytics report
+type subredditAnalyticsKey struct {
+	subredditID uuid.UUID
+	days        int
 }
 
-func NewSubredditActor(metrics *utils.MetricsCollector, mongodb *database.MongoDB) actor.Actor {
+func NewSubredditActor(metrics *utils.MetricsCollector, mongodb *database.MongoDB, creationRules config.SubredditCreationRules) actor.Actor {
 	return &SubredditActor{
 		subredditsByName: make(map[string]*models.Subreddit),
 		subredditsById:   make(map[uuid.UUID]*models.Subreddit),
 		subredditMembers: make(map[uuid.UUID]map[uuid.UUID]bool),
 		metrics:          metrics,
 		mongodb:          mongodb,
+		creationRules:    creationRules,
+		analytics:        make(map[subredditAnalyticsKey]*database.SubredditAnalytics),
 	}
 }
 
@@ -102,6 +445,75 @@ func (a *SubredditActor) Receive(context actor.Context) {
 	case *GetSubredditByNameMsg:
 		a.handleGetSubredditByName(context, msg)
 
+	case *ListFlairsMsg:
+		a.handleListFlairs(context, msg)
+
+	case *CreateFlairMsg:
+		a.handleCreateFlair(context, msg)
+
+	case *UpdateFlairMsg:
+		a.handleUpdateFlair(context, msg)
+
+	case *DeleteFlairMsg:
+		a.handleDeleteFlair(context, msg)
+
+	case *ListUserFlairsMsg:
+		a.handleListUserFlairs(context, msg)
+
+	case *CreateUserFlairMsg:
+		a.handleCreateUserFlair(context, msg)
+
+	case *UpdateUserFlairMsg:
+		a.handleUpdateUserFlair(context, msg)
+
+	case *DeleteUserFlairMsg:
+		a.handleDeleteUserFlair(context, msg)
+
+	case *SetUserFlairMsg:
+		a.handleSetUserFlair(context, msg)
+
+	case *UpdateSubredditSettingsMsg:
+		a.handleUpdateSettings(context, msg)
+
+	case *SearchSubredditsMsg:
+		a.handleSearchSubreddits(context, msg)
+
+	case *ListModeratorsMsg:
+		a.handleListModerators(context, msg)
+
+	case *AddModeratorMsg:
+		a.handleAddModerator(context, msg)
+
+	case *RemoveModeratorMsg:
+		a.handleRemoveModerator(context, msg)
+
+	case *ApproveSubredditUserMsg:
+		a.handleApproveUser(context, msg)
+
+	case *BanUserMsg:
+		a.handleBanUser(context, msg)
+
+	case *UnbanUserMsg:
+		a.handleUnbanUser(context, msg)
+
+	case *ListBansMsg:
+		a.handleListBans(context, msg)
+
+	case *GetAutomodMsg:
+		a.handleGetAutomod(context, msg)
+
+	case *UpdateAutomodMsg:
+		a.handleUpdateAutomod(context, msg)
+
+	case *GetSubredditAnalyticsMsg:
+		a.handleGetSubredditAnalytics(context, msg)
+
+	case *DeleteSubredditMsg:
+		a.handleDeleteSubreddit(context, msg)
+
+	case *ReconcileMemberCountsMsg:
+		a.handleReconcileMemberCounts(context)
+
 	case *GetCountsMsg:
 		context.Respond(len(a.subredditsByName))
 	}
@@ -112,12 +524,27 @@ func (a *SubredditActor) handleCreateSubreddit(ctx actor.Context, msg *CreateSub
 	log.Printf("SubredditActor: Creating subreddit: %s", msg.Name)
 	startTime := time.Now()
 
+	if !subredditNamePattern.MatchString(msg.Name) {
+		ctx.Respond(utils.NewAppError(utils.ErrInvalidInput,
+			"subreddit name must be 3 to 21 letters, digits or underscores", nil))
+		return
+	}
+
 	// Check cache first
 	if _, exists := a.subredditsByName[msg.Name]; exists {
 		ctx.Respond(utils.NewAppError(utils.ErrDuplicate, "subreddit already exists", nil))
 		return
 	}
 
+	subredditType := msg.Type
+	if subredditType == "" {
+		subredditType = models.SubredditPublic
+	}
+	if !subredditType.Valid() {
+		ctx.Respond(utils.NewAppError(utils.ErrInvalidInput, "subreddit type must be public, restricted or private", nil))
+		return
+	}
+
 	newSubreddit := &models.Subreddit{
 		ID:          uuid.New(),
 		Name:        msg.Name,
@@ -125,21 +552,44 @@ func (a *SubredditActor) handleCreateSubreddit(ctx actor.Context, msg *CreateSub
 		CreatorID:   msg.CreatorID,
 		CreatedAt:   time.Now(),
 		Members:     1,
+		Type:        subredditType,
 	}
 
 	// Create a new context for MongoDB operations
 	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
 	defer cancel()
 
+	if !msg.AsAdmin {
+		if appErr := a.checkCanCreateSubreddit(dbCtx, msg.CreatorID); appErr != nil {
+			ctx.Respond(appErr)
+			return
+		}
+	}
+
+	// Names are unique ignoring case; the index catches races this check misses
+	taken, err := a.mongodb.SubredditNameTaken(dbCtx, msg.Name)
+	if err != nil {
+		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to check subreddit name", err))
+		return
+	}
+	if taken {
+		ctx.Respond(utils.NewAppError(utils.ErrDuplicate, "subreddit already exists", nil))
+		return
+	}
+
 	// Create the subreddit in MongoDB
-	err := a.mongodb.CreateSubreddit(dbCtx, newSubreddit)
+	err = a.mongodb.CreateSubreddit(dbCtx, newSubreddit)
 	if err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			ctx.Respond(appErr)
+			return
+		}
 		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to create subreddit", err))
 		return
 	}
 
 	// Update the creator's subreddits list
-	err = a.mongodb.UpdateUserSubreddits(dbCtx, msg.CreatorID, newSubreddit.ID, true)
+	_, err = a.mongodb.UpdateUserSubreddits(dbCtx, msg.CreatorID, newSubreddit.ID, true)
 	if err != nil {
 		log.Printf("Warning: Failed to update creator's subreddit list: %v", err)
 		// Don't fail the whole operation if this fails
@@ -178,7 +628,7 @@ func (a *SubredditActor) handleGetSubredditByID(ctx actor.Context, msg *GetSubre
 		subreddit, err = a.mongodb.GetSubredditByID(dbCtx, msg.SubredditID)
 		if err != nil {
 			log.Printf("Error fetching subreddit from MongoDB: %v", err)
-			ctx.Respond(utils.NewAppError(utils.ErrNotFound, "subreddit not found", err))
+			ctx.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrSubredditNotFound, nil, err))
 			return
 		}
 
@@ -194,7 +644,7 @@ func (a *SubredditActor) handleGetSubredditByID(ctx actor.Context, msg *GetSubre
 	}
 
 	if subreddit == nil {
-		ctx.Respond(utils.NewAppError(utils.ErrNotFound, "subreddit not found", nil))
+		ctx.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrSubredditNotFound, nil, nil))
 		return
 	}
 
@@ -202,23 +652,7 @@ func (a *SubredditActor) handleGetSubredditByID(ctx actor.Context, msg *GetSubre
 	// _, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
 	// defer cancel()
 
-	response := struct {
-		ID          string      `json:"ID"`
-		Name        string      `json:"Name"`
-		Description string      `json:"Description"`
-		CreatorID   string      `json:"CreatorID"`
-		Members     int         `json:"Members"`
-		CreatedAt   time.Time   `json:"CreatedAt"`
-		Posts       []uuid.UUID `json:"Posts"`
-	}{
-		ID:          subreddit.ID.String(),
-		Name:        subreddit.Name,
-		Description: subreddit.Description,
-		CreatorID:   subreddit.CreatorID.String(),
-		Members:     subreddit.Members, // Use the value from the model
-		CreatedAt:   subreddit.CreatedAt,
-		Posts:       subreddit.Posts,
-	}
+	response := newSubredditResponse(subreddit)
 
 	log.Printf("Successfully fetched subreddit details for ID: %s", msg.SubredditID)
 	ctx.Respond(response)
@@ -242,7 +676,7 @@ func (a *SubredditActor) handleGetSubredditByName(ctx actor.Context, msg *GetSub
 		subreddit, err = a.mongodb.GetSubredditByName(dbCtx, msg.Name)
 		if err != nil {
 			log.Printf("Error fetching subreddit from MongoDB: %v", err)
-			ctx.Respond(utils.NewAppError(utils.ErrNotFound, "subreddit not found", err))
+			ctx.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrSubredditNotFound, nil, err))
 			return
 		}
 
@@ -258,32 +692,18 @@ func (a *SubredditActor) handleGetSubredditByName(ctx actor.Context, msg *GetSub
 	}
 
 	if subreddit == nil {
-		ctx.Respond(utils.NewAppError(utils.ErrNotFound, "subreddit not found", nil))
+		ctx.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrSubredditNotFound, nil, nil))
 		return
 	}
 
-	response := struct {
-		ID          string      `json:"ID"`
-		Name        string      `json:"Name"`
-		Description string      `json:"Description"`
-		CreatorID   string      `json:"CreatorID"`
-		Members     int         `json:"Members"`
-		CreatedAt   time.Time   `json:"CreatedAt"`
-		Posts       []uuid.UUID `json:"Posts"`
-	}{
-		ID:          subreddit.ID.String(),
-		Name:        subreddit.Name,
-		Description: subreddit.Description,
-		CreatorID:   subreddit.CreatorID.String(),
-		Members:     subreddit.Members, // Use the value from the model
-		CreatedAt:   subreddit.CreatedAt,
-		Posts:       subreddit.Posts,
-	}
+	response := newSubredditResponse(subreddit)
 
 	log.Printf("Successfully fetched subreddit details for name: %s", msg.Name)
 	ctx.Respond(response)
 }
 
+// Handles a user joining a subreddit. Joining a subreddit the user is already in
+// succeeds without changing anything.
 func (a *SubredditActor) handleJoinSubreddit(ctx actor.Context, msg *JoinSubredditMsg) {
 	startTime := time.Now()
 
@@ -291,119 +711,131 @@ func (a *SubredditActor) handleJoinSubreddit(ctx actor.Context, msg *JoinSubredd
 	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
 	defer cancel()
 
-	// First verify subreddit exists and get latest data from MongoDB
-	subredditFromDB, err := a.mongodb.GetSubredditByID(dbCtx, msg.SubredditID)
-	if err != nil {
-		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to get subreddit", err))
-		return
-	}
-	if subredditFromDB == nil {
-		ctx.Respond(utils.NewAppError(utils.ErrNotFound, "subreddit not found", nil))
-		return
-	}
-
-	// Update local cache with latest data
-	a.subredditsById[msg.SubredditID] = subredditFromDB
-	a.subredditsByName[subredditFromDB.Name] = subredditFromDB
-
-	// Initialize member map if doesn't exist
-	if _, exists := a.subredditMembers[msg.SubredditID]; !exists {
-		a.subredditMembers[msg.SubredditID] = make(map[uuid.UUID]bool)
-	}
-
-	// Check if user is already a member
-	if a.subredditMembers[msg.SubredditID][msg.UserID] {
-		ctx.Respond(utils.NewAppError(utils.ErrDuplicate, "user is already a member", nil))
+	subreddit, appErr := a.loadSubreddit(dbCtx, msg.SubredditID)
+	if appErr != nil {
+		ctx.Respond(appErr)
 		return
 	}
 
-	// Update MongoDB subreddit members count
-	err = a.mongodb.UpdateSubredditMembers(dbCtx, msg.SubredditID, 1)
-	if err != nil {
-		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to update member count", err))
-		return
+	// Private subreddits admit approved users only, though members who joined before
+	// the subreddit went private can still rejoin
+	if subreddit.Type == models.SubredditPrivate {
+		canJoin, err := a.mongodb.CanViewSubreddit(dbCtx, subreddit, msg.UserID)
+		if err != nil {
+			ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to check subreddit membership", err))
+			return
+		}
+		if !canJoin {
+			ctx.Respond(utils.NewAppError(utils.ErrUnauthorized, "only approved users can join a private subreddit", nil))
+			return
+		}
 	}
 
-	// Update user's subreddits list
-	err = a.mongodb.UpdateUserSubreddits(dbCtx, msg.UserID, msg.SubredditID, true)
+	// The user's subscriptions are the record of membership, so they decide whether
+	// the member count changes
+	joined, err := a.mongodb.UpdateUserSubreddits(dbCtx, msg.UserID, msg.SubredditID, true)
 	if err != nil {
-		// Rollback the member count update
-		rollbackErr := a.mongodb.UpdateSubredditMembers(dbCtx, msg.SubredditID, -1)
-		if rollbackErr != nil {
-			log.Printf("Error rolling back member count: %v", rollbackErr)
+		if appErr, ok := err.(*utils.AppError); ok {
+			ctx.Respond(appErr)
+			return
 		}
 		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to update user's subreddit list", err))
 		return
 	}
+	if joined {
+		members, err := a.mongodb.UpdateSubredditMembers(dbCtx, msg.SubredditID, 1)
+		if err != nil {
+			// Undo the subscription so a retry counts the member
+			if _, rollbackErr := a.mongodb.UpdateUserSubreddits(dbCtx, msg.UserID, msg.SubredditID, false); rollbackErr != nil {
+				log.Printf("Error rolling back subreddit join: %v", rollbackErr)
+			}
+			ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to update member count", err))
+			return
+		}
+		subreddit.Members = members
+		log.Printf("SubredditActor: User %s joined subreddit %s", msg.UserID, msg.SubredditID)
+	}
 
 	// Update local cache
+	if _, exists := a.subredditMembers[msg.SubredditID]; !exists {
+		a.subredditMembers[msg.SubredditID] = make(map[uuid.UUID]bool)
+	}
 	a.subredditMembers[msg.SubredditID][msg.UserID] = true
-	subredditFromDB.Members++
 
-	log.Printf("SubredditActor: User %s joined subreddit %s", msg.UserID, msg.SubredditID)
 	a.metrics.AddOperationLatency("join_subreddit", time.Since(startTime))
-	ctx.Respond(true)
+	ctx.Respond(&MembershipResponse{SubredditID: subreddit.ID, IsMember: true, Members: subreddit.Members, Changed: joined})
 }
 
+// Handles a user leaving a subreddit. Leaving a subreddit the user isn't in succeeds
+// without changing anything, but creators can't leave the subreddits they own.
 func (a *SubredditActor) handleLeaveSubreddit(ctx actor.Context, msg *LeaveSubredditMsg) {
 	startTime := time.Now()
 
-	subreddit := a.subredditsById[msg.SubredditID]
-	if subreddit == nil {
-		ctx.Respond(utils.NewAppError(utils.ErrNotFound, "subreddit not found", nil))
-		return
-	}
-
-	members := a.subredditMembers[msg.SubredditID]
-	if !members[msg.UserID] {
-		ctx.Respond(utils.NewAppError(utils.ErrInvalidInput, "user is not a member", nil))
-		return
-	}
-
 	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
 	defer cancel()
 
-	// Update MongoDB subreddit members count
-	err := a.mongodb.UpdateSubredditMembers(dbCtx, msg.SubredditID, -1)
-	if err != nil {
-		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to update member count", err))
+	subreddit, appErr := a.loadSubreddit(dbCtx, msg.SubredditID)
+	if appErr != nil {
+		ctx.Respond(appErr)
+		return
+	}
+	if subreddit.CreatorID == msg.UserID {
+		ctx.Respond(utils.NewAppError(utils.ErrForbidden,
+			"The creator can't leave their subreddit without transferring ownership", nil))
 		return
 	}
 
-	// Update user's subreddits list
-	err = a.mongodb.UpdateUserSubreddits(dbCtx, msg.UserID, msg.SubredditID, false)
+	left, err := a.mongodb.UpdateUserSubreddits(dbCtx, msg.UserID, msg.SubredditID, false)
 	if err != nil {
-		log.Printf("Warning: Failed to update user's subreddit list: %v", err)
-		// Rollback the member count update
-		rollbackErr := a.mongodb.UpdateSubredditMembers(dbCtx, msg.SubredditID, 1)
-		if rollbackErr != nil {
-			log.Printf("Error rolling back member count: %v", rollbackErr)
+		if appErr, ok := err.(*utils.AppError); ok {
+			ctx.Respond(appErr)
+			return
 		}
 		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to update user's subreddit list", err))
 		return
 	}
+	if left {
+		members, err := a.mongodb.UpdateSubredditMembers(dbCtx, msg.SubredditID, -1)
+		if err != nil {
+			if _, rollbackErr := a.mongodb.UpdateUserSubreddits(dbCtx, msg.UserID, msg.SubredditID, true); rollbackErr != nil {
+				log.Printf("Error rolling back subreddit leave: %v", rollbackErr)
+			}
+			ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to update member count", err))
+			return
+		}
+		subreddit.Members = members
+	}
 
 	// Update local cache
 	delete(a.subredditMembers[msg.SubredditID], msg.UserID)
-	subreddit.Members--
 
 	a.metrics.AddOperationLatency("leave_subreddit", time.Since(startTime))
-	ctx.Respond(true)
+	ctx.Respond(&MembershipResponse{SubredditID: subreddit.ID, IsMember: false, Members: subreddit.Members, Changed: left})
+}
+
+// loadSubreddit gets the latest copy of a subreddit from MongoDB and caches it
+func (a *SubredditActor) loadSubreddit(dbCtx stdctx.Context, subredditID uuid.UUID) (*models.Subreddit, *utils.AppError) {
+	subreddit, err := a.mongodb.GetSubredditByID(dbCtx, subredditID)
+	if err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to get subreddit", err)
+	}
+	if subreddit == nil {
+		return nil, utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrSubredditNotFound, nil, nil)
+	}
+	a.subredditsById[subreddit.ID] = subreddit
+	a.subredditsByName[subreddit.Name] = subreddit
+	return subreddit, nil
 }
 
 func (a *SubredditActor) handleListSubreddits(ctx actor.Context) {
 	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
 	defer cancel()
 
-	// Get from MongoDB and update cache
+	// Get from MongoDB and update cache. The cache isn't a fallback: its member counts
+	// may be out of date.
 	subreddits, err := a.mongodb.ListSubreddits(dbCtx)
 	if err != nil {
-		// If MongoDB fails, fall back to cache
-		cachedSubreddits := make([]*models.Subreddit, 0, len(a.subredditsByName))
-		for _, sub := range a.subredditsByName {
-			cachedSubreddits = append(cachedSubreddits, sub)
-		}
-		ctx.Respond(cachedSubreddits)
+		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to list subreddits", err))
 		return
 	}
 
@@ -434,3 +866,985 @@ func (a *SubredditActor) handleGetMembers(ctx actor.Context, msg *GetSubredditMe
 	log.Printf("Found %d members for subreddit: %s", len(memberIDs), msg.SubredditID)
 	ctx.Respond(memberIDs)
 }
+
+func (a *SubredditActor) handleListFlairs(ctx actor.Context, msg *ListFlairsMsg) {
+	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+	defer cancel()
+
+	subreddit, err := a.mongodb.GetSubredditByID(dbCtx, msg.SubredditID)
+	if err != nil {
+		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to get subreddit", err))
+		return
+	}
+	if subreddit == nil {
+		ctx.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrSubredditNotFound, nil, nil))
+		return
+	}
+
+	ctx.Respond(subreddit.Flairs)
+}
+
+func (a *SubredditActor) handleCreateFlair(ctx actor.Context, msg *CreateFlairMsg) {
+	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+	defer cancel()
+
+	subreddit, appErr := a.moderatedSubreddit(dbCtx, msg.SubredditID, msg.RequesterID)
+	if appErr != nil {
+		ctx.Respond(appErr)
+		return
+	}
+
+	flair := models.PostFlair{ID: uuid.New(), Text: strings.TrimSpace(msg.Text), Color: msg.Color}
+	if appErr := validateFlair(flair); appErr != nil {
+		ctx.Respond(appErr)
+		return
+	}
+	if len(subreddit.Flairs) >= maxSubredditFlairs {
+		ctx.Respond(utils.NewAppError(utils.ErrInvalidInput,
+			fmt.Sprintf("A subreddit can have at most %d flairs", maxSubredditFlairs), nil))
+		return
+	}
+
+	if err := a.mongodb.AddSubredditFlair(dbCtx, subreddit.ID, flair); err != nil {
+		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to add flair", err))
+		return
+	}
+
+	subreddit.Flairs = append(subreddit.Flairs, flair)
+	a.cacheSubreddit(subreddit)
+	ctx.Respond(&flair)
+}
+
+func (a *SubredditActor) handleUpdateFlair(ctx actor.Context, msg *UpdateFlairMsg) {
+	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+	defer cancel()
+
+	subreddit, appErr := a.moderatedSubreddit(dbCtx, msg.SubredditID, msg.RequesterID)
+	if appErr != nil {
+		ctx.Respond(appErr)
+		return
+	}
+
+	flair := models.PostFlair{ID: msg.FlairID, Text: strings.TrimSpace(msg.Text), Color: msg.Color}
+	if appErr := validateFlair(flair); appErr != nil {
+		ctx.Respond(appErr)
+		return
+	}
+
+	if err := a.mongodb.UpdateSubredditFlair(dbCtx, subreddit.ID, flair); err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			ctx.Respond(appErr)
+			return
+		}
+		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to update flair", err))
+		return
+	}
+
+	for i := range subreddit.Flairs {
+		if subreddit.Flairs[i].ID == flair.ID {
+			subreddit.Flairs[i] = flair
+		}
+	}
+	a.cacheSubreddit(subreddit)
+	ctx.Respond(&flair)
+}
+
+func (a *SubredditActor) handleDeleteFlair(ctx actor.Context, msg *DeleteFlairMsg) {
+	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+	defer cancel()
+
+	subreddit, appErr := a.moderatedSubreddit(dbCtx, msg.SubredditID, msg.RequesterID)
+	if appErr != nil {
+		ctx.Respond(appErr)
+		return
+	}
+
+	if err := a.mongodb.DeleteSubredditFlair(dbCtx, subreddit.ID, msg.FlairID); err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			ctx.Respond(appErr)
+			return
+		}
+		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to delete flair", err))
+		return
+	}
+
+	remaining := make([]models.PostFlair, 0, len(subreddit.Flairs))
+	for _, flair := range subreddit.Flairs {
+		if flair.ID != msg.FlairID {
+			remaining = append(remaining, flair)
+		}
+	}
+	subreddit.Flairs = remaining
+	a.cacheSubreddit(subreddit)
+	ctx.Respond(true)
+}
+
+func (a *SubredditActor) handleListUserFlairs(ctx actor.Context, msg *ListUserFlairsMsg) {
+	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+	defer cancel()
+
+	subreddit, err := a.mongodb.GetSubredditByID(dbCtx, msg.SubredditID)
+	if err != nil {
+		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to get subreddit", err))
+		return
+	}
+	if subreddit == nil {
+		ctx.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrSubredditNotFound, nil, nil))
+		return
+	}
+
+	ctx.Respond(subreddit.UserFlairs)
+}
+
+func (a *SubredditActor) handleCreateUserFlair(ctx actor.Context, msg *CreateUserFlairMsg) {
+	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+	defer cancel()
+
+	subreddit, appErr := a.moderatedSubreddit(dbCtx, msg.SubredditID, msg.RequesterID)
+	if appErr != nil {
+		ctx.Respond(appErr)
+		return
+	}
+
+	template := models.UserFlairTemplate{ID: uuid.New(), Text: strings.TrimSpace(msg.Text), Color: msg.Color, ModOnly: msg.ModOnly}
+	if appErr := validateFlair(models.PostFlair{Text: template.Text, Color: template.Color}); appErr != nil {
+		ctx.Respond(appErr)
+		return
+	}
+	if len(subreddit.UserFlairs) >= maxSubredditFlairs {
+		ctx.Respond(utils.NewAppError(utils.ErrInvalidInput,
+			fmt.Sprintf("A subreddit can have at most %d user flairs", maxSubredditFlairs), nil))
+		return
+	}
+
+	if err := a.mongodb.AddUserFlairTemplate(dbCtx, subreddit.ID, template); err != nil {
+		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to add user flair", err))
+		return
+	}
+
+	subreddit.UserFlairs = append(subreddit.UserFlairs, template)
+	a.cacheSubreddit(subreddit)
+	ctx.Respond(&template)
+}
+
+func (a *SubredditActor) handleUpdateUserFlair(ctx actor.Context, msg *UpdateUserFlairMsg) {
+	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+	defer cancel()
+
+	subreddit, appErr := a.moderatedSubreddit(dbCtx, msg.SubredditID, msg.RequesterID)
+	if appErr != nil {
+		ctx.Respond(appErr)
+		return
+	}
+
+	template := models.UserFlairTemplate{ID: msg.FlairID, Text: strings.TrimSpace(msg.Text), Color: msg.Color, ModOnly: msg.ModOnly}
+	if appErr := validateFlair(models.PostFlair{Text: template.Text, Color: template.Color}); appErr != nil {
+		ctx.Respond(appErr)
+		return
+	}
+
+	if err := a.mongodb.UpdateUserFlairTemplate(dbCtx, subreddit.ID, template); err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			ctx.Respond(appErr)
+			return
+		}
+		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to update user flair", err))
+		return
+	}
+
+	for i := range subreddit.UserFlairs {
+		if subreddit.UserFlairs[i].ID == template.ID {
+			subreddit.UserFlairs[i] = template
+		}
+	}
+	a.cacheSubreddit(subreddit)
+	ctx.Respond(&template)
+}
+
+func (a *SubredditActor) handleDeleteUserFlair(ctx actor.Context, msg *DeleteUserFlairMsg) {
+	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+	defer cancel()
+
+	subreddit, appErr := a.moderatedSubreddit(dbCtx, msg.SubredditID, msg.RequesterID)
+	if appErr != nil {
+		ctx.Respond(appErr)
+		return
+	}
+
+	if err := a.mongodb.DeleteUserFlairTemplate(dbCtx, subreddit.ID, msg.FlairID); err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			ctx.Respond(appErr)
+			return
+		}
+		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to delete user flair", err))
+		return
+	}
+
+	remaining := make([]models.UserFlairTemplate, 0, len(subreddit.UserFlairs))
+	for _, template := range subreddit.UserFlairs {
+		if template.ID != msg.FlairID {
+			remaining = append(remaining, template)
+		}
+	}
+	subreddit.UserFlairs = remaining
+	a.cacheSubreddit(subreddit)
+	ctx.Respond(true)
+}
+
+// UserFlairResponse is a member's flair in a subreddit after it was set; Flair is nil once
+// cleared
+type UserFlairResponse struct {
+	SubredditID uuid.UUID         `json:"subredditId"`
+	UserID      uuid.UUID         `json:"userId"`
+	Flair       *models.UserFlair `json:"flair"`
+}
+
+// handleSetUserFlair sets the flair a member shows in the subreddit. Regular members can
+// only flair themselves, and only with templates that aren't mod-only.
+func (a *SubredditActor) handleSetUserFlair(ctx actor.Context, msg *SetUserFlairMsg) {
+	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+	defer cancel()
+
+	subreddit, err := a.mongodb.GetSubredditByID(dbCtx, msg.SubredditID)
+	if err != nil {
+		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to get subreddit", err))
+		return
+	}
+	if subreddit == nil {
+		ctx.Respond(utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrSubredditNotFound, nil, nil))
+		return
+	}
+
+	userID := msg.UserID
+	if userID == uuid.Nil {
+		userID = msg.RequesterID
+	}
+	isModerator, err := a.mongodb.IsSubredditModerator(dbCtx, subreddit.ID, msg.RequesterID)
+	if err != nil {
+		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to check moderator status", err))
+		return
+	}
+	if userID != msg.RequesterID && !isModerator {
+		ctx.Respond(utils.NewAppError(utils.ErrForbidden, "only moderators can set other members' flair", nil))
+		return
+	}
+
+	var flair *models.UserFlair
+	if msg.FlairID != nil {
+		var template *models.UserFlairTemplate
+		for i := range subreddit.UserFlairs {
+			if subreddit.UserFlairs[i].ID == *msg.FlairID {
+				template = &subreddit.UserFlairs[i]
+				break
+			}
+		}
+		if template == nil {
+			ctx.Respond(utils.NewAppError(utils.ErrInvalidInput, "flair is not one of the subreddit's user flairs", nil))
+			return
+		}
+		if template.ModOnly && !isModerator {
+			ctx.Respond(utils.NewAppError(utils.ErrForbidden, "only moderators can assign this flair", nil))
+			return
+		}
+		flair = &models.UserFlair{TemplateID: template.ID, Text: template.Text, Color: template.Color}
+	}
+
+	if err := a.mongodb.SetUserFlair(dbCtx, subreddit.ID, userID, flair); err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			ctx.Respond(appErr)
+			return
+		}
+		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to set user flair", err))
+		return
+	}
+
+	ctx.Respond(&UserFlairResponse{SubredditID: subreddit.ID, UserID: userID, Flair: flair})
+}
+
+// handleUpdateSettings lets a moderator change the subreddit's settings
+func (a *SubredditActor) handleUpdateSettings(ctx actor.Context, msg *UpdateSubredditSettingsMsg) {
+	if msg.MinKarmaToPost != nil && *msg.MinKarmaToPost < 0 {
+		ctx.Respond(utils.NewAppError(utils.ErrInvalidInput, "minimum karma to post cannot be negative", nil))
+		return
+	}
+	if msg.Type != nil && !msg.Type.Valid() {
+		ctx.Respond(utils.NewAppError(utils.ErrInvalidInput, "subreddit type must be public, restricted or private", nil))
+		return
+	}
+	if appErr := validateBranding(msg); appErr != nil {
+		ctx.Respond(appErr)
+		return
+	}
+
+	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+	defer cancel()
+
+	subreddit, appErr := a.moderatedSubreddit(dbCtx, msg.SubredditID, msg.RequesterID)
+	if appErr != nil {
+		ctx.Respond(appErr)
+		return
+	}
+	if msg.Name != "" && msg.Name != subreddit.Name {
+		ctx.Respond(utils.NewAppError(utils.ErrInvalidInput, "subreddit names cannot be changed", nil))
+		return
+	}
+
+	update := database.SubredditSettingsUpdate{
+		Description:           msg.Description,
+		PollResultsAfterClose: msg.PollResultsAfterClose,
+		MinKarmaToPost:        msg.MinKarmaToPost,
+		NSFW:                  msg.NSFW,
+		Type:                  msg.Type,
+		IconURL:               msg.IconURL,
+		BannerURL:             msg.BannerURL,
+		PrimaryColor:          msg.PrimaryColor,
+	}
+	if err := a.mongodb.UpdateSubredditSettings(dbCtx, subreddit.ID, update); err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			ctx.Respond(appErr)
+			return
+		}
+		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to update subreddit settings", err))
+		return
+	}
+
+	if msg.Description != nil {
+		subreddit.Description = *msg.Description
+	}
+	if msg.PollResultsAfterClose != nil {
+		subreddit.PollResultsAfterClose = *msg.PollResultsAfterClose
+	}
+	if msg.MinKarmaToPost != nil {
+		subreddit.MinKarmaToPost = *msg.MinKarmaToPost
+	}
+	if msg.NSFW != nil {
+		subreddit.NSFW = *msg.NSFW
+	}
+	if msg.Type != nil {
+		subreddit.Type = *msg.Type
+	}
+	if msg.IconURL != nil {
+		subreddit.IconURL = *msg.IconURL
+	}
+	if msg.BannerURL != nil {
+		subreddit.BannerURL = *msg.BannerURL
+	}
+	if msg.PrimaryColor != nil {
+		subreddit.PrimaryColor = *msg.PrimaryColor
+	}
+	a.cacheSubreddit(subreddit)
+	ctx.Respond(newSubredditResponse(subreddit))
+}
+
+// handleSearchSubreddits finds subreddits whose name starts with the query, and for
+// longer queries those whose description matches it, most members first
+func (a *SubredditActor) handleSearchSubreddits(ctx actor.Context, msg *SearchSubredditsMsg) {
+	query := strings.TrimSpace(msg.Query)
+	if len(query) < minSubredditSearchLength {
+		ctx.Respond(utils.NewAppError(utils.ErrInvalidInput,
+			fmt.Sprintf("search query must be at least %d characters", minSubredditSearchLength), nil))
+		return
+	}
+	if len(query) > maxSubredditSearchLength {
+		ctx.Respond(utils.NewAppError(utils.ErrInvalidInput,
+			fmt.Sprintf("search query must be at most %d characters", maxSubredditSearchLength), nil))
+		return
+	}
+	limit := msg.Limit
+	if limit <= 0 || limit > 25 {
+		limit = 10
+	}
+
+	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+	defer cancel()
+
+	hidden, err := a.mongodb.GetPrivateSubredditIDsHiddenFrom(dbCtx, msg.ViewerID)
+	if err != nil {
+		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to check private subreddits", err))
+		return
+	}
+	results, err := a.mongodb.SearchSubreddits(dbCtx, query, limit, len(query) >= minDescriptionSearchLength, hidden)
+	if err != nil {
+		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to search subreddits", err))
+		return
+	}
+	ctx.Respond(results)
+}
+
+// handleListModerators lists a subreddit's moderators to anyone
+func (a *SubredditActor) handleListModerators(ctx actor.Context, msg *ListModeratorsMsg) {
+	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+	defer cancel()
+
+	subreddit, appErr := a.loadSubreddit(dbCtx, msg.SubredditID)
+	if appErr != nil {
+		ctx.Respond(appErr)
+		return
+	}
+	ctx.Respond(newModeratorsResponse(subreddit))
+}
+
+// handleAddModerator lets a moderator make another user a moderator. Adding an existing
+// moderator changes nothing.
+func (a *SubredditActor) handleAddModerator(ctx actor.Context, msg *AddModeratorMsg) {
+	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+	defer cancel()
+
+	subreddit, appErr := a.moderatedSubreddit(dbCtx, msg.SubredditID, msg.RequesterID)
+	if appErr != nil {
+		ctx.Respond(appErr)
+		return
+	}
+	if subreddit.IsModerator(msg.UserID) {
+		ctx.Respond(newModeratorsResponse(subreddit))
+		return
+	}
+	if _, err := a.mongodb.GetUser(dbCtx, msg.UserID); err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			ctx.Respond(appErr)
+			return
+		}
+		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to get user", err))
+		return
+	}
+
+	moderator := models.Moderator{UserID: msg.UserID, AddedAt: time.Now(), AddedBy: msg.RequesterID}
+	added, err := a.mongodb.AddSubredditModerator(dbCtx, subreddit.ID, moderator)
+	if err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			ctx.Respond(appErr)
+			return
+		}
+		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to add moderator", err))
+		return
+	}
+	if added {
+		subreddit.Moderators = append(subreddit.Moderators, moderator)
+		a.cacheSubreddit(subreddit)
+		log.Printf("SubredditActor: User %s made a moderator of subreddit %s", msg.UserID, subreddit.Name)
+	}
+	ctx.Respond(newModeratorsResponse(subreddit))
+}
+
+// handleRemoveModerator lets a moderator take another moderator, or themselves, off the
+// list. The creator can't be removed; removing a user who isn't a moderator changes nothing.
+func (a *SubredditActor) handleRemoveModerator(ctx actor.Context, msg *RemoveModeratorMsg) {
+	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+	defer cancel()
+
+	subreddit, appErr := a.moderatedSubreddit(dbCtx, msg.SubredditID, msg.RequesterID)
+	if appErr != nil {
+		ctx.Respond(appErr)
+		return
+	}
+	if msg.UserID == subreddit.CreatorID {
+		ctx.Respond(utils.NewAppError(utils.ErrForbidden, "the creator cannot be removed as a moderator", nil))
+		return
+	}
+
+	removed, err := a.mongodb.RemoveSubredditModerator(dbCtx, subreddit.ID, msg.UserID)
+	if err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			ctx.Respond(appErr)
+			return
+		}
+		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to remove moderator", err))
+		return
+	}
+	if removed {
+		remaining := make([]models.Moderator, 0, len(subreddit.Moderators))
+		for _, moderator := range subreddit.Moderators {
+			if moderator.UserID != msg.UserID {
+				remaining = append(remaining, moderator)
+			}
+		}
+		subreddit.Moderators = remaining
+		a.cacheSubreddit(subreddit)
+		log.Printf("SubredditActor: User %s removed as a moderator of subreddit %s", msg.UserID, subreddit.Name)
+	}
+	ctx.Respond(newModeratorsResponse(subreddit))
+}
+
+// handleApproveUser lets a moderator approve a user, who can then post in the subreddit
+// if it's restricted and join it if it's private. Approving a user twice is harmless.
+func (a *SubredditActor) handleApproveUser(ctx actor.Context, msg *ApproveSubredditUserMsg) {
+	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+	defer cancel()
+
+	subreddit, appErr := a.moderatedSubreddit(dbCtx, msg.SubredditID, msg.RequesterID)
+	if appErr != nil {
+		ctx.Respond(appErr)
+		return
+	}
+	if _, err := a.mongodb.GetUser(dbCtx, msg.UserID); err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			ctx.Respond(appErr)
+			return
+		}
+		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to get user", err))
+		return
+	}
+
+	approved, err := a.mongodb.ApproveSubredditUser(dbCtx, subreddit.ID, msg.UserID)
+	if err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			ctx.Respond(appErr)
+			return
+		}
+		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to approve user", err))
+		return
+	}
+	if approved {
+		subreddit.ApprovedUsers = append(subreddit.ApprovedUsers, msg.UserID)
+		a.cacheSubreddit(subreddit)
+		log.Printf("SubredditActor: User %s approved in subreddit %s", msg.UserID, subreddit.Name)
+	}
+	ctx.Respond(&ApprovalResponse{SubredditID: subreddit.ID, UserID: msg.UserID, Approved: true})
+}
+
+// handleBanUser lets a moderator ban a user from posting and commenting in the
+// subreddit, for good or for a while. Moderators can't be banned.
+func (a *SubredditActor) handleBanUser(ctx actor.Context, msg *BanUserMsg) {
+	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+	defer cancel()
+
+	reason := strings.TrimSpace(msg.Reason)
+	if reason == "" {
+		ctx.Respond(utils.NewAppError(utils.ErrInvalidInput, "a ban needs a reason", nil))
+		return
+	}
+	if len(reason) > maxBanReasonLength {
+		ctx.Respond(utils.NewAppError(utils.ErrInvalidInput,
+			fmt.Sprintf("ban reason must be at most %d characters", maxBanReasonLength), nil))
+		return
+	}
+	if msg.Duration < 0 {
+		ctx.Respond(utils.NewAppError(utils.ErrInvalidInput, "ban duration cannot be negative", nil))
+		return
+	}
+
+	subreddit, appErr := a.moderatedSubreddit(dbCtx, msg.SubredditID, msg.RequesterID)
+	if appErr != nil {
+		ctx.Respond(appErr)
+		return
+	}
+	if subreddit.IsModerator(msg.UserID) {
+		ctx.Respond(utils.NewAppError(utils.ErrForbidden, "moderators cannot be banned", nil))
+		return
+	}
+	if _, err := a.mongodb.GetUser(dbCtx, msg.UserID); err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			ctx.Respond(appErr)
+			return
+		}
+		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to get user", err))
+		return
+	}
+
+	now := time.Now()
+	ban := &models.SubredditBan{
+		SubredditID: subreddit.ID,
+		UserID:      msg.UserID,
+		BannedBy:    msg.RequesterID,
+		Reason:      reason,
+		BannedAt:    now,
+	}
+	if msg.Duration > 0 {
+		expiresAt := now.Add(msg.Duration)
+		ban.ExpiresAt = &expiresAt
+	}
+	if err := a.mongodb.BanSubredditUser(dbCtx, ban); err != nil {
+		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to ban user", err))
+		return
+	}
+
+	log.Printf("SubredditActor: User %s banned from subreddit %s by %s", msg.UserID, subreddit.Name, msg.RequesterID)
+	ctx.Respond(ban)
+}
+
+// handleUnbanUser lets a moderator lift a user's ban. Unbanning a user who isn't banned
+// changes nothing.
+func (a *SubredditActor) handleUnbanUser(ctx actor.Context, msg *UnbanUserMsg) {
+	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+	defer cancel()
+
+	subreddit, appErr := a.moderatedSubreddit(dbCtx, msg.SubredditID, msg.RequesterID)
+	if appErr != nil {
+		ctx.Respond(appErr)
+		return
+	}
+
+	unbanned, err := a.mongodb.UnbanSubredditUser(dbCtx, subreddit.ID, msg.UserID)
+	if err != nil {
+		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to unban user", err))
+		return
+	}
+	if unbanned {
+		log.Printf("SubredditActor: User %s unbanned from subreddit %s by %s", msg.UserID, subreddit.Name, msg.RequesterID)
+	}
+	ctx.Respond(&UnbanResponse{SubredditID: subreddit.ID, UserID: msg.UserID, Banned: false})
+}
+
+// handleListBans lists a subreddit's current bans to its moderators. Expired bans are
+// left out even before the TTL index removes them.
+func (a *SubredditActor) handleListBans(ctx actor.Context, msg *ListBansMsg) {
+	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+	defer cancel()
+
+	subreddit, appErr := a.moderatedSubreddit(dbCtx, msg.SubredditID, msg.RequesterID)
+	if appErr != nil {
+		ctx.Respond(appErr)
+		return
+	}
+
+	limit := msg.Limit
+	if limit <= 0 || limit > 100 {
+		limit = 25
+	}
+	bans, nextCursor, err := a.mongodb.GetSubredditBans(dbCtx, subreddit.ID, time.Now(), limit, msg.Cursor)
+	if err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			ctx.Respond(appErr)
+			return
+		}
+		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to get subreddit bans", err))
+		return
+	}
+	ctx.Respond(&types.PaginatedResponse{Items: bans, NextCursor: nextCursor})
+}
+
+func (a *SubredditActor) handleGetAutomod(ctx actor.Context, msg *GetAutomodMsg) {
+	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+	defer cancel()
+
+	subreddit, appErr := a.moderatedSubreddit(dbCtx, msg.SubredditID, msg.RequesterID)
+	if appErr != nil {
+		ctx.Respond(appErr)
+		return
+	}
+	ctx.Respond(newAutomodResponse(subreddit))
+}
+
+func (a *SubredditActor) handleUpdateAutomod(ctx actor.Context, msg *UpdateAutomodMsg) {
+	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 5*time.Second)
+	defer cancel()
+
+	subreddit, appErr := a.moderatedSubreddit(dbCtx, msg.SubredditID, msg.RequesterID)
+	if appErr != nil {
+		ctx.Respond(appErr)
+		return
+	}
+
+	rules, appErr := validateAutomodRules(msg.Rules)
+	if appErr != nil {
+		ctx.Respond(appErr)
+		return
+	}
+	if err := a.mongodb.SetSubredditAutomod(dbCtx, subreddit.ID, rules); err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			ctx.Respond(appErr)
+			return
+		}
+		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to update automod rules", err))
+		return
+	}
+
+	subreddit.Automod = rules
+	a.cacheSubreddit(subreddit)
+	log.Printf("SubredditActor: %s set %d automod rules in subreddit %s", msg.RequesterID, len(rules), subreddit.ID)
+	ctx.Respond(newAutomodResponse(subreddit))
+}
+
+// handleGetSubredditAnalytics reports a subreddit's recent activity to its moderators.
+// Reports are cached for a few minutes since the aggregations scan the whole period.
+func (a *SubredditActor) handleGetSubredditAnalytics(ctx actor.Context, msg *GetSubredditAnalyticsMsg) {
+	days := msg.Days
+	if days == 0 {
+		days = defaultAnalyticsDays
+	}
+	if days < 1 || days > maxAnalyticsDays {
+		ctx.Respond(utils.NewAppError(utils.ErrInvalidInput, fmt.Sprintf("days must be between 1 and %d", maxAnalyticsDays), nil))
+		return
+	}
+
+	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 10*time.Second)
+	defer cancel()
+
+	subreddit, appErr := a.moderatedSubreddit(dbCtx, msg.SubredditID, msg.RequesterID)
+	if appErr != nil {
+		ctx.Respond(appErr)
+		return
+	}
+
+	now := time.Now()
+	key := subredditAnalyticsKey{subredditID: subreddit.ID, days: days}
+	if report, ok := a.analytics[key]; ok && now.Sub(report.GeneratedAt) < subredditAnalyticsTTL {
+		ctx.Respond(report)
+		return
+	}
+
+	report, err := a.mongodb.GetSubredditAnalytics(dbCtx, subreddit.ID, now, days, analyticsTopPosts)
+	if err != nil {
+		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to get subreddit analytics", err))
+		return
+	}
+
+	for cachedKey, cached := range a.analytics {
+		if now.Sub(cached.GeneratedAt) >= subredditAnalyticsTTL {
+			delete(a.analytics, cachedKey)
+		}
+	}
+	a.analytics[key] = report
+	ctx.Respond(report)
+}
+
+// handleReconcileMemberCounts corrects member counts that drifted from subscriptions and
+// updates the cached copies of the subreddits it corrected
+func (a *SubredditActor) handleReconcileMemberCounts(ctx actor.Context) {
+	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), MemberReconcileTimeout)
+	defer cancel()
+
+	corrections, err := a.mongodb.ReconcileSubredditMemberCounts(dbCtx)
+	for _, correction := range corrections {
+		subredditID, parseErr := uuid.Parse(correction.SubredditID)
+		if parseErr != nil {
+			continue
+		}
+		if cached, ok := a.subredditsById[subredditID]; ok {
+			cached.Members = correction.Now
+		}
+		log.Printf("SubredditActor: Corrected member count of subreddit %s from %d to %d",
+			correction.Name, correction.Was, correction.Now)
+	}
+	if err != nil {
+		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to reconcile member counts", err))
+		return
+	}
+	ctx.Respond(corrections)
+}
+
+// handleDeleteSubreddit deletes a subreddit for its creator or an administrator. Posts
+// and comments are soft-deleted and members unsubscribed before the subreddit itself is
+// marked deleted, so a deletion that fails partway can simply be retried.
+func (a *SubredditActor) handleDeleteSubreddit(ctx actor.Context, msg *DeleteSubredditMsg) {
+	dbCtx, cancel := stdctx.WithTimeout(stdctx.Background(), SubredditDeleteTimeout)
+	defer cancel()
+
+	subreddit, appErr := a.loadSubreddit(dbCtx, msg.SubredditID)
+	if appErr != nil {
+		ctx.Respond(appErr)
+		return
+	}
+	if subreddit.CreatorID != msg.RequesterID && !msg.AsAdmin {
+		ctx.Respond(utils.NewAppError(utils.ErrForbidden, "only the creator or an administrator can delete the subreddit", nil))
+		return
+	}
+	if msg.ConfirmName != subreddit.Name {
+		ctx.Respond(utils.NewAppError(utils.ErrInvalidInput, "confirmName must match the subreddit's name", nil))
+		return
+	}
+
+	if msg.DryRun {
+		ctx.Respond(&SubredditDeletion{
+			SubredditID: subreddit.ID.String(),
+			Name:        subreddit.Name,
+			DryRun:      true,
+			Posts:       len(subreddit.Posts),
+			Members:     subreddit.Members,
+		})
+		return
+	}
+
+	deletedAt := time.Now().UTC().Truncate(time.Millisecond)
+	postIDs, err := a.mongodb.SoftDeleteSubredditPosts(dbCtx, subreddit.ID, msg.RequesterID, deletedAt)
+	if err != nil {
+		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to delete subreddit posts", err))
+		return
+	}
+	members, err := a.mongodb.RemoveSubredditFromUsers(dbCtx, subreddit.ID)
+	if err != nil {
+		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to unsubscribe subreddit members", err))
+		return
+	}
+	if err := a.mongodb.MarkSubredditDeleted(dbCtx, subreddit.ID, msg.RequesterID, deletedAt); err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			ctx.Respond(appErr)
+			return
+		}
+		ctx.Respond(utils.NewAppError(utils.ErrDatabase, "failed to delete subreddit", err))
+		return
+	}
+
+	delete(a.subredditsByName, subreddit.Name)
+	delete(a.subredditsById, subreddit.ID)
+	delete(a.subredditMembers, subreddit.ID)
+
+	log.Printf("Deleted subreddit %s with %d posts", subreddit.Name, len(postIDs))
+	ctx.Respond(&SubredditDeletion{
+		SubredditID: subreddit.ID.String(),
+		Name:        subreddit.Name,
+		Posts:       len(postIDs),
+		Members:     int(members),
+		PostIDs:     postIDs,
+	})
+}
+
+// moderatedSubreddit loads a subreddit for a change only its moderators may make
+func (a *SubredditActor) moderatedSubreddit(dbCtx stdctx.Context, subredditID, requesterID uuid.UUID) (*models.Subreddit, *utils.AppError) {
+	subreddit, err := a.mongodb.GetSubredditByID(dbCtx, subredditID)
+	if err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to get subreddit", err)
+	}
+	if subreddit == nil {
+		return nil, utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrSubredditNotFound, nil, nil)
+	}
+
+	isModerator, err := a.mongodb.IsSubredditModerator(dbCtx, subredditID, requesterID)
+	if err != nil {
+		return nil, utils.NewAppError(utils.ErrDatabase, "failed to check moderator status", err)
+	}
+	if !isModerator {
+		return nil, utils.NewAppError(utils.ErrForbidden, "only moderators can manage the subreddit", nil)
+	}
+	return subreddit, nil
+}
+
+// checkCanCreateSubreddit rejects a new subreddit from a user whose account is too new,
+// has too little karma or, when required, an unverified email, or who has created as
+// many subreddits as a day allows
+func (a *SubredditActor) checkCanCreateSubreddit(dbCtx stdctx.Context, creatorID uuid.UUID) *utils.AppError {
+	creator, err := a.mongodb.GetUser(dbCtx, creatorID)
+	if err != nil {
+		if utils.IsErrorCode(err, utils.ErrUserNotFound) {
+			return utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrUserNotFound, nil, nil)
+		}
+		return utils.NewAppError(utils.ErrDatabase, "failed to get user", err)
+	}
+
+	now := time.Now()
+	rules := a.creationRules
+	if rules.RequireVerifiedEmail && !creator.EmailVerified {
+		return utils.NewAppError(utils.ErrUnauthorized, "Creating a subreddit requires a verified email address", nil)
+	}
+	if now.Sub(creator.CreatedAt) < rules.MinAccountAge {
+		return utils.NewAppError(utils.ErrUnauthorized, fmt.Sprintf(
+			"Creating a subreddit requires an account at least %d days old", int(rules.MinAccountAge.Hours()/24)), nil)
+	}
+	if creator.Karma < rules.MinKarma {
+		return utils.NewAppError(utils.ErrUnauthorized, fmt.Sprintf(
+			"Creating a subreddit requires %d karma; you have %d", rules.MinKarma, creator.Karma), nil)
+	}
+
+	if rules.MaxPerDay <= 0 {
+		return nil
+	}
+	created, err := a.mongodb.GetSubredditCreationTimes(dbCtx, creatorID, now.Add(-24*time.Hour))
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "failed to count created subreddits", err)
+	}
+	if len(created) < rules.MaxPerDay {
+		return nil
+	}
+	// Another can be created once enough of the day's subreddits are over a day old
+	wait := created[len(created)-rules.MaxPerDay].Add(24 * time.Hour).Sub(now)
+	return utils.NewLocalizedError(utils.ErrTooManyRequests, i18n.ErrSubredditRateLimited, map[string]string{
+		"limit":      strconv.Itoa(rules.MaxPerDay),
+		"retryAfter": strconv.Itoa(int(math.Ceil(wait.Seconds()))),
+	}, nil)
+}
+
+// checkSubredditBan rejects a post or comment in a subreddit from a user banned from it,
+// giving the moderators' reason. Bans that have expired are ignored.
+func checkSubredditBan(dbCtx stdctx.Context, mongodb *database.MongoDB, subredditID, userID uuid.UUID) *utils.AppError {
+	ban, err := mongodb.GetActiveSubredditBan(dbCtx, subredditID, userID, time.Now())
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "Failed to check subreddit ban", err)
+	}
+	if ban == nil {
+		return nil
+	}
+	if ban.ExpiresAt != nil {
+		return utils.NewAppError(utils.ErrUnauthorized, fmt.Sprintf(
+			"You are banned from this subreddit until %s: %s", ban.ExpiresAt.UTC().Format(time.RFC3339), ban.Reason), nil)
+	}
+	return utils.NewAppError(utils.ErrUnauthorized, "You are banned from this subreddit: "+ban.Reason, nil)
+}
+
+// checkCanViewSubreddit rejects a viewer who may not read the subreddit's posts and
+// comments, as a non-member of a private subreddit
+func checkCanViewSubreddit(dbCtx stdctx.Context, mongodb *database.MongoDB, subredditID, viewerID uuid.UUID) *utils.AppError {
+	subreddit, err := mongodb.GetSubredditByID(dbCtx, subredditID)
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "Failed to get subreddit", err)
+	}
+	if subreddit == nil {
+		return utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrSubredditNotFound, nil, nil)
+	}
+	canView, err := mongodb.CanViewSubreddit(dbCtx, subreddit, viewerID)
+	if err != nil {
+		return utils.NewAppError(utils.ErrDatabase, "Failed to check subreddit membership", err)
+	}
+	if !canView {
+		return utils.NewAppError(utils.ErrUnauthorized, fmt.Sprintf(
+			"r/%s is private; only its members can read it", subreddit.Name), nil)
+	}
+	return nil
+}
+
+// hiddenSubreddits returns the set of private subreddits whose posts and comments the
+// viewer may not read
+func hiddenSubreddits(dbCtx stdctx.Context, mongodb *database.MongoDB, viewerID uuid.UUID) (map[uuid.UUID]bool, error) {
+	ids, err := mongodb.GetPrivateSubredditIDsHiddenFrom(dbCtx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	hidden := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		if subredditID, err := uuid.Parse(id); err == nil {
+			hidden[subredditID] = true
+		}
+	}
+	return hidden, nil
+}
+
+// cacheSubreddit replaces the cached copy of a subreddit
+func (a *SubredditActor) cacheSubreddit(subreddit *models.Subreddit) {
+	a.subredditsByName[subreddit.Name] = subreddit
+	a.subredditsById[subreddit.ID] = subreddit
+}
+
+// validateBranding checks the icon and banner URLs and primary color of a settings
+// update. Empty values are allowed, and remove the setting.
+func validateBranding(msg *UpdateSubredditSettingsMsg) *utils.AppError {
+	for _, setting := range []struct {
+		name string
+		url  *string
+	}{{"icon", msg.IconURL}, {"banner", msg.BannerURL}} {
+		if setting.url == nil || *setting.url == "" {
+			continue
+		}
+		if len(*setting.url) > maxImageURLLength || !isWebURL(*setting.url) {
+			return utils.NewAppError(utils.ErrInvalidInput,
+				fmt.Sprintf("%s URL must be an http or https URL of at most %d characters", setting.name, maxImageURLLength), nil)
+		}
+	}
+	if msg.PrimaryColor != nil && *msg.PrimaryColor != "" && !flairColorPattern.MatchString(*msg.PrimaryColor) {
+		return utils.NewAppError(utils.ErrInvalidInput, "primary color must look like #ff4500", nil)
+	}
+	return nil
+}
+
+// validateFlair checks a flair template's text and color
+func validateFlair(flair models.PostFlair) *utils.AppError {
+	if flair.Text == "" {
+		return utils.NewAppError(utils.ErrInvalidInput, "flair text is required", nil)
+	}
+	if len(flair.Text) > maxFlairTextLength {
+		return utils.NewAppError(utils.ErrInvalidInput,
+			fmt.Sprintf("flair text can be at most %d characters", maxFlairTextLength), nil)
+	}
+	if flair.Color != "" && !flairColorPattern.MatchString(flair.Color) {
+		return utils.NewAppError(utils.ErrInvalidInput, "flair color must look like #ff4500", nil)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package actors
+
+import (
+	stdctx "context"
+	"fmt"
+	"gator-swamp/internal/config"
+	"gator-swamp/internal/database/dbtest"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/google/uuid"
+)
+
+// Two subreddit actors share the database the way two engine processes would, and every
+// user joins through both at once. Some then leave while the rest are still joining.
+func TestConcurrentJoinsKeepMemberCountInStep(t *testing.T) {
+	mongodb := dbtest.New(t)
+	ctx := stdctx.Background()
+	system := actor.NewActorSystem()
+	t.Cleanup(system.Shutdown)
+
+	subreddit := &models.Subreddit{
+		ID:        uuid.New(),
+		Name:      "swamp",
+		CreatorID: uuid.New(),
+		CreatedAt: time.Now(),
+		Type:      models.SubredditPublic,
+	}
+	if err := mongodb.CreateSubreddit(ctx, subreddit); err != nil {
+		t.Fatalf("CreateSubreddit: %v", err)
+	}
+
+	const users, leavers = 20, 5
+	userIDs := make([]uuid.UUID, users)
+	for i := range userIDs {
+		user := &models.User{
+			ID:        uuid.New(),
+			Username:  fmt.Sprintf("member%d", i),
+			Email:     fmt.Sprintf("member%d@example.com", i),
+			CreatedAt: time.Now(),
+		}
+		if err := mongodb.SaveUser(ctx, user); err != nil {
+			t.Fatalf("SaveUser: %v", err)
+		}
+		userIDs[i] = user.ID
+	}
+
+	pids := make([]*actor.PID, 2)
+	for i := range pids {
+		pids[i] = system.Root.Spawn(actor.PropsFromProducer(func() actor.Actor {
+			return NewSubredditActor(utils.NewMetricsCollector(), mongodb, config.SubredditCreationRules{})
+		}))
+	}
+
+	send := func(pid *actor.PID, msg interface{}) error {
+		result, err := system.Root.RequestFuture(pid, msg, 10*time.Second).Result()
+		if err != nil {
+			return err
+		}
+		if appErr, ok := result.(*utils.AppError); ok {
+			return appErr
+		}
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, users*3)
+	for i, userID := range userIDs {
+		for _, pid := range pids {
+			wg.Add(1)
+			go func(pid *actor.PID, userID uuid.UUID) {
+				defer wg.Done()
+				errs <- send(pid, &JoinSubredditMsg{SubredditID: subreddit.ID, UserID: userID})
+			}(pid, userID)
+		}
+		if i < leavers {
+			wg.Add(1)
+			go func(userID uuid.UUID) {
+				defer wg.Done()
+				// Leave once this user's joins are in, through whichever actor
+				if err := send(pids[0], &JoinSubredditMsg{SubredditID: subreddit.ID, UserID: userID}); err != nil {
+					errs <- err
+					return
+				}
+				errs <- send(pids[1], &LeaveSubredditMsg{SubredditID: subreddit.ID, UserID: userID})
+			}(userID)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("join or leave failed: %v", err)
+		}
+	}
+
+	members, err := mongodb.GetSubredditMembers(ctx, subreddit.ID)
+	if err != nil {
+		t.Fatalf("GetSubredditMembers: %v", err)
+	}
+	if len(members) != users-leavers {
+		t.Fatalf("%d users are subscribed, want %d", len(members), users-leavers)
+	}
+	stored, err := mongodb.GetSubredditByID(ctx, subreddit.ID)
+	if err != nil {
+		t.Fatalf("GetSubredditByID: %v", err)
+	}
+	if stored.Members != len(members) {
+		t.Fatalf("member count = %d, but %d users are subscribed", stored.Members, len(members))
+	}
+
+	corrections, err := mongodb.ReconcileSubredditMemberCounts(ctx)
+	if err != nil {
+		t.Fatalf("ReconcileSubredditMemberCounts: %v", err)
+	}
+	for _, correction := range corrections {
+		if correction.SubredditID == subreddit.ID.String() {
+			t.Fatalf("recount corrected the member count from %d to %d", correction.Was, correction.Now)
+		}
+	}
+}
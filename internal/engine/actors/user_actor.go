@@ -2,10 +2,14 @@ package actors
 
 import (
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/base64"
+	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	stdctx "context"
 
@@ -13,6 +17,7 @@ import (
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 
+	"gator-swamp/internal/config"
 	"gator-swamp/internal/database"
 	"gator-swamp/internal/models"
 	"gator-swamp/internal/types"
@@ -26,14 +31,29 @@ type UserSupervisor struct {
 	emailToID  map[string]uuid.UUID     // Maps emails to user IDs for quick lookup
 	mu         sync.RWMutex             // Manages concurrent access to maps
 	mongodb    *database.MongoDB
+
+	duplicateAccountAction string // What to do when a registration matches a banned account
+	passwordCost           int    // bcrypt cost of new password hashes
 }
 
+// recentAbuseWindow is how long an account deleted for abuse blocks re-registration
+const recentAbuseWindow = 90 * 24 * time.Hour
+
+// Limits on the profile details users set
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 500
+	maxAvatarURLLength   = 2048
+)
+
 // NewUserSupervisor initializes a new UserSupervisor with MongoDB connection.
-func NewUserSupervisor(mongodb *database.MongoDB) actor.Actor {
+func NewUserSupervisor(mongodb *database.MongoDB, duplicateAccountAction string, passwordCost int) actor.Actor {
 	return &UserSupervisor{
-		userActors: make(map[uuid.UUID]*actor.PID),
-		emailToID:  make(map[string]uuid.UUID),
-		mongodb:    mongodb,
+		userActors:             make(map[uuid.UUID]*actor.PID),
+		emailToID:              make(map[string]uuid.UUID),
+		mongodb:                mongodb,
+		duplicateAccountAction: duplicateAccountAction,
+		passwordCost:           passwordCost,
 	}
 }
 
@@ -43,24 +63,117 @@ type (
 		Username string
 		Email    string
 		Password string
-		Karma    int
+
+		FlaggedForReview bool // Set by the supervisor when the email matches a banned account
 	}
 
+	// UpdateProfileMsg changes a user's profile details. Nil fields are left as they are,
+	// and empty ones are cleared.
 	UpdateProfileMsg struct {
 		UserID      uuid.UUID
-		NewUsername string
-		NewEmail    string
+		RequesterID uuid.UUID // Only users themselves can update their profile
+		DisplayName *string
+		Bio         *string
+		AvatarURL   *string
 	}
 
-	UpdateKarmaMsg struct {
+	// GetPreferencesMsg requests a user's preferences, with defaults for anything unset
+	GetPreferencesMsg struct {
+		UserID uuid.UUID
+	}
+
+	// SetPreferencesMsg replaces a user's preferences. Fields left at their zero value
+	// go back to the defaults.
+	SetPreferencesMsg struct {
+		UserID      uuid.UUID
+		Preferences models.UserPreferences
+	}
+
+	// ChangePasswordMsg replaces a user's password once the current one is confirmed.
+	// NewPassword must already have passed the strength rules.
+	ChangePasswordMsg struct {
+		UserID          uuid.UUID
+		CurrentPassword string
+		NewPassword     string
+	}
+
+	// ChangeEmailMsg moves a user to a new email address once their password is
+	// confirmed. The new address starts out unverified. Email must already be valid.
+	ChangeEmailMsg struct {
+		UserID          uuid.UUID
+		CurrentPassword string
+		Email           string
+	}
+
+	// ResetPasswordMsg replaces a user's password without the current one, once they
+	// have redeemed a password reset token. NewPassword must already have passed the
+	// strength rules.
+	ResetPasswordMsg struct {
+		UserID      uuid.UUID
+		NewPassword string
+	}
+
+	// FollowUserMsg makes a user follow another. Following again is a no-op.
+	FollowUserMsg struct {
+		FollowerID uuid.UUID
+		FollowedID uuid.UUID
+	}
+
+	// UnfollowUserMsg stops a user following another
+	UnfollowUserMsg struct {
+		FollowerID uuid.UUID
+		FollowedID uuid.UUID
+	}
+
+	// GetFollowingMsg requests a page of the users a user follows, most recent first
+	GetFollowingMsg struct {
+		UserID uuid.UUID
+		Limit  int
+		Cursor string
+	}
+
+	// GetFollowersMsg requests a page of a user's followers, most recent first
+	GetFollowersMsg struct {
+		UserID uuid.UUID
+		Limit  int
+		Cursor string
+	}
+
+	// BlockUserMsg makes a user block another. Blocking again is a no-op.
+	BlockUserMsg struct {
+		BlockerID uuid.UUID
+		BlockedID uuid.UUID
+	}
+
+	// UnblockUserMsg lifts a block
+	UnblockUserMsg struct {
+		BlockerID uuid.UUID
+		BlockedID uuid.UUID
+	}
+
+	// GetBlockedUsersMsg requests a page of the users a user has blocked, most recent first
+	GetBlockedUsersMsg struct {
 		UserID uuid.UUID
-		Delta  int
+		Limit  int
+		Cursor string
+	}
+
+	UpdateKarmaMsg struct {
+		UserID    uuid.UUID
+		Delta     int
+		Source    string // KarmaSourcePost or KarmaSourceComment
+		Persisted bool   // MongoDB already has the change; only the user's actor is updated
 	}
 
 	GetUserProfileMsg struct {
 		UserID uuid.UUID
 	}
 
+	// GetUserByUsernameMsg looks up a profile by username, ignoring case
+	GetUserByUsernameMsg struct {
+		Username string
+	}
+
 	LoginMsg struct {
 		Email    string
 		Password string
@@ -92,20 +205,36 @@ type (
 	}
 )
 
+// Where karma in an UpdateKarmaMsg was earned
+const (
+	KarmaSourcePost    = "post"
+	KarmaSourceComment = "comment"
+)
+
 // UserState represents the internal state of a user maintained by its actor.
 type UserState struct {
 	ID             uuid.UUID
 	Username       string
 	Email          string
-	Karma          int
+	EmailVerified  bool
+	Karma          int // Total of PostKarma and CommentKarma
+	PostKarma      int
+	CommentKarma   int
+	CreatedAt      time.Time
+	FollowerCount  int64
+	FollowingCount int64
 	IsConnected    bool
 	LastActive     time.Time
+	IsAdmin        bool
 	Posts          []uuid.UUID
 	Comments       []uuid.UUID
 	HashedPassword string
 	AuthToken      string
 	Subreddits     []uuid.UUID
 	SubredditNames []string // New field
+	DisplayName    string
+	Bio            string
+	AvatarURL      string
 	VotedPosts     map[uuid.UUID]bool
 	VotedComments  map[uuid.UUID]bool
 }
@@ -120,19 +249,30 @@ func (s *UserSupervisor) Receive(context actor.Context) {
 		s.mu.Lock()
 		defer s.mu.Unlock()
 
-		// Check if the email is already registered in MongoDB
+		// Catch ban evasion through aliases of an address that was banned or deleted for abuse
 		ctx := stdctx.Background()
-		existingUser, _ := s.mongodb.GetUserByEmail(ctx, msg.Email)
-		if existingUser != nil {
-			log.Printf("Email already exists in MongoDB: %s", msg.Email)
-			context.Respond(utils.NewAppError(utils.ErrDuplicate, "Email already registered", nil))
+		related, err := s.mongodb.GetAccountsByNormalizedEmail(ctx, utils.NormalizeEmail(msg.Email))
+		if err != nil {
+			context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to check email", err))
 			return
 		}
+		if hasAbusiveAccount(related) {
+			if s.duplicateAccountAction == config.DuplicateAccountFlag {
+				log.Printf("Flagging registration of %s for review: matches a banned account", msg.Email)
+				msg.FlaggedForReview = true
+			} else {
+				log.Printf("Rejecting registration of %s: matches a banned account", msg.Email)
+				context.Respond(utils.NewAppError(utils.ErrDuplicate, "Email already registered", nil))
+				return
+			}
+		}
 
-		// Create a new user actor for this user
+		// Create a new user actor for this user. Emails and usernames that are taken are
+		// rejected by unique indexes when it saves the user, so concurrent registrations
+		// can't both get through.
 		userID := uuid.New()
 		props := actor.PropsFromProducer(func() actor.Actor {
-			return NewUserActor(userID, msg, s.mongodb)
+			return NewUserActor(userID, msg, s.mongodb, s.passwordCost)
 		})
 
 		pid := context.Spawn(props)
@@ -147,6 +287,14 @@ func (s *UserSupervisor) Receive(context actor.Context) {
 			context.Respond(utils.NewAppError(utils.ErrActorTimeout, "User creation failed", err))
 			return
 		}
+		if _, failed := result.(*utils.AppError); failed {
+			// The user wasn't saved, so drop the actor spawned for it
+			context.Stop(pid)
+			delete(s.userActors, userID)
+			if s.emailToID[msg.Email] == userID {
+				delete(s.emailToID, msg.Email)
+			}
+		}
 		context.Respond(result)
 
 	// Handle login requests
@@ -177,8 +325,7 @@ func (s *UserSupervisor) Receive(context actor.Context) {
 					Username: user.Username,
 					Email:    user.Email,
 					Password: "", // Actual password is from MongoDB
-					Karma:    user.Karma,
-				}, s.mongodb)
+				}, s.mongodb, s.passwordCost)
 			})
 			pid = context.Spawn(props)
 
@@ -205,8 +352,7 @@ func (s *UserSupervisor) Receive(context actor.Context) {
 
 		// Handle user profile retrieval
 	case *GetUserProfileMsg:
-		ctx := stdctx.Background()
-		user, err := s.mongodb.GetUser(ctx, msg.UserID)
+		profile, err := s.loadProfile(stdctx.Background(), msg.UserID)
 		if err != nil {
 			if utils.IsErrorCode(err, utils.ErrUserNotFound) {
 				context.Respond(nil)
@@ -215,35 +361,103 @@ func (s *UserSupervisor) Receive(context actor.Context) {
 			context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch user", err))
 			return
 		}
+		context.Respond(profile)
 
-		// Get the names of all subreddits
-		subredditNames := make([]string, 0, len(user.Subreddits))
-		for _, subID := range user.Subreddits {
-			subreddit, err := s.mongodb.GetSubredditByID(ctx, subID)
-			if err != nil {
-				log.Printf("Error fetching subreddit %s: %v", subID, err)
-				continue
+	case *GetUserByUsernameMsg:
+		ctx := stdctx.Background()
+		userID, err := s.mongodb.GetUserIDByUsername(ctx, msg.Username)
+		if err == nil {
+			var profile *UserState
+			if profile, err = s.loadProfile(ctx, userID); err == nil {
+				context.Respond(profile)
+				return
 			}
-			subredditNames = append(subredditNames, subreddit.Name)
 		}
+		if utils.IsErrorCode(err, utils.ErrUserNotFound) {
+			context.Respond(utils.NewAppError(utils.ErrNotFound, "User not found", nil))
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch user", err))
 
-		// We don't have access to VotedPosts and VotedComments here
-		// Those would need to come from the UserActor's state
-		response := &UserState{
-			ID:             user.ID,
-			Username:       user.Username,
-			Email:          user.Email,
-			Karma:          user.Karma,
-			IsConnected:    user.IsConnected,
-			LastActive:     user.LastActive,
-			Subreddits:     user.Subreddits,
-			SubredditNames: subredditNames,
-			// Initialize empty maps for voted posts/comments
-			VotedPosts:    make(map[uuid.UUID]bool),
-			VotedComments: make(map[uuid.UUID]bool),
+	// Handle profile updates
+	case *UpdateProfileMsg:
+		s.handleUpdateProfile(context, msg)
+
+	// Handle preferences
+	case *GetPreferencesMsg:
+		prefs, err := s.mongodb.GetUserPreferences(stdctx.Background(), msg.UserID)
+		if err != nil {
+			if utils.IsErrorCode(err, utils.ErrUserNotFound) {
+				context.Respond(utils.NewAppError(utils.ErrNotFound, "User not found", nil))
+				return
+			}
+			context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get preferences", err))
+			return
+		}
+		context.Respond(prefs)
+
+	case *SetPreferencesMsg:
+		s.handleSetPreferences(context, msg)
+
+	// Handle password changes
+	case *ChangePasswordMsg:
+		s.handleChangePassword(context, msg)
+
+	// Handle email address changes
+	case *ChangeEmailMsg:
+		s.handleChangeEmail(context, msg)
+
+	// Handle follows between users
+	case *FollowUserMsg:
+		s.handleFollowUser(context, msg)
+
+	case *UnfollowUserMsg:
+		if err := s.mongodb.UnfollowUser(stdctx.Background(), msg.FollowerID, msg.FollowedID); err != nil {
+			context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to unfollow user", err))
+			return
+		}
+		context.Respond(map[string]bool{"following": false})
+
+	case *GetFollowingMsg:
+		s.handleGetFollows(context, msg.UserID, msg.Limit, msg.Cursor, s.mongodb.GetFollowing)
+
+	case *GetFollowersMsg:
+		s.handleGetFollows(context, msg.UserID, msg.Limit, msg.Cursor, s.mongodb.GetFollowers)
+
+	// Handle blocks between users
+	case *BlockUserMsg:
+		s.handleBlockUser(context, msg)
+
+	case *UnblockUserMsg:
+		if err := s.mongodb.UnblockUser(stdctx.Background(), msg.BlockerID, msg.BlockedID); err != nil {
+			context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to unblock user", err))
+			return
+		}
+		context.Respond(map[string]bool{"blocked": false})
+
+	case *GetBlockedUsersMsg:
+		limit := msg.Limit
+		if limit <= 0 || limit > 100 {
+			limit = 25
+		}
+		blocked, nextCursor, err := s.mongodb.GetBlockedUsers(stdctx.Background(), msg.UserID, limit, msg.Cursor)
+		if err != nil {
+			if appErr, ok := err.(*utils.AppError); ok {
+				context.Respond(appErr)
+				return
+			}
+			context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get blocked users", err))
+			return
 		}
+		context.Respond(&types.PaginatedResponse{Items: blocked, NextCursor: nextCursor})
 
-		context.Respond(response)
+	case *ResetPasswordMsg:
+		if appErr := s.storePassword(stdctx.Background(), msg.UserID, msg.NewPassword); appErr != nil {
+			context.Respond(appErr)
+			return
+		}
+		log.Printf("Password reset for user %s", msg.UserID)
+		context.Respond(true)
 
 	// Handle karma updates
 	case *UpdateKarmaMsg:
@@ -256,11 +470,15 @@ func (s *UserSupervisor) Receive(context actor.Context) {
 		}
 
 		// Update MongoDB first
-		ctx := stdctx.Background()
-		err := s.mongodb.UpdateUserKarma(ctx, msg.UserID, msg.Delta)
-		if err != nil {
-			log.Printf("UserSupervisor: Failed to update karma in MongoDB for user %s: %v", msg.UserID, err)
-			return
+		if !msg.Persisted {
+			ctx := stdctx.Background()
+			err := s.mongodb.UpdateUserKarma(ctx, msg.UserID, msg.Delta, msg.Source)
+			if err != nil {
+				// Whatever earned the karma is already stored, so the totals now disagree
+				log.Printf("UserSupervisor: Failed to update karma in MongoDB for user %s, reconcile %s karma by %+d: %v",
+					msg.UserID, msg.Source, msg.Delta, err)
+				return
+			}
 		}
 
 		// Then update the actor's state
@@ -272,6 +490,347 @@ func (s *UserSupervisor) Receive(context actor.Context) {
 	}
 }
 
+// loadProfile reads a user's profile from MongoDB, with the names of their subreddits
+func (s *UserSupervisor) loadProfile(ctx stdctx.Context, userID uuid.UUID) (*UserState, error) {
+	user, err := s.mongodb.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the names of all subreddits
+	subredditNames := make([]string, 0, len(user.Subreddits))
+	for _, subID := range user.Subreddits {
+		subreddit, err := s.mongodb.GetSubredditByID(ctx, subID)
+		if err != nil {
+			log.Printf("Error fetching subreddit %s: %v", subID, err)
+			continue
+		}
+		subredditNames = append(subredditNames, subreddit.Name)
+	}
+
+	followers, following, err := s.mongodb.CountFollows(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// We don't have access to VotedPosts and VotedComments here
+	// Those would need to come from the UserActor's state
+	return &UserState{
+		ID:             user.ID,
+		Username:       user.Username,
+		Email:          user.Email,
+		EmailVerified:  user.EmailVerified,
+		Karma:          user.Karma,
+		PostKarma:      user.PostKarma,
+		CommentKarma:   user.CommentKarma,
+		CreatedAt:      user.CreatedAt,
+		FollowerCount:  followers,
+		FollowingCount: following,
+		IsConnected:    user.IsConnected,
+		LastActive:     user.LastActive,
+		IsAdmin:        user.IsAdmin,
+		Subreddits:     user.Subreddits,
+		SubredditNames: subredditNames,
+		DisplayName:    user.DisplayName,
+		Bio:            user.Bio,
+		AvatarURL:      user.AvatarURL,
+		// Initialize empty maps for voted posts/comments
+		VotedPosts:    make(map[uuid.UUID]bool),
+		VotedComments: make(map[uuid.UUID]bool),
+	}, nil
+}
+
+// handleUpdateProfile saves the profile details a user changed, passes them on to the
+// user's actor if it is running and responds with the updated profile
+func (s *UserSupervisor) handleUpdateProfile(context actor.Context, msg *UpdateProfileMsg) {
+	if msg.RequesterID != msg.UserID {
+		context.Respond(utils.NewAppError(utils.ErrForbidden, "You can only update your own profile", nil))
+		return
+	}
+	if appErr := validateProfileUpdate(msg); appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+
+	ctx := stdctx.Background()
+	update := database.ProfileUpdate{DisplayName: msg.DisplayName, Bio: msg.Bio, AvatarURL: msg.AvatarURL}
+	if err := s.mongodb.UpdateUserProfile(ctx, msg.UserID, update); err != nil {
+		if utils.IsErrorCode(err, utils.ErrUserNotFound) {
+			context.Respond(utils.NewAppError(utils.ErrNotFound, "User not found", nil))
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to update profile", err))
+		return
+	}
+
+	profile, err := s.loadProfile(ctx, msg.UserID)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch user", err))
+		return
+	}
+
+	s.mu.RLock()
+	pid, exists := s.userActors[msg.UserID]
+	s.mu.RUnlock()
+	if exists {
+		context.Send(pid, msg)
+	}
+	context.Respond(profile)
+}
+
+// handleSetPreferences validates and stores a user's preferences
+func (s *UserSupervisor) handleSetPreferences(context actor.Context, msg *SetPreferencesMsg) {
+	prefs := msg.Preferences
+	if appErr := validatePreferences(&prefs); appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+
+	if err := s.mongodb.SetUserPreferences(stdctx.Background(), msg.UserID, &prefs); err != nil {
+		if utils.IsErrorCode(err, utils.ErrUserNotFound) {
+			context.Respond(utils.NewAppError(utils.ErrNotFound, "User not found", nil))
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to save preferences", err))
+		return
+	}
+	context.Respond(&prefs)
+}
+
+// handleFollowUser makes a user follow another, who must have an account in good standing
+func (s *UserSupervisor) handleFollowUser(context actor.Context, msg *FollowUserMsg) {
+	if msg.FollowerID == msg.FollowedID {
+		context.Respond(utils.NewAppError(utils.ErrInvalidInput, "You can't follow yourself", nil))
+		return
+	}
+
+	ctx := stdctx.Background()
+	followed, err := s.mongodb.GetUser(ctx, msg.FollowedID)
+	if err != nil {
+		if utils.IsErrorCode(err, utils.ErrUserNotFound) {
+			context.Respond(utils.NewAppError(utils.ErrNotFound, "User not found", nil))
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch user", err))
+		return
+	}
+	if followed.AbuseDeletedAt != nil {
+		context.Respond(utils.NewAppError(utils.ErrNotFound, "User not found", nil))
+		return
+	}
+
+	if err := s.mongodb.FollowUser(ctx, msg.FollowerID, msg.FollowedID, time.Now()); err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to follow user", err))
+		return
+	}
+	context.Respond(map[string]bool{"following": true})
+}
+
+// handleBlockUser makes a user block another, who must exist
+func (s *UserSupervisor) handleBlockUser(context actor.Context, msg *BlockUserMsg) {
+	if msg.BlockerID == msg.BlockedID {
+		context.Respond(utils.NewAppError(utils.ErrInvalidInput, "You can't block yourself", nil))
+		return
+	}
+
+	ctx := stdctx.Background()
+	if _, err := s.mongodb.GetUser(ctx, msg.BlockedID); err != nil {
+		if utils.IsErrorCode(err, utils.ErrUserNotFound) {
+			context.Respond(utils.NewAppError(utils.ErrNotFound, "User not found", nil))
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch user", err))
+		return
+	}
+
+	if err := s.mongodb.BlockUser(ctx, msg.BlockerID, msg.BlockedID, time.Now()); err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to block user", err))
+		return
+	}
+	context.Respond(map[string]bool{"blocked": true})
+}
+
+// handleGetFollows responds with a page of follows read by list
+func (s *UserSupervisor) handleGetFollows(context actor.Context, userID uuid.UUID, limit int, cursor string,
+	list func(stdctx.Context, uuid.UUID, int, string) ([]*models.FollowEntry, string, error)) {
+	if limit <= 0 || limit > 100 {
+		limit = 25
+	}
+
+	follows, nextCursor, err := list(stdctx.Background(), userID, limit, cursor)
+	if err != nil {
+		if appErr, ok := err.(*utils.AppError); ok {
+			context.Respond(appErr)
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to get follows", err))
+		return
+	}
+	context.Respond(&types.PaginatedResponse{Items: follows, NextCursor: nextCursor})
+}
+
+// handleChangePassword stores a new password hash for a user after checking their
+// current password. A wrong password and a missing account both fail with ErrUnauthorized.
+func (s *UserSupervisor) handleChangePassword(context actor.Context, msg *ChangePasswordMsg) {
+	ctx := stdctx.Background()
+	user, err := s.mongodb.GetUser(ctx, msg.UserID)
+	if err != nil {
+		if utils.IsErrorCode(err, utils.ErrUserNotFound) {
+			context.Respond(utils.NewAppError(utils.ErrUnauthorized, "Current password is incorrect", nil))
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch user", err))
+		return
+	}
+
+	if ok, _ := verifyPassword(user.HashedPassword, msg.CurrentPassword, s.passwordCost); !ok {
+		log.Printf("Password change failed - Password mismatch for user %s", user.ID)
+		context.Respond(utils.NewAppError(utils.ErrUnauthorized, "Current password is incorrect", nil))
+		return
+	}
+
+	if appErr := s.storePassword(ctx, user.ID, msg.NewPassword); appErr != nil {
+		context.Respond(appErr)
+		return
+	}
+
+	log.Printf("Password changed for user %s", user.ID)
+	context.Respond(true)
+}
+
+// handleChangeEmail moves a user to a new email address after checking their password
+// and responds with the updated profile. Addresses taken by another account, or related
+// to one banned or deleted for abuse, fail with ErrDuplicate.
+func (s *UserSupervisor) handleChangeEmail(context actor.Context, msg *ChangeEmailMsg) {
+	ctx := stdctx.Background()
+	user, err := s.mongodb.GetUser(ctx, msg.UserID)
+	if err != nil {
+		if utils.IsErrorCode(err, utils.ErrUserNotFound) {
+			context.Respond(utils.NewAppError(utils.ErrUnauthorized, "Current password is incorrect", nil))
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch user", err))
+		return
+	}
+
+	if ok, _ := verifyPassword(user.HashedPassword, msg.CurrentPassword, s.passwordCost); !ok {
+		log.Printf("Email change failed - Password mismatch for user %s", user.ID)
+		context.Respond(utils.NewAppError(utils.ErrUnauthorized, "Current password is incorrect", nil))
+		return
+	}
+	if user.Email == msg.Email {
+		context.Respond(utils.NewAppError(utils.ErrInvalidInput, "That is already your email address", nil))
+		return
+	}
+
+	// Moving to an alias of an abusive account is ban evasion as much as registering one
+	related, err := s.mongodb.GetAccountsByNormalizedEmail(ctx, utils.NormalizeEmail(msg.Email))
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to check email", err))
+		return
+	}
+	if hasAbusiveAccount(related) {
+		log.Printf("Rejecting email change of user %s: matches a banned account", user.ID)
+		context.Respond(utils.NewAppError(utils.ErrDuplicate, "Email already registered", nil))
+		return
+	}
+
+	if err := s.mongodb.UpdateUserEmail(ctx, user.ID, msg.Email); err != nil {
+		if appErr, ok := err.(*utils.AppError); ok && appErr.Code == utils.ErrDuplicate {
+			context.Respond(appErr)
+			return
+		}
+		if utils.IsErrorCode(err, utils.ErrUserNotFound) {
+			context.Respond(utils.NewAppError(utils.ErrNotFound, "User not found", nil))
+			return
+		}
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to update email", err))
+		return
+	}
+
+	s.mu.Lock()
+	if s.emailToID[user.Email] == user.ID {
+		delete(s.emailToID, user.Email)
+	}
+	s.emailToID[msg.Email] = user.ID
+	pid, exists := s.userActors[user.ID]
+	s.mu.Unlock()
+	if exists {
+		context.Send(pid, msg)
+	}
+	log.Printf("Email changed for user %s", user.ID)
+
+	profile, err := s.loadProfile(ctx, user.ID)
+	if err != nil {
+		context.Respond(utils.NewAppError(utils.ErrDatabase, "Failed to fetch user", err))
+		return
+	}
+	context.Respond(profile)
+}
+
+// storePassword hashes a new password and saves it as the user's credential
+func (s *UserSupervisor) storePassword(ctx stdctx.Context, userID uuid.UUID, password string) *utils.AppError {
+	hashedPassword, err := hashPassword(password, s.passwordCost)
+	if err != nil {
+		return utils.NewAppError(utils.ErrInvalidInput, "Failed to hash password", err)
+	}
+	if err := s.mongodb.UpdateUserPassword(ctx, userID, hashedPassword); err != nil {
+		if utils.IsErrorCode(err, utils.ErrUserNotFound) {
+			return utils.NewAppError(utils.ErrNotFound, "User not found", nil)
+		}
+		return utils.NewAppError(utils.ErrDatabase, "Failed to update password", err)
+	}
+	return nil
+}
+
+// validateProfileUpdate checks the profile details in an update, trimming surrounding
+// whitespace from them
+func validateProfileUpdate(msg *UpdateProfileMsg) *utils.AppError {
+	for _, field := range []*string{msg.DisplayName, msg.Bio, msg.AvatarURL} {
+		if field != nil {
+			*field = strings.TrimSpace(*field)
+		}
+	}
+
+	if msg.DisplayName != nil && utf8.RuneCountInString(*msg.DisplayName) > maxDisplayNameLength {
+		return utils.NewAppError(utils.ErrInvalidInput,
+			fmt.Sprintf("Display name must be at most %d characters", maxDisplayNameLength), nil)
+	}
+	if msg.Bio != nil && utf8.RuneCountInString(*msg.Bio) > maxBioLength {
+		return utils.NewAppError(utils.ErrInvalidInput,
+			fmt.Sprintf("Bio must be at most %d characters", maxBioLength), nil)
+	}
+	if msg.AvatarURL != nil && *msg.AvatarURL != "" {
+		if len(*msg.AvatarURL) > maxAvatarURLLength || !isWebURL(*msg.AvatarURL) {
+			return utils.NewAppError(utils.ErrInvalidInput, "Avatar URL must be an http or https URL", nil)
+		}
+	}
+	return nil
+}
+
+// validatePreferences checks a user's preferences
+func validatePreferences(prefs *models.UserPreferences) *utils.AppError {
+	switch prefs.FeedSort {
+	case "", database.SortHot, database.SortNew, database.SortTop, database.SortControversial:
+	default:
+		return utils.NewAppError(utils.ErrInvalidInput, "feedSort must be one of hot, new, top, controversial", nil)
+	}
+	return nil
+}
+
+// hasAbusiveAccount reports whether any of the accounts is banned or was recently deleted for abuse
+func hasAbusiveAccount(accounts []*database.RelatedAccount) bool {
+	for _, account := range accounts {
+		if account.IsBanned {
+			return true
+		}
+		if account.AbuseDeletedAt != nil && time.Since(*account.AbuseDeletedAt) < recentAbuseWindow {
+			return true
+		}
+	}
+	return false
+}
+
 // getOrCreateUserActor ensures that a user actor exists for the given userID.
 // If it doesn't, it fetches the user from MongoDB and creates a new actor.
 func (s *UserSupervisor) getOrCreateUserActor(context actor.Context, userID uuid.UUID) (*actor.PID, error) {
@@ -296,8 +855,7 @@ func (s *UserSupervisor) getOrCreateUserActor(context actor.Context, userID uuid
 			Username: user.Username,
 			Email:    user.Email,
 			Password: user.HashedPassword, // Use hashed password directly
-			Karma:    user.Karma,
-		}, s.mongodb)
+		}, s.mongodb, s.passwordCost)
 	})
 
 	pid = context.Spawn(props)
@@ -313,20 +871,21 @@ func (s *UserSupervisor) getOrCreateUserActor(context actor.Context, userID uuid
 // UserActor is responsible for managing the state of a single user.
 // It handles messages related to user registration, login, profile updates, voting, etc.
 type UserActor struct {
-	id      uuid.UUID
-	state   *UserState
-	mongodb *database.MongoDB
+	id           uuid.UUID
+	state        *UserState
+	mongodb      *database.MongoDB
+	passwordCost int // bcrypt cost of new password hashes
 }
 
 // NewUserActor creates a new user actor with initial user state, typically during registration or actor creation for an existing user.
-func NewUserActor(id uuid.UUID, msg *RegisterUserMsg, mongodb *database.MongoDB) *UserActor {
+func NewUserActor(id uuid.UUID, msg *RegisterUserMsg, mongodb *database.MongoDB, passwordCost int) *UserActor {
 	return &UserActor{
 		id: id,
 		state: &UserState{
 			ID:            id,
 			Username:      msg.Username,
 			Email:         msg.Email,
-			Karma:         300, // Default initial karma
+			Karma:         0, // New accounts start without karma; it is only earned
 			IsConnected:   true,
 			LastActive:    time.Now(),
 			Posts:         make([]uuid.UUID, 0),
@@ -335,16 +894,33 @@ func NewUserActor(id uuid.UUID, msg *RegisterUserMsg, mongodb *database.MongoDB)
 			VotedComments: make(map[uuid.UUID]bool),
 			Subreddits:    make([]uuid.UUID, 0),
 		},
-		mongodb: mongodb,
+		mongodb:      mongodb,
+		passwordCost: passwordCost,
 	}
 }
 
 // hashPassword securely hashes a user password using bcrypt
-func hashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+func hashPassword(password string, cost int) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), cost)
 	return string(bytes), err
 }
 
+// verifyPassword reports whether password matches a user's stored credential, and
+// whether the credential should be replaced by a fresh hash: it isn't a bcrypt hash but
+// the password itself, as stored for accounts created before passwords were hashed, or
+// it was hashed at a lower cost than cost
+func verifyPassword(stored, password string, cost int) (ok, rehash bool) {
+	hashCost, err := bcrypt.Cost([]byte(stored))
+	if err != nil {
+		ok = stored != "" && subtle.ConstantTimeCompare([]byte(stored), []byte(password)) == 1
+		return ok, ok
+	}
+	if bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)) != nil {
+		return false, false
+	}
+	return true, hashCost < cost
+}
+
 // generateToken creates a secure random token for authentication purposes
 func generateToken() (string, error) {
 	b := make([]byte, 32)
@@ -361,7 +937,7 @@ func (a *UserActor) Receive(context actor.Context) {
 
 	// Handle user registration inside the user actor
 	case *RegisterUserMsg:
-		hashedPassword, err := hashPassword(msg.Password)
+		hashedPassword, err := hashPassword(msg.Password, a.passwordCost)
 		if err != nil {
 			context.Respond(utils.NewAppError(utils.ErrInvalidInput, "Failed to hash password", err))
 			return
@@ -371,8 +947,11 @@ func (a *UserActor) Receive(context actor.Context) {
 		a.state.Username = msg.Username
 		a.state.Email = msg.Email
 		a.state.HashedPassword = hashedPassword
-		a.state.Karma = 300
+		a.state.Karma = 0
+		a.state.PostKarma = 0
+		a.state.CommentKarma = 0
 		a.state.Subreddits = make([]uuid.UUID, 0)
+		a.state.CreatedAt = time.Now()
 
 		// Create a user model for MongoDB storage
 		user := &models.User{
@@ -381,16 +960,23 @@ func (a *UserActor) Receive(context actor.Context) {
 			Email:          a.state.Email,
 			HashedPassword: hashedPassword,
 			Karma:          a.state.Karma,
-			CreatedAt:      time.Now(),
+			CreatedAt:      a.state.CreatedAt,
 			LastActive:     time.Now(),
 			IsConnected:    true,
 			Subreddits:     a.state.Subreddits,
+
+			NormalizedEmail:  utils.NormalizeEmail(a.state.Email),
+			FlaggedForReview: msg.FlaggedForReview,
 		}
 
 		// Persist the user in MongoDB
 		ctx := stdctx.Background()
 		if err := a.mongodb.SaveUser(ctx, user); err != nil {
 			log.Printf("Failed to save user to MongoDB: %v", err)
+			if appErr, ok := err.(*utils.AppError); ok && appErr.Code == utils.ErrDuplicate {
+				context.Respond(appErr)
+				return
+			}
 			context.Respond(utils.NewAppError(utils.ErrInvalidInput, "Failed to save user", err))
 			return
 		}
@@ -398,27 +984,48 @@ func (a *UserActor) Receive(context actor.Context) {
 		log.Printf("Successfully created user %s in MongoDB", a.state.ID)
 
 		context.Respond(&UserState{
-			ID:       a.state.ID,
-			Username: a.state.Username,
-			Email:    a.state.Email,
-			Karma:    a.state.Karma,
+			ID:         a.state.ID,
+			Username:   a.state.Username,
+			Email:      a.state.Email,
+			Karma:      a.state.Karma,
+			CreatedAt:  a.state.CreatedAt,
+			LastActive: a.state.LastActive,
 		})
 
-	// Handle user profile updates (username/email)
+	// Handle profile detail updates
 	case *UpdateProfileMsg:
+		// Already validated and saved by the supervisor
 		if a.state.ID == msg.UserID {
-			a.state.Username = msg.NewUsername
-			a.state.Email = msg.NewEmail
-			context.Respond(true)
-		} else {
-			context.Respond(false)
+			if msg.DisplayName != nil {
+				a.state.DisplayName = *msg.DisplayName
+			}
+			if msg.Bio != nil {
+				a.state.Bio = *msg.Bio
+			}
+			if msg.AvatarURL != nil {
+				a.state.AvatarURL = *msg.AvatarURL
+			}
+		}
+
+	// Handle email address changes
+	case *ChangeEmailMsg:
+		// Already checked and saved by the supervisor
+		if a.state.ID == msg.UserID {
+			a.state.Email = msg.Email
+			a.state.EmailVerified = false
 		}
 
 	// Handle karma updates
 	case *UpdateKarmaMsg:
 		if a.state.ID == msg.UserID {
-			log.Printf("UserActor: Updating karma for user %s by %d", msg.UserID, msg.Delta)
+			log.Printf("UserActor: Updating %s karma for user %s by %d", msg.Source, msg.UserID, msg.Delta)
 			a.state.Karma += msg.Delta
+			switch msg.Source {
+			case KarmaSourcePost:
+				a.state.PostKarma += msg.Delta
+			case KarmaSourceComment:
+				a.state.CommentKarma += msg.Delta
+			}
 		}
 
 	// Handle user profile retrieval
@@ -440,11 +1047,18 @@ func (a *UserActor) Receive(context actor.Context) {
 			ID:             user.ID,
 			Username:       user.Username,
 			Email:          user.Email,
+			EmailVerified:  user.EmailVerified,
 			Karma:          user.Karma,
+			PostKarma:      user.PostKarma,
+			CommentKarma:   user.CommentKarma,
 			IsConnected:    user.IsConnected,
 			LastActive:     user.LastActive,
+			IsAdmin:        user.IsAdmin,
 			HashedPassword: user.HashedPassword,
 			Subreddits:     user.Subreddits,
+			DisplayName:    user.DisplayName,
+			Bio:            user.Bio,
+			AvatarURL:      user.AvatarURL,
 			VotedPosts:     make(map[uuid.UUID]bool),
 			VotedComments:  make(map[uuid.UUID]bool),
 		}
@@ -467,9 +1081,9 @@ func (a *UserActor) Receive(context actor.Context) {
 		}
 
 		// Verify password
-		err = bcrypt.CompareHashAndPassword([]byte(user.HashedPassword), []byte(msg.Password))
-		if err != nil {
-			log.Printf("Login failed - Password mismatch: %v", err)
+		ok, rehash := verifyPassword(user.HashedPassword, msg.Password, a.passwordCost)
+		if !ok {
+			log.Printf("Login failed - Password mismatch for user %s", user.ID)
 			context.Respond(&types.LoginResponse{
 				Success: false,
 				Error:   "Invalid credentials",
@@ -477,6 +1091,18 @@ func (a *UserActor) Receive(context actor.Context) {
 			return
 		}
 
+		// Upgrade the stored credential now that the password is known. The login
+		// succeeds either way, so a failure is only logged and retried next time.
+		if rehash {
+			if hashedPassword, err := hashPassword(msg.Password, a.passwordCost); err != nil {
+				log.Printf("Warning: Failed to rehash password for user %s: %v", user.ID, err)
+			} else if err := a.mongodb.UpdateUserPassword(ctx, user.ID, hashedPassword); err != nil {
+				log.Printf("Warning: Failed to store rehashed password for user %s: %v", user.ID, err)
+			} else {
+				user.HashedPassword = hashedPassword
+			}
+		}
+
 		// Generate a new auth token for the session
 		token, err := generateToken()
 		if err != nil {
@@ -499,7 +1125,10 @@ func (a *UserActor) Receive(context actor.Context) {
 			ID:             user.ID,
 			Username:       user.Username,
 			Email:          user.Email,
+			EmailVerified:  user.EmailVerified,
 			Karma:          user.Karma,
+			PostKarma:      user.PostKarma,
+			CommentKarma:   user.CommentKarma,
 			IsConnected:    true,
 			LastActive:     time.Now(),
 			AuthToken:      token,
@@ -512,9 +1141,10 @@ func (a *UserActor) Receive(context actor.Context) {
 		log.Printf("Login successful for user: %s", user.Username)
 
 		context.Respond(&types.LoginResponse{
-			Success: true,
-			Token:   token,
-			UserID:  user.ID.String(),
+			Success:  true,
+			Token:    token,
+			UserID:   user.ID.String(),
+			Username: user.Username,
 		})
 
 	// Handle voting (upvotes/downvotes on posts or comments)
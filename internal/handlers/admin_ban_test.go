@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"gator-swamp/internal/config"
+	"gator-swamp/internal/database/dbtest"
+	"gator-swamp/internal/engine"
+	"gator-swamp/internal/middleware"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/types"
+	"gator-swamp/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Once an admin bans an account, registering an alias of its email is ban evasion
+func TestAdminBanRejectsRegistrationsOfAliases(t *testing.T) {
+	mongodb := dbtest.New(t)
+	ctx := context.Background()
+	cfg := &config.Config{
+		DuplicateAccountAction: config.DuplicateAccountReject,
+		PasswordHashCost:       bcrypt.MinCost,
+		MinPasswordLength:      8,
+		EmailVerificationTTL:   time.Hour,
+	}
+	system := actor.NewActorSystem()
+	t.Cleanup(system.Shutdown)
+	s := &Server{
+		System:         system,
+		Context:        system.Root,
+		Engine:         engine.NewEngine(system, utils.NewMetricsCollector(), mongodb, cfg),
+		MongoDB:        mongodb,
+		Mailer:         &recordingMailer{},
+		Config:         cfg,
+		RequestTimeout: 5 * time.Second,
+	}
+
+	adminID := uuid.New()
+	admin := &models.User{ID: adminID, Username: "ranger", Email: "ranger@example.com", CreatedAt: time.Now()}
+	if err := mongodb.SaveUser(ctx, admin); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+
+	register := func(username, email string) *httptest.ResponseRecorder {
+		t.Helper()
+		body := `{"username": "` + username + `", "email": "` + email + `", "password": "swamp-water-1"}`
+		w := httptest.NewRecorder()
+		s.HandleUserRegistration()(w, httptest.NewRequest(http.MethodPost, "/user/register", strings.NewReader(body)))
+		return w
+	}
+	ban := func(userID string, banned bool) *httptest.ResponseRecorder {
+		t.Helper()
+		body, _ := json.Marshal(AdminBanRequest{UserID: userID, Banned: banned, Reason: "Harassment"})
+		r := httptest.NewRequest(http.MethodPost, "/admin/users/ban", strings.NewReader(string(body)))
+		w := httptest.NewRecorder()
+		s.HandleAdminBanUser()(w, r.WithContext(middleware.SetUserIDInContext(r.Context(), adminID)))
+		return w
+	}
+
+	w := register("jane", "jane.doe@gmail.com")
+	if w.Code != http.StatusOK {
+		t.Fatalf("registering jane = %d: %s", w.Code, w.Body)
+	}
+	var jane types.PrivateUserProfile
+	if err := json.NewDecoder(w.Body).Decode(&jane); err != nil {
+		t.Fatalf("decoding profile: %v", err)
+	}
+	if w := register("jane_two", "janedoe+two@gmail.com"); w.Code != http.StatusOK {
+		t.Fatalf("registering an alias before the ban = %d: %s", w.Code, w.Body)
+	}
+
+	if w := ban(adminID.String(), true); w.Code != http.StatusBadRequest {
+		t.Fatalf("admin banning themselves = %d, want 400", w.Code)
+	}
+	if w := ban(uuid.New().String(), true); w.Code != http.StatusNotFound {
+		t.Fatalf("banning an unknown user = %d, want 404", w.Code)
+	}
+	if w := ban(jane.ID, true); w.Code != http.StatusOK {
+		t.Fatalf("POST /admin/users/ban = %d: %s", w.Code, w.Body)
+	}
+	audited, err := mongodb.AdminAudit.CountDocuments(ctx, bson.M{"action": "ban_user", "targetId": jane.ID})
+	if err != nil || audited != 1 {
+		t.Fatalf("ban audited %d times (%v), want once", audited, err)
+	}
+
+	if w := register("jane_three", "j.a.n.e.doe+three@gmail.com"); w.Code != http.StatusConflict {
+		t.Fatalf("registering an alias of a banned account = %d, want 409", w.Code)
+	}
+
+	if w := ban(jane.ID, false); w.Code != http.StatusOK {
+		t.Fatalf("lifting the ban = %d: %s", w.Code, w.Body)
+	}
+	if w := register("jane_three", "j.a.n.e.doe+three@gmail.com"); w.Code != http.StatusOK {
+		t.Fatalf("registering an alias once the ban is lifted = %d: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,568 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"gator-swamp/internal/analytics"
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/engine/actors"
+	"gator-swamp/internal/i18n"
+	"gator-swamp/internal/middleware"
+	"gator-swamp/internal/utils"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// AnalyticsResponse is the time series returned by /admin/analytics
+type AnalyticsResponse struct {
+	Metric      string                    `json:"metric"`
+	From        string                    `json:"from"`
+	To          string                    `json:"to"`
+	SubredditID string                    `json:"subredditId,omitempty"`
+	Points      []database.AnalyticsPoint `json:"points"`
+}
+
+// isAdmin reports whether the user is a site administrator. Failed checks count as not
+// being one.
+func (s *Server) isAdmin(ctx context.Context, userID uuid.UUID) bool {
+	isAdmin, err := s.MongoDB.IsUserAdmin(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to check admin status of user %s: %v", userID, err)
+		return false
+	}
+	return isAdmin
+}
+
+// auditAdminAction records an action the authenticated administrator took in the
+// admin audit log. The action has already happened, so failures are only logged.
+func (s *Server) auditAdminAction(r *http.Request, action, targetType, targetID, reason string, details bson.M) {
+	actorID, _ := middleware.GetUserIDFromContext(r.Context())
+	entry := &database.AdminAuditEntry{
+		ActorID:    actorID.String(),
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Reason:     reason,
+		Details:    details,
+		CreatedAt:  time.Now(),
+	}
+
+	log.Printf("Admin %s: %s %s %s", entry.ActorID, action, targetType, targetID)
+	if err := s.MongoDB.RecordAdminAction(r.Context(), entry); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// HandleAdminAnalytics serves GET /admin/analytics?metric=&from=&to=&subredditId=
+// from the daily rollups. Dates are YYYY-MM-DD (UTC) and default to the last 30 days.
+func (s *Server) HandleAdminAnalytics() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		query := r.URL.Query()
+		metric := query.Get("metric")
+		switch metric {
+		case analytics.EventView, analytics.EventVote, analytics.EventPost, analytics.EventSignup, analytics.EventJoin:
+		default:
+			http.Error(w, "metric must be one of view, vote, post, signup, join", http.StatusBadRequest)
+			return
+		}
+
+		now := time.Now().UTC()
+		from := query.Get("from")
+		if from == "" {
+			from = now.AddDate(0, 0, -30).Format(database.AnalyticsDateLayout)
+		}
+		to := query.Get("to")
+		if to == "" {
+			to = now.Format(database.AnalyticsDateLayout)
+		}
+		fromDate, err := time.Parse(database.AnalyticsDateLayout, from)
+		if err != nil {
+			http.Error(w, "Invalid from date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		toDate, err := time.Parse(database.AnalyticsDateLayout, to)
+		if err != nil {
+			http.Error(w, "Invalid to date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		if toDate.Before(fromDate) {
+			http.Error(w, "from must not be after to", http.StatusBadRequest)
+			return
+		}
+
+		subredditID := query.Get("subredditId")
+		if subredditID != "" {
+			if _, err := uuid.Parse(subredditID); err != nil {
+				http.Error(w, "Invalid subreddit ID format", http.StatusBadRequest)
+				return
+			}
+		}
+
+		points, err := s.MongoDB.GetAnalyticsSeries(r.Context(), metric, from, to, subredditID)
+		if err != nil {
+			http.Error(w, "Failed to get analytics", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(AnalyticsResponse{
+			Metric:      metric,
+			From:        from,
+			To:          to,
+			SubredditID: subredditID,
+			Points:      points,
+		})
+	}
+}
+
+// RelatedAccountsResponse lists the accounts sharing a user's normalized email
+type RelatedAccountsResponse struct {
+	UserID          string                     `json:"userId"`
+	NormalizedEmail string                     `json:"normalizedEmail"`
+	Accounts        []*database.RelatedAccount `json:"accounts"`
+}
+
+// HandleAdminRelatedUsers serves GET /admin/users/related?userId= with every other
+// account whose email normalizes to the same address
+func (s *Server) HandleAdminRelatedUsers() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, err := uuid.Parse(r.URL.Query().Get("userId"))
+		if err != nil {
+			http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+			return
+		}
+
+		user, err := s.MongoDB.GetUser(r.Context(), userID)
+		if err != nil {
+			if utils.IsErrorCode(err, utils.ErrUserNotFound) {
+				writeLocalizedError(w, r, i18n.ErrUserNotFound, nil, http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Failed to get user", http.StatusInternalServerError)
+			return
+		}
+
+		normalized := user.NormalizedEmail
+		if normalized == "" {
+			normalized = utils.NormalizeEmail(user.Email)
+		}
+
+		accounts, err := s.MongoDB.GetAccountsByNormalizedEmail(r.Context(), normalized)
+		if err != nil {
+			http.Error(w, "Failed to get related accounts", http.StatusInternalServerError)
+			return
+		}
+
+		related := make([]*database.RelatedAccount, 0, len(accounts))
+		for _, account := range accounts {
+			if account.ID != userID.String() {
+				related = append(related, account)
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(RelatedAccountsResponse{
+			UserID:          userID.String(),
+			NormalizedEmail: normalized,
+			Accounts:        related,
+		})
+	}
+}
+
+// HandleAdminReconcileMembers serves POST /admin/subreddits/reconcile-members, which
+// recounts every subreddit's members from user subscriptions and corrects the counts
+// that drifted. The response lists the corrections.
+func (s *Server) HandleAdminReconcileMembers() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// Sent straight to the subreddit actor: the engine's forwarding timeout is too
+		// short for a recount of every subreddit
+		future := s.Context.RequestFuture(s.Engine.GetSubredditActor(),
+			&actors.ReconcileMemberCountsMsg{}, actors.MemberReconcileTimeout)
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to reconcile member counts", http.StatusInternalServerError)
+			return
+		}
+		if appErr, ok := result.(*utils.AppError); ok {
+			writeAppError(w, r, appErr, http.StatusInternalServerError)
+			return
+		}
+		s.auditAdminAction(r, "reconcile_members", "subreddit", "*", "", nil)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"corrections": result})
+	}
+}
+
+// maxAdminReasonLength bounds the reasons admins give for their actions, in characters
+const maxAdminReasonLength = 500
+
+// AdminDeletePostRequest is the body of POST /admin/posts/delete
+type AdminDeletePostRequest struct {
+	PostID string `json:"postId"`
+	Reason string `json:"reason"`
+}
+
+// AdminDeleteSubredditRequest is the body of POST /admin/subreddits/delete
+type AdminDeleteSubredditRequest struct {
+	SubredditID string `json:"subredditId"`
+	ConfirmName string `json:"confirmName"` // Must repeat the subreddit's name
+	Reason      string `json:"reason"`
+}
+
+// AdminKarmaRequest is the body of POST /admin/users/karma
+type AdminKarmaRequest struct {
+	UserID string `json:"userId"`
+	Delta  int    `json:"delta"`
+	Source string `json:"source"` // "post" or "comment"
+	Reason string `json:"reason"`
+}
+
+// AdminRoleRequest is the body of POST /admin/users/admin
+type AdminRoleRequest struct {
+	UserID  string `json:"userId"`
+	IsAdmin bool   `json:"isAdmin"`
+}
+
+// AdminBanRequest is the body of POST /admin/users/ban
+type AdminBanRequest struct {
+	UserID string `json:"userId"`
+	Banned bool   `json:"banned"`
+	Reason string `json:"reason"`
+}
+
+// HandleAdminDeletePost serves POST /admin/posts/delete, which deletes any post
+// regardless of its author, subreddit or age
+func (s *Server) HandleAdminDeletePost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req AdminDeletePostRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		postID, err := uuid.Parse(req.PostID)
+		if err != nil {
+			http.Error(w, "Invalid post ID format", http.StatusBadRequest)
+			return
+		}
+		if utf8.RuneCountInString(req.Reason) > maxAdminReasonLength {
+			http.Error(w, "Reason is too long", http.StatusBadRequest)
+			return
+		}
+
+		adminID, _ := middleware.GetUserIDFromContext(r.Context())
+		future := s.Context.RequestFuture(s.EnginePID, &actors.DeletePostMsg{
+			PostID:  postID,
+			UserID:  adminID,
+			AsAdmin: true,
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to delete post", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrNotFound:
+				statusCode = http.StatusNotFound
+			case utils.ErrGone:
+				statusCode = http.StatusGone
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+		s.auditAdminAction(r, "delete_post", "post", postID.String(), req.Reason, nil)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]bool{"success": true})
+	}
+}
+
+// HandleAdminDeleteSubreddit serves POST /admin/subreddits/delete, which deletes any
+// subreddit as its creator could. The body must repeat the subreddit's name.
+func (s *Server) HandleAdminDeleteSubreddit() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req AdminDeleteSubredditRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		subredditID, err := uuid.Parse(req.SubredditID)
+		if err != nil {
+			http.Error(w, "Invalid subreddit ID", http.StatusBadRequest)
+			return
+		}
+		if utf8.RuneCountInString(req.Reason) > maxAdminReasonLength {
+			http.Error(w, "Reason is too long", http.StatusBadRequest)
+			return
+		}
+
+		adminID, _ := middleware.GetUserIDFromContext(r.Context())
+		future := s.Context.RequestFuture(s.EnginePID, &actors.DeleteSubredditMsg{
+			SubredditID: subredditID,
+			RequesterID: adminID,
+			ConfirmName: req.ConfirmName,
+			AsAdmin:     true,
+		}, actors.SubredditDeleteTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to delete subreddit", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrNotFound:
+				statusCode = http.StatusNotFound
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+		s.auditAdminAction(r, "delete_subreddit", "subreddit", subredditID.String(), req.Reason,
+			bson.M{"name": req.ConfirmName})
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleAdminUserProfile serves GET /admin/users/profile?userId= with any user's
+// private profile
+func (s *Server) HandleAdminUserProfile() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, err := uuid.Parse(r.URL.Query().Get("userId"))
+		if err != nil {
+			http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+			return
+		}
+
+		future := s.Context.RequestFuture(s.Engine.GetUserSupervisor(),
+			&actors.GetUserProfileMsg{UserID: userID}, s.RequestTimeout)
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to get user profile", http.StatusInternalServerError)
+			return
+		}
+
+		if result == nil {
+			writeLocalizedError(w, r, i18n.ErrUserNotFound, nil, http.StatusNotFound)
+			return
+		}
+		if appErr, ok := result.(*utils.AppError); ok {
+			writeAppError(w, r, appErr, http.StatusInternalServerError)
+			return
+		}
+		userState, ok := result.(*actors.UserState)
+		if !ok {
+			http.Error(w, "Invalid response type", http.StatusInternalServerError)
+			return
+		}
+		s.auditAdminAction(r, "view_profile", "user", userID.String(), "", nil)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.newPrivateProfile(userState))
+	}
+}
+
+// HandleAdminAdjustKarma serves POST /admin/users/karma, which adds delta to a user's
+// post or comment karma. A reason is required and kept in the audit log.
+func (s *Server) HandleAdminAdjustKarma() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req AdminKarmaRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		userID, err := uuid.Parse(req.UserID)
+		if err != nil {
+			http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+			return
+		}
+		if req.Delta == 0 {
+			http.Error(w, "delta must not be zero", http.StatusBadRequest)
+			return
+		}
+		if req.Source != actors.KarmaSourcePost && req.Source != actors.KarmaSourceComment {
+			http.Error(w, "source must be post or comment", http.StatusBadRequest)
+			return
+		}
+		req.Reason = strings.TrimSpace(req.Reason)
+		if req.Reason == "" {
+			http.Error(w, "A reason is required", http.StatusBadRequest)
+			return
+		}
+		if utf8.RuneCountInString(req.Reason) > maxAdminReasonLength {
+			http.Error(w, "Reason is too long", http.StatusBadRequest)
+			return
+		}
+
+		if err := s.MongoDB.UpdateUserKarma(r.Context(), userID, req.Delta, req.Source); err != nil {
+			if utils.IsErrorCode(err, utils.ErrUserNotFound) {
+				writeLocalizedError(w, r, i18n.ErrUserNotFound, nil, http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Failed to adjust karma", http.StatusInternalServerError)
+			return
+		}
+		// Keep the user's actor, if it is running, in step with the stored totals
+		s.Context.Send(s.Engine.GetUserSupervisor(), &actors.UpdateKarmaMsg{
+			UserID:    userID,
+			Delta:     req.Delta,
+			Source:    req.Source,
+			Persisted: true,
+		})
+		s.auditAdminAction(r, "adjust_karma", "user", userID.String(), req.Reason,
+			bson.M{"delta": req.Delta, "source": req.Source})
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]bool{"success": true})
+	}
+}
+
+// HandleAdminSetRole serves POST /admin/users/admin, which grants or revokes a user's
+// administrator role. Admins can't revoke their own, so there is always one left.
+func (s *Server) HandleAdminSetRole() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req AdminRoleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		userID, err := uuid.Parse(req.UserID)
+		if err != nil {
+			http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+			return
+		}
+
+		adminID, _ := middleware.GetUserIDFromContext(r.Context())
+		if userID == adminID && !req.IsAdmin {
+			http.Error(w, "You can't revoke your own admin role", http.StatusBadRequest)
+			return
+		}
+
+		if err := s.MongoDB.SetUserAdmin(r.Context(), userID, req.IsAdmin); err != nil {
+			if utils.IsErrorCode(err, utils.ErrUserNotFound) {
+				writeLocalizedError(w, r, i18n.ErrUserNotFound, nil, http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Failed to update admin role", http.StatusInternalServerError)
+			return
+		}
+
+		action := "grant_admin"
+		if !req.IsAdmin {
+			action = "revoke_admin"
+		}
+		s.auditAdminAction(r, action, "user", userID.String(), "", nil)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]bool{"isAdmin": req.IsAdmin})
+	}
+}
+
+// HandleAdminBanUser serves POST /admin/users/ban, which bans a user site-wide or lifts
+// their ban. New accounts whose email normalizes to a banned account's are ban evasion.
+func (s *Server) HandleAdminBanUser() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req AdminBanRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		userID, err := uuid.Parse(req.UserID)
+		if err != nil {
+			http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+			return
+		}
+		if utf8.RuneCountInString(req.Reason) > maxAdminReasonLength {
+			http.Error(w, "Reason is too long", http.StatusBadRequest)
+			return
+		}
+
+		adminID, _ := middleware.GetUserIDFromContext(r.Context())
+		if userID == adminID && req.Banned {
+			http.Error(w, "You can't ban yourself", http.StatusBadRequest)
+			return
+		}
+
+		if err := s.MongoDB.SetUserBanned(r.Context(), userID, req.Banned); err != nil {
+			if utils.IsErrorCode(err, utils.ErrUserNotFound) {
+				writeLocalizedError(w, r, i18n.ErrUserNotFound, nil, http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Failed to update ban", http.StatusInternalServerError)
+			return
+		}
+
+		action := "ban_user"
+		if !req.Banned {
+			action = "unban_user"
+		}
+		s.auditAdminAction(r, action, "user", userID.String(), req.Reason, nil)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]bool{"banned": req.Banned})
+	}
+}
@@ -4,8 +4,13 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"gator-swamp/internal/database"
 	"gator-swamp/internal/engine/actors"
+	"gator-swamp/internal/i18n"
+	"gator-swamp/internal/middleware"
 	"gator-swamp/internal/utils"
 
 	"github.com/google/uuid"
@@ -14,7 +19,7 @@ import (
 // CreateCommentRequest represents a request to create a new comment
 type CreateCommentRequest struct {
 	Content  string `json:"content"`
-	AuthorID string `json:"authorId"`
+	AuthorID string `json:"authorId"` // Optional; must be the authenticated user
 	PostID   string `json:"postId"`
 	ParentID string `json:"parentId,omitempty"` // Optional, for replies
 }
@@ -22,7 +27,7 @@ type CreateCommentRequest struct {
 // EditCommentRequest represents a request to edit an existing comment
 type EditCommentRequest struct {
 	CommentID string `json:"commentId"`
-	AuthorID  string `json:"authorId"`
+	AuthorID  string `json:"authorId"` // Optional; must be the authenticated user
 	Content   string `json:"content"`
 }
 
@@ -40,15 +45,18 @@ func (s *Server) HandleComment() http.HandlerFunc {
 				return
 			}
 
-			log.Printf("Creating comment for post: %s by author: %s", req.PostID, req.AuthorID)
+			if strings.TrimSpace(req.Content) == "" {
+				http.Error(w, "Comment content is required", http.StatusBadRequest)
+				return
+			}
 
-			authorID, err := uuid.Parse(req.AuthorID)
-			if err != nil {
-				log.Printf("Error parsing author ID: %v", err)
-				http.Error(w, "Invalid author ID", http.StatusBadRequest)
+			authorID, ok := actingUserID(w, r, req.AuthorID)
+			if !ok {
 				return
 			}
 
+			log.Printf("Creating comment for post: %s by author: %s", req.PostID, authorID)
+
 			postID, err := uuid.Parse(req.PostID)
 			if err != nil {
 				log.Printf("Error parsing post ID: %v", err)
@@ -82,6 +90,24 @@ func (s *Server) HandleComment() http.HandlerFunc {
 				return
 			}
 
+			if appErr, ok := result.(*utils.AppError); ok {
+				var statusCode int
+				switch appErr.Code {
+				case utils.ErrNotFound:
+					statusCode = http.StatusNotFound
+				case utils.ErrInvalidInput:
+					statusCode = http.StatusBadRequest
+				case utils.ErrArchived:
+					statusCode = http.StatusForbidden
+				case utils.ErrUnauthorized:
+					statusCode = http.StatusUnauthorized
+				default:
+					statusCode = http.StatusInternalServerError
+				}
+				writeAppError(w, r, appErr, statusCode)
+				return
+			}
+
 			log.Printf("Received result from comment actor: %+v", result)
 			w.Header().Set("Content-Type", "application/json")
 			if err := json.NewEncoder(w).Encode(result); err != nil {
@@ -99,15 +125,19 @@ func (s *Server) HandleComment() http.HandlerFunc {
 				return
 			}
 
+			if strings.TrimSpace(req.Content) == "" {
+				http.Error(w, "Comment content is required", http.StatusBadRequest)
+				return
+			}
+
 			commentID, err := uuid.Parse(req.CommentID)
 			if err != nil {
 				http.Error(w, "Invalid comment ID", http.StatusBadRequest)
 				return
 			}
 
-			authorID, err := uuid.Parse(req.AuthorID)
-			if err != nil {
-				http.Error(w, "Invalid author ID", http.StatusBadRequest)
+			authorID, ok := actingUserID(w, r, req.AuthorID)
+			if !ok {
 				return
 			}
 
@@ -123,16 +153,32 @@ func (s *Server) HandleComment() http.HandlerFunc {
 				return
 			}
 
+			if appErr, ok := result.(*utils.AppError); ok {
+				var statusCode int
+				switch appErr.Code {
+				case utils.ErrNotFound:
+					statusCode = http.StatusNotFound
+				case utils.ErrUnauthorized:
+					statusCode = http.StatusUnauthorized
+				case utils.ErrGone:
+					statusCode = http.StatusGone
+				case utils.ErrInvalidInput:
+					statusCode = http.StatusBadRequest
+				default:
+					statusCode = http.StatusInternalServerError
+				}
+				writeAppError(w, r, appErr, statusCode)
+				return
+			}
+
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(result)
 
 		case http.MethodDelete:
 			// Delete comment
 			commentID := r.URL.Query().Get("commentId")
-			authorID := r.URL.Query().Get("authorId")
-
-			if commentID == "" || authorID == "" {
-				http.Error(w, "Missing comment ID or author ID", http.StatusBadRequest)
+			if commentID == "" {
+				http.Error(w, "Missing comment ID", http.StatusBadRequest)
 				return
 			}
 
@@ -142,9 +188,8 @@ func (s *Server) HandleComment() http.HandlerFunc {
 				return
 			}
 
-			aID, err := uuid.Parse(authorID)
-			if err != nil {
-				http.Error(w, "Invalid author ID", http.StatusBadRequest)
+			aID, ok := actingUserID(w, r, r.URL.Query().Get("authorId"))
+			if !ok {
 				return
 			}
 
@@ -159,25 +204,47 @@ func (s *Server) HandleComment() http.HandlerFunc {
 				return
 			}
 
+			if appErr, ok := result.(*utils.AppError); ok {
+				var statusCode int
+				switch appErr.Code {
+				case utils.ErrNotFound:
+					statusCode = http.StatusNotFound
+				case utils.ErrUnauthorized:
+					statusCode = http.StatusUnauthorized
+				case utils.ErrGone:
+					statusCode = http.StatusGone
+				default:
+					statusCode = http.StatusInternalServerError
+				}
+				writeAppError(w, r, appErr, statusCode)
+				return
+			}
+
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(map[string]bool{"success": result.(bool)})
 
 		case http.MethodGet:
-			// Get a specific comment
-			commentID := r.URL.Query().Get("commentId")
-			if commentID == "" {
-				http.Error(w, "Missing comment ID", http.StatusBadRequest)
+			// Permalink: the comment with up to `context` ancestors and its direct replies
+			commentID, err := uuid.Parse(r.URL.Query().Get("commentId"))
+			if err != nil {
+				writeLocalizedError(w, r, i18n.ErrCommentNotFound, nil, http.StatusNotFound)
 				return
 			}
 
-			cID, err := uuid.Parse(commentID)
-			if err != nil {
-				http.Error(w, "Invalid comment ID", http.StatusBadRequest)
-				return
+			depth := 0
+			if contextStr := r.URL.Query().Get("context"); contextStr != "" {
+				depth, err = strconv.Atoi(contextStr)
+				if err != nil || depth < 0 {
+					http.Error(w, "Invalid context", http.StatusBadRequest)
+					return
+				}
 			}
 
-			future := s.Context.RequestFuture(s.CommentActor, &actors.GetCommentMsg{
-				CommentID: cID,
+			viewerID, _ := middleware.GetUserIDFromContext(r.Context())
+			future := s.Context.RequestFuture(s.CommentActor, &actors.GetCommentContextMsg{
+				CommentID: commentID,
+				Context:   depth,
+				ViewerID:  viewerID,
 			}, s.RequestTimeout)
 
 			result, err := future.Result()
@@ -186,18 +253,17 @@ func (s *Server) HandleComment() http.HandlerFunc {
 				return
 			}
 
-			// Check for application errors
 			if appErr, ok := result.(*utils.AppError); ok {
 				var statusCode int
 				switch appErr.Code {
 				case utils.ErrNotFound:
 					statusCode = http.StatusNotFound
-				case utils.ErrDatabase:
-					statusCode = http.StatusInternalServerError
+				case utils.ErrUnauthorized:
+					statusCode = http.StatusUnauthorized
 				default:
 					statusCode = http.StatusInternalServerError
 				}
-				http.Error(w, appErr.Error(), statusCode)
+				writeAppError(w, r, appErr, statusCode)
 				return
 			}
 
@@ -209,7 +275,252 @@ func (s *Server) HandleComment() http.HandlerFunc {
 	}
 }
 
-// HandleGetPostComments retrieves all comments for a given post
+// HandleUndeleteComment restores a comment the requester deleted within the undo window
+func (s *Server) HandleUndeleteComment() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := middleware.GetUserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			CommentID string `json:"commentId"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		commentID, err := uuid.Parse(req.CommentID)
+		if err != nil {
+			http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+			return
+		}
+
+		future := s.Context.RequestFuture(s.CommentActor, &actors.UndeleteCommentMsg{
+			CommentID:   commentID,
+			RequesterID: userID,
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to restore comment", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			writeAppError(w, r, appErr, undeleteErrorStatus(appErr))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleSearchComments searches the comments of a single post
+func (s *Server) HandleSearchComments() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		postID, err := uuid.Parse(r.URL.Query().Get("postId"))
+		if err != nil {
+			http.Error(w, "Invalid post ID", http.StatusBadRequest)
+			return
+		}
+
+		query := strings.TrimSpace(r.URL.Query().Get("q"))
+		if query == "" {
+			http.Error(w, "Search query is required", http.StatusBadRequest)
+			return
+		}
+
+		limit := 0
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			limit, err = strconv.Atoi(limitStr)
+			if err != nil || limit <= 0 {
+				http.Error(w, "Invalid limit", http.StatusBadRequest)
+				return
+			}
+		}
+
+		viewerID, _ := middleware.GetUserIDFromContext(r.Context())
+		future := s.Context.RequestFuture(s.CommentActor, &actors.SearchCommentsMsg{
+			PostID:   postID,
+			Query:    query,
+			Limit:    limit,
+			ViewerID: viewerID,
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to search comments", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrNotFound:
+				statusCode = http.StatusNotFound
+			case utils.ErrUnauthorized:
+				statusCode = http.StatusUnauthorized
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleDistinguishComment distinguishes or stickies a comment for moderators and post authors
+func (s *Server) HandleDistinguishComment() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := middleware.GetUserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			CommentID       string `json:"commentId"`
+			DistinguishedAs string `json:"distinguishedAs"`
+			Sticky          bool   `json:"sticky"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		commentID, err := uuid.Parse(req.CommentID)
+		if err != nil {
+			http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+			return
+		}
+
+		future := s.Context.RequestFuture(s.CommentActor, &actors.DistinguishCommentMsg{
+			CommentID:       commentID,
+			RequesterID:     userID,
+			DistinguishedAs: req.DistinguishedAs,
+			Sticky:          req.Sticky,
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to distinguish comment", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrNotFound:
+				statusCode = http.StatusNotFound
+			case utils.ErrForbidden:
+				statusCode = http.StatusForbidden
+			case utils.ErrGone:
+				statusCode = http.StatusGone
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleGetCommentHistory returns the previous versions of a comment to its author or a moderator
+func (s *Server) HandleGetCommentHistory() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := middleware.GetUserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		commentID, err := uuid.Parse(r.URL.Query().Get("commentId"))
+		if err != nil {
+			http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+			return
+		}
+
+		future := s.Context.RequestFuture(s.CommentActor, &actors.GetCommentHistoryMsg{
+			CommentID:   commentID,
+			RequesterID: userID,
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to get comment history", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrNotFound:
+				statusCode = http.StatusNotFound
+			case utils.ErrForbidden:
+				statusCode = http.StatusForbidden
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// undeleteErrorStatus maps the errors of an undelete request to HTTP status codes
+func undeleteErrorStatus(appErr *utils.AppError) int {
+	switch appErr.Code {
+	case utils.ErrNotFound:
+		return http.StatusNotFound
+	case utils.ErrUnauthorized:
+		return http.StatusUnauthorized
+	case utils.ErrForbidden:
+		return http.StatusForbidden
+	case utils.ErrGone:
+		return http.StatusGone
+	case utils.ErrInvalidInput:
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// HandleGetPostComments retrieves all comments for a given post as a tree of replies
 func (s *Server) HandleGetPostComments() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -229,8 +540,77 @@ func (s *Server) HandleGetPostComments() http.HandlerFunc {
 			return
 		}
 
-		future := s.Context.RequestFuture(s.CommentActor, &actors.GetCommentsForPostMsg{
-			PostID: pID,
+		limit := 0
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			limit, err = strconv.Atoi(limitStr)
+			if err != nil || limit <= 0 {
+				http.Error(w, "Invalid limit", http.StatusBadRequest)
+				return
+			}
+		}
+
+		sort := r.URL.Query().Get("sort")
+		switch sort {
+		case "", database.SortOld, database.SortNew, database.SortTop, database.SortControversial, database.SortBest:
+		default:
+			http.Error(w, "sort must be one of old, new, top, controversial, best", http.StatusBadRequest)
+			return
+		}
+
+		maxDepth := 0
+		if maxDepthStr := r.URL.Query().Get("maxDepth"); maxDepthStr != "" {
+			maxDepth, err = strconv.Atoi(maxDepthStr)
+			if err != nil || maxDepth <= 0 {
+				http.Error(w, "Invalid maxDepth", http.StatusBadRequest)
+				return
+			}
+		}
+
+		maxNodes := 0
+		if maxNodesStr := r.URL.Query().Get("maxNodes"); maxNodesStr != "" {
+			maxNodes, err = strconv.Atoi(maxNodesStr)
+			if err != nil || maxNodes <= 0 {
+				http.Error(w, "Invalid maxNodes", http.StatusBadRequest)
+				return
+			}
+		}
+
+		// A continuation token is the ID of the comment whose branch was cut off
+		var branchID *uuid.UUID
+		if token := r.URL.Query().Get("continue"); token != "" {
+			parsed, err := uuid.Parse(token)
+			if err != nil {
+				http.Error(w, "Invalid continuation token", http.StatusBadRequest)
+				return
+			}
+			branchID = &parsed
+		}
+
+		// Without a threshold in the request, the viewer's preferred one applies, and
+		// failing that the site default
+		viewerID, _ := middleware.GetUserIDFromContext(r.Context())
+		collapseThreshold := s.Config.CommentCollapseThreshold
+		if thresholdStr := r.URL.Query().Get("collapseThreshold"); thresholdStr != "" {
+			collapseThreshold, err = strconv.Atoi(thresholdStr)
+			if err != nil {
+				http.Error(w, "Invalid collapseThreshold", http.StatusBadRequest)
+				return
+			}
+		} else if preferred := s.viewerPreferences(r, viewerID).CollapseThreshold; preferred != nil {
+			collapseThreshold = *preferred
+		}
+
+		future := s.Context.RequestFuture(s.CommentActor, &actors.GetCommentTreeMsg{
+			PostID:            pID,
+			Sort:              sort,
+			Limit:             limit,
+			Cursor:            r.URL.Query().Get("after"),
+			MaxDepth:          maxDepth,
+			MaxNodes:          maxNodes,
+			BranchID:          branchID,
+			CollapseThreshold: collapseThreshold,
+			Expand:            r.URL.Query().Get("expand") == "true",
+			ViewerID:          viewerID,
 		}, s.RequestTimeout)
 
 		result, err := future.Result()
@@ -239,6 +619,22 @@ func (s *Server) HandleGetPostComments() http.HandlerFunc {
 			return
 		}
 
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrNotFound:
+				statusCode = http.StatusNotFound
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			case utils.ErrUnauthorized:
+				statusCode = http.StatusUnauthorized
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(result)
 	}
@@ -269,9 +665,8 @@ func (s *Server) HandleCommentVote() http.HandlerFunc {
 			return
 		}
 
-		userID, err := uuid.Parse(req.UserID)
-		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		userID, ok := actingUserID(w, r, req.UserID)
+		if !ok {
 			return
 		}
 
@@ -288,6 +683,20 @@ func (s *Server) HandleCommentVote() http.HandlerFunc {
 			return
 		}
 
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrNotFound:
+				statusCode = http.StatusNotFound
+			case utils.ErrDuplicate:
+				statusCode = http.StatusConflict
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(result)
 	}
@@ -3,9 +3,16 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
+	"gator-swamp/internal/analytics"
 	"gator-swamp/internal/engine/actors"
+	"gator-swamp/internal/i18n"
+	"gator-swamp/internal/middleware"
+	"gator-swamp/internal/models"
 	"gator-swamp/internal/utils"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,19 +20,74 @@ import (
 
 // CreatePostRequest represents a request to create a new post
 type CreatePostRequest struct {
-	Title       string `json:"title"`       // Post title
-	Content     string `json:"content"`     // Post content
-	AuthorID    string `json:"authorId"`    // Author ID (UUID as string)
-	SubredditID string `json:"subredditId"` // Subreddit ID (UUID as string)
+	Title       string             `json:"title"`       // Post title
+	Content     string             `json:"content"`     // Post content
+	URL         string             `json:"url"`         // Optional link; makes this a link post
+	MediaURLs   []string           `json:"mediaUrls"`   // Optional image or video URLs
+	FlairID     string             `json:"flairId"`     // Optional flair template ID from the subreddit
+	Force       bool               `json:"force"`       // Moderators only: post a link even if it was posted recently
+	ScheduledAt *time.Time         `json:"scheduledAt"` // Optional future publish time
+	Poll        *CreatePollRequest `json:"poll"`        // Optional; makes this a poll post
+	AuthorID    string             `json:"authorId"`    // Optional; must be the authenticated user
+	SubredditID string             `json:"subredditId"` // Subreddit ID (UUID as string)
+
+	// Optional key that makes retries safe; the Idempotency-Key header takes precedence
+	IdempotencyKey string `json:"idempotencyKey"`
+}
+
+// maxIdempotencyKeyLength is the longest idempotency key accepted when creating a post
+const maxIdempotencyKeyLength = 255
+
+// CreatePollRequest describes the poll of a new poll post
+type CreatePollRequest struct {
+	Options       []string `json:"options"`       // 2 to 6 choices
+	DurationHours int      `json:"durationHours"` // How long the poll stays open, 1 to 168 hours
+}
+
+// EditPostRequest represents a request to edit a post. Omitted fields are left unchanged.
+type EditPostRequest struct {
+	PostID   string  `json:"postId"`
+	AuthorID string  `json:"authorId"` // Optional; must be the authenticated user
+	Title    *string `json:"title,omitempty"`
+	Content  *string `json:"content,omitempty"`
 }
 
 // VoteRequest represents a request to vote on a post
 type VoteRequest struct {
-	UserID   string `json:"userId"`
+	UserID   string `json:"userId"` // Optional; must be the authenticated user
 	PostID   string `json:"postId"`
 	IsUpvote bool   `json:"isUpvote"`
 }
 
+// UnvoteRequest represents a request to take back a vote on a post
+type UnvoteRequest struct {
+	UserID string `json:"userId"` // Optional; must be the authenticated user
+	PostID string `json:"postId"`
+}
+
+// VoteBatchRequest represents a batch of votes queued by an offline client
+type VoteBatchRequest struct {
+	UserID string `json:"userId"` // Optional; must be the authenticated user
+	Votes  []struct {
+		PostID          string     `json:"postId"`
+		IsUpvote        bool       `json:"isUpvote"`
+		ClientTimestamp *time.Time `json:"clientTimestamp"`
+	} `json:"votes"`
+}
+
+// CrosspostRequest represents a request to share a post into another subreddit
+type CrosspostRequest struct {
+	OriginalPostID    string `json:"originalPostId"`
+	TargetSubredditID string `json:"targetSubredditId"`
+	AuthorID          string `json:"authorId"` // Optional; must be the authenticated user
+}
+
+// HidePostRequest represents a request to hide or unhide a post for a user
+type HidePostRequest struct {
+	UserID string `json:"userId"` // Optional; must be the authenticated user
+	PostID string `json:"postId"`
+}
+
 // HandleHealth handles health check requests
 func (s *Server) HandleHealth() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -60,6 +122,8 @@ func (s *Server) HandleHealth() http.HandlerFunc {
 			"subreddit_count": subredditCount,
 			"post_count":      postCount,
 			"server_time":     time.Now(),
+			// Fallbacks to English per "lang:key", to spot translation gaps
+			"missing_translations": i18n.MissingTranslations(),
 		})
 	}
 }
@@ -76,9 +140,8 @@ func (s *Server) HandlePost() http.HandlerFunc {
 				return
 			}
 
-			authorID, err := uuid.Parse(req.AuthorID)
-			if err != nil {
-				http.Error(w, "Invalid author ID format", http.StatusBadRequest)
+			authorID, ok := actingUserID(w, r, req.AuthorID)
+			if !ok {
 				return
 			}
 
@@ -88,11 +151,46 @@ func (s *Server) HandlePost() http.HandlerFunc {
 				return
 			}
 
+			var flairID *uuid.UUID
+			if req.FlairID != "" {
+				id, err := uuid.Parse(req.FlairID)
+				if err != nil {
+					http.Error(w, "Invalid flair ID format", http.StatusBadRequest)
+					return
+				}
+				flairID = &id
+			}
+
+			idempotencyKey := strings.TrimSpace(r.Header.Get("Idempotency-Key"))
+			if idempotencyKey == "" {
+				idempotencyKey = strings.TrimSpace(req.IdempotencyKey)
+			}
+			if len(idempotencyKey) > maxIdempotencyKeyLength {
+				http.Error(w, "Idempotency key is too long", http.StatusBadRequest)
+				return
+			}
+
+			var poll *actors.PollSpec
+			if req.Poll != nil {
+				poll = &actors.PollSpec{
+					Options:  req.Poll.Options,
+					Duration: time.Duration(req.Poll.DurationHours) * time.Hour,
+				}
+			}
+
 			future := s.Context.RequestFuture(s.EnginePID, &actors.CreatePostMsg{
 				Title:       req.Title,
 				Content:     req.Content,
+				URL:         req.URL,
+				MediaURLs:   req.MediaURLs,
+				FlairID:     flairID,
 				AuthorID:    authorID,
 				SubredditID: subredditID,
+				Force:       req.Force,
+				ScheduledAt: req.ScheduledAt,
+				Poll:        poll,
+
+				IdempotencyKey: idempotencyKey,
 			}, s.RequestTimeout)
 
 			result, err := future.Result()
@@ -113,13 +211,29 @@ func (s *Server) HandlePost() http.HandlerFunc {
 					statusCode = http.StatusBadRequest
 				case utils.ErrUnauthorized:
 					statusCode = http.StatusUnauthorized
+				case utils.ErrForbidden:
+					statusCode = http.StatusForbidden
+				case utils.ErrDuplicate:
+					// Point the client at the existing discussion, unless the conflict is
+					// a reused idempotency key
+					statusCode = http.StatusConflict
+					if postID := appErr.Params["postId"]; postID != "" {
+						w.Header().Set("Location", "/post?id="+postID)
+					}
+				case utils.ErrTooManyRequests:
+					statusCode = http.StatusTooManyRequests
+					w.Header().Set("Retry-After", appErr.Params["retryAfter"])
 				default:
 					statusCode = http.StatusInternalServerError
 				}
-				http.Error(w, appErr.Error(), statusCode)
+				writeAppError(w, r, appErr, statusCode)
 				return
 			}
 
+			if post, ok := result.(*models.Post); ok && post.Status == models.PostStatusPublished {
+				analytics.RecordInSubreddit(analytics.EventPost, post.ID, post.AuthorID, post.SubredditID)
+			}
+
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(result)
 
@@ -136,8 +250,9 @@ func (s *Server) HandlePost() http.HandlerFunc {
 					return
 				}
 
+				viewerID, _ := middleware.GetUserIDFromContext(r.Context())
 				future := s.Context.RequestFuture(s.Engine.GetPostActor(),
-					&actors.GetPostMsg{PostID: id},
+					&actors.GetPostMsg{PostID: id, ViewerID: viewerID},
 					s.RequestTimeout)
 
 				result, err := future.Result()
@@ -152,15 +267,22 @@ func (s *Server) HandlePost() http.HandlerFunc {
 					switch appErr.Code {
 					case utils.ErrNotFound:
 						statusCode = http.StatusNotFound
+					case utils.ErrUnauthorized:
+						statusCode = http.StatusUnauthorized
 					case utils.ErrDatabase:
 						statusCode = http.StatusInternalServerError
 					default:
 						statusCode = http.StatusInternalServerError
 					}
-					http.Error(w, appErr.Error(), statusCode)
+					writeAppError(w, r, appErr, statusCode)
 					return
 				}
 
+				if post, ok := result.(*models.Post); ok {
+					analytics.RecordInSubreddit(analytics.EventView, post.ID, viewerID, post.SubredditID)
+					s.Context.Send(s.Engine.GetPostActor(), &actors.RecordPostViewMsg{PostID: post.ID})
+				}
+
 				w.Header().Set("Content-Type", "application/json")
 				json.NewEncoder(w).Encode(result)
 				return
@@ -174,8 +296,56 @@ func (s *Server) HandlePost() http.HandlerFunc {
 					return
 				}
 
+				limit := 0
+				if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+					limit, err = strconv.Atoi(limitStr)
+					if err != nil || limit <= 0 {
+						http.Error(w, "Invalid limit", http.StatusBadRequest)
+						return
+					}
+				}
+
+				// Callers that identify themselves don't see the posts they've hidden. The
+				// viewer also decides access to private subreddits, so it can't be someone else.
+				viewerID, _ := middleware.GetUserIDFromContext(r.Context())
+				if userIDStr := r.URL.Query().Get("userId"); userIDStr != "" {
+					queryID, err := uuid.Parse(userIDStr)
+					if err != nil {
+						http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+						return
+					}
+					if viewerID != uuid.Nil && queryID != viewerID {
+						http.Error(w, "userId must be the authenticated user", http.StatusForbidden)
+						return
+					}
+					viewerID = queryID
+				}
+
+				flairID := uuid.Nil
+				if flairStr := r.URL.Query().Get("flair"); flairStr != "" {
+					flairID, err = uuid.Parse(flairStr)
+					if err != nil {
+						http.Error(w, "Invalid flair ID format", http.StatusBadRequest)
+						return
+					}
+				}
+
+				// Viewers' preferred sort applies when they don't ask for one
+				sort := r.URL.Query().Get("sort")
+				if sort == "" {
+					sort = s.viewerPreferences(r, viewerID).FeedSort
+				}
+
 				future := s.Context.RequestFuture(s.Engine.GetPostActor(),
-					&actors.GetSubredditPostsMsg{SubredditID: id},
+					&actors.GetSubredditPostsMsg{
+						SubredditID: id,
+						ViewerID:    viewerID,
+						FlairID:     flairID,
+						Sort:        sort,
+						Window:      r.URL.Query().Get("t"),
+						Limit:       limit,
+						Cursor:      r.URL.Query().Get("after"),
+					},
 					s.RequestTimeout)
 
 				result, err := future.Result()
@@ -184,6 +354,22 @@ func (s *Server) HandlePost() http.HandlerFunc {
 					return
 				}
 
+				if appErr, ok := result.(*utils.AppError); ok {
+					var statusCode int
+					switch appErr.Code {
+					case utils.ErrInvalidInput:
+						statusCode = http.StatusBadRequest
+					case utils.ErrNotFound:
+						statusCode = http.StatusNotFound
+					case utils.ErrUnauthorized:
+						statusCode = http.StatusUnauthorized
+					default:
+						statusCode = http.StatusInternalServerError
+					}
+					writeAppError(w, r, appErr, statusCode)
+					return
+				}
+
 				w.Header().Set("Content-Type", "application/json")
 				json.NewEncoder(w).Encode(result)
 				return
@@ -191,12 +377,164 @@ func (s *Server) HandlePost() http.HandlerFunc {
 
 			http.Error(w, "Either post ID or subreddit ID is required", http.StatusBadRequest)
 
+		case http.MethodPut:
+			// Edit post
+			var req EditPostRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request", http.StatusBadRequest)
+				return
+			}
+
+			postID, err := uuid.Parse(req.PostID)
+			if err != nil {
+				http.Error(w, "Invalid post ID format", http.StatusBadRequest)
+				return
+			}
+
+			authorID, ok := actingUserID(w, r, req.AuthorID)
+			if !ok {
+				return
+			}
+
+			future := s.Context.RequestFuture(s.EnginePID, &actors.EditPostMsg{
+				PostID:   postID,
+				AuthorID: authorID,
+				Title:    req.Title,
+				Content:  req.Content,
+			}, s.RequestTimeout)
+
+			result, err := future.Result()
+			if err != nil {
+				http.Error(w, "Failed to edit post", http.StatusInternalServerError)
+				return
+			}
+
+			if appErr, ok := result.(*utils.AppError); ok {
+				var statusCode int
+				switch appErr.Code {
+				case utils.ErrNotFound:
+					statusCode = http.StatusNotFound
+				case utils.ErrUnauthorized:
+					statusCode = http.StatusUnauthorized
+				case utils.ErrInvalidInput:
+					statusCode = http.StatusBadRequest
+				case utils.ErrArchived:
+					statusCode = http.StatusForbidden
+				default:
+					statusCode = http.StatusInternalServerError
+				}
+				writeAppError(w, r, appErr, statusCode)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(result)
+
+		case http.MethodDelete:
+			// Delete a post as its author or a subreddit moderator
+			userID, ok := middleware.GetUserIDFromContext(r.Context())
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			postID, err := uuid.Parse(r.URL.Query().Get("id"))
+			if err != nil {
+				http.Error(w, "Invalid post ID format", http.StatusBadRequest)
+				return
+			}
+
+			future := s.Context.RequestFuture(s.EnginePID, &actors.DeletePostMsg{
+				PostID: postID,
+				UserID: userID,
+			}, s.RequestTimeout)
+
+			result, err := future.Result()
+			if err != nil {
+				http.Error(w, "Failed to delete post", http.StatusInternalServerError)
+				return
+			}
+
+			if appErr, ok := result.(*utils.AppError); ok {
+				var statusCode int
+				switch appErr.Code {
+				case utils.ErrNotFound:
+					statusCode = http.StatusNotFound
+				case utils.ErrUnauthorized:
+					statusCode = http.StatusUnauthorized
+				case utils.ErrGone:
+					statusCode = http.StatusGone
+				case utils.ErrArchived:
+					statusCode = http.StatusForbidden
+				default:
+					statusCode = http.StatusInternalServerError
+				}
+				writeAppError(w, r, appErr, statusCode)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]bool{"success": result.(bool)})
+
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	}
 }
 
+// HandlePostBySlug serves GET /r/{subreddit}/post/{slug}, the readable permalink of a
+// post. Slugs the post had before its title was edited redirect to the current one.
+func (s *Server) HandlePostBySlug() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/r/"), "/")
+		if len(parts) != 3 || parts[0] == "" || parts[1] != "post" || parts[2] == "" {
+			http.NotFound(w, r)
+			return
+		}
+		subredditName, slug := parts[0], parts[2]
+
+		viewerID, _ := middleware.GetUserIDFromContext(r.Context())
+		future := s.Context.RequestFuture(s.Engine.GetPostActor(),
+			&actors.GetPostBySlugMsg{SubredditName: subredditName, Slug: slug, ViewerID: viewerID},
+			s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get post: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			switch appErr.Code {
+			case utils.ErrNotFound:
+				writeAppError(w, r, appErr, http.StatusNotFound)
+			case utils.ErrUnauthorized:
+				writeAppError(w, r, appErr, http.StatusUnauthorized)
+			default:
+				writeAppError(w, r, appErr, http.StatusInternalServerError)
+			}
+			return
+		}
+
+		post := result.(*models.Post)
+		if post.Slug != slug {
+			http.Redirect(w, r, "/r/"+url.PathEscape(post.SubredditName)+"/post/"+url.PathEscape(post.Slug), http.StatusMovedPermanently)
+			return
+		}
+
+		analytics.RecordInSubreddit(analytics.EventView, post.ID, viewerID, post.SubredditID)
+		s.Context.Send(s.Engine.GetPostActor(), &actors.RecordPostViewMsg{PostID: post.ID})
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(post)
+	}
+}
+
 // HandleVote handles post voting
 func (s *Server) HandleVote() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -211,9 +549,8 @@ func (s *Server) HandleVote() http.HandlerFunc {
 			return
 		}
 
-		userID, err := uuid.Parse(req.UserID)
-		if err != nil {
-			http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+		userID, ok := actingUserID(w, r, req.UserID)
+		if !ok {
 			return
 		}
 
@@ -245,45 +582,728 @@ func (s *Server) HandleVote() http.HandlerFunc {
 				statusCode = http.StatusUnauthorized
 			case utils.ErrDuplicate:
 				statusCode = http.StatusConflict
+			case utils.ErrArchived:
+				statusCode = http.StatusForbidden
 			default:
 				statusCode = http.StatusInternalServerError
 			}
-			http.Error(w, appErr.Error(), statusCode)
+			writeAppError(w, r, appErr, statusCode)
 			return
 		}
 
+		if post, ok := result.(*models.Post); ok {
+			analytics.RecordInSubreddit(analytics.EventVote, post.ID, userID, post.SubredditID)
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(result)
 	}
 }
 
-// HandleRecentPosts returns the most recent posts across all subreddits
-func (s *Server) HandleRecentPosts() http.HandlerFunc {
+// HandleUnvote takes back the user's vote on a post
+func (s *Server) HandleUnvote() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet {
+		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
-		limit := 10 // Default limit
-		// You can add logic to parse a limit parameter from the query string if needed
+		var req UnvoteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
 
-		// Send request to PostActor through Engine
-		future := s.Context.RequestFuture(
-			s.Engine.GetPostActor(),
-			&actors.GetRecentPostsMsg{Limit: limit},
-			s.RequestTimeout,
-		)
+		userID, ok := actingUserID(w, r, req.UserID)
+		if !ok {
+			return
+		}
+
+		postID, err := uuid.Parse(req.PostID)
+		if err != nil {
+			http.Error(w, "Invalid post ID format", http.StatusBadRequest)
+			return
+		}
+
+		future := s.Context.RequestFuture(s.EnginePID, &actors.UnvotePostMsg{
+			PostID: postID,
+			UserID: userID,
+		}, s.RequestTimeout)
 
 		result, err := future.Result()
 		if err != nil {
-			http.Error(w, "Failed to fetch recent posts", http.StatusInternalServerError)
+			http.Error(w, fmt.Sprintf("Failed to remove vote: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrNotFound:
+				statusCode = http.StatusNotFound
+			case utils.ErrArchived:
+				statusCode = http.StatusForbidden
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleVoteBatch applies a batch of votes in order and reports the outcome of each
+func (s *Server) HandleVoteBatch() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req VoteBatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+
+		userID, ok := actingUserID(w, r, req.UserID)
+		if !ok {
+			return
+		}
+
+		votes := make([]actors.BatchVote, len(req.Votes))
+		for i, vote := range req.Votes {
+			postID, err := uuid.Parse(vote.PostID)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid post ID %q", vote.PostID), http.StatusBadRequest)
+				return
+			}
+			votes[i] = actors.BatchVote{PostID: postID, IsUpvote: vote.IsUpvote, ClientTimestamp: vote.ClientTimestamp}
+		}
+
+		future := s.Context.RequestFuture(s.EnginePID, &actors.VotePostBatchMsg{
+			UserID: userID,
+			Votes:  votes,
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to process votes: %v", err), http.StatusInternalServerError)
 			return
 		}
 
-		// Check for application errors
 		if appErr, ok := result.(*utils.AppError); ok {
-			http.Error(w, appErr.Error(), http.StatusInternalServerError)
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		if results, ok := result.([]actors.BatchVoteResult); ok {
+			for _, item := range results {
+				if item.Status != actors.BatchVoteApplied {
+					continue
+				}
+				if postID, err := uuid.Parse(item.PostID); err == nil {
+					analytics.RecordInSubreddit(analytics.EventVote, postID, userID, item.SubredditID)
+				}
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleGetPostVoteStatuses reports how a user voted on each of a list of posts, for
+// clients rendering vote arrows across a feed
+func (s *Server) HandleGetPostVoteStatuses() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := actingUserID(w, r, r.URL.Query().Get("userId"))
+		if !ok {
+			return
+		}
+
+		postIDsParam := strings.TrimSpace(r.URL.Query().Get("postIds"))
+		if postIDsParam == "" {
+			http.Error(w, "postIds is required", http.StatusBadRequest)
+			return
+		}
+		var postIDs []uuid.UUID
+		for _, idStr := range strings.Split(postIDsParam, ",") {
+			id, err := uuid.Parse(strings.TrimSpace(idStr))
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid post ID %q", idStr), http.StatusBadRequest)
+				return
+			}
+			postIDs = append(postIDs, id)
+		}
+
+		future := s.Context.RequestFuture(s.EnginePID, &actors.GetPostVoteStatusesMsg{
+			UserID:  userID,
+			PostIDs: postIDs,
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to get votes", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleHidePost hides a post from a user's feed and subreddit listings
+func (s *Server) HandleHidePost() http.HandlerFunc {
+	return s.handleHiddenPostChange(true)
+}
+
+// HandleUnhidePost shows a hidden post again
+func (s *Server) HandleUnhidePost() http.HandlerFunc {
+	return s.handleHiddenPostChange(false)
+}
+
+// handleHiddenPostChange serves both hide and unhide, which take the same request
+func (s *Server) handleHiddenPostChange(hide bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req HidePostRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+
+		userID, ok := actingUserID(w, r, req.UserID)
+		if !ok {
+			return
+		}
+
+		postID, err := uuid.Parse(req.PostID)
+		if err != nil {
+			http.Error(w, "Invalid post ID format", http.StatusBadRequest)
+			return
+		}
+
+		var msg interface{} = &actors.UnhidePostMsg{PostID: postID, UserID: userID}
+		if hide {
+			msg = &actors.HidePostMsg{PostID: postID, UserID: userID}
+		}
+
+		future := s.Context.RequestFuture(s.EnginePID, msg, s.RequestTimeout)
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to update hidden posts", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrNotFound:
+				statusCode = http.StatusNotFound
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleGetHiddenPosts lists the posts the authenticated user has hidden
+func (s *Server) HandleGetHiddenPosts() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := middleware.GetUserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		limit := 0
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			var err error
+			limit, err = strconv.Atoi(limitStr)
+			if err != nil || limit <= 0 {
+				http.Error(w, "Invalid limit", http.StatusBadRequest)
+				return
+			}
+		}
+
+		future := s.Context.RequestFuture(s.EnginePID, &actors.GetHiddenPostsMsg{
+			UserID: userID,
+			Limit:  limit,
+			Cursor: r.URL.Query().Get("after"),
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to get hidden posts", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleRecentPosts returns the most recent posts across all subreddits
+func (s *Server) HandleRecentPosts() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		limit := 10 // Default limit
+		// You can add logic to parse a limit parameter from the query string if needed
+
+		// Send request to PostActor through Engine
+		future := s.Context.RequestFuture(
+			s.Engine.GetPostActor(),
+			&actors.GetRecentPostsMsg{Limit: limit},
+			s.RequestTimeout,
+		)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to fetch recent posts", http.StatusInternalServerError)
+			return
+		}
+
+		// Check for application errors
+		if appErr, ok := result.(*utils.AppError); ok {
+			writeAppError(w, r, appErr, http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleUndeletePost restores a post the requester deleted within the undo window
+func (s *Server) HandleUndeletePost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := middleware.GetUserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			PostID string `json:"postId"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		postID, err := uuid.Parse(req.PostID)
+		if err != nil {
+			http.Error(w, "Invalid post ID", http.StatusBadRequest)
+			return
+		}
+
+		future := s.Context.RequestFuture(s.EnginePID, &actors.UndeletePostMsg{
+			PostID:      postID,
+			RequesterID: userID,
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to restore post", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			writeAppError(w, r, appErr, undeleteErrorStatus(appErr))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleCrosspost shares an existing post into another subreddit (POST /post/crosspost)
+func (s *Server) HandleCrosspost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req CrosspostRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		originalPostID, err := uuid.Parse(req.OriginalPostID)
+		if err != nil {
+			http.Error(w, "Invalid original post ID format", http.StatusBadRequest)
+			return
+		}
+		targetSubredditID, err := uuid.Parse(req.TargetSubredditID)
+		if err != nil {
+			http.Error(w, "Invalid subreddit ID format", http.StatusBadRequest)
+			return
+		}
+		authorID, ok := actingUserID(w, r, req.AuthorID)
+		if !ok {
+			return
+		}
+
+		future := s.Context.RequestFuture(s.EnginePID, &actors.CrosspostMsg{
+			OriginalPostID:    originalPostID,
+			TargetSubredditID: targetSubredditID,
+			AuthorID:          authorID,
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to crosspost", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrNotFound:
+				statusCode = http.StatusNotFound
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			case utils.ErrUnauthorized:
+				statusCode = http.StatusUnauthorized
+			case utils.ErrTooManyRequests:
+				statusCode = http.StatusTooManyRequests
+				w.Header().Set("Retry-After", appErr.Params["retryAfter"])
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		if post, ok := result.(*models.Post); ok {
+			analytics.RecordInSubreddit(analytics.EventPost, post.ID, post.AuthorID, post.SubredditID)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleVotePoll records the authenticated user's choice in a poll (POST /post/poll/vote)
+func (s *Server) HandleVotePoll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := middleware.GetUserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			PostID string `json:"postId"`
+			Option int    `json:"option"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		postID, err := uuid.Parse(req.PostID)
+		if err != nil {
+			http.Error(w, "Invalid post ID", http.StatusBadRequest)
+			return
+		}
+
+		future := s.Context.RequestFuture(s.EnginePID, &actors.VotePollMsg{
+			PostID: postID,
+			UserID: userID,
+			Option: req.Option,
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to record poll vote", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrNotFound:
+				statusCode = http.StatusNotFound
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			case utils.ErrDuplicate:
+				statusCode = http.StatusConflict
+			case utils.ErrForbidden, utils.ErrArchived:
+				statusCode = http.StatusForbidden
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleTopPosts lists the highest-karma posts across all subreddits over a time window
+// (GET /posts/top?t=&limit=&after=)
+func (s *Server) HandleTopPosts() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		limit := 0
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			var err error
+			limit, err = strconv.Atoi(limitStr)
+			if err != nil || limit <= 0 {
+				http.Error(w, "Invalid limit", http.StatusBadRequest)
+				return
+			}
+		}
+
+		viewerID, _ := middleware.GetUserIDFromContext(r.Context())
+		future := s.Context.RequestFuture(s.EnginePID, &actors.GetTopPostsMsg{
+			ViewerID: viewerID,
+			Sort:     r.URL.Query().Get("sort"),
+			Window:   r.URL.Query().Get("t"),
+			Limit:    limit,
+			Cursor:   r.URL.Query().Get("after"),
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to get top posts", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleRelatedPosts returns posts from the same subreddit with titles like the given post's
+func (s *Server) HandleRelatedPosts() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		postID, err := uuid.Parse(r.URL.Query().Get("postId"))
+		if err != nil {
+			http.Error(w, "Invalid post ID", http.StatusBadRequest)
+			return
+		}
+
+		limit := 0
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			limit, err = strconv.Atoi(limitStr)
+			if err != nil || limit <= 0 {
+				http.Error(w, "Invalid limit", http.StatusBadRequest)
+				return
+			}
+		}
+
+		viewerID, _ := middleware.GetUserIDFromContext(r.Context())
+		future := s.Context.RequestFuture(s.EnginePID, &actors.GetRelatedPostsMsg{
+			PostID:   postID,
+			ViewerID: viewerID,
+			Limit:    limit,
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to get related posts", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrNotFound:
+				statusCode = http.StatusNotFound
+			case utils.ErrUnauthorized:
+				statusCode = http.StatusUnauthorized
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleTrendingPosts returns the recent posts gaining upvotes fastest
+func (s *Server) HandleTrendingPosts() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		limit := 0
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			var err error
+			limit, err = strconv.Atoi(limitStr)
+			if err != nil || limit <= 0 {
+				http.Error(w, "Invalid limit", http.StatusBadRequest)
+				return
+			}
+		}
+
+		viewerID, _ := middleware.GetUserIDFromContext(r.Context())
+		future := s.Context.RequestFuture(s.EnginePID, &actors.GetTrendingPostsMsg{
+			ViewerID: viewerID,
+			Limit:    limit,
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to get trending posts", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			writeAppError(w, r, appErr, http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleGetPostsByIDs returns many posts at once, for clients hydrating saved or hidden lists
+func (s *Server) HandleGetPostsByIDs() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		idsParam := strings.TrimSpace(r.URL.Query().Get("ids"))
+		if idsParam == "" {
+			http.Error(w, "ids is required", http.StatusBadRequest)
+			return
+		}
+		var postIDs []uuid.UUID
+		for _, idStr := range strings.Split(idsParam, ",") {
+			id, err := uuid.Parse(strings.TrimSpace(idStr))
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid post ID %q", idStr), http.StatusBadRequest)
+				return
+			}
+			postIDs = append(postIDs, id)
+		}
+
+		viewerID, _ := middleware.GetUserIDFromContext(r.Context())
+		future := s.Context.RequestFuture(s.EnginePID, &actors.GetPostsByIDsMsg{
+			PostIDs:  postIDs,
+			ViewerID: viewerID,
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to get posts", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
 			return
 		}
 
@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"gator-swamp/internal/i18n"
+	"gator-swamp/internal/utils"
+	"net/http"
+)
+
+// writeAppError writes an actor error with the given status code. Localizable errors
+// are rendered in the language negotiated from the request's Accept-Language header.
+func writeAppError(w http.ResponseWriter, r *http.Request, appErr *utils.AppError, statusCode int) {
+	lang := i18n.MatchLanguage(r.Header.Get("Accept-Language"))
+	w.Header().Set("Content-Language", lang)
+	http.Error(w, appErr.LocalizedMessage(lang), statusCode)
+}
+
+// writeLocalizedError writes a handler-level error message identified by an i18n key
+func writeLocalizedError(w http.ResponseWriter, r *http.Request, key string, params map[string]string, statusCode int) {
+	lang := i18n.MatchLanguage(r.Header.Get("Accept-Language"))
+	w.Header().Set("Content-Language", lang)
+	http.Error(w, i18n.T(lang, key, params), statusCode)
+}
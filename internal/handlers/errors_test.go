@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"gator-swamp/internal/i18n"
+	"gator-swamp/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNotFoundBodyFollowsAcceptLanguage(t *testing.T) {
+	appErr := utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrPostNotFound, nil, nil)
+
+	tests := []struct {
+		acceptLanguage string
+		wantLang       string
+		wantBody       string
+	}{
+		{"", "en", "Post not found"},
+		{"en-US", "en", "Post not found"},
+		{"es", "es", "Publicación no encontrada"},
+		{"es-MX,en;q=0.5", "es", "Publicación no encontrada"},
+		{"fr, es;q=0.8", "es", "Publicación no encontrada"},
+		{"es;q=0.2, en", "en", "Post not found"},
+		{"fr", "en", "Post not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.acceptLanguage, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/post?id=x", nil)
+			if tt.acceptLanguage != "" {
+				r.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			w := httptest.NewRecorder()
+			writeAppError(w, r, appErr, http.StatusNotFound)
+
+			if w.Code != http.StatusNotFound {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+			}
+			if body := strings.TrimSpace(w.Body.String()); body != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+			if lang := w.Header().Get("Content-Language"); lang != tt.wantLang {
+				t.Errorf("Content-Language = %q, want %q", lang, tt.wantLang)
+			}
+		})
+	}
+}
+
+// A handler's own 404, rendered without going through an actor
+func TestHandlerNotFoundIsLocalized(t *testing.T) {
+	s := &Server{}
+	bodies := map[string]string{}
+	for _, lang := range []string{"en", "es"} {
+		r := httptest.NewRequest(http.MethodGet, "/s/", nil)
+		r.Header.Set("Accept-Language", lang)
+		w := httptest.NewRecorder()
+		s.HandleShareRedirect()(w, r)
+
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("GET /s/ in %s = %d, want %d", lang, w.Code, http.StatusNotFound)
+		}
+		bodies[lang] = strings.TrimSpace(w.Body.String())
+	}
+
+	if bodies["en"] != "Share link not found" || bodies["es"] != "Enlace compartido no encontrado" {
+		t.Errorf("404 bodies = %q, want the English and Spanish share link messages", bodies)
+	}
+}
+
+func TestLocalizedErrorRendersParams(t *testing.T) {
+	appErr := utils.NewLocalizedError(utils.ErrNotFound, i18n.ErrDigestNotFound,
+		map[string]string{"date": "2024-05-01"}, nil)
+
+	want := map[string]string{
+		"en": "No digest for 2024-05-01",
+		"es": "No hay resumen para 2024-05-01",
+	}
+	for lang, body := range want {
+		r := httptest.NewRequest(http.MethodGet, "/digest?date=2024-05-01", nil)
+		r.Header.Set("Accept-Language", lang)
+		w := httptest.NewRecorder()
+		writeAppError(w, r, appErr, http.StatusNotFound)
+
+		if got := strings.TrimSpace(w.Body.String()); got != body {
+			t.Errorf("body in %s = %q, want %q", lang, got, body)
+		}
+	}
+	// Errors keep their English message for logs and non-HTTP callers
+	if appErr.Message != want["en"] {
+		t.Errorf("Message = %q, want %q", appErr.Message, want["en"])
+	}
+}
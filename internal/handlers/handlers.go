@@ -1,12 +1,18 @@
 package handlers
 
 import (
+	"gator-swamp/internal/config"
 	"gator-swamp/internal/database"
 	"gator-swamp/internal/engine"
+	"gator-swamp/internal/mailer"
+	"gator-swamp/internal/middleware"
+	"gator-swamp/internal/storage"
 	"gator-swamp/internal/utils"
+	"net/http"
 	"time"
 
 	"github.com/asynkron/protoactor-go/actor"
+	"github.com/google/uuid"
 )
 
 // Server holds all server dependencies, including the actor system and engine
@@ -18,8 +24,19 @@ type Server struct {
 	Metrics            *utils.MetricsCollector
 	CommentActor       *actor.PID
 	DirectMessageActor *actor.PID
+	ShareActor         *actor.PID
+	MultiredditActor   *actor.PID
+	SitemapActor       *actor.PID
+	DigestActor        *actor.PID
+	NotificationActor  *actor.PID
+	SavedItemActor     *actor.PID
+	MediaStorage       storage.Storage
+	Mailer             mailer.Mailer
 	MongoDB            *database.MongoDB
+	Config             *config.Config
 	RequestTimeout     time.Duration
+
+	passwordResetRequests requestLimiter // Password reset requests by IP address
 }
 
 // NewServer creates a new Server instance with the given components
@@ -31,7 +48,16 @@ func NewServer(
 	metrics *utils.MetricsCollector,
 	commentActor *actor.PID,
 	directMessageActor *actor.PID,
+	shareActor *actor.PID,
+	multiredditActor *actor.PID,
+	sitemapActor *actor.PID,
+	digestActor *actor.PID,
+	notificationActor *actor.PID,
+	savedItemActor *actor.PID,
+	mediaStorage storage.Storage,
+	mail mailer.Mailer,
 	mongodb *database.MongoDB,
+	cfg *config.Config,
 ) *Server {
 	return &Server{
 		System:             system,
@@ -41,7 +67,41 @@ func NewServer(
 		Metrics:            metrics,
 		CommentActor:       commentActor,
 		DirectMessageActor: directMessageActor,
+		ShareActor:         shareActor,
+		MultiredditActor:   multiredditActor,
+		SitemapActor:       sitemapActor,
+		DigestActor:        digestActor,
+		NotificationActor:  notificationActor,
+		SavedItemActor:     savedItemActor,
+		MediaStorage:       mediaStorage,
+		Mailer:             mail,
 		MongoDB:            mongodb,
+		Config:             cfg,
 		RequestTimeout:     5 * time.Second, // Default timeout for actor requests
 	}
 }
+
+// actingUserID returns the authenticated user a request acts as. claimed is the user ID
+// the request names for itself, which older clients still send: it may be empty, but
+// when set it must be the authenticated user. On failure the error has been written.
+func actingUserID(w http.ResponseWriter, r *http.Request, claimed string) (uuid.UUID, bool) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return uuid.Nil, false
+	}
+	if claimed == "" {
+		return userID, true
+	}
+
+	claimedID, err := uuid.Parse(claimed)
+	if err != nil {
+		http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	if claimedID != userID {
+		http.Error(w, "User ID does not match the authenticated user", http.StatusForbidden)
+		return uuid.Nil, false
+	}
+	return userID, true
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"gator-swamp/internal/middleware"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// logout posts to /user/logout, or /user/logout-all when everywhere is set, with the
+// access token, if any, and the refresh token in the body
+func logout(t *testing.T, s *Server, everywhere bool, accessToken, refreshToken string) {
+	t.Helper()
+	path, handler := "/user/logout", s.HandleUserLogout()
+	if everywhere {
+		path, handler = "/user/logout-all", s.HandleUserLogoutAll()
+	}
+	r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"refreshToken": "`+refreshToken+`"}`))
+	if accessToken != "" {
+		r.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	w := httptest.NewRecorder()
+	handler(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST %s = %d: %s", path, w.Code, w.Body)
+	}
+}
+
+// accessWorks reports whether a protected endpoint accepts the access token
+func accessWorks(t *testing.T, token string) bool {
+	t.Helper()
+	handler := middleware.ApplyJWTMiddleware(func(w http.ResponseWriter, r *http.Request) {}, "/user/profile")
+	r := httptest.NewRequest(http.MethodGet, "/user/profile", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w.Code == http.StatusOK
+}
+
+func TestLogoutEndsOnlyThatSession(t *testing.T) {
+	s, user := newSessionServer(t)
+	session := login(t, s, user)
+	otherDevice := login(t, s, user)
+
+	logout(t, s, false, session.Token, session.RefreshToken)
+
+	if accessWorks(t, session.Token) {
+		t.Fatalf("access token still works after logging out")
+	}
+	if w, _ := refresh(t, s, session.RefreshToken); w.Code != http.StatusUnauthorized {
+		t.Fatalf("refreshing after logging out = %d, want 401", w.Code)
+	}
+	if !accessWorks(t, otherDevice.Token) {
+		t.Fatalf("another device's access token stopped working")
+	}
+	if w, _ := refresh(t, s, otherDevice.RefreshToken); w.Code != http.StatusOK {
+		t.Fatalf("refreshing another device's session = %d: %s", w.Code, w.Body)
+	}
+}
+
+// Clients whose access token has run out can still end their session with the refresh
+// token alone
+func TestLogoutWithOnlyRefreshToken(t *testing.T) {
+	s, user := newSessionServer(t)
+	session := login(t, s, user)
+
+	logout(t, s, false, "", session.RefreshToken)
+
+	if w, _ := refresh(t, s, session.RefreshToken); w.Code != http.StatusUnauthorized {
+		t.Fatalf("refreshing after logging out = %d, want 401", w.Code)
+	}
+}
+
+func TestLogoutAllEndsEverySession(t *testing.T) {
+	s, user := newSessionServer(t)
+	session := login(t, s, user)
+	otherDevice := login(t, s, user)
+	// Tokens are revoked by issue time, which is kept to the second
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+
+	logout(t, s, true, session.Token, "")
+
+	for name, device := range map[string]string{"this": session.RefreshToken, "other": otherDevice.RefreshToken} {
+		if w, _ := refresh(t, s, device); w.Code != http.StatusUnauthorized {
+			t.Fatalf("refreshing %s device's session after logging out everywhere = %d, want 401", name, w.Code)
+		}
+	}
+	if accessWorks(t, session.Token) || accessWorks(t, otherDevice.Token) {
+		t.Fatalf("access tokens still work after logging out everywhere")
+	}
+
+	// Logging in again starts afresh
+	if next := login(t, s, user); !accessWorks(t, next.Token) {
+		t.Fatalf("access token from a new login doesn't work")
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"gator-swamp/internal/middleware"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// MediaUploadResponse describes a stored upload. URL is what goes in a post's mediaUrls.
+type MediaUploadResponse struct {
+	MediaID     string `json:"mediaId"`
+	URL         string `json:"url"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+}
+
+// multipartOverhead leaves room for form boundaries and headers on top of the file itself
+const multipartOverhead = 1 << 20
+
+// HandleMediaUpload stores a file sent as the "file" field of a multipart form
+func (s *Server) HandleMediaUpload() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if _, ok := middleware.GetUserIDFromContext(r.Context()); !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		maxSize := s.Config.MediaMaxUploadBytes
+		r.Body = http.MaxBytesReader(w, r.Body, maxSize+multipartOverhead)
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, "File too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "Expected a multipart form with a file field", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		defer r.MultipartForm.RemoveAll()
+
+		if header.Size > maxSize {
+			http.Error(w, "File too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		// Trust the file's contents rather than the type the client declared
+		sniff := make([]byte, 512)
+		n, err := io.ReadFull(file, sniff)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			http.Error(w, "Failed to read file", http.StatusBadRequest)
+			return
+		}
+		contentType := http.DetectContentType(sniff[:n])
+		ext, ok := s.Config.MediaUploadTypes[contentType]
+		if !ok {
+			http.Error(w, "Unsupported media type", http.StatusUnsupportedMediaType)
+			return
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			http.Error(w, "Failed to read file", http.StatusInternalServerError)
+			return
+		}
+
+		mediaID := uuid.New()
+		url, err := s.MediaStorage.Put(r.Context(), mediaID.String()+"."+ext, file, contentType)
+		if err != nil {
+			log.Printf("Failed to store upload %s: %v", mediaID, err)
+			http.Error(w, "Failed to store file", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(MediaUploadResponse{
+			MediaID:     mediaID.String(),
+			URL:         url,
+			ContentType: contentType,
+			Size:        header.Size,
+		})
+	}
+}
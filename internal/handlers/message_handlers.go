@@ -5,13 +5,14 @@ import (
 	"net/http"
 
 	"gator-swamp/internal/engine/actors"
+	"gator-swamp/internal/utils"
 
 	"github.com/google/uuid"
 )
 
 // SendMessageRequest represents a request to send a direct message
 type SendMessageRequest struct {
-	FromID  string `json:"fromId"`
+	FromID  string `json:"fromId"` // Optional; must be the authenticated user
 	ToID    string `json:"toId"`
 	Content string `json:"content"`
 }
@@ -28,9 +29,8 @@ func (s *Server) HandleDirectMessages() http.HandlerFunc {
 				return
 			}
 
-			fromID, err := uuid.Parse(req.FromID)
-			if err != nil {
-				http.Error(w, "Invalid sender ID", http.StatusBadRequest)
+			fromID, ok := actingUserID(w, r, req.FromID)
+			if !ok {
 				return
 			}
 
@@ -53,20 +53,25 @@ func (s *Server) HandleDirectMessages() http.HandlerFunc {
 				return
 			}
 
+			if appErr, ok := result.(*utils.AppError); ok {
+				var statusCode int
+				switch appErr.Code {
+				case utils.ErrForbidden:
+					statusCode = http.StatusForbidden
+				default:
+					statusCode = http.StatusInternalServerError
+				}
+				writeAppError(w, r, appErr, statusCode)
+				return
+			}
+
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(result)
 
 		case http.MethodGet:
-			// Get messages for a user
-			userID := r.URL.Query().Get("userId")
-			if userID == "" {
-				http.Error(w, "User ID required", http.StatusBadRequest)
-				return
-			}
-
-			parsedID, err := uuid.Parse(userID)
-			if err != nil {
-				http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			// Get the authenticated user's messages
+			parsedID, ok := actingUserID(w, r, r.URL.Query().Get("userId"))
+			if !ok {
 				return
 			}
 
@@ -84,10 +89,8 @@ func (s *Server) HandleDirectMessages() http.HandlerFunc {
 		case http.MethodDelete:
 			// Delete a message
 			messageID := r.URL.Query().Get("messageId")
-			userID := r.URL.Query().Get("userId")
-
-			if messageID == "" || userID == "" {
-				http.Error(w, "Message ID and User ID required", http.StatusBadRequest)
+			if messageID == "" {
+				http.Error(w, "Message ID required", http.StatusBadRequest)
 				return
 			}
 
@@ -97,9 +100,8 @@ func (s *Server) HandleDirectMessages() http.HandlerFunc {
 				return
 			}
 
-			parsedUserID, err := uuid.Parse(userID)
-			if err != nil {
-				http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			parsedUserID, ok := actingUserID(w, r, r.URL.Query().Get("userId"))
+			if !ok {
 				return
 			}
 
@@ -132,17 +134,14 @@ func (s *Server) HandleConversation() http.HandlerFunc {
 			return
 		}
 
-		userID := r.URL.Query().Get("userId")
 		otherID := r.URL.Query().Get("otherUserId")
-
-		if userID == "" || otherID == "" {
-			http.Error(w, "Both user IDs required", http.StatusBadRequest)
+		if otherID == "" {
+			http.Error(w, "Other user ID required", http.StatusBadRequest)
 			return
 		}
 
-		parsedUserID, err := uuid.Parse(userID)
-		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		parsedUserID, ok := actingUserID(w, r, r.URL.Query().Get("userId"))
+		if !ok {
 			return
 		}
 
@@ -193,9 +192,8 @@ func (s *Server) HandleMarkMessageRead() http.HandlerFunc {
 			return
 		}
 
-		userID, err := uuid.Parse(req.UserID)
-		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		userID, ok := actingUserID(w, r, req.UserID)
+		if !ok {
 			return
 		}
 
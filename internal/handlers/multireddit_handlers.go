@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"gator-swamp/internal/engine/actors"
+	"gator-swamp/internal/middleware"
+	"gator-swamp/internal/utils"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// CreateMultiredditRequest represents a request to create a multireddit
+type CreateMultiredditRequest struct {
+	Name         string   `json:"name"`
+	SubredditIDs []string `json:"subredditIds"`
+	IsPublic     bool     `json:"isPublic"`
+}
+
+// UpdateMultiredditRequest represents a request to rename a multireddit or change its visibility
+type UpdateMultiredditRequest struct {
+	MultiredditID string  `json:"multiredditId"`
+	Name          *string `json:"name,omitempty"`
+	IsPublic      *bool   `json:"isPublic,omitempty"`
+}
+
+// MultiredditSubredditRequest represents a request to add or remove a subreddit from a multireddit
+type MultiredditSubredditRequest struct {
+	MultiredditID string `json:"multiredditId"`
+	SubredditID   string `json:"subredditId"`
+}
+
+// HandleMultireddits handles creating, reading, updating and deleting multireddits
+func (s *Server) HandleMultireddits() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.GetUserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var msg interface{}
+
+		switch r.Method {
+		case http.MethodGet:
+			// A specific multireddit by ID, otherwise the caller's own list
+			if idStr := r.URL.Query().Get("id"); idStr != "" {
+				id, err := uuid.Parse(idStr)
+				if err != nil {
+					http.Error(w, "Invalid multireddit ID format", http.StatusBadRequest)
+					return
+				}
+				msg = &actors.GetMultiredditMsg{MultiredditID: id, ViewerID: userID}
+			} else {
+				msg = &actors.ListMultiredditsMsg{OwnerID: userID}
+			}
+
+		case http.MethodPost:
+			var req CreateMultiredditRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request", http.StatusBadRequest)
+				return
+			}
+
+			subredditIDs := make([]uuid.UUID, 0, len(req.SubredditIDs))
+			for _, idStr := range req.SubredditIDs {
+				id, err := uuid.Parse(idStr)
+				if err != nil {
+					http.Error(w, fmt.Sprintf("Invalid subreddit ID format: %s", idStr), http.StatusBadRequest)
+					return
+				}
+				subredditIDs = append(subredditIDs, id)
+			}
+
+			msg = &actors.CreateMultiredditMsg{
+				OwnerID:      userID,
+				Name:         req.Name,
+				SubredditIDs: subredditIDs,
+				IsPublic:     req.IsPublic,
+			}
+
+		case http.MethodPut:
+			var req UpdateMultiredditRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request", http.StatusBadRequest)
+				return
+			}
+
+			id, err := uuid.Parse(req.MultiredditID)
+			if err != nil {
+				http.Error(w, "Invalid multireddit ID format", http.StatusBadRequest)
+				return
+			}
+
+			msg = &actors.UpdateMultiredditMsg{
+				MultiredditID: id,
+				RequesterID:   userID,
+				Name:          req.Name,
+				IsPublic:      req.IsPublic,
+			}
+
+		case http.MethodDelete:
+			id, err := uuid.Parse(r.URL.Query().Get("id"))
+			if err != nil {
+				http.Error(w, "Invalid multireddit ID format", http.StatusBadRequest)
+				return
+			}
+			msg = &actors.DeleteMultiredditMsg{MultiredditID: id, RequesterID: userID}
+
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		s.respondFromMultiredditActor(w, r, msg)
+	}
+}
+
+// HandleMultiredditSubreddits adds (POST) or removes (DELETE) a subreddit from a multireddit
+func (s *Server) HandleMultiredditSubreddits() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := middleware.GetUserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req MultiredditSubredditRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		multiID, err := uuid.Parse(req.MultiredditID)
+		if err != nil {
+			http.Error(w, "Invalid multireddit ID format", http.StatusBadRequest)
+			return
+		}
+
+		subredditID, err := uuid.Parse(req.SubredditID)
+		if err != nil {
+			http.Error(w, "Invalid subreddit ID format", http.StatusBadRequest)
+			return
+		}
+
+		s.respondFromMultiredditActor(w, r, &actors.UpdateMultiredditSubredditMsg{
+			MultiredditID: multiID,
+			RequesterID:   userID,
+			SubredditID:   subredditID,
+			IsAdding:      r.Method == http.MethodPost,
+		})
+	}
+}
+
+// multiredditFeedPath serves multireddit feeds with the multireddit named by the multiId
+// query parameter rather than in the path
+const multiredditFeedPath = "/user/multireddit/feed"
+
+// HandleMultiredditFeed serves GET /user/multireddits/{id}/feed?sort=&limit=&cursor=, and
+// the same feed as GET /user/multireddit/feed?multiId={id}&sort=&limit=&cursor=
+func (s *Server) HandleMultiredditFeed() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := middleware.GetUserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		idStr := r.URL.Query().Get("multiId")
+		if r.URL.Path != multiredditFeedPath {
+			parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/user/multireddits/"), "/")
+			if len(parts) != 2 || parts[1] != "feed" {
+				http.Error(w, "Not found", http.StatusNotFound)
+				return
+			}
+			idStr = parts[0]
+		}
+
+		multiID, err := uuid.Parse(idStr)
+		if err != nil {
+			http.Error(w, "Invalid multireddit ID format", http.StatusBadRequest)
+			return
+		}
+
+		limit := 0
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			fmt.Sscanf(limitStr, "%d", &limit)
+		}
+
+		s.respondFromMultiredditActor(w, r, &actors.GetMultiredditFeedMsg{
+			MultiredditID: multiID,
+			ViewerID:      userID,
+			Sort:          r.URL.Query().Get("sort"),
+			Limit:         limit,
+			Cursor:        r.URL.Query().Get("cursor"),
+		})
+	}
+}
+
+// respondFromMultiredditActor sends msg to the multireddit actor and writes its reply
+func (s *Server) respondFromMultiredditActor(w http.ResponseWriter, r *http.Request, msg interface{}) {
+	future := s.Context.RequestFuture(s.MultiredditActor, msg, s.RequestTimeout)
+	result, err := future.Result()
+	if err != nil {
+		http.Error(w, "Failed to process multireddit request", http.StatusInternalServerError)
+		return
+	}
+
+	if appErr, ok := result.(*utils.AppError); ok {
+		var statusCode int
+		switch appErr.Code {
+		case utils.ErrNotFound:
+			statusCode = http.StatusNotFound
+		case utils.ErrInvalidInput:
+			statusCode = http.StatusBadRequest
+		case utils.ErrForbidden:
+			statusCode = http.StatusForbidden
+		default:
+			statusCode = http.StatusInternalServerError
+		}
+		writeAppError(w, r, appErr, statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if deleted, ok := result.(bool); ok {
+		json.NewEncoder(w).Encode(map[string]bool{"success": deleted})
+		return
+	}
+	json.NewEncoder(w).Encode(result)
+}
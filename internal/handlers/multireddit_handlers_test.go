@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"gator-swamp/internal/database/dbtest"
+	"gator-swamp/internal/engine/actors"
+	"gator-swamp/internal/middleware"
+	"gator-swamp/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/google/uuid"
+)
+
+// Clients may use /user/multireddit, naming the feed's multireddit with multiId, as well
+// as /user/multireddits
+func TestSingularMultiredditPathsServeTheSameMultireddit(t *testing.T) {
+	mongodb := dbtest.New(t)
+	ctx := context.Background()
+	system := actor.NewActorSystem()
+	multireddits := system.Root.Spawn(actor.PropsFromProducer(func() actor.Actor {
+		return actors.NewMultiredditActor(mongodb)
+	}))
+	t.Cleanup(func() {
+		system.Root.StopFuture(multireddits).Wait()
+		system.Shutdown()
+	})
+	s := &Server{
+		System:           system,
+		Context:          system.Root,
+		MultiredditActor: multireddits,
+		MongoDB:          mongodb,
+		RequestTimeout:   5 * time.Second,
+	}
+
+	ownerID := uuid.New()
+	subreddit := &models.Subreddit{
+		ID:        uuid.New(),
+		Name:      "swamp",
+		CreatorID: ownerID,
+		CreatedAt: time.Now(),
+		Type:      models.SubredditPublic,
+	}
+	if err := mongodb.CreateSubreddit(ctx, subreddit); err != nil {
+		t.Fatalf("CreateSubreddit: %v", err)
+	}
+	post := &models.Post{
+		ID:          uuid.New(),
+		Title:       "In the swamp",
+		Slug:        "in-the-swamp",
+		AuthorID:    ownerID,
+		SubredditID: subreddit.ID,
+		CreatedAt:   time.Now(),
+		Status:      models.PostStatusPublished,
+	}
+	if err := mongodb.SavePost(ctx, post); err != nil {
+		t.Fatalf("SavePost: %v", err)
+	}
+
+	serve := func(handler http.HandlerFunc, method, target, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		r = r.WithContext(middleware.SetUserIDInContext(r.Context(), ownerID))
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w
+	}
+
+	w := serve(s.HandleMultireddits(), http.MethodPost, "/user/multireddit",
+		`{"name": "wetlands", "subredditIds": ["`+subreddit.ID.String()+`"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /user/multireddit = %d: %s", w.Code, w.Body)
+	}
+	var multi struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&multi); err != nil {
+		t.Fatalf("decoding multireddit: %v", err)
+	}
+
+	feed := s.HandleMultiredditFeed()
+	plural := serve(feed, http.MethodGet, "/user/multireddits/"+multi.ID+"/feed?sort=new", "")
+	singular := serve(feed, http.MethodGet, "/user/multireddit/feed?multiId="+multi.ID+"&sort=new", "")
+	if plural.Code != http.StatusOK || singular.Code != http.StatusOK {
+		t.Fatalf("feeds = %d and %d: %s %s", plural.Code, singular.Code, plural.Body, singular.Body)
+	}
+	if plural.Body.String() != singular.Body.String() {
+		t.Fatalf("singular feed %s differs from plural feed %s", singular.Body, plural.Body)
+	}
+	if !strings.Contains(singular.Body.String(), post.ID.String()) {
+		t.Fatalf("feed %s doesn't have the subreddit's post", singular.Body)
+	}
+
+	if w := serve(feed, http.MethodGet, "/user/multireddit/feed", ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("feed without multiId = %d, want 400", w.Code)
+	}
+}
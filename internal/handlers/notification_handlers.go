@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"gator-swamp/internal/engine/actors"
+	"gator-swamp/internal/i18n"
+	"gator-swamp/internal/middleware"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/types"
+	"gator-swamp/internal/utils"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// notificationMessageKeys maps notification types to the message shown to the recipient
+var notificationMessageKeys = map[string]string{
+	models.NotificationCommentReply: i18n.NotificationCommentReply,
+}
+
+// HandleGetNotifications lists the authenticated user's notifications, newest first
+func (s *Server) HandleGetNotifications() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := middleware.GetUserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		query := r.URL.Query()
+		limit := 0
+		if limitStr := query.Get("limit"); limitStr != "" {
+			fmt.Sscanf(limitStr, "%d", &limit)
+		}
+
+		future := s.Context.RequestFuture(s.NotificationActor, &actors.GetNotificationsMsg{
+			UserID:     userID,
+			Limit:      limit,
+			Cursor:     query.Get("after"),
+			UnreadOnly: query.Get("unread") == "true",
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to get notifications", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		// Render each notification's message in the reader's language
+		lang := i18n.MatchLanguage(r.Header.Get("Accept-Language"))
+		if page, ok := result.(*types.PaginatedResponse); ok {
+			if notifications, ok := page.Items.([]*models.Notification); ok {
+				for _, notification := range notifications {
+					if key, ok := notificationMessageKeys[notification.Type]; ok {
+						notification.Message = i18n.T(lang, key, nil)
+					}
+				}
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Language", lang)
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleMarkNotificationsRead acknowledges the authenticated user's notifications.
+// Omitting notificationIds marks all of them as read.
+func (s *Server) HandleMarkNotificationsRead() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := middleware.GetUserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			NotificationIDs []string `json:"notificationIds"`
+		}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+		}
+
+		notificationIDs := make([]uuid.UUID, 0, len(req.NotificationIDs))
+		for _, idStr := range req.NotificationIDs {
+			id, err := uuid.Parse(idStr)
+			if err != nil {
+				http.Error(w, "Invalid notification ID", http.StatusBadRequest)
+				return
+			}
+			notificationIDs = append(notificationIDs, id)
+		}
+
+		future := s.Context.RequestFuture(s.NotificationActor, &actors.MarkNotificationsReadMsg{
+			UserID:          userID,
+			NotificationIDs: notificationIDs,
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to mark notifications read", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			writeAppError(w, r, appErr, http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"gator-swamp/internal/auth"
+	"gator-swamp/internal/config"
+	"gator-swamp/internal/database/dbtest"
+	"gator-swamp/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// recordingMailer keeps the bodies of the messages sent through it
+type recordingMailer struct {
+	mu     sync.Mutex
+	bodies []string
+}
+
+func (m *recordingMailer) Send(ctx context.Context, to, subject, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bodies = append(m.bodies, body)
+	return nil
+}
+
+func (m *recordingMailer) sent() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.bodies...)
+}
+
+func TestPasswordResetRequestsAreCappedPerIP(t *testing.T) {
+	s := &Server{Config: &config.Config{PasswordResetIPLimit: 2}}
+	handler := s.HandlePasswordResetRequest()
+
+	request := func(remoteAddr string) *httptest.ResponseRecorder {
+		// No email, so nothing is sent in the background
+		r := httptest.NewRequest("POST", "/user/password-reset/request", strings.NewReader(`{"email": ""}`))
+		r.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := request("203.0.113.7:4000"); w.Code != http.StatusOK {
+			t.Fatalf("request %d = %d, want 200", i+1, w.Code)
+		}
+	}
+	// Another port is the same client
+	w := request("203.0.113.7:4001")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("request over the cap = %d with Retry-After %q, want 429 with Retry-After",
+			w.Code, w.Header().Get("Retry-After"))
+	}
+	if w := request("198.51.100.2:4000"); w.Code != http.StatusOK {
+		t.Fatalf("request from another client = %d, want 200", w.Code)
+	}
+}
+
+func TestPasswordResetEmailsAreThrottledPerUser(t *testing.T) {
+	mongodb := dbtest.New(t)
+	ctx := context.Background()
+	mail := &recordingMailer{}
+	s := &Server{
+		MongoDB: mongodb,
+		Mailer:  mail,
+		Config: &config.Config{
+			PasswordResetTTL:            15 * time.Minute,
+			PasswordResetResendInterval: 5 * time.Minute,
+		},
+	}
+
+	user := &models.User{
+		ID:        uuid.New(),
+		Username:  "gator",
+		Email:     "gator@example.com",
+		CreatedAt: time.Now(),
+	}
+	if err := mongodb.SaveUser(ctx, user); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+
+	s.sendPasswordReset(user.Email)
+	s.sendPasswordReset(user.Email)
+	sent := mail.sent()
+	if len(sent) != 1 {
+		t.Fatalf("%d reset emails sent, want 1", len(sent))
+	}
+	// The throttled request left the emailed token in place
+	token := regexp.MustCompile(`(?m)^\s*(\S+)\s*$`).FindStringSubmatch(strings.Split(sent[0], "once:")[1])
+	if token == nil {
+		t.Fatalf("no token in reset email: %q", sent[0])
+	}
+	stored, err := mongodb.PasswordResets.CountDocuments(ctx, bson.M{"_id": auth.HashPasswordResetToken(token[1])})
+	if err != nil || stored != 1 {
+		t.Fatalf("emailed token stored %d times (%v), want it kept", stored, err)
+	}
+
+	// Once the interval has passed the user can be sent another
+	_, err = mongodb.PasswordResets.UpdateMany(ctx, bson.M{"userId": user.ID.String()},
+		bson.M{"$set": bson.M{"createdAt": time.Now().Add(-10 * time.Minute)}})
+	if err != nil {
+		t.Fatalf("moving reset back: %v", err)
+	}
+	s.sendPasswordReset(user.Email)
+	if sent := mail.sent(); len(sent) != 2 {
+		t.Fatalf("%d reset emails sent after the interval, want 2", len(sent))
+	}
+}
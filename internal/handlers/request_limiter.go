@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// requestLimiter caps the requests accepted per key, such as a client's IP address,
+// over fixed windows. The zero value is ready to use.
+type requestLimiter struct {
+	mu        sync.Mutex
+	windows   map[string]*requestWindow
+	lastSweep time.Time
+}
+
+type requestWindow struct {
+	start time.Time
+	count int
+}
+
+// allow records a request for key and reports whether it is within limit requests per
+// window. When it isn't, it also returns how long until the key's window ends. A limit
+// of 0 allows everything.
+func (l *requestLimiter) allow(key string, limit int, window time.Duration, now time.Time) (bool, time.Duration) {
+	if limit <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.windows == nil {
+		l.windows = make(map[string]*requestWindow)
+	}
+	// Forget keys whose windows have ended so the map doesn't grow without bound
+	if now.Sub(l.lastSweep) >= window {
+		for k, w := range l.windows {
+			if now.Sub(w.start) >= window {
+				delete(l.windows, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	w, exists := l.windows[key]
+	if !exists || now.Sub(w.start) >= window {
+		w = &requestWindow{start: now}
+		l.windows[key] = w
+	}
+	if w.count >= limit {
+		return false, w.start.Add(window).Sub(now)
+	}
+	w.count++
+	return true, 0
+}
+
+// clientIP returns the address a request came from. Forwarding headers are ignored
+// since clients can set them to anything.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
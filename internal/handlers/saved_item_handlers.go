@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"gator-swamp/internal/engine/actors"
+	"gator-swamp/internal/middleware"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// SaveItemRequest identifies a post or comment to save or unsave. Exactly one ID is set.
+type SaveItemRequest struct {
+	PostID    string `json:"postId,omitempty"`
+	CommentID string `json:"commentId,omitempty"`
+}
+
+// HandleSaveItem saves a post or comment for the authenticated user
+func (s *Server) HandleSaveItem() http.HandlerFunc {
+	return s.handleSavedItemChange(true, "")
+}
+
+// HandleUnsaveItem removes a post or comment from the authenticated user's saved items
+func (s *Server) HandleUnsaveItem() http.HandlerFunc {
+	return s.handleSavedItemChange(false, "")
+}
+
+// HandleSavePost saves a post for the authenticated user
+func (s *Server) HandleSavePost() http.HandlerFunc {
+	return s.handleSavedItemChange(true, models.SavedItemPost)
+}
+
+// HandleUnsavePost removes a post from the authenticated user's saved items
+func (s *Server) HandleUnsavePost() http.HandlerFunc {
+	return s.handleSavedItemChange(false, models.SavedItemPost)
+}
+
+// handleSavedItemChange serves both save and unsave, which take the same request. A
+// non-empty onlyType restricts the endpoint to that kind of item.
+func (s *Server) handleSavedItemChange(save bool, onlyType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := middleware.GetUserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req SaveItemRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		var itemType, rawID string
+		switch {
+		case req.PostID != "" && req.CommentID == "":
+			itemType, rawID = models.SavedItemPost, req.PostID
+		case req.CommentID != "" && req.PostID == "":
+			itemType, rawID = models.SavedItemComment, req.CommentID
+		default:
+			http.Error(w, "Provide either postId or commentId", http.StatusBadRequest)
+			return
+		}
+		if onlyType != "" && itemType != onlyType {
+			http.Error(w, fmt.Sprintf("Only a %sId is accepted here", onlyType), http.StatusBadRequest)
+			return
+		}
+
+		itemID, err := uuid.Parse(rawID)
+		if err != nil {
+			http.Error(w, "Invalid item ID", http.StatusBadRequest)
+			return
+		}
+
+		var msg interface{} = &actors.UnsaveItemMsg{UserID: userID, ItemType: itemType, ItemID: itemID}
+		if save {
+			msg = &actors.SaveItemMsg{UserID: userID, ItemType: itemType, ItemID: itemID}
+		}
+
+		future := s.Context.RequestFuture(s.SavedItemActor, msg, s.RequestTimeout)
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to update saved items", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrNotFound:
+				statusCode = http.StatusNotFound
+			case utils.ErrGone:
+				statusCode = http.StatusGone
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleGetSavedItems lists the authenticated user's saved posts and comments
+func (s *Server) HandleGetSavedItems() http.HandlerFunc {
+	return s.handleSavedItemList("")
+}
+
+// HandleGetSavedPosts lists the authenticated user's saved posts
+func (s *Server) HandleGetSavedPosts() http.HandlerFunc {
+	return s.handleSavedItemList(models.SavedItemPost)
+}
+
+// handleSavedItemList serves a page of saved items, optionally of a single type
+func (s *Server) handleSavedItemList(itemType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := middleware.GetUserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		limit := 0
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			fmt.Sscanf(limitStr, "%d", &limit)
+		}
+
+		future := s.Context.RequestFuture(s.SavedItemActor, &actors.GetSavedItemsMsg{
+			UserID:   userID,
+			ItemType: itemType,
+			Limit:    limit,
+			Cursor:   r.URL.Query().Get("after"),
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to get saved items", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"gator-swamp/internal/auth"
+	"gator-swamp/internal/config"
+	"gator-swamp/internal/database/dbtest"
+	"gator-swamp/internal/engine"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/types"
+	"gator-swamp/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const sessionPassword = "swamp-water-1"
+
+// newSessionServer serves logins for one stored user through a running engine
+func newSessionServer(t *testing.T) (*Server, *models.User) {
+	t.Helper()
+	auth.Configure("test-signing-secret", time.Hour)
+	mongodb := dbtest.New(t)
+	cfg := &config.Config{PasswordHashCost: bcrypt.MinCost, RefreshTokenTTL: time.Hour}
+	system := actor.NewActorSystem()
+	t.Cleanup(system.Shutdown)
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(sessionPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hashing password: %v", err)
+	}
+	user := &models.User{
+		ID:             uuid.New(),
+		Username:       "gator",
+		Email:          "gator@example.com",
+		HashedPassword: string(hashed),
+		CreatedAt:      time.Now(),
+	}
+	if err := mongodb.SaveUser(context.Background(), user); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+
+	return &Server{
+		System:         system,
+		Context:        system.Root,
+		Engine:         engine.NewEngine(system, utils.NewMetricsCollector(), mongodb, cfg),
+		MongoDB:        mongodb,
+		Config:         cfg,
+		RequestTimeout: 5 * time.Second,
+	}, user
+}
+
+// login starts a session for the user as a new device would
+func login(t *testing.T, s *Server, user *models.User) *types.LoginResponse {
+	t.Helper()
+	body := `{"email": "` + user.Email + `", "password": "` + sessionPassword + `"}`
+	w := httptest.NewRecorder()
+	s.HandleUserLogin()(w, httptest.NewRequest(http.MethodPost, "/user/login", strings.NewReader(body)))
+	var resp types.LoginResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || !resp.Success || resp.RefreshToken == "" {
+		t.Fatalf("POST /user/login = %d %+v (%v), want a session", w.Code, resp, err)
+	}
+	return &resp
+}
+
+// refresh exchanges a refresh token, returning the response and, on success, its tokens
+func refresh(t *testing.T, s *Server, refreshToken string) (*httptest.ResponseRecorder, *types.LoginResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	s.HandleUserRefresh()(w, httptest.NewRequest(http.MethodPost, "/user/refresh",
+		strings.NewReader(`{"refreshToken": "`+refreshToken+`"}`)))
+	if w.Code != http.StatusOK {
+		return w, nil
+	}
+	var resp types.LoginResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decoding refresh response: %v", err)
+	}
+	return w, &resp
+}
+
+func TestRefreshRotatesRefreshToken(t *testing.T) {
+	s, user := newSessionServer(t)
+	session := login(t, s, user)
+
+	w, rotated := refresh(t, s, session.RefreshToken)
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /user/refresh = %d: %s", w.Code, w.Body)
+	}
+	if rotated.RefreshToken == "" || rotated.RefreshToken == session.RefreshToken {
+		t.Fatalf("refresh returned refresh token %q, want a new one", rotated.RefreshToken)
+	}
+	claims, err := auth.ValidateToken(rotated.Token)
+	if err != nil || claims.UserID != user.ID {
+		t.Fatalf("refreshed access token is for %v (%v), want %s", claims, err, user.ID)
+	}
+
+	// The new token keeps the session going
+	if w, _ := refresh(t, s, rotated.RefreshToken); w.Code != http.StatusOK {
+		t.Fatalf("refreshing with the rotated token = %d: %s", w.Code, w.Body)
+	}
+}
+
+// A refresh token used twice was stolen or replayed, so the whole session ends, but the
+// user's sessions on other devices carry on
+func TestReusedRefreshTokenRevokesSession(t *testing.T) {
+	s, user := newSessionServer(t)
+	session := login(t, s, user)
+	otherDevice := login(t, s, user)
+
+	_, rotated := refresh(t, s, session.RefreshToken)
+	if rotated == nil {
+		t.Fatalf("first refresh failed")
+	}
+	if w, _ := refresh(t, s, session.RefreshToken); w.Code != http.StatusUnauthorized {
+		t.Fatalf("reusing a rotated refresh token = %d, want 401", w.Code)
+	}
+	if w, _ := refresh(t, s, rotated.RefreshToken); w.Code != http.StatusUnauthorized {
+		t.Fatalf("refreshing the revoked session's latest token = %d, want 401", w.Code)
+	}
+
+	if w, _ := refresh(t, s, otherDevice.RefreshToken); w.Code != http.StatusOK {
+		t.Fatalf("refreshing another device's session = %d: %s", w.Code, w.Body)
+	}
+}
+
+// Expired tokens are refused without being taken for reuse, so the rest of the session
+// is left alone
+func TestExpiredRefreshTokenIsRejected(t *testing.T) {
+	s, user := newSessionServer(t)
+	session := login(t, s, user)
+	ctx := context.Background()
+
+	hash := auth.HashRefreshToken(session.RefreshToken)
+	_, err := s.MongoDB.Sessions.UpdateOne(ctx, bson.M{"_id": hash},
+		bson.M{"$set": bson.M{"expiresAt": time.Now().Add(-time.Minute)}})
+	if err != nil {
+		t.Fatalf("expiring session: %v", err)
+	}
+
+	if w, _ := refresh(t, s, session.RefreshToken); w.Code != http.StatusUnauthorized {
+		t.Fatalf("refreshing with an expired token = %d, want 401", w.Code)
+	}
+	if stored, err := s.MongoDB.Sessions.CountDocuments(ctx, bson.M{"_id": hash}); err != nil || stored != 1 {
+		t.Fatalf("expired token stored %d times (%v), want it left to its TTL", stored, err)
+	}
+	if w, _ := refresh(t, s, "not-a-refresh-token"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("refreshing with an unknown token = %d, want 401", w.Code)
+	}
+}
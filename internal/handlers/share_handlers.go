@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"gator-swamp/internal/engine/actors"
+	"gator-swamp/internal/i18n"
+	"gator-swamp/internal/middleware"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// SharePostRequest represents a request to create a short share link for a post
+type SharePostRequest struct {
+	PostID  string `json:"postId"`
+	Channel string `json:"channel,omitempty"` // Where the link is being shared, e.g. "twitter"
+}
+
+// HandleSharePost creates share links (POST) and returns share statistics to the post's author (GET)
+func (s *Server) HandleSharePost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.GetUserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPost:
+			var req SharePostRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request", http.StatusBadRequest)
+				return
+			}
+
+			postID, err := uuid.Parse(req.PostID)
+			if err != nil {
+				http.Error(w, "Invalid post ID format", http.StatusBadRequest)
+				return
+			}
+
+			future := s.Context.RequestFuture(s.ShareActor, &actors.CreateShareLinkMsg{
+				PostID:    postID,
+				CreatorID: userID,
+				Channel:   strings.TrimSpace(req.Channel),
+			}, s.RequestTimeout)
+
+			result, err := future.Result()
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Failed to create share link: %v", err), http.StatusInternalServerError)
+				return
+			}
+
+			if appErr, ok := result.(*utils.AppError); ok {
+				var statusCode int
+				switch appErr.Code {
+				case utils.ErrNotFound:
+					statusCode = http.StatusNotFound
+				case utils.ErrInvalidInput:
+					statusCode = http.StatusBadRequest
+				case utils.ErrDuplicate:
+					statusCode = http.StatusConflict
+				default:
+					statusCode = http.StatusInternalServerError
+				}
+				writeAppError(w, r, appErr, statusCode)
+				return
+			}
+
+			link := result.(*models.ShareLink)
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"code":      link.Code,
+				"postId":    link.PostID.String(),
+				"url":       fmt.Sprintf("%s/s/%s", s.Config.PublicBaseURL, link.Code),
+				"channel":   link.Channel,
+				"createdAt": link.CreatedAt,
+			})
+
+		case http.MethodGet:
+			postID, err := uuid.Parse(r.URL.Query().Get("postId"))
+			if err != nil {
+				http.Error(w, "Invalid post ID format", http.StatusBadRequest)
+				return
+			}
+
+			future := s.Context.RequestFuture(s.ShareActor, &actors.GetShareStatsMsg{
+				PostID:      postID,
+				RequesterID: userID,
+			}, s.RequestTimeout)
+
+			result, err := future.Result()
+			if err != nil {
+				http.Error(w, "Failed to get share statistics", http.StatusInternalServerError)
+				return
+			}
+
+			if appErr, ok := result.(*utils.AppError); ok {
+				var statusCode int
+				switch appErr.Code {
+				case utils.ErrNotFound:
+					statusCode = http.StatusNotFound
+				case utils.ErrForbidden:
+					statusCode = http.StatusForbidden
+				default:
+					statusCode = http.StatusInternalServerError
+				}
+				writeAppError(w, r, appErr, statusCode)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(result)
+
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// HandleShareRedirect resolves /s/{code} and redirects to the post's page on the web client
+func (s *Server) HandleShareRedirect() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		code := strings.TrimPrefix(r.URL.Path, "/s/")
+		if code == "" || strings.Contains(code, "/") {
+			writeLocalizedError(w, r, i18n.ErrShareLinkNotFound, nil, http.StatusNotFound)
+			return
+		}
+
+		future := s.Context.RequestFuture(s.ShareActor, &actors.ResolveShareLinkMsg{Code: code}, s.RequestTimeout)
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to resolve share link", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			switch appErr.Code {
+			case utils.ErrNotFound:
+				writeLocalizedError(w, r, i18n.ErrShareLinkNotFound, nil, http.StatusNotFound)
+			case utils.ErrGone:
+				writeLocalizedError(w, r, i18n.ErrPostDeleted, nil, http.StatusGone)
+			default:
+				writeAppError(w, r, appErr, http.StatusInternalServerError)
+			}
+			return
+		}
+
+		link := result.(*models.ShareLink)
+		http.Redirect(w, r, fmt.Sprintf("%s/post/%s", s.Config.PublicBaseURL, link.PostID), http.StatusFound)
+	}
+}
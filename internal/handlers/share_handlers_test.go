@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"gator-swamp/internal/config"
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/database/dbtest"
+	"gator-swamp/internal/engine/actors"
+	"gator-swamp/internal/middleware"
+	"gator-swamp/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/google/uuid"
+)
+
+const shareBaseURL = "https://gator.example"
+
+// shareServer serves share links from a ShareActor backed by a test database
+type shareServer struct {
+	*Server
+	shareActor *actor.PID
+	post       *models.Post
+}
+
+func newShareServer(t *testing.T) *shareServer {
+	t.Helper()
+	mongodb := dbtest.New(t)
+	system := actor.NewActorSystem()
+
+	// Shares are counted on the post by the PostActor, which these tests don't need
+	postActor := system.Root.Spawn(actor.PropsFromFunc(func(actor.Context) {}))
+	shareActor := system.Root.Spawn(actor.PropsFromProducer(func() actor.Actor {
+		return actors.NewShareActor(mongodb, postActor)
+	}))
+	t.Cleanup(func() {
+		system.Root.StopFuture(shareActor).Wait()
+		system.Shutdown()
+	})
+
+	post := &models.Post{
+		ID:        uuid.New(),
+		Title:     "Shared post",
+		Slug:      "shared-post",
+		AuthorID:  uuid.New(),
+		CreatedAt: time.Now(),
+		Status:    models.PostStatusPublished,
+	}
+	if err := mongodb.SavePost(context.Background(), post); err != nil {
+		t.Fatalf("SavePost: %v", err)
+	}
+
+	return &shareServer{
+		Server: &Server{
+			System:         system,
+			Context:        system.Root,
+			ShareActor:     shareActor,
+			MongoDB:        mongodb,
+			Config:         &config.Config{PublicBaseURL: shareBaseURL},
+			RequestTimeout: 5 * time.Second,
+		},
+		shareActor: shareActor,
+		post:       post,
+	}
+}
+
+// share creates a share link for the post as userID and returns its code
+func (s *shareServer) share(t *testing.T, userID uuid.UUID) string {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/post/share", strings.NewReader(`{"postId":"`+s.post.ID.String()+`"}`))
+	r = r.WithContext(middleware.SetUserIDInContext(r.Context(), userID))
+	w := httptest.NewRecorder()
+	s.HandleSharePost()(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /post/share = %d: %s", w.Code, w.Body)
+	}
+
+	var resp struct {
+		Code string `json:"code"`
+		URL  string `json:"url"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decoding share link: %v", err)
+	}
+	if len(resp.Code) < 8 || resp.URL != shareBaseURL+"/s/"+resp.Code {
+		t.Fatalf("share link code %q, url %q; want 8+ characters and %s/s/<code>", resp.Code, resp.URL, shareBaseURL)
+	}
+	return resp.Code
+}
+
+func (s *shareServer) follow(code string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.HandleShareRedirect()(w, httptest.NewRequest(http.MethodGet, "/s/"+code, nil))
+	return w
+}
+
+// stats returns the share and click counts the post's author sees
+func (s *shareServer) stats(t *testing.T) actors.ShareStatsResponse {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/post/share?postId="+s.post.ID.String(), nil)
+	r = r.WithContext(middleware.SetUserIDInContext(r.Context(), s.post.AuthorID))
+	w := httptest.NewRecorder()
+	s.HandleSharePost()(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /post/share = %d: %s", w.Code, w.Body)
+	}
+
+	var stats actors.ShareStatsResponse
+	if err := json.NewDecoder(w.Body).Decode(&stats); err != nil {
+		t.Fatalf("decoding share stats: %v", err)
+	}
+	return stats
+}
+
+func TestShareLinkRedirectsToPost(t *testing.T) {
+	s := newShareServer(t)
+	code := s.share(t, uuid.New())
+
+	w := s.follow(code)
+	if w.Code != http.StatusFound {
+		t.Fatalf("GET /s/%s = %d, want %d: %s", code, w.Code, http.StatusFound, w.Body)
+	}
+	if want := shareBaseURL + "/post/" + s.post.ID.String(); w.Header().Get("Location") != want {
+		t.Errorf("redirect Location = %q, want %q", w.Header().Get("Location"), want)
+	}
+
+	if w := s.follow("unknown1"); w.Code != http.StatusNotFound {
+		t.Errorf("GET /s/ of an unknown code = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestShareLinkCountsClicks(t *testing.T) {
+	s := newShareServer(t)
+	first := s.share(t, uuid.New())
+	second := s.share(t, uuid.New())
+
+	for _, code := range []string{first, first, second} {
+		if w := s.follow(code); w.Code != http.StatusFound {
+			t.Fatalf("GET /s/%s = %d: %s", code, w.Code, w.Body)
+		}
+	}
+	// Clicks not yet written behind are still counted
+	if stats := s.stats(t); stats.Shares != 2 || stats.Clicks != 3 {
+		t.Errorf("share stats = %d shares, %d clicks; want 2 shares, 3 clicks", stats.Shares, stats.Clicks)
+	}
+
+	// Stopping the actor writes the pending clicks to each link
+	s.System.Root.StopFuture(s.shareActor).Wait()
+	for code, want := range map[string]int{first: 2, second: 1} {
+		link, err := s.MongoDB.GetShareLink(context.Background(), code)
+		if err != nil {
+			t.Fatalf("GetShareLink: %v", err)
+		}
+		if link.Clicks != want {
+			t.Errorf("link %s has %d stored clicks, want %d", code, link.Clicks, want)
+		}
+	}
+}
+
+func TestShareLinkToDeletedPostIsGone(t *testing.T) {
+	tests := []struct {
+		name   string
+		delete func(*database.MongoDB, *models.Post) error
+	}{
+		{"deleted by its author", func(mongodb *database.MongoDB, post *models.Post) error {
+			return mongodb.SoftDeletePost(context.Background(), post.ID, post.AuthorID, time.Now())
+		}},
+		{"no longer stored", func(mongodb *database.MongoDB, post *models.Post) error {
+			_, err := mongodb.Posts.DeleteOne(context.Background(), map[string]string{"_id": post.ID.String()})
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newShareServer(t)
+			code := s.share(t, uuid.New())
+			if err := tt.delete(s.MongoDB, s.post); err != nil {
+				t.Fatalf("deleting post: %v", err)
+			}
+
+			w := s.follow(code)
+			if w.Code != http.StatusGone {
+				t.Fatalf("GET /s/%s = %d, want %d: %s", code, w.Code, http.StatusGone, w.Body)
+			}
+			if w.Header().Get("Location") != "" {
+				t.Errorf("a link to a deleted post redirected to %s", w.Header().Get("Location"))
+			}
+		})
+	}
+}
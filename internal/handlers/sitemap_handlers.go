@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"gator-swamp/internal/engine/actors"
+	"gator-swamp/internal/utils"
+	"net/http"
+	"strings"
+)
+
+// HandleSitemap serves /sitemap.xml and the sitemap files it points to
+// (/sitemap-subreddits.xml and /sitemap-posts-<n>.xml)
+func (s *Server) HandleSitemap() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var name string
+		switch {
+		case r.URL.Path == "/sitemap.xml":
+			name = actors.SitemapIndexName
+		case strings.HasPrefix(r.URL.Path, "/sitemap-") && strings.HasSuffix(r.URL.Path, ".xml"):
+			name = strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/sitemap-"), ".xml")
+		default:
+			http.NotFound(w, r)
+			return
+		}
+
+		// Generating a full posts file can take longer than a normal actor request
+		future := s.Context.RequestFuture(s.SitemapActor, &actors.GetSitemapMsg{Name: name}, 4*s.RequestTimeout)
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to generate sitemap", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			switch appErr.Code {
+			case utils.ErrNotFound:
+				http.NotFound(w, r)
+			default:
+				writeAppError(w, r, appErr, http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		w.Write(result.([]byte))
+	}
+}
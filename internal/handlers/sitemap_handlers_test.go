@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"gator-swamp/internal/config"
+	"gator-swamp/internal/database"
+	"gator-swamp/internal/database/dbtest"
+	"gator-swamp/internal/engine/actors"
+	"gator-swamp/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/google/uuid"
+)
+
+const sitemapBaseURL = "https://gator.example"
+
+func newSitemapServer(t *testing.T, mongodb *database.MongoDB) *Server {
+	t.Helper()
+	system := actor.NewActorSystem()
+	sitemapActor := system.Root.Spawn(actor.PropsFromProducer(func() actor.Actor {
+		return actors.NewSitemapActor(mongodb, sitemapBaseURL)
+	}))
+	t.Cleanup(system.Shutdown)
+
+	return &Server{
+		System:         system,
+		Context:        system.Root,
+		SitemapActor:   sitemapActor,
+		MongoDB:        mongodb,
+		Config:         &config.Config{PublicBaseURL: sitemapBaseURL},
+		RequestTimeout: 5 * time.Second,
+	}
+}
+
+// getSitemap fetches a sitemap file by its URL
+func getSitemap(t *testing.T, s *Server, loc string) []byte {
+	t.Helper()
+	w := httptest.NewRecorder()
+	s.HandleSitemap()(w, httptest.NewRequest(http.MethodGet, strings.TrimPrefix(loc, sitemapBaseURL), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s = %d: %s", loc, w.Code, w.Body)
+	}
+	return w.Body.Bytes()
+}
+
+// validateSitemap checks a sitemap file against the sitemap protocol's schema. It uses
+// xmllint, so it is skipped where that isn't installed.
+func validateSitemap(t *testing.T, schema string, data []byte) {
+	t.Helper()
+	xmllint, err := exec.LookPath("xmllint")
+	if err != nil {
+		t.Skip("xmllint is not installed; skipping sitemap schema validation")
+	}
+
+	cmd := exec.Command(xmllint, "--noout", "--schema", "testdata/"+schema, "-")
+	cmd.Stdin = bytes.NewReader(data)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("sitemap doesn't validate against %s: %v\n%s\n%s", schema, err, out, data)
+	}
+}
+
+// sitemapLocs returns the locations listed in a sitemap or sitemap index, sorted
+func sitemapLocs(t *testing.T, data []byte) []string {
+	t.Helper()
+	type entry struct {
+		Loc string `xml:"loc"`
+	}
+	var doc struct {
+		URLs     []entry `xml:"url"`
+		Sitemaps []entry `xml:"sitemap"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("decoding sitemap: %v", err)
+	}
+
+	locs := make([]string, 0)
+	for _, e := range append(doc.URLs, doc.Sitemaps...) {
+		locs = append(locs, e.Loc)
+	}
+	sort.Strings(locs)
+	return locs
+}
+
+// validateSitemaps fetches the sitemap index and every file it lists, validating each
+// against its schema, and returns the files' contents by URL
+func validateSitemaps(t *testing.T, s *Server) map[string][]byte {
+	t.Helper()
+	index := getSitemap(t, s, sitemapBaseURL+"/sitemap.xml")
+	t.Run("index", func(t *testing.T) { validateSitemap(t, "siteindex.xsd", index) })
+
+	files := make(map[string][]byte)
+	for _, loc := range sitemapLocs(t, index) {
+		data := getSitemap(t, s, loc)
+		t.Run(strings.TrimPrefix(loc, sitemapBaseURL+"/"), func(t *testing.T) {
+			validateSitemap(t, "sitemap.xsd", data)
+		})
+		files[loc] = data
+	}
+	return files
+}
+
+func TestSitemapsValidateAgainstSchema(t *testing.T) {
+	mongodb := dbtest.New(t)
+	ctx := context.Background()
+
+	subreddits := map[string]*models.Subreddit{}
+	for _, name := range []string{"swamp", "secret", "late_night"} {
+		subreddit := &models.Subreddit{
+			ID:        uuid.New(),
+			Name:      name,
+			CreatorID: uuid.New(),
+			CreatedAt: time.Now().Add(-time.Hour),
+			Type:      models.SubredditPublic,
+		}
+		if name == "secret" {
+			subreddit.Type = models.SubredditPrivate
+		}
+		if err := mongodb.CreateSubreddit(ctx, subreddit); err != nil {
+			t.Fatalf("CreateSubreddit: %v", err)
+		}
+		subreddits[name] = subreddit
+	}
+	// As its moderators would from the subreddit's settings
+	nsfw := true
+	err := mongodb.UpdateSubredditSettings(ctx, subreddits["late_night"].ID, database.SubredditSettingsUpdate{NSFW: &nsfw})
+	if err != nil {
+		t.Fatalf("UpdateSubredditSettings: %v", err)
+	}
+
+	newPost := func(subreddit string, deleted bool) *models.Post {
+		id := uuid.New()
+		post := &models.Post{
+			ID:          id,
+			Title:       "A post & <more>",
+			Slug:        "a-post-" + id.String()[:8],
+			AuthorID:    uuid.New(),
+			SubredditID: subreddits[subreddit].ID,
+			CreatedAt:   time.Now(),
+			Status:      models.PostStatusPublished,
+			IsDeleted:   deleted,
+		}
+		if err := mongodb.SavePost(ctx, post); err != nil {
+			t.Fatalf("SavePost: %v", err)
+		}
+		return post
+	}
+	public := newPost("swamp", false)
+	newPost("swamp", true)
+	newPost("secret", false)
+	newPost("late_night", false)
+
+	files := validateSitemaps(t, newSitemapServer(t, mongodb))
+
+	subredditLocs := sitemapLocs(t, files[sitemapBaseURL+"/sitemap-subreddits.xml"])
+	if want := []string{sitemapBaseURL + "/", sitemapBaseURL + "/r/swamp"}; !equalStrings(subredditLocs, want) {
+		t.Errorf("subreddit sitemap lists %v, want %v", subredditLocs, want)
+	}
+	postLocs := sitemapLocs(t, files[sitemapBaseURL+"/sitemap-posts-1.xml"])
+	if want := []string{sitemapBaseURL + "/post/" + public.ID.String()}; !equalStrings(postLocs, want) {
+		t.Errorf("posts sitemap lists %v, want %v", postLocs, want)
+	}
+}
+
+// An empty site still has to produce files the schema accepts, which needs at least
+// one URL in each
+func TestEmptySiteSitemapsValidateAgainstSchema(t *testing.T) {
+	s := newSitemapServer(t, dbtest.New(t))
+	files := validateSitemaps(t, s)
+
+	if _, ok := files[sitemapBaseURL+"/sitemap-subreddits.xml"]; !ok {
+		t.Errorf("sitemap index of an empty site doesn't list the subreddit sitemap: %v", files)
+	}
+
+	w := httptest.NewRecorder()
+	s.HandleSitemap()(w, httptest.NewRequest(http.MethodGet, "/sitemap-posts-1.xml", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("GET /sitemap-posts-1.xml without posts = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -3,9 +3,14 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
+	"gator-swamp/internal/analytics"
 	"gator-swamp/internal/engine/actors"
+	"gator-swamp/internal/middleware"
+	"gator-swamp/internal/models"
 	"gator-swamp/internal/utils"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -14,7 +19,8 @@ import (
 type CreateSubredditRequest struct {
 	Name        string `json:"name"`        // Subreddit name
 	Description string `json:"description"` // Subreddit description
-	CreatorID   string `json:"creatorId"`   // Creator ID (UUID as string)
+	CreatorID   string `json:"creatorId"`   // Optional; must be the authenticated user
+	Type        string `json:"type"`        // "public" (default), "restricted" or "private"
 }
 
 // HandleSubreddits handles requests related to subreddits
@@ -61,7 +67,7 @@ func (s *Server) HandleSubreddits() http.HandlerFunc {
 					if appErr.Code == utils.ErrNotFound {
 						http.Error(w, "Subreddit not found", http.StatusNotFound)
 					} else {
-						http.Error(w, appErr.Error(), http.StatusInternalServerError)
+						writeAppError(w, r, appErr, http.StatusInternalServerError)
 					}
 					return
 				}
@@ -87,7 +93,7 @@ func (s *Server) HandleSubreddits() http.HandlerFunc {
 					if appErr.Code == utils.ErrNotFound {
 						http.Error(w, "Subreddit not found", http.StatusNotFound)
 					} else {
-						http.Error(w, appErr.Error(), http.StatusInternalServerError)
+						writeAppError(w, r, appErr, http.StatusInternalServerError)
 					}
 					return
 				}
@@ -104,9 +110,8 @@ func (s *Server) HandleSubreddits() http.HandlerFunc {
 				return
 			}
 
-			creatorID, err := uuid.Parse(req.CreatorID)
-			if err != nil {
-				http.Error(w, "Invalid creator ID format", http.StatusBadRequest)
+			creatorID, ok := actingUserID(w, r, req.CreatorID)
+			if !ok {
 				return
 			}
 
@@ -115,6 +120,8 @@ func (s *Server) HandleSubreddits() http.HandlerFunc {
 				Name:        req.Name,
 				Description: req.Description,
 				CreatorID:   creatorID,
+				Type:        models.SubredditType(req.Type),
+				AsAdmin:     s.isAdmin(r.Context(), creatorID),
 			}
 
 			// Send to Engine for validation and processing
@@ -135,22 +142,97 @@ func (s *Server) HandleSubreddits() http.HandlerFunc {
 					statusCode = http.StatusBadRequest
 				case utils.ErrUnauthorized:
 					statusCode = http.StatusUnauthorized
+				case utils.ErrDuplicate:
+					statusCode = http.StatusConflict
+				case utils.ErrTooManyRequests:
+					statusCode = http.StatusTooManyRequests
+					w.Header().Set("Retry-After", appErr.Params["retryAfter"])
 				default:
 					statusCode = http.StatusInternalServerError
 				}
-				http.Error(w, appErr.Error(), statusCode)
+				writeAppError(w, r, appErr, statusCode)
 				return
 			}
 
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(result)
 
+		case http.MethodPut:
+			s.updateSubredditSettings(w, r)
+
+		case http.MethodDelete:
+			s.deleteSubreddit(w, r)
+
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	}
 }
 
+// deleteSubreddit deletes a subreddit on behalf of the authenticated user, who must be
+// its creator or an administrator. The body must repeat the subreddit's name.
+func (s *Server) deleteSubreddit(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		SubredditID string `json:"subredditId"`
+		RequesterID string `json:"requesterId,omitempty"`
+		ConfirmName string `json:"confirmName"`
+		DryRun      bool   `json:"dryRun,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	subredditID, err := uuid.Parse(req.SubredditID)
+	if err != nil {
+		http.Error(w, "Invalid subreddit ID", http.StatusBadRequest)
+		return
+	}
+	if req.RequesterID != "" && req.RequesterID != userID.String() {
+		http.Error(w, "Requester does not match the authenticated user", http.StatusForbidden)
+		return
+	}
+
+	future := s.Context.RequestFuture(s.EnginePID, &actors.DeleteSubredditMsg{
+		SubredditID: subredditID,
+		RequesterID: userID,
+		ConfirmName: req.ConfirmName,
+		AsAdmin:     s.isAdmin(r.Context(), userID),
+		DryRun:      req.DryRun,
+	}, actors.SubredditDeleteTimeout)
+
+	result, err := future.Result()
+	if err != nil {
+		http.Error(w, "Failed to delete subreddit", http.StatusInternalServerError)
+		return
+	}
+
+	if appErr, ok := result.(*utils.AppError); ok {
+		var statusCode int
+		switch appErr.Code {
+		case utils.ErrNotFound:
+			statusCode = http.StatusNotFound
+		case utils.ErrForbidden:
+			statusCode = http.StatusForbidden
+		case utils.ErrInvalidInput:
+			statusCode = http.StatusBadRequest
+		default:
+			statusCode = http.StatusInternalServerError
+		}
+		writeAppError(w, r, appErr, statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
 // HandleSubredditMembers handles subreddit membership operations
 func (s *Server) HandleSubredditMembers() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -181,85 +263,1343 @@ func (s *Server) HandleSubredditMembers() http.HandlerFunc {
 			json.NewEncoder(w).Encode(result)
 
 		case http.MethodPost:
-			// Join a subreddit
-			var req struct {
-				SubredditID string `json:"subredditId"`
-				UserID      string `json:"userId"`
+			s.handleMembershipChange(true)(w, r)
+
+		case http.MethodDelete:
+			s.handleMembershipChange(false)(w, r)
+
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// HandleJoinSubreddit adds a user to a subreddit
+func (s *Server) HandleJoinSubreddit() http.HandlerFunc {
+	join := s.handleMembershipChange(true)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		join(w, r)
+	}
+}
+
+// HandleLeaveSubreddit removes a user from a subreddit
+func (s *Server) HandleLeaveSubreddit() http.HandlerFunc {
+	leave := s.handleMembershipChange(false)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		leave(w, r)
+	}
+}
+
+// handleMembershipChange serves joining and leaving, which take the same request. Both
+// are also served by POST and DELETE on /subreddit/members.
+func (s *Server) handleMembershipChange(join bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			SubredditID string `json:"subredditId"`
+			UserID      string `json:"userId"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		subredditID, err := uuid.Parse(req.SubredditID)
+		if err != nil {
+			http.Error(w, "Invalid subreddit ID format", http.StatusBadRequest)
+			return
+		}
+
+		userID, ok := actingUserID(w, r, req.UserID)
+		if !ok {
+			return
+		}
+
+		var msg interface{} = &actors.LeaveSubredditMsg{SubredditID: subredditID, UserID: userID}
+		if join {
+			msg = &actors.JoinSubredditMsg{SubredditID: subredditID, UserID: userID}
+		}
+
+		future := s.Context.RequestFuture(s.Engine.GetSubredditActor(), msg, s.RequestTimeout)
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to update membership", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrNotFound, utils.ErrUserNotFound:
+				statusCode = http.StatusNotFound
+			case utils.ErrUnauthorized:
+				statusCode = http.StatusUnauthorized
+			case utils.ErrForbidden:
+				statusCode = http.StatusForbidden
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+		if membership, ok := result.(*actors.MembershipResponse); ok && join && membership.Changed {
+			analytics.RecordInSubreddit(analytics.EventJoin, subredditID, userID, subredditID)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleApproveSubredditUser approves a user in a subreddit the authenticated user
+// moderates (POST /subreddit/approve). Approved users can post in restricted subreddits
+// and join private ones.
+func (s *Server) HandleApproveSubredditUser() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		requesterID, ok := middleware.GetUserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			SubredditID string `json:"subredditId"`
+			UserID      string `json:"userId"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		subredditID, err := uuid.Parse(req.SubredditID)
+		if err != nil {
+			http.Error(w, "Invalid subreddit ID format", http.StatusBadRequest)
+			return
+		}
+		userID, err := uuid.Parse(req.UserID)
+		if err != nil {
+			http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+			return
+		}
+
+		future := s.Context.RequestFuture(s.EnginePID, &actors.ApproveSubredditUserMsg{
+			SubredditID: subredditID,
+			RequesterID: requesterID,
+			UserID:      userID,
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to approve user", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrNotFound, utils.ErrUserNotFound:
+				statusCode = http.StatusNotFound
+			case utils.ErrForbidden:
+				statusCode = http.StatusForbidden
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleSearchSubreddits finds subreddits by name as the user types
+// (GET /subreddit/search?q=&limit=). Private subreddits the user can't read are left out.
+func (s *Server) HandleSearchSubreddits() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// Anonymous searches leave out every private subreddit
+		viewerID, _ := middleware.GetUserIDFromContext(r.Context())
+
+		limit := 0
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			var err error
+			limit, err = strconv.Atoi(limitStr)
+			if err != nil || limit <= 0 {
+				http.Error(w, "Invalid limit", http.StatusBadRequest)
+				return
+			}
+		}
+
+		future := s.Context.RequestFuture(s.EnginePID, &actors.SearchSubredditsMsg{
+			Query:    r.URL.Query().Get("q"),
+			Limit:    limit,
+			ViewerID: viewerID,
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to search subreddits", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleBanSubredditUser bans a user from posting and commenting in a subreddit the
+// authenticated user moderates (POST /subreddit/ban). A durationDays of zero bans them
+// for good.
+func (s *Server) HandleBanSubredditUser() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		requesterID, ok := middleware.GetUserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			SubredditID  string `json:"subredditId"`
+			UserID       string `json:"userId"`
+			Reason       string `json:"reason"`
+			DurationDays int    `json:"durationDays"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		subredditID, err := uuid.Parse(req.SubredditID)
+		if err != nil {
+			http.Error(w, "Invalid subreddit ID format", http.StatusBadRequest)
+			return
+		}
+		userID, err := uuid.Parse(req.UserID)
+		if err != nil {
+			http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+			return
+		}
+		if req.DurationDays < 0 {
+			http.Error(w, "durationDays cannot be negative", http.StatusBadRequest)
+			return
+		}
+
+		future := s.Context.RequestFuture(s.EnginePID, &actors.BanUserMsg{
+			SubredditID: subredditID,
+			RequesterID: requesterID,
+			UserID:      userID,
+			Reason:      req.Reason,
+			Duration:    time.Duration(req.DurationDays) * 24 * time.Hour,
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to ban user", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrNotFound, utils.ErrUserNotFound:
+				statusCode = http.StatusNotFound
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			case utils.ErrForbidden:
+				statusCode = http.StatusForbidden
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleUnbanSubredditUser lifts a user's ban from a subreddit the authenticated user
+// moderates (POST /subreddit/unban)
+func (s *Server) HandleUnbanSubredditUser() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		requesterID, ok := middleware.GetUserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			SubredditID string `json:"subredditId"`
+			UserID      string `json:"userId"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		subredditID, err := uuid.Parse(req.SubredditID)
+		if err != nil {
+			http.Error(w, "Invalid subreddit ID format", http.StatusBadRequest)
+			return
+		}
+		userID, err := uuid.Parse(req.UserID)
+		if err != nil {
+			http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+			return
+		}
+
+		future := s.Context.RequestFuture(s.EnginePID, &actors.UnbanUserMsg{
+			SubredditID: subredditID,
+			RequesterID: requesterID,
+			UserID:      userID,
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to unban user", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrNotFound:
+				statusCode = http.StatusNotFound
+			case utils.ErrForbidden:
+				statusCode = http.StatusForbidden
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleSubredditBans lists the current bans of a subreddit the authenticated user
+// moderates, most recent first (GET /subreddit/bans?subredditId=&limit=&after=)
+func (s *Server) HandleSubredditBans() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		requesterID, ok := middleware.GetUserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		subredditID, err := uuid.Parse(r.URL.Query().Get("subredditId"))
+		if err != nil {
+			http.Error(w, "Invalid subreddit ID format", http.StatusBadRequest)
+			return
+		}
+
+		limit := 0
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			limit, err = strconv.Atoi(limitStr)
+			if err != nil || limit <= 0 {
+				http.Error(w, "Invalid limit", http.StatusBadRequest)
+				return
 			}
+		}
+
+		future := s.Context.RequestFuture(s.EnginePID, &actors.ListBansMsg{
+			SubredditID: subredditID,
+			RequesterID: requesterID,
+			Limit:       limit,
+			Cursor:      r.URL.Query().Get("after"),
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to get subreddit bans", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrNotFound:
+				statusCode = http.StatusNotFound
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			case utils.ErrForbidden:
+				statusCode = http.StatusForbidden
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleModQueue lists the posts awaiting review in a subreddit the authenticated user
+// moderates, oldest first (GET /subreddit/modqueue?subredditId=&limit=&after=)
+func (s *Server) HandleModQueue() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		requesterID, ok := middleware.GetUserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		subredditID, err := uuid.Parse(r.URL.Query().Get("subredditId"))
+		if err != nil {
+			http.Error(w, "Invalid subreddit ID format", http.StatusBadRequest)
+			return
+		}
+
+		limit := 0
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			limit, err = strconv.Atoi(limitStr)
+			if err != nil || limit <= 0 {
+				http.Error(w, "Invalid limit", http.StatusBadRequest)
+				return
+			}
+		}
+
+		future := s.Context.RequestFuture(s.EnginePID, &actors.GetModQueueMsg{
+			SubredditID: subredditID,
+			RequesterID: requesterID,
+			Limit:       limit,
+			Cursor:      r.URL.Query().Get("after"),
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to get mod queue", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			case utils.ErrForbidden:
+				statusCode = http.StatusForbidden
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleSubredditAnalytics reports a subreddit's posts, comments and new members per day,
+// and its top posts, to its moderators (GET /subreddit/analytics?subredditId=&days=)
+func (s *Server) HandleSubredditAnalytics() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		requesterID, ok := middleware.GetUserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		subredditID, err := uuid.Parse(r.URL.Query().Get("subredditId"))
+		if err != nil {
+			http.Error(w, "Invalid subreddit ID format", http.StatusBadRequest)
+			return
+		}
+
+		days := 0
+		if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+			days, err = strconv.Atoi(daysStr)
+			if err != nil || days <= 0 {
+				http.Error(w, "Invalid days", http.StatusBadRequest)
+				return
+			}
+		}
+
+		future := s.Context.RequestFuture(s.Engine.GetSubredditActor(), &actors.GetSubredditAnalyticsMsg{
+			SubredditID: subredditID,
+			RequesterID: requesterID,
+			Days:        days,
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to get subreddit analytics", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			case utils.ErrNotFound:
+				statusCode = http.StatusNotFound
+			case utils.ErrForbidden:
+				statusCode = http.StatusForbidden
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleModQueueAction approves or removes a post awaiting review in a subreddit the
+// authenticated user moderates (POST /subreddit/modqueue/action)
+func (s *Server) HandleModQueueAction() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		requesterID, ok := middleware.GetUserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			PostID string `json:"postId"`
+			Action string `json:"action"` // "approve" or "remove"
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		postID, err := uuid.Parse(req.PostID)
+		if err != nil {
+			http.Error(w, "Invalid post ID format", http.StatusBadRequest)
+			return
+		}
+
+		future := s.Context.RequestFuture(s.EnginePID, &actors.ModQueueActionMsg{
+			PostID:      postID,
+			RequesterID: requesterID,
+			Action:      req.Action,
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to review post", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrNotFound:
+				statusCode = http.StatusNotFound
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			case utils.ErrForbidden:
+				statusCode = http.StatusForbidden
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		// A held post counts as posted when it is approved, not when it was submitted
+		if post, ok := result.(*models.Post); ok && post.Status == models.PostStatusPublished {
+			analytics.RecordInSubreddit(analytics.EventPost, post.ID, post.AuthorID, post.SubredditID)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleSubredditDigest returns a subreddit's top posts for a day (GET /subreddit/digest?id=&date=)
+func (s *Server) HandleSubredditDigest() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		subredditID, err := uuid.Parse(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "Invalid subreddit ID", http.StatusBadRequest)
+			return
+		}
+
+		future := s.Context.RequestFuture(s.DigestActor, &actors.GetDigestMsg{
+			SubredditID: subredditID,
+			Date:        r.URL.Query().Get("date"),
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to get digest", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrNotFound:
+				statusCode = http.StatusNotFound
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandlePinPost pins a post to the top of its subreddit or unpins it (POST /subreddit/pin)
+func (s *Server) HandlePinPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := middleware.GetUserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
 
+		var req struct {
+			PostID string `json:"postId"`
+			Pinned bool   `json:"pinned"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		postID, err := uuid.Parse(req.PostID)
+		if err != nil {
+			http.Error(w, "Invalid post ID", http.StatusBadRequest)
+			return
+		}
+
+		future := s.Context.RequestFuture(s.EnginePID, &actors.PinPostMsg{
+			PostID:      postID,
+			RequesterID: userID,
+			Pinned:      req.Pinned,
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to pin post", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrNotFound:
+				statusCode = http.StatusNotFound
+			case utils.ErrForbidden:
+				statusCode = http.StatusForbidden
+			case utils.ErrGone:
+				statusCode = http.StatusGone
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// FlairRequest creates or updates a subreddit's flair template
+type FlairRequest struct {
+	SubredditID string `json:"subredditId"`
+	FlairID     string `json:"flairId,omitempty"` // Required for updates
+	Text        string `json:"text"`
+	Color       string `json:"color,omitempty"`
+}
+
+// UserFlairRequest creates or updates a subreddit's user flair template
+type UserFlairRequest struct {
+	SubredditID string `json:"subredditId"`
+	FlairID     string `json:"flairId,omitempty"` // Required for updates
+	Text        string `json:"text"`
+	Color       string `json:"color,omitempty"`
+	ModOnly     bool   `json:"modOnly"`
+}
+
+// HandleSubredditSettings updates a subreddit's moderator-editable settings (PUT /subreddit/settings)
+func (s *Server) HandleSubredditSettings() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.updateSubredditSettings(w, r)
+	}
+}
+
+// updateSubredditSettings changes a subreddit's description and settings on behalf of the
+// authenticated user, who must moderate it. A requesterId in the body must be theirs.
+func (s *Server) updateSubredditSettings(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		SubredditID           string  `json:"subredditId"`
+		RequesterID           string  `json:"requesterId,omitempty"`
+		Name                  string  `json:"name,omitempty"`
+		Description           *string `json:"description,omitempty"`
+		PollResultsAfterClose *bool   `json:"pollResultsAfterClose,omitempty"`
+		MinKarmaToPost        *int    `json:"minKarmaToPost,omitempty"`
+		NSFW                  *bool   `json:"nsfw,omitempty"`
+		Type                  *string `json:"type,omitempty"`
+		IconURL               *string `json:"iconUrl,omitempty"`
+		BannerURL             *string `json:"bannerUrl,omitempty"`
+		PrimaryColor          *string `json:"primaryColor,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	subredditID, err := uuid.Parse(req.SubredditID)
+	if err != nil {
+		http.Error(w, "Invalid subreddit ID", http.StatusBadRequest)
+		return
+	}
+	if req.RequesterID != "" && req.RequesterID != userID.String() {
+		http.Error(w, "Requester does not match the authenticated user", http.StatusForbidden)
+		return
+	}
+
+	msg := &actors.UpdateSubredditSettingsMsg{
+		SubredditID:           subredditID,
+		RequesterID:           userID,
+		Name:                  req.Name,
+		Description:           req.Description,
+		PollResultsAfterClose: req.PollResultsAfterClose,
+		MinKarmaToPost:        req.MinKarmaToPost,
+		NSFW:                  req.NSFW,
+		IconURL:               req.IconURL,
+		BannerURL:             req.BannerURL,
+		PrimaryColor:          req.PrimaryColor,
+	}
+	if req.Type != nil {
+		subredditType := models.SubredditType(*req.Type)
+		msg.Type = &subredditType
+	}
+	future := s.Context.RequestFuture(s.EnginePID, msg, s.RequestTimeout)
+
+	result, err := future.Result()
+	if err != nil {
+		http.Error(w, "Failed to update subreddit settings", http.StatusInternalServerError)
+		return
+	}
+
+	if appErr, ok := result.(*utils.AppError); ok {
+		var statusCode int
+		switch appErr.Code {
+		case utils.ErrNotFound:
+			statusCode = http.StatusNotFound
+		case utils.ErrUnauthorized:
+			statusCode = http.StatusUnauthorized
+		case utils.ErrForbidden:
+			statusCode = http.StatusForbidden
+		case utils.ErrInvalidInput:
+			statusCode = http.StatusBadRequest
+		default:
+			statusCode = http.StatusInternalServerError
+		}
+		writeAppError(w, r, appErr, statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// HandleSubredditFlairs lists (GET ?subredditId=), creates (POST), updates (PUT) and
+// deletes (DELETE ?subredditId=&flairId=) a subreddit's flair templates
+func (s *Server) HandleSubredditFlairs() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var msg interface{}
+		switch r.Method {
+		case http.MethodGet:
+			subredditID, err := uuid.Parse(r.URL.Query().Get("subredditId"))
+			if err != nil {
+				http.Error(w, "Invalid subreddit ID", http.StatusBadRequest)
+				return
+			}
+			msg = &actors.ListFlairsMsg{SubredditID: subredditID}
+
+		case http.MethodPost, http.MethodPut:
+			userID, ok := middleware.GetUserIDFromContext(r.Context())
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			var req FlairRequest
 			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 				http.Error(w, "Invalid request body", http.StatusBadRequest)
 				return
 			}
+			subredditID, err := uuid.Parse(req.SubredditID)
+			if err != nil {
+				http.Error(w, "Invalid subreddit ID", http.StatusBadRequest)
+				return
+			}
 
+			if r.Method == http.MethodPost {
+				msg = &actors.CreateFlairMsg{SubredditID: subredditID, RequesterID: userID, Text: req.Text, Color: req.Color}
+				break
+			}
+			flairID, err := uuid.Parse(req.FlairID)
+			if err != nil {
+				http.Error(w, "Invalid flair ID", http.StatusBadRequest)
+				return
+			}
+			msg = &actors.UpdateFlairMsg{SubredditID: subredditID, FlairID: flairID, RequesterID: userID, Text: req.Text, Color: req.Color}
+
+		case http.MethodDelete:
+			userID, ok := middleware.GetUserIDFromContext(r.Context())
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			subredditID, err := uuid.Parse(r.URL.Query().Get("subredditId"))
+			if err != nil {
+				http.Error(w, "Invalid subreddit ID", http.StatusBadRequest)
+				return
+			}
+			flairID, err := uuid.Parse(r.URL.Query().Get("flairId"))
+			if err != nil {
+				http.Error(w, "Invalid flair ID", http.StatusBadRequest)
+				return
+			}
+			msg = &actors.DeleteFlairMsg{SubredditID: subredditID, FlairID: flairID, RequesterID: userID}
+
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		future := s.Context.RequestFuture(s.EnginePID, msg, s.RequestTimeout)
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to process flair request", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrNotFound:
+				statusCode = http.StatusNotFound
+			case utils.ErrForbidden:
+				statusCode = http.StatusForbidden
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodDelete:
+			result = map[string]bool{"success": true}
+		}
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleSubredditUserFlairs lists (GET ?subredditId=), creates (POST), updates (PUT) and
+// deletes (DELETE ?subredditId=&flairId=) a subreddit's user flair templates
+func (s *Server) HandleSubredditUserFlairs() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var msg interface{}
+		switch r.Method {
+		case http.MethodGet:
+			subredditID, err := uuid.Parse(r.URL.Query().Get("subredditId"))
+			if err != nil {
+				http.Error(w, "Invalid subreddit ID", http.StatusBadRequest)
+				return
+			}
+			msg = &actors.ListUserFlairsMsg{SubredditID: subredditID}
+
+		case http.MethodPost, http.MethodPut:
+			userID, ok := middleware.GetUserIDFromContext(r.Context())
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			var req UserFlairRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
 			subredditID, err := uuid.Parse(req.SubredditID)
 			if err != nil {
-				http.Error(w, "Invalid subreddit ID format", http.StatusBadRequest)
+				http.Error(w, "Invalid subreddit ID", http.StatusBadRequest)
 				return
 			}
 
-			userID, err := uuid.Parse(req.UserID)
+			if r.Method == http.MethodPost {
+				msg = &actors.CreateUserFlairMsg{SubredditID: subredditID, RequesterID: userID, Text: req.Text, Color: req.Color, ModOnly: req.ModOnly}
+				break
+			}
+			flairID, err := uuid.Parse(req.FlairID)
 			if err != nil {
-				http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+				http.Error(w, "Invalid flair ID", http.StatusBadRequest)
 				return
 			}
+			msg = &actors.UpdateUserFlairMsg{SubredditID: subredditID, FlairID: flairID, RequesterID: userID, Text: req.Text, Color: req.Color, ModOnly: req.ModOnly}
 
-			future := s.Context.RequestFuture(s.Engine.GetSubredditActor(),
-				&actors.JoinSubredditMsg{
-					SubredditID: subredditID,
-					UserID:      userID,
-				}, s.RequestTimeout)
+		case http.MethodDelete:
+			userID, ok := middleware.GetUserIDFromContext(r.Context())
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
 
-			result, err := future.Result()
+			subredditID, err := uuid.Parse(r.URL.Query().Get("subredditId"))
+			if err != nil {
+				http.Error(w, "Invalid subreddit ID", http.StatusBadRequest)
+				return
+			}
+			flairID, err := uuid.Parse(r.URL.Query().Get("flairId"))
 			if err != nil {
-				http.Error(w, "Failed to join subreddit", http.StatusInternalServerError)
+				http.Error(w, "Invalid flair ID", http.StatusBadRequest)
 				return
 			}
+			msg = &actors.DeleteUserFlairMsg{SubredditID: subredditID, FlairID: flairID, RequesterID: userID}
 
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(result)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		future := s.Context.RequestFuture(s.EnginePID, msg, s.RequestTimeout)
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to process user flair request", http.StatusInternalServerError)
+			return
+		}
 
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrNotFound:
+				statusCode = http.StatusNotFound
+			case utils.ErrForbidden:
+				statusCode = http.StatusForbidden
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
 		case http.MethodDelete:
-			// Leave a subreddit
+			result = map[string]bool{"success": true}
+		}
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleSetUserFlair sets the flair a member shows in a subreddit (POST /subreddit/userflair).
+// Members set their own; moderators can pass a userId to flair another member. An empty
+// flairId clears the flair.
+func (s *Server) HandleSetUserFlair() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		requesterID, ok := middleware.GetUserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			SubredditID string `json:"subredditId"`
+			UserID      string `json:"userId,omitempty"`
+			FlairID     string `json:"flairId,omitempty"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		subredditID, err := uuid.Parse(req.SubredditID)
+		if err != nil {
+			http.Error(w, "Invalid subreddit ID", http.StatusBadRequest)
+			return
+		}
+		msg := &actors.SetUserFlairMsg{SubredditID: subredditID, RequesterID: requesterID}
+		if req.UserID != "" {
+			if msg.UserID, err = uuid.Parse(req.UserID); err != nil {
+				http.Error(w, "Invalid user ID", http.StatusBadRequest)
+				return
+			}
+		}
+		if req.FlairID != "" {
+			flairID, err := uuid.Parse(req.FlairID)
+			if err != nil {
+				http.Error(w, "Invalid flair ID", http.StatusBadRequest)
+				return
+			}
+			msg.FlairID = &flairID
+		}
+
+		future := s.Context.RequestFuture(s.EnginePID, msg, s.RequestTimeout)
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to set user flair", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrNotFound:
+				statusCode = http.StatusNotFound
+			case utils.ErrForbidden:
+				statusCode = http.StatusForbidden
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleSubredditModerators lists (GET ?subredditId=), adds (POST) and removes
+// (DELETE ?subredditId=&userId=) a subreddit's moderators. Listing needs no
+// authentication; changes can only be made by the subreddit's moderators.
+func (s *Server) HandleSubredditModerators() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var msg interface{}
+		switch r.Method {
+		case http.MethodGet:
+			subredditID, err := uuid.Parse(r.URL.Query().Get("subredditId"))
+			if err != nil {
+				http.Error(w, "Invalid subreddit ID", http.StatusBadRequest)
+				return
+			}
+			msg = &actors.ListModeratorsMsg{SubredditID: subredditID}
+
+		case http.MethodPost:
+			requesterID, ok := middleware.GetUserIDFromContext(r.Context())
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
 			var req struct {
 				SubredditID string `json:"subredditId"`
 				UserID      string `json:"userId"`
 			}
-
 			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 				http.Error(w, "Invalid request body", http.StatusBadRequest)
 				return
 			}
-
 			subredditID, err := uuid.Parse(req.SubredditID)
 			if err != nil {
-				http.Error(w, "Invalid subreddit ID format", http.StatusBadRequest)
+				http.Error(w, "Invalid subreddit ID", http.StatusBadRequest)
 				return
 			}
-
 			userID, err := uuid.Parse(req.UserID)
 			if err != nil {
-				http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+				http.Error(w, "Invalid user ID", http.StatusBadRequest)
 				return
 			}
+			msg = &actors.AddModeratorMsg{SubredditID: subredditID, RequesterID: requesterID, UserID: userID}
 
-			future := s.Context.RequestFuture(s.Engine.GetSubredditActor(),
-				&actors.LeaveSubredditMsg{
-					SubredditID: subredditID,
-					UserID:      userID,
-				}, s.RequestTimeout)
+		case http.MethodDelete:
+			requesterID, ok := middleware.GetUserIDFromContext(r.Context())
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
 
-			result, err := future.Result()
+			subredditID, err := uuid.Parse(r.URL.Query().Get("subredditId"))
 			if err != nil {
-				http.Error(w, "Failed to leave subreddit", http.StatusInternalServerError)
+				http.Error(w, "Invalid subreddit ID", http.StatusBadRequest)
+				return
+			}
+			userID, err := uuid.Parse(r.URL.Query().Get("userId"))
+			if err != nil {
+				http.Error(w, "Invalid user ID", http.StatusBadRequest)
 				return
 			}
+			msg = &actors.RemoveModeratorMsg{SubredditID: subredditID, RequesterID: requesterID, UserID: userID}
 
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(result)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		future := s.Context.RequestFuture(s.EnginePID, msg, s.RequestTimeout)
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to process moderator request", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrNotFound, utils.ErrUserNotFound:
+				statusCode = http.StatusNotFound
+			case utils.ErrForbidden:
+				statusCode = http.StatusForbidden
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleSubredditAutomod shows (GET ?subredditId=) and replaces (PUT) a subreddit's
+// automod rules. Both are limited to the subreddit's moderators.
+func (s *Server) HandleSubredditAutomod() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requesterID, ok := middleware.GetUserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var msg interface{}
+		switch r.Method {
+		case http.MethodGet:
+			subredditID, err := uuid.Parse(r.URL.Query().Get("subredditId"))
+			if err != nil {
+				http.Error(w, "Invalid subreddit ID", http.StatusBadRequest)
+				return
+			}
+			msg = &actors.GetAutomodMsg{SubredditID: subredditID, RequesterID: requesterID}
+
+		case http.MethodPut:
+			var req struct {
+				SubredditID string               `json:"subredditId"`
+				Rules       []models.AutomodRule `json:"rules"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			subredditID, err := uuid.Parse(req.SubredditID)
+			if err != nil {
+				http.Error(w, "Invalid subreddit ID", http.StatusBadRequest)
+				return
+			}
+			msg = &actors.UpdateAutomodMsg{SubredditID: subredditID, RequesterID: requesterID, Rules: req.Rules}
 
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		future := s.Context.RequestFuture(s.Engine.GetSubredditActor(), msg, s.RequestTimeout)
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to process automod request", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			case utils.ErrNotFound:
+				statusCode = http.StatusNotFound
+			case utils.ErrForbidden:
+				statusCode = http.StatusForbidden
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleSetPostFlair sets or clears a post's flair (POST /post/flair)
+func (s *Server) HandleSetPostFlair() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := middleware.GetUserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
 		}
+
+		var req struct {
+			PostID  string `json:"postId"`
+			FlairID string `json:"flairId"` // Empty clears the flair
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		postID, err := uuid.Parse(req.PostID)
+		if err != nil {
+			http.Error(w, "Invalid post ID", http.StatusBadRequest)
+			return
+		}
+		var flairID *uuid.UUID
+		if req.FlairID != "" {
+			id, err := uuid.Parse(req.FlairID)
+			if err != nil {
+				http.Error(w, "Invalid flair ID", http.StatusBadRequest)
+				return
+			}
+			flairID = &id
+		}
+
+		future := s.Context.RequestFuture(s.EnginePID, &actors.SetPostFlairMsg{
+			PostID:      postID,
+			RequesterID: userID,
+			FlairID:     flairID,
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to set post flair", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrNotFound:
+				statusCode = http.StatusNotFound
+			case utils.ErrForbidden:
+				statusCode = http.StatusForbidden
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
 	}
 }
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"gator-swamp/internal/config"
+	"gator-swamp/internal/database/dbtest"
+	"gator-swamp/internal/engine/actors"
+	"gator-swamp/internal/middleware"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/google/uuid"
+)
+
+// Moderators mark their subreddit NSFW in its settings, which leaves its posts out of the
+// home feed of readers who haven't asked to see them
+func TestNSFWSubredditSettingHidesPostsFromFeed(t *testing.T) {
+	mongodb := dbtest.New(t)
+	ctx := context.Background()
+	system := actor.NewActorSystem()
+	subreddits := system.Root.Spawn(actor.PropsFromProducer(func() actor.Actor {
+		return actors.NewSubredditActor(utils.NewMetricsCollector(), mongodb, config.SubredditCreationRules{})
+	}))
+	posts := system.Root.Spawn(actor.PropsFromProducer(func() actor.Actor {
+		return actors.NewPostActor(utils.NewMetricsCollector(), nil, mongodb, time.Hour, 0, 0,
+			300, 40000, nil, nil, 1, time.Hour, 10)
+	}))
+	// Stands in for the engine, which routes these messages the same way
+	engine := system.Root.Spawn(actor.PropsFromFunc(func(c actor.Context) {
+		switch c.Message().(type) {
+		case *actors.UpdateSubredditSettingsMsg:
+			c.Forward(subreddits)
+		case *actors.GetUserFeedMsg:
+			c.Forward(posts)
+		}
+	}))
+	t.Cleanup(func() {
+		system.Root.StopFuture(engine).Wait()
+		system.Root.StopFuture(posts).Wait()
+		system.Root.StopFuture(subreddits).Wait()
+		system.Shutdown()
+	})
+	s := &Server{
+		System:         system,
+		Context:        system.Root,
+		EnginePID:      engine,
+		MongoDB:        mongodb,
+		RequestTimeout: 5 * time.Second,
+	}
+
+	moderatorID, readerID := uuid.New(), uuid.New()
+	for _, user := range []*models.User{
+		{ID: moderatorID, Username: "moderator", Email: "moderator@example.com", CreatedAt: time.Now()},
+		{ID: readerID, Username: "reader", Email: "reader@example.com", CreatedAt: time.Now()},
+	} {
+		if err := mongodb.SaveUser(ctx, user); err != nil {
+			t.Fatalf("SaveUser: %v", err)
+		}
+	}
+	subreddit := &models.Subreddit{
+		ID:        uuid.New(),
+		Name:      "swampnights",
+		CreatorID: moderatorID,
+		CreatedAt: time.Now(),
+		Type:      models.SubredditPublic,
+	}
+	if err := mongodb.CreateSubreddit(ctx, subreddit); err != nil {
+		t.Fatalf("CreateSubreddit: %v", err)
+	}
+	if _, err := mongodb.UpdateUserSubreddits(ctx, readerID, subreddit.ID, true); err != nil {
+		t.Fatalf("UpdateUserSubreddits: %v", err)
+	}
+	post := &models.Post{
+		ID:          uuid.New(),
+		Title:       "After dark",
+		Slug:        "after-dark",
+		AuthorID:    moderatorID,
+		SubredditID: subreddit.ID,
+		CreatedAt:   time.Now(),
+		Status:      models.PostStatusPublished,
+	}
+	if err := mongodb.SavePost(ctx, post); err != nil {
+		t.Fatalf("SavePost: %v", err)
+	}
+
+	setNSFW := func(userID uuid.UUID) *httptest.ResponseRecorder {
+		t.Helper()
+		body := `{"subredditId": "` + subreddit.ID.String() + `", "nsfw": true}`
+		r := httptest.NewRequest(http.MethodPut, "/subreddit/settings", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		s.HandleSubredditSettings()(w, r.WithContext(middleware.SetUserIDInContext(r.Context(), userID)))
+		return w
+	}
+	feed := func() int {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodGet, "/user/feed?sort=new", nil)
+		w := httptest.NewRecorder()
+		s.HandleGetFeed()(w, r.WithContext(middleware.SetUserIDInContext(r.Context(), readerID)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET /user/feed = %d: %s", w.Code, w.Body)
+		}
+		var items []json.RawMessage
+		if err := json.NewDecoder(w.Body).Decode(&items); err != nil {
+			t.Fatalf("decoding feed: %v", err)
+		}
+		return len(items)
+	}
+
+	if n := feed(); n != 1 {
+		t.Fatalf("feed has %d posts before the subreddit is marked NSFW, want 1", n)
+	}
+	if w := setNSFW(readerID); w.Code != http.StatusForbidden {
+		t.Fatalf("PUT /subreddit/settings from a non-moderator = %d, want 403", w.Code)
+	}
+	w := setNSFW(moderatorID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT /subreddit/settings = %d: %s", w.Code, w.Body)
+	}
+	var updated actors.SubredditResponse
+	if err := json.NewDecoder(w.Body).Decode(&updated); err != nil || !updated.NSFW {
+		t.Fatalf("settings response NSFW %v (%v), want true", updated.NSFW, err)
+	}
+
+	if n := feed(); n != 0 {
+		t.Fatalf("feed has %d posts from the NSFW subreddit, want none", n)
+	}
+	if err := mongodb.SetUserPreferences(ctx, readerID, &models.UserPreferences{ShowNSFW: true}); err != nil {
+		t.Fatalf("SetUserPreferences: %v", err)
+	}
+	if n := feed(); n != 1 {
+		t.Fatalf("feed has %d posts with showNsfw on, want 1", n)
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"gator-swamp/internal/config"
+	"gator-swamp/internal/database/dbtest"
+	"gator-swamp/internal/engine/actors"
+	"gator-swamp/internal/middleware"
+	"gator-swamp/internal/models"
+	"gator-swamp/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"github.com/google/uuid"
+)
+
+func TestSubredditSearchWorksWithoutToken(t *testing.T) {
+	mongodb := dbtest.New(t)
+	system := actor.NewActorSystem()
+	// The engine forwards searches to the SubredditActor, which stands in for it here
+	subreddits := system.Root.Spawn(actor.PropsFromProducer(func() actor.Actor {
+		return actors.NewSubredditActor(utils.NewMetricsCollector(), mongodb, config.SubredditCreationRules{})
+	}))
+	t.Cleanup(func() {
+		system.Root.StopFuture(subreddits).Wait()
+		system.Shutdown()
+	})
+
+	creatorID := uuid.New()
+	for name, kind := range map[string]models.SubredditType{
+		"swampland":   models.SubredditPublic,
+		"swampsecret": models.SubredditPrivate,
+	} {
+		err := mongodb.CreateSubreddit(context.Background(), &models.Subreddit{
+			ID:        uuid.New(),
+			Name:      name,
+			CreatorID: creatorID,
+			CreatedAt: time.Now(),
+			Type:      kind,
+		})
+		if err != nil {
+			t.Fatalf("CreateSubreddit: %v", err)
+		}
+	}
+
+	s := &Server{
+		System:         system,
+		Context:        system.Root,
+		EnginePID:      subreddits,
+		MongoDB:        mongodb,
+		RequestTimeout: 5 * time.Second,
+	}
+	handler := middleware.ApplyOptionalJWTMiddleware(s.HandleSearchSubreddits(), "/subreddit/search")
+
+	// Two letters, so only names are searched
+	search := func(viewerID uuid.UUID) []string {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodGet, "/subreddit/search?q=sw", nil)
+		w := httptest.NewRecorder()
+		if viewerID == uuid.Nil {
+			handler(w, r)
+		} else {
+			// As the JWT middleware passes on a valid token
+			s.HandleSearchSubreddits()(w, r.WithContext(middleware.SetUserIDInContext(r.Context(), viewerID)))
+		}
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET /subreddit/search = %d: %s", w.Code, w.Body)
+		}
+		var results []models.SubredditSearchResult
+		if err := json.NewDecoder(w.Body).Decode(&results); err != nil {
+			t.Fatalf("decoding results: %v", err)
+		}
+		names := make([]string, 0, len(results))
+		for _, result := range results {
+			names = append(names, result.Name)
+		}
+		return names
+	}
+
+	if names := search(uuid.Nil); !equalStrings(names, []string{"swampland"}) {
+		t.Fatalf("anonymous search found %v, want just the public subreddit", names)
+	}
+	if names := search(creatorID); len(names) != 2 {
+		t.Fatalf("creator's search found %v, want both subreddits", names)
+	}
+}
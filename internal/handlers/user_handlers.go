@@ -1,24 +1,110 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"gator-swamp/internal/analytics"
+	"gator-swamp/internal/auth"
+	"gator-swamp/internal/database"
 	"gator-swamp/internal/engine/actors"
+	"gator-swamp/internal/i18n"
 	"gator-swamp/internal/middleware"
+	"gator-swamp/internal/models"
 	"gator-swamp/internal/types"
+	"gator-swamp/internal/utils"
+	"io"
 	"log"
+	"math"
 	"net/http"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
 
-// RegisterUserRequest represents a request to register a new user
+// RegisterUserRequest represents a request to register a new user. Karma isn't accepted;
+// new accounts start with none.
 type RegisterUserRequest struct {
 	Username string `json:"username"`
 	Email    string `json:"email"`
 	Password string `json:"password"`
-	Karma    int    `json:"karma"`
+}
+
+// FieldError describes one problem with a field of a request
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrorResponse lists every problem found with a request
+type ValidationErrorResponse struct {
+	Error  string       `json:"error"`
+	Errors []FieldError `json:"errors"`
+}
+
+const (
+	minUsernameLength = 3
+	maxUsernameLength = 20
+
+	// bcrypt only uses the first 72 bytes of a password and rejects longer ones
+	maxPasswordBytes = 72
+)
+
+// usernamePattern matches the characters usernames may contain
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// validateRegistration returns every problem with a registration request, or nil if
+// there are none
+func validateRegistration(req *RegisterUserRequest, minPasswordLength int) []FieldError {
+	var problems []FieldError
+	add := func(field, format string, args ...interface{}) {
+		problems = append(problems, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if n := utf8.RuneCountInString(req.Username); n < minUsernameLength || n > maxUsernameLength {
+		add("username", "must be %d to %d characters long", minUsernameLength, maxUsernameLength)
+	}
+	if req.Username != "" && !usernamePattern.MatchString(req.Username) {
+		add("username", "may only contain letters, digits, underscores and hyphens")
+	}
+	if req.Username != "" && req.Username[0] >= '0' && req.Username[0] <= '9' {
+		add("username", "must not start with a digit")
+	}
+
+	problems = append(problems, validateEmail("email", req.Email)...)
+	return append(problems, validatePassword("password", req.Password, minPasswordLength)...)
+}
+
+// validateEmail returns every problem with an email address, reported against field
+func validateEmail(field, email string) []FieldError {
+	if email == "" {
+		return []FieldError{{Field: field, Message: "is required"}}
+	}
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email ||
+		!strings.Contains(email[strings.LastIndex(email, "@")+1:], ".") {
+		return []FieldError{{Field: field, Message: "must be a valid email address"}}
+	}
+	return nil
+}
+
+// validatePassword returns every problem with a new password, reported against field
+func validatePassword(field, password string, minPasswordLength int) []FieldError {
+	var problems []FieldError
+	if utf8.RuneCountInString(password) < minPasswordLength {
+		problems = append(problems, FieldError{Field: field,
+			Message: fmt.Sprintf("must be at least %d characters long", minPasswordLength)})
+	}
+	if len(password) > maxPasswordBytes {
+		problems = append(problems, FieldError{Field: field,
+			Message: fmt.Sprintf("must be at most %d bytes long", maxPasswordBytes)})
+	}
+	return problems
 }
 
 // LoginRequest represents a request to log in a user
@@ -48,6 +134,15 @@ func (s *Server) HandleUserRegistration() http.HandlerFunc {
 			http.Error(w, "Invalid request", http.StatusBadRequest)
 			return
 		}
+		req.Username = strings.TrimSpace(req.Username)
+		req.Email = strings.TrimSpace(req.Email)
+
+		if problems := validateRegistration(&req, s.Config.MinPasswordLength); len(problems) > 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ValidationErrorResponse{Error: "Invalid registration", Errors: problems})
+			return
+		}
 
 		future := s.Context.RequestFuture(
 			s.Engine.GetUserSupervisor(),
@@ -55,7 +150,6 @@ func (s *Server) HandleUserRegistration() http.HandlerFunc {
 				Username: req.Username,
 				Email:    req.Email,
 				Password: req.Password,
-				Karma:    req.Karma,
 			},
 			s.RequestTimeout,
 		)
@@ -66,8 +160,30 @@ func (s *Server) HandleUserRegistration() http.HandlerFunc {
 			return
 		}
 
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrDuplicate:
+				statusCode = http.StatusConflict
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		user, ok := result.(*actors.UserState)
+		if !ok {
+			http.Error(w, "Invalid response type", http.StatusInternalServerError)
+			return
+		}
+		analytics.Record(analytics.EventSignup, user.ID, user.ID)
+		go s.sendEmailVerification(user.ID, user.Username, user.Email)
+
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(result)
+		json.NewEncoder(w).Encode(s.newPrivateProfile(user))
 	}
 }
 
@@ -123,15 +239,27 @@ func (s *Server) HandleUserLogin() http.HandlerFunc {
 			}
 
 			// Generate JWT token
-			token, err := middleware.GenerateToken(userID)
+			token, err := auth.GenerateToken(userID, loginResp.Username)
 			if err != nil {
 				log.Printf("HTTP Handler: Failed to generate token: %v", err)
 				http.Error(w, "Failed to generate auth token", http.StatusInternalServerError)
 				return
 			}
 
-			// Add token to response
+			// Start a session the client can renew the token from
+			refreshToken, refreshHash, err := auth.NewRefreshToken()
+			if err == nil {
+				err = s.MongoDB.CreateSession(r.Context(), userID, refreshHash, time.Now(), s.Config.RefreshTokenTTL)
+			}
+			if err != nil {
+				log.Printf("HTTP Handler: Failed to create session: %v", err)
+				http.Error(w, "Failed to generate auth token", http.StatusInternalServerError)
+				return
+			}
+
+			// Add tokens to response
 			loginResp.Token = token
+			loginResp.RefreshToken = refreshToken
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -143,170 +271,1451 @@ func (s *Server) HandleUserLogin() http.HandlerFunc {
 	}
 }
 
-// HandleUserProfile handles requests to get a user's profile
-func (s *Server) HandleUserProfile() http.HandlerFunc {
+// HandleUserRefresh exchanges a refresh token for a new access token and the refresh
+// token that replaces it
+func (s *Server) HandleUserRefresh() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet {
+		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
-		userIDStr := r.URL.Query().Get("userId")
-		if userIDStr == "" {
-			http.Error(w, "User ID required", http.StatusBadRequest)
+		var req types.RefreshRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
 			return
 		}
 
-		userID, err := uuid.Parse(userIDStr)
+		refreshToken, refreshHash, err := auth.NewRefreshToken()
 		if err != nil {
-			http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+			log.Printf("HTTP Handler: %v", err)
+			http.Error(w, "Failed to refresh token", http.StatusInternalServerError)
 			return
 		}
 
-		future := s.Context.RequestFuture(
-			s.Engine.GetUserSupervisor(),
-			&actors.GetUserProfileMsg{UserID: userID},
-			s.RequestTimeout,
-		)
+		userID, err := s.MongoDB.RotateSession(r.Context(), auth.HashRefreshToken(req.RefreshToken),
+			refreshHash, time.Now(), s.Config.RefreshTokenTTL)
+		if err != nil {
+			if appErr, ok := err.(*utils.AppError); ok && appErr.Code == utils.ErrUnauthorized {
+				writeAppError(w, r, appErr, http.StatusUnauthorized)
+				return
+			}
+			log.Printf("HTTP Handler: Failed to rotate session: %v", err)
+			http.Error(w, "Failed to refresh token", http.StatusInternalServerError)
+			return
+		}
 
-		result, err := future.Result()
+		user, err := s.MongoDB.GetUser(r.Context(), userID)
 		if err != nil {
-			http.Error(w, "Failed to get user profile", http.StatusInternalServerError)
+			if utils.IsErrorCode(err, utils.ErrUserNotFound) {
+				http.Error(w, "Invalid or expired refresh token", http.StatusUnauthorized)
+				return
+			}
+			http.Error(w, "Failed to refresh token", http.StatusInternalServerError)
 			return
 		}
 
-		if result == nil {
-			http.Error(w, "User not found", http.StatusNotFound)
+		token, err := auth.GenerateToken(user.ID, user.Username)
+		if err != nil {
+			log.Printf("HTTP Handler: Failed to generate token: %v", err)
+			http.Error(w, "Failed to generate auth token", http.StatusInternalServerError)
 			return
 		}
 
-		userState, ok := result.(*actors.UserState)
-		if !ok {
-			http.Error(w, "Invalid response type", http.StatusInternalServerError)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&types.LoginResponse{
+			Success:      true,
+			Token:        token,
+			UserID:       user.ID.String(),
+			Username:     user.Username,
+			RefreshToken: refreshToken,
+		})
+	}
+}
+
+// HandleUserLogout ends the caller's session: the access token it was sent with stops
+// working and the refresh token in the body can't be exchanged any more
+func (s *Server) HandleUserLogout() http.HandlerFunc {
+	return s.handleLogout(false)
+}
+
+// HandleUserLogoutAll ends every session of the caller, on all devices
+func (s *Server) HandleUserLogoutAll() http.HandlerFunc {
+	return s.handleLogout(true)
+}
+
+// handleLogout serves both logout endpoints. They're public so that logging out with a
+// token that is already expired or revoked succeeds; the caller is identified by the
+// access token if it's valid, otherwise by the refresh token.
+func (s *Server) handleLogout(everywhere bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req types.LogoutRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
 			return
 		}
 
-		// Create response in the format you requested
-		response := struct {
-			ID            string    `json:"id"`
-			Username      string    `json:"username"`
-			Email         string    `json:"email"`
-			Karma         int       `json:"karma"`
-			IsConnected   bool      `json:"isConnected"`
-			LastActive    time.Time `json:"lastActive"`
-			SubredditID   []string  `json:"subredditID"`
-			SubredditName []string  `json:"subredditName"`
-		}{
-			ID:          userState.ID.String(),
-			Username:    userState.Username,
-			Email:       userState.Email,
-			Karma:       userState.Karma,
-			IsConnected: userState.IsConnected,
-			LastActive:  userState.LastActive,
+		userID := uuid.Nil
+		tokenString := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if claims, err := auth.ValidateToken(tokenString); err == nil {
+			userID = claims.UserID
+			auth.RevokeToken(claims)
 		}
 
-		// Convert UUID slices to string slices
-		response.SubredditID = make([]string, len(userState.Subreddits))
-		for i, id := range userState.Subreddits {
-			response.SubredditID[i] = id.String()
+		if req.RefreshToken != "" {
+			owner, err := s.MongoDB.DeleteSession(r.Context(), auth.HashRefreshToken(req.RefreshToken))
+			if err != nil {
+				log.Printf("HTTP Handler: Failed to end session: %v", err)
+				http.Error(w, "Failed to log out", http.StatusInternalServerError)
+				return
+			}
+			if userID == uuid.Nil {
+				userID = owner
+			}
+		}
+
+		if everywhere && userID != uuid.Nil {
+			if err := s.MongoDB.DeleteUserSessions(r.Context(), userID); err != nil {
+				log.Printf("HTTP Handler: Failed to end sessions: %v", err)
+				http.Error(w, "Failed to log out", http.StatusInternalServerError)
+				return
+			}
+			auth.RevokeUserTokens(userID, time.Now())
 		}
-		response.SubredditName = userState.SubredditNames
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
+		json.NewEncoder(w).Encode(map[string]bool{"success": true})
 	}
 }
 
-// HandleGetAllUsers handles requests to get all users
-func (s *Server) HandleGetAllUsers() http.HandlerFunc {
+// HandleChangePassword replaces the caller's password after checking the current one.
+// The caller's other sessions are ended and their access tokens revoked; the response
+// carries a new access token for the session that is kept.
+func (s *Server) HandleChangePassword() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet {
+		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
-		ctx := r.Context()
-		cursor, err := s.MongoDB.Users.Find(ctx, map[string]interface{}{})
+		userID, ok := middleware.GetUserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req types.ChangePasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+
+		if problems := validatePassword("newPassword", req.NewPassword, s.Config.MinPasswordLength); len(problems) > 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ValidationErrorResponse{Error: "Invalid password", Errors: problems})
+			return
+		}
+
+		future := s.Context.RequestFuture(s.Engine.GetUserSupervisor(), &actors.ChangePasswordMsg{
+			UserID:          userID,
+			CurrentPassword: req.CurrentPassword,
+			NewPassword:     req.NewPassword,
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
 		if err != nil {
-			http.Error(w, "Failed to fetch users", http.StatusInternalServerError)
+			http.Error(w, "Failed to change password", http.StatusInternalServerError)
 			return
 		}
-		defer cursor.Close(ctx)
 
-		var users []struct {
-			ID       string    `json:"id"`
-			Username string    `json:"username"`
-			Email    string    `json:"email"`
-			Karma    int       `json:"karma"`
-			JoinedAt time.Time `json:"joinedAt"`
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrUnauthorized:
+				statusCode = http.StatusUnauthorized
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
 		}
 
-		for cursor.Next(ctx) {
-			var user struct {
-				ID        string    `bson:"_id"`
-				Username  string    `bson:"username"`
-				Email     string    `bson:"email"`
-				Karma     int       `bson:"karma"`
-				CreatedAt time.Time `bson:"createdAt"`
+		// Log out every other device, then give this one a token issued after the revocation
+		if err := s.MongoDB.DeleteOtherUserSessions(r.Context(), userID, auth.HashRefreshToken(req.RefreshToken)); err != nil {
+			log.Printf("HTTP Handler: Failed to end sessions: %v", err)
+			http.Error(w, "Password changed but other sessions could not be ended", http.StatusInternalServerError)
+			return
+		}
+		auth.RevokeUserTokens(userID, time.Now())
+
+		user, err := s.MongoDB.GetUser(r.Context(), userID)
+		if err != nil {
+			http.Error(w, "Failed to change password", http.StatusInternalServerError)
+			return
+		}
+		token, err := auth.GenerateToken(user.ID, user.Username)
+		if err != nil {
+			log.Printf("HTTP Handler: Failed to generate token: %v", err)
+			http.Error(w, "Failed to generate auth token", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&types.LoginResponse{
+			Success:  true,
+			Token:    token,
+			UserID:   user.ID.String(),
+			Username: user.Username,
+		})
+	}
+}
+
+// passwordResetTimeout bounds the lookup, storage and delivery of one reset email
+const passwordResetTimeout = 30 * time.Second
+
+// passwordResetIPWindow is the period over which Config.PasswordResetIPLimit applies
+const passwordResetIPWindow = time.Hour
+
+// HandlePasswordResetRequest emails a password reset token to the account registered
+// with an email address. It always succeeds, whether or not the address has an account,
+// and sends the email in the background so response times don't reveal it either.
+// Clients making too many requests are turned away, but a user sent a reset email
+// recently isn't sent another, without the response showing it.
+func (s *Server) HandlePasswordResetRequest() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req types.PasswordResetRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+
+		allowed, wait := s.passwordResetRequests.allow(clientIP(r), s.Config.PasswordResetIPLimit, passwordResetIPWindow, time.Now())
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "Too many password reset requests; try again later", http.StatusTooManyRequests)
+			return
+		}
+
+		if email := strings.TrimSpace(req.Email); email != "" {
+			go s.sendPasswordReset(email)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]bool{"success": true})
+	}
+}
+
+// sendPasswordReset issues a reset token for the account registered with email, if
+// there is one, and mails it. Failures are only logged since the client has already
+// been answered.
+func (s *Server) sendPasswordReset(email string) {
+	ctx, cancel := context.WithTimeout(context.Background(), passwordResetTimeout)
+	defer cancel()
+
+	user, err := s.MongoDB.GetUserByEmail(ctx, email)
+	if err != nil {
+		if !utils.IsErrorCode(err, utils.ErrUserNotFound) {
+			log.Printf("Password reset: Failed to look up account: %v", err)
+		}
+		return
+	}
+
+	// The token already sent still works, and replacing it would only flood the inbox
+	sentAt, err := s.MongoDB.LastPasswordResetSentAt(ctx, user.ID)
+	if err != nil {
+		log.Printf("Password reset: %v", err)
+		return
+	}
+	if time.Since(sentAt) < s.Config.PasswordResetResendInterval {
+		return
+	}
+
+	token, tokenHash, err := auth.NewPasswordResetToken()
+	if err != nil {
+		log.Printf("Password reset: %v", err)
+		return
+	}
+	if err := s.MongoDB.CreatePasswordReset(ctx, user.ID, tokenHash, time.Now(), s.Config.PasswordResetTTL); err != nil {
+		log.Printf("Password reset: %v", err)
+		return
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\nUse this token to reset your password. It expires in %d minutes and can only be used once:\n\n%s\n\nIf you didn't ask to reset your password, you can ignore this email.",
+		user.Username, int(s.Config.PasswordResetTTL.Minutes()), token)
+	if err := s.Mailer.Send(ctx, user.Email, "Reset your password", body); err != nil {
+		log.Printf("Password reset: Failed to send email to user %s: %v", user.ID, err)
+	}
+}
+
+// HandlePasswordResetConfirm sets a new password using an emailed reset token. The
+// token is used up, and every session of the user is ended.
+func (s *Server) HandlePasswordResetConfirm() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req types.PasswordResetConfirmRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+
+		// Check the password first so a weak one doesn't use up the token
+		if problems := validatePassword("newPassword", req.NewPassword, s.Config.MinPasswordLength); len(problems) > 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ValidationErrorResponse{Error: "Invalid password", Errors: problems})
+			return
+		}
+
+		userID, err := s.MongoDB.ConsumePasswordReset(r.Context(), auth.HashPasswordResetToken(req.Token), time.Now())
+		if err != nil {
+			if appErr, ok := err.(*utils.AppError); ok && appErr.Code == utils.ErrInvalidToken {
+				writeAppError(w, r, appErr, http.StatusBadRequest)
+				return
 			}
-			if err := cursor.Decode(&user); err != nil {
-				continue
+			log.Printf("HTTP Handler: Failed to redeem password reset: %v", err)
+			http.Error(w, "Failed to reset password", http.StatusInternalServerError)
+			return
+		}
+
+		future := s.Context.RequestFuture(s.Engine.GetUserSupervisor(), &actors.ResetPasswordMsg{
+			UserID:      userID,
+			NewPassword: req.NewPassword,
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to reset password", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrNotFound:
+				statusCode = http.StatusNotFound
+			default:
+				statusCode = http.StatusInternalServerError
 			}
-			users = append(users, struct {
-				ID       string    `json:"id"`
-				Username string    `json:"username"`
-				Email    string    `json:"email"`
-				Karma    int       `json:"karma"`
-				JoinedAt time.Time `json:"joinedAt"`
-			}{
-				ID:       user.ID,
-				Username: user.Username,
-				Email:    user.Email,
-				Karma:    user.Karma,
-				JoinedAt: user.CreatedAt,
-			})
+			writeAppError(w, r, appErr, statusCode)
+			return
 		}
 
+		// Whoever knew the old password is logged out everywhere
+		if err := s.MongoDB.DeleteUserSessions(r.Context(), userID); err != nil {
+			log.Printf("HTTP Handler: Failed to end sessions after password reset: %v", err)
+		}
+		auth.RevokeUserTokens(userID, time.Now())
+
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(users)
+		json.NewEncoder(w).Encode(map[string]bool{"success": true})
 	}
 }
 
-// HandleGetFeed handles requests to get a user's feed
-func (s *Server) HandleGetFeed() http.HandlerFunc {
+// emailVerificationTimeout bounds the storage and delivery of one verification email
+const emailVerificationTimeout = 30 * time.Second
+
+// sendEmailVerification issues a verification token for a user's email address and
+// mails it. Failures are only logged; the user can ask for another email.
+func (s *Server) sendEmailVerification(userID uuid.UUID, username, email string) {
+	ctx, cancel := context.WithTimeout(context.Background(), emailVerificationTimeout)
+	defer cancel()
+
+	token, tokenHash, err := auth.NewEmailVerificationToken()
+	if err != nil {
+		log.Printf("Email verification: %v", err)
+		return
+	}
+	if err := s.MongoDB.CreateEmailVerification(ctx, userID, email, tokenHash, time.Now(), s.Config.EmailVerificationTTL); err != nil {
+		log.Printf("Email verification: %v", err)
+		return
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\nConfirm this is your email address by opening /user/verify-email?token=%s within %d hours.\n\nIf you didn't sign up or change your email, you can ignore this email.",
+		username, url.QueryEscape(token), int(s.Config.EmailVerificationTTL.Hours()))
+	if err := s.Mailer.Send(ctx, email, "Verify your email address", body); err != nil {
+		log.Printf("Email verification: Failed to send email to user %s: %v", userID, err)
+	}
+}
+
+// HandleVerifyEmail marks a user's email address verified using the token emailed to it.
+// The token is used up.
+func (s *Server) HandleVerifyEmail() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
-		userIDStr := r.URL.Query().Get("userId")
-		if userIDStr == "" {
-			http.Error(w, "User ID required", http.StatusBadRequest)
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			http.Error(w, "token is required", http.StatusBadRequest)
 			return
 		}
 
-		userID, err := uuid.Parse(userIDStr)
+		userID, err := s.MongoDB.VerifyEmail(r.Context(), auth.HashEmailVerificationToken(token), time.Now())
 		if err != nil {
-			http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+			if appErr, ok := err.(*utils.AppError); ok && appErr.Code == utils.ErrInvalidToken {
+				writeAppError(w, r, appErr, http.StatusBadRequest)
+				return
+			}
+			log.Printf("HTTP Handler: Failed to verify email: %v", err)
+			http.Error(w, "Failed to verify email", http.StatusInternalServerError)
 			return
 		}
 
-		// Get limit from query params, default to 50
-		limit := 50
-		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-			fmt.Sscanf(limitStr, "%d", &limit)
+		// Listed admin emails confer the role once their owner has proven they receive
+		// mail there. Everyone verified earlier was already granted at startup.
+		if granted, err := s.MongoDB.GrantBootstrapAdmins(r.Context(), s.Config.AdminEmails, nil); err != nil {
+			log.Printf("HTTP Handler: Failed to grant bootstrap admin to user %s: %v", userID, err)
+		} else if granted > 0 {
+			log.Printf("Granted admin role to user %s: verified a listed admin email", userID)
 		}
 
-		// Send to Engine
-		future := s.Context.RequestFuture(s.EnginePID, &actors.GetUserFeedMsg{
-			UserID: userID,
-			Limit:  limit,
-		}, s.RequestTimeout)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]bool{"success": true, "emailVerified": true})
+	}
+}
 
-		result, err := future.Result()
-		if err != nil {
-			http.Error(w, "Failed to get feed", http.StatusInternalServerError)
+// HandleResendEmailVerification emails the caller a new verification token, replacing
+// the one sent before. Users who are already verified get 400, and users asking again
+// within the resend interval get 429.
+func (s *Server) HandleResendEmailVerification() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := middleware.GetUserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		user, err := s.MongoDB.GetUser(r.Context(), userID)
+		if err != nil {
+			if utils.IsErrorCode(err, utils.ErrUserNotFound) {
+				writeLocalizedError(w, r, i18n.ErrUserNotFound, nil, http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Failed to fetch user", http.StatusInternalServerError)
+			return
+		}
+		if user.EmailVerified {
+			http.Error(w, "Email is already verified", http.StatusBadRequest)
+			return
+		}
+
+		sentAt, err := s.MongoDB.LastEmailVerificationSentAt(r.Context(), userID)
+		if err != nil {
+			log.Printf("HTTP Handler: Failed to check email verification: %v", err)
+			http.Error(w, "Failed to resend verification email", http.StatusInternalServerError)
+			return
+		}
+		if wait := s.Config.EmailVerificationResendInterval - time.Since(sentAt); wait > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "A verification email was sent recently; try again later", http.StatusTooManyRequests)
+			return
+		}
+
+		go s.sendEmailVerification(user.ID, user.Username, user.Email)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]bool{"success": true})
+	}
+}
+
+// HandleChangeEmail moves the caller to a new email address after checking their
+// password. The new address is unverified until the user follows the verification
+// email sent to it.
+func (s *Server) HandleChangeEmail() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := middleware.GetUserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req types.ChangeEmailRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		req.Email = strings.TrimSpace(req.Email)
+
+		if problems := validateEmail("email", req.Email); len(problems) > 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ValidationErrorResponse{Error: "Invalid email", Errors: problems})
+			return
+		}
+
+		future := s.Context.RequestFuture(s.Engine.GetUserSupervisor(), &actors.ChangeEmailMsg{
+			UserID:          userID,
+			CurrentPassword: req.CurrentPassword,
+			Email:           req.Email,
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to change email", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrUnauthorized:
+				statusCode = http.StatusUnauthorized
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			case utils.ErrDuplicate:
+				statusCode = http.StatusConflict
+			case utils.ErrNotFound:
+				statusCode = http.StatusNotFound
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		user, ok := result.(*actors.UserState)
+		if !ok {
+			http.Error(w, "Invalid response type", http.StatusInternalServerError)
+			return
+		}
+		go s.sendEmailVerification(user.ID, user.Username, user.Email)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.newPrivateProfile(user))
+	}
+}
+
+// HandleUserProfile handles requests to get a user's profile
+func (s *Server) HandleUserProfile() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			s.updateUserProfile(w, r)
+			return
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userIDStr := r.URL.Query().Get("userId")
+		if userIDStr == "" {
+			http.Error(w, "User ID required", http.StatusBadRequest)
+			return
+		}
+
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+			return
+		}
+
+		future := s.Context.RequestFuture(
+			s.Engine.GetUserSupervisor(),
+			&actors.GetUserProfileMsg{UserID: userID},
+			s.RequestTimeout,
+		)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to get user profile", http.StatusInternalServerError)
+			return
+		}
+
+		if result == nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		userState, ok := result.(*actors.UserState)
+		if !ok {
+			http.Error(w, "Invalid response type", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.profileFor(r, userState))
+	}
+}
+
+// profileFor returns the private profile when the request was made by the user
+// themselves and the public profile to everyone else
+func (s *Server) profileFor(r *http.Request, userState *actors.UserState) interface{} {
+	if callerID, ok := middleware.GetUserIDFromContext(r.Context()); ok && callerID == userState.ID {
+		return s.newPrivateProfile(userState)
+	}
+	return s.newPublicProfile(userState)
+}
+
+func (s *Server) newPublicProfile(userState *actors.UserState) *types.PublicUserProfile {
+	return &types.PublicUserProfile{
+		ID:             userState.ID.String(),
+		Username:       userState.Username,
+		DisplayName:    userState.DisplayName,
+		Bio:            userState.Bio,
+		AvatarURL:      userState.AvatarURL,
+		Karma:          userState.Karma,
+		PostKarma:      userState.PostKarma,
+		CommentKarma:   userState.CommentKarma,
+		FollowerCount:  userState.FollowerCount,
+		FollowingCount: userState.FollowingCount,
+		ActiveRecently: time.Since(userState.LastActive) < s.Config.ActiveRecentlyWindow,
+		CreatedAt:      userState.CreatedAt,
+	}
+}
+
+func (s *Server) newPrivateProfile(userState *actors.UserState) *types.PrivateUserProfile {
+	profile := &types.PrivateUserProfile{
+		PublicUserProfile: *s.newPublicProfile(userState),
+		Email:             userState.Email,
+		EmailVerified:     userState.EmailVerified,
+		IsConnected:       userState.IsConnected,
+		LastActive:        userState.LastActive,
+		IsAdmin:           userState.IsAdmin,
+		SubredditName:     userState.SubredditNames,
+	}
+
+	// Convert UUID slices to string slices
+	profile.SubredditID = make([]string, len(userState.Subreddits))
+	for i, id := range userState.Subreddits {
+		profile.SubredditID[i] = id.String()
+	}
+	return profile
+}
+
+// HandleGetUserByUsername returns the profile of the user with a username, matched
+// regardless of case. Only users looking themselves up get the private profile.
+func (s *Server) HandleGetUserByUsername() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		username := strings.TrimSpace(r.URL.Query().Get("username"))
+		if username == "" {
+			http.Error(w, "Username is required", http.StatusBadRequest)
+			return
+		}
+
+		future := s.Context.RequestFuture(s.Engine.GetUserSupervisor(), &actors.GetUserByUsernameMsg{Username: username}, s.RequestTimeout)
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to get user profile", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrNotFound:
+				statusCode = http.StatusNotFound
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		userState, ok := result.(*actors.UserState)
+		if !ok {
+			http.Error(w, "Invalid response type", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.profileFor(r, userState))
+	}
+}
+
+// UpdateProfileRequest changes the caller's profile details. Omitted fields are left
+// unchanged and empty ones are cleared.
+type UpdateProfileRequest struct {
+	UserID      string  `json:"userId"` // Optional; must be the authenticated user
+	DisplayName *string `json:"displayName"`
+	Bio         *string `json:"bio"`
+	AvatarURL   *string `json:"avatarUrl"`
+}
+
+// updateUserProfile serves PUT /user/profile
+func (s *Server) updateUserProfile(w http.ResponseWriter, r *http.Request) {
+	requesterID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req UpdateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	userID := requesterID
+	if req.UserID != "" {
+		parsed, err := uuid.Parse(req.UserID)
+		if err != nil {
+			http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+			return
+		}
+		userID = parsed
+	}
+
+	future := s.Context.RequestFuture(s.Engine.GetUserSupervisor(), &actors.UpdateProfileMsg{
+		UserID:      userID,
+		RequesterID: requesterID,
+		DisplayName: req.DisplayName,
+		Bio:         req.Bio,
+		AvatarURL:   req.AvatarURL,
+	}, s.RequestTimeout)
+
+	result, err := future.Result()
+	if err != nil {
+		http.Error(w, "Failed to update profile", http.StatusInternalServerError)
+		return
+	}
+
+	if appErr, ok := result.(*utils.AppError); ok {
+		var statusCode int
+		switch appErr.Code {
+		case utils.ErrInvalidInput:
+			statusCode = http.StatusBadRequest
+		case utils.ErrForbidden:
+			statusCode = http.StatusForbidden
+		case utils.ErrNotFound:
+			statusCode = http.StatusNotFound
+		default:
+			statusCode = http.StatusInternalServerError
+		}
+		writeAppError(w, r, appErr, statusCode)
+		return
+	}
+
+	userState, ok := result.(*actors.UserState)
+	if !ok {
+		http.Error(w, "Invalid response type", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.newPrivateProfile(userState))
+}
+
+// HandleGetAllUsers handles requests to get all users
+func (s *Server) HandleGetAllUsers() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		ctx := r.Context()
+		cursor, err := s.MongoDB.Users.Find(ctx, map[string]interface{}{})
+		if err != nil {
+			http.Error(w, "Failed to fetch users", http.StatusInternalServerError)
+			return
+		}
+		defer cursor.Close(ctx)
+
+		var users []struct {
+			ID       string    `json:"id"`
+			Username string    `json:"username"`
+			Karma    int       `json:"karma"`
+			JoinedAt time.Time `json:"joinedAt"`
+		}
+
+		for cursor.Next(ctx) {
+			var user struct {
+				ID        string    `bson:"_id"`
+				Username  string    `bson:"username"`
+				Karma     int       `bson:"karma"`
+				CreatedAt time.Time `bson:"createdAt"`
+			}
+			if err := cursor.Decode(&user); err != nil {
+				continue
+			}
+			users = append(users, struct {
+				ID       string    `json:"id"`
+				Username string    `json:"username"`
+				Karma    int       `json:"karma"`
+				JoinedAt time.Time `json:"joinedAt"`
+			}{
+				ID:       user.ID,
+				Username: user.Username,
+				Karma:    user.Karma,
+				JoinedAt: user.CreatedAt,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(users)
+	}
+}
+
+// HandleUserPreferences returns (GET) or replaces (PUT) the caller's preferences.
+// Preferences left out of a PUT go back to their defaults, and unknown ones are rejected.
+func (s *Server) HandleUserPreferences() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.GetUserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var msg interface{}
+		switch r.Method {
+		case http.MethodGet:
+			msg = &actors.GetPreferencesMsg{UserID: userID}
+		case http.MethodPut:
+			var prefs models.UserPreferences
+			decoder := json.NewDecoder(r.Body)
+			decoder.DisallowUnknownFields()
+			if err := decoder.Decode(&prefs); err != nil {
+				if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+					http.Error(w, "Unknown preference "+field, http.StatusBadRequest)
+					return
+				}
+				http.Error(w, "Invalid request", http.StatusBadRequest)
+				return
+			}
+			msg = &actors.SetPreferencesMsg{UserID: userID, Preferences: prefs}
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		future := s.Context.RequestFuture(s.Engine.GetUserSupervisor(), msg, s.RequestTimeout)
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to process preferences", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			case utils.ErrNotFound:
+				statusCode = http.StatusNotFound
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// viewerPreferences returns a user's preferences for read paths to fall back on when
+// the request doesn't say. Anonymous viewers, and failed lookups, get the defaults.
+func (s *Server) viewerPreferences(r *http.Request, viewerID uuid.UUID) *models.UserPreferences {
+	if viewerID == uuid.Nil {
+		return &models.UserPreferences{}
+	}
+	prefs, err := s.MongoDB.GetUserPreferences(r.Context(), viewerID)
+	if err != nil {
+		log.Printf("Failed to get preferences of user %s: %v", viewerID, err)
+		return &models.UserPreferences{}
+	}
+	return prefs
+}
+
+// HandleGetFeed handles requests to get a user's feed
+func (s *Server) HandleGetFeed() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := actingUserID(w, r, r.URL.Query().Get("userId"))
+		if !ok {
+			return
+		}
+
+		// Get limit from query params, default to 50
+		limit := 50
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			fmt.Sscanf(limitStr, "%d", &limit)
+		}
+
+		// The user's preferences fill in the sort when none is asked for
+		prefs := s.viewerPreferences(r, userID)
+		sort := r.URL.Query().Get("sort")
+		switch sort {
+		case "":
+			sort = prefs.FeedSort
+		case database.SortHot, database.SortNew, database.SortTop, database.SortControversial:
+		default:
+			http.Error(w, "sort must be one of hot, new, top, controversial", http.StatusBadRequest)
+			return
+		}
+
+		// Send to Engine
+		future := s.Context.RequestFuture(s.EnginePID, &actors.GetUserFeedMsg{
+			UserID:   userID,
+			Sort:     sort,
+			HideNSFW: !prefs.ShowNSFW,
+			Limit:    limit,
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to get feed", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleGetFollowingFeed returns recent posts by the users the caller follows, newest first
+func (s *Server) HandleGetFollowingFeed() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := actingUserID(w, r, r.URL.Query().Get("userId"))
+		if !ok {
+			return
+		}
+
+		limit := 0
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			fmt.Sscanf(limitStr, "%d", &limit)
+		}
+
+		future := s.Context.RequestFuture(s.EnginePID, &actors.GetFollowingFeedMsg{
+			UserID: userID,
+			Limit:  limit,
+			Cursor: r.URL.Query().Get("cursor"),
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to get feed", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// FollowRequest represents a request to follow or unfollow a user
+type FollowRequest struct {
+	UserID     string `json:"userId"` // Optional; must be the authenticated user
+	FollowedID string `json:"followedId"`
+}
+
+// HandleFollowUser makes the caller follow another user
+func (s *Server) HandleFollowUser() http.HandlerFunc {
+	return s.handleFollowChange(true)
+}
+
+// HandleUnfollowUser stops the caller following another user
+func (s *Server) HandleUnfollowUser() http.HandlerFunc {
+	return s.handleFollowChange(false)
+}
+
+// handleFollowChange serves both follow and unfollow, which take the same request
+func (s *Server) handleFollowChange(follow bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req FollowRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+
+		userID, ok := actingUserID(w, r, req.UserID)
+		if !ok {
+			return
+		}
+
+		followedID, err := uuid.Parse(req.FollowedID)
+		if err != nil {
+			http.Error(w, "Invalid followed user ID format", http.StatusBadRequest)
+			return
+		}
+
+		var msg interface{} = &actors.UnfollowUserMsg{FollowerID: userID, FollowedID: followedID}
+		if follow {
+			msg = &actors.FollowUserMsg{FollowerID: userID, FollowedID: followedID}
+		}
+
+		future := s.Context.RequestFuture(s.Engine.GetUserSupervisor(), msg, s.RequestTimeout)
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to update follows", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			case utils.ErrNotFound:
+				statusCode = http.StatusNotFound
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleGetFollowing lists the users a user follows, most recently followed first
+func (s *Server) HandleGetFollowing() http.HandlerFunc {
+	return s.handleFollowList(false)
+}
+
+// HandleGetFollowers lists a user's followers, most recent first
+func (s *Server) HandleGetFollowers() http.HandlerFunc {
+	return s.handleFollowList(true)
+}
+
+// handleFollowList serves both follow lists. They're public; userId defaults to the caller.
+func (s *Server) handleFollowList(followers bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		query := r.URL.Query()
+		userID, ok := middleware.GetUserIDFromContext(r.Context())
+		if userIDStr := query.Get("userId"); userIDStr != "" {
+			parsed, err := uuid.Parse(userIDStr)
+			if err != nil {
+				http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+				return
+			}
+			userID, ok = parsed, true
+		}
+		if !ok {
+			http.Error(w, "User ID required", http.StatusBadRequest)
+			return
+		}
+
+		limit := 0
+		if limitStr := query.Get("limit"); limitStr != "" {
+			fmt.Sscanf(limitStr, "%d", &limit)
+		}
+
+		var msg interface{} = &actors.GetFollowingMsg{UserID: userID, Limit: limit, Cursor: query.Get("cursor")}
+		if followers {
+			msg = &actors.GetFollowersMsg{UserID: userID, Limit: limit, Cursor: query.Get("cursor")}
+		}
+
+		future := s.Context.RequestFuture(s.Engine.GetUserSupervisor(), msg, s.RequestTimeout)
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to get follows", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// BlockRequest represents a request to block or unblock a user
+type BlockRequest struct {
+	UserID    string `json:"userId"` // Optional; must be the authenticated user
+	BlockedID string `json:"blockedId"`
+}
+
+// HandleBlockUser hides another user's content from the caller
+func (s *Server) HandleBlockUser() http.HandlerFunc {
+	return s.handleBlockChange(true)
+}
+
+// HandleUnblockUser lifts a block the caller placed
+func (s *Server) HandleUnblockUser() http.HandlerFunc {
+	return s.handleBlockChange(false)
+}
+
+// handleBlockChange serves both block and unblock, which take the same request
+func (s *Server) handleBlockChange(block bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req BlockRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+
+		userID, ok := actingUserID(w, r, req.UserID)
+		if !ok {
+			return
+		}
+
+		blockedID, err := uuid.Parse(req.BlockedID)
+		if err != nil {
+			http.Error(w, "Invalid blocked user ID format", http.StatusBadRequest)
+			return
+		}
+
+		var msg interface{} = &actors.UnblockUserMsg{BlockerID: userID, BlockedID: blockedID}
+		if block {
+			msg = &actors.BlockUserMsg{BlockerID: userID, BlockedID: blockedID}
+		}
+
+		future := s.Context.RequestFuture(s.Engine.GetUserSupervisor(), msg, s.RequestTimeout)
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to update blocks", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			case utils.ErrNotFound:
+				statusCode = http.StatusNotFound
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleGetBlockedUsers lists the users the caller has blocked, most recent first
+func (s *Server) HandleGetBlockedUsers() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := actingUserID(w, r, r.URL.Query().Get("userId"))
+		if !ok {
+			return
+		}
+
+		limit := 0
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			fmt.Sscanf(limitStr, "%d", &limit)
+		}
+
+		future := s.Context.RequestFuture(s.Engine.GetUserSupervisor(), &actors.GetBlockedUsersMsg{
+			UserID: userID,
+			Limit:  limit,
+			Cursor: r.URL.Query().Get("cursor"),
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to get blocked users", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleGetUserActivity returns a user's posts and comments as one newest-first timeline
+func (s *Server) HandleGetUserActivity() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, err := uuid.Parse(r.URL.Query().Get("userId"))
+		if err != nil {
+			http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+			return
+		}
+
+		limit := 0
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			fmt.Sscanf(limitStr, "%d", &limit)
+		}
+
+		viewerID, _ := middleware.GetUserIDFromContext(r.Context())
+		future := s.Context.RequestFuture(s.EnginePID, &actors.GetUserActivityMsg{
+			UserID:   userID,
+			ViewerID: viewerID,
+			Limit:    limit,
+			Cursor:   r.URL.Query().Get("after"),
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to get user activity", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			case utils.ErrNotFound:
+				statusCode = http.StatusNotFound
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleGetUserComments serves GET /user/comments?userId=&limit=&after=&includeDeleted=
+// with a user's comments, newest first
+func (s *Server) HandleGetUserComments() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		query := r.URL.Query()
+		userID, err := uuid.Parse(query.Get("userId"))
+		if err != nil {
+			http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+			return
+		}
+
+		limit := 0
+		if limitStr := query.Get("limit"); limitStr != "" {
+			fmt.Sscanf(limitStr, "%d", &limit)
+		}
+
+		cursor := query.Get("after")
+		if cursor == "" {
+			cursor = query.Get("cursor")
+		}
+
+		viewerID, _ := middleware.GetUserIDFromContext(r.Context())
+		future := s.Context.RequestFuture(s.CommentActor, &actors.GetUserCommentsMsg{
+			UserID:         userID,
+			Limit:          limit,
+			Cursor:         cursor,
+			IncludeDeleted: query.Get("includeDeleted") == "true",
+			ViewerID:       viewerID,
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to get user comments", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleGetUserPosts serves GET /user/posts?userId=&limit=&cursor= with a user's posts,
+// newest first, and the same list as /user/overview-posts for profile pages. Users see
+// their own deleted posts too.
+func (s *Server) HandleGetUserPosts() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		query := r.URL.Query()
+		userID, err := uuid.Parse(query.Get("userId"))
+		if err != nil {
+			http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+			return
+		}
+
+		limit := 0
+		if limitStr := query.Get("limit"); limitStr != "" {
+			fmt.Sscanf(limitStr, "%d", &limit)
+		}
+
+		// Lists elsewhere page with "cursor"; "after" is kept for existing clients
+		cursor := query.Get("cursor")
+		if cursor == "" {
+			cursor = query.Get("after")
+		}
+
+		requesterID, _ := middleware.GetUserIDFromContext(r.Context())
+		future := s.Context.RequestFuture(s.EnginePID, &actors.GetUserPostsMsg{
+			UserID:      userID,
+			RequesterID: requesterID,
+			Limit:       limit,
+			Cursor:      cursor,
+		}, s.RequestTimeout)
+
+		result, err := future.Result()
+		if err != nil {
+			http.Error(w, "Failed to get user posts", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrInvalidInput:
+				statusCode = http.StatusBadRequest
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleScheduledPosts lists the caller's posts that are waiting to be published
+// (GET /user/scheduled) and cancels one of them (DELETE /user/scheduled?id=<post_id>)
+func (s *Server) HandleScheduledPosts() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.GetUserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var msg interface{}
+		switch r.Method {
+		case http.MethodGet:
+			msg = &actors.GetScheduledPostsMsg{AuthorID: userID}
+		case http.MethodDelete:
+			postID, err := uuid.Parse(r.URL.Query().Get("id"))
+			if err != nil {
+				http.Error(w, "Invalid post ID format", http.StatusBadRequest)
+				return
+			}
+			msg = &actors.CancelScheduledPostMsg{PostID: postID, AuthorID: userID}
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		result, err := s.Context.RequestFuture(s.EnginePID, msg, s.RequestTimeout).Result()
+		if err != nil {
+			http.Error(w, "Failed to process scheduled posts request", http.StatusInternalServerError)
+			return
+		}
+
+		if appErr, ok := result.(*utils.AppError); ok {
+			var statusCode int
+			switch appErr.Code {
+			case utils.ErrNotFound:
+				statusCode = http.StatusNotFound
+			default:
+				statusCode = http.StatusInternalServerError
+			}
+			writeAppError(w, r, appErr, statusCode)
 			return
 		}
 
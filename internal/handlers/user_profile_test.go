@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"gator-swamp/internal/config"
+	"gator-swamp/internal/engine/actors"
+	"gator-swamp/internal/middleware"
+	"gator-swamp/internal/types"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const profileEmail = "private.gator@example.com"
+
+func profileUser() *actors.UserState {
+	return &actors.UserState{
+		ID:             uuid.New(),
+		Username:       "gator",
+		Email:          profileEmail,
+		EmailVerified:  true,
+		Karma:          12,
+		CreatedAt:      time.Now().Add(-time.Hour),
+		LastActive:     time.Now(),
+		IsConnected:    true,
+		IsAdmin:        true,
+		Subreddits:     []uuid.UUID{uuid.New()},
+		SubredditNames: []string{"swamp"},
+		DisplayName:    "Gator",
+	}
+}
+
+// profileJSON renders the profile of user as the viewer would get it; uuid.Nil is an
+// anonymous viewer
+func profileJSON(t *testing.T, user *actors.UserState, viewerID uuid.UUID) string {
+	t.Helper()
+	s := &Server{Config: &config.Config{ActiveRecentlyWindow: 15 * time.Minute}}
+
+	r := httptest.NewRequest("GET", "/user/profile?userId="+user.ID.String(), nil)
+	if viewerID != uuid.Nil {
+		r = r.WithContext(middleware.SetUserIDInContext(r.Context(), viewerID))
+	}
+	body, err := json.Marshal(s.profileFor(r, user))
+	if err != nil {
+		t.Fatalf("encoding profile: %v", err)
+	}
+	return string(body)
+}
+
+func TestPublicProfileNeverContainsEmail(t *testing.T) {
+	user := profileUser()
+
+	viewers := map[string]uuid.UUID{
+		"anonymous":    uuid.Nil,
+		"another user": uuid.New(),
+	}
+	for name, viewerID := range viewers {
+		t.Run(name, func(t *testing.T) {
+			body := profileJSON(t, user, viewerID)
+			if strings.Contains(body, profileEmail) {
+				t.Errorf("public profile contains the email: %s", body)
+			}
+
+			var fields map[string]interface{}
+			if err := json.Unmarshal([]byte(body), &fields); err != nil {
+				t.Fatalf("decoding profile: %v", err)
+			}
+			for _, private := range []string{"email", "emailVerified", "isAdmin", "lastActive", "isConnected", "subredditID"} {
+				if _, ok := fields[private]; ok {
+					t.Errorf("public profile has private field %q: %s", private, body)
+				}
+			}
+			if fields["username"] != "gator" {
+				t.Errorf("public profile username = %v, want gator", fields["username"])
+			}
+		})
+	}
+}
+
+func TestOwnProfileContainsEmail(t *testing.T) {
+	user := profileUser()
+	body := profileJSON(t, user, user.ID)
+
+	var profile types.PrivateUserProfile
+	if err := json.Unmarshal([]byte(body), &profile); err != nil {
+		t.Fatalf("decoding profile: %v", err)
+	}
+	if profile.Email != profileEmail || !profile.EmailVerified {
+		t.Errorf("own profile email = %q verified %v, want %q verified", profile.Email, profile.EmailVerified, profileEmail)
+	}
+}
+
+// Fields added to the public profile later must not carry the email either
+func TestPublicUserProfileHasNoEmailField(t *testing.T) {
+	profileType := reflect.TypeOf(types.PublicUserProfile{})
+	for i := 0; i < profileType.NumField(); i++ {
+		field := profileType.Field(i)
+		name := strings.ToLower(field.Name + " " + field.Tag.Get("json"))
+		if strings.Contains(name, "email") {
+			t.Errorf("PublicUserProfile has field %s, which looks like it carries the email", field.Name)
+		}
+	}
+}
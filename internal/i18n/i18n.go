@@ -0,0 +1,124 @@
+// Package i18n renders user-facing messages from message keys in the caller's language.
+// Messages are templates with {param} placeholders; any key missing from a locale
+// falls back to English and is counted so coverage gaps are visible.
+package i18n
+
+import (
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultLanguage is used when the requested language or key isn't available
+const DefaultLanguage = "en"
+
+// catalogs maps language -> message key -> template
+var catalogs = map[string]map[string]string{
+	"en": english,
+	"es": spanish,
+}
+
+var (
+	missingMu sync.Mutex
+	missing   = make(map[string]uint64) // "lang:key" -> number of fallbacks
+)
+
+// T renders the message for key in lang, substituting params. Unknown languages
+// and missing keys fall back to English; a key unknown even in English renders as itself.
+func T(lang, key string, params map[string]string) string {
+	template, ok := catalogs[lang][key]
+	if !ok {
+		if lang != DefaultLanguage {
+			recordMissing(lang, key)
+		}
+		template, ok = english[key]
+		if !ok {
+			recordMissing(DefaultLanguage, key)
+			template = key
+		}
+	}
+
+	if len(params) == 0 {
+		return template
+	}
+	pairs := make([]string, 0, len(params)*2)
+	for name, value := range params {
+		pairs = append(pairs, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(template)
+}
+
+// Supported reports whether lang has a catalog
+func Supported(lang string) bool {
+	_, ok := catalogs[lang]
+	return ok
+}
+
+// MatchLanguage picks the best supported language from an Accept-Language header,
+// honouring q-values and ignoring region subtags. It returns DefaultLanguage when
+// nothing matches.
+func MatchLanguage(acceptLanguage string) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+
+	candidates := make([]candidate, 0)
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, field := range fields[1:] {
+			field = strings.TrimSpace(field)
+			if strings.HasPrefix(field, "q=") {
+				parsed, err := strconv.ParseFloat(field[2:], 64)
+				if err != nil {
+					parsed = 0
+				}
+				q = parsed
+			}
+		}
+
+		if base, _, found := strings.Cut(tag, "-"); found {
+			tag = base
+		}
+		if q > 0 && Supported(tag) {
+			candidates = append(candidates, candidate{lang: tag, q: q})
+		}
+	}
+
+	if len(candidates) == 0 {
+		return DefaultLanguage
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].lang
+}
+
+// MissingTranslations returns how often each "lang:key" pair fell back to English
+func MissingTranslations() map[string]uint64 {
+	missingMu.Lock()
+	defer missingMu.Unlock()
+
+	snapshot := make(map[string]uint64, len(missing))
+	for k, v := range missing {
+		snapshot[k] = v
+	}
+	return snapshot
+}
+
+func recordMissing(lang, key string) {
+	missingMu.Lock()
+	defer missingMu.Unlock()
+
+	id := lang + ":" + key
+	if missing[id] == 0 {
+		log.Printf("i18n: Missing translation for %s", id)
+	}
+	missing[id]++
+}
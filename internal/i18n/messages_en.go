@@ -0,0 +1,38 @@
+package i18n
+
+// Message keys shared by actors, handlers and notifications
+const (
+	ErrPostNotFound          = "error.post_not_found"
+	ErrCommentNotFound       = "error.comment_not_found"
+	ErrParentCommentNotFound = "error.parent_comment_not_found"
+	ErrSubredditNotFound     = "error.subreddit_not_found"
+	ErrUserNotFound          = "error.user_not_found"
+	ErrMultiredditNotFound   = "error.multireddit_not_found"
+	ErrShareLinkNotFound     = "error.share_link_not_found"
+	ErrDigestNotFound        = "error.digest_not_found"
+	ErrPostDeleted           = "error.post_deleted"
+	ErrDuplicateLink         = "error.duplicate_link"
+	ErrPostArchived          = "error.post_archived"
+	ErrPostRateLimited       = "error.post_rate_limited"
+	ErrSubredditRateLimited  = "error.subreddit_rate_limited"
+
+	NotificationCommentReply = "notification.comment_reply"
+)
+
+var english = map[string]string{
+	ErrPostNotFound:          "Post not found",
+	ErrCommentNotFound:       "Comment not found",
+	ErrParentCommentNotFound: "Parent comment not found",
+	ErrSubredditNotFound:     "Subreddit not found",
+	ErrUserNotFound:          "User not found",
+	ErrMultiredditNotFound:   "Multireddit not found",
+	ErrShareLinkNotFound:     "Share link not found",
+	ErrDigestNotFound:        "No digest for {date}",
+	ErrPostDeleted:           "This post has been deleted and is no longer available",
+	ErrDuplicateLink:         "This link was already posted in this subreddit: {postId}",
+	ErrPostArchived:          "This post is archived and can no longer be voted or commented on",
+	ErrPostRateLimited:       "You're posting too often. Try again in {retryAfter} seconds",
+	ErrSubredditRateLimited:  "You can create {limit} subreddits a day. Try again in {retryAfter} seconds",
+
+	NotificationCommentReply: "Someone replied to your comment",
+}
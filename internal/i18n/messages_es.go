@@ -0,0 +1,19 @@
+package i18n
+
+var spanish = map[string]string{
+	ErrPostNotFound:          "Publicación no encontrada",
+	ErrCommentNotFound:       "Comentario no encontrado",
+	ErrParentCommentNotFound: "Comentario principal no encontrado",
+	ErrSubredditNotFound:     "Subreddit no encontrado",
+	ErrUserNotFound:          "Usuario no encontrado",
+	ErrMultiredditNotFound:   "Multireddit no encontrado",
+	ErrShareLinkNotFound:     "Enlace compartido no encontrado",
+	ErrDigestNotFound:        "No hay resumen para {date}",
+	ErrPostDeleted:           "Esta publicación fue eliminada y ya no está disponible",
+	ErrDuplicateLink:         "Este enlace ya se publicó en este subreddit: {postId}",
+	ErrPostArchived:          "Esta publicación está archivada y ya no admite votos ni comentarios",
+	ErrPostRateLimited:       "Estás publicando demasiado seguido. Vuelve a intentarlo en {retryAfter} segundos",
+	ErrSubredditRateLimited:  "Puedes crear {limit} subreddits al día. Vuelve a intentarlo en {retryAfter} segundos",
+
+	NotificationCommentReply: "Alguien respondió a tu comentario",
+}
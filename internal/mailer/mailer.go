@@ -0,0 +1,29 @@
+// Package mailer sends email to users. Handlers send through the Mailer interface so the
+// logging backend used in development can be swapped for a real mail service.
+package mailer
+
+import (
+	"context"
+	"log"
+)
+
+// Mailer delivers plain-text email
+type Mailer interface {
+	// Send delivers a message to a single recipient
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// LogMailer writes messages to the server log instead of delivering them. It keeps
+// flows that depend on email usable in development.
+type LogMailer struct{}
+
+// NewLogMailer returns a Mailer that logs every message
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+// Send logs the message
+func (m *LogMailer) Send(ctx context.Context, to, subject, body string) error {
+	log.Printf("Mail to %s: %s\n%s", to, subject, body)
+	return nil
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ActivityRecorder stores the time a user was last active
+type ActivityRecorder func(ctx context.Context, userID uuid.UUID, at time.Time) error
+
+// activityWriteTimeout bounds each background write so a slow database can't pile them up
+const activityWriteTimeout = 5 * time.Second
+
+// Authenticated requests record their user's activity at most once per interval. The
+// times of recent writes live in memory, so only the first request from each user in
+// an interval reaches the database.
+var (
+	activityMu        sync.Mutex
+	activityRecorder  ActivityRecorder
+	activityInterval  time.Duration
+	activityWrittenAt = make(map[uuid.UUID]time.Time)
+	activityPrunedAt  time.Time
+)
+
+// TrackActivity makes the JWT middleware record when authenticated users were last
+// active, writing each user's time at most once per interval
+func TrackActivity(interval time.Duration, record ActivityRecorder) {
+	activityMu.Lock()
+	defer activityMu.Unlock()
+	activityRecorder = record
+	activityInterval = interval
+}
+
+// touchActivity records a user's activity in the background unless it was recorded
+// within the interval. It never blocks the request on the database.
+func touchActivity(userID uuid.UUID) {
+	now := time.Now()
+
+	activityMu.Lock()
+	record := activityRecorder
+	if record == nil || now.Sub(activityWrittenAt[userID]) < activityInterval {
+		activityMu.Unlock()
+		return
+	}
+	activityWrittenAt[userID] = now
+	pruneActivity(now)
+	activityMu.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), activityWriteTimeout)
+		defer cancel()
+		if err := record(ctx, userID, now); err != nil {
+			log.Printf("Warning: Failed to record activity for user %s: %v", userID, err)
+		}
+	}()
+}
+
+// pruneActivity forgets writes older than the interval, at most once per interval. The
+// caller holds activityMu.
+func pruneActivity(now time.Time) {
+	if now.Sub(activityPrunedAt) < activityInterval {
+		return
+	}
+	activityPrunedAt = now
+	for userID, writtenAt := range activityWrittenAt {
+		if now.Sub(writtenAt) >= activityInterval {
+			delete(activityWrittenAt, userID)
+		}
+	}
+}
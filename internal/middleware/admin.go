@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// AdminChecker reports whether a user is a site administrator
+type AdminChecker func(ctx context.Context, userID uuid.UUID) (bool, error)
+
+var (
+	adminMu      sync.RWMutex
+	adminChecker AdminChecker
+)
+
+// AuthorizeAdmins sets how ApplyAdminMiddleware decides who is an administrator
+func AuthorizeAdmins(check AdminChecker) {
+	adminMu.Lock()
+	defer adminMu.Unlock()
+	adminChecker = check
+}
+
+// IsAdmin reports whether a user is a site administrator. Until AuthorizeAdmins is
+// called nobody is.
+func IsAdmin(ctx context.Context, userID uuid.UUID) (bool, error) {
+	adminMu.RLock()
+	check := adminChecker
+	adminMu.RUnlock()
+
+	if check == nil {
+		return false, nil
+	}
+	return check(ctx, userID)
+}
+
+// ApplyAdminMiddleware wraps a handler that only administrators may call. Requests are
+// authenticated as by ApplyJWTMiddleware, and authenticated users who aren't
+// administrators get 403.
+func ApplyAdminMiddleware(handler http.HandlerFunc, path string) http.HandlerFunc {
+	return ApplyJWTMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := GetUserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		isAdmin, err := IsAdmin(r.Context(), userID)
+		if err != nil {
+			log.Printf("Failed to check admin status of user %s: %v", userID, err)
+			http.Error(w, "Failed to check admin status", http.StatusInternalServerError)
+			return
+		}
+		if !isAdmin {
+			http.Error(w, "Admin access required", http.StatusForbidden)
+			return
+		}
+
+		handler(w, r)
+	}, path)
+}
@@ -24,7 +24,7 @@ func DefaultCORSConfig(allowedOrigins []string) *CORSConfig {
 	return &CORSConfig{
 		AllowedOrigins:   allowedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowedHeaders:   []string{"Content-Type", "Authorization", "Accept", "Origin", "X-Requested-With"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "Accept", "Origin", "X-Requested-With", "Idempotency-Key"},
 		ExposedHeaders:   []string{"Content-Length", "Content-Type"},
 		MaxAge:           86400, // 24 hours
 		AllowCredentials: true,
@@ -4,92 +4,25 @@ package middleware
 import (
 	"context"
 	"errors"
-	"fmt"
+	"gator-swamp/internal/auth"
 	"log"
 	"net/http"
 	"strings"
-	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
-const (
-	// JWT secret key for signing tokens
-	// In production, this should be loaded from environment variables or a secure vault
-	jwtSecret = "gatorswamp_secret_key_should_be_loaded_from_env"
-
-	// Token expiration time - 24 hours
-	tokenExpiration = 24 * time.Hour
-)
-
-// Claims represents the JWT claims for our application
-type Claims struct {
-	UserID uuid.UUID `json:"user_id"`
-	jwt.RegisteredClaims
-}
-
 // UnprotectedRoutes defines routes that don't require JWT authentication
 var UnprotectedRoutes = map[string]bool{
-	"/health":        true,
-	"/user/register": true,
-	"/user/login":    true,
-}
-
-// GenerateToken creates a new JWT token for the given user ID
-func GenerateToken(userID uuid.UUID) (string, error) {
-	// Create token expiration time
-	expirationTime := time.Now().Add(tokenExpiration)
-
-	// Create claims with user ID and standard claims
-	claims := &Claims{
-		UserID: userID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "gator-swamp-api",
-			Subject:   userID.String(),
-		},
-	}
-
-	// Create token with claims and signing method
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	// Sign token with secret key
-	tokenString, err := token.SignedString([]byte(jwtSecret))
-	if err != nil {
-		return "", err
-	}
-
-	return tokenString, nil
-}
-
-// ValidateToken validates the provided JWT token
-func ValidateToken(tokenString string) (*Claims, error) {
-	// Parse token with claims
-	token, err := jwt.ParseWithClaims(
-		tokenString,
-		&Claims{},
-		func(token *jwt.Token) (interface{}, error) {
-			// Verify signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(jwtSecret), nil
-		},
-	)
-
-	if err != nil {
-		return nil, err
-	}
-
-	// Validate token and extract claims
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
-	}
-
-	return nil, errors.New("invalid token")
+	"/health":                      true,
+	"/user/register":               true,
+	"/user/login":                  true,
+	"/user/refresh":                true,
+	"/user/logout":                 true,
+	"/user/logout-all":             true,
+	"/user/password-reset/request": true,
+	"/user/password-reset/confirm": true,
+	"/user/verify-email":           true,
 }
 
 // AuthMiddleware is a middleware function to validate JWT tokens
@@ -118,21 +51,24 @@ func AuthMiddleware(next http.Handler) http.Handler {
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
 		// Validate token
-		claims, err := ValidateToken(tokenString)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Invalid token: %v", err), http.StatusUnauthorized)
+		claims, err := auth.ValidateToken(tokenString)
+		if errors.Is(err, auth.ErrTokenExpired) {
+			http.Error(w, "Token expired", http.StatusUnauthorized)
 			return
 		}
-
-		// Check if token is expired
-		if time.Now().After(claims.ExpiresAt.Time) {
-			http.Error(w, "Token expired", http.StatusUnauthorized)
+		if errors.Is(err, auth.ErrTokenRevoked) {
+			http.Error(w, "Token revoked", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Invalid token", http.StatusUnauthorized)
 			return
 		}
 
 		// Set user ID in request context
 		ctx := r.Context()
 		ctx = SetUserIDInContext(ctx, claims.UserID)
+		touchActivity(claims.UserID)
 
 		// Continue with request
 		next.ServeHTTP(w, r.WithContext(ctx))
@@ -165,28 +101,45 @@ func ApplyJWTMiddleware(handler http.HandlerFunc, path string) http.HandlerFunc
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
 		// Validate token
-		claims, err := ValidateToken(tokenString)
+		claims, err := auth.ValidateToken(tokenString)
+		if errors.Is(err, auth.ErrTokenExpired) {
+			http.Error(w, "Token expired", http.StatusUnauthorized)
+			return
+		}
+		if errors.Is(err, auth.ErrTokenRevoked) {
+			http.Error(w, "Token revoked", http.StatusUnauthorized)
+			return
+		}
 		if err != nil {
 			log.Printf("JWT Error: %v", err)
 			http.Error(w, "Invalid token", http.StatusUnauthorized)
 			return
 		}
 
-		// Check if token is expired
-		if time.Now().After(claims.ExpiresAt.Time) {
-			http.Error(w, "Token expired", http.StatusUnauthorized)
-			return
-		}
-
 		// Set user ID in request context
 		ctx := r.Context()
 		ctx = SetUserIDInContext(ctx, claims.UserID)
+		touchActivity(claims.UserID)
 
 		// Continue with handler
 		handler(w, r.WithContext(ctx))
 	}
 }
 
+// ApplyOptionalJWTMiddleware wraps a handler whose reads are public. GET requests without
+// an Authorization header reach the handler anonymously; any other request, or a GET that
+// does send a token, is authenticated as by ApplyJWTMiddleware.
+func ApplyOptionalJWTMiddleware(handler http.HandlerFunc, path string) http.HandlerFunc {
+	protected := ApplyJWTMiddleware(handler, path)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if (r.Method == http.MethodGet || r.Method == http.MethodHead) && r.Header.Get("Authorization") == "" {
+			handler(w, r)
+			return
+		}
+		protected(w, r)
+	}
+}
+
 // Define a custom context key type to avoid collisions
 type contextKey string
 
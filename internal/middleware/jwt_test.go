@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"gator-swamp/internal/auth"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// serveWithToken calls a protected handler with the given access token and returns the
+// response, along with the user the handler saw
+func serveWithToken(t *testing.T, token string) (*httptest.ResponseRecorder, uuid.UUID) {
+	t.Helper()
+	var seen uuid.UUID
+	handler := ApplyJWTMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = GetUserIDFromContext(r.Context())
+	}, "/user/profile")
+
+	r := httptest.NewRequest(http.MethodGet, "/user/profile", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w, seen
+}
+
+func generateToken(t *testing.T, userID uuid.UUID) string {
+	t.Helper()
+	token, err := auth.GenerateToken(userID, "gator")
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	return token
+}
+
+// A logout revokes its access token by ID; the user's other tokens keep working
+func TestRevokedTokenIsRejected(t *testing.T) {
+	auth.Configure("test-signing-secret", time.Hour)
+	userID := uuid.New()
+	loggedOut, other := generateToken(t, userID), generateToken(t, userID)
+
+	if w, seen := serveWithToken(t, loggedOut); w.Code != http.StatusOK || seen != userID {
+		t.Fatalf("token before logout = %d for %s, want 200 for %s", w.Code, seen, userID)
+	}
+	claims, err := auth.ValidateToken(loggedOut)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	auth.RevokeToken(claims)
+
+	w, _ := serveWithToken(t, loggedOut)
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "Token revoked") {
+		t.Fatalf("revoked token = %d %q, want 401 Token revoked", w.Code, w.Body)
+	}
+	if w, _ := serveWithToken(t, other); w.Code != http.StatusOK {
+		t.Fatalf("the user's other token = %d, want 200", w.Code)
+	}
+}
+
+// Logging out everywhere revokes every token issued to the user until then
+func TestRevokedUserTokensAreRejected(t *testing.T) {
+	auth.Configure("test-signing-secret", time.Hour)
+	userID, otherUserID := uuid.New(), uuid.New()
+	token, otherUser := generateToken(t, userID), generateToken(t, otherUserID)
+
+	// Issue times are kept to the second, so the revocation is dated after this one
+	auth.RevokeUserTokens(userID, time.Now().Add(time.Second))
+
+	if w, _ := serveWithToken(t, token); w.Code != http.StatusUnauthorized {
+		t.Fatalf("token issued before logging out everywhere = %d, want 401", w.Code)
+	}
+	if w, _ := serveWithToken(t, otherUser); w.Code != http.StatusOK {
+		t.Fatalf("another user's token = %d, want 200", w.Code)
+	}
+}
+
+func TestUnauthenticatedRequestIsRejected(t *testing.T) {
+	auth.Configure("test-signing-secret", time.Hour)
+	for name, header := range map[string]string{
+		"no header":     "",
+		"not bearer":    "Basic Z2F0b3I6c3dhbXA=",
+		"invalid token": "Bearer not-a-token",
+	} {
+		t.Run(name, func(t *testing.T) {
+			handler := ApplyJWTMiddleware(func(w http.ResponseWriter, r *http.Request) {
+				t.Errorf("handler called")
+			}, "/user/profile")
+			r := httptest.NewRequest(http.MethodGet, "/user/profile", nil)
+			if header != "" {
+				r.Header.Set("Authorization", header)
+			}
+			w := httptest.NewRecorder()
+			handler(w, r)
+			if w.Code != http.StatusUnauthorized {
+				t.Fatalf("request = %d, want 401", w.Code)
+			}
+		})
+	}
+}
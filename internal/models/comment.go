@@ -7,17 +7,48 @@ import (
 )
 
 type Comment struct {
-	ID          uuid.UUID   `json:"id"`
-	Content     string      `json:"content"`
-	AuthorID    uuid.UUID   `json:"authorId"`
-	PostID      uuid.UUID   `json:"postId"`
-	SubredditID uuid.UUID   `json:"subredditId"`
-	ParentID    *uuid.UUID  `json:"parentId,omitempty"`
-	Children    []uuid.UUID `json:"children"`
-	CreatedAt   time.Time   `json:"createdAt"`
-	UpdatedAt   time.Time   `json:"updatedAt"`
-	IsDeleted   bool        `json:"isDeleted"`
-	Upvotes     int         `json:"upvotes"`
-	Downvotes   int         `json:"downvotes"`
-	Karma       int         `json:"karma"`
+	ID              uuid.UUID   `json:"id"`
+	Content         string      `json:"content"`
+	AuthorID        uuid.UUID   `json:"authorId"`
+	PostID          uuid.UUID   `json:"postId"`
+	SubredditID     uuid.UUID   `json:"subredditId"`
+	ParentID        *uuid.UUID  `json:"parentId,omitempty"`
+	Depth           int         `json:"depth"` // 0 for top-level comments, parent's depth + 1 for replies
+	Path            string      `json:"-"`     // IDs from the thread's top-level comment down to this one, joined by "/"
+	Children        []uuid.UUID `json:"children"`
+	CreatedAt       time.Time   `json:"createdAt"`
+	UpdatedAt       time.Time   `json:"updatedAt"`
+	IsDeleted       bool        `json:"isDeleted"`
+	IsEdited        bool        `json:"isEdited"`
+	IsStickied      bool        `json:"isStickied"`                // Pinned above the post's other comments
+	DistinguishedAs string      `json:"distinguishedAs,omitempty"` // One of the CommentDistinguished* values
+	DeletedAt       *time.Time  `json:"deletedAt,omitempty"`
+	DeletedBy       *uuid.UUID  `json:"-"` // Author for self-deletions, uuid.Nil for automod, otherwise the moderator who removed it
+	Upvotes         int         `json:"upvotes"`
+	Downvotes       int         `json:"downvotes"`
+	Karma           int         `json:"karma"`
+
+	AuthorFlair *UserFlair `json:"authorFlair,omitempty"` // The author's flair in the subreddit; filled in when the comment is served
+
+	Automod *AutomodRule `json:"-"` // The automod rule the comment matched when it was created, as it was then
+}
+
+// Ways a comment can be distinguished
+const (
+	CommentDistinguishedModerator = "moderator"
+	CommentDistinguishedOP        = "op"
+)
+
+// CommentNode is a comment with its replies nested beneath it
+type CommentNode struct {
+	Comment
+	Replies   []*CommentNode `json:"replies"`
+	More      []uuid.UUID    `json:"more,omitempty"`      // Replies cut from the response; each ID loads that branch
+	Collapsed bool           `json:"collapsed,omitempty"` // Scored too low to show by default
+}
+
+// CommentEdit is a previous version of an edited comment
+type CommentEdit struct {
+	Content  string    `json:"content" bson:"content"`
+	EditedAt time.Time `json:"editedAt" bson:"editedAt"` // When this version was replaced
 }
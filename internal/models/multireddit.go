@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Multireddit is a user-defined collection of subreddits shown as a single feed
+type Multireddit struct {
+	ID           uuid.UUID   `json:"id"`
+	OwnerID      uuid.UUID   `json:"ownerId"`
+	Name         string      `json:"name"`
+	SubredditIDs []uuid.UUID `json:"subredditIds"`
+	IsPublic     bool        `json:"isPublic"`
+	CreatedAt    time.Time   `json:"createdAt"`
+	UpdatedAt    time.Time   `json:"updatedAt"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Notification types
+const (
+	NotificationCommentReply = "comment_reply" // Someone replied to the recipient's comment
+)
+
+// Notification tells a user about activity on their content
+type Notification struct {
+	ID          uuid.UUID `json:"id"`
+	RecipientID uuid.UUID `json:"recipientId"`
+	Type        string    `json:"type"`
+	CommentID   uuid.UUID `json:"commentId"` // The comment that triggered the notification, e.g. the reply
+	PostID      uuid.UUID `json:"postId"`
+	CreatedAt   time.Time `json:"createdAt"`
+	Read        bool      `json:"read"`
+	Message     string    `json:"message,omitempty"` // Rendered per request in the reader's language, never stored
+}
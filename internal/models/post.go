@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,13 +10,128 @@ import (
 type Post struct {
 	ID             uuid.UUID
 	Title          string
+	Slug           string   // Readable part of the post's permalink, unique within its subreddit
+	OldSlugs       []string `json:"-"` // Slugs from earlier titles, which still resolve to the post
 	Content        string
+	URL            string     // Target of a link post; empty for text posts
+	NormalizedURL  string     `json:"-"` // URL in the form used to detect reposts of the same link
+	PostType       string     // One of the PostType* values
+	Status         string     // One of the PostStatus* values
+	ScheduledAt    *time.Time // When a scheduled post will be published; nil once it is
+	MediaURLs      []string
+	MediaType      string // Kind of media in MediaURLs, e.g. "image" or "video"; empty without media
 	AuthorID       uuid.UUID
 	AuthorUsername string
 	SubredditID    uuid.UUID
 	SubredditName  string
+	FlairID        *uuid.UUID // One of the subreddit's flair templates; nil without flair
+	FlairText      string     // The template's text, kept if the template is deleted
 	CreatedAt      time.Time
+	EditedAt       *time.Time // Set when the author last changed the title or content
 	Upvotes        int
 	Downvotes      int
-	Karma          int // Add Karma field to track post karma
+	Karma          int        // Add Karma field to track post karma
+	HotScore       float64    `bson:"hot"` // Karma weighted towards newer posts, see utils.HotScore
+	CommentCount   int        // Comments that aren't deleted; computed when the post is served
+	ViewCount      int        // Times the post was opened, including views not yet flushed to the database
+	ShareCount     int        // Times the post was shared, counted like ViewCount
+	IsPinned       bool       // Shown above the subreddit's other posts whatever the sort
+	PinnedAt       *time.Time // When a moderator pinned the post; nil unless pinned
+	IsArchived     bool       // Old enough to be read-only: no votes, comments or edits
+	IsDeleted      bool
+	DeletedAt      *time.Time
+	DeletedBy      *uuid.UUID `json:"-"` // Author for self-deletions, otherwise the moderator who removed it
+
+	FlaggedForReview bool // Link posts that also carry body text, a common spam pattern, and posts automod reported
+
+	Automod *AutomodRule `json:"-"` // The automod rule the post matched when it was created, as it was then
+
+	ControversyScore float64 `bson:"controversy"` // Many votes split closely between up and down, see utils.PostControversyScore
+
+	CrosspostParentID *uuid.UUID       // The post this one was crossposted from
+	CrosspostParent   *CrosspostOrigin // Summary of that post; filled in when the post is served
+
+	Poll *Poll // Set on poll posts
+
+	SubredditIconURL string // The subreddit's icon when the post was written, for feed cards
+
+	AuthorFlair *UserFlair `json:",omitempty"` // The author's flair in the subreddit; filled in when the post is served
+
+	UserVote *VoteDirection `json:",omitempty"` // The viewer's vote; left out for anonymous viewers
+}
+
+// VoteDirection is how a user voted on a post: VoteUp, VoteDown or VoteNone
+type VoteDirection string
+
+// Directions of a vote
+const (
+	VoteUp   VoteDirection = "up"
+	VoteDown VoteDirection = "down"
+	VoteNone VoteDirection = "" // Encoded as null
+)
+
+// MarshalJSON encodes VoteNone as null and other directions as strings
+func (d VoteDirection) MarshalJSON() ([]byte, error) {
+	if d == VoteNone {
+		return []byte("null"), nil
+	}
+	return json.Marshal(string(d))
+}
+
+// ArchivedAt reports whether the post is archived at now: flagged by the archival job,
+// or older than archiveAfter and so due to be flagged on its next pass. A zero
+// archiveAfter disables age-based archival.
+func (p *Post) ArchivedAt(now time.Time, archiveAfter time.Duration) bool {
+	return p.IsArchived || (archiveAfter > 0 && now.Sub(p.CreatedAt) > archiveAfter)
+}
+
+// Kinds of post
+const (
+	PostTypeText      = "text"
+	PostTypeLink      = "link"
+	PostTypeCrosspost = "crosspost"
+	PostTypePoll      = "poll"
+)
+
+// Poll is the question part of a poll post. Votes are tallied in Tally; Counts,
+// TotalVotes and ViewerChoice are filled in for the viewer when the post is served.
+type Poll struct {
+	Options           []string
+	ClosesAt          time.Time
+	ResultsAfterClose bool  // Results stay hidden until the poll closes, even from voters
+	Tally             []int `json:"-"` // Votes per option
+
+	IsClosed     bool
+	TotalVotes   int
+	Counts       []int `json:",omitempty"` // Votes per option; left out while results are hidden from the viewer
+	ViewerChoice *int  `json:",omitempty"` // Index of the option the viewer voted for
+}
+
+// Publication states of a post. Scheduled posts are left out of every listing and feed
+// until they are published, and pending posts until a moderator approves them.
+const (
+	PostStatusPublished = "published"
+	PostStatusScheduled = "scheduled"
+	PostStatusPending   = "pending" // Held for moderator review in a restricted subreddit
+	PostStatusRemoved   = "removed" // Rejected by a moderator from the review queue, or by automod
+)
+
+// CrosspostOrigin summarizes the original of a crosspost so clients can embed it
+type CrosspostOrigin struct {
+	PostID         uuid.UUID
+	Title          string
+	SubredditID    uuid.UUID
+	SubredditName  string
+	AuthorID       uuid.UUID
+	AuthorUsername string
+	Karma          int
+	IsDeleted      bool // The original was deleted after being crossposted; only PostID is set
+}
+
+// HiddenPost is a post a user has hidden from their listings
+type HiddenPost struct {
+	PostID      uuid.UUID `json:"postId"`
+	Title       string    `json:"title"`
+	SubredditID uuid.UUID `json:"subredditId"`
+	HiddenAt    time.Time `json:"hiddenAt"`
 }
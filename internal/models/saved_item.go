@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of content a user can save
+const (
+	SavedItemPost    = "post"
+	SavedItemComment = "comment"
+)
+
+// SavedItem is a post or comment a user saved. Exactly one of Post and Comment is set,
+// unless the content has since been deleted, in which case the item is a tombstone with
+// Deleted set and neither attached.
+type SavedItem struct {
+	ItemType string    `json:"itemType"`
+	ItemID   uuid.UUID `json:"itemId"`
+	SavedAt  time.Time `json:"savedAt"`
+	Deleted  bool      `json:"deleted"`
+	Title    string    `json:"title,omitempty"` // Kept on tombstones of deleted posts
+	Post     *Post     `json:"post,omitempty"`
+	Comment  *Comment  `json:"comment,omitempty"`
+}
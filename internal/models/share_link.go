@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ShareLink struct {
+	Code      string    `json:"code"`
+	PostID    uuid.UUID `json:"postId"`
+	CreatorID uuid.UUID `json:"creatorId"`
+	CreatedAt time.Time `json:"createdAt"`
+	Clicks    int       `json:"clicks"`
+	Channel   string    `json:"channel,omitempty"` // Where the link was shared, e.g. "twitter"; free-form
+}
@@ -14,4 +14,127 @@ type Subreddit struct {
 	Members     int
 	CreatedAt   time.Time
 	Posts       []uuid.UUID
+	Flairs      []PostFlair // Flair templates posts in the subreddit can use
+
+	PollResultsAfterClose bool // Poll results stay hidden until the poll closes, not just until the viewer votes
+	MinKarmaToPost        int  // Karma authors need to post here, other than moderators; 0 lets anyone post
+	NSFW                  bool // Posts are left out of the home feeds of users who haven't turned on showNsfw
+
+	Type          SubredditType
+	ApprovedUsers []uuid.UUID // Users moderators approved to post in a restricted subreddit or join a private one
+
+	Moderators []Moderator // Moderators besides the creator, who always moderates
+
+	IconURL      string
+	BannerURL    string
+	PrimaryColor string // Hex color such as "#ff4500"
+
+	UserFlairs []UserFlairTemplate // Flair templates members can show beside their username
+
+	Automod []AutomodRule `json:"-"` // Only shown to moderators, so authors can't word around them
+}
+
+// SubredditSearchResult is a subreddit as listed in search results
+type SubredditSearchResult struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Members     int       `json:"members"`
+}
+
+// Moderator is a user the creator or another moderator made a moderator of a subreddit
+type Moderator struct {
+	UserID  uuid.UUID `json:"userId"`
+	AddedAt time.Time `json:"addedAt"`
+	AddedBy uuid.UUID `json:"addedBy"`
+}
+
+// SubredditBan keeps a user from posting or commenting in a subreddit
+type SubredditBan struct {
+	SubredditID uuid.UUID  `json:"subredditId"`
+	UserID      uuid.UUID  `json:"userId"`
+	BannedBy    uuid.UUID  `json:"bannedBy"`
+	Reason      string     `json:"reason"`
+	BannedAt    time.Time  `json:"bannedAt"`
+	ExpiresAt   *time.Time `json:"expiresAt"` // Nil for a permanent ban
+}
+
+// SubredditType controls who can read and post in a subreddit
+type SubredditType string
+
+const (
+	SubredditPublic     SubredditType = "public"     // Anyone can read, join and post
+	SubredditRestricted SubredditType = "restricted" // Anyone can read and join; only approved users post
+	SubredditPrivate    SubredditType = "private"    // Only approved users join, and only members read
+)
+
+// Valid reports whether t is one of the subreddit types
+func (t SubredditType) Valid() bool {
+	return t == SubredditPublic || t == SubredditRestricted || t == SubredditPrivate
+}
+
+// IsModerator reports whether the user is the subreddit's creator or one of its moderators
+func (s *Subreddit) IsModerator(userID uuid.UUID) bool {
+	if userID == s.CreatorID {
+		return true
+	}
+	for _, moderator := range s.Moderators {
+		if moderator.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// IsApproved reports whether the user moderates the subreddit or is one of its approved users
+func (s *Subreddit) IsApproved(userID uuid.UUID) bool {
+	if s.IsModerator(userID) {
+		return true
+	}
+	for _, id := range s.ApprovedUsers {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// UserFlairTemplate is a label moderators define for members to show beside their
+// username in the subreddit, e.g. "Verified"
+type UserFlairTemplate struct {
+	ID      uuid.UUID `json:"id"`
+	Text    string    `json:"text"`
+	Color   string    `json:"color,omitempty"` // Hex color such as "#ff4500"
+	ModOnly bool      `json:"modOnly"`         // Only moderators can assign it
+}
+
+// UserFlair is the flair a member shows beside their username in one subreddit, copied
+// from its template
+type UserFlair struct {
+	TemplateID uuid.UUID `json:"templateId"`
+	Text       string    `json:"text"`
+	Color      string    `json:"color,omitempty"`
+}
+
+// AutomodRule removes, holds or reports new posts and comments that match a pattern or
+// link to a domain. Exactly one of Pattern and Domain is set.
+type AutomodRule struct {
+	ID      uuid.UUID `json:"id"`
+	Pattern string    `json:"pattern,omitempty"` // Regular expression, matched case-insensitively against the text
+	Domain  string    `json:"domain,omitempty"`  // Matches links to the domain and its subdomains
+	Action  string    `json:"action"`            // One of the AutomodAction* values
+}
+
+// What happens to a submission matching an automod rule
+const (
+	AutomodActionRemove = "remove" // Stored removed, hidden from everyone
+	AutomodActionQueue  = "queue"  // Held in the mod queue like posts in restricted subreddits
+	AutomodActionReport = "report" // Published, but flagged for moderators
+)
+
+// PostFlair is a label moderators define for categorizing posts, e.g. "Discussion"
+type PostFlair struct {
+	ID    uuid.UUID `json:"id"`
+	Text  string    `json:"text"`
+	Color string    `json:"color,omitempty"` // Hex color such as "#ff4500"
 }
@@ -10,10 +10,52 @@ type User struct {
 	ID             uuid.UUID   `json:"id"`
 	Username       string      `json:"username"`
 	Email          string      `json:"email"`
-	HashedPassword string      `json:"-"` // Won't be included in JSON responses
+	EmailVerified  bool        `json:"emailVerified"` // Reset whenever the email changes
+	HashedPassword string      `json:"-"`             // Won't be included in JSON responses
 	Karma          int         `json:"karma"`
+	PostKarma      int         `json:"postKarma"`    // Karma from post votes
+	CommentKarma   int         `json:"commentKarma"` // Karma from comment votes; Karma is the total
 	CreatedAt      time.Time   `json:"createdAt"`
 	LastActive     time.Time   `json:"lastActive"`
 	IsConnected    bool        `json:"isConnected"`
 	Subreddits     []uuid.UUID `json:"subreddits" bson:"subreddits"`
+
+	// Profile details the user chooses
+	DisplayName string `json:"displayName,omitempty"`
+	Bio         string `json:"bio,omitempty"`
+	AvatarURL   string `json:"avatarUrl,omitempty"`
+
+	// Account standing, used to catch ban evasion through re-registration
+	NormalizedEmail  string     `json:"-"`
+	IsBanned         bool       `json:"-"`
+	AbuseDeletedAt   *time.Time `json:"-"` // Set when the account was deleted for abuse
+	FlaggedForReview bool       `json:"-"`
+
+	IsAdmin bool `json:"-"` // Site administrator, granted by the bootstrap lists or another admin
+
+	Preferences UserPreferences `json:"-"`
+}
+
+// UserPreferences are settings users keep for how content is shown to them. The zero
+// value holds the defaults, which apply to users who never set anything.
+type UserPreferences struct {
+	FeedSort          string `json:"feedSort" bson:"feedSort,omitempty"`                   // Sort of the home feed and subreddit listings; empty for each listing's own default
+	ShowNSFW          bool   `json:"showNsfw" bson:"showNsfw,omitempty"`                   // Include posts from NSFW subreddits in the home feed
+	CollapseThreshold *int   `json:"collapseThreshold" bson:"collapseThreshold,omitempty"` // Collapse comments at or below this karma; nil for the site default
+}
+
+// BlockedUser is one entry in the list of users someone has blocked
+type BlockedUser struct {
+	UserID    uuid.UUID `json:"userId"`
+	Username  string    `json:"username"`
+	BlockedAt time.Time `json:"blockedAt"`
+}
+
+// FollowEntry is one user in a list of the users someone follows or is followed by
+type FollowEntry struct {
+	UserID      uuid.UUID `json:"userId"`
+	Username    string    `json:"username"`
+	DisplayName string    `json:"displayName,omitempty"`
+	AvatarURL   string    `json:"avatarUrl,omitempty"`
+	FollowedAt  time.Time `json:"followedAt"`
 }
@@ -0,0 +1,16 @@
+// Package storage holds uploaded media files. Handlers write through the Storage
+// interface so the local-disk backend can be swapped for an object store.
+package storage
+
+import (
+	"context"
+	"io"
+)
+
+// Storage stores files under caller-chosen keys and serves them from public URLs
+type Storage interface {
+	// Put stores the contents of r under key and returns the URL the file is served from
+	Put(ctx context.Context, key string, r io.Reader, contentType string) (string, error)
+	// Delete removes the file stored under key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,8 @@
+package types
+
+// PaginatedResponse is the standard envelope for cursor-paginated lists.
+// NextCursor is empty on the last page.
+type PaginatedResponse struct {
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"nextCursor"`
+}
@@ -5,4 +5,46 @@ type LoginResponse struct {
 	Token   string `json:"token,omitempty"`
 	Error   string `json:"error,omitempty"`
 	UserID  string `json:"userId"`
+
+	Username string `json:"username,omitempty"`
+
+	// Opaque token that POST /user/refresh exchanges for a new access token. It can be
+	// used once; the refresh returns its replacement.
+	RefreshToken string `json:"refreshToken,omitempty"`
+}
+
+// RefreshRequest asks for a new access token in exchange for a refresh token
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// ChangePasswordRequest replaces the caller's password. RefreshToken names the session
+// to keep; every other session is ended.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+	RefreshToken    string `json:"refreshToken"`
+}
+
+// PasswordResetRequest asks for a password reset token to be emailed
+type PasswordResetRequest struct {
+	Email string `json:"email"`
+}
+
+// PasswordResetConfirmRequest sets a new password using an emailed reset token
+type PasswordResetConfirmRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"newPassword"`
+}
+
+// ChangeEmailRequest moves the caller to a new email address, which must then be verified
+type ChangeEmailRequest struct {
+	CurrentPassword string `json:"currentPassword"`
+	Email           string `json:"email"`
+}
+
+// LogoutRequest names the refresh token of the session to end. It is optional when the
+// request carries an access token.
+type LogoutRequest struct {
+	RefreshToken string `json:"refreshToken"`
 }
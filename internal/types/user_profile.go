@@ -0,0 +1,33 @@
+package types
+
+import "time"
+
+// PublicUserProfile is what anyone can see of a user. It is built field by field so that
+// nothing private reaches other users by being added to the stored user.
+type PublicUserProfile struct {
+	ID             string    `json:"id"`
+	Username       string    `json:"username"`
+	DisplayName    string    `json:"displayName"`
+	Bio            string    `json:"bio"`
+	AvatarURL      string    `json:"avatarUrl"`
+	Karma          int       `json:"karma"` // Total of PostKarma and CommentKarma
+	PostKarma      int       `json:"postKarma"`
+	CommentKarma   int       `json:"commentKarma"`
+	FollowerCount  int64     `json:"followerCount"`
+	FollowingCount int64     `json:"followingCount"`
+	ActiveRecently bool      `json:"activeRecently"` // Active within the configured window; the exact time is private
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// PrivateUserProfile is a user's view of their own profile: the public profile plus
+// their account details, activity and subscriptions
+type PrivateUserProfile struct {
+	PublicUserProfile
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"emailVerified"`
+	IsConnected   bool      `json:"isConnected"`
+	LastActive    time.Time `json:"lastActive"`
+	IsAdmin       bool      `json:"isAdmin"`
+	SubredditID   []string  `json:"subredditID"`
+	SubredditName []string  `json:"subredditName"`
+}
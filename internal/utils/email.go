@@ -0,0 +1,58 @@
+package utils
+
+import "strings"
+
+// emailProvider describes how a mail provider treats variations of the same address
+type emailProvider struct {
+	canonicalDomain string // Domain the provider's aliases are folded into
+	ignoreDots      bool   // Dots in the local part are not significant
+}
+
+// knownEmailProviders lists domains whose addressing rules are known. Other domains
+// only get case folding and plus-suffix stripping.
+var knownEmailProviders = map[string]emailProvider{
+	"gmail.com":      {canonicalDomain: "gmail.com", ignoreDots: true},
+	"googlemail.com": {canonicalDomain: "gmail.com", ignoreDots: true},
+	"outlook.com":    {canonicalDomain: "outlook.com"},
+	"hotmail.com":    {canonicalDomain: "hotmail.com"},
+	"live.com":       {canonicalDomain: "live.com"},
+	"icloud.com":     {canonicalDomain: "icloud.com"},
+	"me.com":         {canonicalDomain: "icloud.com"},
+	"mac.com":        {canonicalDomain: "icloud.com"},
+	"protonmail.com": {canonicalDomain: "proton.me"},
+	"protonmail.ch":  {canonicalDomain: "proton.me"},
+	"pm.me":          {canonicalDomain: "proton.me"},
+	"proton.me":      {canonicalDomain: "proton.me"},
+	"fastmail.com":   {canonicalDomain: "fastmail.com"},
+}
+
+// NormalizeEmail reduces an email address to a canonical form so that addresses
+// delivering to the same mailbox compare equal. It lowercases the address, strips
+// "+tag" suffixes and applies provider-specific rules such as Gmail ignoring dots.
+// Input without a usable "@" is returned trimmed and lowercased.
+func NormalizeEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		return email
+	}
+	local, domain := email[:at], strings.TrimSuffix(email[at+1:], ".")
+
+	if plus := strings.Index(local, "+"); plus >= 0 {
+		local = local[:plus]
+	}
+
+	if provider, ok := knownEmailProviders[domain]; ok {
+		domain = provider.canonicalDomain
+		if provider.ignoreDots {
+			local = strings.ReplaceAll(local, ".", "")
+		}
+	}
+
+	// An address like "+tag@example.com" has no mailbox part left to compare
+	if local == "" {
+		return email
+	}
+	return local + "@" + domain
+}
@@ -0,0 +1,57 @@
+package utils
+
+import "testing"
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		name  string
+		email string
+		want  string
+	}{
+		// Case folding and whitespace
+		{"lowercases", "Gator.User@Example.COM", "gator.user@example.com"},
+		{"trims whitespace", "  user@example.com\t", "user@example.com"},
+		{"plain address unchanged", "user@example.com", "user@example.com"},
+
+		// +tags
+		{"strips plus tag", "user+reddit@example.com", "user@example.com"},
+		{"strips from first plus", "user+a+b@example.com", "user@example.com"},
+		{"empty plus tag", "user+@example.com", "user@example.com"},
+
+		// Gmail ignores dots and folds googlemail.com into gmail.com
+		{"gmail strips dots", "g.a.t.o.r@gmail.com", "gator@gmail.com"},
+		{"gmail dots and tag", "Ga.Tor+spam@Gmail.com", "gator@gmail.com"},
+		{"googlemail folds to gmail", "ga.tor@googlemail.com", "gator@gmail.com"},
+		{"gmail trailing dot in domain", "ga.tor@gmail.com.", "gator@gmail.com"},
+
+		// Other providers keep dots
+		{"outlook keeps dots", "ga.tor@outlook.com", "ga.tor@outlook.com"},
+		{"unknown domain keeps dots", "ga.tor+x@example.org", "ga.tor@example.org"},
+		{"icloud aliases fold", "ga.tor@me.com", "ga.tor@icloud.com"},
+		{"proton aliases fold", "Ga.Tor+x@protonmail.ch", "ga.tor@proton.me"},
+
+		// Malformed input is only trimmed and lowercased
+		{"no at sign", " Not-An-Email ", "not-an-email"},
+		{"empty local part", "@Example.com", "@example.com"},
+		{"empty domain", "User@", "user@"},
+		{"only a plus tag", "+Tag@Example.com", "+tag@example.com"},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeEmail(tt.email); got != tt.want {
+				t.Errorf("NormalizeEmail(%q) = %q, want %q", tt.email, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeEmailMatchesAliases(t *testing.T) {
+	aliases := []string{"gator@gmail.com", "G.A.T.O.R@gmail.com", "gator+swamp@googlemail.com"}
+	for _, alias := range aliases {
+		if got := NormalizeEmail(alias); got != NormalizeEmail(aliases[0]) {
+			t.Errorf("NormalizeEmail(%q) = %q, want it to match %q", alias, got, NormalizeEmail(aliases[0]))
+		}
+	}
+}
@@ -1,11 +1,16 @@
 package utils
 
-import "fmt"
+import (
+	"fmt"
+	"gator-swamp/internal/i18n"
+)
 
 type AppError struct {
-	Code    string
-	Message string
-	Origin  error // Original error that caused this error, if any
+	Code       string
+	Message    string            // English message, used for logs and as the fallback text
+	MessageKey string            // i18n key for the user-facing message, if localizable
+	Params     map[string]string // Values substituted into the localized message
+	Origin     error             // Original error that caused this error, if any
 }
 
 func (appErr *AppError) Error() string {
@@ -21,6 +26,8 @@ const (
 	ErrNotFound     = "NOT_FOUND"
 	ErrDuplicate    = "DUPLICATE"
 	ErrInvalidInput = "INVALID_INPUT"
+	ErrGone         = "GONE"     // Resource existed but has since been removed
+	ErrArchived     = "ARCHIVED" // Post is old enough to be read-only
 
 	// Authentication/Authorization errors
 	ErrUnauthorized = "UNAUTHORIZED"
@@ -59,6 +66,27 @@ func NewAppError(code string, message string, originalErr error) *AppError {
 	}
 }
 
+// NewLocalizedError creates an error whose user-facing message is looked up by key
+// in the client's language. Message holds the English rendering.
+func NewLocalizedError(code string, key string, params map[string]string, originalErr error) *AppError {
+	return &AppError{
+		Code:       code,
+		Message:    i18n.T(i18n.DefaultLanguage, key, params),
+		MessageKey: key,
+		Params:     params,
+		Origin:     originalErr,
+	}
+}
+
+// LocalizedMessage renders the error for a client in lang. Errors without a
+// message key keep their English message.
+func (appErr *AppError) LocalizedMessage(lang string) string {
+	if appErr.MessageKey == "" {
+		return appErr.Error()
+	}
+	return i18n.T(lang, appErr.MessageKey, appErr.Params)
+}
+
 // Specific error creators for common cases
 func NewUserNotFoundError(userId string) *AppError {
 	return &AppError{
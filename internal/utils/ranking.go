@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"math"
+	"time"
+)
+
+// wilsonZ is the z-score for a 95% confidence level
+const wilsonZ = 1.96
+
+// WilsonScore is the lower bound of the Wilson score confidence interval for the share
+// of upvotes. It ranks a comment by how good it is likely to be given the votes so far,
+// so a few unanimous upvotes beat many votes with a weak majority. It is zero without votes.
+func WilsonScore(upvotes, downvotes int) float64 {
+	n := float64(upvotes + downvotes)
+	if n <= 0 {
+		return 0
+	}
+	p := float64(upvotes) / n
+	z2 := wilsonZ * wilsonZ
+	return (p + z2/(2*n) - wilsonZ*math.Sqrt((p*(1-p)+z2/(4*n))/n)) / (1 + z2/n)
+}
+
+// ControversyScore rates how evenly split and how heavily voted an item is. It is
+// zero unless the item has both upvotes and downvotes, and grows with the total
+// number of votes the closer the split is to even.
+func ControversyScore(upvotes, downvotes int) float64 {
+	if upvotes <= 0 || downvotes <= 0 {
+		return 0
+	}
+	magnitude := float64(upvotes + downvotes)
+	balance := float64(min(upvotes, downvotes)) / float64(max(upvotes, downvotes))
+	return math.Pow(magnitude, balance)
+}
+
+// PostControversyScore is the ControversyScore of a post, or zero if it has fewer than
+// minVotes votes so a post split one to one doesn't lead controversial listings
+func PostControversyScore(upvotes, downvotes, minVotes int) float64 {
+	if upvotes+downvotes < minVotes {
+		return 0
+	}
+	return ControversyScore(upvotes, downvotes)
+}
+
+// hotEpoch anchors hot scores so they stay small; only differences between scores matter
+var hotEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// hotHalfDay is how many seconds of recency are worth a tenfold change in karma
+const hotHalfDay = 45000
+
+// HotScore ranks a post by its karma, weighted towards newer posts: a post 12.5 hours
+// newer than another needs a tenth of its karma to rank alongside it. The score only
+// depends on the post's karma and creation time, so it can be stored and indexed.
+func HotScore(karma int, createdAt time.Time) float64 {
+	order := math.Log10(math.Max(math.Abs(float64(karma)), 1))
+	var sign float64
+	switch {
+	case karma > 0:
+		sign = 1
+	case karma < 0:
+		sign = -1
+	}
+	return sign*order + createdAt.Sub(hotEpoch).Seconds()/hotHalfDay
+}
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"math"
+	"sort"
+	"testing"
+)
+
+func TestWilsonScoreRanksConfidenceOverVolume(t *testing.T) {
+	unanimous := WilsonScore(5, 0)
+	contested := WilsonScore(100, 80)
+	if unanimous <= contested {
+		t.Errorf("WilsonScore(5, 0) = %v, want it above WilsonScore(100, 80) = %v", unanimous, contested)
+	}
+}
+
+func TestWilsonScoreOrdering(t *testing.T) {
+	// Best first, as the best sort should list them
+	votes := [][2]int{
+		{1000, 10}, // overwhelming and heavily voted
+		{50, 1},
+		{5, 0},
+		{100, 80},
+		{1, 0},
+		{1, 1},
+		{0, 0},
+		{0, 5},
+	}
+
+	scores := make([]float64, len(votes))
+	for i, v := range votes {
+		scores[i] = WilsonScore(v[0], v[1])
+	}
+	if !sort.SliceIsSorted(scores, func(i, j int) bool { return scores[i] > scores[j] }) {
+		t.Errorf("scores out of order for %v: %v", votes, scores)
+	}
+}
+
+func TestWilsonScoreBounds(t *testing.T) {
+	tests := []struct {
+		up, down int
+	}{
+		{0, 0}, {1, 0}, {0, 1}, {10, 10}, {100000, 0}, {0, 100000},
+	}
+	for _, tt := range tests {
+		score := WilsonScore(tt.up, tt.down)
+		if math.IsNaN(score) || score < 0 || score > 1 {
+			t.Errorf("WilsonScore(%d, %d) = %v, want a value in [0, 1]", tt.up, tt.down, score)
+		}
+	}
+	if got := WilsonScore(0, 0); got != 0 {
+		t.Errorf("WilsonScore(0, 0) = %v, want 0", got)
+	}
+}
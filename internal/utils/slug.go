@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// maxSlugLength caps slugs so permalinks stay readable
+const maxSlugLength = 60
+
+// Slugify turns a title into the URL-safe part of a permalink: lowercased, with runs of
+// anything other than letters and digits replaced by a single hyphen, and truncated at a
+// word boundary where possible. Titles with no letters or digits give "post".
+func Slugify(title string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			hyphen = false
+			b.WriteRune(r)
+			continue
+		}
+		hyphen = true
+	}
+
+	slug := b.String()
+	if len(slug) > maxSlugLength {
+		cut := maxSlugLength
+		for cut > 0 && slug[cut]&0xC0 == 0x80 { // Don't split a multi-byte letter
+			cut--
+		}
+		slug = slug[:cut]
+		if i := strings.LastIndexByte(slug, '-'); i > maxSlugLength/2 {
+			slug = slug[:i]
+		}
+		slug = strings.TrimRight(slug, "-")
+	}
+	if slug == "" {
+		return "post"
+	}
+	return slug
+}
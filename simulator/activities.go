@@ -360,13 +360,16 @@ func (s *EnhancedSimulator) getRandomPostToComment(user *SimulatedUser) (uuid.UU
 			continue
 		}
 
-		// If it's not an error response, try to parse as array of posts
-		var posts []models.Post
-		if err := json.Unmarshal(resp, &posts); err != nil {
+		// If it's not an error response, try to parse as a page of posts
+		var page struct {
+			Items []models.Post `json:"items"`
+		}
+		if err := json.Unmarshal(resp, &page); err != nil {
 			log.Printf("Debug: Error parsing posts: %v", err)
 			log.Printf("Debug: Raw API response: %s", string(resp))
 			continue
 		}
+		posts := page.Items
 
 		if len(posts) == 0 {
 			log.Printf("Debug: Empty posts array for subreddit %s", subredditID)
@@ -249,7 +249,6 @@ func (s *EnhancedSimulator) registerUserWithClient(ctx context.Context, user *Si
 		"username": user.Username,
 		"email":    user.Email,
 		"password": "testpass123",
-		"karma":    300,
 	}
 
 	// First verify if user already exists
@@ -568,7 +567,6 @@ func (s *EnhancedSimulator) registerUserWithRetry(ctx context.Context, user *Sim
 		"username": user.Username,
 		"email":    user.Email,
 		"password": "testpass123",
-		"karma":    300,
 	}
 
 	// Create custom client with shorter timeout